@@ -5,12 +5,26 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"os"
+	"os/signal"
+	"time"
 
 	"github.com/mitchellh/cli"
 	"github.com/spiffe/go-spiffe/v2/proto/spiffe/workload"
+	"github.com/spiffe/go-spiffe/v2/svid/jwtsvid"
 	common_cli "github.com/spiffe/spire/pkg/common/cli"
 )
 
+const (
+	// minJWTRefreshInterval bounds how often JWT-SVIDs are fetched in watch
+	// mode, even if the SVID is about to expire
+	minJWTRefreshInterval = time.Second
+
+	// jwtRetryInterval is how long to wait before fetching again in watch
+	// mode after a failed fetch
+	jwtRetryInterval = 5 * time.Second
+)
+
 func NewFetchJWTCommand() cli.Command {
 	return newFetchJWTCommand(common_cli.DefaultEnv, newWorkloadClient)
 }
@@ -22,6 +36,7 @@ func newFetchJWTCommand(env *common_cli.Env, clientMaker workloadClientMaker) cl
 type fetchJWTCommand struct {
 	audience common_cli.CommaStringsFlag
 	spiffeID string
+	watch    bool
 }
 
 func (c *fetchJWTCommand) name() string {
@@ -37,6 +52,10 @@ func (c *fetchJWTCommand) run(ctx context.Context, env *common_cli.Env, client *
 		return errors.New("audience must be specified")
 	}
 
+	if c.watch {
+		return c.watchJWTSVID(ctx, env, client)
+	}
+
 	bundlesResp, err := c.fetchJWTBundles(ctx, client)
 	if err != nil {
 		return err
@@ -60,6 +79,73 @@ func (c *fetchJWTCommand) run(ctx context.Context, env *common_cli.Env, client *
 func (c *fetchJWTCommand) appendFlags(fs *flag.FlagSet) {
 	fs.Var(&c.audience, "audience", "comma separated list of audience values")
 	fs.StringVar(&c.spiffeID, "spiffeID", "", "SPIFFE ID subject (optional)")
+	fs.BoolVar(&c.watch, "watch", false, "Keep running and fetch a new JWT SVID when half of the lifetime of the current one has elapsed")
+}
+
+// watchJWTSVID fetches JWT-SVIDs until interrupted, fetching new ones when
+// half of the lifetime of the current ones has elapsed. Each token is printed
+// along with its issued and expiry times.
+func (c *fetchJWTCommand) watchJWTSVID(ctx context.Context, env *common_cli.Env, client *workloadClient) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	for {
+		refreshIn, err := c.fetchAndPrintJWTSVID(ctx, env, client)
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case err != nil:
+			_ = env.ErrPrintf("Failed to fetch JWT SVID, retrying in %s: %v\n", jwtRetryInterval, err)
+			refreshIn = jwtRetryInterval
+		}
+
+		select {
+		case <-time.After(refreshIn):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// fetchAndPrintJWTSVID fetches and prints the JWT-SVIDs and returns how long
+// to wait before fetching them again.
+func (c *fetchJWTCommand) fetchAndPrintJWTSVID(ctx context.Context, env *common_cli.Env, client *workloadClient) (time.Duration, error) {
+	fetchedAt := time.Now()
+	svidResp, err := c.fetchJWTSVID(ctx, client)
+	if err != nil {
+		return 0, err
+	}
+	if len(svidResp.Svids) == 0 {
+		return 0, errors.New("no JWT SVIDs in response")
+	}
+
+	var refreshAt time.Time
+	for _, svid := range svidResp.Svids {
+		parsed, err := jwtsvid.ParseInsecure(svid.Svid, c.audience)
+		if err != nil {
+			return 0, fmt.Errorf("unable to parse JWT SVID: %w", err)
+		}
+
+		issuedAt := fetchedAt
+		if iat, ok := parsed.Claims["iat"].(float64); ok {
+			issuedAt = time.Unix(int64(iat), 0)
+		}
+
+		_ = env.Printf("token(%s):\n\t%s\n", svid.SpiffeId, svid.Svid)
+		_ = env.Printf("issued at:\t%s\n", issuedAt.UTC().Format(time.RFC3339))
+		_ = env.Printf("expires at:\t%s\n", parsed.Expiry.UTC().Format(time.RFC3339))
+
+		svidRefreshAt := issuedAt.Add(parsed.Expiry.Sub(issuedAt) / 2)
+		if refreshAt.IsZero() || svidRefreshAt.Before(refreshAt) {
+			refreshAt = svidRefreshAt
+		}
+	}
+
+	refreshIn := time.Until(refreshAt)
+	if refreshIn < minJWTRefreshInterval {
+		refreshIn = minJWTRefreshInterval
+	}
+	return refreshIn, nil
 }
 
 func (c *fetchJWTCommand) fetchJWTSVID(ctx context.Context, client *workloadClient) (*workload.JWTSVIDResponse, error) {
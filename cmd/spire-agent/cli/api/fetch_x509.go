@@ -9,6 +9,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"path"
 	"time"
 
@@ -18,8 +19,13 @@ import (
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
 	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
 	common_cli "github.com/spiffe/spire/pkg/common/cli"
+	"github.com/spiffe/spire/pkg/common/diskutil"
 )
 
+// x509RetryInterval is how long to wait before fetching again in watch mode
+// after the X509-SVID stream fails
+const x509RetryInterval = 5 * time.Second
+
 func NewFetchX509Command() cli.Command {
 	return newFetchX509Command(common_cli.DefaultEnv, newWorkloadClient)
 }
@@ -31,6 +37,14 @@ func newFetchX509Command(env *common_cli.Env, clientMaker workloadClientMaker) c
 type fetchX509Command struct {
 	silent    bool
 	writePath string
+	watch     bool
+
+	// Paths to write the default X509-SVID, its key and the bundles of its
+	// trust domain to
+	certOut      string
+	keyOut       string
+	bundleOut    string
+	jwtBundleOut string
 }
 
 func (*fetchX509Command) name() string {
@@ -42,6 +56,10 @@ func (*fetchX509Command) synopsis() string {
 }
 
 func (c *fetchX509Command) run(ctx context.Context, env *common_cli.Env, client *workloadClient) error {
+	if c.watch {
+		return c.watchX509SVID(ctx, env, client)
+	}
+
 	start := time.Now()
 	resp, err := c.fetchX509SVID(ctx, client)
 	respTime := time.Since(start)
@@ -49,6 +67,22 @@ func (c *fetchX509Command) run(ctx context.Context, env *common_cli.Env, client
 		return err
 	}
 
+	return c.handleResponse(ctx, env, client, resp, respTime)
+}
+
+func (c *fetchX509Command) appendFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.silent, "silent", false, "Suppress stdout")
+	fs.StringVar(&c.writePath, "write", "", "Write SVID data to the specified path (optional)")
+	fs.BoolVar(&c.watch, "watch", false, "Keep running and handle every X509 SVID update, rewriting the output files on rotation")
+	fs.StringVar(&c.certOut, "certOut", "", "Write the default X509 SVID certificate chain to the specified file, in PEM format (optional)")
+	fs.StringVar(&c.keyOut, "keyOut", "", "Write the default X509 SVID private key to the specified file, in PEM format (optional)")
+	fs.StringVar(&c.bundleOut, "bundleOut", "", "Write the X509 bundle of the default X509 SVID trust domain to the specified file, in PEM format (optional)")
+	fs.StringVar(&c.jwtBundleOut, "jwtBundleOut", "", "Write the JWT bundle of the default X509 SVID trust domain to the specified file, as a JWKS (optional)")
+}
+
+// handleResponse prints the X509-SVIDs in the response and writes them to
+// the requested files.
+func (c *fetchX509Command) handleResponse(ctx context.Context, env *common_cli.Env, client *workloadClient, resp *workload.X509SVIDResponse, respTime time.Duration) error {
 	svids, err := parseAndValidateX509SVIDResponse(resp)
 	if err != nil {
 		return err
@@ -64,12 +98,52 @@ func (c *fetchX509Command) run(ctx context.Context, env *common_cli.Env, client
 		}
 	}
 
-	return nil
+	return c.writeOutFiles(ctx, env, client, svids[0])
 }
 
-func (c *fetchX509Command) appendFlags(fs *flag.FlagSet) {
-	fs.BoolVar(&c.silent, "silent", false, "Suppress stdout")
-	fs.StringVar(&c.writePath, "write", "", "Write SVID data to the specified path (optional)")
+// watchX509SVID handles X509-SVID updates until interrupted, fetching them
+// again if the stream fails.
+func (c *fetchX509Command) watchX509SVID(ctx context.Context, env *common_cli.Env, client *workloadClient) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	for {
+		err := c.streamX509SVID(ctx, env, client)
+		if ctx.Err() != nil {
+			return nil
+		}
+		_ = env.ErrPrintf("Failed to fetch X509 SVID, retrying in %s: %v\n", x509RetryInterval, err)
+
+		select {
+		case <-time.After(x509RetryInterval):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// streamX509SVID handles the X509-SVID updates received on a single stream
+// until it fails. The timeout does not apply since the stream is long-lived.
+func (c *fetchX509Command) streamX509SVID(ctx context.Context, env *common_cli.Env, client *workloadClient) error {
+	ctx, cancel := context.WithCancel(withSecurityHeader(ctx))
+	defer cancel()
+
+	start := time.Now()
+	stream, err := client.FetchX509SVID(ctx, &workload.X509SVIDRequest{})
+	if err != nil {
+		return err
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if err := c.handleResponse(ctx, env, client, resp, time.Since(start)); err != nil {
+			return err
+		}
+		start = time.Now()
+	}
 }
 
 func (c *fetchX509Command) fetchX509SVID(ctx context.Context, client *workloadClient) (*workload.X509SVIDResponse, error) {
@@ -127,6 +201,69 @@ func (c *fetchX509Command) writeResponse(svids []*X509SVID) error {
 	return nil
 }
 
+// writeOutFiles writes the given X509-SVID, its key and the bundles of its
+// trust domain to the requested files. Each file is replaced atomically so
+// readers never observe a partially written file.
+func (c *fetchX509Command) writeOutFiles(ctx context.Context, env *common_cli.Env, client *workloadClient, svid *X509SVID) error {
+	if c.certOut != "" {
+		if err := c.writeCerts(c.certOut, svid.Certificates); err != nil {
+			return fmt.Errorf("failed to write X509 SVID: %w", err)
+		}
+	}
+
+	if c.keyOut != "" {
+		if err := c.writeKey(c.keyOut, svid.PrivateKey); err != nil {
+			return fmt.Errorf("failed to write X509 SVID key: %w", err)
+		}
+	}
+
+	if c.bundleOut != "" {
+		if err := c.writeCerts(c.bundleOut, svid.Bundle); err != nil {
+			return fmt.Errorf("failed to write X509 bundle: %w", err)
+		}
+	}
+
+	if c.jwtBundleOut != "" {
+		id, err := spiffeid.FromString(svid.SPIFFEID)
+		if err != nil {
+			return err
+		}
+		jwksJSON, err := c.fetchJWTBundle(ctx, client, id.TrustDomain())
+		if err != nil {
+			return err
+		}
+		if err := c.writeFile(c.jwtBundleOut, jwksJSON); err != nil {
+			return fmt.Errorf("failed to write JWT bundle: %w", err)
+		}
+	}
+
+	if !c.silent && (c.certOut != "" || c.keyOut != "" || c.bundleOut != "" || c.jwtBundleOut != "") {
+		_ = env.Printf("Wrote X509 SVID for %q, valid until %s\n", svid.SPIFFEID, svid.Certificates[0].NotAfter.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// fetchJWTBundle fetches the JWT bundle of the given trust domain, as a JWKS.
+func (c *fetchX509Command) fetchJWTBundle(ctx context.Context, client *workloadClient, td spiffeid.TrustDomain) ([]byte, error) {
+	ctx, cancel := client.prepareContext(ctx)
+	defer cancel()
+
+	stream, err := client.FetchJWTBundles(ctx, &workload.JWTBundlesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive JWT bundles: %w", err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive JWT bundles: %w", err)
+	}
+
+	jwksJSON, ok := resp.Bundles[td.IDString()]
+	if !ok {
+		return nil, fmt.Errorf("no JWT bundle for trust domain %q", td)
+	}
+	return jwksJSON, nil
+}
+
 // writeCerts takes a slice of data, which may contain multiple certificates,
 // and encodes them as PEM blocks, writing them to filename
 func (c *fetchX509Command) writeCerts(filename string, certs []*x509.Certificate) error {
@@ -153,12 +290,12 @@ func (c *fetchX509Command) writeKey(filename string, privateKey crypto.PrivateKe
 		Bytes: data,
 	}
 
-	return os.WriteFile(filename, pem.EncodeToMemory(b), 0600)
+	return diskutil.AtomicWriteFile(filename, pem.EncodeToMemory(b), 0600)
 }
 
-// writeFile creates or truncates filename, and writes data to it
+// writeFile atomically replaces filename with data
 func (c *fetchX509Command) writeFile(filename string, data []byte) error {
-	return os.WriteFile(filename, data, 0644) // nolint: gosec // expected permission for certificates
+	return diskutil.AtomicWriteFile(filename, data, 0644)
 }
 
 type X509SVID struct {
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/proto/spiffe/workload"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	common_cli "github.com/spiffe/spire/pkg/common/cli"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/spiffe/spire/test/testca"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+var (
+	td         = spiffeid.RequireTrustDomainFromString("example.org")
+	workloadID = spiffeid.RequireFromPath(td, "/workload")
+)
+
+func TestFetchX509WritesOutFiles(t *testing.T) {
+	ca := testca.New(t, td)
+	resp := newX509SVIDResponse(t, ca)
+	server := &fakeWorkloadAPIServer{
+		x509Responses: []*workload.X509SVIDResponse{resp},
+		jwtBundles:    map[string][]byte{td.IDString(): []byte(`{"keys":[]}`)},
+	}
+	dir := spiretest.TempDir(t)
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	cmd := newFetchX509Command(&common_cli.Env{
+		Stdin:  new(bytes.Buffer),
+		Stdout: stdout,
+		Stderr: stderr,
+	}, newWorkloadClient)
+
+	returnCode := cmd.Run([]string{
+		"-socketPath", startWorkloadAPIServer(t, server),
+		"-silent",
+		"-certOut", filepath.Join(dir, "svid.pem"),
+		"-keyOut", filepath.Join(dir, "svid.key"),
+		"-bundleOut", filepath.Join(dir, "bundle.pem"),
+		"-jwtBundleOut", filepath.Join(dir, "bundle.jwks"),
+	})
+	require.Empty(t, stderr.String())
+	require.Empty(t, stdout.String())
+	require.Equal(t, 0, returnCode)
+
+	requireOutFile(t, filepath.Join(dir, "svid.pem"), 0644, pemCerts(t, resp.Svids[0].X509Svid))
+	requireOutFile(t, filepath.Join(dir, "svid.key"), 0600, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: resp.Svids[0].X509SvidKey}))
+	requireOutFile(t, filepath.Join(dir, "bundle.pem"), 0644, pemCerts(t, resp.Svids[0].Bundle))
+	requireOutFile(t, filepath.Join(dir, "bundle.jwks"), 0644, []byte(`{"keys":[]}`))
+}
+
+func TestFetchX509WatchRewritesOutFilesOnRotation(t *testing.T) {
+	ca := testca.New(t, td)
+	first := newX509SVIDResponse(t, ca)
+	second := newX509SVIDResponse(t, ca)
+	rotate := make(chan struct{})
+	server := &fakeWorkloadAPIServer{
+		x509Responses: []*workload.X509SVIDResponse{first, second},
+		rotate:        rotate,
+	}
+	dir := spiretest.TempDir(t)
+	certOut := filepath.Join(dir, "svid.pem")
+
+	client, err := newWorkloadClient(context.Background(), startWorkloadAPIServer(t, server), time.Second)
+	require.NoError(t, err)
+
+	stdout := new(bytes.Buffer)
+	cmd := &fetchX509Command{
+		silent:  true,
+		watch:   true,
+		certOut: certOut,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- cmd.run(ctx, &common_cli.Env{Stdout: stdout, Stderr: stdout}, client)
+	}()
+
+	requireEventuallyOutFile(t, certOut, pemCerts(t, first.Svids[0].X509Svid))
+
+	// Simulate a rotation
+	close(rotate)
+	requireEventuallyOutFile(t, certOut, pemCerts(t, second.Svids[0].X509Svid))
+
+	cancel()
+	select {
+	case err := <-errCh:
+		require.NoError(t, err)
+	case <-time.After(time.Minute):
+		require.FailNow(t, "timed out waiting for the watch to stop")
+	}
+}
+
+func newX509SVIDResponse(t *testing.T, ca *testca.CA) *workload.X509SVIDResponse {
+	svid := ca.CreateX509SVID(workloadID)
+	certs, key, err := svid.MarshalRaw()
+	require.NoError(t, err)
+
+	var bundle []byte
+	for _, authority := range ca.X509Authorities() {
+		bundle = append(bundle, authority.Raw...)
+	}
+
+	return &workload.X509SVIDResponse{
+		Svids: []*workload.X509SVID{
+			{
+				SpiffeId:    workloadID.String(),
+				X509Svid:    certs,
+				X509SvidKey: key,
+				Bundle:      bundle,
+			},
+		},
+	}
+}
+
+func pemCerts(t *testing.T, der []byte) []byte {
+	certs, err := x509.ParseCertificates(der)
+	require.NoError(t, err)
+
+	var pemData []byte
+	for _, cert := range certs {
+		pemData = append(pemData, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+	return pemData
+}
+
+func requireOutFile(t *testing.T, path string, mode os.FileMode, expected []byte) {
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, mode, info.Mode().Perm())
+
+	actual, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, string(expected), string(actual))
+}
+
+func requireEventuallyOutFile(t *testing.T, path string, expected []byte) {
+	require.Eventually(t, func() bool {
+		actual, err := os.ReadFile(path)
+		return err == nil && bytes.Equal(expected, actual)
+	}, time.Minute, 10*time.Millisecond)
+}
+
+func startWorkloadAPIServer(t *testing.T, server workload.SpiffeWorkloadAPIServer) string {
+	return spiretest.StartGRPCSocketServerOnTempSocket(t, func(s *grpc.Server) {
+		workload.RegisterSpiffeWorkloadAPIServer(s, server)
+	})
+}
+
+type fakeWorkloadAPIServer struct {
+	workload.UnimplementedSpiffeWorkloadAPIServer
+
+	// x509Responses are sent on the X509-SVID stream. When rotate is set,
+	// every response after the first waits for it to be closed.
+	x509Responses []*workload.X509SVIDResponse
+	rotate        chan struct{}
+
+	jwtBundles map[string][]byte
+}
+
+func (s *fakeWorkloadAPIServer) FetchX509SVID(req *workload.X509SVIDRequest, stream workload.SpiffeWorkloadAPI_FetchX509SVIDServer) error {
+	for i, resp := range s.x509Responses {
+		if i > 0 && s.rotate != nil {
+			select {
+			case <-s.rotate:
+			case <-stream.Context().Done():
+				return nil
+			}
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+	<-stream.Context().Done()
+	return nil
+}
+
+func (s *fakeWorkloadAPIServer) FetchJWTBundles(req *workload.JWTBundlesRequest, stream workload.SpiffeWorkloadAPI_FetchJWTBundlesServer) error {
+	return stream.Send(&workload.JWTBundlesResponse{Bundles: s.jwtBundles})
+}
@@ -0,0 +1,162 @@
+package debug
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/mitchellh/cli"
+	debugv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/agent/debug/v1"
+	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
+	common_cli "github.com/spiffe/spire/pkg/common/cli"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	outputPretty = "pretty"
+	outputJSON   = "json"
+)
+
+func NewDebugCommand() cli.Command {
+	return newDebugCommand(common_cli.DefaultEnv)
+}
+
+func newDebugCommand(env *common_cli.Env) *debugCommand {
+	return &debugCommand{
+		env: env,
+	}
+}
+
+type debugCommand struct {
+	env *common_cli.Env
+
+	socketPath string
+	output     string
+	timeout    common_cli.DurationFlag
+}
+
+func (c *debugCommand) Help() string {
+	// ignoring parsing errors since "-h" is always supported by the flags package
+	_ = c.parseFlags([]string{"-h"})
+	return ""
+}
+
+func (c *debugCommand) Synopsis() string {
+	return "Prints agent SVID and cache state from the agent debug API"
+}
+
+func (c *debugCommand) Run(args []string) int {
+	if err := c.parseFlags(args); err != nil {
+		return 1
+	}
+	if err := c.run(); err != nil {
+		// Ignore error since a failure to write to stderr cannot very well be
+		// reported
+		_ = c.env.ErrPrintf("Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func (c *debugCommand) parseFlags(args []string) error {
+	c.timeout = common_cli.DurationFlag(5 * time.Second)
+
+	fs := flag.NewFlagSet("debug", flag.ContinueOnError)
+	fs.SetOutput(c.env.Stderr)
+	fs.StringVar(&c.socketPath, "socketPath", "", "Path to the SPIRE Agent admin API socket, as configured with admin_socket_path")
+	fs.StringVar(&c.output, "output", outputPretty, "Output format. Options: pretty and json")
+	fs.Var(&c.timeout, "timeout", "Time to wait for a response")
+	return fs.Parse(args)
+}
+
+func (c *debugCommand) run() error {
+	switch c.output {
+	case outputPretty, outputJSON:
+	default:
+		return fmt.Errorf("invalid output format %q: must be %q or %q", c.output, outputPretty, outputJSON)
+	}
+	if c.socketPath == "" {
+		return errors.New("the admin API socket path is required")
+	}
+
+	socketPath, err := filepath.Abs(c.socketPath)
+	if err != nil {
+		return err
+	}
+	if runtime.GOOS == "windows" {
+		// filepath.Abs on Windows  uses "\\" as separator, use "/" instead
+		socketPath = filepath.ToSlash(socketPath)
+	}
+	conn, err := grpc.DialContext(context.Background(), "unix:"+socketPath, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.timeout))
+	defer cancel()
+
+	resp, err := debugv1.NewDebugClient(conn).GetInfo(ctx, &debugv1.GetInfoRequest{})
+	if err != nil {
+		return fmt.Errorf("failed to get agent debug information: %w", err)
+	}
+
+	if c.output == outputJSON {
+		return c.printJSON(resp)
+	}
+	c.printPretty(resp)
+	return nil
+}
+
+// printJSON prints the response using the proto field names. Unset fields are
+// included so the keys present do not depend on the agent state.
+func (c *debugCommand) printJSON(resp *debugv1.GetInfoResponse) error {
+	out, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("unable to marshal output: %w", err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := json.Indent(buf, out, "", "  "); err != nil {
+		return fmt.Errorf("unable to marshal output: %w", err)
+	}
+	buf.WriteByte('\n')
+
+	_, err = buf.WriteTo(c.env.Stdout)
+	return err
+}
+
+func (c *debugCommand) printPretty(resp *debugv1.GetInfoResponse) {
+	for i, cert := range resp.SvidChain {
+		if i == 0 {
+			c.env.Println("Agent SVID:")
+		} else {
+			c.env.Printf("Intermediate #%d:\n", i)
+		}
+		if cert.Id != nil {
+			c.env.Printf("  SPIFFE ID  : %s\n", idString(cert.Id))
+		}
+		c.env.Printf("  Subject    : %s\n", cert.Subject)
+		c.env.Printf("  Expires at : %s\n", time.Unix(cert.ExpiresAt, 0).UTC())
+	}
+
+	c.env.Printf("Uptime            : %s\n", time.Duration(resp.Uptime)*time.Second)
+	c.env.Printf("Cached SVIDs      : %d\n", resp.SvidsCount)
+	if resp.LastSyncSuccess > 0 {
+		c.env.Printf("Last sync success : %s\n", time.Unix(resp.LastSyncSuccess, 0).UTC())
+	} else {
+		c.env.Println("Last sync success : never")
+	}
+}
+
+func idString(id *types.SPIFFEID) string {
+	return fmt.Sprintf("spiffe://%s%s", id.TrustDomain, id.Path)
+}
@@ -0,0 +1,154 @@
+package debug
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	debugv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/agent/debug/v1"
+	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
+	common_cli "github.com/spiffe/spire/pkg/common/cli"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	expiresAt = time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	lastSync  = time.Date(2029, 12, 31, 23, 0, 0, 0, time.UTC)
+
+	info = &debugv1.GetInfoResponse{
+		SvidChain: []*debugv1.GetInfoResponse_Cert{
+			{
+				Id:        &types.SPIFFEID{TrustDomain: "example.org", Path: "/spire/agent/join_token/abc"},
+				ExpiresAt: expiresAt.Unix(),
+				Subject:   "O=SPIRE,C=US",
+			},
+			{
+				ExpiresAt: expiresAt.Unix(),
+				Subject:   "CN=intermediate",
+			},
+		},
+		Uptime:          90,
+		SvidsCount:      3,
+		LastSyncSuccess: lastSync.Unix(),
+	}
+)
+
+func TestDebugPretty(t *testing.T) {
+	socketPath := startDebugServer(t, &fakeDebugServer{resp: info})
+
+	stdout, stderr, code := runCommand(t, "-socketPath", socketPath)
+	require.Equal(t, 0, code, stderr)
+	require.Equal(t, `Agent SVID:
+  SPIFFE ID  : spiffe://example.org/spire/agent/join_token/abc
+  Subject    : O=SPIRE,C=US
+  Expires at : 2030-01-02 03:04:05 +0000 UTC
+Intermediate #1:
+  Subject    : CN=intermediate
+  Expires at : 2030-01-02 03:04:05 +0000 UTC
+Uptime            : 1m30s
+Cached SVIDs      : 3
+Last sync success : 2029-12-31 23:00:00 +0000 UTC
+`, stdout)
+}
+
+func TestDebugPrettyNeverSynced(t *testing.T) {
+	socketPath := startDebugServer(t, &fakeDebugServer{resp: &debugv1.GetInfoResponse{}})
+
+	stdout, stderr, code := runCommand(t, "-socketPath", socketPath)
+	require.Equal(t, 0, code, stderr)
+	require.Equal(t, `Uptime            : 0s
+Cached SVIDs      : 0
+Last sync success : never
+`, stdout)
+}
+
+func TestDebugJSON(t *testing.T) {
+	socketPath := startDebugServer(t, &fakeDebugServer{resp: info})
+
+	stdout, stderr, code := runCommand(t, "-socketPath", socketPath, "-output", "json")
+	require.Equal(t, 0, code, stderr)
+	require.JSONEq(t, `{
+		"svid_chain": [
+			{
+				"id": {"trust_domain": "example.org", "path": "/spire/agent/join_token/abc"},
+				"expires_at": "1893553445",
+				"subject": "O=SPIRE,C=US"
+			},
+			{
+				"id": null,
+				"expires_at": "1893553445",
+				"subject": "CN=intermediate"
+			}
+		],
+		"uptime": 90,
+		"svids_count": 3,
+		"last_sync_success": "1893452400"
+	}`, stdout)
+}
+
+func TestDebugErrors(t *testing.T) {
+	socketPath := startDebugServer(t, &fakeDebugServer{err: status.Error(codes.Internal, "oh no")})
+
+	for _, tt := range []struct {
+		name      string
+		args      []string
+		expectErr string
+	}{
+		{
+			name:      "no socket path",
+			expectErr: "Error: the admin API socket path is required\n",
+		},
+		{
+			name:      "invalid output",
+			args:      []string{"-socketPath", socketPath, "-output", "yaml"},
+			expectErr: "Error: invalid output format \"yaml\": must be \"pretty\" or \"json\"\n",
+		},
+		{
+			name:      "debug API failure",
+			args:      []string{"-socketPath", socketPath},
+			expectErr: "Error: failed to get agent debug information: rpc error: code = Internal desc = oh no\n",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, code := runCommand(t, tt.args...)
+			require.Equal(t, 1, code)
+			require.Empty(t, stdout)
+			require.Equal(t, tt.expectErr, stderr)
+		})
+	}
+}
+
+func runCommand(t *testing.T, args ...string) (string, string, int) {
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	cmd := newDebugCommand(&common_cli.Env{
+		Stdin:  new(bytes.Buffer),
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+	code := cmd.Run(args)
+	return stdout.String(), stderr.String(), code
+}
+
+func startDebugServer(t *testing.T, server *fakeDebugServer) string {
+	return spiretest.StartGRPCSocketServerOnTempSocket(t, func(s *grpc.Server) {
+		debugv1.RegisterDebugServer(s, server)
+	})
+}
+
+type fakeDebugServer struct {
+	debugv1.UnsafeDebugServer
+
+	resp *debugv1.GetInfoResponse
+	err  error
+}
+
+func (s *fakeDebugServer) GetInfo(context.Context, *debugv1.GetInfoRequest) (*debugv1.GetInfoResponse, error) {
+	return s.resp, s.err
+}
@@ -26,6 +26,7 @@ import (
 	common_cli "github.com/spiffe/spire/pkg/common/cli"
 	"github.com/spiffe/spire/pkg/common/health"
 	"github.com/spiffe/spire/pkg/common/idutil"
+	"github.com/spiffe/spire/pkg/common/jwtsvid"
 	"github.com/spiffe/spire/pkg/common/log"
 	"github.com/spiffe/spire/pkg/common/pemutil"
 	"github.com/spiffe/spire/pkg/common/telemetry"
@@ -62,6 +63,8 @@ type agentConfig struct {
 	LogFile                       string    `hcl:"log_file"`
 	LogFormat                     string    `hcl:"log_format"`
 	LogLevel                      string    `hcl:"log_level"`
+	MaxRecvMsgSize                int       `hcl:"max_recv_message_size"`
+	MaxSendMsgSize                int       `hcl:"max_send_message_size"`
 	SDS                           sdsConfig `hcl:"sds"`
 	ServerAddress                 string    `hcl:"server_address"`
 	ServerPort                    int       `hcl:"server_port"`
@@ -72,7 +75,14 @@ type agentConfig struct {
 	AllowUnauthenticatedVerifiers bool      `hcl:"allow_unauthenticated_verifiers"`
 	AllowedForeignJWTClaims       []string  `hcl:"allowed_foreign_jwt_claims"`
 
-	AuthorizedDelegates []string `hcl:"authorized_delegates"`
+	AuthorizedDelegates         []string            `hcl:"authorized_delegates"`
+	AuthorizedDelegateSelectors map[string][]string `hcl:"authorized_delegate_selectors"`
+
+	WorkloadAPI *workloadAPIConfig `hcl:"workload_api"`
+
+	AllowedJWTAudienceWildcards []string `hcl:"allowed_jwt_audience_wildcards"`
+
+	JWTSVIDValidationLeeway string `hcl:"jwt_svid_validation_leeway"`
 
 	ConfigPath string
 	ExpandEnv  bool
@@ -93,6 +103,13 @@ type sdsConfig struct {
 	DefaultAllBundlesName string `hcl:"default_all_bundles_name"`
 }
 
+type workloadAPIConfig struct {
+	MaxRequestsPerSecond int      `hcl:"max_requests_per_second"`
+	Burst                int      `hcl:"burst"`
+	ShutdownGracePeriod  string   `hcl:"shutdown_grace_period"`
+	UnusedKeys           []string `hcl:",unusedKeys"`
+}
+
 type experimentalConfig struct {
 	SyncInterval string `hcl:"sync_interval"`
 
@@ -359,6 +376,8 @@ func NewAgentConfig(c *Config, logOptions []log.Option, allowUnknownConfig bool)
 
 	serverHostPort := net.JoinHostPort(c.Agent.ServerAddress, strconv.Itoa(c.Agent.ServerPort))
 	ac.ServerAddress = fmt.Sprintf("dns:///%s", serverHostPort)
+	ac.MaxRecvMessageSize = c.Agent.MaxRecvMsgSize
+	ac.MaxSendMessageSize = c.Agent.MaxSendMsgSize
 
 	logOptions = append(logOptions,
 		log.WithLevel(c.Agent.LogLevel),
@@ -421,6 +440,34 @@ func NewAgentConfig(c *Config, logOptions []log.Option, allowUnknownConfig bool)
 	ac.ProfilingNames = c.Agent.ProfilingNames
 
 	ac.AllowedForeignJWTClaims = c.Agent.AllowedForeignJWTClaims
+	ac.AllowedJWTAudienceWildcards = c.Agent.AllowedJWTAudienceWildcards
+
+	ac.JWTSVIDValidationLeeway = jwtsvid.DefaultLeeway
+	if c.Agent.JWTSVIDValidationLeeway != "" {
+		ac.JWTSVIDValidationLeeway, err = time.ParseDuration(c.Agent.JWTSVIDValidationLeeway)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse JWT-SVID validation leeway: %w", err)
+		}
+		if ac.JWTSVIDValidationLeeway < 0 {
+			return nil, errors.New("jwt_svid_validation_leeway cannot be negative")
+		}
+	}
+
+	if c.Agent.WorkloadAPI != nil {
+		ac.WorkloadAPIMaxRequestsPerSecond = c.Agent.WorkloadAPI.MaxRequestsPerSecond
+		ac.WorkloadAPIBurst = c.Agent.WorkloadAPI.Burst
+
+		if c.Agent.WorkloadAPI.ShutdownGracePeriod != "" {
+			var err error
+			ac.WorkloadAPIShutdownGracePeriod, err = time.ParseDuration(c.Agent.WorkloadAPI.ShutdownGracePeriod)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse workload API shutdown grace period: %w", err)
+			}
+			if ac.WorkloadAPIShutdownGracePeriod < 0 {
+				return nil, errors.New("workload_api.shutdown_grace_period cannot be negative")
+			}
+		}
+	}
 
 	ac.PluginConfigs = *c.Plugins
 	ac.Telemetry = c.Telemetry
@@ -442,6 +489,23 @@ func NewAgentConfig(c *Config, logOptions []log.Option, allowUnknownConfig bool)
 
 	ac.AuthorizedDelegates = c.Agent.AuthorizedDelegates
 
+	authorizedDelegates := make(map[string]bool, len(c.Agent.AuthorizedDelegates))
+	for _, authorizedDelegate := range c.Agent.AuthorizedDelegates {
+		authorizedDelegates[authorizedDelegate] = true
+	}
+	for delegate, patterns := range c.Agent.AuthorizedDelegateSelectors {
+		if !authorizedDelegates[delegate] {
+			return nil, fmt.Errorf("authorized_delegate_selectors has patterns for %q, which is not an authorized delegate", delegate)
+		}
+		for _, pattern := range patterns {
+			parts := strings.SplitN(pattern, ":", 2)
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				return nil, fmt.Errorf("invalid selector pattern %q for authorized delegate %q: expected \"type:value\"", pattern, delegate)
+			}
+		}
+	}
+	ac.AuthorizedDelegateSelectors = c.Agent.AuthorizedDelegateSelectors
+
 	return ac, nil
 }
 
@@ -462,6 +526,14 @@ func validateConfig(c *Config) error {
 		return errors.New("trust_domain must be configured")
 	}
 
+	if c.Agent.MaxRecvMsgSize < 0 {
+		return errors.New("max_recv_message_size must not be negative")
+	}
+
+	if c.Agent.MaxSendMsgSize < 0 {
+		return errors.New("max_send_message_size must not be negative")
+	}
+
 	// If trust_bundle_url is set, download the trust bundle using HTTP and parse it from memory
 	// If trust_bundle_path is set, parse the trust bundle file on disk
 	// Both cannot be set
@@ -488,6 +560,30 @@ func validateConfig(c *Config) error {
 		return errors.New("plugins section must be configured")
 	}
 
+	for _, wildcard := range c.Agent.AllowedJWTAudienceWildcards {
+		prefix := strings.TrimSuffix(wildcard, "*")
+		if prefix == wildcard || prefix == "" || strings.Contains(prefix, "*") {
+			return fmt.Errorf("invalid audience wildcard %q in allowed_jwt_audience_wildcards: must be a non-empty prefix followed by a single trailing \"*\"", wildcard)
+		}
+		// The wildcard must match whole path segments, so that e.g.
+		// "spiffe://example.org/gateway*" cannot match
+		// "spiffe://example.org/gatewayevil".
+		if !strings.HasSuffix(prefix, "/") {
+			return fmt.Errorf("invalid audience wildcard %q in allowed_jwt_audience_wildcards: must end with \"/*\"", wildcard)
+		}
+	}
+
+	if w := c.Agent.WorkloadAPI; w != nil {
+		switch {
+		case w.MaxRequestsPerSecond < 0:
+			return errors.New("workload_api.max_requests_per_second cannot be negative")
+		case w.Burst < 0:
+			return errors.New("workload_api.burst cannot be negative")
+		case w.Burst > 0 && w.MaxRequestsPerSecond == 0:
+			return errors.New("workload_api.burst requires workload_api.max_requests_per_second to be configured")
+		}
+	}
+
 	return nil
 }
 
@@ -508,6 +604,10 @@ func checkForUnknownConfig(c *Config, l logrus.FieldLogger) (err error) {
 		detectedUnknown("agent", a.UnusedKeys)
 	}
 
+	if a := c.Agent; a != nil && a.WorkloadAPI != nil && len(a.WorkloadAPI.UnusedKeys) != 0 {
+		detectedUnknown("workload api", a.WorkloadAPI.UnusedKeys)
+	}
+
 	// TODO: Re-enable unused key detection for telemetry. See
 	// https://github.com/spiffe/spire/issues/1101 for more information
 	//
@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/hcl/hcl/printer"
 	"github.com/sirupsen/logrus"
@@ -807,6 +808,37 @@ func TestNewAgentConfig(t *testing.T) {
 				require.Nil(t, c)
 			},
 		},
+		{
+			msg: "max message sizes are set",
+			input: func(c *Config) {
+				c.Agent.MaxRecvMsgSize = 32 * 1024 * 1024
+				c.Agent.MaxSendMsgSize = 8 * 1024 * 1024
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.Equal(t, 32*1024*1024, c.MaxRecvMessageSize)
+				require.Equal(t, 8*1024*1024, c.MaxSendMessageSize)
+			},
+		},
+		{
+			msg:         "negative max_recv_message_size returns an error",
+			expectError: true,
+			input: func(c *Config) {
+				c.Agent.MaxRecvMsgSize = -1
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.Nil(t, c)
+			},
+		},
+		{
+			msg:         "negative max_send_message_size returns an error",
+			expectError: true,
+			input: func(c *Config) {
+				c.Agent.MaxSendMsgSize = -1
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.Nil(t, c)
+			},
+		},
 		{
 			msg: "admin_socket_path should be correctly configured",
 			input: func(c *Config) {
@@ -850,6 +882,46 @@ func TestNewAgentConfig(t *testing.T) {
 				require.Nil(t, c.AdminBindAddress)
 			},
 		},
+		{
+			msg: "authorized_delegate_selectors provided",
+			input: func(c *Config) {
+				c.Agent.AuthorizedDelegates = []string{"spiffe://example.org/delegate"}
+				c.Agent.AuthorizedDelegateSelectors = map[string][]string{
+					"spiffe://example.org/delegate": {"k8s:ns:payments", "k8s:sa:*"},
+				}
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.Equal(t, map[string][]string{
+					"spiffe://example.org/delegate": {"k8s:ns:payments", "k8s:sa:*"},
+				}, c.AuthorizedDelegateSelectors)
+			},
+		},
+		{
+			msg:         "authorized_delegate_selectors for a delegate that is not authorized",
+			expectError: true,
+			input: func(c *Config) {
+				c.Agent.AuthorizedDelegates = []string{"spiffe://example.org/delegate"}
+				c.Agent.AuthorizedDelegateSelectors = map[string][]string{
+					"spiffe://example.org/other": {"k8s:ns:payments"},
+				}
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.Nil(t, c)
+			},
+		},
+		{
+			msg:         "authorized_delegate_selectors with an invalid pattern",
+			expectError: true,
+			input: func(c *Config) {
+				c.Agent.AuthorizedDelegates = []string{"spiffe://example.org/delegate"}
+				c.Agent.AuthorizedDelegateSelectors = map[string][]string{
+					"spiffe://example.org/delegate": {"k8s"},
+				}
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.Nil(t, c)
+			},
+		},
 		{
 			msg: "allowed_foreign_jwt_claims provided",
 			input: func(c *Config) {
@@ -867,6 +939,183 @@ func TestNewAgentConfig(t *testing.T) {
 				require.Empty(t, c.AllowedForeignJWTClaims)
 			},
 		},
+		{
+			msg: "allowed_jwt_audience_wildcards provided",
+			input: func(c *Config) {
+				c.Agent.AllowedJWTAudienceWildcards = []string{"spiffe://example.org/gateway/*"}
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.Equal(t, []string{"spiffe://example.org/gateway/*"}, c.AllowedJWTAudienceWildcards)
+			},
+		},
+		{
+			msg:         "allowed_jwt_audience_wildcards without trailing wildcard",
+			expectError: true,
+			input: func(c *Config) {
+				c.Agent.AllowedJWTAudienceWildcards = []string{"spiffe://example.org/gateway/"}
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.Nil(t, c)
+			},
+		},
+		{
+			msg:         "allowed_jwt_audience_wildcards without path boundary",
+			expectError: true,
+			input: func(c *Config) {
+				c.Agent.AllowedJWTAudienceWildcards = []string{"spiffe://example.org/gateway*"}
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.Nil(t, c)
+			},
+		},
+		{
+			msg:         "allowed_jwt_audience_wildcards with empty prefix",
+			expectError: true,
+			input: func(c *Config) {
+				c.Agent.AllowedJWTAudienceWildcards = []string{"*"}
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.Nil(t, c)
+			},
+		},
+		{
+			msg:         "allowed_jwt_audience_wildcards with inner wildcard",
+			expectError: true,
+			input: func(c *Config) {
+				c.Agent.AllowedJWTAudienceWildcards = []string{"spiffe://*/gateway/*"}
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.Nil(t, c)
+			},
+		},
+		{
+			msg: "jwt_svid_validation_leeway not provided",
+			input: func(c *Config) {
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.Equal(t, time.Minute, c.JWTSVIDValidationLeeway)
+			},
+		},
+		{
+			msg: "jwt_svid_validation_leeway provided",
+			input: func(c *Config) {
+				c.Agent.JWTSVIDValidationLeeway = "30s"
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.Equal(t, 30*time.Second, c.JWTSVIDValidationLeeway)
+			},
+		},
+		{
+			msg: "jwt_svid_validation_leeway disabled",
+			input: func(c *Config) {
+				c.Agent.JWTSVIDValidationLeeway = "0s"
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.Zero(t, c.JWTSVIDValidationLeeway)
+			},
+		},
+		{
+			msg:         "jwt_svid_validation_leeway is invalid",
+			expectError: true,
+			input: func(c *Config) {
+				c.Agent.JWTSVIDValidationLeeway = "soon"
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.Nil(t, c)
+			},
+		},
+		{
+			msg:         "jwt_svid_validation_leeway cannot be negative",
+			expectError: true,
+			input: func(c *Config) {
+				c.Agent.JWTSVIDValidationLeeway = "-1s"
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.Nil(t, c)
+			},
+		},
+		{
+			msg: "workload_api rate limit provided",
+			input: func(c *Config) {
+				c.Agent.WorkloadAPI = &workloadAPIConfig{
+					MaxRequestsPerSecond: 10,
+					Burst:                20,
+				}
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.Equal(t, 10, c.WorkloadAPIMaxRequestsPerSecond)
+				require.Equal(t, 20, c.WorkloadAPIBurst)
+			},
+		},
+		{
+			msg: "workload_api rate limit not provided",
+			input: func(c *Config) {
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.Zero(t, c.WorkloadAPIMaxRequestsPerSecond)
+				require.Zero(t, c.WorkloadAPIBurst)
+				require.Zero(t, c.WorkloadAPIShutdownGracePeriod)
+			},
+		},
+		{
+			msg: "workload_api shutdown grace period provided",
+			input: func(c *Config) {
+				c.Agent.WorkloadAPI = &workloadAPIConfig{
+					ShutdownGracePeriod: "10s",
+				}
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.Equal(t, 10*time.Second, c.WorkloadAPIShutdownGracePeriod)
+			},
+		},
+		{
+			msg:         "workload_api shutdown grace period is invalid",
+			expectError: true,
+			input: func(c *Config) {
+				c.Agent.WorkloadAPI = &workloadAPIConfig{
+					ShutdownGracePeriod: "soon",
+				}
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.Nil(t, c)
+			},
+		},
+		{
+			msg:         "workload_api shutdown grace period cannot be negative",
+			expectError: true,
+			input: func(c *Config) {
+				c.Agent.WorkloadAPI = &workloadAPIConfig{
+					ShutdownGracePeriod: "-1s",
+				}
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.Nil(t, c)
+			},
+		},
+		{
+			msg:         "workload_api.max_requests_per_second cannot be negative",
+			expectError: true,
+			input: func(c *Config) {
+				c.Agent.WorkloadAPI = &workloadAPIConfig{
+					MaxRequestsPerSecond: -1,
+				}
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.Nil(t, c)
+			},
+		},
+		{
+			msg:         "workload_api.burst requires max_requests_per_second",
+			expectError: true,
+			input: func(c *Config) {
+				c.Agent.WorkloadAPI = &workloadAPIConfig{
+					Burst: 5,
+				}
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.Nil(t, c)
+			},
+		},
 		{
 			msg:         "admin_socket_path same folder as socket_path",
 			expectError: true,
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"google.golang.org/grpc/codes"
@@ -242,6 +244,8 @@ func TestListHelp(t *testing.T) {
 	require.Equal(t, `Usage of agent list:
   -matchSelectorsOn string
     	The match mode used when filtering by selectors. Options: exact, any, superset and subset (default "superset")
+  -output string
+    	Output format. Options: pretty and json (default "pretty")
   -selector value
     	A colon-delimited type:value selector. Can be used more than once
   -socketPath string
@@ -390,6 +394,22 @@ func TestList(t *testing.T) {
 			expectedReturnCode: 1,
 			expectedStderr:     "Error: error parsing selector \"invalid-selector\": selector \"invalid-selector\" must be formatted as type:value\n",
 		},
+		{
+			name:               "no agents with JSON output",
+			args:               []string{"-output", "json"},
+			expectedReturnCode: 0,
+			expectedStdout:     "{\n  \"agents\": [],\n  \"next_page_token\": \"\"\n}\n",
+			expectReq: &agentv1.ListAgentsRequest{
+				Filter:   &agentv1.ListAgentsRequest_Filter{},
+				PageSize: 1000,
+			},
+		},
+		{
+			name:               "invalid output format",
+			args:               []string{"-output", "yaml"},
+			expectedReturnCode: 1,
+			expectedStderr:     "Error: invalid output format \"yaml\": must be \"pretty\" or \"json\"\n",
+		},
 		{
 			name:               "wrong UDS path",
 			args:               []string{"-socketPath", "does-not-exist.sock"},
@@ -412,6 +432,27 @@ func TestList(t *testing.T) {
 	}
 }
 
+func TestListJSON(t *testing.T) {
+	test := setupTest(t, agent.NewListCommandWithEnv)
+	test.server.agents = []*types.Agent{
+		{
+			Id:                   &types.SPIFFEID{TrustDomain: "example.org", Path: "/spire/agent/agent1"},
+			AttestationType:      "join_token",
+			X509SvidSerialNumber: "1",
+			X509SvidExpiresAt:    1552410266,
+		},
+		testAgentsWithBanned[0],
+		testAgentsWithSelectors[0],
+	}
+
+	returnCode := test.client.Run(append(test.args, "-output", "json"))
+	require.Equal(t, 0, returnCode)
+
+	expected, err := os.ReadFile(filepath.Join("testdata", "list.golden.json"))
+	require.NoError(t, err)
+	require.Equal(t, string(expected), test.stdout.String())
+}
+
 func TestShowHelp(t *testing.T) {
 	test := setupTest(t, agent.NewShowCommandWithEnv)
 
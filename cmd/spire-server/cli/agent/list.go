@@ -24,6 +24,9 @@ type listCommand struct {
 
 	// Match used when filtering agents by selectors
 	matchSelectorsOn string
+
+	// Output format, either "pretty" or "json"
+	output string
 }
 
 // NewListCommand creates a new "list" subcommand for "agent" command.
@@ -47,6 +50,10 @@ func (listCommand) Synopsis() string {
 
 // Run lists attested agents
 func (c *listCommand) Run(ctx context.Context, env *common_cli.Env, serverClient util.ServerClient) error {
+	if err := util.ValidateOutputFormat(c.output); err != nil {
+		return err
+	}
+
 	filter := &agentv1.ListAgentsRequest_Filter{}
 	if len(c.selectors) > 0 {
 		matchBehavior, err := parseToSelectorMatch(c.matchSelectorsOn)
@@ -87,6 +94,10 @@ func (c *listCommand) Run(ctx context.Context, env *common_cli.Env, serverClient
 		}
 	}
 
+	if c.output == util.OutputJSON {
+		return util.PrintProtoJSON(env.Stdout, &agentv1.ListAgentsResponse{Agents: agents})
+	}
+
 	if len(agents) == 0 {
 		return env.Printf("No attested agents found\n")
 	}
@@ -101,6 +112,7 @@ func (c *listCommand) Run(ctx context.Context, env *common_cli.Env, serverClient
 func (c *listCommand) AppendFlags(fs *flag.FlagSet) {
 	fs.StringVar(&c.matchSelectorsOn, "matchSelectorsOn", "superset", "The match mode used when filtering by selectors. Options: exact, any, superset and subset")
 	fs.Var(&c.selectors, "selector", "A colon-delimited type:value selector. Can be used more than once")
+	fs.StringVar(&c.output, "output", util.OutputPretty, util.OutputFlagUsage)
 }
 
 func printAgents(env *common_cli.Env, agents ...*types.Agent) error {
@@ -0,0 +1,176 @@
+package agent
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/andres-erbsen/clock"
+	"github.com/mitchellh/cli"
+
+	agentv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/agent/v1"
+	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
+	"github.com/spiffe/spire/cmd/spire-server/util"
+	common_cli "github.com/spiffe/spire/pkg/common/cli"
+	"github.com/spiffe/spire/pkg/common/idutil"
+
+	"golang.org/x/net/context"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type purgeCommand struct {
+	clk clock.Clock
+
+	// Purge agents whose SVID expired at least this long ago
+	expiredFor time.Duration
+
+	// Only purge banned agents
+	banned bool
+
+	// Report the agents that would be purged without deleting them
+	dryRun bool
+}
+
+// NewPurgeCommand creates a new "purge" subcommand for "agent" command.
+func NewPurgeCommand() cli.Command {
+	return NewPurgeCommandWithEnv(common_cli.DefaultEnv)
+}
+
+// NewPurgeCommandWithEnv creates a new "purge" subcommand for "agent" command
+// using the environment specified
+func NewPurgeCommandWithEnv(env *common_cli.Env) cli.Command {
+	return newPurgeCommand(env, clock.New())
+}
+
+func newPurgeCommand(env *common_cli.Env, clk clock.Clock) cli.Command {
+	return util.AdaptCommand(env, &purgeCommand{clk: clk})
+}
+
+func (*purgeCommand) Name() string {
+	return "agent purge"
+}
+
+func (purgeCommand) Synopsis() string {
+	return "Deletes long-expired agents in bulk"
+}
+
+// Run purges the agents matching all of the given filters. Agents that are
+// not expired are never purged. Banned agents are only purged once expired
+// since deleting an agent lifts its ban, letting node attestors that trust on
+// first use attest it again.
+func (c *purgeCommand) Run(ctx context.Context, env *common_cli.Env, serverClient util.ServerClient) error {
+	switch {
+	case c.expiredFor < 0:
+		return errors.New("expiredFor must not be negative")
+	case c.expiredFor == 0 && c.banned:
+		return errors.New("banned requires expiredFor, since purging a banned agent lifts its ban")
+	case c.expiredFor == 0:
+		return errors.New("expiredFor is required")
+	}
+
+	filter := &agentv1.ListAgentsRequest_Filter{}
+	if c.banned {
+		filter.ByBanned = wrapperspb.Bool(true)
+	}
+
+	agentClient := serverClient.NewAgentClient()
+
+	pageToken := ""
+	var agents []*types.Agent
+	for {
+		listResponse, err := agentClient.ListAgents(ctx, &agentv1.ListAgentsRequest{
+			PageSize:   1000, // comfortably under the (4 MB/theoretical maximum size of 1 agent in MB)
+			PageToken:  pageToken,
+			Filter:     filter,
+			OutputMask: &types.AgentMask{X509SvidExpiresAt: true, Banned: true},
+		})
+		if err != nil {
+			return err
+		}
+		for _, agent := range listResponse.Agents {
+			if c.shouldPurge(agent) {
+				agents = append(agents, agent)
+			}
+		}
+		if pageToken = listResponse.NextPageToken; pageToken == "" {
+			break
+		}
+	}
+
+	if len(agents) == 0 {
+		return env.Println("No agents to purge")
+	}
+
+	ids := make([]string, 0, len(agents))
+	for _, agent := range agents {
+		id, err := idutil.IDProtoString(agent.Id)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+
+	if c.dryRun {
+		msg := fmt.Sprintf("Found %d ", len(agents))
+		msg = util.Pluralizer(msg, "agent", "agents", len(agents))
+		if err := env.Printf("%s to purge:\n", msg); err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := env.Println(id); err != nil {
+				return err
+			}
+		}
+		for _, agent := range agents {
+			if agent.Banned {
+				return env.Println("Purging banned agents lifts their bans, so they can attest again")
+			}
+		}
+		return nil
+	}
+
+	var purged, failed int
+	for i, agent := range agents {
+		if _, err := agentClient.DeleteAgent(ctx, &agentv1.DeleteAgentRequest{Id: agent.Id}); err != nil {
+			failed++
+			env.ErrPrintf("Failed to purge agent %q: %v\n", ids[i], err)
+			continue
+		}
+		purged++
+	}
+
+	msg := fmt.Sprintf("Purged %d ", purged)
+	msg = util.Pluralizer(msg, "agent", "agents", purged)
+	if err := env.Println(msg); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to purge %d of %d agents", failed, len(agents))
+	}
+	return nil
+}
+
+func (c *purgeCommand) AppendFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&c.expiredFor, "expiredFor", 0, "Purge agents whose SVID expired at least this long ago")
+	fs.BoolVar(&c.banned, "banned", false, "Only purge banned agents. Purging an agent lifts its ban. Requires expiredFor")
+	fs.BoolVar(&c.dryRun, "dryRun", false, "Report the agents that would be purged without deleting them")
+}
+
+func (c *purgeCommand) shouldPurge(agent *types.Agent) bool {
+	if c.banned && !agent.Banned {
+		return false
+	}
+	if c.expiredFor > 0 {
+		// Agents without a known expiration are kept since they cannot be
+		// proven to be expired.
+		if agent.X509SvidExpiresAt == 0 {
+			return false
+		}
+		expiredAt := time.Unix(agent.X509SvidExpiresAt, 0)
+		if c.clk.Now().Sub(expiredAt) < c.expiredFor {
+			return false
+		}
+	}
+	return true
+}
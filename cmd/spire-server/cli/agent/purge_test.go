@@ -0,0 +1,201 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	agentv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/agent/v1"
+	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
+	common_cli "github.com/spiffe/spire/pkg/common/cli"
+	"github.com/spiffe/spire/test/clock"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestPurgeHelp(t *testing.T) {
+	stderr := new(bytes.Buffer)
+	cmd := newPurgeCommand(&common_cli.Env{Stderr: stderr}, clock.NewMock(t))
+
+	cmd.Help()
+	require.Equal(t, `Usage of agent purge:
+  -banned
+    	Only purge banned agents. Purging an agent lifts its ban. Requires expiredFor
+  -dryRun
+    	Report the agents that would be purged without deleting them
+  -expiredFor duration
+    	Purge agents whose SVID expired at least this long ago
+  -socketPath string
+    	Path to the SPIRE Server API socket (default "/tmp/spire-server/private/api.sock")
+`, stderr.String())
+}
+
+func TestPurge(t *testing.T) {
+	clk := clock.NewMock(t)
+	now := clk.Now()
+
+	newAgent := func(name string, expiresAt time.Time, banned bool) *types.Agent {
+		return &types.Agent{
+			Id:                &types.SPIFFEID{TrustDomain: "example.org", Path: "/spire/agent/" + name},
+			X509SvidExpiresAt: expiresAt.Unix(),
+			Banned:            banned,
+		}
+	}
+
+	agents := []*types.Agent{
+		// Currently attested agent
+		newAgent("active", now.Add(time.Hour), false),
+		// Agent that expired recently
+		newAgent("expired-recently", now.Add(-time.Hour), false),
+		// Agent that expired long ago
+		newAgent("expired-long-ago", now.Add(-30*24*time.Hour), false),
+		// Banned agents, with and without an unexpired SVID
+		newAgent("banned", now.Add(time.Hour), true),
+		newAgent("banned-expired-long-ago", now.Add(-30*24*time.Hour), true),
+	}
+
+	for _, tt := range []struct {
+		name               string
+		args               []string
+		deleteErr          map[string]error
+		expectReturnCode   int
+		expectStdout       string
+		expectStderr       string
+		expectDeletedPaths []string
+	}{
+		{
+			name:             "no filters",
+			expectReturnCode: 1,
+			expectStderr:     "Error: expiredFor is required\n",
+		},
+		{
+			name:             "banned without expiredFor",
+			args:             []string{"-banned"},
+			expectReturnCode: 1,
+			expectStderr:     "Error: banned requires expiredFor, since purging a banned agent lifts its ban\n",
+		},
+		{
+			name:             "negative expiredFor",
+			args:             []string{"-expiredFor", "-1h"},
+			expectReturnCode: 1,
+			expectStderr:     "Error: expiredFor must not be negative\n",
+		},
+		{
+			name:               "expiredFor",
+			args:               []string{"-expiredFor", "168h"},
+			expectStdout:       "Purged 2 agents\n",
+			expectDeletedPaths: []string{"/spire/agent/expired-long-ago", "/spire/agent/banned-expired-long-ago"},
+		},
+		{
+			name:               "banned and expiredFor",
+			args:               []string{"-banned", "-expiredFor", "168h"},
+			expectStdout:       "Purged 1 agent\n",
+			expectDeletedPaths: []string{"/spire/agent/banned-expired-long-ago"},
+		},
+		{
+			name:               "expiredFor includes recently expired agents",
+			args:               []string{"-expiredFor", "1m"},
+			expectStdout:       "Purged 3 agents\n",
+			expectDeletedPaths: []string{"/spire/agent/expired-recently", "/spire/agent/expired-long-ago", "/spire/agent/banned-expired-long-ago"},
+		},
+		{
+			name:         "nothing to purge",
+			args:         []string{"-expiredFor", "8760h"},
+			expectStdout: "No agents to purge\n",
+		},
+		{
+			name: "dry run",
+			args: []string{"-expiredFor", "168h", "-dryRun"},
+			expectStdout: `Found 2 agents to purge:
+spiffe://example.org/spire/agent/expired-long-ago
+spiffe://example.org/spire/agent/banned-expired-long-ago
+Purging banned agents lifts their bans, so they can attest again
+`,
+		},
+		{
+			name: "delete failure",
+			args: []string{"-expiredFor", "168h"},
+			deleteErr: map[string]error{
+				"/spire/agent/expired-long-ago": status.Error(codes.Internal, "oh no"),
+			},
+			expectReturnCode: 1,
+			expectStdout:     "Purged 1 agent\n",
+			expectStderr: `Failed to purge agent "spiffe://example.org/spire/agent/expired-long-ago": rpc error: code = Internal desc = oh no
+Error: failed to purge 1 of 2 agents
+`,
+			expectDeletedPaths: []string{"/spire/agent/banned-expired-long-ago"},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			server := &fakePurgeAgentServer{
+				agents:    agents,
+				deleteErr: tt.deleteErr,
+			}
+			socketPath := spiretest.StartGRPCSocketServerOnTempSocket(t, func(s *grpc.Server) {
+				agentv1.RegisterAgentServer(s, server)
+			})
+
+			stdout := new(bytes.Buffer)
+			stderr := new(bytes.Buffer)
+			cmd := newPurgeCommand(&common_cli.Env{
+				Stdin:  new(bytes.Buffer),
+				Stdout: stdout,
+				Stderr: stderr,
+			}, clk)
+
+			returnCode := cmd.Run(append([]string{"-socketPath", socketPath}, tt.args...))
+			require.Equal(t, tt.expectStdout, stdout.String())
+			require.Equal(t, tt.expectStderr, stderr.String())
+			require.Equal(t, tt.expectReturnCode, returnCode)
+			require.Equal(t, tt.expectDeletedPaths, server.deletedPaths)
+		})
+	}
+}
+
+type fakePurgeAgentServer struct {
+	agentv1.UnimplementedAgentServer
+
+	agents       []*types.Agent
+	deleteErr    map[string]error
+	deletedPaths []string
+}
+
+func (s *fakePurgeAgentServer) ListAgents(ctx context.Context, req *agentv1.ListAgentsRequest) (*agentv1.ListAgentsResponse, error) {
+	// Serve one agent per page to exercise paging
+	start := 0
+	if req.PageToken != "" {
+		for i, agent := range s.agents {
+			if agent.Id.Path == req.PageToken {
+				start = i
+			}
+		}
+	}
+
+	resp := &agentv1.ListAgentsResponse{}
+	for i := start; i < len(s.agents); i++ {
+		agent := s.agents[i]
+		if req.Filter.GetByBanned() != nil && req.Filter.ByBanned.Value != agent.Banned {
+			continue
+		}
+		if len(resp.Agents) == 1 {
+			resp.NextPageToken = agent.Id.Path
+			break
+		}
+		resp.Agents = append(resp.Agents, agent)
+	}
+	return resp, nil
+}
+
+func (s *fakePurgeAgentServer) DeleteAgent(ctx context.Context, req *agentv1.DeleteAgentRequest) (*emptypb.Empty, error) {
+	if err := s.deleteErr[req.Id.Path]; err != nil {
+		return nil, err
+	}
+	s.deletedPaths = append(s.deletedPaths, req.Id.Path)
+	return &emptypb.Empty{}, nil
+}
@@ -97,6 +97,8 @@ func TestSetHelp(t *testing.T) {
     	The format of the bundle data. Either "pem" or "spiffe". (default "pem")
   -id string
     	SPIFFE ID of the trust domain
+  -merge
+    	Add the X.509 authorities and JWT keys to the stored bundle instead of replacing it
   -path string
     	Path to the bundle data
   -socketPath string
@@ -116,6 +118,23 @@ func TestSet(t *testing.T) {
 	key1Pkix, err := x509.MarshalPKIXPublicKey(cert1.PublicKey)
 	require.NoError(t, err)
 
+	cert2, err := pemutil.ParseCertificate([]byte(cert2PEM))
+	require.NoError(t, err)
+
+	key2Pkix, err := x509.MarshalPKIXPublicKey(cert2.PublicKey)
+	require.NoError(t, err)
+
+	okSetResponse := &bundlev1.BatchSetFederatedBundleResponse{
+		Results: []*bundlev1.BatchSetFederatedBundleResponse_Result{
+			{
+				Status: &types.Status{Code: int32(codes.OK)},
+				Bundle: &types.Bundle{
+					TrustDomain: "spiffe://otherdomain.test",
+				},
+			},
+		},
+	}
+
 	for _, tt := range []struct {
 		name           string
 		args           []string
@@ -123,6 +142,7 @@ func TestSet(t *testing.T) {
 		stdin          string
 		fileData       string
 		serverErr      error
+		stored         []*types.Bundle
 		toSet          *types.Bundle
 		setResponse    *bundlev1.BatchSetFederatedBundleResponse
 	}{
@@ -256,6 +276,79 @@ func TestSet(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:  "merge adds new authorities",
+			stdin: otherDomainJWKS,
+			args:  []string{"-id", "spiffe://otherdomain.test", "-format", util.FormatSPIFFE, "-merge"},
+			stored: []*types.Bundle{
+				{
+					TrustDomain:     "otherdomain.test",
+					X509Authorities: []*types.X509Certificate{{Asn1: cert2.Raw}},
+					JwtAuthorities:  []*types.JWTKey{{KeyId: "KID2", PublicKey: key2Pkix}},
+					SequenceNumber:  3,
+				},
+			},
+			toSet: &types.Bundle{
+				TrustDomain: "otherdomain.test",
+				X509Authorities: []*types.X509Certificate{
+					{Asn1: cert2.Raw},
+					{Asn1: cert1.Raw},
+				},
+				JwtAuthorities: []*types.JWTKey{
+					{KeyId: "KID2", PublicKey: key2Pkix},
+					{KeyId: "KID", PublicKey: key1Pkix},
+				},
+				SequenceNumber: 3,
+			},
+			setResponse: okSetResponse,
+		},
+		{
+			name:  "merge with nothing new",
+			stdin: otherDomainJWKS,
+			args:  []string{"-id", "spiffe://otherdomain.test", "-format", util.FormatSPIFFE, "-merge"},
+			stored: []*types.Bundle{
+				{
+					TrustDomain:     "otherdomain.test",
+					X509Authorities: []*types.X509Certificate{{Asn1: cert1.Raw}},
+					JwtAuthorities:  []*types.JWTKey{{KeyId: "KID", PublicKey: key1Pkix}},
+				},
+			},
+			toSet: &types.Bundle{
+				TrustDomain:     "otherdomain.test",
+				X509Authorities: []*types.X509Certificate{{Asn1: cert1.Raw}},
+				JwtAuthorities:  []*types.JWTKey{{KeyId: "KID", PublicKey: key1Pkix}},
+			},
+			setResponse: okSetResponse,
+		},
+		{
+			name:  "merge without stored bundle",
+			stdin: cert1PEM,
+			args:  []string{"-id", "spiffe://otherdomain.test", "-merge"},
+			toSet: &types.Bundle{
+				TrustDomain:     "spiffe://otherdomain.test",
+				X509Authorities: []*types.X509Certificate{{Asn1: cert1.Raw}},
+			},
+			setResponse: okSetResponse,
+		},
+		{
+			name:  "merge with conflicting JWT key",
+			stdin: otherDomainJWKS,
+			args:  []string{"-id", "spiffe://otherdomain.test", "-format", util.FormatSPIFFE, "-merge"},
+			stored: []*types.Bundle{
+				{
+					TrustDomain:    "otherdomain.test",
+					JwtAuthorities: []*types.JWTKey{{KeyId: "KID", PublicKey: key2Pkix}},
+				},
+			},
+			expectedStderr: "Error: JWT key \"KID\" conflicts with the stored key with the same key ID; set the bundle without -merge to replace it\n",
+		},
+		{
+			name:           "merge fails to get stored bundle",
+			stdin:          cert1PEM,
+			args:           []string{"-id", "spiffe://otherdomain.test", "-merge"},
+			serverErr:      status.New(codes.Internal, "some error").Err(),
+			expectedStderr: "Error: failed to get federated bundle: rpc error: code = Internal desc = some error\n",
+		},
 		{
 			name:           "invalid file name",
 			expectedStderr: fmt.Sprintf("Error: unable to load bundle data: open /not/a/real/path/to/a/bundle: %s\n", spiretest.PathNotFound()),
@@ -343,6 +436,7 @@ func TestSet(t *testing.T) {
 			test.server.expectedSetBundle = tt.toSet
 			test.server.setResponse = tt.setResponse
 			test.server.err = tt.serverErr
+			test.server.bundles = tt.stored
 
 			test.stdin.WriteString(tt.stdin)
 			var extraArgs []string
@@ -466,6 +560,8 @@ func TestListHelp(t *testing.T) {
     	The format to list federated bundles. Either "pem" or "spiffe". (default "pem")
   -id string
     	SPIFFE ID of the trust domain
+  -output string
+    	Output format. Options: pretty and json. The -format flag only applies to the pretty output (default "pretty")
   -socketPath string
     	Path to the SPIRE Server API socket (default "/tmp/spire-server/private/api.sock")
 `, test.stderr.String())
@@ -534,6 +630,21 @@ func TestList(t *testing.T) {
 			args:           []string{"-id", "spiffe://domain2.test", "-format", util.FormatSPIFFE},
 			expectedStdout: cert2JWKS,
 		},
+		{
+			name:           "all bundles (json output)",
+			args:           []string{"-output", util.OutputJSON},
+			expectedStdout: readGolden(t, "list.golden.json"),
+		},
+		{
+			name:           "one bundle (json output)",
+			args:           []string{"-id", "spiffe://domain2.test", "-output", util.OutputJSON},
+			expectedStdout: readGolden(t, "list_one.golden.json"),
+		},
+		{
+			name:           "invalid output format",
+			args:           []string{"-output", "yaml"},
+			expectedStderr: "Error: invalid output format \"yaml\": must be \"pretty\" or \"json\"\n",
+		},
 	} {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
@@ -571,6 +682,12 @@ func TestList(t *testing.T) {
 	}
 }
 
+func readGolden(t *testing.T, name string) string {
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return string(data)
+}
+
 func TestDeleteHelp(t *testing.T) {
 	test := setupTest(t, newDeleteCommand)
 	test.client.Help()
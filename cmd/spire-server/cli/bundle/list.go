@@ -7,6 +7,7 @@ import (
 
 	"github.com/mitchellh/cli"
 	bundlev1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/bundle/v1"
+	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
 	"github.com/spiffe/spire/cmd/spire-server/util"
 	common_cli "github.com/spiffe/spire/pkg/common/cli"
 )
@@ -23,6 +24,7 @@ func newListCommand(env *common_cli.Env) cli.Command {
 type listCommand struct {
 	id     string // SPIFFE ID of the trust bundle
 	format string
+	output string
 }
 
 func (c *listCommand) Name() string {
@@ -36,9 +38,14 @@ func (c *listCommand) Synopsis() string {
 func (c *listCommand) AppendFlags(fs *flag.FlagSet) {
 	fs.StringVar(&c.id, "id", "", "SPIFFE ID of the trust domain")
 	fs.StringVar(&c.format, "format", util.FormatPEM, fmt.Sprintf("The format to list federated bundles. Either %q or %q.", util.FormatPEM, util.FormatSPIFFE))
+	fs.StringVar(&c.output, "output", util.OutputPretty, util.OutputFlagUsage+". The -format flag only applies to the pretty output")
 }
 
 func (c *listCommand) Run(ctx context.Context, env *common_cli.Env, serverClient util.ServerClient) error {
+	if err := util.ValidateOutputFormat(c.output); err != nil {
+		return err
+	}
+
 	bundleClient := serverClient.NewBundleClient()
 	if c.id != "" {
 		resp, err := bundleClient.GetFederatedBundle(ctx, &bundlev1.GetFederatedBundleRequest{
@@ -47,6 +54,12 @@ func (c *listCommand) Run(ctx context.Context, env *common_cli.Env, serverClient
 		if err != nil {
 			return err
 		}
+		if c.output == util.OutputJSON {
+			// Use the list response so the schema does not depend on -id
+			return util.PrintProtoJSON(env.Stdout, &bundlev1.ListFederatedBundlesResponse{
+				Bundles: []*types.Bundle{resp},
+			})
+		}
 		return printBundleWithFormat(env.Stdout, resp, c.format, false)
 	}
 
@@ -55,6 +68,10 @@ func (c *listCommand) Run(ctx context.Context, env *common_cli.Env, serverClient
 		return err
 	}
 
+	if c.output == util.OutputJSON {
+		return util.PrintProtoJSON(env.Stdout, resp)
+	}
+
 	for i, b := range resp.Bundles {
 		if i != 0 {
 			if err := env.Println(); err != nil {
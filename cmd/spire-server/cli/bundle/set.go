@@ -1,17 +1,21 @@
 package bundle
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"flag"
 	"fmt"
 
 	"github.com/mitchellh/cli"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
 	bundlev1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/bundle/v1"
 	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
 	"github.com/spiffe/spire/cmd/spire-server/util"
 	common_cli "github.com/spiffe/spire/pkg/common/cli"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 // NewSetCommand creates a new "set" subcommand for "bundle" command.
@@ -31,6 +35,9 @@ type setCommand struct {
 	path string
 
 	format string
+
+	// Merge the bundle into the stored one instead of replacing it
+	merge bool
 }
 
 func (c *setCommand) Name() string {
@@ -45,6 +52,7 @@ func (c *setCommand) AppendFlags(fs *flag.FlagSet) {
 	fs.StringVar(&c.id, "id", "", "SPIFFE ID of the trust domain")
 	fs.StringVar(&c.path, "path", "", "Path to the bundle data")
 	fs.StringVar(&c.format, "format", util.FormatPEM, fmt.Sprintf("The format of the bundle data. Either %q or %q.", util.FormatPEM, util.FormatSPIFFE))
+	fs.BoolVar(&c.merge, "merge", false, "Add the X.509 authorities and JWT keys to the stored bundle instead of replacing it")
 }
 
 func (c *setCommand) Run(ctx context.Context, env *common_cli.Env, serverClient util.ServerClient) error {
@@ -68,6 +76,27 @@ func (c *setCommand) Run(ctx context.Context, env *common_cli.Env, serverClient
 	}
 
 	bundleClient := serverClient.NewBundleClient()
+	if c.merge {
+		td, err := spiffeid.TrustDomainFromString(c.id)
+		if err != nil {
+			return err
+		}
+		existing, err := bundleClient.GetFederatedBundle(ctx, &bundlev1.GetFederatedBundleRequest{
+			TrustDomain: td.String(),
+		})
+		switch status.Code(err) {
+		case codes.OK:
+			bundle, err = mergeBundles(existing, bundle)
+			if err != nil {
+				return err
+			}
+		case codes.NotFound:
+			// Nothing to merge with; the bundle is created
+		default:
+			return fmt.Errorf("failed to get federated bundle: %w", err)
+		}
+	}
+
 	resp, err := bundleClient.BatchSetFederatedBundle(ctx, &bundlev1.BatchSetFederatedBundleRequest{
 		Bundle: []*types.Bundle{bundle},
 	})
@@ -84,3 +113,51 @@ func (c *setCommand) Run(ctx context.Context, env *common_cli.Env, serverClient
 		return fmt.Errorf("failed to set federated bundle: %s", result.Status.Message)
 	}
 }
+
+// mergeBundles adds the X.509 authorities and JWT keys of the incoming bundle
+// that are not in the existing one to a copy of the existing bundle. Nothing
+// is removed. A JWT key that has the same key ID as an existing key but a
+// different public key is a conflict, since it can't be added without
+// replacing the existing key.
+func mergeBundles(existing, incoming *types.Bundle) (*types.Bundle, error) {
+	merged := proto.Clone(existing).(*types.Bundle)
+
+	for _, x509Authority := range incoming.X509Authorities {
+		if !hasX509Authority(merged.X509Authorities, x509Authority) {
+			merged.X509Authorities = append(merged.X509Authorities, x509Authority)
+		}
+	}
+
+	for _, jwtAuthority := range incoming.JwtAuthorities {
+		existingKey := findJWTAuthority(merged.JwtAuthorities, jwtAuthority.KeyId)
+		switch {
+		case existingKey == nil:
+			merged.JwtAuthorities = append(merged.JwtAuthorities, jwtAuthority)
+		case !bytes.Equal(existingKey.PublicKey, jwtAuthority.PublicKey):
+			return nil, fmt.Errorf("JWT key %q conflicts with the stored key with the same key ID; set the bundle without -merge to replace it", jwtAuthority.KeyId)
+		}
+	}
+
+	if incoming.RefreshHint != 0 {
+		merged.RefreshHint = incoming.RefreshHint
+	}
+	return merged, nil
+}
+
+func hasX509Authority(x509Authorities []*types.X509Certificate, x509Authority *types.X509Certificate) bool {
+	for _, existing := range x509Authorities {
+		if bytes.Equal(existing.Asn1, x509Authority.Asn1) {
+			return true
+		}
+	}
+	return false
+}
+
+func findJWTAuthority(jwtAuthorities []*types.JWTKey, keyID string) *types.JWTKey {
+	for _, jwtAuthority := range jwtAuthorities {
+		if jwtAuthority.KeyId == keyID {
+			return jwtAuthority
+		}
+	}
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/spiffe/spire/cmd/spire-server/cli/federation"
 	"github.com/spiffe/spire/cmd/spire-server/cli/healthcheck"
 	"github.com/spiffe/spire/cmd/spire-server/cli/jwt"
+	"github.com/spiffe/spire/cmd/spire-server/cli/migrate"
 	"github.com/spiffe/spire/cmd/spire-server/cli/run"
 	"github.com/spiffe/spire/cmd/spire-server/cli/token"
 	"github.com/spiffe/spire/cmd/spire-server/cli/validate"
@@ -41,6 +42,9 @@ func (cc *CLI) Run(args []string) int {
 		"agent list": func() (cli.Command, error) {
 			return agent.NewListCommand(), nil
 		},
+		"agent purge": func() (cli.Command, error) {
+			return agent.NewPurgeCommand(), nil
+		},
 		"agent show": func() (cli.Command, error) {
 			return agent.NewShowCommand(), nil
 		},
@@ -74,6 +78,12 @@ func (cc *CLI) Run(args []string) int {
 		"entry show": func() (cli.Command, error) {
 			return entry.NewShowCommand(), nil
 		},
+		"entry export": func() (cli.Command, error) {
+			return entry.NewExportCommand(), nil
+		},
+		"entry import": func() (cli.Command, error) {
+			return entry.NewImportCommand(), nil
+		},
 		"federation create": func() (cli.Command, error) {
 			return federation.NewCreateCommand(), nil
 		},
@@ -92,6 +102,9 @@ func (cc *CLI) Run(args []string) int {
 		"federation update": func() (cli.Command, error) {
 			return federation.NewUpdateCommand(), nil
 		},
+		"migrate": func() (cli.Command, error) {
+			return migrate.NewMigrateCommand(), nil
+		},
 		"run": func() (cli.Command, error) {
 			return run.NewRunCommand(cc.LogOptions, cc.AllowUnknownConfig), nil
 		},
@@ -1,19 +1,30 @@
 package entry
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/mitchellh/cli"
 
 	entryv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/entry/v1"
+	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
 	"github.com/spiffe/spire/cmd/spire-server/util"
 	common_cli "github.com/spiffe/spire/pkg/common/cli"
 
 	"golang.org/x/net/context"
 )
 
-type countCommand struct{}
+type countCommand struct {
+	// Whether or not to group the entries by selector type
+	bySelector bool
+
+	// Output format, either "pretty" or "json"
+	output string
+}
 
 // NewCountCommand creates a new "count" subcommand for "entry" command.
 func NewCountCommand() cli.Command {
@@ -36,13 +47,27 @@ func (countCommand) Synopsis() string {
 
 // Run counts attested entries
 func (c *countCommand) Run(ctx context.Context, env *common_cli.Env, serverClient util.ServerClient) error {
+	if err := util.ValidateOutputFormat(c.output); err != nil {
+		return err
+	}
+
 	entryClient := serverClient.NewEntryClient()
+	if c.bySelector {
+		return c.countBySelector(ctx, env, entryClient)
+	}
+
 	countResponse, err := entryClient.CountEntries(ctx, &entryv1.CountEntriesRequest{})
 	if err != nil {
 		return err
 	}
 
 	count := int(countResponse.Count)
+	if c.output == util.OutputJSON {
+		return printJSON(env, struct {
+			Count int `json:"count"`
+		}{Count: count})
+	}
+
 	msg := fmt.Sprintf("%d registration ", count)
 	msg = util.Pluralizer(msg, "entry", "entries", count)
 	env.Println(msg)
@@ -51,4 +76,87 @@ func (c *countCommand) Run(ctx context.Context, env *common_cli.Env, serverClien
 }
 
 func (c *countCommand) AppendFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.bySelector, "bySelector", false, "Count the registration entries grouped by selector type (e.g. k8s:ns, unix:uid)")
+	fs.StringVar(&c.output, "output", util.OutputPretty, util.OutputFlagUsage)
+}
+
+// countBySelector builds a histogram of entries keyed by selector type. The
+// entry API has no aggregation RPC, so the entries are paged through with an
+// output mask that only includes the selectors, keeping the transfer small.
+func (c *countCommand) countBySelector(ctx context.Context, env *common_cli.Env, client entryv1.EntryClient) error {
+	total := 0
+	counts := make(map[string]int)
+
+	pageToken := ""
+	for {
+		resp, err := client.ListEntries(ctx, &entryv1.ListEntriesRequest{
+			PageSize:   1000,
+			PageToken:  pageToken,
+			OutputMask: &types.EntryMask{Selectors: true},
+		})
+		if err != nil {
+			return fmt.Errorf("error fetching entries: %w", err)
+		}
+		for _, entry := range resp.Entries {
+			total++
+			for selectorType := range selectorTypes(entry.Selectors) {
+				counts[selectorType]++
+			}
+		}
+		if pageToken = resp.NextPageToken; pageToken == "" {
+			break
+		}
+	}
+
+	if c.output == util.OutputJSON {
+		return printJSON(env, struct {
+			Count          int            `json:"count"`
+			BySelectorType map[string]int `json:"by_selector_type"`
+		}{Count: total, BySelectorType: counts})
+	}
+
+	msg := fmt.Sprintf("%d registration ", total)
+	msg = util.Pluralizer(msg, "entry", "entries", total)
+	env.Println(msg)
+	if len(counts) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	env.Println()
+	tw := tabwriter.NewWriter(env.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "SELECTOR TYPE\tENTRIES")
+	for _, key := range keys {
+		fmt.Fprintf(tw, "%s\t%d\n", key, counts[key])
+	}
+	return tw.Flush()
+}
+
+// selectorTypes returns the distinct selector types of an entry. The selector
+// type is the plugin type plus the first segment of the value, e.g. "unix:uid"
+// for "unix:uid:1000". Selectors whose value has a single segment or is a URI
+// (e.g. "spiffe_id:spiffe://example.org/foo") are keyed by the plugin type alone.
+func selectorTypes(selectors []*types.Selector) map[string]struct{} {
+	set := make(map[string]struct{}, len(selectors))
+	for _, s := range selectors {
+		key := s.Type
+		if i := strings.Index(s.Value, ":"); i > 0 && !strings.HasPrefix(s.Value[i+1:], "//") {
+			key += ":" + s.Value[:i]
+		}
+		set[key] = struct{}{}
+	}
+	return set
+}
+
+func printJSON(env *common_cli.Env, v interface{}) error {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal output: %w", err)
+	}
+	return env.Println(string(out))
 }
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	entryv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/entry/v1"
+	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -14,6 +15,10 @@ func TestCountHelp(t *testing.T) {
 	test.client.Help()
 
 	require.Equal(t, `Usage of entry count:
+  -bySelector
+    	Count the registration entries grouped by selector type (e.g. k8s:ns, unix:uid)
+  -output string
+    	Output format. Options: pretty and json (default "pretty")
   -socketPath string
     	Path to the SPIRE Server API socket (default "/tmp/spire-server/private/api.sock")
 `, test.stderr.String())
@@ -58,6 +63,17 @@ func TestCount(t *testing.T) {
 			fakeCountResp: fakeResp0,
 			expOut:        "0 registration entries\n",
 		},
+		{
+			name:          "JSON output",
+			args:          []string{"-output", "json"},
+			fakeCountResp: fakeResp4,
+			expOut:        "{\n  \"count\": 4\n}\n",
+		},
+		{
+			name:   "Invalid output format",
+			args:   []string{"-output", "yaml"},
+			expErr: "Error: invalid output format \"yaml\": must be \"pretty\" or \"json\"\n",
+		},
 		{
 			name:      "Server error",
 			serverErr: status.Error(codes.Internal, "internal server error"),
@@ -82,3 +98,112 @@ func TestCount(t *testing.T) {
 		})
 	}
 }
+
+func TestCountBySelector(t *testing.T) {
+	expReq := &entryv1.ListEntriesRequest{
+		PageSize:   1000,
+		OutputMask: &types.EntryMask{Selectors: true},
+	}
+	entries := []*types.Entry{
+		{
+			Selectors: []*types.Selector{
+				{Type: "unix", Value: "uid:1000"},
+				{Type: "unix", Value: "gid:1000"},
+			},
+		},
+		{
+			Selectors: []*types.Selector{
+				{Type: "k8s", Value: "ns:prod"},
+				{Type: "k8s", Value: "sa:api"},
+			},
+		},
+		{
+			Selectors: []*types.Selector{
+				{Type: "k8s", Value: "ns:dev"},
+				{Type: "unix", Value: "uid:1001"},
+			},
+		},
+		{
+			Selectors: []*types.Selector{
+				{Type: "spiffe_id", Value: "spiffe://example.org/foo"},
+				{Type: "foo", Value: "bar"},
+			},
+		},
+	}
+
+	for _, tt := range []struct {
+		name        string
+		args        []string
+		fakeEntries []*types.Entry
+		serverErr   error
+		expOut      string
+		expErr      string
+	}{
+		{
+			name:        "Pretty output",
+			args:        []string{"-bySelector"},
+			fakeEntries: entries,
+			expOut: `4 registration entries
+
+SELECTOR TYPE  ENTRIES
+foo            1
+k8s:ns         2
+k8s:sa         1
+spiffe_id      1
+unix:gid       1
+unix:uid       2
+`,
+		},
+		{
+			name:        "JSON output",
+			args:        []string{"-bySelector", "-output", "json"},
+			fakeEntries: entries,
+			expOut: `{
+  "count": 4,
+  "by_selector_type": {
+    "foo": 1,
+    "k8s:ns": 2,
+    "k8s:sa": 1,
+    "spiffe_id": 1,
+    "unix:gid": 1,
+    "unix:uid": 2
+  }
+}
+`,
+		},
+		{
+			name:   "No entries",
+			args:   []string{"-bySelector"},
+			expOut: "0 registration entries\n",
+		},
+		{
+			name:   "No entries with JSON output",
+			args:   []string{"-bySelector", "-output", "json"},
+			expOut: "{\n  \"count\": 0,\n  \"by_selector_type\": {}\n}\n",
+		},
+		{
+			name:      "Server error",
+			args:      []string{"-bySelector"},
+			serverErr: status.Error(codes.Internal, "internal server error"),
+			expErr:    "Error: error fetching entries: rpc error: code = Internal desc = internal server error\n",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			test := setupTest(t, NewCountCommandWithEnv)
+			test.server.err = tt.serverErr
+			test.server.expListEntriesReq = expReq
+			test.server.listEntriesResp = &entryv1.ListEntriesResponse{Entries: tt.fakeEntries}
+
+			rc := test.client.Run(test.args(tt.args...))
+			if tt.expErr != "" {
+				require.Equal(t, 1, rc)
+				require.Equal(t, tt.expErr, test.stderr.String())
+				return
+			}
+
+			require.Equal(t, 0, rc)
+			require.Equal(t, tt.expOut, test.stdout.String())
+		})
+	}
+}
@@ -93,9 +93,10 @@ func (c *createCommand) Run(ctx context.Context, env *common_cli.Env, serverClie
 	}
 
 	var entries []*types.Entry
+	var invalid []error
 	var err error
 	if c.path != "" {
-		entries, err = parseFile(c.path)
+		entries, invalid, err = parseFileEntries(c.path)
 	} else {
 		entries, err = c.parseConfig()
 	}
@@ -103,9 +104,14 @@ func (c *createCommand) Run(ctx context.Context, env *common_cli.Env, serverClie
 		return err
 	}
 
-	succeeded, failed, err := createEntries(ctx, serverClient.NewEntryClient(), entries)
-	if err != nil {
-		return err
+	// Entries that could not be parsed don't prevent the valid ones from
+	// being created
+	var succeeded, failed []*entryv1.BatchCreateEntryResponse_Result
+	if len(entries) > 0 {
+		succeeded, failed, err = createEntries(ctx, serverClient.NewEntryClient(), entries)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Print entries that succeeded to be created
@@ -121,7 +127,12 @@ func (c *createCommand) Run(ctx context.Context, env *common_cli.Env, serverClie
 		printEntry(r.Entry, env.ErrPrintf)
 	}
 
-	if len(failed) > 0 {
+	// Print entries that failed to be parsed
+	for _, err := range invalid {
+		env.ErrPrintf("Failed to parse %v\n", err)
+	}
+
+	if len(failed) > 0 || len(invalid) > 0 {
 		return errors.New("failed to create one or more entries")
 	}
 
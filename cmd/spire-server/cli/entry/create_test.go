@@ -139,6 +139,47 @@ func TestCreate(t *testing.T) {
 		},
 	}
 
+	fakeReqFromFile := &entryv1.BatchCreateEntryRequest{
+		Entries: []*types.Entry{
+			{
+				SpiffeId:  &types.SPIFFEID{TrustDomain: "example.org", Path: "/Blog"},
+				ParentId:  &types.SPIFFEID{TrustDomain: "example.org", Path: "/spire/agent/join_token/TokenBlog"},
+				Selectors: []*types.Selector{{Type: "unix", Value: "uid:1111"}},
+				Ttl:       200,
+				Admin:     true,
+			},
+			{
+				SpiffeId:  &types.SPIFFEID{TrustDomain: "example.org", Path: "/Database"},
+				ParentId:  &types.SPIFFEID{TrustDomain: "example.org", Path: "/spire/agent/join_token/TokenDatabase"},
+				Selectors: []*types.Selector{{Type: "unix", Value: "uid:1111"}},
+				Ttl:       200,
+			},
+			{
+				SpiffeId: &types.SPIFFEID{TrustDomain: "example.org", Path: "/storesvid"},
+				ParentId: &types.SPIFFEID{TrustDomain: "example.org", Path: "/spire/agent/join_token/TokenDatabase"},
+				Selectors: []*types.Selector{
+					{Type: "type", Value: "key1:value"},
+					{Type: "type", Value: "key2:value"},
+				},
+				Ttl:       200,
+				StoreSvid: true,
+			},
+		},
+	}
+
+	fakeRespMixed := &entryv1.BatchCreateEntryResponse{
+		Results: []*entryv1.BatchCreateEntryResponse_Result{
+			fakeRespOKFromFile.Results[0],
+			{
+				Status: &types.Status{
+					Code:    int32(codes.AlreadyExists),
+					Message: "similar entry already exists",
+				},
+			},
+			fakeRespOKFromFile.Results[2],
+		},
+	}
+
 	for _, tt := range []struct {
 		name string
 		args []string
@@ -252,6 +293,39 @@ StoreSvid        : true
 			args: []string{
 				"-data", "../../../../test/fixture/registration/good.json",
 			},
+			expReq:   fakeReqFromFile,
+			fakeResp: fakeRespOKFromFile,
+			expOut: `Entry ID         : entry-id-1
+SPIFFE ID        : spiffe://example.org/Blog
+Parent ID        : spiffe://example.org/spire/agent/join_token/TokenBlog
+Revision         : 0
+TTL              : 200
+Selector         : unix:uid:1111
+Admin            : true
+
+Entry ID         : entry-id-2
+SPIFFE ID        : spiffe://example.org/Database
+Parent ID        : spiffe://example.org/spire/agent/join_token/TokenDatabase
+Revision         : 0
+TTL              : 200
+Selector         : unix:uid:1111
+
+Entry ID         : entry-id-3
+SPIFFE ID        : spiffe://example.org/storesvid
+Parent ID        : spiffe://example.org/spire/agent/join_token/TokenDatabase
+Revision         : 0
+TTL              : 200
+Selector         : type:key1:value
+Selector         : type:key2:value
+StoreSvid        : true
+
+`,
+		},
+		{
+			name: "Create from data file with an invalid entry",
+			args: []string{
+				"-data", "testdata/create_mixed.json",
+			},
 			expReq: &entryv1.BatchCreateEntryRequest{
 				Entries: []*types.Entry{
 					{
@@ -261,25 +335,11 @@ StoreSvid        : true
 						Ttl:       200,
 						Admin:     true,
 					},
-					{
-						SpiffeId:  &types.SPIFFEID{TrustDomain: "example.org", Path: "/Database"},
-						ParentId:  &types.SPIFFEID{TrustDomain: "example.org", Path: "/spire/agent/join_token/TokenDatabase"},
-						Selectors: []*types.Selector{{Type: "unix", Value: "uid:1111"}},
-						Ttl:       200,
-					},
-					{
-						SpiffeId: &types.SPIFFEID{TrustDomain: "example.org", Path: "/storesvid"},
-						ParentId: &types.SPIFFEID{TrustDomain: "example.org", Path: "/spire/agent/join_token/TokenDatabase"},
-						Selectors: []*types.Selector{
-							{Type: "type", Value: "key1:value"},
-							{Type: "type", Value: "key2:value"},
-						},
-						Ttl:       200,
-						StoreSvid: true,
-					},
 				},
 			},
-			fakeResp: fakeRespOKFromFile,
+			fakeResp: &entryv1.BatchCreateEntryResponse{
+				Results: fakeRespOKFromFile.Results[:1],
+			},
 			expOut: `Entry ID         : entry-id-1
 SPIFFE ID        : spiffe://example.org/Blog
 Parent ID        : spiffe://example.org/spire/agent/join_token/TokenBlog
@@ -288,12 +348,25 @@ TTL              : 200
 Selector         : unix:uid:1111
 Admin            : true
 
-Entry ID         : entry-id-2
-SPIFFE ID        : spiffe://example.org/Database
-Parent ID        : spiffe://example.org/spire/agent/join_token/TokenDatabase
+`,
+			expErr: `Failed to parse entry 2: invalid SPIFFE ID: scheme is missing or invalid
+Error: failed to create one or more entries
+`,
+		},
+		{
+			name: "Create from data file with an entry rejected by the server",
+			args: []string{
+				"-data", "../../../../test/fixture/registration/good.json",
+			},
+			expReq:   fakeReqFromFile,
+			fakeResp: fakeRespMixed,
+			expOut: `Entry ID         : entry-id-1
+SPIFFE ID        : spiffe://example.org/Blog
+Parent ID        : spiffe://example.org/spire/agent/join_token/TokenBlog
 Revision         : 0
 TTL              : 200
 Selector         : unix:uid:1111
+Admin            : true
 
 Entry ID         : entry-id-3
 SPIFFE ID        : spiffe://example.org/storesvid
@@ -304,6 +377,16 @@ Selector         : type:key1:value
 Selector         : type:key2:value
 StoreSvid        : true
 
+`,
+			expErr: `Failed to create the following entry (code: AlreadyExists, msg: "similar entry already exists"):
+Entry ID         : (none)
+SPIFFE ID        : spiffe://example.org/Database
+Parent ID        : spiffe://example.org/spire/agent/join_token/TokenDatabase
+Revision         : 0
+TTL              : 200
+Selector         : unix:uid:1111
+
+Error: failed to create one or more entries
 `,
 		},
 		{
@@ -337,6 +420,7 @@ Error: failed to create one or more entries
 			test.server.batchCreateEntryResp = tt.fakeResp
 
 			rc := test.client.Run(test.args(tt.args...))
+			require.Equal(t, tt.expOut, test.stdout.String())
 			if tt.expErr != "" {
 				require.Equal(t, 1, rc)
 				require.Equal(t, tt.expErr, test.stderr.String())
@@ -344,7 +428,6 @@ Error: failed to create one or more entries
 			}
 
 			require.Equal(t, 0, rc)
-			require.Equal(t, tt.expOut, test.stdout.String())
 		})
 	}
 }
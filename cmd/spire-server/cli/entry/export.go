@@ -0,0 +1,200 @@
+package entry
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mitchellh/cli"
+	entryv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/entry/v1"
+	trustdomainv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/trustdomain/v1"
+	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
+	"github.com/spiffe/spire/cmd/spire-server/util"
+	common_cli "github.com/spiffe/spire/pkg/common/cli"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	// backupVersion is the version of the backup document written by
+	// "entry export". It is bumped when the document changes in a way that
+	// older versions of "entry import" cannot read.
+	backupVersion = 1
+
+	// listPageSize is the page size used to list entries and federation
+	// relationships.
+	listPageSize = 1000
+)
+
+// backup is the portable JSON document written by "entry export" and read by
+// "entry import". Entries and federation relationships are encoded with the
+// JSON representation of the corresponding API types.
+type backup struct {
+	Version                 int               `json:"version"`
+	Entries                 []json.RawMessage `json:"entries"`
+	FederationRelationships []json.RawMessage `json:"federation_relationships"`
+}
+
+// NewExportCommand creates a new "export" subcommand for "entry" command.
+func NewExportCommand() cli.Command {
+	return newExportCommand(common_cli.DefaultEnv)
+}
+
+func newExportCommand(env *common_cli.Env) cli.Command {
+	return util.AdaptCommand(env, new(exportCommand))
+}
+
+type exportCommand struct {
+	// Path to the file where the backup is written. If not set, the backup
+	// is written to stdout.
+	path string
+}
+
+func (*exportCommand) Name() string {
+	return "entry export"
+}
+
+func (*exportCommand) Synopsis() string {
+	return "Exports all registration entries and federation relationships to a JSON backup"
+}
+
+func (c *exportCommand) AppendFlags(f *flag.FlagSet) {
+	f.StringVar(&c.path, "file", "", "Path to the file where the backup is written. If not set, the backup is written to stdout")
+}
+
+func (c *exportCommand) Run(ctx context.Context, env *common_cli.Env, serverClient util.ServerClient) error {
+	entries, err := listAllEntries(ctx, serverClient.NewEntryClient())
+	if err != nil {
+		return err
+	}
+
+	relationships, err := listAllFederationRelationships(ctx, serverClient.NewTrustDomainClient())
+	if err != nil {
+		return err
+	}
+
+	data, err := marshalBackup(entries, relationships)
+	if err != nil {
+		return err
+	}
+
+	if c.path == "" {
+		_, err := env.Stdout.Write(data)
+		return err
+	}
+
+	if err := os.WriteFile(c.path, data, 0600); err != nil {
+		return fmt.Errorf("unable to write backup: %w", err)
+	}
+
+	msg := fmt.Sprintf("Exported %d registration ", len(entries))
+	msg = util.Pluralizer(msg, "entry", "entries", len(entries))
+	msg += fmt.Sprintf(" and %d ", len(relationships))
+	msg = util.Pluralizer(msg, "federation relationship", "federation relationships", len(relationships))
+	return env.Printf("%s to %s\n", msg, c.path)
+}
+
+func listAllEntries(ctx context.Context, client entryv1.EntryClient) ([]*types.Entry, error) {
+	var entries []*types.Entry
+	pageToken := ""
+	for {
+		resp, err := client.ListEntries(ctx, &entryv1.ListEntriesRequest{
+			PageSize:  listPageSize,
+			PageToken: pageToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error fetching entries: %w", err)
+		}
+		entries = append(entries, resp.Entries...)
+		if pageToken = resp.NextPageToken; pageToken == "" {
+			return entries, nil
+		}
+	}
+}
+
+func listAllFederationRelationships(ctx context.Context, client trustdomainv1.TrustDomainClient) ([]*types.FederationRelationship, error) {
+	var relationships []*types.FederationRelationship
+	pageToken := ""
+	for {
+		resp, err := client.ListFederationRelationships(ctx, &trustdomainv1.ListFederationRelationshipsRequest{
+			PageSize:  listPageSize,
+			PageToken: pageToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error fetching federation relationships: %w", err)
+		}
+		relationships = append(relationships, resp.FederationRelationships...)
+		if pageToken = resp.NextPageToken; pageToken == "" {
+			return relationships, nil
+		}
+	}
+}
+
+func marshalBackup(entries []*types.Entry, relationships []*types.FederationRelationship) ([]byte, error) {
+	doc := backup{
+		Version:                 backupVersion,
+		Entries:                 make([]json.RawMessage, 0, len(entries)),
+		FederationRelationships: make([]json.RawMessage, 0, len(relationships)),
+	}
+	for _, entry := range entries {
+		raw, err := marshalBackupMessage(entry)
+		if err != nil {
+			return nil, err
+		}
+		doc.Entries = append(doc.Entries, raw)
+	}
+	for _, relationship := range relationships {
+		raw, err := marshalBackupMessage(relationship)
+		if err != nil {
+			return nil, err
+		}
+		doc.FederationRelationships = append(doc.FederationRelationships, raw)
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal backup: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+func marshalBackupMessage(m proto.Message) (json.RawMessage, error) {
+	raw, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal backup: %w", err)
+	}
+	return raw, nil
+}
+
+func unmarshalBackup(data []byte) ([]*types.Entry, []*types.FederationRelationship, error) {
+	doc := new(backup)
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, nil, fmt.Errorf("unable to parse backup: %w", err)
+	}
+	if doc.Version != backupVersion {
+		return nil, nil, fmt.Errorf("unsupported backup version %d", doc.Version)
+	}
+
+	entries := make([]*types.Entry, 0, len(doc.Entries))
+	for i, raw := range doc.Entries {
+		entry := new(types.Entry)
+		if err := protojson.Unmarshal(raw, entry); err != nil {
+			return nil, nil, fmt.Errorf("unable to parse entry %d: %w", i+1, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	relationships := make([]*types.FederationRelationship, 0, len(doc.FederationRelationships))
+	for i, raw := range doc.FederationRelationships {
+		relationship := new(types.FederationRelationship)
+		if err := protojson.Unmarshal(raw, relationship); err != nil {
+			return nil, nil, fmt.Errorf("unable to parse federation relationship %d: %w", i+1, err)
+		}
+		relationships = append(relationships, relationship)
+	}
+
+	return entries, relationships, nil
+}
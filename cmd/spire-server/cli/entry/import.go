@@ -0,0 +1,240 @@
+package entry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/mitchellh/cli"
+	entryv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/entry/v1"
+	trustdomainv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/trustdomain/v1"
+	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
+	"github.com/spiffe/spire/cmd/spire-server/util"
+	common_cli "github.com/spiffe/spire/pkg/common/cli"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+
+	"golang.org/x/net/context"
+)
+
+// importBatchSize is the maximum number of entries created per request.
+const importBatchSize = 100
+
+// NewImportCommand creates a new "import" subcommand for "entry" command.
+func NewImportCommand() cli.Command {
+	return newImportCommand(common_cli.DefaultEnv)
+}
+
+func newImportCommand(env *common_cli.Env) cli.Command {
+	return util.AdaptCommand(env, new(importCommand))
+}
+
+type importCommand struct {
+	// Path to the backup written by "entry export"
+	path string
+}
+
+func (*importCommand) Name() string {
+	return "entry import"
+}
+
+func (*importCommand) Synopsis() string {
+	return "Imports registration entries and federation relationships from a JSON backup"
+}
+
+func (c *importCommand) AppendFlags(f *flag.FlagSet) {
+	f.StringVar(&c.path, "data", "", "Path to a backup written by \"entry export\". If set to '-', read the backup from stdin.")
+}
+
+// Run imports the federation relationships and then the entries of the
+// backup, so that entries are imported after the trust domains they federate
+// with. Importing is idempotent: federation relationships that already exist
+// for a trust domain, and entries that already exist with the same ID or the
+// same content, are skipped.
+func (c *importCommand) Run(ctx context.Context, env *common_cli.Env, serverClient util.ServerClient) error {
+	if c.path == "" {
+		return errors.New("a backup file is required")
+	}
+
+	data, err := readBackup(env.Stdin, c.path)
+	if err != nil {
+		return err
+	}
+	entries, relationships, err := unmarshalBackup(data)
+	if err != nil {
+		return err
+	}
+
+	relationshipsFailed, err := importFederationRelationships(ctx, env, serverClient.NewTrustDomainClient(), relationships)
+	if err != nil {
+		return err
+	}
+
+	entriesFailed, err := importEntries(ctx, env, serverClient.NewEntryClient(), entries)
+	if err != nil {
+		return err
+	}
+
+	if relationshipsFailed || entriesFailed {
+		return errors.New("failed to import one or more entries or federation relationships")
+	}
+	return nil
+}
+
+func readBackup(stdin io.Reader, path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(stdin)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read backup: %w", err)
+	}
+	return data, nil
+}
+
+func importFederationRelationships(ctx context.Context, env *common_cli.Env, client trustdomainv1.TrustDomainClient, relationships []*types.FederationRelationship) (bool, error) {
+	existing, err := listAllFederationRelationships(ctx, client)
+	if err != nil {
+		return false, err
+	}
+	existingTrustDomains := make(map[string]struct{}, len(existing))
+	for _, relationship := range existing {
+		existingTrustDomains[relationship.TrustDomain] = struct{}{}
+	}
+
+	var toCreate []*types.FederationRelationship
+	skipped := 0
+	for _, relationship := range relationships {
+		if _, ok := existingTrustDomains[relationship.TrustDomain]; ok {
+			skipped++
+			continue
+		}
+		toCreate = append(toCreate, relationship)
+	}
+
+	imported := 0
+	failed := false
+	if len(toCreate) > 0 {
+		resp, err := client.BatchCreateFederationRelationship(ctx, &trustdomainv1.BatchCreateFederationRelationshipRequest{
+			FederationRelationships: toCreate,
+		})
+		if err != nil {
+			return false, fmt.Errorf("error creating federation relationships: %w", err)
+		}
+		for i, r := range resp.Results {
+			switch codes.Code(r.Status.Code) {
+			case codes.OK:
+				imported++
+			case codes.AlreadyExists:
+				skipped++
+			default:
+				env.ErrPrintf("Failed to import federation relationship with %q (code: %s, msg: %q)\n",
+					toCreate[i].TrustDomain,
+					codes.Code(r.Status.Code),
+					r.Status.Message)
+				failed = true
+			}
+		}
+	}
+
+	msg := fmt.Sprintf("Imported %d ", imported)
+	msg = util.Pluralizer(msg, "federation relationship", "federation relationships", imported)
+	env.Printf("%s (%d skipped)\n", msg, skipped)
+	return failed, nil
+}
+
+func importEntries(ctx context.Context, env *common_cli.Env, client entryv1.EntryClient, entries []*types.Entry) (bool, error) {
+	existing, err := listAllEntries(ctx, client)
+	if err != nil {
+		return false, err
+	}
+	existingIDs := make(map[string]struct{}, len(existing))
+	existingHashes := make(map[string]struct{}, len(existing))
+	for _, entry := range existing {
+		existingIDs[entry.Id] = struct{}{}
+		hash, err := entryContentHash(entry)
+		if err != nil {
+			return false, err
+		}
+		existingHashes[hash] = struct{}{}
+	}
+
+	var toCreate []*types.Entry
+	skipped := 0
+	for _, entry := range entries {
+		hash, err := entryContentHash(entry)
+		if err != nil {
+			return false, err
+		}
+		_, idExists := existingIDs[entry.Id]
+		_, hashExists := existingHashes[hash]
+		if idExists || hashExists {
+			skipped++
+			continue
+		}
+		// Entries with the same content in the backup are only created once
+		existingHashes[hash] = struct{}{}
+		toCreate = append(toCreate, entry)
+	}
+
+	imported := 0
+	failed := false
+	for len(toCreate) > 0 {
+		batch := toCreate
+		if len(batch) > importBatchSize {
+			batch = batch[:importBatchSize]
+		}
+		toCreate = toCreate[len(batch):]
+
+		succeeded, failures, err := createEntries(ctx, client, batch)
+		if err != nil {
+			return false, err
+		}
+		imported += len(succeeded)
+		for _, r := range failures {
+			if codes.Code(r.Status.Code) == codes.AlreadyExists {
+				skipped++
+				continue
+			}
+			env.ErrPrintf("Failed to import the following entry (code: %s, msg: %q):\n",
+				codes.Code(r.Status.Code),
+				r.Status.Message)
+			printEntry(r.Entry, env.ErrPrintf)
+			failed = true
+		}
+	}
+
+	msg := fmt.Sprintf("Imported %d registration ", imported)
+	msg = util.Pluralizer(msg, "entry", "entries", imported)
+	env.Printf("%s (%d skipped)\n", msg, skipped)
+	return failed, nil
+}
+
+// entryContentHash returns a hash of the content of the entry, ignoring the
+// entry ID and revision number, which are assigned by the server, and the
+// order of selectors and federated trust domains. It is used to recognize
+// entries that were already imported under a different entry ID.
+func entryContentHash(entry *types.Entry) (string, error) {
+	content := proto.Clone(entry).(*types.Entry)
+	content.Id = ""
+	content.RevisionNumber = 0
+	sort.Slice(content.Selectors, func(i, j int) bool {
+		if content.Selectors[i].Type != content.Selectors[j].Type {
+			return content.Selectors[i].Type < content.Selectors[j].Type
+		}
+		return content.Selectors[i].Value < content.Selectors[j].Value
+	})
+	sort.Strings(content.FederatesWith)
+
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(content)
+	if err != nil {
+		return "", fmt.Errorf("unable to hash entry: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,319 @@
+package entry
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/mitchellh/cli"
+	entryv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/entry/v1"
+	trustdomainv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/trustdomain/v1"
+	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
+	common_cli "github.com/spiffe/spire/pkg/common/cli"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestExportHelp(t *testing.T) {
+	test := setupTest(t, newExportCommand)
+	test.client.Help()
+
+	require.Equal(t, `Usage of entry export:
+  -file string
+    	Path to the file where the backup is written. If not set, the backup is written to stdout
+  -socketPath string
+    	Path to the SPIRE Server API socket (default "/tmp/spire-server/private/api.sock")
+`, test.stderr.String())
+}
+
+func TestImportHelp(t *testing.T) {
+	test := setupTest(t, newImportCommand)
+	test.client.Help()
+
+	require.Equal(t, `Usage of entry import:
+  -data string
+    	Path to a backup written by "entry export". If set to '-', read the backup from stdin.
+  -socketPath string
+    	Path to the SPIRE Server API socket (default "/tmp/spire-server/private/api.sock")
+`, test.stderr.String())
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	source := newBackupServer(t)
+	source.relationships = []*types.FederationRelationship{
+		{
+			TrustDomain:           "domain1.org",
+			BundleEndpointUrl:     "https://domain1.org/bundle",
+			BundleEndpointProfile: &types.FederationRelationship_HttpsWeb{HttpsWeb: &types.HTTPSWebProfile{}},
+		},
+	}
+	// More entries than fit in a page, to exercise pagination
+	for i := 0; i < 3; i++ {
+		source.addEntry(&types.Entry{
+			SpiffeId:      &types.SPIFFEID{TrustDomain: "example.org", Path: fmt.Sprintf("/workload%d", i)},
+			ParentId:      &types.SPIFFEID{TrustDomain: "example.org", Path: "/parent"},
+			Selectors:     []*types.Selector{{Type: "unix", Value: fmt.Sprintf("uid:%d", i)}},
+			FederatesWith: []string{"domain1.org"},
+			DnsNames:      []string{"workload.example.org"},
+			Ttl:           60,
+		})
+	}
+
+	backupPath := filepath.Join(spiretest.TempDir(t), "backup.json")
+	out, errOut, code := runBackupCommand(t, source, newExportCommand, "-file", backupPath)
+	require.Equal(t, 0, code, errOut)
+	require.Equal(t, "Exported 3 registration entries and 1 federation relationship to "+backupPath+"\n", out)
+
+	// Import into an empty server
+	target := newBackupServer(t)
+	out, errOut, code = runBackupCommand(t, target, newImportCommand, "-data", backupPath)
+	require.Equal(t, 0, code, errOut)
+	require.Equal(t, "Imported 1 federation relationship (0 skipped)\nImported 3 registration entries (0 skipped)\n", out)
+	spiretest.RequireProtoListEqual(t, source.relationships, target.relationships)
+	require.Len(t, target.entries, len(source.entries))
+	for i, entry := range target.entries {
+		// Entry IDs are assigned by the server
+		expected := proto.Clone(source.entries[i]).(*types.Entry)
+		expected.Id = entry.Id
+		spiretest.RequireProtoEqual(t, expected, entry)
+	}
+
+	// Importing again skips the entries already imported under a different
+	// ID and the existing federation relationships
+	out, errOut, code = runBackupCommand(t, target, newImportCommand, "-data", backupPath)
+	require.Equal(t, 0, code, errOut)
+	require.Equal(t, "Imported 0 federation relationships (1 skipped)\nImported 0 registration entries (3 skipped)\n", out)
+	require.Len(t, target.entries, 3)
+
+	// Importing into the source server skips the entries by ID
+	out, errOut, code = runBackupCommand(t, source, newImportCommand, "-data", backupPath)
+	require.Equal(t, 0, code, errOut)
+	require.Equal(t, "Imported 0 federation relationships (1 skipped)\nImported 0 registration entries (3 skipped)\n", out)
+	require.Len(t, source.entries, 3)
+
+	// The exported backups of both servers only differ in the entry IDs
+	sourceBackup, errOut, code := runBackupCommand(t, source, newExportCommand)
+	require.Equal(t, 0, code, errOut)
+	for i, entry := range target.entries {
+		entry.Id = source.entries[i].Id
+	}
+	targetBackup, errOut, code := runBackupCommand(t, target, newExportCommand)
+	require.Equal(t, 0, code, errOut)
+	require.JSONEq(t, sourceBackup, targetBackup)
+}
+
+func TestImportFromStdin(t *testing.T) {
+	server := newBackupServer(t)
+	entry := &types.Entry{
+		SpiffeId:  &types.SPIFFEID{TrustDomain: "example.org", Path: "/workload"},
+		ParentId:  &types.SPIFFEID{TrustDomain: "example.org", Path: "/parent"},
+		Selectors: []*types.Selector{{Type: "unix", Value: "uid:1"}},
+	}
+	data, err := marshalBackup([]*types.Entry{entry}, nil)
+	require.NoError(t, err)
+
+	stdin := bytes.NewBuffer(data)
+	out, errOut, code := runBackupCommandWithStdin(t, server, newImportCommand, stdin, "-data", "-")
+	require.Equal(t, 0, code, errOut)
+	require.Equal(t, "Imported 0 federation relationships (0 skipped)\nImported 1 registration entry (0 skipped)\n", out)
+	require.Len(t, server.entries, 1)
+}
+
+func TestImportSkipsSimilarEntries(t *testing.T) {
+	server := newBackupServer(t)
+	server.addEntry(&types.Entry{
+		SpiffeId:  &types.SPIFFEID{TrustDomain: "example.org", Path: "/workload"},
+		ParentId:  &types.SPIFFEID{TrustDomain: "example.org", Path: "/parent"},
+		Selectors: []*types.Selector{{Type: "unix", Value: "uid:1"}},
+	})
+
+	// The entry differs in content but the server considers it the same
+	data, err := marshalBackup([]*types.Entry{
+		{
+			SpiffeId:  &types.SPIFFEID{TrustDomain: "example.org", Path: "/workload"},
+			ParentId:  &types.SPIFFEID{TrustDomain: "example.org", Path: "/parent"},
+			Selectors: []*types.Selector{{Type: "unix", Value: "uid:1"}},
+			Ttl:       60,
+		},
+	}, nil)
+	require.NoError(t, err)
+	backupPath := filepath.Join(spiretest.TempDir(t), "backup.json")
+	require.NoError(t, os.WriteFile(backupPath, data, 0600))
+
+	out, errOut, code := runBackupCommand(t, server, newImportCommand, "-data", backupPath)
+	require.Equal(t, 0, code, errOut)
+	require.Equal(t, "Imported 0 federation relationships (0 skipped)\nImported 0 registration entries (1 skipped)\n", out)
+	require.Len(t, server.entries, 1)
+}
+
+func TestImportFailures(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		backup string
+		expErr string
+	}{
+		{
+			name:   "invalid JSON",
+			backup: "{",
+			expErr: "Error: unable to parse backup: unexpected end of JSON input\n",
+		},
+		{
+			name:   "unsupported version",
+			backup: `{"version": 2}`,
+			expErr: "Error: unsupported backup version 2\n",
+		},
+		{
+			name:   "invalid entry",
+			backup: `{"version": 1, "entries": [{"ttl": "not a number"}]}`,
+			expErr: "Error: unable to parse entry 1: ",
+		},
+		{
+			name: "entry rejected by the server",
+			backup: `{"version": 1, "entries": [{
+				"spiffe_id": {"trust_domain": "example.org", "path": "/workload"},
+				"parent_id": {"trust_domain": "example.org", "path": "/parent"}
+			}]}`,
+			expErr: `Failed to import the following entry (code: InvalidArgument, msg: "selector list is empty"):`,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			backupPath := filepath.Join(spiretest.TempDir(t), "backup.json")
+			require.NoError(t, os.WriteFile(backupPath, []byte(tt.backup), 0600))
+
+			_, errOut, code := runBackupCommand(t, newBackupServer(t), newImportCommand, "-data", backupPath)
+			require.Equal(t, 1, code)
+			require.Contains(t, errOut, tt.expErr)
+		})
+	}
+
+	t.Run("missing backup", func(t *testing.T) {
+		_, errOut, code := runBackupCommand(t, newBackupServer(t), newImportCommand)
+		require.Equal(t, 1, code)
+		require.Equal(t, "Error: a backup file is required\n", errOut)
+	})
+}
+
+func runBackupCommand(t *testing.T, server *backupServer, newClient func(*common_cli.Env) cli.Command, args ...string) (string, string, int) {
+	return runBackupCommandWithStdin(t, server, newClient, new(bytes.Buffer), args...)
+}
+
+func runBackupCommandWithStdin(t *testing.T, server *backupServer, newClient func(*common_cli.Env) cli.Command, stdin *bytes.Buffer, args ...string) (string, string, int) {
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	client := newClient(&common_cli.Env{
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+	code := client.Run(append([]string{"-socketPath", server.socketPath}, args...))
+	return stdout.String(), stderr.String(), code
+}
+
+// backupServer is an in-memory implementation of the entry and trust domain
+// APIs used by the export and import commands.
+type backupServer struct {
+	entryv1.UnimplementedEntryServer
+	trustdomainv1.UnimplementedTrustDomainServer
+
+	socketPath    string
+	nextID        int
+	entries       []*types.Entry
+	relationships []*types.FederationRelationship
+}
+
+func newBackupServer(t *testing.T) *backupServer {
+	server := new(backupServer)
+	server.socketPath = spiretest.StartGRPCSocketServerOnTempSocket(t, func(s *grpc.Server) {
+		entryv1.RegisterEntryServer(s, server)
+		trustdomainv1.RegisterTrustDomainServer(s, server)
+	})
+	return server
+}
+
+func (s *backupServer) addEntry(entry *types.Entry) {
+	s.nextID++
+	entry = proto.Clone(entry).(*types.Entry)
+	entry.Id = fmt.Sprintf("%s-entry-%d", s.socketPath, s.nextID)
+	s.entries = append(s.entries, entry)
+}
+
+func (s *backupServer) ListEntries(ctx context.Context, req *entryv1.ListEntriesRequest) (*entryv1.ListEntriesResponse, error) {
+	// Use small pages so pagination is exercised
+	const pageSize = 2
+
+	start := 0
+	if req.PageToken != "" {
+		var err error
+		if start, err = strconv.Atoi(req.PageToken); err != nil {
+			return nil, err
+		}
+	}
+	end := start + pageSize
+	if end >= len(s.entries) {
+		return &entryv1.ListEntriesResponse{Entries: s.entries[start:]}, nil
+	}
+	return &entryv1.ListEntriesResponse{
+		Entries:       s.entries[start:end],
+		NextPageToken: strconv.Itoa(end),
+	}, nil
+}
+
+func (s *backupServer) BatchCreateEntry(ctx context.Context, req *entryv1.BatchCreateEntryRequest) (*entryv1.BatchCreateEntryResponse, error) {
+	resp := new(entryv1.BatchCreateEntryResponse)
+	for _, entry := range req.Entries {
+		switch {
+		case len(entry.Selectors) == 0:
+			resp.Results = append(resp.Results, &entryv1.BatchCreateEntryResponse_Result{
+				Status: &types.Status{Code: int32(codes.InvalidArgument), Message: "selector list is empty"},
+			})
+		case s.hasSimilarEntry(entry):
+			resp.Results = append(resp.Results, &entryv1.BatchCreateEntryResponse_Result{
+				Status: &types.Status{Code: int32(codes.AlreadyExists), Message: "similar entry already exists"},
+			})
+		default:
+			s.addEntry(entry)
+			resp.Results = append(resp.Results, &entryv1.BatchCreateEntryResponse_Result{
+				Status: &types.Status{Code: int32(codes.OK), Message: "OK"},
+				Entry:  s.entries[len(s.entries)-1],
+			})
+		}
+	}
+	return resp, nil
+}
+
+func (s *backupServer) hasSimilarEntry(entry *types.Entry) bool {
+	for _, existing := range s.entries {
+		if proto.Equal(existing.SpiffeId, entry.SpiffeId) &&
+			proto.Equal(existing.ParentId, entry.ParentId) &&
+			proto.Equal(&types.Entry{Selectors: existing.Selectors}, &types.Entry{Selectors: entry.Selectors}) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *backupServer) ListFederationRelationships(ctx context.Context, req *trustdomainv1.ListFederationRelationshipsRequest) (*trustdomainv1.ListFederationRelationshipsResponse, error) {
+	return &trustdomainv1.ListFederationRelationshipsResponse{
+		FederationRelationships: s.relationships,
+	}, nil
+}
+
+func (s *backupServer) BatchCreateFederationRelationship(ctx context.Context, req *trustdomainv1.BatchCreateFederationRelationshipRequest) (*trustdomainv1.BatchCreateFederationRelationshipResponse, error) {
+	resp := new(trustdomainv1.BatchCreateFederationRelationshipResponse)
+	for _, relationship := range req.FederationRelationships {
+		s.relationships = append(s.relationships, relationship)
+		resp.Results = append(resp.Results, &trustdomainv1.BatchCreateFederationRelationshipResponse_Result{
+			Status:                 &types.Status{Code: int32(codes.OK), Message: "OK"},
+			FederationRelationship: relationship,
+		})
+	}
+	return resp, nil
+}
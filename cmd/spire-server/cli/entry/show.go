@@ -4,6 +4,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"strings"
 
 	"github.com/mitchellh/cli"
 	entryv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/entry/v1"
@@ -35,6 +36,9 @@ type showCommand struct {
 	// Workload parent spiffeID
 	parentID string
 
+	// Prefix of the workload parent spiffeID
+	parentIDPrefix string
+
 	// Workload spiffeID
 	spiffeID string
 
@@ -49,6 +53,9 @@ type showCommand struct {
 
 	// Match used when filtering by selectors
 	matchSelectorsOn string
+
+	// Output format, either "pretty" or "json"
+	output string
 }
 
 func (c *showCommand) Name() string {
@@ -62,12 +69,14 @@ func (*showCommand) Synopsis() string {
 func (c *showCommand) AppendFlags(f *flag.FlagSet) {
 	f.StringVar(&c.entryID, "entryID", "", "The Entry ID of the records to show")
 	f.StringVar(&c.parentID, "parentID", "", "The Parent ID of the records to show")
+	f.StringVar(&c.parentIDPrefix, "parentIDPrefix", "", "A prefix of the Parent ID of the records to show (e.g. spiffe://example.org/cluster-a/)")
 	f.StringVar(&c.spiffeID, "spiffeID", "", "The SPIFFE ID of the records to show")
 	f.BoolVar(&c.downstream, "downstream", false, "A boolean value that, when set, indicates that the entry describes a downstream SPIRE server")
 	f.Var(&c.selectors, "selector", "A colon-delimited type:value selector. Can be used more than once")
 	f.Var(&c.federatesWith, "federatesWith", "SPIFFE ID of a trust domain an entry is federate with. Can be used more than once")
 	f.StringVar(&c.matchFederatesWithOn, "matchFederatesWithOn", "superset", "The match mode used when filtering by federates with. Options: exact, any, superset and subset")
 	f.StringVar(&c.matchSelectorsOn, "matchSelectorsOn", "superset", "The match mode used when filtering by selectors. Options: exact, any, superset and subset")
+	f.StringVar(&c.output, "output", util.OutputPretty, util.OutputFlagUsage)
 }
 
 // Run executes all logic associated with a single invocation of the
@@ -83,6 +92,10 @@ func (c *showCommand) Run(ctx context.Context, env *common_cli.Env, serverClient
 	}
 
 	commonutil.SortTypesEntries(entries)
+	if c.output == util.OutputJSON {
+		return util.PrintProtoJSON(env.Stdout, &entryv1.ListEntriesResponse{Entries: entries})
+	}
+
 	printEntries(entries, env)
 	return nil
 }
@@ -91,12 +104,27 @@ func (c *showCommand) Run(ctx context.Context, env *common_cli.Env, serverClient
 func (c *showCommand) validate() error {
 	// If entryID is given, it should be the only constraint
 	if c.entryID != "" {
-		if c.parentID != "" || c.spiffeID != "" || len(c.selectors) > 0 {
+		if c.parentID != "" || c.parentIDPrefix != "" || c.spiffeID != "" || len(c.selectors) > 0 {
 			return errors.New("the -entryID flag can't be combined with others")
 		}
 	}
 
-	return nil
+	if c.parentIDPrefix != "" {
+		if c.parentID != "" {
+			return errors.New("the -parentID and -parentIDPrefix flags can't be combined")
+		}
+		if !strings.HasPrefix(c.parentIDPrefix, "spiffe://") {
+			return fmt.Errorf("parent ID prefix %q must start with \"spiffe://\"", c.parentIDPrefix)
+		}
+	}
+
+	// Validate the match behavior even when no selectors are given so a
+	// typo is not silently ignored
+	if _, err := parseToSelectorMatch(c.matchSelectorsOn); err != nil {
+		return err
+	}
+
+	return util.ValidateOutputFormat(c.output)
 }
 
 func (c *showCommand) fetchEntries(ctx context.Context, client entryv1.EntryClient) ([]*types.Entry, error) {
@@ -165,9 +193,24 @@ func (c *showCommand) fetchEntries(ctx context.Context, client entryv1.EntryClie
 		return nil, fmt.Errorf("error fetching entries: %w", err)
 	}
 
+	if c.parentIDPrefix != "" {
+		// The entry API only filters by exact parent ID, so entries are
+		// filtered by parent ID prefix here.
+		return filterByParentIDPrefix(resp.Entries, c.parentIDPrefix), nil
+	}
 	return resp.Entries, nil
 }
 
+func filterByParentIDPrefix(entries []*types.Entry, prefix string) []*types.Entry {
+	filtered := []*types.Entry{}
+	for _, entry := range entries {
+		if strings.HasPrefix(protoToIDString(entry.ParentId), prefix) {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
 // fetchByEntryID uses the configured EntryID to fetch the appropriate registration entry
 func (c *showCommand) fetchByEntryID(ctx context.Context, id string, client entryv1.EntryClient) (*types.Entry, error) {
 	entry, err := client.GetEntry(ctx, &entryv1.GetEntryRequest{Id: id})
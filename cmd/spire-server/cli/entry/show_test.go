@@ -2,6 +2,8 @@ package entry
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -27,8 +29,12 @@ func TestShowHelp(t *testing.T) {
     	The match mode used when filtering by federates with. Options: exact, any, superset and subset (default "superset")
   -matchSelectorsOn string
     	The match mode used when filtering by selectors. Options: exact, any, superset and subset (default "superset")
+  -output string
+    	Output format. Options: pretty and json (default "pretty")
   -parentID string
     	The Parent ID of the records to show
+  -parentIDPrefix string
+    	A prefix of the Parent ID of the records to show (e.g. spiffe://example.org/cluster-a/)
   -selector value
     	A colon-delimited type:value selector. Can be used more than once
   -socketPath string
@@ -126,6 +132,51 @@ func TestShow(t *testing.T) {
 			args:   []string{"-parentID", "invalid-id"},
 			expErr: "Error: error parsing parent ID \"invalid-id\": scheme is missing or invalid\n",
 		},
+		{
+			name: "List by parent ID prefix matching several parents",
+			args: []string{"-parentIDPrefix", "spiffe://example.org/"},
+			expListReq: &entryv1.ListEntriesRequest{
+				Filter: &entryv1.ListEntriesRequest_Filter{},
+			},
+			fakeListResp: fakeRespAll,
+			expOut: fmt.Sprintf("Found 4 entries\n%s%s%s%s",
+				getPrintedEntry(1),
+				getPrintedEntry(2),
+				getPrintedEntry(0),
+				getPrintedEntry(3),
+			),
+		},
+		{
+			name: "List by parent ID prefix matching one parent",
+			args: []string{"-parentIDPrefix", "spiffe://example.org/moth"},
+			expListReq: &entryv1.ListEntriesRequest{
+				Filter: &entryv1.ListEntriesRequest_Filter{},
+			},
+			fakeListResp: fakeRespAll,
+			expOut: fmt.Sprintf("Found 2 entries\n%s%s",
+				getPrintedEntry(2),
+				getPrintedEntry(3),
+			),
+		},
+		{
+			name: "List by parent ID prefix with no matches",
+			args: []string{"-parentIDPrefix", "spiffe://example.org/cluster-a/"},
+			expListReq: &entryv1.ListEntriesRequest{
+				Filter: &entryv1.ListEntriesRequest_Filter{},
+			},
+			fakeListResp: fakeRespAll,
+			expOut:       "Found 0 entries\n",
+		},
+		{
+			name:   "List by parent ID prefix without the SPIFFE scheme",
+			args:   []string{"-parentIDPrefix", "example.org/"},
+			expErr: "Error: parent ID prefix \"example.org/\" must start with \"spiffe://\"\n",
+		},
+		{
+			name:   "List by parent ID and parent ID prefix",
+			args:   []string{"-parentID", "spiffe://example.org/father", "-parentIDPrefix", "spiffe://example.org/"},
+			expErr: "Error: the -parentID and -parentIDPrefix flags can't be combined\n",
+		},
 		{
 			name: "List by SPIFFE ID",
 			args: []string{"-spiffeID", "spiffe://example.org/daughter"},
@@ -240,6 +291,54 @@ func TestShow(t *testing.T) {
 				getPrintedEntry(1),
 			),
 		},
+		{
+			name: "List by selectors: exact matcher with a single selector",
+			args: []string{"-selector", "k8s:ns:prod", "-matchSelectorsOn", "exact"},
+			expListReq: &entryv1.ListEntriesRequest{
+				Filter: &entryv1.ListEntriesRequest_Filter{
+					BySelectors: &types.SelectorMatch{
+						Selectors: []*types.Selector{
+							{Type: "k8s", Value: "ns:prod"},
+						},
+						Match: types.SelectorMatch_MATCH_EXACT,
+					},
+				},
+			},
+			fakeListResp: &entryv1.ListEntriesResponse{},
+			expOut:       "Found 0 entries\n",
+		},
+		{
+			name:         "List by selectors: subset matcher without selectors",
+			args:         []string{"-matchSelectorsOn", "subset"},
+			expListReq:   &entryv1.ListEntriesRequest{Filter: &entryv1.ListEntriesRequest_Filter{}},
+			fakeListResp: fakeRespAll,
+			expOut: fmt.Sprintf("Found 4 entries\n%s%s%s%s",
+				getPrintedEntry(1),
+				getPrintedEntry(2),
+				getPrintedEntry(0),
+				getPrintedEntry(3),
+			),
+		},
+		{
+			name:   "List by selectors: empty selector value",
+			args:   []string{"-selector", "k8s:", "-matchSelectorsOn", "subset"},
+			expErr: "Error: error parsing selectors: selector \"k8s:\" must be formatted as type:value\n",
+		},
+		{
+			name:   "List by selectors: empty selector type",
+			args:   []string{"-selector", ":ns:prod", "-matchSelectorsOn", "exact"},
+			expErr: "Error: error parsing selectors: selector \":ns:prod\" must be formatted as type:value\n",
+		},
+		{
+			name:   "List by selectors: Invalid matcher without selectors",
+			args:   []string{"-matchSelectorsOn", "NO-MATCHER"},
+			expErr: "Error: match behavior \"NO-MATCHER\" unknown\n",
+		},
+		{
+			name:   "Invalid output format",
+			args:   []string{"-output", "yaml"},
+			expErr: "Error: invalid output format \"yaml\": must be \"pretty\" or \"json\"\n",
+		},
 		{
 			name:   "List by selectors: Invalid matcher",
 			args:   []string{"-selector", "foo:bar", "-selector", "bar:baz", "-matchSelectorsOn", "NO-MATCHER"},
@@ -370,6 +469,23 @@ func TestShow(t *testing.T) {
 }
 
 // registrationEntries returns `count` registration entry records. At most 4.
+func TestShowJSON(t *testing.T) {
+	test := setupTest(t, newShowCommand)
+	test.server.expListEntriesReq = &entryv1.ListEntriesRequest{
+		Filter: &entryv1.ListEntriesRequest_Filter{},
+	}
+	test.server.listEntriesResp = &entryv1.ListEntriesResponse{
+		Entries: getEntries(4),
+	}
+
+	rc := test.client.Run(test.args("-output", "json"))
+	require.Equal(t, 0, rc)
+
+	expected, err := os.ReadFile(filepath.Join("testdata", "show.golden.json"))
+	require.NoError(t, err)
+	require.Equal(t, string(expected), test.stdout.String())
+}
+
 func getEntries(count int) []*types.Entry {
 	selectors := []*types.Selector{
 		{Type: "foo", Value: "bar"},
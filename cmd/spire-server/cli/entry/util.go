@@ -1,6 +1,7 @@
 package entry
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -90,8 +91,38 @@ func parseFile(path string) ([]*types.Entry, error) {
 }
 
 func parseEntryJSON(in io.Reader, path string) ([]*types.Entry, error) {
-	entries := &common.RegistrationEntries{}
+	entries, err := readEntryJSON(in, path)
+	if err != nil {
+		return nil, err
+	}
+	return api.RegistrationEntriesToProto(entries)
+}
+
+// parseFileEntries parses JSON represented RegistrationEntries like
+// parseFile, but entries that fail to convert are returned as errors instead
+// of failing the whole file.
+func parseFileEntries(path string) ([]*types.Entry, []error, error) {
+	entries, err := readEntryJSON(os.Stdin, path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var pbs []*types.Entry
+	var errs []error
+	for i, e := range entries {
+		pb, err := api.RegistrationEntryToProto(e)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("entry %d: %w", i+1, err))
+			continue
+		}
+		pbs = append(pbs, pb)
+	}
+	return pbs, errs, nil
+}
 
+// readEntryJSON reads registration entries from either a JSON array of
+// entries or an object with an "entries" array.
+func readEntryJSON(in io.Reader, path string) ([]*common.RegistrationEntry, error) {
 	r := in
 	if path != "-" {
 		f, err := os.Open(path)
@@ -107,10 +138,19 @@ func parseEntryJSON(in io.Reader, path string) ([]*types.Entry, error) {
 		return nil, err
 	}
 
+	if trimmed := bytes.TrimSpace(dat); len(trimmed) > 0 && trimmed[0] == '[' {
+		var entries []*common.RegistrationEntry
+		if err := json.Unmarshal(dat, &entries); err != nil {
+			return nil, err
+		}
+		return entries, nil
+	}
+
+	entries := &common.RegistrationEntries{}
 	if err := json.Unmarshal(dat, &entries); err != nil {
 		return nil, err
 	}
-	return api.RegistrationEntriesToProto(entries.Entries)
+	return entries.Entries, nil
 }
 
 // StringsFlag defines a custom type for string lists. Doing
@@ -34,6 +34,10 @@ func TestParseEntryJSON(t *testing.T) {
 			testDataPath: path.Join(util.ProjectRoot(), "test/fixture/registration/good.json"),
 			in:           new(bytes.Buffer),
 		},
+		{
+			name:         "Parse valid JSON array",
+			testDataPath: path.Join(util.ProjectRoot(), "test/fixture/registration/good_array.json"),
+		},
 		{
 			name:         "Parse invalid JSON",
 			testDataPath: "test/fixture/registration/invalid_json.json",
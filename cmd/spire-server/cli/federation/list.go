@@ -20,6 +20,8 @@ func newListCommand(env *common_cli.Env) cli.Command {
 }
 
 type listCommand struct {
+	// Output format, either "pretty" or "json"
+	output string
 }
 
 func (c *listCommand) Name() string {
@@ -31,9 +33,14 @@ func (c *listCommand) Synopsis() string {
 }
 
 func (c *listCommand) AppendFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.output, "output", util.OutputPretty, util.OutputFlagUsage)
 }
 
 func (c *listCommand) Run(ctx context.Context, env *common_cli.Env, serverClient util.ServerClient) error {
+	if err := util.ValidateOutputFormat(c.output); err != nil {
+		return err
+	}
+
 	trustDomainClient := serverClient.NewTrustDomainClient()
 
 	resp, err := trustDomainClient.ListFederationRelationships(ctx, &trustdomainv1.ListFederationRelationshipsRequest{})
@@ -41,6 +48,10 @@ func (c *listCommand) Run(ctx context.Context, env *common_cli.Env, serverClient
 		return fmt.Errorf("error listing federation relationship: %w", err)
 	}
 
+	if c.output == util.OutputJSON {
+		return util.PrintProtoJSON(env.Stdout, resp)
+	}
+
 	msg := fmt.Sprintf("Found %v ", len(resp.FederationRelationships))
 	msg = util.Pluralizer(msg, "federation relationship", "federation relationships", len(resp.FederationRelationships))
 
@@ -1,6 +1,8 @@
 package federation
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	trustdomainv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/trustdomain/v1"
@@ -15,6 +17,8 @@ func TestListHelp(t *testing.T) {
 	test.client.Help()
 
 	require.Equal(t, `Usage of federation list:
+  -output string
+    	Output format. Options: pretty and json (default "pretty")
   -socketPath string
     	Path to the SPIRE Server API socket (default "/tmp/spire-server/private/api.sock")
 `, test.stderr.String())
@@ -110,6 +114,24 @@ Bundle endpoint profile   : https_spiffe
 Endpoint SPIFFE ID        : spiffe://baz.test/id
 `,
 		},
+		{
+			name:          "multiple federations (json output)",
+			args:          []string{"-output", "json"},
+			expectListReq: &trustdomainv1.ListFederationRelationshipsRequest{},
+			listResp: &trustdomainv1.ListFederationRelationshipsResponse{
+				FederationRelationships: []*types.FederationRelationship{
+					federation1,
+					federation2,
+					federation3,
+				},
+			},
+			expectOut: readGolden(t, "list.golden.json"),
+		},
+		{
+			name:      "invalid output format",
+			args:      []string{"-output", "yaml"},
+			expectErr: "Error: invalid output format \"yaml\": must be \"pretty\" or \"json\"\n",
+		},
 		{
 			name:      "server fails",
 			serverErr: status.Error(codes.Internal, "oh! no"),
@@ -134,3 +156,9 @@ Endpoint SPIFFE ID        : spiffe://baz.test/id
 		})
 	}
 }
+
+func readGolden(t *testing.T, name string) string {
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return string(data)
+}
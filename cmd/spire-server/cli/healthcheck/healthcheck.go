@@ -9,6 +9,7 @@ import (
 	"github.com/mitchellh/cli"
 	"github.com/spiffe/spire/cmd/spire-server/util"
 	common_cli "github.com/spiffe/spire/pkg/common/cli"
+	healthv1 "github.com/spiffe/spire/pkg/server/api/health/v1"
 	"google.golang.org/grpc/health/grpc_health_v1"
 )
 
@@ -21,6 +22,7 @@ func newHealthCheckCommand(env *common_cli.Env) cli.Command {
 }
 
 type healthCheckCommand struct {
+	deep    bool
 	shallow bool
 	verbose bool
 }
@@ -34,6 +36,7 @@ func (c *healthCheckCommand) Synopsis() string {
 }
 
 func (c *healthCheckCommand) AppendFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.deep, "deep", false, "Also verify that the server can query its datastore")
 	fs.BoolVar(&c.shallow, "shallow", false, "Perform a less stringent health check")
 	fs.BoolVar(&c.verbose, "verbose", false, "Print verbose information")
 }
@@ -52,8 +55,13 @@ func (c *healthCheckCommand) run(ctx context.Context, env *common_cli.Env, clien
 		}
 	}
 
+	req := &grpc_health_v1.HealthCheckRequest{}
+	if c.deep {
+		req.Service = healthv1.DataStoreService
+	}
+
 	healthClient := client.NewHealthClient()
-	resp, err := healthClient.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	resp, err := healthClient.Check(ctx, req)
 	if err != nil {
 		if c.verbose {
 			// Ignore error since a failure to write to stderr cannot very well
@@ -46,6 +46,8 @@ func (s *HealthCheckSuite) TestSynopsis() {
 func (s *HealthCheckSuite) TestHelp() {
 	s.Equal("flag: help requested", s.cmd.Help())
 	s.Equal(`Usage of healthcheck:
+  -deep
+    	Also verify that the server can query its datastore
   -shallow
     	Perform a less stringent health check
   -socketPath string
@@ -61,6 +63,8 @@ func (s *HealthCheckSuite) TestBadFlags() {
 	s.Equal("", s.stdout.String(), "stdout")
 	s.Equal(`flag provided but not defined: -badflag
 Usage of healthcheck:
+  -deep
+    	Also verify that the server can query its datastore
   -shallow
     	Perform a less stringent health check
   -socketPath string
@@ -120,6 +124,25 @@ func (s *HealthCheckSuite) TestFailsIfServiceStatusOther() {
 `, s.stderr.String(), "stderr")
 }
 
+func (s *HealthCheckSuite) TestDeepRequestsDataStoreService() {
+	socketPath := spiretest.StartGRPCSocketServerOnTempSocket(s.T(), func(srv *grpc.Server) {
+		grpc_health_v1.RegisterHealthServer(srv, healthServer{
+			status:  grpc_health_v1.HealthCheckResponse_NOT_SERVING,
+			service: "datastore",
+		})
+	})
+	code := s.cmd.Run([]string{"--socketPath", socketPath})
+	s.Equal(0, code, "exit code")
+	s.Equal("Server is healthy.\n", s.stdout.String(), "stdout")
+
+	s.stdout.Reset()
+	code = s.cmd.Run([]string{"--socketPath", socketPath, "--deep"})
+	s.NotEqual(0, code, "exit code")
+	s.Equal("", s.stdout.String(), "stdout")
+	s.Equal(`Error: server is unhealthy: server returned status "NOT_SERVING"
+`, s.stderr.String(), "stderr")
+}
+
 func withStatus(status grpc_health_v1.HealthCheckResponse_ServingStatus) healthServer {
 	return healthServer{status: status}
 }
@@ -128,12 +151,21 @@ type healthServer struct {
 	grpc_health_v1.UnimplementedHealthServer
 	status grpc_health_v1.HealthCheckResponse_ServingStatus
 	err    error
+
+	// service, if set, is the only service reported with status. Other
+	// services are reported as serving.
+	service string
 }
 
-func (s healthServer) Check(context.Context, *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+func (s healthServer) Check(_ context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
 	if s.err != nil {
 		return nil, s.err
 	}
+	if s.service != "" && req.Service != s.service {
+		return &grpc_health_v1.HealthCheckResponse{
+			Status: grpc_health_v1.HealthCheckResponse_SERVING,
+		}, nil
+	}
 	return &grpc_health_v1.HealthCheckResponse{
 		Status: s.status,
 	}, nil
@@ -0,0 +1,152 @@
+package migrate
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/mitchellh/cli"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/spire/cmd/spire-server/cli/run"
+	"github.com/spiffe/spire/pkg/common/catalog"
+	common_cli "github.com/spiffe/spire/pkg/common/cli"
+	"github.com/spiffe/spire/pkg/server/datastore/sqlstore"
+)
+
+const (
+	commandName   = "migrate"
+	dataStoreType = "DataStore"
+)
+
+func NewMigrateCommand() cli.Command {
+	return newMigrateCommand(common_cli.DefaultEnv)
+}
+
+func newMigrateCommand(env *common_cli.Env) *migrateCommand {
+	return &migrateCommand{
+		env: env,
+	}
+}
+
+type migrateCommand struct {
+	env *common_cli.Env
+
+	configPath string
+	expandEnv  bool
+	dryRun     bool
+}
+
+// Help prints the migrate cmd usage
+func (c *migrateCommand) Help() string {
+	_, err := c.parseFlags([]string{"-h"})
+	// Error is always present because -h is passed
+	return err.Error()
+}
+
+func (c *migrateCommand) Synopsis() string {
+	return "Reports the pending datastore schema migrations"
+}
+
+func (c *migrateCommand) Run(args []string) int {
+	if _, err := c.parseFlags(args); err != nil {
+		return 1
+	}
+
+	if err := c.run(); err != nil {
+		// Ignore error since a failure to write to stderr cannot very well be reported
+		_ = c.env.ErrPrintln(err)
+		return 1
+	}
+	return 0
+}
+
+func (c *migrateCommand) parseFlags(args []string) (*flag.FlagSet, error) {
+	flags := flag.NewFlagSet(commandName, flag.ContinueOnError)
+	flags.SetOutput(c.env.Stderr)
+	flags.StringVar(&c.configPath, "config", "", "Path to a SPIRE config file")
+	flags.BoolVar(&c.expandEnv, "expandEnv", false, "Expand environment variables in SPIRE config file")
+	flags.BoolVar(&c.dryRun, "dryRun", false, "Run the pending migrations in a transaction that is rolled back and report the changes they make")
+	return flags, flags.Parse(args)
+}
+
+func (c *migrateCommand) run() error {
+	config, err := run.ParseFile(c.configPath, c.expandEnv)
+	if err != nil {
+		return err
+	}
+	hclConfiguration, err := dataStoreConfiguration(config)
+	if err != nil {
+		return err
+	}
+
+	// Only warnings and errors are of interest; the migration steps log
+	// their progress as if they were applied for real.
+	log := logrus.New()
+	log.SetOutput(c.env.Stderr)
+	log.SetLevel(logrus.WarnLevel)
+
+	var plan *sqlstore.MigrationPlan
+	if c.dryRun {
+		plan, err = sqlstore.DryRunMigrations(log, hclConfiguration)
+	} else {
+		plan, err = sqlstore.PlanMigrations(log, hclConfiguration)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.printPlan(plan)
+	return nil
+}
+
+func (c *migrateCommand) printPlan(plan *sqlstore.MigrationPlan) {
+	if !plan.Initialized {
+		_ = c.env.Printf("Database is not initialized; it will be created with schema version %d.\n", plan.LatestSchemaVersion)
+		return
+	}
+
+	codeVersion := plan.CodeVersion
+	if codeVersion == "" {
+		codeVersion = "unknown"
+	}
+	_ = c.env.Printf("Database schema version: %d (last migrated by SPIRE Server %s)\n", plan.SchemaVersion, codeVersion)
+	_ = c.env.Printf("Latest schema version  : %d\n", plan.LatestSchemaVersion)
+
+	switch {
+	case plan.SchemaVersion > plan.LatestSchemaVersion:
+		_ = c.env.Println("Database schema is ahead of this SPIRE Server version; no migrations to run.")
+		return
+	case len(plan.Steps) == 0:
+		_ = c.env.Println("No pending migrations.")
+		return
+	}
+
+	_ = c.env.Println("Pending migrations:")
+	for _, step := range plan.Steps {
+		_ = c.env.Printf("  %d -> %d: %s\n", step.SchemaVersion-1, step.SchemaVersion, step.Description)
+		for _, change := range step.Changes {
+			_ = c.env.Printf("    %s\n", change)
+		}
+	}
+
+	if c.dryRun {
+		_ = c.env.Println("Dry run complete; all changes were rolled back.")
+	}
+}
+
+// dataStoreConfiguration returns the configuration of the SQL DataStore
+// plugin from the server configuration.
+func dataStoreConfiguration(config *run.Config) (string, error) {
+	if config.Plugins == nil {
+		return "", errors.New("plugins section must be configured")
+	}
+	hclConfig, ok := (*config.Plugins)[dataStoreType][sqlstore.PluginName]
+	if !ok {
+		return "", fmt.Errorf("the %q DataStore plugin must be configured", sqlstore.PluginName)
+	}
+	pluginConfig, err := catalog.PluginConfigFromHCL(dataStoreType, sqlstore.PluginName, hclConfig)
+	if err != nil {
+		return "", err
+	}
+	return pluginConfig.Data, nil
+}
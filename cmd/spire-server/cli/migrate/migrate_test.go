@@ -0,0 +1,115 @@
+package migrate
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	common_cli "github.com/spiffe/spire/pkg/common/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSynopsis(t *testing.T) {
+	cmd, _, _ := newTestCommand()
+	require.Equal(t, "Reports the pending datastore schema migrations", cmd.Synopsis())
+}
+
+func TestHelp(t *testing.T) {
+	cmd, _, stderr := newTestCommand()
+	require.Equal(t, "flag: help requested", cmd.Help())
+	require.Contains(t, stderr.String(), "Usage of migrate:")
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "datastore.sqlite3")
+
+	writeConfig := func(t *testing.T, plugins string) string {
+		configPath := filepath.Join(t.TempDir(), "server.conf")
+		require.NoError(t, os.WriteFile(configPath, []byte(plugins), 0600))
+		return configPath
+	}
+
+	sqlConfig := writeConfig(t, fmt.Sprintf(`
+		plugins {
+			DataStore "sql" {
+				plugin_data {
+					database_type = "sqlite3"
+					connection_string = %q
+				}
+			}
+		}
+	`, dbPath))
+
+	for _, tt := range []struct {
+		name           string
+		args           []string
+		expectedCode   int
+		expectedStdout string
+		expectedStderr string
+	}{
+		{
+			name:           "bad flag",
+			args:           []string{"-badflag"},
+			expectedCode:   1,
+			expectedStderr: "flag provided but not defined: -badflag",
+		},
+		{
+			name:           "missing config file",
+			args:           []string{"-config", filepath.Join(dir, "missing.conf")},
+			expectedCode:   1,
+			expectedStderr: "could not find config file",
+		},
+		{
+			name:           "missing plugins",
+			args:           []string{"-config", writeConfig(t, `server {}`)},
+			expectedCode:   1,
+			expectedStderr: "plugins section must be configured",
+		},
+		{
+			name: "missing sql datastore",
+			args: []string{"-config", writeConfig(t, `
+				plugins {
+					KeyManager "memory" {}
+				}
+			`)},
+			expectedCode:   1,
+			expectedStderr: `the "sql" DataStore plugin must be configured`,
+		},
+		{
+			name:           "uninitialized database",
+			args:           []string{"-config", sqlConfig},
+			expectedStdout: "Database is not initialized; it will be created with schema version 18.\n",
+		},
+		{
+			name:           "dry run on uninitialized database",
+			args:           []string{"-config", sqlConfig, "-dryRun"},
+			expectedStdout: "Database is not initialized; it will be created with schema version 18.\n",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, stdout, stderr := newTestCommand()
+			code := cmd.Run(tt.args)
+			require.Equal(t, tt.expectedCode, code, "exit code")
+			require.Equal(t, tt.expectedStdout, stdout.String(), "stdout")
+			if tt.expectedStderr == "" {
+				require.Empty(t, stderr.String(), "stderr")
+			} else {
+				require.Contains(t, stderr.String(), tt.expectedStderr, "stderr")
+			}
+		})
+	}
+}
+
+func newTestCommand() (*migrateCommand, *bytes.Buffer, *bytes.Buffer) {
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	return newMigrateCommand(&common_cli.Env{
+		Stdin:  new(bytes.Buffer),
+		Stdout: stdout,
+		Stderr: stderr,
+	}), stdout, stderr
+}
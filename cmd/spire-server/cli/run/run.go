@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -22,11 +23,13 @@ import (
 	"github.com/mitchellh/cli"
 	"github.com/sirupsen/logrus"
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/spire/pkg/common/bundleutil"
 	"github.com/spiffe/spire/pkg/common/catalog"
 	common_cli "github.com/spiffe/spire/pkg/common/cli"
 	"github.com/spiffe/spire/pkg/common/health"
 	"github.com/spiffe/spire/pkg/common/log"
 	"github.com/spiffe/spire/pkg/common/telemetry"
+	"github.com/spiffe/spire/pkg/common/tlspolicy"
 	"github.com/spiffe/spire/pkg/common/util"
 	"github.com/spiffe/spire/pkg/server"
 	"github.com/spiffe/spire/pkg/server/authpolicy"
@@ -34,6 +37,7 @@ import (
 	"github.com/spiffe/spire/pkg/server/ca"
 	"github.com/spiffe/spire/pkg/server/endpoints/bundle"
 	"github.com/spiffe/spire/pkg/server/plugin/keymanager"
+	"gopkg.in/square/go-jose.v2"
 )
 
 const (
@@ -42,6 +46,8 @@ const (
 	defaultConfigPath = "conf/server/server.conf"
 	defaultSocketPath = "/tmp/spire-server/private/api.sock"
 	defaultLogLevel   = "INFO"
+
+	bundleEndpointUnixScheme = "unix://"
 )
 
 var (
@@ -63,15 +69,19 @@ type Config struct {
 }
 
 type serverConfig struct {
+	AdminAPI        adminAPIConfig     `hcl:"admin_api"`
 	AdminIDs        []string           `hcl:"admin_ids"`
 	AgentTTL        string             `hcl:"agent_ttl"`
 	AuditLogEnabled bool               `hcl:"audit_log_enabled"`
+	AuditLogPath    string             `hcl:"audit_log_path"`
 	BindAddress     string             `hcl:"bind_address"`
 	BindPort        int                `hcl:"bind_port"`
 	CAKeyType       string             `hcl:"ca_key_type"`
 	CASubject       *caSubjectConfig   `hcl:"ca_subject"`
 	CATTL           string             `hcl:"ca_ttl"`
+	CRLDistPoint    string             `hcl:"crl_distribution_point"`
 	DataDir         string             `hcl:"data_dir"`
+	DeadNodeTTL     string             `hcl:"dead_node_ttl"`
 	DefaultSVIDTTL  string             `hcl:"default_svid_ttl"`
 	Experimental    experimentalConfig `hcl:"experimental"`
 	Federation      *federationConfig  `hcl:"federation"`
@@ -80,10 +90,20 @@ type serverConfig struct {
 	LogFile         string             `hcl:"log_file"`
 	LogLevel        string             `hcl:"log_level"`
 	LogFormat       string             `hcl:"log_format"`
+	MaxJWTSVIDTTL   string             `hcl:"max_jwt_svid_ttl"`
+	MaxRecvMsgSize  int                `hcl:"max_recv_message_size"`
+	MaxSendMsgSize  int                `hcl:"max_send_message_size"`
+	MaxX509SVIDTTL  string             `hcl:"max_x509_svid_ttl"`
 	RateLimit       rateLimitConfig    `hcl:"ratelimit"`
 	SocketPath      string             `hcl:"socket_path"`
+	TLSCipherSuites []string           `hcl:"tls_cipher_suites"`
+	TLSMinVersion   string             `hcl:"tls_min_version"`
 	TrustDomain     string             `hcl:"trust_domain"`
 
+	UpstreamAuthorityOrder []string `hcl:"upstream_authority_order"`
+	JWTAllowedSigningAlgs  []string `hcl:"jwt_allowed_signing_algs"`
+	DataStoreMaxAttempts   int      `hcl:"datastore_max_attempts"`
+
 	ConfigPath string
 	ExpandEnv  bool
 
@@ -96,6 +116,12 @@ type serverConfig struct {
 	UnusedKeys []string `hcl:",unusedKeys"`
 }
 
+type adminAPIConfig struct {
+	EnableReflection bool `hcl:"enable_reflection"`
+
+	UnusedKeys []string `hcl:",unusedKeys"`
+}
+
 type experimentalConfig struct {
 	CacheReloadInterval string `hcl:"cache_reload_interval"`
 
@@ -118,10 +144,11 @@ type federationConfig struct {
 }
 
 type bundleEndpointConfig struct {
-	Address    string                    `hcl:"address"`
-	Port       int                       `hcl:"port"`
-	ACME       *bundleEndpointACMEConfig `hcl:"acme"`
-	UnusedKeys []string                  `hcl:",unusedKeys"`
+	Address              string                    `hcl:"address"`
+	Port                 int                       `hcl:"port"`
+	ACME                 *bundleEndpointACMEConfig `hcl:"acme"`
+	AllowedClientIDsFile string                    `hcl:"allowed_client_ids_file"`
+	UnusedKeys           []string                  `hcl:",unusedKeys"`
 }
 
 type bundleEndpointACMEConfig struct {
@@ -135,6 +162,7 @@ type bundleEndpointACMEConfig struct {
 type federatesWithConfig struct {
 	BundleEndpointURL     string   `hcl:"bundle_endpoint_url"`
 	BundleEndpointProfile ast.Node `hcl:"bundle_endpoint_profile"`
+	RefreshInterval       string   `hcl:"refresh_interval"`
 	UnusedKeys            []string `hcl:",unusedKeys"`
 }
 
@@ -150,6 +178,8 @@ type httpsSPIFFEProfileConfig struct {
 }
 
 type httpsWebProfileConfig struct {
+	CABundlePath string   `hcl:"ca_bundle_path"`
+	UnusedKeys   []string `hcl:",unusedKeys"`
 }
 
 type rateLimitConfig struct {
@@ -365,6 +395,31 @@ func NewServerConfig(c *Config, logOptions []log.Option, allowUnknownConfig bool
 
 	sc.DataDir = c.Server.DataDir
 	sc.AuditLogEnabled = c.Server.AuditLogEnabled
+	if c.Server.AuditLogPath != "" {
+		auditLog, err := log.NewLogger(
+			log.WithFormat(log.JSONFormat),
+			log.WithOutputFile(c.Server.AuditLogPath))
+		if err != nil {
+			return nil, fmt.Errorf("could not open audit log: %w", err)
+		}
+		sc.AuditLog = auditLog
+	}
+	sc.AdminAPIReflectionEnabled = c.Server.AdminAPI.EnableReflection
+	sc.MaxRecvMessageSize = c.Server.MaxRecvMsgSize
+	sc.MaxSendMessageSize = c.Server.MaxSendMsgSize
+	sc.DataStoreMaxAttempts = c.Server.DataStoreMaxAttempts
+
+	sc.TLSPolicy.MinVersion, err = tlspolicy.ParseMinVersion(c.Server.TLSMinVersion)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse tls_min_version: %w", err)
+	}
+	sc.TLSPolicy.CipherSuites, err = tlspolicy.ParseCipherSuites(c.Server.TLSCipherSuites)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse tls_cipher_suites: %w", err)
+	}
+	if err := sc.TLSPolicy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid TLS configuration: %w", err)
+	}
 
 	td, err := spiffeid.TrustDomainFromString(c.Server.TrustDomain)
 	if err != nil {
@@ -390,6 +445,13 @@ func NewServerConfig(c *Config, logOptions []log.Option, allowUnknownConfig bool
 					IP:   net.ParseIP(c.Server.Federation.BundleEndpoint.Address),
 					Port: c.Server.Federation.BundleEndpoint.Port,
 				},
+				AllowedClientIDsFile: c.Server.Federation.BundleEndpoint.AllowedClientIDsFile,
+			}
+			if socketPath, ok := bundleEndpointSocketPath(c.Server.Federation.BundleEndpoint.Address); ok {
+				sc.Federation.BundleEndpoint.Address = &net.UnixAddr{
+					Name: socketPath,
+					Net:  "unix",
+				}
 			}
 
 			if acme := c.Server.Federation.BundleEndpoint.ACME; acme != nil {
@@ -421,6 +483,15 @@ func NewServerConfig(c *Config, logOptions []log.Option, allowUnknownConfig bool
 			default:
 				return nil, fmt.Errorf("federation configuration for trust domain %q: missing bundle endpoint configuration", trustDomain)
 			}
+			if config.RefreshInterval != "" {
+				trustDomainConfig.RefreshInterval, err = time.ParseDuration(config.RefreshInterval)
+				if err != nil {
+					return nil, fmt.Errorf("could not parse refresh interval for trust domain %q: %w", trustDomain, err)
+				}
+				if trustDomainConfig.RefreshInterval < bundleutil.MinimumRefreshHint {
+					return nil, fmt.Errorf("refresh interval for trust domain %q must be at least %s", trustDomain, bundleutil.MinimumRefreshHint)
+				}
+			}
 			federatesWith[td] = *trustDomainConfig
 		}
 		sc.Federation.FederatesWith = federatesWith
@@ -450,6 +521,17 @@ func NewServerConfig(c *Config, logOptions []log.Option, allowUnknownConfig bool
 		sc.AgentTTL = ttl
 	}
 
+	if c.Server.DeadNodeTTL != "" {
+		ttl, err := time.ParseDuration(c.Server.DeadNodeTTL)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse dead_node_ttl %q: %w", c.Server.DeadNodeTTL, err)
+		}
+		if ttl < 0 {
+			return nil, errors.New("dead_node_ttl cannot be negative")
+		}
+		sc.DeadNodeTTL = ttl
+	}
+
 	if c.Server.DefaultSVIDTTL != "" {
 		ttl, err := time.ParseDuration(c.Server.DefaultSVIDTTL)
 		if err != nil {
@@ -458,6 +540,28 @@ func NewServerConfig(c *Config, logOptions []log.Option, allowUnknownConfig bool
 		sc.SVIDTTL = ttl
 	}
 
+	if c.Server.MaxX509SVIDTTL != "" {
+		ttl, err := time.ParseDuration(c.Server.MaxX509SVIDTTL)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse max X509-SVID ttl %q: %w", c.Server.MaxX509SVIDTTL, err)
+		}
+		if ttl <= 0 {
+			return nil, errors.New("max_x509_svid_ttl must be positive")
+		}
+		sc.MaxX509SVIDTTL = ttl
+	}
+
+	if c.Server.MaxJWTSVIDTTL != "" {
+		ttl, err := time.ParseDuration(c.Server.MaxJWTSVIDTTL)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse max JWT-SVID ttl %q: %w", c.Server.MaxJWTSVIDTTL, err)
+		}
+		if ttl <= 0 {
+			return nil, errors.New("max_jwt_svid_ttl must be positive")
+		}
+		sc.MaxJWTSVIDTTL = ttl
+	}
+
 	if c.Server.CATTL != "" {
 		ttl, err := time.ParseDuration(c.Server.CATTL)
 		if err != nil {
@@ -466,6 +570,10 @@ func NewServerConfig(c *Config, logOptions []log.Option, allowUnknownConfig bool
 		sc.CATTL = ttl
 	}
 
+	if err := checkMaxSVIDTTLs(sc); err != nil {
+		return nil, err
+	}
+
 	// If the configured TTLs can lead to surprises, then do our best to log an
 	// accurate message and guide the user to resolution
 	if !hasCompatibleTTLs(sc.CATTL, sc.SVIDTTL) {
@@ -528,6 +636,14 @@ func NewServerConfig(c *Config, logOptions []log.Option, allowUnknownConfig bool
 	}
 
 	sc.JWTIssuer = c.Server.JWTIssuer
+	for _, name := range c.Server.JWTAllowedSigningAlgs {
+		alg, err := jwtSigningAlgFromString(name)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing jwt_allowed_signing_algs: %w", err)
+		}
+		sc.JWTAllowedSigningAlgorithms = append(sc.JWTAllowedSigningAlgorithms, alg)
+	}
+	sc.CRLDistributionPoint = c.Server.CRLDistPoint
 
 	if subject := c.Server.CASubject; subject != nil {
 		sc.CASubject = pkix.Name{
@@ -545,6 +661,7 @@ func NewServerConfig(c *Config, logOptions []log.Option, allowUnknownConfig bool
 	}
 
 	sc.PluginConfigs = *c.Plugins
+	sc.UpstreamAuthorityOrder = c.Server.UpstreamAuthorityOrder
 	sc.Telemetry = c.Telemetry
 	sc.HealthChecks = c.HealthChecks
 
@@ -591,7 +708,7 @@ func parseBundleEndpointProfile(config federatesWithConfig) (trustDomainConfig *
 	var endpointProfile bundleClient.EndpointProfileInfo
 	switch {
 	case profileConfig.HTTPSWeb != nil:
-		endpointProfile = bundleClient.HTTPSWebProfile{}
+		endpointProfile = bundleClient.HTTPSWebProfile{CABundlePath: profileConfig.HTTPSWeb.CABundlePath}
 	case profileConfig.HTTPSSPIFFE != nil:
 		spiffeID, err := spiffeid.FromString(profileConfig.HTTPSSPIFFE.EndpointSPIFFEID)
 		if err != nil {
@@ -608,6 +725,16 @@ func parseBundleEndpointProfile(config federatesWithConfig) (trustDomainConfig *
 	}, nil
 }
 
+// bundleEndpointSocketPath returns the Unix domain socket path of a bundle
+// endpoint address in the form of "unix:///path/to/socket". It returns false
+// if the address is not a Unix domain socket address.
+func bundleEndpointSocketPath(address string) (string, bool) {
+	if !strings.HasPrefix(address, bundleEndpointUnixScheme) {
+		return "", false
+	}
+	return strings.TrimPrefix(address, bundleEndpointUnixScheme), true
+}
+
 func validateConfig(c *Config) error {
 	if c.Server == nil {
 		return errors.New("server section must be configured")
@@ -629,7 +756,48 @@ func validateConfig(c *Config) error {
 		return errors.New("plugins section must be configured")
 	}
 
+	if c.Server.AuditLogPath != "" && !c.Server.AuditLogEnabled {
+		return errors.New("audit_log_path requires audit_log_enabled")
+	}
+
+	if c.Server.CRLDistPoint != "" {
+		u, err := url.Parse(c.Server.CRLDistPoint)
+		if err != nil || !u.IsAbs() || u.Host == "" {
+			return fmt.Errorf("crl_distribution_point %q must be an absolute URL", c.Server.CRLDistPoint)
+		}
+	}
+
+	if c.Server.MaxRecvMsgSize < 0 {
+		return errors.New("max_recv_message_size must not be negative")
+	}
+
+	if c.Server.DataStoreMaxAttempts < 0 {
+		return errors.New("datastore_max_attempts must not be negative")
+	}
+
+	if c.Server.MaxSendMsgSize < 0 {
+		return errors.New("max_send_message_size must not be negative")
+	}
+
 	if c.Server.Federation != nil {
+		if be := c.Server.Federation.BundleEndpoint; be != nil {
+			if socketPath, ok := bundleEndpointSocketPath(be.Address); ok {
+				switch {
+				case socketPath == "":
+					return errors.New("federation.bundle_endpoint.address must include a socket path when using a unix:// address")
+				case be.Port != 0:
+					return errors.New("federation.bundle_endpoint.port cannot be set when using a unix:// address")
+				case be.ACME != nil:
+					return errors.New("federation.bundle_endpoint.acme cannot be configured when using a unix:// address")
+				case be.AllowedClientIDsFile != "":
+					return errors.New("federation.bundle_endpoint.allowed_client_ids_file cannot be set when using a unix:// address")
+				}
+			}
+			if be.ACME != nil && be.AllowedClientIDsFile != "" {
+				return errors.New("federation.bundle_endpoint.allowed_client_ids_file cannot be set when using ACME")
+			}
+		}
+
 		if c.Server.Federation.BundleEndpoint != nil &&
 			c.Server.Federation.BundleEndpoint.ACME != nil {
 			acme := c.Server.Federation.BundleEndpoint.ACME
@@ -678,6 +846,10 @@ func checkForUnknownConfig(c *Config, l logrus.FieldLogger) (err error) {
 			detectedUnknown("ca_subject", cs.UnusedKeys)
 		}
 
+		if aa := c.Server.AdminAPI; len(aa.UnusedKeys) != 0 {
+			detectedUnknown("admin_api", aa.UnusedKeys)
+		}
+
 		if rl := c.Server.RateLimit; len(rl.UnusedKeys) != 0 {
 			detectedUnknown("ratelimit", rl.UnusedKeys)
 		}
@@ -787,9 +959,40 @@ func keyTypeFromString(s string) (keymanager.KeyType, error) {
 	}
 }
 
+func jwtSigningAlgFromString(s string) (jose.SignatureAlgorithm, error) {
+	switch alg := jose.SignatureAlgorithm(strings.ToUpper(s)); alg {
+	case jose.RS256, jose.ES256, jose.ES384:
+		return alg, nil
+	default:
+		return "", fmt.Errorf("signing algorithm %q is unknown; must be one of [RS256, ES256, ES384]", s)
+	}
+}
+
 // hasCompatibleTTLs checks if we can guarantee the configured SVID TTL given the
 // configurd CA TTL. If we detect that a new SVIDs TTL may be cut short due to
 // a scheduled CA rotation, this function will return false.
+// checkMaxSVIDTTLs makes sure the TTLs the server uses by default do not
+// exceed the configured maximums. Otherwise every SVID minted with a
+// default TTL would be clamped, logging a warning each time.
+func checkMaxSVIDTTLs(sc *server.Config) error {
+	if sc.MaxX509SVIDTTL > 0 {
+		svidTTL := sc.SVIDTTL
+		if svidTTL <= 0 {
+			svidTTL = ca.DefaultX509SVIDTTL
+		}
+		if svidTTL > sc.MaxX509SVIDTTL {
+			return fmt.Errorf("default_svid_ttl (%v) must not exceed max_x509_svid_ttl (%v)", svidTTL, sc.MaxX509SVIDTTL)
+		}
+		if sc.AgentTTL > sc.MaxX509SVIDTTL {
+			return fmt.Errorf("agent_ttl (%v) must not exceed max_x509_svid_ttl (%v)", sc.AgentTTL, sc.MaxX509SVIDTTL)
+		}
+	}
+	if sc.MaxJWTSVIDTTL > 0 && ca.DefaultJWTSVIDTTL > sc.MaxJWTSVIDTTL {
+		return fmt.Errorf("max_jwt_svid_ttl (%v) must not be less than the default JWT-SVID TTL (%v)", sc.MaxJWTSVIDTTL, ca.DefaultJWTSVIDTTL)
+	}
+	return nil
+}
+
 func hasCompatibleTTLs(caTTL, svidTTL time.Duration) bool {
 	return ca.MaxSVIDTTLForCATTL(caTTL) >= svidTTL
 }
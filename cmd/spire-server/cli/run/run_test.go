@@ -2,8 +2,10 @@ package run
 
 import (
 	"bytes"
+	"crypto/tls"
 	"crypto/x509/pkix"
 	"io"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
@@ -17,6 +19,7 @@ import (
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
 	"github.com/spiffe/spire/pkg/common/catalog"
 	"github.com/spiffe/spire/pkg/common/log"
+	"github.com/spiffe/spire/pkg/common/tlspolicy"
 	"github.com/spiffe/spire/pkg/server"
 	bundleClient "github.com/spiffe/spire/pkg/server/bundle/client"
 	"github.com/spiffe/spire/pkg/server/ca"
@@ -24,6 +27,7 @@ import (
 	"github.com/spiffe/spire/test/spiretest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/square/go-jose.v2"
 )
 
 func TestParseConfigGood(t *testing.T) {
@@ -440,6 +444,16 @@ func TestMergeInput(t *testing.T) {
 				require.True(t, c.Server.AuditLogEnabled)
 			},
 		},
+		{
+			msg: "audit_log_path should be configurable by file",
+			fileInput: func(c *Config) {
+				c.Server.AuditLogPath = "/var/log/spire/audit.log"
+			},
+			cliFlags: []string{},
+			test: func(t *testing.T, c *Config) {
+				require.Equal(t, "/var/log/spire/audit.log", c.Server.AuditLogPath)
+			},
+		},
 	}
 
 	for _, testCase := range cases {
@@ -461,6 +475,8 @@ func TestMergeInput(t *testing.T) {
 }
 
 func TestNewServerConfig(t *testing.T) {
+	auditLogPath := filepath.Join(t.TempDir(), "audit.log")
+
 	cases := []struct {
 		msg         string
 		expectError bool
@@ -536,6 +552,32 @@ func TestNewServerConfig(t *testing.T) {
 				require.Equal(t, "ISSUER", c.JWTIssuer)
 			},
 		},
+		{
+			msg:   "jwt_allowed_signing_algs is not restricted by default",
+			input: func(c *Config) {},
+			test: func(t *testing.T, c *server.Config) {
+				require.Nil(t, c.JWTAllowedSigningAlgorithms)
+			},
+		},
+		{
+			msg: "jwt_allowed_signing_algs is correctly parsed",
+			input: func(c *Config) {
+				c.Server.JWTAllowedSigningAlgs = []string{"ES256", "rs256"}
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.Equal(t, []jose.SignatureAlgorithm{jose.ES256, jose.RS256}, c.JWTAllowedSigningAlgorithms)
+			},
+		},
+		{
+			msg:         "jwt_allowed_signing_algs with an unknown algorithm",
+			expectError: true,
+			input: func(c *Config) {
+				c.Server.JWTAllowedSigningAlgs = []string{"ES256", "HS256"}
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.Nil(t, c)
+			},
+		},
 		{
 			msg: "logger gets set correctly",
 			input: func(c *Config) {
@@ -595,8 +637,42 @@ func TestNewServerConfig(t *testing.T) {
 				}
 			},
 			test: func(t *testing.T, c *server.Config) {
-				require.Equal(t, "192.168.1.1", c.Federation.BundleEndpoint.Address.IP.String())
-				require.Equal(t, 1337, c.Federation.BundleEndpoint.Address.Port)
+				require.Equal(t, &net.TCPAddr{
+					IP:   net.ParseIP("192.168.1.1"),
+					Port: 1337,
+				}, c.Federation.BundleEndpoint.Address)
+			},
+		},
+		{
+			msg: "bundle endpoint allowed client IDs file is configured correctly",
+			input: func(c *Config) {
+				c.Server.Federation = &federationConfig{
+					BundleEndpoint: &bundleEndpointConfig{
+						Address:              "192.168.1.1",
+						Port:                 1337,
+						AllowedClientIDsFile: "/etc/spire/bundle-clients",
+					},
+				}
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.Equal(t, "/etc/spire/bundle-clients", c.Federation.BundleEndpoint.AllowedClientIDsFile)
+			},
+		},
+		{
+			msg: "bundle endpoint unix socket address is parsed and configured correctly",
+			input: func(c *Config) {
+				c.Server.Federation = &federationConfig{
+					BundleEndpoint: &bundleEndpointConfig{
+						Address: "unix:///tmp/spire-server/bundle.sock",
+					},
+				}
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.Equal(t, &net.UnixAddr{
+					Name: "/tmp/spire-server/bundle.sock",
+					Net:  "unix",
+				}, c.Federation.BundleEndpoint.Address)
+				require.Nil(t, c.Federation.BundleEndpoint.ACME)
 			},
 		},
 		{
@@ -624,6 +700,77 @@ func TestNewServerConfig(t *testing.T) {
 				}, c.Federation.FederatesWith)
 			},
 		},
+		{
+			msg: "https_web bundle endpoint profile with a CA bundle is parsed correctly",
+			input: func(c *Config) {
+				c.Server.Federation = &federationConfig{
+					FederatesWith: map[string]federatesWithConfig{
+						"domain2.test": webPKIWithCABundleConfigTest(t),
+					},
+				}
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.Equal(t, map[spiffeid.TrustDomain]bundleClient.TrustDomainConfig{
+					spiffeid.RequireTrustDomainFromString("domain2.test"): {
+						EndpointURL:     "https://192.168.1.1:1337",
+						EndpointProfile: bundleClient.HTTPSWebProfile{CABundlePath: "/path/to/ca.pem"},
+					},
+				}, c.Federation.FederatesWith)
+			},
+		},
+		{
+			msg: "federation refresh_interval is parsed correctly",
+			input: func(c *Config) {
+				config := webPKIConfigTest(t)
+				config.RefreshInterval = "1h"
+				c.Server.Federation = &federationConfig{
+					FederatesWith: map[string]federatesWithConfig{
+						"domain2.test": config,
+					},
+				}
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.Equal(t, map[spiffeid.TrustDomain]bundleClient.TrustDomainConfig{
+					spiffeid.RequireTrustDomainFromString("domain2.test"): {
+						EndpointURL:     "https://192.168.1.1:1337",
+						EndpointProfile: bundleClient.HTTPSWebProfile{},
+						RefreshInterval: time.Hour,
+					},
+				}, c.Federation.FederatesWith)
+			},
+		},
+		{
+			msg:         "invalid federation refresh_interval returns an error",
+			expectError: true,
+			input: func(c *Config) {
+				config := webPKIConfigTest(t)
+				config.RefreshInterval = "b"
+				c.Server.Federation = &federationConfig{
+					FederatesWith: map[string]federatesWithConfig{
+						"domain2.test": config,
+					},
+				}
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.Nil(t, c)
+			},
+		},
+		{
+			msg:         "federation refresh_interval below the minimum returns an error",
+			expectError: true,
+			input: func(c *Config) {
+				config := webPKIConfigTest(t)
+				config.RefreshInterval = "30s"
+				c.Server.Federation = &federationConfig{
+					FederatesWith: map[string]federatesWithConfig{
+						"domain2.test": config,
+					},
+				}
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.Nil(t, c)
+			},
+		},
 		{
 			msg: "default_svid_ttl is correctly parsed",
 			input: func(c *Config) {
@@ -633,6 +780,87 @@ func TestNewServerConfig(t *testing.T) {
 				require.Equal(t, time.Minute, c.SVIDTTL)
 			},
 		},
+		{
+			msg:   "max SVID TTLs are not set by default",
+			input: func(c *Config) {},
+			test: func(t *testing.T, c *server.Config) {
+				require.Zero(t, c.MaxX509SVIDTTL)
+				require.Zero(t, c.MaxJWTSVIDTTL)
+			},
+		},
+		{
+			msg: "max_x509_svid_ttl and max_jwt_svid_ttl are correctly parsed",
+			input: func(c *Config) {
+				c.Server.MaxX509SVIDTTL = "24h"
+				c.Server.MaxJWTSVIDTTL = "1h"
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.Equal(t, 24*time.Hour, c.MaxX509SVIDTTL)
+				require.Equal(t, time.Hour, c.MaxJWTSVIDTTL)
+			},
+		},
+		{
+			msg:         "invalid max_x509_svid_ttl returns an error",
+			expectError: true,
+			input: func(c *Config) {
+				c.Server.MaxX509SVIDTTL = "b"
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.Nil(t, c)
+			},
+		},
+		{
+			msg:         "default_svid_ttl above max_x509_svid_ttl returns an error",
+			expectError: true,
+			input: func(c *Config) {
+				c.Server.DefaultSVIDTTL = "2h"
+				c.Server.MaxX509SVIDTTL = "1h"
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.Nil(t, c)
+			},
+		},
+		{
+			msg:         "unset default_svid_ttl above max_x509_svid_ttl returns an error",
+			expectError: true,
+			input: func(c *Config) {
+				c.Server.MaxX509SVIDTTL = "30m"
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.Nil(t, c)
+			},
+		},
+		{
+			msg:         "agent_ttl above max_x509_svid_ttl returns an error",
+			expectError: true,
+			input: func(c *Config) {
+				c.Server.AgentTTL = "48h"
+				c.Server.MaxX509SVIDTTL = "24h"
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.Nil(t, c)
+			},
+		},
+		{
+			msg:         "max_jwt_svid_ttl below the default JWT-SVID TTL returns an error",
+			expectError: true,
+			input: func(c *Config) {
+				c.Server.MaxJWTSVIDTTL = "1m"
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.Nil(t, c)
+			},
+		},
+		{
+			msg:         "non-positive max_jwt_svid_ttl returns an error",
+			expectError: true,
+			input: func(c *Config) {
+				c.Server.MaxJWTSVIDTTL = "0s"
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.Nil(t, c)
+			},
+		},
 		{
 			msg:         "invalid default_svid_ttl returns an error",
 			expectError: true,
@@ -938,6 +1166,142 @@ func TestNewServerConfig(t *testing.T) {
 				require.False(t, c.AuditLogEnabled)
 			},
 		},
+		{
+			msg:   "audit logs go to the server log by default",
+			input: func(c *Config) {},
+			test: func(t *testing.T, c *server.Config) {
+				require.Nil(t, c.AuditLog)
+			},
+		},
+		{
+			msg: "audit_log_path sends audit logs to a file",
+			input: func(c *Config) {
+				c.Server.AuditLogEnabled = true
+				c.Server.AuditLogPath = auditLogPath
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.NotNil(t, c.AuditLog)
+				c.AuditLog.Info("API accessed")
+
+				data, err := os.ReadFile(auditLogPath)
+				require.NoError(t, err)
+				require.Contains(t, string(data), `"msg":"API accessed"`)
+			},
+		},
+		{
+			msg:   "admin_api reflection is disabled by default",
+			input: func(c *Config) {},
+			test: func(t *testing.T, c *server.Config) {
+				require.False(t, c.AdminAPIReflectionEnabled)
+			},
+		},
+		{
+			msg: "admin_api reflection is enabled",
+			input: func(c *Config) {
+				c.Server.AdminAPI.EnableReflection = true
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.True(t, c.AdminAPIReflectionEnabled)
+			},
+		},
+		{
+			msg: "crl_distribution_point is set",
+			input: func(c *Config) {
+				c.Server.CRLDistPoint = "http://crl.example.org/spire.crl"
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.Equal(t, "http://crl.example.org/spire.crl", c.CRLDistributionPoint)
+			},
+		},
+		{
+			msg: "upstream_authority_order is set",
+			input: func(c *Config) {
+				c.Server.UpstreamAuthorityOrder = []string{"vault", "disk"}
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.Equal(t, []string{"vault", "disk"}, c.UpstreamAuthorityOrder)
+			},
+		},
+		{
+			msg: "datastore_max_attempts is set",
+			input: func(c *Config) {
+				c.Server.DataStoreMaxAttempts = 3
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.Equal(t, 3, c.DataStoreMaxAttempts)
+			},
+		},
+		{
+			msg: "max message sizes are set",
+			input: func(c *Config) {
+				c.Server.MaxRecvMsgSize = 32 * 1024 * 1024
+				c.Server.MaxSendMsgSize = 8 * 1024 * 1024
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.Equal(t, 32*1024*1024, c.MaxRecvMessageSize)
+				require.Equal(t, 8*1024*1024, c.MaxSendMessageSize)
+			},
+		},
+		{
+			msg:   "TLS policy defaults",
+			input: func(c *Config) {},
+			test: func(t *testing.T, c *server.Config) {
+				require.Equal(t, tlspolicy.Policy{}, c.TLSPolicy)
+			},
+		},
+		{
+			msg: "TLS policy is set",
+			input: func(c *Config) {
+				c.Server.TLSMinVersion = "1.2"
+				c.Server.TLSCipherSuites = []string{"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384", "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"}
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.Equal(t, tlspolicy.Policy{
+					MinVersion:   tls.VersionTLS12,
+					CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384, tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384},
+				}, c.TLSPolicy)
+			},
+		},
+		{
+			msg: "TLS 1.3 minimum version is set",
+			input: func(c *Config) {
+				c.Server.TLSMinVersion = "1.3"
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.Equal(t, tlspolicy.Policy{MinVersion: tls.VersionTLS13}, c.TLSPolicy)
+			},
+		},
+		{
+			msg:         "invalid tls_min_version should return an error",
+			expectError: true,
+			input: func(c *Config) {
+				c.Server.TLSMinVersion = "1.1"
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.Nil(t, c)
+			},
+		},
+		{
+			msg:         "unknown tls_cipher_suites should return an error",
+			expectError: true,
+			input: func(c *Config) {
+				c.Server.TLSCipherSuites = []string{"TLS_NOT_A_CIPHER_SUITE"}
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.Nil(t, c)
+			},
+		},
+		{
+			msg:         "tls_cipher_suites with TLS 1.3 minimum version should return an error",
+			expectError: true,
+			input: func(c *Config) {
+				c.Server.TLSMinVersion = "1.3"
+				c.Server.TLSCipherSuites = []string{"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"}
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.Nil(t, c)
+			},
+		},
 		{
 			msg: "admin IDs are set",
 			input: func(c *Config) {
@@ -1041,6 +1405,97 @@ func TestValidateConfig(t *testing.T) {
 			applyConf:   func(c *Config) { c.Plugins = nil },
 			expectedErr: "plugins section must be configured",
 		},
+		{
+			name:        "audit_log_path requires audit_log_enabled",
+			applyConf:   func(c *Config) { c.Server.AuditLogPath = "audit.log" },
+			expectedErr: "audit_log_path requires audit_log_enabled",
+		},
+		{
+			name:        "crl_distribution_point must be an absolute URL",
+			applyConf:   func(c *Config) { c.Server.CRLDistPoint = "spire.crl" },
+			expectedErr: `crl_distribution_point "spire.crl" must be an absolute URL`,
+		},
+		{
+			name:        "datastore_max_attempts must not be negative",
+			applyConf:   func(c *Config) { c.Server.DataStoreMaxAttempts = -1 },
+			expectedErr: "datastore_max_attempts must not be negative",
+		},
+		{
+			name:        "max_recv_message_size must not be negative",
+			applyConf:   func(c *Config) { c.Server.MaxRecvMsgSize = -1 },
+			expectedErr: "max_recv_message_size must not be negative",
+		},
+		{
+			name:        "max_send_message_size must not be negative",
+			applyConf:   func(c *Config) { c.Server.MaxSendMsgSize = -1 },
+			expectedErr: "max_send_message_size must not be negative",
+		},
+		{
+			name: "federation.bundle_endpoint unix address must include a socket path",
+			applyConf: func(c *Config) {
+				c.Server.Federation = &federationConfig{
+					BundleEndpoint: &bundleEndpointConfig{
+						Address: "unix://",
+					},
+				}
+			},
+			expectedErr: "federation.bundle_endpoint.address must include a socket path when using a unix:// address",
+		},
+		{
+			name: "federation.bundle_endpoint.port cannot be set with a unix address",
+			applyConf: func(c *Config) {
+				c.Server.Federation = &federationConfig{
+					BundleEndpoint: &bundleEndpointConfig{
+						Address: "unix:///tmp/bundle.sock",
+						Port:    8443,
+					},
+				}
+			},
+			expectedErr: "federation.bundle_endpoint.port cannot be set when using a unix:// address",
+		},
+		{
+			name: "federation.bundle_endpoint.acme cannot be configured with a unix address",
+			applyConf: func(c *Config) {
+				c.Server.Federation = &federationConfig{
+					BundleEndpoint: &bundleEndpointConfig{
+						Address: "unix:///tmp/bundle.sock",
+						ACME: &bundleEndpointACMEConfig{
+							DomainName: "example.org",
+							Email:      "mail@example.org",
+						},
+					},
+				}
+			},
+			expectedErr: "federation.bundle_endpoint.acme cannot be configured when using a unix:// address",
+		},
+		{
+			name: "federation.bundle_endpoint.allowed_client_ids_file cannot be set with a unix address",
+			applyConf: func(c *Config) {
+				c.Server.Federation = &federationConfig{
+					BundleEndpoint: &bundleEndpointConfig{
+						Address:              "unix:///tmp/bundle.sock",
+						AllowedClientIDsFile: "/etc/spire/bundle-clients",
+					},
+				}
+			},
+			expectedErr: "federation.bundle_endpoint.allowed_client_ids_file cannot be set when using a unix:// address",
+		},
+		{
+			name: "federation.bundle_endpoint.allowed_client_ids_file cannot be set with ACME",
+			applyConf: func(c *Config) {
+				c.Server.Federation = &federationConfig{
+					BundleEndpoint: &bundleEndpointConfig{
+						Address:              "0.0.0.0",
+						AllowedClientIDsFile: "/etc/spire/bundle-clients",
+						ACME: &bundleEndpointACMEConfig{
+							DomainName: "example.org",
+							Email:      "mail@example.org",
+						},
+					},
+				}
+			},
+			expectedErr: "federation.bundle_endpoint.allowed_client_ids_file cannot be set when using ACME",
+		},
 		{
 			name: "if ACME is used, federation.bundle_endpoint.acme.domain_name must be configured",
 			applyConf: func(c *Config) {
@@ -1150,6 +1605,16 @@ func TestWarnOnUnknownConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			msg:      "in admin_api block",
+			confFile: "server_bad_admin_api_block.conf",
+			expectedLogEntries: []logEntry{
+				{
+					section: "admin_api",
+					keys:    "unknown_option1,unknown_option2",
+				},
+			},
+		},
 		{
 			msg:      "in ratelimit block",
 			confFile: "server_bad_ratelimit_block.conf",
@@ -1558,6 +2023,41 @@ func TestAgentTTL(t *testing.T) {
 	}
 }
 
+func TestDeadNodeTTL(t *testing.T) {
+	for _, c := range []struct {
+		deadNodeTTL      string
+		expectedDuration time.Duration
+		expectedErr      string
+	}{
+		{
+			deadNodeTTL:      "24h",
+			expectedDuration: 24 * time.Hour,
+		},
+		{
+			deadNodeTTL:      "",
+			expectedDuration: 0,
+		},
+		{
+			deadNodeTTL: "forever",
+			expectedErr: `could not parse dead_node_ttl "forever"`,
+		},
+		{
+			deadNodeTTL: "-1h",
+			expectedErr: "dead_node_ttl cannot be negative",
+		},
+	} {
+		config := defaultValidConfig()
+		config.Server.DeadNodeTTL = c.deadNodeTTL
+		sconfig, err := NewServerConfig(config, []log.Option{}, false)
+		if c.expectedErr != "" {
+			spiretest.AssertErrorContains(t, err, c.expectedErr)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, c.expectedDuration, sconfig.DeadNodeTTL)
+	}
+}
+
 func httpsSPIFFEConfigTest(t *testing.T) federatesWithConfig {
 	configString := `bundle_endpoint_url = "https://192.168.1.1:1337"
 	bundle_endpoint_profile "https_spiffe" {
@@ -1577,3 +2077,14 @@ func webPKIConfigTest(t *testing.T) federatesWithConfig {
 
 	return *webPKIConfig
 }
+
+func webPKIWithCABundleConfigTest(t *testing.T) federatesWithConfig {
+	configString := `bundle_endpoint_url = "https://192.168.1.1:1337"
+		bundle_endpoint_profile "https_web" {
+			ca_bundle_path = "/path/to/ca.pem"
+		}`
+	webPKIConfig := new(federatesWithConfig)
+	require.NoError(t, hcl.Decode(webPKIConfig, configString))
+
+	return *webPKIConfig
+}
@@ -0,0 +1,53 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// OutputPretty is the human readable output format
+	OutputPretty = "pretty"
+
+	// OutputJSON is the machine readable JSON output format
+	OutputJSON = "json"
+)
+
+// OutputFlagUsage is the usage string for the "-output" flag
+const OutputFlagUsage = "Output format. Options: pretty and json"
+
+// ValidateOutputFormat returns an error if the format is not a supported
+// output format.
+func ValidateOutputFormat(format string) error {
+	switch format {
+	case OutputPretty, OutputJSON:
+		return nil
+	default:
+		return fmt.Errorf("invalid output format %q: must be %q or %q", format, OutputPretty, OutputJSON)
+	}
+}
+
+// PrintProtoJSON writes the message to w as indented JSON using the proto
+// field names. Unset fields are included so the keys present do not depend
+// on the data. The protojson output is intentionally unstable, so it is
+// reindented to keep the output stable for scripts.
+func PrintProtoJSON(w io.Writer, m proto.Message) error {
+	out, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("unable to marshal output: %w", err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := json.Indent(buf, out, "", "  "); err != nil {
+		return fmt.Errorf("unable to marshal output: %w", err)
+	}
+	buf.WriteByte('\n')
+
+	_, err = buf.WriteTo(w)
+	return err
+}
@@ -177,7 +177,7 @@ func (a *Adapter) Synopsis() string {
 // Everything to the right of the first ":" is considered a selector value.
 func ParseSelector(str string) (*api_types.Selector, error) {
 	parts := strings.SplitAfterN(str, ":", 2)
-	if len(parts) < 2 {
+	if len(parts) < 2 || parts[0] == ":" || parts[1] == "" {
 		return nil, fmt.Errorf("selector \"%s\" must be formatted as type:value", str)
 	}
 
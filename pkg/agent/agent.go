@@ -111,7 +111,7 @@ func (a *Agent) Run(ctx context.Context) error {
 	}
 
 	if a.c.AdminBindAddress != nil {
-		adminEndpoints := a.newAdminEndpoints(manager, workloadAttestor, a.c.AuthorizedDelegates)
+		adminEndpoints := a.newAdminEndpoints(manager, workloadAttestor, a.c.AuthorizedDelegates, a.c.AuthorizedDelegateSelectors)
 		tasks = append(tasks, adminEndpoints.ListenAndServe)
 	}
 
@@ -190,6 +190,9 @@ func (a *Agent) attest(ctx context.Context, cat catalog.Catalog, metrics telemet
 		SVIDCachePath:     a.agentSVIDPath(),
 		Log:               a.c.Log.WithField(telemetry.SubsystemName, telemetry.Attestor),
 		ServerAddress:     a.c.ServerAddress,
+
+		MaxRecvMessageSize: a.c.MaxRecvMessageSize,
+		MaxSendMessageSize: a.c.MaxSendMessageSize,
 	}
 	return node_attestor.New(&config).Attest(ctx)
 }
@@ -208,6 +211,9 @@ func (a *Agent) newManager(ctx context.Context, cat catalog.Catalog, metrics tel
 		SVIDCachePath:   a.agentSVIDPath(),
 		SyncInterval:    a.c.SyncInterval,
 		SVIDStoreCache:  cache,
+
+		MaxRecvMessageSize: a.c.MaxRecvMessageSize,
+		MaxSendMessageSize: a.c.MaxSendMessageSize,
 	}
 
 	mgr := manager.New(config)
@@ -251,11 +257,18 @@ func (a *Agent) newEndpoints(metrics telemetry.Metrics, mgr manager.Manager, att
 		DefaultAllBundlesName:         a.c.DefaultAllBundlesName,
 		AllowUnauthenticatedVerifiers: a.c.AllowUnauthenticatedVerifiers,
 		AllowedForeignJWTClaims:       a.c.AllowedForeignJWTClaims,
+		AllowedJWTAudienceWildcards:   a.c.AllowedJWTAudienceWildcards,
+		JWTSVIDValidationLeeway:       a.c.JWTSVIDValidationLeeway,
 		TrustDomain:                   a.c.TrustDomain,
+		WorkloadAPIRateLimit: endpoints.RateLimitConfig{
+			RequestsPerSecond: a.c.WorkloadAPIMaxRequestsPerSecond,
+			Burst:             a.c.WorkloadAPIBurst,
+		},
+		ShutdownGracePeriod: a.c.WorkloadAPIShutdownGracePeriod,
 	})
 }
 
-func (a *Agent) newAdminEndpoints(mgr manager.Manager, attestor workload_attestor.Attestor, authorizedDelegates []string) admin_api.Server {
+func (a *Agent) newAdminEndpoints(mgr manager.Manager, attestor workload_attestor.Attestor, authorizedDelegates []string, authorizedDelegateSelectors map[string][]string) admin_api.Server {
 	config := &admin_api.Config{
 		BindAddr:            a.c.AdminBindAddress,
 		Manager:             mgr,
@@ -264,6 +277,8 @@ func (a *Agent) newAdminEndpoints(mgr manager.Manager, attestor workload_attesto
 		Uptime:              uptime.Uptime,
 		Attestor:            attestor,
 		AuthorizedDelegates: authorizedDelegates,
+
+		AuthorizedDelegateSelectors: authorizedDelegateSelectors,
 	}
 
 	return admin_api.New(config)
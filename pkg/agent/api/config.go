@@ -26,6 +26,10 @@ type Config struct {
 	Attestor attestor.Attestor
 
 	AuthorizedDelegates []string
+
+	// AuthorizedDelegateSelectors restricts the selectors that authorized
+	// delegates can request SVIDs for, keyed by delegate SPIFFE ID.
+	AuthorizedDelegateSelectors map[string][]string
 }
 
 func New(c *Config) *Endpoints {
@@ -5,6 +5,7 @@ import (
 	"crypto/x509"
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 	delegatedidentityv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/agent/delegatedidentity/v1"
@@ -37,6 +38,12 @@ type Config struct {
 	Manager             manager.Manager
 	Attestor            workload_attestor.Attestor
 	AuthorizedDelegates []string
+
+	// AuthorizedDelegateSelectors restricts the selectors that authorized
+	// delegates can request SVIDs for, keyed by delegate SPIFFE ID. Each
+	// pattern is in the "type:value" form, and a trailing "*" matches any
+	// value suffix. Delegates without patterns are not restricted.
+	AuthorizedDelegateSelectors map[string][]string
 }
 
 func New(config Config) *Service {
@@ -50,6 +57,7 @@ func New(config Config) *Service {
 		manager:             config.Manager,
 		attestor:            endpoints.PeerTrackerAttestor{Attestor: config.Attestor},
 		authorizedDelegates: AuthorizedDelegates,
+		delegateSelectors:   config.AuthorizedDelegateSelectors,
 	}
 }
 
@@ -62,10 +70,16 @@ type Service struct {
 
 	// SPIFFE IDs of delegates that are authorized to use this API
 	authorizedDelegates map[string]bool
+
+	// Selector patterns restricting the selectors each delegate can request
+	// SVIDs for, keyed by delegate SPIFFE ID
+	delegateSelectors map[string][]string
 }
 
 // isCallerAuthorized attests the caller based on the authorized delegates map.
-func (s *Service) isCallerAuthorized(ctx context.Context, log logrus.FieldLogger, cachedSelectors []*common.Selector) ([]*common.Selector, error) {
+// It returns the selectors of the caller and the authorized delegate SPIFFE
+// IDs the caller has.
+func (s *Service) isCallerAuthorized(ctx context.Context, log logrus.FieldLogger, cachedSelectors []*common.Selector) ([]*common.Selector, []string, error) {
 	var err error
 	callerSelectors := cachedSelectors
 
@@ -73,7 +87,7 @@ func (s *Service) isCallerAuthorized(ctx context.Context, log logrus.FieldLogger
 		callerSelectors, err = s.attestor.Attest(ctx)
 		if err != nil {
 			log.WithError(err).Error("Workload attestation failed")
-			return nil, status.Error(codes.Internal, "workload attestation failed")
+			return nil, nil, status.Error(codes.Internal, "workload attestation failed")
 		}
 	}
 
@@ -82,14 +96,18 @@ func (s *Service) isCallerAuthorized(ctx context.Context, log logrus.FieldLogger
 
 	if numRegisteredIDs == 0 {
 		log.Error("no identity issued")
-		return nil, status.Error(codes.PermissionDenied, "no identity issued")
+		return nil, nil, status.Error(codes.PermissionDenied, "no identity issued")
 	}
 
+	var delegates []string
 	for _, identity := range identities {
 		if _, ok := s.authorizedDelegates[identity.Entry.SpiffeId]; ok {
-			return callerSelectors, nil
+			delegates = append(delegates, identity.Entry.SpiffeId)
 		}
 	}
+	if len(delegates) > 0 {
+		return callerSelectors, delegates, nil
+	}
 
 	// caller has identity associeted with but none is authorized
 	log.WithFields(logrus.Fields{
@@ -97,13 +115,55 @@ func (s *Service) isCallerAuthorized(ctx context.Context, log logrus.FieldLogger
 		"default_id":         identities[0].Entry.SpiffeId,
 	}).Error("Permission denied; caller not configured as an authorized delegate.")
 
-	return nil, status.Error(codes.PermissionDenied, "caller not configured as an authorized delegate")
+	return nil, nil, status.Error(codes.PermissionDenied, "caller not configured as an authorized delegate")
+}
+
+// checkSelectorsScope verifies that at least one of the delegates the caller
+// is authorized as can request SVIDs for all of the given selectors.
+func (s *Service) checkSelectorsScope(log logrus.FieldLogger, delegates []string, selectors []*common.Selector) error {
+	for _, delegate := range delegates {
+		patterns, ok := s.delegateSelectors[delegate]
+		if !ok || selectorsMatchPatterns(selectors, patterns) {
+			return nil
+		}
+	}
+
+	log.WithField("delegates", strings.Join(delegates, ",")).Error("Permission denied; selectors are outside the scope of the delegate")
+	return status.Error(codes.PermissionDenied, "selectors are outside the scope of the caller")
+}
+
+// selectorsMatchPatterns returns true if every selector matches at least one
+// of the patterns.
+func selectorsMatchPatterns(selectors []*common.Selector, patterns []string) bool {
+	for _, selector := range selectors {
+		matched := false
+		for _, pattern := range patterns {
+			if selectorMatchesPattern(selector, pattern) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// selectorMatchesPattern returns true if the selector matches the pattern, in
+// the "type:value" form. A trailing "*" in the pattern matches any suffix.
+func selectorMatchesPattern(selector *common.Selector, pattern string) bool {
+	value := selector.Type + ":" + selector.Value
+	if prefix := strings.TrimSuffix(pattern, "*"); prefix != pattern {
+		return strings.HasPrefix(value, prefix)
+	}
+	return value == pattern
 }
 
 func (s *Service) SubscribeToX509SVIDs(req *delegatedidentityv1.SubscribeToX509SVIDsRequest, stream delegatedidentityv1.DelegatedIdentity_SubscribeToX509SVIDsServer) error {
 	ctx := stream.Context()
 	log := rpccontext.Logger(ctx)
-	cachedSelectors, err := s.isCallerAuthorized(ctx, log, nil)
+	cachedSelectors, delegates, err := s.isCallerAuthorized(ctx, log, nil)
 
 	if err != nil {
 		return err
@@ -115,13 +175,21 @@ func (s *Service) SubscribeToX509SVIDs(req *delegatedidentityv1.SubscribeToX509S
 		return status.Error(codes.InvalidArgument, "could not parse provided selectors")
 	}
 
+	if err := s.checkSelectorsScope(log, delegates, selectors); err != nil {
+		return err
+	}
+
 	subscriber := s.manager.SubscribeToCacheChanges(selectors)
 	defer subscriber.Finish()
 
 	for {
 		select {
 		case update := <-subscriber.Updates():
-			if _, err := s.isCallerAuthorized(ctx, log, cachedSelectors); err != nil {
+			_, delegates, err := s.isCallerAuthorized(ctx, log, cachedSelectors)
+			if err != nil {
+				return err
+			}
+			if err := s.checkSelectorsScope(log, delegates, selectors); err != nil {
 				return err
 			}
 
@@ -198,7 +266,7 @@ func composeX509SVIDBySelectors(update *cache.WorkloadUpdate) (*delegatedidentit
 func (s *Service) SubscribeToX509Bundles(req *delegatedidentityv1.SubscribeToX509BundlesRequest, stream delegatedidentityv1.DelegatedIdentity_SubscribeToX509BundlesServer) error {
 	ctx := stream.Context()
 	log := rpccontext.Logger(ctx)
-	cachedSelectors, err := s.isCallerAuthorized(ctx, log, nil)
+	cachedSelectors, _, err := s.isCallerAuthorized(ctx, log, nil)
 
 	if err != nil {
 		return err
@@ -223,7 +291,7 @@ func (s *Service) SubscribeToX509Bundles(req *delegatedidentityv1.SubscribeToX50
 	for {
 		select {
 		case <-subscriber.Changes():
-			if _, err := s.isCallerAuthorized(ctx, log, cachedSelectors); err != nil {
+			if _, _, err := s.isCallerAuthorized(ctx, log, cachedSelectors); err != nil {
 				return err
 			}
 
@@ -60,6 +60,8 @@ func TestSubscribeToX509SVIDs(t *testing.T) {
 		identities   []cache.Identity
 		updates      []*cache.WorkloadUpdate
 		authSpiffeID []string
+		authScopes   map[string][]string
+		reqSelectors []*types.Selector
 		expectCode   codes.Code
 		expectMsg    string
 		attestErr    error
@@ -81,6 +83,49 @@ func TestSubscribeToX509SVIDs(t *testing.T) {
 			expectCode: codes.PermissionDenied,
 			expectMsg:  "caller not configured as an authorized delegate",
 		},
+		{
+			testName:     "selectors outside the scope of the delegate",
+			authSpiffeID: []string{"spiffe://example.org/one"},
+			authScopes: map[string][]string{
+				"spiffe://example.org/one": {"sa:bar", "ns:*"},
+			},
+			reqSelectors: []*types.Selector{{Type: "sa", Value: "foo"}, {Type: "ns", Value: "default"}},
+			identities: []cache.Identity{
+				identityFromX509SVID(x509SVID1),
+			},
+			expectCode: codes.PermissionDenied,
+			expectMsg:  "selectors are outside the scope of the caller",
+		},
+		{
+			testName:     "selectors in the scope of the delegate",
+			authSpiffeID: []string{"spiffe://example.org/one"},
+			authScopes: map[string][]string{
+				"spiffe://example.org/one": {"sa:foo", "ns:*"},
+			},
+			reqSelectors: []*types.Selector{{Type: "sa", Value: "foo"}, {Type: "ns", Value: "default"}},
+			identities: []cache.Identity{
+				identityFromX509SVID(x509SVID1),
+			},
+			updates: []*cache.WorkloadUpdate{
+				{Identities: []cache.Identity{
+					identityFromX509SVID(x509SVID1),
+				},
+					Bundle: utilBundleFromBundle(t, bundle),
+				},
+			},
+			expectResp: &delegatedidentityv1.SubscribeToX509SVIDsResponse{
+				X509Svids: []*delegatedidentityv1.X509SVIDWithKey{
+					{
+						X509Svid: &types.X509SVID{
+							Id:        utilIDProtoFromString(t, x509SVID1.ID.String()),
+							CertChain: x509util.RawCertsFromCertificates(x509SVID1.Certificates),
+							ExpiresAt: x509SVID1.Certificates[0].NotAfter.Unix(),
+						},
+						X509SvidKey: pkcs8FromSigner(t, x509SVID1.PrivateKey),
+					},
+				},
+			},
+		},
 		{
 			testName:     "workload update with one identity",
 			authSpiffeID: []string{"spiffe://example.org/one"},
@@ -233,12 +278,16 @@ func TestSubscribeToX509SVIDs(t *testing.T) {
 				Identities:   tt.identities,
 				Updates:      tt.updates,
 				AuthSpiffeID: tt.authSpiffeID,
+				AuthScopes:   tt.authScopes,
 				AttestErr:    tt.attestErr,
 				ManagerErr:   tt.managerErr,
 			}
 			runTest(t, params,
 				func(ctx context.Context, client delegatedidentityv1.DelegatedIdentityClient) {
 					selectors := []*types.Selector{{Type: "sa", Value: "foo"}}
+					if tt.reqSelectors != nil {
+						selectors = tt.reqSelectors
+					}
 					req := &delegatedidentityv1.SubscribeToX509SVIDsRequest{
 						Selectors: selectors,
 					}
@@ -357,6 +406,7 @@ type testParams struct {
 	Updates      []*cache.WorkloadUpdate
 	CacheUpdates map[spiffeid.TrustDomain]*cache.Bundle
 	AuthSpiffeID []string
+	AuthScopes   map[string][]string
 	AttestErr    error
 	ManagerErr   error
 }
@@ -378,6 +428,8 @@ func runTest(t *testing.T, params testParams, fn func(ctx context.Context, clien
 		Log:                 log,
 		Manager:             manager,
 		AuthorizedDelegates: params.AuthSpiffeID,
+
+		AuthorizedDelegateSelectors: params.AuthScopes,
 	})
 
 	service.attestor = FakeAttestor{
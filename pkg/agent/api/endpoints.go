@@ -78,6 +78,8 @@ func (e *Endpoints) registerDelegatedIdentityAPI(server *grpc.Server) {
 		Attestor:            e.c.Attestor,
 		AuthorizedDelegates: e.c.AuthorizedDelegates,
 		Log:                 e.c.Log.WithField(telemetry.SubsystemName, telemetry.DelegatedIdentityAPI),
+
+		AuthorizedDelegateSelectors: e.c.AuthorizedDelegateSelectors,
 	})
 
 	delegatedidentityv1.RegisterService(server, service)
@@ -49,6 +49,10 @@ type Config struct {
 	SVIDCachePath     string
 	Log               logrus.FieldLogger
 	ServerAddress     string
+
+	// Maximum size in bytes of the messages exchanged with the server
+	MaxRecvMessageSize int
+	MaxSendMessageSize int
 }
 
 type attestor struct {
@@ -218,9 +222,11 @@ func (a *attestor) newSVID(ctx context.Context, key keymanager.Key, bundle *bund
 func (a *attestor) serverConn(ctx context.Context, bundle *bundleutil.Bundle) (*grpc.ClientConn, error) {
 	if bundle != nil {
 		return client.DialServer(ctx, client.DialServerConfig{
-			Address:     a.c.ServerAddress,
-			TrustDomain: a.c.TrustDomain,
-			GetBundle:   bundle.RootCAs,
+			Address:            a.c.ServerAddress,
+			TrustDomain:        a.c.TrustDomain,
+			GetBundle:          bundle.RootCAs,
+			MaxRecvMessageSize: a.c.MaxRecvMessageSize,
+			MaxSendMessageSize: a.c.MaxSendMessageSize,
 		})
 	}
 
@@ -63,6 +63,11 @@ type Config struct {
 
 	// RotMtx is used to prevent the creation of new connections during SVID rotations
 	RotMtx *sync.RWMutex
+
+	// MaxRecvMessageSize and MaxSendMessageSize limit the size in bytes of
+	// the messages exchanged with the server. See DialServerConfig.
+	MaxRecvMessageSize int
+	MaxSendMessageSize int
 }
 
 type client struct {
@@ -300,7 +305,9 @@ func (c *client) dial(ctx context.Context) (*grpc.ClientConn, error) {
 			}
 			return agentCert
 		},
-		dialContext: c.dialContext,
+		MaxRecvMessageSize: c.c.MaxRecvMessageSize,
+		MaxSendMessageSize: c.c.MaxSendMessageSize,
+		dialContext:        c.dialContext,
 	})
 }
 
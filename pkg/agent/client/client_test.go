@@ -5,6 +5,8 @@ import (
 	"crypto"
 	"crypto/x509"
 	"errors"
+	"fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -17,11 +19,13 @@ import (
 	svidv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/svid/v1"
 	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
 	"github.com/spiffe/spire/proto/spire/common"
+	"github.com/spiffe/spire/test/spiretest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 var (
@@ -708,6 +712,67 @@ func TestFetchJWTSVID(t *testing.T) {
 	}
 }
 
+func TestFetchEntriesOverDefaultGRPCMessageSize(t *testing.T) {
+	// Build a response larger than the gRPC default limit of 4 MiB
+	var entries []*types.Entry
+	for i := 0; i < 6*1024; i++ {
+		entries = append(entries, &types.Entry{
+			Id:        fmt.Sprintf("ENTRYID%d", i),
+			ParentId:  &types.SPIFFEID{TrustDomain: "example.org", Path: "/host"},
+			SpiffeId:  &types.SPIFFEID{TrustDomain: "example.org", Path: fmt.Sprintf("/id%d", i)},
+			Selectors: []*types.Selector{{Type: "S", Value: strings.Repeat("x", 1024)}},
+		})
+	}
+	socketPath := spiretest.StartGRPCSocketServerOnTempSocket(t, func(s *grpc.Server) {
+		entryv1.RegisterEntryServer(s, &fakeEntryServer{entries: entries})
+	})
+
+	for _, tt := range []struct {
+		name               string
+		maxRecvMessageSize int
+		expectCode         codes.Code
+	}{
+		{
+			name: "default",
+		},
+		{
+			name:               "configured",
+			maxRecvMessageSize: 8 * 1024 * 1024,
+		},
+		{
+			name:               "too small",
+			maxRecvMessageSize: 4 * 1024 * 1024,
+			expectCode:         codes.ResourceExhausted,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			client := newClient(&Config{
+				Addr:               "unix://" + socketPath,
+				Log:                log,
+				KeysAndBundle:      keysAndBundle,
+				RotMtx:             new(sync.RWMutex),
+				TrustDomain:        trustDomain,
+				MaxRecvMessageSize: tt.maxRecvMessageSize,
+			})
+			t.Cleanup(client.Release)
+			client.dialContext = func(ctx context.Context, addr string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+				// keep the provided options but dial without TLS
+				opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+				return grpc.DialContext(ctx, addr, opts...)
+			}
+
+			resp, err := client.fetchEntries(context.Background())
+			if tt.expectCode != codes.OK {
+				require.Equal(t, tt.expectCode, status.Code(errors.Unwrap(err)))
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, resp, len(entries))
+		})
+	}
+}
+
 // createClient creates a sample client with mocked components for testing purposes
 func createClient() (*client, *testClient) {
 	tc := &testClient{
@@ -759,6 +824,17 @@ func assertConnectionIsNotNil(t *testing.T, client *client) {
 	client.m.Unlock()
 }
 
+type fakeEntryServer struct {
+	entryv1.UnimplementedEntryServer
+	entries []*types.Entry
+}
+
+func (s *fakeEntryServer) GetAuthorizedEntries(ctx context.Context, in *entryv1.GetAuthorizedEntriesRequest) (*entryv1.GetAuthorizedEntriesResponse, error) {
+	return &entryv1.GetAuthorizedEntriesResponse{
+		Entries: s.entries,
+	}, nil
+}
+
 type fakeEntryClient struct {
 	entryv1.EntryClient
 	entries []*types.Entry
@@ -22,6 +22,12 @@ import (
 
 const (
 	_defaultDialTimeout = 30 * time.Second
+
+	// DefaultMaxRecvMessageSize is the default maximum size in bytes of the
+	// messages the agent receives from the server. It is larger than the gRPC
+	// default of 4 MiB so that syncing the entries of agents with thousands
+	// of entries does not fail.
+	DefaultMaxRecvMessageSize = 16 * 1024 * 1024
 )
 
 type DialServerConfig struct {
@@ -38,6 +44,14 @@ type DialServerConfig struct {
 	// certificate to present to the server during the TLS handshake.
 	GetAgentCertificate func() *tls.Certificate
 
+	// MaxRecvMessageSize is the maximum size in bytes of the messages
+	// received from the server. Defaults to DefaultMaxRecvMessageSize.
+	MaxRecvMessageSize int
+
+	// MaxSendMessageSize is the maximum size in bytes of the messages sent to
+	// the server. Defaults to the gRPC default.
+	MaxSendMessageSize int
+
 	// dialContext is an optional constructor for the grpc client connection.
 	dialContext func(ctx context.Context, target string, opts ...grpc.DialOption) (*grpc.ClientConn, error)
 }
@@ -63,6 +77,16 @@ func DialServer(ctx context.Context, config DialServerConfig) (*grpc.ClientConn,
 	if config.dialContext == nil {
 		config.dialContext = grpc.DialContext
 	}
+
+	maxRecvMessageSize := config.MaxRecvMessageSize
+	if maxRecvMessageSize == 0 {
+		maxRecvMessageSize = DefaultMaxRecvMessageSize
+	}
+	callOptions := []grpc.CallOption{grpc.MaxCallRecvMsgSize(maxRecvMessageSize)}
+	if config.MaxSendMessageSize > 0 {
+		callOptions = append(callOptions, grpc.MaxCallSendMsgSize(config.MaxSendMessageSize))
+	}
+
 	client, err := config.dialContext(ctx, config.Address,
 		// TODO: port to non-deprecated option
 		grpc.WithBalancerName(roundrobin.Name), //nolint:staticcheck // not ready to port
@@ -70,6 +94,7 @@ func DialServer(ctx context.Context, config DialServerConfig) (*grpc.ClientConn,
 		grpc.WithBlock(),
 		grpc.WithReturnConnectionError(),
 		grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)),
+		grpc.WithDefaultCallOptions(callOptions...),
 	)
 	switch {
 	case err == nil:
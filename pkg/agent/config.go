@@ -45,6 +45,11 @@ type Config struct {
 	// Address of SPIRE server
 	ServerAddress string
 
+	// Maximum size in bytes of the messages received from and sent to the
+	// SPIRE server. Zero means the default.
+	MaxRecvMessageSize int
+	MaxSendMessageSize int
+
 	// SyncInterval controls how often the agent sync synchronizer waits
 	SyncInterval time.Duration
 
@@ -75,7 +80,33 @@ type Config struct {
 	// List of allowed claims response when calling ValidateJWTSVID using a foreign identity
 	AllowedForeignJWTClaims []string
 
+	// List of audience wildcards that can be used to validate JWT-SVIDs by
+	// audience prefix
+	AllowedJWTAudienceWildcards []string
+
+	// JWTSVIDValidationLeeway is the clock skew leeway applied to the
+	// time-based claims of JWT-SVIDs validated over the Workload API
+	JWTSVIDValidationLeeway time.Duration
+
 	AuthorizedDelegates []string
+
+	// AuthorizedDelegateSelectors restricts the selectors that authorized
+	// delegates can request SVIDs for, keyed by delegate SPIFFE ID.
+	// Delegates without selector patterns are not restricted.
+	AuthorizedDelegateSelectors map[string][]string
+
+	// WorkloadAPIMaxRequestsPerSecond is the number of Workload API calls per
+	// second allowed for each workload process. Zero disables rate limiting.
+	WorkloadAPIMaxRequestsPerSecond int
+
+	// WorkloadAPIBurst is the maximum number of Workload API calls a workload
+	// process can make at once.
+	WorkloadAPIBurst int
+
+	// WorkloadAPIShutdownGracePeriod is how long Workload API streams are
+	// given to close cleanly when the agent shuts down. Zero disables
+	// graceful draining.
+	WorkloadAPIShutdownGracePeriod time.Duration
 }
 
 func New(c *Config) *Agent {
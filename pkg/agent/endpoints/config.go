@@ -2,6 +2,7 @@ package endpoints
 
 import (
 	"net"
+	"time"
 
 	discovery_v2 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v2"
 	secret_v3 "github.com/envoyproxy/go-control-plane/envoy/service/secret/v3"
@@ -42,8 +43,25 @@ type Config struct {
 
 	AllowedForeignJWTClaims []string
 
+	// AllowedJWTAudienceWildcards is the list of audience wildcards that can
+	// be used to validate JWT-SVIDs by audience prefix.
+	AllowedJWTAudienceWildcards []string
+
+	// JWTSVIDValidationLeeway is the clock skew leeway applied to the
+	// time-based claims of JWT-SVIDs validated over the Workload API.
+	JWTSVIDValidationLeeway time.Duration
+
 	TrustDomain spiffeid.TrustDomain
 
+	// WorkloadAPIRateLimit is the per-process rate limit imposed on Workload
+	// API calls.
+	WorkloadAPIRateLimit RateLimitConfig
+
+	// ShutdownGracePeriod is how long open Workload API streams are given to
+	// close after being sent a final update when the agent shuts down. Zero
+	// stops the server immediately.
+	ShutdownGracePeriod time.Duration
+
 	// Hooks used by the unit tests to assert that the configuration provided
 	// to each handler is correct and return fake handlers.
 	newWorkloadAPIServer func(workload.Config) workload_pb.SpiffeWorkloadAPIServer
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"time"
 
 	discovery_v2 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v2"
 	secret_v3 "github.com/envoyproxy/go-control-plane/envoy/service/secret/v3"
@@ -34,6 +35,10 @@ type Endpoints struct {
 	sdsv2Server       discovery_v2.SecretDiscoveryServiceServer
 	sdsv3Server       secret_v3.SecretDiscoveryServiceServer
 	healthServer      grpc_health_v1.HealthServer
+	rateLimit         RateLimitConfig
+
+	shutdownGracePeriod time.Duration
+	draining            chan struct{}
 }
 
 func New(c Config) *Endpoints {
@@ -60,17 +65,27 @@ func New(c Config) *Endpoints {
 		}
 	}
 
+	draining := make(chan struct{})
+
 	allowedClaims := make(map[string]struct{}, len(c.AllowedForeignJWTClaims))
 	for _, claim := range c.AllowedForeignJWTClaims {
 		allowedClaims[claim] = struct{}{}
 	}
 
+	allowedAudienceWildcards := make(map[string]struct{}, len(c.AllowedJWTAudienceWildcards))
+	for _, wildcard := range c.AllowedJWTAudienceWildcards {
+		allowedAudienceWildcards[wildcard] = struct{}{}
+	}
+
 	workloadAPIServer := c.newWorkloadAPIServer(workload.Config{
 		Manager:                       c.Manager,
 		Attestor:                      attestor,
 		AllowUnauthenticatedVerifiers: c.AllowUnauthenticatedVerifiers,
 		AllowedForeignJWTClaims:       allowedClaims,
 		TrustDomain:                   c.TrustDomain,
+		AllowedJWTAudienceWildcards:   allowedAudienceWildcards,
+		JWTSVIDValidationLeeway:       c.JWTSVIDValidationLeeway,
+		Draining:                      draining,
 	})
 
 	sdsv2Server := c.newSDSv2Server(sdsv2.Config{
@@ -100,12 +115,16 @@ func New(c Config) *Endpoints {
 		sdsv2Server:       sdsv2Server,
 		sdsv3Server:       sdsv3Server,
 		healthServer:      healthServer,
+		rateLimit:         c.WorkloadAPIRateLimit,
+
+		shutdownGracePeriod: c.ShutdownGracePeriod,
+		draining:            draining,
 	}
 }
 
 func (e *Endpoints) ListenAndServe(ctx context.Context) error {
 	unaryInterceptor, streamInterceptor := middleware.Interceptors(
-		Middleware(e.log, e.metrics),
+		Middleware(e.log, e.metrics, e.rateLimit),
 	)
 
 	server := grpc.NewServer(
@@ -133,7 +152,7 @@ func (e *Endpoints) ListenAndServe(ctx context.Context) error {
 	case err = <-errChan:
 	case <-ctx.Done():
 		e.log.Info("Stopping Workload and SDS APIs")
-		server.Stop()
+		e.stop(server)
 		err = <-errChan
 		if errors.Is(err, grpc.ErrServerStopped) {
 			err = nil
@@ -142,6 +161,36 @@ func (e *Endpoints) ListenAndServe(ctx context.Context) error {
 	return err
 }
 
+// stop stops the server. When a shutdown grace period is configured, the
+// server stops accepting new connections and Workload API streams are sent a
+// final update and closed. Whatever is still open when the grace period
+// expires (e.g. SDS streams) is forcibly terminated.
+func (e *Endpoints) stop(server *grpc.Server) {
+	if e.shutdownGracePeriod <= 0 {
+		server.Stop()
+		return
+	}
+
+	close(e.draining)
+
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	timer := time.NewTimer(e.shutdownGracePeriod)
+	defer timer.Stop()
+
+	select {
+	case <-stopped:
+	case <-timer.C:
+		e.log.Warn("Shutdown grace period expired; forcibly closing remaining connections")
+		server.Stop()
+		<-stopped
+	}
+}
+
 func (e *Endpoints) createUDSListener() (net.Listener, error) {
 	// Remove uds if already exists
 	os.Remove(e.addr.String())
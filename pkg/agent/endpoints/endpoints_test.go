@@ -3,6 +3,7 @@ package endpoints
 import (
 	"context"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"path/filepath"
@@ -181,12 +182,14 @@ func TestEndpoints(t *testing.T) {
 				DefaultBundleName:       "DefaultBundleName",
 				DefaultAllBundlesName:   "DefaultAllBundlesName",
 				AllowedForeignJWTClaims: tt.allowedClaims,
+				JWTSVIDValidationLeeway: 30 * time.Second,
 
 				// Assert the provided config and return a fake Workload API server
 				newWorkloadAPIServer: func(c workload.Config) workload_pb.SpiffeWorkloadAPIServer {
 					attestor, ok := c.Attestor.(PeerTrackerAttestor)
 					require.True(t, ok, "attestor was not a PeerTrackerAttestor wrapper")
 					assert.Equal(t, FakeManager{}, c.Manager)
+					assert.Equal(t, 30*time.Second, c.JWTSVIDValidationLeeway)
 					if tt.expectClaims != nil {
 						assert.Equal(t, tt.expectClaims, c.AllowedForeignJWTClaims)
 					} else {
@@ -259,6 +262,200 @@ func TestEndpoints(t *testing.T) {
 	}
 }
 
+func TestEndpointsRateLimit(t *testing.T) {
+	// TODO: Endpoint uses peertracker that is not compatible with Windows.
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	udsPath := filepath.Join(spiretest.TempDir(t), "agent.sock")
+	log, _ := test.NewNullLogger()
+
+	endpoints := New(Config{
+		BindAddr: &net.UnixAddr{
+			Net:  "unix",
+			Name: udsPath,
+		},
+		Log:      log,
+		Metrics:  fakemetrics.New(),
+		Attestor: FakeAttestor{},
+		Manager:  FakeManager{},
+		WorkloadAPIRateLimit: RateLimitConfig{
+			RequestsPerSecond: 1,
+			Burst:             3,
+		},
+		newWorkloadAPIServer: func(c workload.Config) workload_pb.SpiffeWorkloadAPIServer {
+			attestor, ok := c.Attestor.(PeerTrackerAttestor)
+			require.True(t, ok, "attestor was not a PeerTrackerAttestor wrapper")
+			return FakeWorkloadAPIServer{Attestor: attestor}
+		},
+		newSDSv3Server: func(c sdsv3.Config) secret_v3.SecretDiscoveryServiceServer {
+			attestor, ok := c.Attestor.(PeerTrackerAttestor)
+			require.True(t, ok, "attestor was not a PeerTrackerAttestor wrapper")
+			return FakeSDSv3Server{Attestor: attestor}
+		},
+	})
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- endpoints.ListenAndServe(ctx)
+	}()
+	defer func() {
+		cancel()
+		assert.NoError(t, <-errCh)
+	}()
+
+	connectParams := grpc.ConnectParams{
+		Backoff: backoff.DefaultConfig,
+	}
+	connectParams.Backoff.BaseDelay = 5 * time.Millisecond
+	conn, err := grpc.DialContext(ctx, "unix:"+udsPath,
+		grpc.WithReturnConnectionError(),
+		grpc.WithConnectParams(connectParams),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	wlClient := workload_pb.NewSpiffeWorkloadAPIClient(conn)
+	wlCtx := metadata.NewOutgoingContext(ctx, metadata.Pairs("workload.spiffe.io", "true"))
+
+	// Opening the stream counts against the limit, but the messages
+	// received over it do not.
+	stream, err := wlClient.FetchX509SVID(wlCtx, &workload_pb.X509SVIDRequest{})
+	require.NoError(t, err)
+	for i := 0; i < fakeX509SVIDResponses; i++ {
+		_, err := stream.Recv()
+		require.NoError(t, err)
+	}
+
+	// The remaining burst is available for unary calls.
+	for i := 0; i < 2; i++ {
+		_, err = wlClient.FetchJWTSVID(wlCtx, &workload_pb.JWTSVIDRequest{})
+		require.NoError(t, err)
+	}
+
+	// Flooding the Workload API trips the limiter.
+	var limited bool
+	for i := 0; i < 10 && !limited; i++ {
+		_, err = wlClient.FetchJWTSVID(wlCtx, &workload_pb.JWTSVIDRequest{})
+		if status.Code(err) == codes.ResourceExhausted {
+			limited = true
+			continue
+		}
+		require.NoError(t, err)
+	}
+	require.True(t, limited, "workload api calls were not rate limited")
+	spiretest.RequireGRPCStatus(t, err, codes.ResourceExhausted, "rate limit exceeded")
+
+	// Other APIs served by the agent are not affected.
+	sdsClient := secret_v3.NewSecretDiscoveryServiceClient(conn)
+	_, err = sdsClient.FetchSecrets(ctx, &discovery_v3.DiscoveryRequest{})
+	require.NoError(t, err)
+}
+
+func TestEndpointsGracefulShutdown(t *testing.T) {
+	// TODO: Endpoint uses peertracker that is not compatible with Windows.
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+
+	for _, tt := range []struct {
+		name         string
+		ignoreDrain  bool
+		expectedLogs []spiretest.LogEntry
+	}{
+		{
+			name: "streams are drained",
+		},
+		{
+			name:        "grace period expires",
+			ignoreDrain: true,
+			expectedLogs: []spiretest.LogEntry{
+				{Level: logrus.WarnLevel, Message: "Shutdown grace period expired; forcibly closing remaining connections"},
+			},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			udsPath := filepath.Join(spiretest.TempDir(t), "agent.sock")
+			log, hook := test.NewNullLogger()
+
+			endpoints := New(Config{
+				BindAddr: &net.UnixAddr{
+					Net:  "unix",
+					Name: udsPath,
+				},
+				Log:                 log,
+				Metrics:             fakemetrics.New(),
+				Attestor:            FakeAttestor{},
+				Manager:             FakeManager{},
+				ShutdownGracePeriod: 100 * time.Millisecond,
+				newWorkloadAPIServer: func(c workload.Config) workload_pb.SpiffeWorkloadAPIServer {
+					require.NotNil(t, c.Draining)
+					draining := c.Draining
+					if tt.ignoreDrain {
+						draining = nil
+					}
+					return drainingWorkloadAPIServer{draining: draining}
+				},
+			})
+
+			serveCtx, stop := context.WithCancel(ctx)
+			defer stop()
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- endpoints.ListenAndServe(serveCtx)
+			}()
+
+			connectParams := grpc.ConnectParams{
+				Backoff: backoff.DefaultConfig,
+			}
+			connectParams.Backoff.BaseDelay = 5 * time.Millisecond
+			conn, err := grpc.DialContext(ctx, "unix:"+udsPath,
+				grpc.WithReturnConnectionError(),
+				grpc.WithConnectParams(connectParams),
+				grpc.WithTransportCredentials(insecure.NewCredentials()))
+			require.NoError(t, err)
+			defer conn.Close()
+
+			wlClient := workload_pb.NewSpiffeWorkloadAPIClient(conn)
+			wlCtx := metadata.NewOutgoingContext(ctx, metadata.Pairs("workload.spiffe.io", "true"))
+			stream, err := wlClient.FetchX509SVID(wlCtx, &workload_pb.X509SVIDRequest{})
+			require.NoError(t, err)
+
+			// Wait for the subscription to be established before shutting
+			// down.
+			_, err = stream.Recv()
+			require.NoError(t, err)
+
+			stop()
+			if tt.ignoreDrain {
+				_, err = stream.Recv()
+				require.Equal(t, codes.Unavailable, status.Code(err))
+			} else {
+				// The final update is delivered and the stream is closed
+				// cleanly.
+				_, err = stream.Recv()
+				require.NoError(t, err)
+				_, err = stream.Recv()
+				require.Equal(t, io.EOF, err)
+			}
+			require.NoError(t, <-errCh)
+
+			spiretest.AssertLogs(t, hook.AllEntries(), append([]spiretest.LogEntry{
+				{Level: logrus.InfoLevel, Message: "Starting Workload and SDS APIs"},
+				{Level: logrus.InfoLevel, Message: "Stopping Workload and SDS APIs"},
+			}, tt.expectedLogs...))
+		})
+	}
+}
+
 type FakeManager struct {
 	manager.Manager
 }
@@ -275,6 +472,41 @@ func (s FakeWorkloadAPIServer) FetchJWTSVID(ctx context.Context, in *workload_pb
 	return &workload_pb.JWTSVIDResponse{}, nil
 }
 
+// fakeX509SVIDResponses is the number of responses sent over the
+// FetchX509SVID stream by the fake Workload API server.
+const fakeX509SVIDResponses = 5
+
+func (s FakeWorkloadAPIServer) FetchX509SVID(in *workload_pb.X509SVIDRequest, stream workload_pb.SpiffeWorkloadAPI_FetchX509SVIDServer) error {
+	if err := attest(stream.Context(), s.Attestor); err != nil {
+		return err
+	}
+	for i := 0; i < fakeX509SVIDResponses; i++ {
+		if err := stream.Send(&workload_pb.X509SVIDResponse{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drainingWorkloadAPIServer keeps FetchX509SVID streams open until draining
+// starts, at which point it sends a final response and closes the stream.
+type drainingWorkloadAPIServer struct {
+	*workload_pb.UnimplementedSpiffeWorkloadAPIServer
+	draining <-chan struct{}
+}
+
+func (s drainingWorkloadAPIServer) FetchX509SVID(in *workload_pb.X509SVIDRequest, stream workload_pb.SpiffeWorkloadAPI_FetchX509SVIDServer) error {
+	if err := stream.Send(&workload_pb.X509SVIDResponse{}); err != nil {
+		return err
+	}
+	select {
+	case <-s.draining:
+		return stream.Send(&workload_pb.X509SVIDResponse{})
+	case <-stream.Context().Done():
+		return nil
+	}
+}
+
 type FakeSDSv2Server struct {
 	Attestor PeerTrackerAttestor
 	*discovery_v2.UnimplementedSecretDiscoveryServiceServer
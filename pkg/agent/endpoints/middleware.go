@@ -4,6 +4,7 @@ import (
 	"context"
 	"strings"
 
+	"github.com/andres-erbsen/clock"
 	"github.com/sirupsen/logrus"
 	"github.com/spiffe/spire/pkg/agent/api/rpccontext"
 	"github.com/spiffe/spire/pkg/common/api/middleware"
@@ -18,14 +19,18 @@ const (
 	workloadAPIMethodPrefix = "/SpiffeWorkloadAPI/"
 )
 
-func Middleware(log logrus.FieldLogger, metrics telemetry.Metrics) middleware.Middleware {
-	return middleware.Chain(
+func Middleware(log logrus.FieldLogger, metrics telemetry.Metrics, rateLimit RateLimitConfig) middleware.Middleware {
+	chain := []middleware.Middleware{
 		middleware.WithLogger(log),
 		middleware.WithMetrics(metrics),
 		withPerServiceConnectionMetrics(metrics),
 		middleware.Preprocess(addWatcherPID),
 		middleware.Preprocess(verifySecurityHeader),
-	)
+	}
+	if rateLimit.RequestsPerSecond > 0 {
+		chain = append(chain, middleware.Preprocess(newPerPIDLimiter(rateLimit, clock.New()).Preprocess))
+	}
+	return middleware.Chain(chain...)
 }
 
 func addWatcherPID(ctx context.Context, fullMethod string, req interface{}) (context.Context, error) {
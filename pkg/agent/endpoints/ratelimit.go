@@ -0,0 +1,118 @@
+package endpoints
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/andres-erbsen/clock"
+	"github.com/spiffe/spire/pkg/common/peertracker"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// rateLimitGCInterval is the interval at which per-PID limiters are
+	// garbage collected.
+	rateLimitGCInterval = time.Minute
+)
+
+// RateLimitConfig configures the per-process rate limit imposed on Workload
+// API calls.
+type RateLimitConfig struct {
+	// RequestsPerSecond is the number of calls per second a workload process
+	// is allowed to make. Rate limiting is disabled when zero.
+	RequestsPerSecond int
+
+	// Burst is the maximum number of calls a workload process can make at
+	// once. Defaults to RequestsPerSecond when zero.
+	Burst int
+}
+
+// perPIDLimiter limits the rate of Workload API calls made by each workload
+// process. Calls are counted when the RPC is invoked, so a streaming RPC is
+// only counted when the stream is opened and not for each message sent over
+// it.
+type perPIDLimiter struct {
+	limit rate.Limit
+	burst int
+	clk   clock.Clock
+
+	mtx sync.Mutex
+
+	// previous holds all of the limiters that were current at the last GC.
+	previous map[int32]*rate.Limiter
+
+	// current holds all of the limiters that have been created or moved
+	// from the previous limiters since the last GC.
+	current map[int32]*rate.Limiter
+
+	// lastGC is the time of the last GC
+	lastGC time.Time
+}
+
+func newPerPIDLimiter(config RateLimitConfig, clk clock.Clock) *perPIDLimiter {
+	burst := config.Burst
+	if burst == 0 {
+		burst = config.RequestsPerSecond
+	}
+	return &perPIDLimiter{
+		limit:   rate.Limit(config.RequestsPerSecond),
+		burst:   burst,
+		clk:     clk,
+		current: make(map[int32]*rate.Limiter),
+		lastGC:  clk.Now(),
+	}
+}
+
+// Preprocess fails Workload API calls with a ResourceExhausted status when
+// the calling process has exceeded its rate limit. Rejected calls are not
+// logged to avoid flooding the logs when a workload tight-loops; they are
+// reported by the RPC metrics instead. Calls from callers that could not be
+// identified and calls to other APIs are not limited.
+func (lim *perPIDLimiter) Preprocess(ctx context.Context, fullMethod string, req interface{}) (context.Context, error) {
+	if !isWorkloadAPIMethod(fullMethod) {
+		return ctx, nil
+	}
+	watcher, ok := peertracker.WatcherFromContext(ctx)
+	if !ok {
+		return ctx, nil
+	}
+	if !lim.getLimiter(watcher.PID()).AllowN(lim.clk.Now(), 1) {
+		return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+	return ctx, nil
+}
+
+func (lim *perPIDLimiter) getLimiter(pid int32) *rate.Limiter {
+	lim.mtx.Lock()
+	defer lim.mtx.Unlock()
+
+	if limiter, ok := lim.current[pid]; ok {
+		return limiter
+	}
+
+	// Check the "previous" entries to see if a limiter exists for this PID
+	// as of the last GC. If so, move it to current and return it.
+	if limiter, ok := lim.previous[pid]; ok {
+		lim.current[pid] = limiter
+		delete(lim.previous, pid)
+		return limiter
+	}
+
+	// There is no limiter for this PID. Before we create one, we should see
+	// if we need to do GC. Limiters that haven't been used since the last
+	// GC are dropped, which keeps limiters for exited processes from piling
+	// up.
+	now := lim.clk.Now()
+	if now.Sub(lim.lastGC) >= rateLimitGCInterval {
+		lim.previous = lim.current
+		lim.current = make(map[int32]*rate.Limiter)
+		lim.lastGC = now
+	}
+
+	limiter := rate.NewLimiter(lim.limit, lim.burst)
+	lim.current[pid] = limiter
+	return limiter
+}
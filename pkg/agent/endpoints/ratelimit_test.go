@@ -0,0 +1,101 @@
+package endpoints
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/peertracker"
+	"github.com/spiffe/spire/test/clock"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	fetchX509SVIDMethod = "/SpiffeWorkloadAPI/FetchX509SVID"
+	fetchJWTSVIDMethod  = "/SpiffeWorkloadAPI/FetchJWTSVID"
+	sdsv3Method         = "/envoy.service.secret.v3.SecretDiscoveryService/FetchSecrets"
+)
+
+func TestPerPIDLimiter(t *testing.T) {
+	clk := clock.NewMock(t)
+	lim := newPerPIDLimiter(RateLimitConfig{RequestsPerSecond: 1, Burst: 2}, clk)
+
+	call := func(pid int32, fullMethod string) error {
+		_, err := lim.Preprocess(withPIDWatcher(pid), fullMethod, nil)
+		return err
+	}
+
+	// Exhaust the burst for the first process
+	require.NoError(t, call(1, fetchJWTSVIDMethod))
+	require.NoError(t, call(1, fetchX509SVIDMethod))
+	spiretest.RequireGRPCStatus(t, call(1, fetchJWTSVIDMethod), codes.ResourceExhausted, "rate limit exceeded")
+
+	// Other processes are not affected
+	require.NoError(t, call(2, fetchJWTSVIDMethod))
+	require.NoError(t, call(2, fetchJWTSVIDMethod))
+
+	// Other APIs are not limited
+	require.NoError(t, call(1, sdsv3Method))
+
+	// Callers that can't be identified are not limited
+	_, err := lim.Preprocess(context.Background(), fetchJWTSVIDMethod, nil)
+	require.NoError(t, err)
+
+	// The limit replenishes over time
+	clk.Add(time.Second)
+	require.NoError(t, call(1, fetchJWTSVIDMethod))
+	spiretest.RequireGRPCStatus(t, call(1, fetchJWTSVIDMethod), codes.ResourceExhausted, "rate limit exceeded")
+}
+
+func TestPerPIDLimiterDefaultBurst(t *testing.T) {
+	lim := newPerPIDLimiter(RateLimitConfig{RequestsPerSecond: 3}, clock.NewMock(t))
+	require.Equal(t, 3, lim.burst)
+}
+
+func TestPerPIDLimiterGC(t *testing.T) {
+	clk := clock.NewMock(t)
+	lim := newPerPIDLimiter(RateLimitConfig{RequestsPerSecond: 1}, clk)
+
+	limiter1 := lim.getLimiter(1)
+	require.Same(t, limiter1, lim.getLimiter(1))
+
+	// After the GC interval, creating a limiter for a new PID moves the
+	// current limiters to previous.
+	clk.Add(rateLimitGCInterval)
+	lim.getLimiter(2)
+	require.Len(t, lim.current, 1)
+	require.Len(t, lim.previous, 1)
+
+	// Limiters used since the last GC survive the next one.
+	require.Same(t, limiter1, lim.getLimiter(1))
+	clk.Add(rateLimitGCInterval)
+	lim.getLimiter(3)
+	require.Contains(t, lim.previous, int32(1))
+	require.Contains(t, lim.previous, int32(2))
+
+	// Limiters unused for a whole GC interval are dropped.
+	clk.Add(rateLimitGCInterval)
+	lim.getLimiter(4)
+	require.NotContains(t, lim.previous, int32(1))
+	require.NotContains(t, lim.previous, int32(2))
+	require.NotSame(t, limiter1, lim.getLimiter(1))
+}
+
+func withPIDWatcher(pid int32) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: peertracker.AuthInfo{
+			Watcher: pidWatcher(pid),
+		},
+	})
+}
+
+type pidWatcher int32
+
+func (w pidWatcher) Close() {}
+
+func (w pidWatcher) IsAlive() error { return nil }
+
+func (w pidWatcher) PID() int32 { return int32(w) }
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -46,6 +47,22 @@ type Config struct {
 	AllowUnauthenticatedVerifiers bool
 	AllowedForeignJWTClaims       map[string]struct{}
 	TrustDomain                   spiffeid.TrustDomain
+
+	// AllowedJWTAudienceWildcards is the set of audience wildcards (e.g.
+	// "spiffe://example.org/gateway/*") that can be used to validate
+	// JWT-SVIDs. When the audience requested for validation is one of these
+	// wildcards, JWT-SVIDs with any audience starting with the wildcard prefix
+	// are accepted.
+	AllowedJWTAudienceWildcards map[string]struct{}
+
+	// JWTSVIDValidationLeeway is the clock skew leeway applied to the
+	// time-based claims of JWT-SVIDs being validated.
+	JWTSVIDValidationLeeway time.Duration
+
+	// Draining, when closed, signals that the agent is shutting down. Open
+	// streams are sent one final update and then closed so that workloads
+	// can reconnect cleanly instead of seeing the connection torn down.
+	Draining <-chan struct{}
 }
 
 type Handler struct {
@@ -146,6 +163,8 @@ func (h *Handler) FetchJWTBundles(req *workload.JWTBundlesRequest, stream worklo
 			if err := sendJWTBundlesResponse(update, stream, log, h.c.AllowUnauthenticatedVerifiers); err != nil {
 				return err
 			}
+		case <-h.c.Draining:
+			return sendJWTBundlesResponse(h.c.Manager.FetchWorkloadUpdate(selectors), stream, log, h.c.AllowUnauthenticatedVerifiers)
 		case <-ctx.Done():
 			return nil
 		}
@@ -178,7 +197,13 @@ func (h *Handler) ValidateJWTSVID(ctx context.Context, req *workload.ValidateJWT
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	id, claims, err := jwtsvid.ValidateToken(ctx, req.Svid, keyStore, []string{req.Audience})
+	var id spiffeid.ID
+	var claims map[string]interface{}
+	if _, ok := h.c.AllowedJWTAudienceWildcards[req.Audience]; ok {
+		id, claims, err = jwtsvid.ValidateTokenWithAudiencePrefix(ctx, req.Svid, keyStore, strings.TrimSuffix(req.Audience, "*"), jwtsvid.WithLeeway(h.c.JWTSVIDValidationLeeway))
+	} else {
+		id, claims, err = jwtsvid.ValidateToken(ctx, req.Svid, keyStore, []string{req.Audience}, jwtsvid.WithLeeway(h.c.JWTSVIDValidationLeeway))
+	}
 	if err != nil {
 		log.WithError(err).Warn("Failed to validate JWT")
 		return nil, status.Error(codes.InvalidArgument, err.Error())
@@ -231,6 +256,8 @@ func (h *Handler) FetchX509SVID(_ *workload.X509SVIDRequest, stream workload.Spi
 			if err := sendX509SVIDResponse(update, stream, log, quietLogging); err != nil {
 				return err
 			}
+		case <-h.c.Draining:
+			return sendX509SVIDResponse(h.c.Manager.FetchWorkloadUpdate(selectors), stream, log, quietLogging)
 		case <-ctx.Done():
 			return nil
 		}
@@ -258,6 +285,8 @@ func (h *Handler) FetchX509Bundles(_ *workload.X509BundlesRequest, stream worklo
 			if err != nil {
 				return err
 			}
+		case <-h.c.Draining:
+			return sendX509BundlesResponse(h.c.Manager.FetchWorkloadUpdate(selectors), stream, log, h.c.AllowUnauthenticatedVerifiers)
 		case <-ctx.Done():
 			return nil
 		}
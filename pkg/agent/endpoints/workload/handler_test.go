@@ -7,6 +7,7 @@ import (
 	"crypto/x509"
 	"encoding/json"
 	"errors"
+	"io"
 	"os"
 	"sync/atomic"
 	"testing"
@@ -179,6 +180,49 @@ func TestFetchX509SVID(t *testing.T) {
 				})
 		})
 	}
+
+	t.Run("final update while draining", func(t *testing.T) {
+		expectResp := &workloadPB.X509SVIDResponse{
+			Svids: []*workloadPB.X509SVID{
+				{
+					SpiffeId:    x509SVID1.ID.String(),
+					X509Svid:    x509util.DERFromCertificates(x509SVID1.Certificates),
+					X509SvidKey: pkcs8FromSigner(t, x509SVID1.PrivateKey),
+					Bundle:      x509util.DERFromCertificates(bundle.X509Authorities()),
+				},
+			},
+		}
+
+		draining := make(chan struct{})
+		params := testParams{
+			CA: ca,
+			Updates: []*cache.WorkloadUpdate{{
+				Identities: []cache.Identity{identityFromX509SVID(x509SVID1)},
+				Bundle:     utilBundleFromBundle(t, bundle),
+			}},
+			Draining: draining,
+		}
+		runTest(t, params,
+			func(ctx context.Context, client workloadPB.SpiffeWorkloadAPIClient) {
+				stream, err := client.FetchX509SVID(ctx, &workloadPB.X509SVIDRequest{})
+				require.NoError(t, err)
+
+				resp, err := stream.Recv()
+				require.NoError(t, err)
+				spiretest.RequireProtoEqual(t, expectResp, resp)
+
+				// Once the agent starts draining, the subscribed stream
+				// receives a final update and is closed cleanly.
+				close(draining)
+
+				resp, err = stream.Recv()
+				require.NoError(t, err)
+				spiretest.RequireProtoEqual(t, expectResp, resp)
+
+				_, err = stream.Recv()
+				require.Equal(t, io.EOF, err)
+			})
+	})
 }
 
 func TestFetchX509Bundles(t *testing.T) {
@@ -941,6 +985,152 @@ func TestValidateJWTSVID(t *testing.T) {
 	}
 }
 
+func TestValidateJWTSVIDWithAudienceWildcards(t *testing.T) {
+	ca := testca.New(t, td)
+	updates := []*cache.WorkloadUpdate{{
+		Bundle: utilBundleFromBundle(t, ca.Bundle()),
+	}}
+
+	gatewaySVID := ca.CreateJWTSVID(workloadID, []string{"spiffe://domain.test/gateway/api"})
+	wildcardSVID := ca.CreateJWTSVID(workloadID, []string{"spiffe://domain.test/gateway/*"})
+	otherSVID := ca.CreateJWTSVID(workloadID, []string{"spiffe://domain.test/other/api"})
+	siblingSVID := ca.CreateJWTSVID(workloadID, []string{"spiffe://domain.test/gatewayevil"})
+
+	wildcards := map[string]struct{}{"spiffe://domain.test/gateway/*": {}}
+
+	for _, tt := range []struct {
+		name       string
+		svid       string
+		audience   string
+		wildcards  map[string]struct{}
+		expectCode codes.Code
+		expectMsg  string
+		expectLogs []spiretest.LogEntry
+	}{
+		{
+			name:       "matching prefix",
+			svid:       gatewaySVID.Marshal(),
+			audience:   "spiffe://domain.test/gateway/*",
+			wildcards:  wildcards,
+			expectCode: codes.OK,
+		},
+		{
+			name:       "non-matching prefix",
+			svid:       otherSVID.Marshal(),
+			audience:   "spiffe://domain.test/gateway/*",
+			wildcards:  wildcards,
+			expectCode: codes.InvalidArgument,
+			expectMsg:  `expected audience with prefix "spiffe://domain.test/gateway/" (audience=["spiffe://domain.test/other/api"])`,
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.WarnLevel,
+					Message: "Failed to validate JWT",
+					Data: logrus.Fields{
+						"audience":      "spiffe://domain.test/gateway/*",
+						"service":       "WorkloadAPI",
+						"method":        "ValidateJWTSVID",
+						logrus.ErrorKey: `expected audience with prefix "spiffe://domain.test/gateway/" (audience=["spiffe://domain.test/other/api"])`,
+					},
+				},
+			},
+		},
+		{
+			name:       "sibling prefix",
+			svid:       siblingSVID.Marshal(),
+			audience:   "spiffe://domain.test/gateway/*",
+			wildcards:  wildcards,
+			expectCode: codes.InvalidArgument,
+			expectMsg:  `expected audience with prefix "spiffe://domain.test/gateway/" (audience=["spiffe://domain.test/gatewayevil"])`,
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.WarnLevel,
+					Message: "Failed to validate JWT",
+					Data: logrus.Fields{
+						"audience":      "spiffe://domain.test/gateway/*",
+						"service":       "WorkloadAPI",
+						"method":        "ValidateJWTSVID",
+						logrus.ErrorKey: `expected audience with prefix "spiffe://domain.test/gateway/" (audience=["spiffe://domain.test/gatewayevil"])`,
+					},
+				},
+			},
+		},
+		{
+			name:       "exact audience",
+			svid:       gatewaySVID.Marshal(),
+			audience:   "spiffe://domain.test/gateway/api",
+			wildcards:  wildcards,
+			expectCode: codes.OK,
+		},
+		{
+			name:       "exact audience mismatch",
+			svid:       otherSVID.Marshal(),
+			audience:   "spiffe://domain.test/gateway/api",
+			wildcards:  wildcards,
+			expectCode: codes.InvalidArgument,
+			expectMsg:  `expected audience in ["spiffe://domain.test/gateway/api"] (audience=["spiffe://domain.test/other/api"])`,
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.WarnLevel,
+					Message: "Failed to validate JWT",
+					Data: logrus.Fields{
+						"audience":      "spiffe://domain.test/gateway/api",
+						"service":       "WorkloadAPI",
+						"method":        "ValidateJWTSVID",
+						logrus.ErrorKey: `expected audience in ["spiffe://domain.test/gateway/api"] (audience=["spiffe://domain.test/other/api"])`,
+					},
+				},
+			},
+		},
+		{
+			name:       "wildcard not allowed",
+			svid:       gatewaySVID.Marshal(),
+			audience:   "spiffe://domain.test/gateway/*",
+			expectCode: codes.InvalidArgument,
+			expectMsg:  `expected audience in ["spiffe://domain.test/gateway/*"] (audience=["spiffe://domain.test/gateway/api"])`,
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.WarnLevel,
+					Message: "Failed to validate JWT",
+					Data: logrus.Fields{
+						"audience":      "spiffe://domain.test/gateway/*",
+						"service":       "WorkloadAPI",
+						"method":        "ValidateJWTSVID",
+						logrus.ErrorKey: `expected audience in ["spiffe://domain.test/gateway/*"] (audience=["spiffe://domain.test/gateway/api"])`,
+					},
+				},
+			},
+		},
+		{
+			name:       "wildcard not allowed but exact match",
+			svid:       wildcardSVID.Marshal(),
+			audience:   "spiffe://domain.test/gateway/*",
+			expectCode: codes.OK,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			params := testParams{
+				Updates:                     updates,
+				ExpectLogs:                  tt.expectLogs,
+				AllowedJWTAudienceWildcards: tt.wildcards,
+			}
+			runTest(t, params,
+				func(ctx context.Context, client workloadPB.SpiffeWorkloadAPIClient) {
+					resp, err := client.ValidateJWTSVID(ctx, &workloadPB.ValidateJWTSVIDRequest{
+						Svid:     tt.svid,
+						Audience: tt.audience,
+					})
+					spiretest.RequireGRPCStatus(t, err, tt.expectCode, tt.expectMsg)
+					if tt.expectCode != codes.OK {
+						assert.Nil(t, resp)
+						return
+					}
+					assert.Equal(t, workloadID.String(), resp.SpiffeId)
+				})
+		})
+	}
+}
+
 type testParams struct {
 	CA                            *testca.CA
 	Identities                    []cache.Identity
@@ -951,6 +1141,8 @@ type testParams struct {
 	AsPID                         int
 	AllowUnauthenticatedVerifiers bool
 	AllowedForeignJWTClaims       map[string]struct{}
+	AllowedJWTAudienceWildcards   map[string]struct{}
+	Draining                      <-chan struct{}
 }
 
 func runTest(t *testing.T, params testParams, fn func(ctx context.Context, client workloadPB.SpiffeWorkloadAPIClient)) {
@@ -969,6 +1161,8 @@ func runTest(t *testing.T, params testParams, fn func(ctx context.Context, clien
 		Attestor:                      &FakeAttestor{err: params.AttestErr},
 		AllowUnauthenticatedVerifiers: params.AllowUnauthenticatedVerifiers,
 		AllowedForeignJWTClaims:       params.AllowedForeignJWTClaims,
+		AllowedJWTAudienceWildcards:   params.AllowedJWTAudienceWildcards,
+		Draining:                      params.Draining,
 	})
 
 	unaryInterceptor, streamInterceptor := middleware.Interceptors(middleware.Chain(
@@ -33,6 +33,10 @@ type Config struct {
 	RotationInterval time.Duration
 	SVIDStoreCache   *storecache.Cache
 
+	// Maximum size in bytes of the messages exchanged with the server
+	MaxRecvMessageSize int
+	MaxSendMessageSize int
+
 	// Clk is the clock the manager will use to get time
 	Clk clock.Clock
 }
@@ -68,6 +72,9 @@ func newManager(c *Config) *manager {
 		TrustDomain:    c.TrustDomain,
 		Interval:       c.RotationInterval,
 		Clk:            c.Clk,
+
+		MaxRecvMessageSize: c.MaxRecvMessageSize,
+		MaxSendMessageSize: c.MaxSendMessageSize,
 	}
 	svidRotator, client := svid.NewRotator(rotCfg)
 
@@ -106,11 +106,32 @@ func (p *Plugin) Attest(ctx context.Context, req *workloadattestorv1.AttestReque
 		return nil, err
 	}
 
+	selectorValues := getSelectorValuesFromConfig(container.Config)
+	if imageID := resolvedImageID(container); imageID != "" {
+		selectorValues = append(selectorValues, fmt.Sprintf("%s:%s", subselectorImageID, imageID))
+	}
+
 	return &workloadattestorv1.AttestResponse{
-		SelectorValues: getSelectorValuesFromConfig(container.Config),
+		SelectorValues: selectorValues,
 	}, nil
 }
 
+// resolvedImageID returns the ID (i.e. "sha256:<digest>") of the image the
+// container runs, as resolved by the docker daemon, unless it is the same as
+// the image the container was configured with, which is already used as an
+// image_id selector. Unlike the configured image, which is typically a
+// mutable reference like "nginx:latest", the resolved ID identifies the
+// image content, including for local builds that were never pushed.
+func resolvedImageID(container types.ContainerJSON) string {
+	if container.ContainerJSONBase == nil || container.Image == "" {
+		return ""
+	}
+	if container.Config != nil && container.Config.Image == container.Image {
+		return ""
+	}
+	return container.Image
+}
+
 func getSelectorValuesFromConfig(cfg *container.Config) []string {
 	var selectorValues []string
 	for label, value := range cfg.Labels {
@@ -38,6 +38,7 @@ func TestDockerSelectors(t *testing.T) {
 		mockContainerLabels  map[string]string
 		mockEnv              []string
 		mockImageID          string
+		mockResolvedImageID  string
 		expectSelectorValues []string
 	}{
 		{
@@ -73,15 +74,46 @@ func TestDockerSelectors(t *testing.T) {
 				"image_id:my-docker-image",
 			},
 		},
+		{
+			desc:                "resolved image id",
+			mockImageID:         "nginx:latest",
+			mockResolvedImageID: "sha256:4cdc5dd7eaadff5080649e8d0014f2f8d36d4ddf2eff2fdf577dd13da85c5d2f",
+			expectSelectorValues: []string{
+				"image_id:nginx:latest",
+				"image_id:sha256:4cdc5dd7eaadff5080649e8d0014f2f8d36d4ddf2eff2fdf577dd13da85c5d2f",
+			},
+		},
+		{
+			desc:                "container started by image id",
+			mockImageID:         "sha256:4cdc5dd7eaadff5080649e8d0014f2f8d36d4ddf2eff2fdf577dd13da85c5d2f",
+			mockResolvedImageID: "sha256:4cdc5dd7eaadff5080649e8d0014f2f8d36d4ddf2eff2fdf577dd13da85c5d2f",
+			expectSelectorValues: []string{
+				"image_id:sha256:4cdc5dd7eaadff5080649e8d0014f2f8d36d4ddf2eff2fdf577dd13da85c5d2f",
+			},
+		},
+		{
+			desc:                "labels and resolved image id",
+			mockContainerLabels: map[string]string{"app": "web"},
+			mockImageID:         "my-local-build",
+			mockResolvedImageID: "sha256:9b2c8f3a1e4d",
+			expectSelectorValues: []string{
+				"image_id:my-local-build",
+				"image_id:sha256:9b2c8f3a1e4d",
+				"label:app:web",
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		tt := tt // alias loop variable as it is used in the closure
 		t.Run(tt.desc, func(t *testing.T) {
-			d := fakeContainer{
-				Labels: tt.mockContainerLabels,
-				Image:  tt.mockImageID,
-				Env:    tt.mockEnv,
+			d := fakeContainerWithImageID{
+				fakeContainer: fakeContainer{
+					Labels: tt.mockContainerLabels,
+					Image:  tt.mockImageID,
+					Env:    tt.mockEnv,
+				},
+				imageID: tt.mockResolvedImageID,
 			}
 
 			fs := newFakeFileSystem(testCgroupEntries)
@@ -403,6 +435,24 @@ func (f fakeContainer) ContainerInspect(ctx context.Context, containerID string)
 	}, nil
 }
 
+// fakeContainerWithImageID also reports the ID of the image the container
+// runs, as resolved by the docker daemon.
+type fakeContainerWithImageID struct {
+	fakeContainer
+	imageID string
+}
+
+func (f fakeContainerWithImageID) ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error) {
+	container, err := f.fakeContainer.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return types.ContainerJSON{}, err
+	}
+	container.ContainerJSONBase = &types.ContainerJSONBase{
+		Image: f.imageID,
+	}
+	return container, nil
+}
+
 func newFakeFileSystem(cgroups string) FakeFileSystem {
 	return FakeFileSystem{
 		Files: map[string]string{
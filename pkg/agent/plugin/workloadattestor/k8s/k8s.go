@@ -116,6 +116,35 @@ type HCLConfig struct {
 	// ReloadInterval controls how often TLS and token configuration is loaded
 	// from the disk.
 	ReloadInterval string `hcl:"reload_interval"`
+
+	// PodAnnotationSelectors enables the pod-annotation selectors.
+	PodAnnotationSelectors bool `hcl:"pod_annotation_selectors"`
+
+	// PodAnnotationAllowList, if set, restricts the pod-annotation selectors
+	// to the annotations with these keys.
+	PodAnnotationAllowList []string `hcl:"pod_annotation_allow_list"`
+
+	// PodAnnotationDenyList excludes the annotations with these keys from the
+	// pod-annotation selectors.
+	PodAnnotationDenyList []string `hcl:"pod_annotation_deny_list"`
+
+	// PodAnnotationMaxSize is the maximum size, in bytes, of an annotation
+	// value for it to produce a selector. If zero, no limit is enforced.
+	PodAnnotationMaxSize int `hcl:"pod_annotation_max_size"`
+
+	// ResolvePodOwners enables the pod-owner and pod-owner-uid selectors for
+	// the controller of the ReplicaSet owning the pod (e.g. a Deployment),
+	// which are looked up in the API server.
+	ResolvePodOwners bool `hcl:"resolve_pod_owners"`
+
+	// KubeConfigFilePath is the path to the kubeconfig file used to contact
+	// the API server when ResolvePodOwners is set. If unset, the in-cluster
+	// configuration is used.
+	KubeConfigFilePath string `hcl:"kube_config_file_path"`
+
+	// PodOwnerCacheTTL controls how long the owners looked up in the API
+	// server are cached.
+	PodOwnerCacheTTL string `hcl:"pod_owner_cache_ttl"`
 }
 
 // k8sConfig holds the configuration distilled from HCL
@@ -131,6 +160,8 @@ type k8sConfig struct {
 	KubeletCAPath           string
 	NodeName                string
 	ReloadInterval          time.Duration
+	PodAnnotations          *annotationFilter
+	PodOwners               *podOwnerResolver
 
 	Client     *kubeletClient
 	LastReload time.Time
@@ -145,6 +176,8 @@ type Plugin struct {
 	clock  clock.Clock
 	getenv func(string) string
 
+	newReplicaSetGetter func(kubeConfigFilePath string) (replicaSetGetter, error)
+
 	mu     sync.RWMutex
 	config *k8sConfig
 }
@@ -154,6 +187,8 @@ func New() *Plugin {
 		fs:     cgroups.OSFileSystem{},
 		clock:  clock.New(),
 		getenv: os.Getenv,
+
+		newReplicaSetGetter: newReplicaSetGetter,
 	}
 }
 
@@ -201,8 +236,18 @@ func (p *Plugin) Attest(ctx context.Context, req *workloadattestorv1.AttestReque
 			status, lookup := lookUpContainerInPod(containerID, item.Status)
 			switch lookup {
 			case containerInPod:
+				selectorValues := getSelectorValuesFromPodInfo(&item, status, config.PodAnnotations)
+				if config.PodOwners != nil {
+					ownerSelectorValues, err := config.PodOwners.selectorValues(ctx, &item)
+					if err != nil {
+						// The attestation goes on without the selectors for
+						// the owner since they only grant more identities.
+						log.Warn("Unable to resolve the pod owner", telemetry.Error, err)
+					}
+					selectorValues = append(selectorValues, ownerSelectorValues...)
+				}
 				return &workloadattestorv1.AttestResponse{
-					SelectorValues: getSelectorValuesFromPodInfo(&item, status),
+					SelectorValues: selectorValues,
 				}, nil
 			case containerNotInPod:
 			}
@@ -262,6 +307,32 @@ func (p *Plugin) Configure(ctx context.Context, req *configv1.ConfigureRequest)
 		reloadInterval = defaultReloadInterval
 	}
 
+	// Determine the pod annotation selectors filter
+	var podAnnotations *annotationFilter
+	if config.PodAnnotationSelectors {
+		if config.PodAnnotationMaxSize < 0 {
+			return nil, status.Error(codes.InvalidArgument, "pod_annotation_max_size cannot be negative")
+		}
+		podAnnotations = newAnnotationFilter(config.PodAnnotationAllowList, config.PodAnnotationDenyList, config.PodAnnotationMaxSize)
+	}
+
+	// Determine the pod owner resolver
+	var podOwners *podOwnerResolver
+	if config.ResolvePodOwners {
+		podOwnerCacheTTL := defaultPodOwnerCacheTTL
+		if config.PodOwnerCacheTTL != "" {
+			podOwnerCacheTTL, err = time.ParseDuration(config.PodOwnerCacheTTL)
+			if err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "unable to parse pod owner cache TTL: %v", err)
+			}
+		}
+		getter, err := p.newReplicaSetGetter(config.KubeConfigFilePath)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "unable to create the API server client: %v", err)
+		}
+		podOwners = newPodOwnerResolver(getter, p.clock, podOwnerCacheTTL)
+	}
+
 	// Determine which kubelet port to hit. Default to the secure port if none
 	// is specified (this is backwards compatible because the read-only-port
 	// config value has always been required, so it should already be set in
@@ -296,6 +367,8 @@ func (p *Plugin) Configure(ctx context.Context, req *configv1.ConfigureRequest)
 		KubeletCAPath:           config.KubeletCAPath,
 		NodeName:                nodeName,
 		ReloadInterval:          reloadInterval,
+		PodAnnotations:          podAnnotations,
+		PodOwners:               podOwners,
 	}
 	if err := p.reloadKubeletClient(c); err != nil {
 		return nil, err
@@ -674,7 +747,7 @@ func getPodImageIdentifiers(containerStatusArray []corev1.ContainerStatus) map[s
 	return podImages
 }
 
-func getSelectorValuesFromPodInfo(pod *corev1.Pod, status *corev1.ContainerStatus) []string {
+func getSelectorValuesFromPodInfo(pod *corev1.Pod, status *corev1.ContainerStatus, podAnnotations *annotationFilter) []string {
 	podImageIdentifiers := getPodImageIdentifiers(pod.Status.ContainerStatuses)
 	podInitImageIdentifiers := getPodImageIdentifiers(pod.Status.InitContainerStatuses)
 	containerImageIdentifiers := getPodImageIdentifiers([]corev1.ContainerStatus{*status})
@@ -703,6 +776,13 @@ func getSelectorValuesFromPodInfo(pod *corev1.Pod, status *corev1.ContainerStatu
 	for k, v := range pod.Labels {
 		selectorValues = append(selectorValues, fmt.Sprintf("pod-label:%s:%s", k, v))
 	}
+	if podAnnotations != nil {
+		for k, v := range pod.Annotations {
+			if podAnnotations.allowed(k, v) {
+				selectorValues = append(selectorValues, fmt.Sprintf("pod-annotation:%s:%s", k, v))
+			}
+		}
+	}
 	for _, ownerReference := range pod.OwnerReferences {
 		selectorValues = append(selectorValues, fmt.Sprintf("pod-owner:%s:%s", ownerReference.Kind, ownerReference.Name))
 		selectorValues = append(selectorValues, fmt.Sprintf("pod-owner-uid:%s:%s", ownerReference.Kind, ownerReference.UID))
@@ -711,6 +791,42 @@ func getSelectorValuesFromPodInfo(pod *corev1.Pod, status *corev1.ContainerStatu
 	return selectorValues
 }
 
+// annotationFilter decides which pod annotations produce selectors.
+type annotationFilter struct {
+	allowList map[string]struct{}
+	denyList  map[string]struct{}
+	maxSize   int
+}
+
+func newAnnotationFilter(allowList, denyList []string, maxSize int) *annotationFilter {
+	f := &annotationFilter{
+		denyList: make(map[string]struct{}, len(denyList)),
+		maxSize:  maxSize,
+	}
+	if len(allowList) > 0 {
+		f.allowList = make(map[string]struct{}, len(allowList))
+		for _, key := range allowList {
+			f.allowList[key] = struct{}{}
+		}
+	}
+	for _, key := range denyList {
+		f.denyList[key] = struct{}{}
+	}
+	return f
+}
+
+func (f *annotationFilter) allowed(key, value string) bool {
+	if f.allowList != nil {
+		if _, ok := f.allowList[key]; !ok {
+			return false
+		}
+	}
+	if _, ok := f.denyList[key]; ok {
+		return false
+	}
+	return f.maxSize == 0 || len(value) <= f.maxSize
+}
+
 func tryRead(r io.Reader) string {
 	buf := make([]byte, 1024)
 	n, _ := r.Read(buf)
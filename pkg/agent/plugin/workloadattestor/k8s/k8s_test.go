@@ -33,7 +33,10 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/codes"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 const (
@@ -156,6 +159,9 @@ type Suite struct {
 	server      *httptest.Server
 	kubeletCert *x509.Certificate
 	clientCert  *x509.Certificate
+
+	// API server stuff
+	clientset *fake.Clientset
 }
 
 func (s *Suite) SetupTest() {
@@ -167,6 +173,7 @@ func (s *Suite) SetupTest() {
 
 	s.podList = nil
 	s.env = map[string]string{}
+	s.clientset = fake.NewSimpleClientset()
 }
 
 func (s *Suite) TearDownTest() {
@@ -195,6 +202,91 @@ func (s *Suite) TestAttestWithPidInPodSystemdCgroups() {
 	s.requireAttestSuccessWithPodSystemdCgroups(p)
 }
 
+func (s *Suite) TestAttestWithPodAnnotations() {
+	s.startInsecureKubelet()
+	p := s.loadPlugin(fmt.Sprintf(`
+		kubelet_read_only_port = %d
+		pod_annotation_selectors = true
+		pod_annotation_deny_list = ["kubernetes.io/config.seen"]
+		pod_annotation_max_size = 64
+`, s.kubeletPort()))
+
+	s.addPodListResponse(podListFilePath)
+	s.addCgroupsResponse(cgPidInPodFilePath)
+
+	// The kubernetes.io/created-by annotation is larger than the max size
+	expectedSelectors := append([]*common.Selector{
+		{Type: "k8s", Value: "pod-annotation:kubernetes.io/config.source:api"},
+	}, testPodSelectors...)
+	util.SortSelectors(expectedSelectors)
+	s.requireAttestSuccess(p, expectedSelectors)
+}
+
+func (s *Suite) TestAttestWithPodOwnerResolution() {
+	s.startInsecureKubelet()
+	p := s.loadPlugin(fmt.Sprintf(`
+		kubelet_read_only_port = %d
+		resolve_pod_owners = true
+		pod_owner_cache_ttl = "1m"
+`, s.kubeletPort()))
+
+	// The ReplicaSet owning the pod is owned by a Deployment
+	controller := true
+	_, err := s.clientset.AppsV1().ReplicaSets("default").Create(context.Background(), &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "sample-workload-6658cb9566",
+			UID:       "349d135e-3781-43e3-bc25-c900aedf1d0c",
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: "apps/v1",
+					Kind:       "Deployment",
+					Name:       "sample-workload",
+					UID:        "c2e8bb72-4b2c-4b35-9a67-2fd2c8a0a7f9",
+					Controller: &controller,
+				},
+			},
+		},
+	}, metav1.CreateOptions{})
+	s.Require().NoError(err)
+	s.clientset.ClearActions()
+
+	expectedSelectors := append([]*common.Selector{
+		{Type: "k8s", Value: "pod-owner:Deployment:sample-workload"},
+		{Type: "k8s", Value: "pod-owner-uid:Deployment:c2e8bb72-4b2c-4b35-9a67-2fd2c8a0a7f9"},
+	}, testKindPodSelectors...)
+	util.SortSelectors(expectedSelectors)
+
+	// The owner of the ReplicaSet is looked up once and then cached
+	for i := 0; i < 2; i++ {
+		s.addPodListResponse(kindPodListFilePath)
+		s.addCgroupsResponse(cgPidInKindPodFilePath)
+		s.requireAttestSuccess(p, expectedSelectors)
+	}
+	s.Require().Len(s.clientset.Actions(), 1)
+
+	// The owner is looked up again once the cache entry expires
+	s.clock.Add(time.Minute)
+	s.addPodListResponse(kindPodListFilePath)
+	s.addCgroupsResponse(cgPidInKindPodFilePath)
+	s.requireAttestSuccess(p, expectedSelectors)
+	s.Require().Len(s.clientset.Actions(), 2)
+}
+
+func (s *Suite) TestAttestWithUnresolvablePodOwner() {
+	s.startInsecureKubelet()
+	p := s.loadPlugin(fmt.Sprintf(`
+		kubelet_read_only_port = %d
+		resolve_pod_owners = true
+`, s.kubeletPort()))
+
+	// The ReplicaSet owning the pod cannot be found, so the attestation
+	// succeeds without the selectors for its owner
+	s.addPodListResponse(kindPodListFilePath)
+	s.addCgroupsResponse(cgPidInKindPodFilePath)
+	s.requireAttestSuccess(p, testKindPodSelectors)
+}
+
 func (s *Suite) TestAttestWithInitPidInPod() {
 	s.startInsecureKubelet()
 	p := s.loadInsecurePlugin()
@@ -474,6 +566,14 @@ func (s *Suite) TestConfigure() {
 			`,
 			err: "cannot use both the read-only and secure port",
 		},
+		{
+			name: "negative pod annotation max size",
+			hcl: `
+				pod_annotation_selectors = true
+				pod_annotation_max_size = -1
+			`,
+			err: "pod_annotation_max_size cannot be negative",
+		},
 		{
 			name: "non-existent kubelet ca",
 			hcl: `
@@ -512,6 +612,15 @@ func (s *Suite) TestConfigure() {
 			`,
 			err: "unable to parse reload interval",
 		},
+		{
+			name: "invalid pod owner cache TTL",
+			hcl: `
+				kubelet_read_only_port = 10255
+				resolve_pod_owners = true
+				pod_owner_cache_ttl = "blah"
+			`,
+			err: "unable to parse pod owner cache TTL",
+		},
 		{
 			name: "cert but no key",
 			hcl: `
@@ -621,6 +730,9 @@ func (s *Suite) newPlugin() *Plugin {
 	p.getenv = func(key string) string {
 		return s.env[key]
 	}
+	p.newReplicaSetGetter = func(string) (replicaSetGetter, error) {
+		return clientsetReplicaSetGetter{clientset: s.clientset}, nil
+	}
 	return p
 }
 
@@ -854,6 +966,74 @@ func (s *Suite) addCgroupsResponse(fixturePath string) {
 	s.Require().NoError(os.Symlink(filepath.Join(wd, fixturePath), cgroupPath))
 }
 
+func TestAnnotationFilter(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		allowList []string
+		denyList  []string
+		maxSize   int
+		key       string
+		value     string
+		allowed   bool
+	}{
+		{
+			name:    "no filtering",
+			key:     "example.org/team",
+			value:   "payments",
+			allowed: true,
+		},
+		{
+			name:      "included by allow list",
+			allowList: []string{"example.org/team"},
+			key:       "example.org/team",
+			value:     "payments",
+			allowed:   true,
+		},
+		{
+			name:      "not in allow list",
+			allowList: []string{"example.org/team"},
+			key:       "example.org/owner",
+			value:     "alice",
+			allowed:   false,
+		},
+		{
+			name:     "excluded by deny list",
+			denyList: []string{"example.org/team"},
+			key:      "example.org/team",
+			value:    "payments",
+			allowed:  false,
+		},
+		{
+			name:      "deny list takes precedence over allow list",
+			allowList: []string{"example.org/team"},
+			denyList:  []string{"example.org/team"},
+			key:       "example.org/team",
+			value:     "payments",
+			allowed:   false,
+		},
+		{
+			name:    "within max size",
+			maxSize: 8,
+			key:     "example.org/team",
+			value:   "payments",
+			allowed: true,
+		},
+		{
+			name:    "oversized",
+			maxSize: 7,
+			key:     "example.org/team",
+			value:   "payments",
+			allowed: false,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAnnotationFilter(tt.allowList, tt.denyList, tt.maxSize)
+			require.Equal(t, tt.allowed, f.allowed(tt.key, tt.value))
+		})
+	}
+}
+
 func TestGetContainerIDFromCGroups(t *testing.T) {
 	makeCGroups := func(groupPaths []string) []cgroups.Cgroup {
 		var out []cgroups.Cgroup
@@ -0,0 +1,142 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/andres-erbsen/clock"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	defaultPodOwnerCacheTTL = 10 * time.Minute
+)
+
+// replicaSetGetter gets ReplicaSets from the API server.
+type replicaSetGetter interface {
+	GetReplicaSet(ctx context.Context, namespace, name string) (*appsv1.ReplicaSet, error)
+}
+
+// newReplicaSetGetter returns a replicaSetGetter for the API server
+// configured in the given kubeconfig file, or for the cluster the agent is
+// running in if no file is provided.
+func newReplicaSetGetter(kubeConfigFilePath string) (replicaSetGetter, error) {
+	var config *rest.Config
+	var err error
+	if kubeConfigFilePath != "" {
+		config, err = clientcmd.BuildConfigFromFlags("", kubeConfigFilePath)
+	} else {
+		config, err = rest.InClusterConfig()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return clientsetReplicaSetGetter{clientset: clientset}, nil
+}
+
+type clientsetReplicaSetGetter struct {
+	clientset kubernetes.Interface
+}
+
+func (g clientsetReplicaSetGetter) GetReplicaSet(ctx context.Context, namespace, name string) (*appsv1.ReplicaSet, error) {
+	return g.clientset.AppsV1().ReplicaSets(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+// podOwnerResolver resolves the workload controller owning a pod through an
+// intermediary ReplicaSet, e.g. the Deployment that owns the ReplicaSet that
+// owns the pod. Pods owned directly by their workload controller (e.g.
+// StatefulSets and DaemonSets) already get selectors for their owner from the
+// pod itself.
+type podOwnerResolver struct {
+	getter   replicaSetGetter
+	clock    clock.Clock
+	cacheTTL time.Duration
+
+	mu sync.Mutex
+	// cache holds the controller of each ReplicaSet looked up, keyed by the
+	// ReplicaSet UID, to avoid querying the API server on every attestation.
+	cache map[types.UID]podOwnerCacheEntry
+}
+
+type podOwnerCacheEntry struct {
+	// owner is the controller of the ReplicaSet, or nil if it has none.
+	owner     *metav1.OwnerReference
+	expiresAt time.Time
+}
+
+func newPodOwnerResolver(getter replicaSetGetter, clk clock.Clock, cacheTTL time.Duration) *podOwnerResolver {
+	return &podOwnerResolver{
+		getter:   getter,
+		clock:    clk,
+		cacheTTL: cacheTTL,
+		cache:    make(map[types.UID]podOwnerCacheEntry),
+	}
+}
+
+// selectorValues returns the pod-owner and pod-owner-uid selector values for
+// the controller of the ReplicaSet controlling the pod, if any.
+func (r *podOwnerResolver) selectorValues(ctx context.Context, pod *corev1.Pod) ([]string, error) {
+	podOwner := metav1.GetControllerOf(pod)
+	if podOwner == nil || podOwner.Kind != "ReplicaSet" {
+		return nil, nil
+	}
+
+	owner, err := r.getReplicaSetOwner(ctx, pod.Namespace, podOwner)
+	if err != nil || owner == nil {
+		return nil, err
+	}
+	return []string{
+		fmt.Sprintf("pod-owner:%s:%s", owner.Kind, owner.Name),
+		fmt.Sprintf("pod-owner-uid:%s:%s", owner.Kind, owner.UID),
+	}, nil
+}
+
+func (r *podOwnerResolver) getReplicaSetOwner(ctx context.Context, namespace string, replicaSetRef *metav1.OwnerReference) (*metav1.OwnerReference, error) {
+	now := r.clock.Now()
+
+	r.mu.Lock()
+	entry, ok := r.cache[replicaSetRef.UID]
+	r.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.owner, nil
+	}
+
+	replicaSet, err := r.getter.GetReplicaSet(ctx, namespace, replicaSetRef.Name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get ReplicaSet %s/%s: %w", namespace, replicaSetRef.Name, err)
+	}
+	if replicaSet.UID != replicaSetRef.UID {
+		// The ReplicaSet owning the pod has been replaced by another one
+		// with the same name.
+		return nil, fmt.Errorf("ReplicaSet %s/%s has UID %s; expected %s", namespace, replicaSetRef.Name, replicaSet.UID, replicaSetRef.UID)
+	}
+	owner := metav1.GetControllerOf(replicaSet)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Drop the expired entries so that ReplicaSets that are gone do not
+	// pile up in the cache.
+	for uid, entry := range r.cache {
+		if !now.Before(entry.expiresAt) {
+			delete(r.cache, uid)
+		}
+	}
+	r.cache[replicaSetRef.UID] = podOwnerCacheEntry{
+		owner:     owner,
+		expiresAt: now.Add(r.cacheTTL),
+	}
+	return owner, nil
+}
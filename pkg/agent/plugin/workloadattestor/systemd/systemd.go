@@ -0,0 +1,143 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl"
+	workloadattestorv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/agent/workloadattestor/v1"
+	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
+	"github.com/spiffe/spire/pkg/agent/common/cgroups"
+	"github.com/spiffe/spire/pkg/common/catalog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	pluginName = "systemd"
+
+	subselectorUnit  = "unit"
+	subselectorSlice = "slice"
+
+	sliceSuffix = ".slice"
+)
+
+// unitSuffixes are the suffixes of the systemd unit types that can own
+// processes.
+var unitSuffixes = []string{".service", ".scope", ".socket", ".mount", ".swap"}
+
+func BuiltIn() catalog.BuiltIn {
+	return builtin(New())
+}
+
+func builtin(p *Plugin) catalog.BuiltIn {
+	return catalog.MakeBuiltIn(pluginName,
+		workloadattestorv1.WorkloadAttestorPluginServer(p),
+		configv1.ConfigServiceServer(p),
+	)
+}
+
+// Configuration is the systemd workload attestor configuration. The plugin
+// has no configurables at the moment.
+type Configuration struct{}
+
+type Plugin struct {
+	workloadattestorv1.UnsafeWorkloadAttestorServer
+	configv1.UnsafeConfigServer
+
+	log hclog.Logger
+	fs  cgroups.FileSystem
+}
+
+func New() *Plugin {
+	return &Plugin{
+		fs: cgroups.OSFileSystem{},
+	}
+}
+
+func (p *Plugin) SetLogger(log hclog.Logger) {
+	p.log = log
+}
+
+func (p *Plugin) Attest(ctx context.Context, req *workloadattestorv1.AttestRequest) (*workloadattestorv1.AttestResponse, error) {
+	cgroupList, err := cgroups.GetCgroups(req.Pid, p.fs)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		// There is no cgroup information for the process (e.g. the host
+		// does not run Linux). It cannot be a systemd workload.
+		return &workloadattestorv1.AttestResponse{}, nil
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "unable to read cgroups: %v", err)
+	}
+
+	unit, slice := unitFromCgroups(cgroupList)
+	if unit == "" {
+		// Not a systemd workload. Nothing more to do.
+		return &workloadattestorv1.AttestResponse{}, nil
+	}
+
+	selectorValues := []string{fmt.Sprintf("%s:%s", subselectorUnit, unit)}
+	if slice != "" {
+		selectorValues = append(selectorValues, fmt.Sprintf("%s:%s", subselectorSlice, slice))
+	}
+	return &workloadattestorv1.AttestResponse{
+		SelectorValues: selectorValues,
+	}, nil
+}
+
+func (p *Plugin) Configure(ctx context.Context, req *configv1.ConfigureRequest) (*configv1.ConfigureResponse, error) {
+	config := new(Configuration)
+	if err := hcl.Decode(config, req.HclConfiguration); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to decode configuration: %v", err)
+	}
+	return &configv1.ConfigureResponse{}, nil
+}
+
+// unitFromCgroups returns the systemd unit owning the process, and the slice
+// containing that unit, from the cgroup hierarchy managed by systemd. The
+// unified hierarchy (cgroup v2) is used when the named systemd hierarchy
+// (cgroup v1) is not present.
+func unitFromCgroups(cgroupList []cgroups.Cgroup) (unit, slice string) {
+	var groupPath string
+	for _, cgroup := range cgroupList {
+		switch {
+		case cgroup.ControllerList == "name=systemd":
+			return unitFromGroupPath(cgroup.GroupPath)
+		case cgroup.HierarchyID == "0" && cgroup.ControllerList == "":
+			groupPath = cgroup.GroupPath
+		}
+	}
+	return unitFromGroupPath(groupPath)
+}
+
+// unitFromGroupPath returns the innermost unit in the cgroup path, along
+// with the slice it belongs to. For example, "/system.slice/nginx.service"
+// yields the "nginx.service" unit in the "system.slice" slice. Units running
+// under a user manager (e.g. "/user.slice/user-1000.slice/user@1000.service/app.slice/foo.service")
+// resolve to the user unit ("foo.service" in "app.slice").
+func unitFromGroupPath(groupPath string) (unit, slice string) {
+	var currentSlice string
+	for _, component := range strings.Split(groupPath, "/") {
+		switch {
+		case strings.HasSuffix(component, sliceSuffix):
+			currentSlice = component
+		case isUnitName(component):
+			unit = component
+			slice = currentSlice
+		}
+	}
+	return unit, slice
+}
+
+func isUnitName(name string) bool {
+	for _, suffix := range unitSuffixes {
+		if len(name) > len(suffix) && strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
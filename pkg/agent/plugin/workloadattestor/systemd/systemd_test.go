@@ -0,0 +1,142 @@
+package systemd
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spiffe/spire/pkg/agent/plugin/workloadattestor"
+	"github.com/spiffe/spire/proto/spire/common"
+	"github.com/spiffe/spire/test/plugintest"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+const testPID = 123
+
+func TestAttest(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		cgroups   string
+		selectors []string
+		err       string
+	}{
+		{
+			name: "cgroup v2 system service",
+			cgroups: `0::/system.slice/nginx.service
+`,
+			selectors: []string{"unit:nginx.service", "slice:system.slice"},
+		},
+		{
+			name: "cgroup v1 named systemd hierarchy",
+			cgroups: `12:memory:/system.slice/nginx.service
+11:cpu,cpuacct:/system.slice/nginx.service
+1:name=systemd:/system.slice/nginx.service
+0::/
+`,
+			selectors: []string{"unit:nginx.service", "slice:system.slice"},
+		},
+		{
+			name: "nested slices",
+			cgroups: `0::/system.slice/workloads.slice/workloads-web.slice/api.service
+`,
+			selectors: []string{"unit:api.service", "slice:workloads-web.slice"},
+		},
+		{
+			name: "sub-cgroup of a service",
+			cgroups: `0::/system.slice/containerd.service/payload
+`,
+			selectors: []string{"unit:containerd.service", "slice:system.slice"},
+		},
+		{
+			name: "scope",
+			cgroups: `0::/user.slice/user-1000.slice/session-2.scope
+`,
+			selectors: []string{"unit:session-2.scope", "slice:user-1000.slice"},
+		},
+		{
+			name: "user manager unit",
+			cgroups: `0::/user.slice/user-1000.slice/user@1000.service/app.slice/foo.service
+`,
+			selectors: []string{"unit:foo.service", "slice:app.slice"},
+		},
+		{
+			name: "unit outside of a slice",
+			cgroups: `0::/init.scope
+`,
+			selectors: []string{"unit:init.scope"},
+		},
+		{
+			name: "root cgroup",
+			cgroups: `0::/
+`,
+		},
+		{
+			name: "slice without unit",
+			cgroups: `0::/system.slice
+`,
+		},
+		{
+			name: "not managed by systemd",
+			cgroups: `0::/kubepods/besteffort/pod2c48913c-b29f-11e7-9350-020968147796/9bca8d63d5fa610783847915bcff0ecac1273e5b4bed3f6fa1b07350e0135961
+`,
+		},
+		{
+			name: "no cgroup information",
+		},
+		{
+			name:    "malformed cgroups",
+			cgroups: "malformed",
+			err:     "workloadattestor(systemd): unable to read cgroups: cgroup entry contains 1 colons, but expected at least 2 colons",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			fs := fakeFileSystem{}
+			if tt.cgroups != "" {
+				fs["/proc/123/cgroup"] = tt.cgroups
+			}
+
+			p := New()
+			p.fs = fs
+			attestor := new(workloadattestor.V1)
+			plugintest.Load(t, builtin(p), attestor)
+
+			selectors, err := attestor.Attest(context.Background(), testPID)
+			if tt.err != "" {
+				spiretest.RequireGRPCStatusContains(t, err, codes.Internal, tt.err)
+				return
+			}
+			require.NoError(t, err)
+
+			var expected []*common.Selector
+			for _, value := range tt.selectors {
+				expected = append(expected, &common.Selector{Type: pluginName, Value: value})
+			}
+			spiretest.RequireProtoListEqual(t, expected, selectors)
+		})
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	var err error
+	plugintest.Load(t, BuiltIn(), nil,
+		plugintest.Configure("MALFORMED"),
+		plugintest.CaptureConfigureError(&err))
+	spiretest.RequireGRPCStatusContains(t, err, codes.InvalidArgument, "failed to decode configuration")
+
+	plugintest.Load(t, BuiltIn(), nil, plugintest.Configure(""))
+}
+
+type fakeFileSystem map[string]string
+
+func (fs fakeFileSystem) Open(path string) (io.ReadCloser, error) {
+	data, ok := fs[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return io.NopCloser(strings.NewReader(data)), nil
+}
@@ -0,0 +1,33 @@
+//go:build !windows
+// +build !windows
+
+package unix
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileKey identifies the contents of a file by its inode, modification time
+// and size.
+type fileKey struct {
+	dev   uint64
+	ino   uint64
+	mtime int64
+	size  int64
+}
+
+// getFileKey returns the key identifying the contents of the file, along with
+// its number of hard links, which is zero when the file has been deleted.
+func getFileKey(fi os.FileInfo) (fileKey, uint64, bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileKey{}, 0, false
+	}
+	return fileKey{
+		dev:   uint64(st.Dev), //nolint: unconvert // Dev is not a uint64 on all platforms
+		ino:   st.Ino,
+		mtime: fi.ModTime().UnixNano(),
+		size:  fi.Size(),
+	}, uint64(st.Nlink), true //nolint: unconvert // Nlink is not a uint64 on all platforms
+}
@@ -0,0 +1,15 @@
+//go:build windows
+// +build windows
+
+package unix
+
+import (
+	"os"
+)
+
+type fileKey struct{}
+
+// getFileKey is not supported on Windows. Digests are not cached.
+func getFileKey(fi os.FileInfo) (fileKey, uint64, bool) {
+	return fileKey{}, 0, false
+}
@@ -27,6 +27,11 @@ import (
 
 const (
 	pluginName = "unix"
+
+	// maxHashCacheEntries bounds the number of binary digests kept in the
+	// cache. The cache is reset when the bound is reached, which only
+	// happens when a large number of distinct binaries is attested.
+	maxHashCacheEntries = 1024
 )
 
 func BuiltIn() catalog.BuiltIn {
@@ -107,6 +112,9 @@ type Plugin struct {
 	config *Configuration
 	log    hclog.Logger
 
+	hashCacheMu sync.Mutex
+	hashCache   map[fileKey]string
+
 	// hooks for tests
 	hooks struct {
 		newProcess      func(pid int32) (processInfo, error)
@@ -116,7 +124,9 @@ type Plugin struct {
 }
 
 func New() *Plugin {
-	p := &Plugin{}
+	p := &Plugin{
+		hashCache: make(map[fileKey]string),
+	}
 	p.hooks.newProcess = func(pid int32) (processInfo, error) { p, err := process.NewProcess(pid); return PSProcessInfo{p}, err }
 	p.hooks.lookupUserByID = user.LookupId
 	p.hooks.lookupGroupByID = user.LookupGroupId
@@ -185,12 +195,14 @@ func (p *Plugin) Attest(ctx context.Context, req *workloadattestorv1.AttestReque
 
 		if config.WorkloadSizeLimit >= 0 {
 			exePath := p.getNamespacedPath(proc)
-			sha256Digest, err := getSHA256Digest(exePath, config.WorkloadSizeLimit)
+			sha256Digest, err := p.getSHA256Digest(exePath, config.WorkloadSizeLimit)
 			if err != nil {
 				return nil, err
 			}
 
-			selectorValues = append(selectorValues, makeSelectorValue("sha256", sha256Digest))
+			if sha256Digest != "" {
+				selectorValues = append(selectorValues, makeSelectorValue("sha256", sha256Digest))
+			}
 		}
 	}
 
@@ -287,28 +299,66 @@ func (p *Plugin) getNamespacedPath(proc processInfo) string {
 	return proc.NamespacedExe()
 }
 
-func getSHA256Digest(path string, limit int64) (string, error) {
+// getSHA256Digest returns the SHA256 digest of the workload binary. An empty
+// digest is returned when the binary cannot be read or has been deleted since
+// the workload was started, since the digest would not reliably identify the
+// running workload. Digests are cached by file identity and modification
+// time, so the same binary is only hashed once.
+func (p *Plugin) getSHA256Digest(path string, limit int64) (string, error) {
 	f, err := os.Open(path)
 	if err != nil {
-		return "", status.Errorf(codes.Internal, "SHA256 digest: %v", err)
+		p.log.Warn("Unable to open workload binary for hashing", "path", path, "error", err)
+		return "", nil
 	}
 	defer f.Close()
 
-	if limit > 0 {
-		fi, err := f.Stat()
-		if err != nil {
-			return "", status.Errorf(codes.Internal, "SHA256 digest: %v", err)
-		}
-		if fi.Size() > limit {
-			return "", status.Errorf(codes.Internal, "SHA256 digest: workload %s exceeds size limit (%d > %d)", path, fi.Size(), limit)
+	fi, err := f.Stat()
+	if err != nil {
+		p.log.Warn("Unable to stat workload binary for hashing", "path", path, "error", err)
+		return "", nil
+	}
+	if limit > 0 && fi.Size() > limit {
+		return "", status.Errorf(codes.Internal, "SHA256 digest: workload %s exceeds size limit (%d > %d)", path, fi.Size(), limit)
+	}
+
+	key, nlink, ok := getFileKey(fi)
+	if ok && nlink == 0 {
+		p.log.Warn("Workload binary has been deleted; not hashing", "path", path)
+		return "", nil
+	}
+	if ok {
+		if digest, cached := p.getCachedDigest(key); cached {
+			return digest, nil
 		}
 	}
 
 	h := sha256.New()
 	if _, err := io.Copy(h, f); err != nil {
-		return "", status.Errorf(codes.Internal, "SHA256 digest: %v", err)
+		p.log.Warn("Unable to read workload binary for hashing", "path", path, "error", err)
+		return "", nil
+	}
+	digest := hex.EncodeToString(h.Sum(nil))
+
+	if ok {
+		p.setCachedDigest(key, digest)
+	}
+	return digest, nil
+}
+
+func (p *Plugin) getCachedDigest(key fileKey) (string, bool) {
+	p.hashCacheMu.Lock()
+	defer p.hashCacheMu.Unlock()
+	digest, ok := p.hashCache[key]
+	return digest, ok
+}
+
+func (p *Plugin) setCachedDigest(key fileKey, digest string) {
+	p.hashCacheMu.Lock()
+	defer p.hashCacheMu.Unlock()
+	if len(p.hashCache) >= maxHashCacheEntries {
+		p.hashCache = make(map[fileKey]string)
 	}
-	return hex.EncodeToString(h.Sum(nil)), nil
+	p.hashCache[key] = digest
 }
 
 func makeSelectorValue(kind, value string) string {
@@ -9,8 +9,10 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/sirupsen/logrus/hooks/test"
@@ -149,11 +151,27 @@ func (s *Suite) TestAttest() {
 			expectMsg:  "workloadattestor(unix): path lookup: unable to get EXE for PID 9",
 		},
 		{
-			name:       "fail to hash process binary",
-			pid:        10,
-			config:     "discover_workload_path = true",
-			expectCode: codes.Internal,
-			expectMsg:  "workloadattestor(unix): SHA256 digest: open /proc/10/unreadable-exe: no such file or directory",
+			name:   "unreadable process binary is not hashed",
+			pid:    10,
+			config: "discover_workload_path = true",
+			selectorValues: []string{
+				"uid:1000",
+				"user:u1000",
+				"gid:2000",
+				"group:g2000",
+				fmt.Sprintf("path:%s", filepath.Join(s.dir, "unreadable-exe")),
+			},
+			expectCode: codes.OK,
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.WarnLevel,
+					Message: "Unable to open workload binary for hashing",
+					Data: logrus.Fields{
+						"path":          "/proc/10/unreadable-exe",
+						logrus.ErrorKey: "open /proc/10/unreadable-exe: no such file or directory",
+					},
+				},
+			},
 		},
 		{
 			name:       "process binary exceeds size limits",
@@ -259,6 +277,65 @@ func (s *Suite) TestAttest() {
 	}
 }
 
+func (s *Suite) TestSHA256DigestIsCached() {
+	p := s.newPlugin()
+	plugintest.Load(s.T(), builtin(p), nil, plugintest.Log(s.log))
+
+	path := filepath.Join(s.dir, "exe")
+	mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	s.writeFile("exe", []byte("data"))
+	s.Require().NoError(os.Chtimes(path, mtime, mtime))
+
+	digest, err := p.getSHA256Digest(path, 0)
+	s.Require().NoError(err)
+	s.Require().Equal("3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7", digest)
+
+	// Rewrite the binary without changing its size or modification time. The
+	// cached digest is returned since the binary is not hashed again.
+	s.writeFile("exe", []byte("DATA"))
+	s.Require().NoError(os.Chtimes(path, mtime, mtime))
+	digest, err = p.getSHA256Digest(path, 0)
+	s.Require().NoError(err)
+	s.Require().Equal("3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7", digest)
+
+	// Once the modification time changes, the binary is hashed again.
+	s.Require().NoError(os.Chtimes(path, mtime.Add(time.Second), mtime.Add(time.Second)))
+	digest, err = p.getSHA256Digest(path, 0)
+	s.Require().NoError(err)
+	s.Require().Equal("c97c29c7a71b392b437ee03fd17f09bb10b75e879466fc0eb757b2c4a78ac938", digest)
+}
+
+func (s *Suite) TestSHA256DigestOfDeletedBinary() {
+	if runtime.GOOS != "linux" {
+		s.T().Skip("relies on /proc to open deleted files")
+	}
+
+	p := s.newPlugin()
+	plugintest.Load(s.T(), builtin(p), nil, plugintest.Log(s.log))
+
+	// A deleted binary can still be opened through /proc, like the
+	// executable of a process whose binary was removed after it started.
+	s.writeFile("deleted-exe", []byte("data"))
+	f, err := os.Open(filepath.Join(s.dir, "deleted-exe"))
+	s.Require().NoError(err)
+	defer f.Close()
+	s.Require().NoError(os.Remove(filepath.Join(s.dir, "deleted-exe")))
+
+	path := fmt.Sprintf("/proc/self/fd/%d", f.Fd())
+	digest, err := p.getSHA256Digest(path, 0)
+	s.Require().NoError(err)
+	s.Require().Empty(digest)
+	spiretest.AssertLogs(s.T(), s.logHook.AllEntries(), []spiretest.LogEntry{
+		{
+			Level:   logrus.WarnLevel,
+			Message: "Workload binary has been deleted; not hashing",
+			Data: logrus.Fields{
+				"path": path,
+			},
+		},
+	})
+}
+
 func (s *Suite) writeFile(path string, data []byte) {
 	s.Require().NoError(os.WriteFile(filepath.Join(s.dir, path), data, 0600))
 }
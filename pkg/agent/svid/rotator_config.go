@@ -26,6 +26,10 @@ type RotatorConfig struct {
 	TrustDomain    spiffeid.TrustDomain
 	ServerAddr     string
 
+	// Maximum size in bytes of the messages exchanged with the server
+	MaxRecvMessageSize int
+	MaxSendMessageSize int
+
 	// Initial SVID and key
 	SVID    []*x509.Certificate
 	SVIDKey keymanager.Key
@@ -65,6 +69,9 @@ func newRotator(c *RotatorConfig) (*rotator, client.Client) {
 		Log:         c.Log,
 		Addr:        c.ServerAddr,
 		RotMtx:      rotMtx,
+
+		MaxRecvMessageSize: c.MaxRecvMessageSize,
+		MaxSendMessageSize: c.MaxSendMessageSize,
 		KeysAndBundle: func() ([]*x509.Certificate, crypto.Signer, []*x509.Certificate) {
 			s := state.Value().(State)
 
@@ -20,6 +20,11 @@ type SignerConfig struct {
 
 	// Issuer is used as the value of the issuer (iss) claim, if set.
 	Issuer string
+
+	// AllowedAlgorithms, if set, restricts the algorithms that tokens can be
+	// signed with. Signing fails if the algorithm for the signing key is not
+	// in the list.
+	AllowedAlgorithms []jose.SignatureAlgorithm
 }
 
 type Signer struct {
@@ -81,6 +86,10 @@ func (s *Signer) SignToken(id spiffeid.ID, audience []string, expires time.Time,
 		return "", errs.New("unable to determine signature algorithm for public key type %T", publicKey)
 	}
 
+	if !s.isAllowed(alg) {
+		return "", errs.New("signing algorithm %s is not allowed", alg)
+	}
+
 	jwtSigner, err := jose.NewSigner(
 		jose.SigningKey{
 			Algorithm: alg,
@@ -103,6 +112,18 @@ func (s *Signer) SignToken(id spiffeid.ID, audience []string, expires time.Time,
 	return signedToken, nil
 }
 
+func (s *Signer) isAllowed(alg jose.SignatureAlgorithm) bool {
+	if len(s.c.AllowedAlgorithms) == 0 {
+		return true
+	}
+	for _, allowed := range s.c.AllowedAlgorithms {
+		if allowed == alg {
+			return true
+		}
+	}
+	return false
+}
+
 func pruneEmptyValues(values []string) []string {
 	pruned := make([]string, 0, len(values))
 	for _, value := range values {
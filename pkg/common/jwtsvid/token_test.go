@@ -206,6 +206,21 @@ func (s *TokenSuite) TestSignEmptyAudience() {
 	s.Require().EqualError(err, "audience is required")
 }
 
+func (s *TokenSuite) TestSignWithAllowedAlgorithms() {
+	signer := NewSigner(SignerConfig{
+		Clock:             clock.NewMock(s.T()),
+		AllowedAlgorithms: []jose.SignatureAlgorithm{jose.RS256},
+	})
+
+	token, err := signer.SignToken(fakeSpiffeID, fakeAudience, time.Now().Add(time.Hour), rsa2048Key, "rsa2048Key")
+	s.Require().NoError(err)
+	_, _, err = ValidateToken(ctx, token, s.bundle, fakeAudience[0:1])
+	s.Require().NoError(err)
+
+	_, err = signer.SignToken(fakeSpiffeID, fakeAudience, time.Now().Add(time.Hour), ec256Key, "ec256Key")
+	s.Require().EqualError(err, "signing algorithm ES256 is not allowed")
+}
+
 func (s *TokenSuite) TestValidateBadAlgorithm() {
 	token := s.signToken(jose.HS256, []byte("BLAH"), jwt.Claims{})
 
@@ -291,6 +306,140 @@ func (s *TokenSuite) TestValidateUnexpectedAudienceList() {
 	s.Require().Nil(claims)
 }
 
+func (s *TokenSuite) TestValidateWithAudiencePrefix() {
+	token, err := s.signer.SignToken(fakeSpiffeID, []string{"spiffe://example.org/gateway/api"}, time.Now().Add(time.Hour), ec256Key, "ec256Key")
+	s.Require().NoError(err)
+
+	spiffeID, claims, err := ValidateTokenWithAudiencePrefix(ctx, token, s.bundle, "spiffe://example.org/gateway/")
+	s.Require().NoError(err)
+	s.Require().Equal(fakeSpiffeID, spiffeID)
+	s.Require().NotEmpty(claims)
+
+	spiffeID, claims, err = ValidateTokenWithAudiencePrefix(ctx, token, s.bundle, "spiffe://example.org/other/")
+	s.Require().EqualError(err, `expected audience with prefix "spiffe://example.org/other/" (audience=["spiffe://example.org/gateway/api"])`)
+	s.Require().Empty(spiffeID)
+	s.Require().Nil(claims)
+
+	spiffeID, claims, err = ValidateTokenWithAudiencePrefix(ctx, token, s.bundle, "")
+	s.Require().EqualError(err, "audience prefix is required")
+	s.Require().Empty(spiffeID)
+	s.Require().Nil(claims)
+
+	spiffeID, claims, err = ValidateTokenWithAudiencePrefix(ctx, token, s.bundle, "spiffe://example.org/gate")
+	s.Require().EqualError(err, `audience prefix "spiffe://example.org/gate" must end with "/"`)
+	s.Require().Empty(spiffeID)
+	s.Require().Nil(claims)
+}
+
+func (s *TokenSuite) TestValidateWithAudiencePrefixRejectsSiblingPrefix() {
+	token, err := s.signer.SignToken(fakeSpiffeID, []string{"spiffe://example.org/gatewayevil"}, time.Now().Add(time.Hour), ec256Key, "ec256Key")
+	s.Require().NoError(err)
+
+	spiffeID, claims, err := ValidateTokenWithAudiencePrefix(ctx, token, s.bundle, "spiffe://example.org/gateway/")
+	s.Require().EqualError(err, `expected audience with prefix "spiffe://example.org/gateway/" (audience=["spiffe://example.org/gatewayevil"])`)
+	s.Require().Empty(spiffeID)
+	s.Require().Nil(claims)
+}
+
+func (s *TokenSuite) TestValidateWithAudiencePrefixExpiredToken() {
+	token, err := s.signer.SignToken(fakeSpiffeID, []string{"spiffe://example.org/gateway/api"}, time.Now().Add(-time.Hour), ec256Key, "ec256Key")
+	s.Require().NoError(err)
+
+	spiffeID, claims, err := ValidateTokenWithAudiencePrefix(ctx, token, s.bundle, "spiffe://example.org/gateway/")
+	s.Require().EqualError(err, "token has expired")
+	s.Require().Empty(spiffeID)
+	s.Require().Nil(claims)
+}
+
+func (s *TokenSuite) TestValidateWithLeeway() {
+	now := time.Now()
+	leeway := 30 * time.Second
+
+	for _, tt := range []struct {
+		name   string
+		claims jwt.Claims
+		err    string
+	}{
+		{
+			name:   "expired within leeway",
+			claims: jwt.Claims{Expiry: jwt.NewNumericDate(now.Add(-20 * time.Second))},
+		},
+		{
+			name:   "expired outside of leeway",
+			claims: jwt.Claims{Expiry: jwt.NewNumericDate(now.Add(-40 * time.Second))},
+			err:    "token has expired",
+		},
+		{
+			name: "not yet valid within leeway",
+			claims: jwt.Claims{
+				NotBefore: jwt.NewNumericDate(now.Add(20 * time.Second)),
+				Expiry:    jwt.NewNumericDate(now.Add(time.Hour)),
+			},
+		},
+		{
+			name: "not yet valid outside of leeway",
+			claims: jwt.Claims{
+				NotBefore: jwt.NewNumericDate(now.Add(40 * time.Second)),
+				Expiry:    jwt.NewNumericDate(now.Add(time.Hour)),
+			},
+			err: "token is not yet valid",
+		},
+		{
+			name: "issued in the future within leeway",
+			claims: jwt.Claims{
+				IssuedAt: jwt.NewNumericDate(now.Add(20 * time.Second)),
+				Expiry:   jwt.NewNumericDate(now.Add(time.Hour)),
+			},
+		},
+		{
+			name: "issued in the future outside of leeway",
+			claims: jwt.Claims{
+				IssuedAt: jwt.NewNumericDate(now.Add(40 * time.Second)),
+				Expiry:   jwt.NewNumericDate(now.Add(time.Hour)),
+			},
+			err: "token was issued in the future",
+		},
+	} {
+		tt := tt
+		s.T().Run(tt.name, func(t *testing.T) {
+			tt.claims.Subject = fakeSpiffeID.String()
+			tt.claims.Audience = fakeAudience
+			token := s.signToken(jose.ES256, jose.JSONWebKey{Key: ec256Key, KeyID: "ec256Key"}, tt.claims)
+
+			spiffeID, claims, err := ValidateToken(ctx, token, s.bundle, fakeAudience, WithLeeway(leeway))
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				require.Empty(t, spiffeID)
+				require.Nil(t, claims)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, fakeSpiffeID, spiffeID)
+
+			tt.claims.Audience = []string{"spiffe://example.org/gateway/api"}
+			token = s.signToken(jose.ES256, jose.JSONWebKey{Key: ec256Key, KeyID: "ec256Key"}, tt.claims)
+			spiffeID, _, err = ValidateTokenWithAudiencePrefix(ctx, token, s.bundle, "spiffe://example.org/gateway/", WithLeeway(leeway))
+			require.NoError(t, err)
+			require.Equal(t, fakeSpiffeID, spiffeID)
+		})
+	}
+}
+
+func (s *TokenSuite) TestValidateWithoutLeeway() {
+	token := s.signToken(jose.ES256, jose.JSONWebKey{Key: ec256Key, KeyID: "ec256Key"}, jwt.Claims{
+		Subject:  fakeSpiffeID.String(),
+		Audience: fakeAudience,
+		Expiry:   jwt.NewNumericDate(time.Now().Add(-20 * time.Second)),
+	})
+
+	// The default leeway tolerates a token that just expired
+	_, _, err := ValidateToken(ctx, token, s.bundle, fakeAudience)
+	s.Require().NoError(err)
+
+	_, _, err = ValidateToken(ctx, token, s.bundle, fakeAudience, WithLeeway(0))
+	s.Require().EqualError(err, "token has expired")
+}
+
 func (s *TokenSuite) TestValidateKeyNotFound() {
 	token, err := s.signer.SignToken(fakeSpiffeID, fakeAudience, time.Now().Add(time.Hour), ec256Key, "whatever")
 	s.Require().NoError(err)
@@ -5,6 +5,7 @@ import (
 	"crypto"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
@@ -13,6 +14,25 @@ import (
 	"gopkg.in/square/go-jose.v2/jwt"
 )
 
+// DefaultLeeway is the clock skew leeway applied by default when validating
+// the time-based claims (exp, nbf and iat) of a token.
+const DefaultLeeway = jwt.DefaultLeeway
+
+// ValidateOption is an option for token validation.
+type ValidateOption func(*validateConfig)
+
+type validateConfig struct {
+	leeway time.Duration
+}
+
+// WithLeeway sets the clock skew leeway applied when validating the
+// time-based claims (exp, nbf and iat) of a token. Defaults to DefaultLeeway.
+func WithLeeway(leeway time.Duration) ValidateOption {
+	return func(c *validateConfig) {
+		c.leeway = leeway
+	}
+}
+
 type KeyStore interface {
 	FindPublicKey(ctx context.Context, td spiffeid.TrustDomain, kid string) (crypto.PublicKey, error)
 }
@@ -39,7 +59,41 @@ func (t *keyStore) FindPublicKey(ctx context.Context, td spiffeid.TrustDomain, k
 	return publicKey, nil
 }
 
-func ValidateToken(ctx context.Context, token string, keyStore KeyStore, audience []string) (spiffeid.ID, map[string]interface{}, error) {
+func ValidateToken(ctx context.Context, token string, keyStore KeyStore, audience []string, opts ...ValidateOption) (spiffeid.ID, map[string]interface{}, error) {
+	return validateToken(ctx, token, keyStore, audience, nil, opts)
+}
+
+// ValidateTokenWithAudiencePrefix validates the token like ValidateToken, but
+// instead of requiring an exact audience match, it accepts the token if any
+// of its audience values starts with the given prefix. The prefix must end
+// with "/", so that it only matches whole path segments, e.g. the
+// "spiffe://example.org/gateway/" prefix does not match
+// "spiffe://example.org/gatewayevil".
+func ValidateTokenWithAudiencePrefix(ctx context.Context, token string, keyStore KeyStore, audiencePrefix string, opts ...ValidateOption) (spiffeid.ID, map[string]interface{}, error) {
+	if audiencePrefix == "" {
+		return spiffeid.ID{}, nil, errs.New("audience prefix is required")
+	}
+	if !strings.HasSuffix(audiencePrefix, "/") {
+		return spiffeid.ID{}, nil, errs.New("audience prefix %q must end with \"/\"", audiencePrefix)
+	}
+	return validateToken(ctx, token, keyStore, nil, func(audience jwt.Audience) error {
+		for _, value := range audience {
+			if strings.HasPrefix(value, audiencePrefix) {
+				return nil
+			}
+		}
+		return errs.New("expected audience with prefix %q (audience=%q)", audiencePrefix, audience)
+	}, opts)
+}
+
+func validateToken(ctx context.Context, token string, keyStore KeyStore, audience []string, validateAudience func(jwt.Audience) error, opts []ValidateOption) (spiffeid.ID, map[string]interface{}, error) {
+	config := validateConfig{
+		leeway: DefaultLeeway,
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
 	tok, err := jwt.ParseSigned(token)
 	if err != nil {
 		return spiffeid.ID{}, nil, errs.New("unable to parse JWT token")
@@ -94,14 +148,18 @@ func ValidateToken(ctx context.Context, token string, keyStore KeyStore, audienc
 
 	// Now that the signature over the claims has been verified, validate the
 	// standard claims.
-	if err := claims.Validate(jwt.Expected{
+	if err := claims.ValidateWithLeeway(jwt.Expected{
 		Audience: audience,
 		Time:     time.Now(),
-	}); err != nil {
+	}, config.leeway); err != nil {
 		// Convert expected validation errors for pretty errors
 		switch {
 		case errors.Is(err, jwt.ErrExpired):
 			err = errs.New("token has expired")
+		case errors.Is(err, jwt.ErrNotValidYet):
+			err = errs.New("token is not yet valid")
+		case errors.Is(err, jwt.ErrIssuedInTheFuture):
+			err = errs.New("token was issued in the future")
 		case errors.Is(err, jwt.ErrInvalidAudience):
 			err = errs.New("expected audience in %q (audience=%q)", audience, claims.Audience)
 		default:
@@ -110,5 +168,11 @@ func ValidateToken(ctx context.Context, token string, keyStore KeyStore, audienc
 		return spiffeid.ID{}, nil, err
 	}
 
+	if validateAudience != nil {
+		if err := validateAudience(claims.Audience); err != nil {
+			return spiffeid.ID{}, nil, err
+		}
+	}
+
 	return spiffeID, claimsMap, nil
 }
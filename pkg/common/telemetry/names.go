@@ -193,6 +193,9 @@ const (
 	// to add clarity
 	Connections = "connections"
 
+	// ConsecutiveFailures tags the number of consecutive failures of some operation
+	ConsecutiveFailures = "consecutive_failures"
+
 	// ContainerID tags some container ID, most likely for use in attestation
 	ContainerID = "container_id"
 
@@ -302,6 +305,9 @@ const (
 	// Kid tags some key ID
 	Kid = "kid"
 
+	// MaxTTL tags a maximum TTL
+	MaxTTL = "max_ttl"
+
 	// Mode tags a bundle deletion mode
 	Mode = "mode"
 
@@ -19,3 +19,19 @@ func IncrBundleManagerUpdateFederatedBundleCounter(m telemetry.Metrics, trustDom
 }
 
 // End Counters
+
+// Gauges (value can go up or down)
+
+// SetBundleManagerFederatedBundleConsecutiveFailuresGauge sets the number of
+// consecutive failures refreshing the bundle of the given trust domain
+func SetBundleManagerFederatedBundleConsecutiveFailuresGauge(m telemetry.Metrics, trustDomain string, failures int) {
+	m.SetGaugeWithLabels([]string{
+		telemetry.BundleManager,
+		telemetry.FederatedBundle,
+		telemetry.ConsecutiveFailures,
+	}, float32(failures), []telemetry.Label{
+		{Name: telemetry.TrustDomainID, Value: trustDomain},
+	})
+}
+
+// End Gauges
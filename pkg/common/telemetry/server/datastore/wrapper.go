@@ -107,10 +107,10 @@ func (w metricsWrapper) FetchAttestedNode(ctx context.Context, spiffeID string)
 	return w.ds.FetchAttestedNode(ctx, spiffeID)
 }
 
-func (w metricsWrapper) FetchBundle(ctx context.Context, trustDomain string) (_ *common.Bundle, err error) {
+func (w metricsWrapper) FetchBundle(ctx context.Context, trustDomain string, dataConsistency datastore.DataConsistency) (_ *common.Bundle, err error) {
 	callCounter := StartFetchBundleCall(w.m)
 	defer callCounter.Done(&err)
-	return w.ds.FetchBundle(ctx, trustDomain)
+	return w.ds.FetchBundle(ctx, trustDomain, dataConsistency)
 }
 
 func (w metricsWrapper) FetchJoinToken(ctx context.Context, token string) (_ *datastore.JoinToken, err error) {
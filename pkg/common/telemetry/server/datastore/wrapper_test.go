@@ -325,7 +325,7 @@ func (ds *fakeDataStore) FetchAttestedNode(context.Context, string) (*common.Att
 	return &common.AttestedNode{}, ds.err
 }
 
-func (ds *fakeDataStore) FetchBundle(context.Context, string) (*common.Bundle, error) {
+func (ds *fakeDataStore) FetchBundle(context.Context, string, datastore.DataConsistency) (*common.Bundle, error) {
 	return &common.Bundle{}, ds.err
 }
 
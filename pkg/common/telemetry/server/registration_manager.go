@@ -11,4 +11,20 @@ func StartRegistrationManagerPruneEntryCall(m telemetry.Metrics) *telemetry.Call
 	return telemetry.StartCall(m, telemetry.RegistrationEntry, telemetry.Manager, telemetry.Prune)
 }
 
+// StartRegistrationManagerPruneNodeCall returns metric for
+// for server registration manager dead node pruning
+func StartRegistrationManagerPruneNodeCall(m telemetry.Metrics) *telemetry.CallCounter {
+	return telemetry.StartCall(m, telemetry.Node, telemetry.Manager, telemetry.Prune)
+}
+
 // End Call Counters
+
+// Gauge (remember previous value set)
+
+// SetRegistrationManagerPrunedNodesGauge emits a gauge with the number of
+// dead attested nodes pruned by the last sweep.
+func SetRegistrationManagerPrunedNodesGauge(m telemetry.Metrics, pruned int) {
+	m.SetGauge([]string{telemetry.Node, telemetry.Manager, telemetry.Pruned}, float32(pruned))
+}
+
+// End Gauge
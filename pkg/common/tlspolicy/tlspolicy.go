@@ -0,0 +1,97 @@
+// Package tlspolicy applies the configured TLS version and cipher suite
+// restrictions to the TLS configurations of the SPIRE listeners.
+package tlspolicy
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+)
+
+// Policy restricts the TLS versions and cipher suites negotiated by a server.
+// The zero value keeps the defaults, i.e. TLS 1.2 as the minimum version and
+// the Go default cipher suites.
+type Policy struct {
+	// MinVersion is the minimum TLS version. TLS 1.2 is used when zero.
+	MinVersion uint16
+
+	// CipherSuites are the cipher suites allowed for TLS 1.2 connections. The
+	// Go defaults are used when empty. The TLS 1.3 cipher suites cannot be
+	// configured.
+	CipherSuites []uint16
+}
+
+// ParseMinVersion parses a TLS version as it appears in configuration (i.e.
+// "1.2" or "1.3"). An empty string returns zero, meaning the default.
+func ParseMinVersion(version string) (uint16, error) {
+	switch version {
+	case "":
+		return 0, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version %q: expected 1.2 or 1.3", version)
+	}
+}
+
+// ParseCipherSuites parses the names of TLS 1.2 cipher suites (e.g.
+// "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"). Only the cipher suites that Go
+// considers secure are supported.
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	suites := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		for _, version := range suite.SupportedVersions {
+			if version == tls.VersionTLS12 {
+				suites[suite.Name] = suite.ID
+			}
+		}
+	}
+
+	var ids []uint16
+	for _, name := range names {
+		id, ok := suites[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported TLS 1.2 cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Validate returns an error if the policy is inconsistent.
+func (p Policy) Validate() error {
+	if p.MinVersion == tls.VersionTLS13 && len(p.CipherSuites) > 0 {
+		return errors.New("cipher suites cannot be configured when the minimum TLS version is 1.3")
+	}
+	return nil
+}
+
+// Apply applies the policy to the given configuration. If the configuration
+// has a GetConfigForClient hook, the policy is also applied to the
+// configurations it returns.
+func (p Policy) Apply(config *tls.Config) {
+	p.apply(config)
+
+	if getConfigForClient := config.GetConfigForClient; getConfigForClient != nil {
+		config.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			clientConfig, err := getConfigForClient(hello)
+			if clientConfig != nil {
+				clientConfig = clientConfig.Clone()
+				p.apply(clientConfig)
+			}
+			return clientConfig, err
+		}
+	}
+}
+
+func (p Policy) apply(config *tls.Config) {
+	config.MinVersion = tls.VersionTLS12
+	if p.MinVersion != 0 {
+		config.MinVersion = p.MinVersion
+	}
+	if len(p.CipherSuites) > 0 {
+		config.CipherSuites = p.CipherSuites
+	}
+}
@@ -0,0 +1,111 @@
+package tlspolicy
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMinVersion(t *testing.T) {
+	for _, tt := range []struct {
+		version   string
+		expected  uint16
+		expectErr string
+	}{
+		{version: "", expected: 0},
+		{version: "1.2", expected: tls.VersionTLS12},
+		{version: "1.3", expected: tls.VersionTLS13},
+		{version: "1.1", expectErr: `unsupported TLS version "1.1": expected 1.2 or 1.3`},
+		{version: "TLS13", expectErr: `unsupported TLS version "TLS13": expected 1.2 or 1.3`},
+	} {
+		version, err := ParseMinVersion(tt.version)
+		if tt.expectErr != "" {
+			require.EqualError(t, err, tt.expectErr)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, tt.expected, version)
+	}
+}
+
+func TestParseCipherSuites(t *testing.T) {
+	suites, err := ParseCipherSuites(nil)
+	require.NoError(t, err)
+	require.Empty(t, suites)
+
+	suites, err = ParseCipherSuites([]string{"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256", "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"})
+	require.NoError(t, err)
+	require.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256, tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}, suites)
+
+	_, err = ParseCipherSuites([]string{"TLS_NOT_A_CIPHER_SUITE"})
+	require.EqualError(t, err, `unsupported TLS 1.2 cipher suite "TLS_NOT_A_CIPHER_SUITE"`)
+
+	// TLS 1.3 cipher suites are not configurable
+	_, err = ParseCipherSuites([]string{"TLS_AES_128_GCM_SHA256"})
+	require.EqualError(t, err, `unsupported TLS 1.2 cipher suite "TLS_AES_128_GCM_SHA256"`)
+
+	// Insecure cipher suites are not supported
+	_, err = ParseCipherSuites([]string{"TLS_RSA_WITH_RC4_128_SHA"})
+	require.EqualError(t, err, `unsupported TLS 1.2 cipher suite "TLS_RSA_WITH_RC4_128_SHA"`)
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Policy{}.Validate())
+	require.NoError(t, Policy{MinVersion: tls.VersionTLS13}.Validate())
+	require.NoError(t, Policy{
+		MinVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+	}.Validate())
+	require.EqualError(t, Policy{
+		MinVersion:   tls.VersionTLS13,
+		CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+	}.Validate(), "cipher suites cannot be configured when the minimum TLS version is 1.3")
+}
+
+func TestApply(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		config := new(tls.Config)
+		Policy{}.Apply(config)
+		require.Equal(t, uint16(tls.VersionTLS12), config.MinVersion)
+		require.Nil(t, config.CipherSuites)
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		suites := []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}
+		config := new(tls.Config)
+		Policy{MinVersion: tls.VersionTLS13, CipherSuites: suites}.Apply(config)
+		require.Equal(t, uint16(tls.VersionTLS13), config.MinVersion)
+		require.Equal(t, suites, config.CipherSuites)
+	})
+
+	t.Run("configurations returned for clients", func(t *testing.T) {
+		clientConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+		config := &tls.Config{ //nolint: gosec // MinVersion is set by Apply
+			GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+				return clientConfig, nil
+			},
+		}
+		Policy{MinVersion: tls.VersionTLS13}.Apply(config)
+		require.Equal(t, uint16(tls.VersionTLS13), config.MinVersion)
+
+		returned, err := config.GetConfigForClient(&tls.ClientHelloInfo{})
+		require.NoError(t, err)
+		require.Equal(t, uint16(tls.VersionTLS13), returned.MinVersion)
+		// The original configuration is not modified
+		require.Equal(t, uint16(tls.VersionTLS12), clientConfig.MinVersion)
+	})
+
+	t.Run("no configuration returned for clients", func(t *testing.T) {
+		config := &tls.Config{ //nolint: gosec // MinVersion is set by Apply
+			GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+				return nil, nil
+			},
+		}
+		Policy{MinVersion: tls.VersionTLS13}.Apply(config)
+		returned, err := config.GetConfigForClient(&tls.ClientHelloInfo{})
+		require.NoError(t, err)
+		require.Nil(t, returned)
+	})
+}
@@ -165,6 +165,7 @@ func TestAuditWitTypesStatus(t *testing.T) {
 	for _, tt := range []struct {
 		name            string
 		status          *types.Status
+		addFields       logrus.Fields
 		expect          []spiretest.LogEntry
 		parameterFields logrus.Fields
 	}{
@@ -234,11 +235,32 @@ func TestAuditWitTypesStatus(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:            "error with fields added",
+			status:          &types.Status{Code: int32(codes.Internal), Message: "some error"},
+			addFields:       logrus.Fields{"a": "1"},
+			parameterFields: logrus.Fields{"emit": "test"},
+			expect: []spiretest.LogEntry{
+				{
+					Level:   logrus.InfoLevel,
+					Message: "API accessed",
+					Data: logrus.Fields{
+						"a":                     "1",
+						"emit":                  "test",
+						telemetry.Status:        "error",
+						telemetry.StatusCode:    "Internal",
+						telemetry.StatusMessage: "some error",
+						telemetry.Type:          "audit",
+					},
+				},
+			},
+		},
 	} {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			auditLog := audit.New(log)
 			logHook.Reset()
+			auditLog.AddFields(tt.addFields)
 			auditLog.AuditWithTypesStatus(tt.parameterFields, tt.status)
 			spiretest.AssertLogs(t, logHook.AllEntries(), tt.expect)
 		})
@@ -79,7 +79,7 @@ func (s *Service) GetBundle(ctx context.Context, req *bundlev1.GetBundleRequest)
 	rpccontext.AddRPCAuditFields(ctx, logrus.Fields{telemetry.TrustDomainID: s.td.String()})
 	log := rpccontext.Logger(ctx)
 
-	commonBundle, err := s.ds.FetchBundle(dscache.WithCache(ctx), s.td.IDString())
+	commonBundle, err := s.ds.FetchBundle(dscache.WithCache(ctx), s.td.IDString(), datastore.TolerateStale)
 	if err != nil {
 		return nil, api.MakeErr(log, codes.Internal, "failed to fetch bundle", err)
 	}
@@ -252,7 +252,7 @@ func (s *Service) GetFederatedBundle(ctx context.Context, req *bundlev1.GetFeder
 		return nil, api.MakeErr(log, codes.InvalidArgument, "getting a federated bundle for the server's own trust domain is not allowed", nil)
 	}
 
-	commonBundle, err := s.ds.FetchBundle(ctx, td.IDString())
+	commonBundle, err := s.ds.FetchBundle(ctx, td.IDString(), datastore.RequireCurrent)
 	if err != nil {
 		return nil, api.MakeErr(log, codes.Internal, "failed to fetch bundle", err)
 	}
@@ -2476,7 +2476,7 @@ func TestBatchUpdateFederatedBundle(t *testing.T) {
 					case codes.OK, codes.NotFound:
 					default:
 						td := spiffeid.RequireTrustDomainFromString(tt.bundlesToUpdate[i].TrustDomain)
-						updatedBundle, err := test.ds.FetchBundle(ctx, td.IDString())
+						updatedBundle, err := test.ds.FetchBundle(ctx, td.IDString(), datastore.RequireCurrent)
 						require.NoError(t, err)
 						require.Equal(t, tt.preExistentBundle, updatedBundle)
 					}
@@ -116,7 +116,7 @@ func (s *Service) getCertificateChain(ctx context.Context, log logrus.FieldLogge
 	trustDomainID := s.td.IDString()
 
 	// Extract trustdomains bundle and append federated bundles
-	bundle, err := s.ds.FetchBundle(ctx, trustDomainID)
+	bundle, err := s.ds.FetchBundle(ctx, trustDomainID, datastore.RequireCurrent)
 	if err != nil {
 		return nil, api.MakeErr(log, codes.Internal, "failed to fetch trust domain bundle", err)
 	}
@@ -2,6 +2,8 @@ package health
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
 	"github.com/spiffe/spire/pkg/common/telemetry"
@@ -13,6 +15,14 @@ import (
 	"google.golang.org/grpc/health/grpc_health_v1"
 )
 
+const (
+	// DataStoreService is the service name used to request a deep health
+	// check that also verifies the server can query its datastore.
+	DataStoreService = "datastore"
+
+	defaultDataStoreTimeout = 5 * time.Second
+)
+
 // RegisterService registers the service on the gRPC server.
 func RegisterService(s *grpc.Server, service *Service) {
 	grpc_health_v1.RegisterHealthServer(s, service)
@@ -22,13 +32,22 @@ func RegisterService(s *grpc.Server, service *Service) {
 type Config struct {
 	TrustDomain spiffeid.TrustDomain
 	DataStore   datastore.DataStore
+
+	// DataStoreTimeout bounds the datastore round-trips made by the deep
+	// health check. Defaults to 5 seconds.
+	DataStoreTimeout time.Duration
 }
 
 // New creates a new Health service
 func New(config Config) *Service {
+	dsTimeout := config.DataStoreTimeout
+	if dsTimeout <= 0 {
+		dsTimeout = defaultDataStoreTimeout
+	}
 	return &Service{
-		ds: config.DataStore,
-		td: config.TrustDomain,
+		ds:        config.DataStore,
+		td:        config.TrustDomain,
+		dsTimeout: dsTimeout,
 	}
 }
 
@@ -36,19 +55,32 @@ func New(config Config) *Service {
 type Service struct {
 	grpc_health_v1.UnimplementedHealthServer
 
-	ds datastore.DataStore
-	td spiffeid.TrustDomain
+	ds        datastore.DataStore
+	td        spiffeid.TrustDomain
+	dsTimeout time.Duration
 }
 
 func (s *Service) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
 	log := rpccontext.Logger(ctx)
 
-	// Ensure per-service health is not being requested.
-	if req.Service != "" {
+	// Ensure per-service health is not being requested, other than the
+	// datastore service used for deep health checks.
+	deep := false
+	switch req.Service {
+	case "":
+	case DataStoreService:
+		deep = true
+	default:
 		return nil, api.MakeErr(log, codes.InvalidArgument, "per-service health is not supported", nil)
 	}
 
-	bundle, err := s.ds.FetchBundle(ctx, s.td.IDString())
+	if deep {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.dsTimeout)
+		defer cancel()
+	}
+
+	bundle, err := s.ds.FetchBundle(ctx, s.td.IDString(), datastore.RequireCurrent)
 
 	var unhealthyReason string
 	switch {
@@ -57,6 +89,16 @@ func (s *Service) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequ
 		unhealthyReason = "unable to fetch bundle"
 	case bundle == nil:
 		unhealthyReason = "bundle is missing"
+	case deep:
+		// Make an additional round-trip to the datastore that is not served
+		// from any cache, to make sure it is still responsive.
+		if _, err := s.ds.CountBundles(ctx); err != nil {
+			log = log.WithError(err)
+			unhealthyReason = "unable to query datastore"
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				unhealthyReason = "datastore query timed out"
+			}
+		}
 	}
 
 	healthStatus := grpc_health_v1.HealthCheckResponse_SERVING
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/sirupsen/logrus/hooks/test"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/spiffe/spire/pkg/server/api/health/v1"
 	"github.com/spiffe/spire/pkg/server/api/rpccontext"
+	"github.com/spiffe/spire/pkg/server/datastore"
 	"github.com/spiffe/spire/proto/spire/common"
 	"github.com/spiffe/spire/test/fakes/fakedatastore"
 	"github.com/spiffe/spire/test/spiretest"
@@ -30,6 +32,8 @@ func TestServiceCheck(t *testing.T) {
 		name                string
 		bundle              *common.Bundle
 		dsErr               error
+		countErr            error
+		countHangs          bool
 		service             string
 		expectCode          codes.Code
 		expectMsg           string
@@ -70,6 +74,49 @@ func TestServiceCheck(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:                "deep check success",
+			bundle:              &common.Bundle{TrustDomainId: td.IDString()},
+			service:             health.DataStoreService,
+			expectCode:          codes.OK,
+			expectServingStatus: grpc_health_v1.HealthCheckResponse_SERVING,
+		},
+		{
+			name:                "deep check unable to query datastore",
+			bundle:              &common.Bundle{TrustDomainId: td.IDString()},
+			countErr:            errors.New("ohno"),
+			service:             health.DataStoreService,
+			expectCode:          codes.OK,
+			expectServingStatus: grpc_health_v1.HealthCheckResponse_NOT_SERVING,
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.WarnLevel,
+					Message: "Health check failed",
+					Data: logrus.Fields{
+						"reason": "unable to query datastore",
+						"error":  "ohno",
+					},
+				},
+			},
+		},
+		{
+			name:                "deep check datastore query timed out",
+			bundle:              &common.Bundle{TrustDomainId: td.IDString()},
+			countHangs:          true,
+			service:             health.DataStoreService,
+			expectCode:          codes.OK,
+			expectServingStatus: grpc_health_v1.HealthCheckResponse_NOT_SERVING,
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.WarnLevel,
+					Message: "Health check failed",
+					Data: logrus.Fields{
+						"reason": "datastore query timed out",
+						"error":  "context deadline exceeded",
+					},
+				},
+			},
+		},
 		{
 			name:                "bundle is missing",
 			expectCode:          codes.OK,
@@ -100,7 +147,12 @@ func TestServiceCheck(t *testing.T) {
 
 			service := health.New(health.Config{
 				TrustDomain: td,
-				DataStore:   ds,
+				DataStore: &unhealthyDataStore{
+					DataStore: ds,
+					err:       tt.countErr,
+					hangs:     tt.countHangs,
+				},
+				DataStoreTimeout: 100 * time.Millisecond,
 			})
 
 			conn, done := spiretest.NewAPIServer(t,
@@ -128,3 +180,23 @@ func TestServiceCheck(t *testing.T) {
 		})
 	}
 }
+
+// unhealthyDataStore fails or hangs CountBundles to simulate an unhealthy
+// datastore.
+type unhealthyDataStore struct {
+	datastore.DataStore
+
+	err   error
+	hangs bool
+}
+
+func (ds *unhealthyDataStore) CountBundles(ctx context.Context) (int32, error) {
+	if ds.hangs {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+	if ds.err != nil {
+		return 0, ds.err
+	}
+	return ds.DataStore.CountBundles(ctx)
+}
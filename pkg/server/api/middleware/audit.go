@@ -10,12 +10,22 @@ import (
 	"github.com/spiffe/spire/pkg/server/api/audit"
 	"github.com/spiffe/spire/pkg/server/api/rpccontext"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 )
 
 func WithAuditLog(udsTrackerEnabled bool) Middleware {
+	return WithAuditLogSink(udsTrackerEnabled, nil)
+}
+
+// WithAuditLogSink returns a middleware that emits audit records to the
+// given sink instead of the request logger. The records carry the same
+// fields as the request logger (e.g. service and method). The request logger
+// is used when the sink is nil.
+func WithAuditLogSink(udsTrackerEnabled bool, sink logrus.FieldLogger) Middleware {
 	return auditLogMiddleware{
 		udsTrackerEnabled: udsTrackerEnabled,
+		sink:              sink,
 	}
 }
 
@@ -23,11 +33,15 @@ type auditLogMiddleware struct {
 	Middleware
 
 	udsTrackerEnabled bool
+	sink              logrus.FieldLogger
 }
 
 func (m auditLogMiddleware) Preprocess(ctx context.Context, fullMethod string, req interface{}) (context.Context, error) {
 	log := rpccontext.Logger(ctx)
-	if rpccontext.CallerIsLocal(ctx) && m.udsTrackerEnabled {
+	if m.sink != nil {
+		log = m.sink.WithFields(fieldsFromLogger(log))
+	}
+	if m.udsTrackerEnabled && callerIsLocal(ctx) {
 		fields, err := fieldsFromTracker(ctx)
 		if err != nil {
 			return nil, err
@@ -51,6 +65,21 @@ func (m auditLogMiddleware) Postprocess(ctx context.Context, fullMethod string,
 	}
 }
 
+// callerIsLocal returns true if the caller connected over the UDS endpoint.
+// The audit log may run before the caller is authenticated, so it can't rely
+// on the caller context.
+func callerIsLocal(ctx context.Context) bool {
+	p, ok := peer.FromContext(ctx)
+	return ok && isLocalNetwork(p.Addr.Network())
+}
+
+func fieldsFromLogger(log logrus.FieldLogger) logrus.Fields {
+	if entry, ok := log.(*logrus.Entry); ok {
+		return entry.Data
+	}
+	return nil
+}
+
 func fieldsFromTracker(ctx context.Context) (logrus.Fields, error) {
 	fields := make(logrus.Fields)
 	watcher, ok := peertracker.WatcherFromContext(ctx)
@@ -52,6 +52,9 @@ func (m *authorizationMiddleware) Preprocess(ctx context.Context, methodName str
 
 	if len(fields) > 0 {
 		ctx = rpccontext.WithLogger(ctx, rpccontext.Logger(ctx).WithFields(fields))
+		// The audit log is set up before authorization so denied calls are
+		// audited too; it needs the caller fields as well.
+		rpccontext.AddRPCAuditFields(ctx, fields)
 	}
 
 	ctx, allow, err := m.opaAuth(ctx, req, methodName)
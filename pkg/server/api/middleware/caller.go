@@ -19,10 +19,10 @@ func callerContextFromContext(ctx context.Context) (context.Context, error) {
 
 	ctx = rpccontext.WithCallerAddr(ctx, p.Addr)
 
-	switch p.Addr.Network() {
-	case "unix", "unixgram", "unixpacket":
+	switch network := p.Addr.Network(); {
+	case isLocalNetwork(network):
 		return rpccontext.WithLocalCaller(ctx), nil
-	case "tcp", "tcp4", "tcp6":
+	case network == "tcp", network == "tcp4", network == "tcp6":
 		return tcpCallerContextFromPeer(ctx, p)
 	default:
 		return nil, status.Errorf(codes.Internal, "unsupported network %q", p.Addr.Network())
@@ -68,3 +68,12 @@ func tcpCallerContextFromPeer(ctx context.Context, p *peer.Peer) (context.Contex
 	ctx = rpccontext.WithCallerX509SVID(ctx, x509SVID)
 	return ctx, nil
 }
+
+func isLocalNetwork(network string) bool {
+	switch network {
+	case "unix", "unixgram", "unixpacket":
+		return true
+	default:
+		return false
+	}
+}
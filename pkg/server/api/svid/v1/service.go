@@ -388,7 +388,7 @@ func (s *Service) NewDownstreamX509CA(ctx context.Context, req *svidv1.NewDownst
 		telemetry.Expiration: x509CASvid[0].NotAfter.Format(time.RFC3339),
 	}).Debug("Signed X509 CA SVID")
 
-	bundle, err := s.ds.FetchBundle(ctx, s.td.IDString())
+	bundle, err := s.ds.FetchBundle(ctx, s.td.IDString(), datastore.RequireCurrent)
 	if err != nil {
 		return nil, api.MakeErr(log, codes.Internal, "failed to fetch bundle", err)
 	}
@@ -381,7 +381,7 @@ func fieldsFromRelationshipProto(proto *types.FederationRelationship, mask *type
 }
 
 func validateEndpointBundle(ctx context.Context, ds datastore.DataStore, log logrus.FieldLogger, endpointSPIFFEID spiffeid.ID) {
-	bundle, err := ds.FetchBundle(ctx, endpointSPIFFEID.TrustDomain().IDString())
+	bundle, err := ds.FetchBundle(ctx, endpointSPIFFEID.TrustDomain().IDString(), datastore.RequireCurrent)
 	if err != nil {
 		log.WithField(telemetry.EndpointSpiffeID, endpointSPIFFEID).Warn("failed to check whether a bundle exists for the endpoint SPIFFE ID trust domain")
 
@@ -2,18 +2,28 @@ package client
 
 import (
 	"context"
+	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
 	"github.com/spiffe/go-spiffe/v2/spiffetls/tlsconfig"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
 	"github.com/spiffe/spire/pkg/common/bundleutil"
 	"github.com/zeebo/errs"
 )
 
+const (
+	// fetchBundleTimeout is the maximum amount of time a bundle fetch can
+	// take, so an unresponsive endpoint does not hold up the refresh of its
+	// bundle indefinitely.
+	fetchBundleTimeout = time.Second * 30
+)
+
 type SPIFFEAuthConfig struct {
 	// EndpointSpiffeID is the expected SPIFFE ID of the bundle endpoint server.
 	EndpointSpiffeID spiffeid.ID
@@ -35,6 +45,15 @@ type ClientConfig struct { //revive:disable-line:exported name stutter is intent
 	// using SPIFFE authentication. If unset, it is assumed that the endpoint
 	// is authenticated via Web PKI.
 	SPIFFEAuth *SPIFFEAuthConfig
+
+	// WebPKIRootCAs is the set of root CA certificates used to authenticate
+	// the endpoint server via Web PKI. If unset, the system roots are used.
+	// It is ignored when SPIFFEAuth is set.
+	WebPKIRootCAs []*x509.Certificate
+
+	// SVIDSource, if set, provides the X509-SVID presented to endpoints using
+	// SPIFFE authentication that require clients to authenticate.
+	SVIDSource x509svid.Source
 }
 
 // Client is used to fetch a bundle and metadata from a bundle endpoint
@@ -48,7 +67,9 @@ type client struct {
 }
 
 func NewClient(config ClientConfig) (Client, error) {
-	httpClient := &http.Client{}
+	httpClient := &http.Client{
+		Timeout: fetchBundleTimeout,
+	}
 	if config.SPIFFEAuth != nil {
 		endpointID := config.SPIFFEAuth.EndpointSpiffeID
 		if endpointID.IsZero() {
@@ -59,8 +80,23 @@ func NewClient(config ClientConfig) (Client, error) {
 
 		authorizer := tlsconfig.AuthorizeID(endpointID)
 
+		tlsConfig := tlsconfig.TLSClientConfig(bundle, authorizer)
+		if config.SVIDSource != nil {
+			tlsConfig = tlsconfig.MTLSClientConfig(config.SVIDSource, bundle, authorizer)
+		}
 		httpClient.Transport = &http.Transport{
-			TLSClientConfig: tlsconfig.TLSClientConfig(bundle, authorizer),
+			TLSClientConfig: tlsConfig,
+		}
+	} else if len(config.WebPKIRootCAs) > 0 {
+		rootCAs := x509.NewCertPool()
+		for _, rootCA := range config.WebPKIRootCAs {
+			rootCAs.AddCert(rootCA)
+		}
+		httpClient.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{
+				RootCAs:    rootCAs,
+				MinVersion: tls.VersionTLS12,
+			},
 		}
 	}
 	return &client{
@@ -70,7 +106,12 @@ func NewClient(config ClientConfig) (Client, error) {
 }
 
 func (c *client) FetchBundle(ctx context.Context) (*bundleutil.Bundle, error) {
-	resp, err := c.client.Get(c.c.EndpointURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.c.EndpointURL, nil)
+	if err != nil {
+		return nil, errs.New("failed to create request: %v", err)
+	}
+
+	resp, err := c.client.Do(req)
 	if err != nil {
 		return nil, errs.New("failed to fetch bundle: %v", err)
 	}
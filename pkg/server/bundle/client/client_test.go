@@ -5,6 +5,7 @@ import (
 	"crypto"
 	"crypto/tls"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"fmt"
 	"math/big"
 	"net"
@@ -15,7 +16,10 @@ import (
 	"time"
 
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/spiffetls/tlsconfig"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
 	"github.com/spiffe/spire/test/spiretest"
+	"github.com/spiffe/spire/test/testca"
 	"github.com/stretchr/testify/require"
 )
 
@@ -125,6 +129,130 @@ func TestClient(t *testing.T) {
 	}
 }
 
+func TestClientPresentsSVID(t *testing.T) {
+	serverCert, serverKey := createServerCertificate(t, serverID)
+	clientCA := testca.New(t, spiffeid.RequireTrustDomainFromString("client.test"))
+	clientID := spiffeid.RequireFromString("spiffe://client.test/spire/server")
+	clientSVID := clientCA.CreateX509SVID(clientID)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{"spiffe_refresh_hint": 10}`))
+	}))
+	serverSVID := &x509svid.SVID{
+		ID:           serverID,
+		Certificates: []*x509.Certificate{serverCert},
+		PrivateKey:   serverKey,
+	}
+	server.TLS = tlsconfig.MTLSServerConfig(serverSVID, clientCA.X509Bundle(), tlsconfig.AuthorizeID(clientID))
+	// httptest serves its own certificate unless one is provided
+	server.TLS.Certificates = []tls.Certificate{
+		{
+			Certificate: [][]byte{serverCert.Raw},
+			PrivateKey:  serverKey,
+		},
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	client, err := NewClient(ClientConfig{
+		TrustDomain: trustDomain,
+		EndpointURL: server.URL,
+		SPIFFEAuth: &SPIFFEAuthConfig{
+			EndpointSpiffeID: serverID,
+			RootCAs:          []*x509.Certificate{serverCert},
+		},
+		SVIDSource: clientSVID,
+	})
+	require.NoError(t, err)
+
+	bundle, err := client.FetchBundle(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, trustDomain.IDString(), bundle.TrustDomainID())
+}
+
+func TestClientWebPKI(t *testing.T) {
+	caCert, caKey := spiretest.SelfSignCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(0),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		Subject:               pkix.Name{CommonName: "CA"},
+	})
+	serverCert := spiretest.CreateCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, caCert, caKey.Public(), caKey)
+
+	server := newWebPKIServer(t, serverCert, caKey)
+
+	t.Run("custom root CAs", func(t *testing.T) {
+		client, err := NewClient(ClientConfig{
+			TrustDomain:   trustDomain,
+			EndpointURL:   server.URL,
+			WebPKIRootCAs: []*x509.Certificate{caCert},
+		})
+		require.NoError(t, err)
+
+		bundle, err := client.FetchBundle(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, trustDomain.IDString(), bundle.TrustDomainID())
+		require.Equal(t, 10*time.Second, bundle.RefreshHint())
+	})
+
+	t.Run("system roots", func(t *testing.T) {
+		client, err := NewClient(ClientConfig{
+			TrustDomain: trustDomain,
+			EndpointURL: server.URL,
+		})
+		require.NoError(t, err)
+
+		_, err = client.FetchBundle(context.Background())
+		spiretest.RequireErrorContains(t, err, "certificate signed by unknown authority")
+	})
+}
+
+func TestClientFetchBundleHonorsContext(t *testing.T) {
+	// The endpoint hangs until the request is canceled.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(ClientConfig{
+		TrustDomain: trustDomain,
+		EndpointURL: server.URL,
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = client.FetchBundle(ctx)
+	spiretest.RequireErrorContains(t, err, "context deadline exceeded")
+}
+
+func newWebPKIServer(t *testing.T, serverCert *x509.Certificate, serverKey crypto.Signer) *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{"spiffe_refresh_hint": 10}`))
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{
+			{
+				Certificate: [][]byte{serverCert.Raw},
+				PrivateKey:  serverKey,
+			},
+		},
+		MinVersion: tls.VersionTLS12,
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
 func createServerCertificate(t *testing.T, serverID spiffeid.ID) (*x509.Certificate, crypto.Signer) {
 	return spiretest.SelfSignCertificate(t, &x509.Certificate{
 		SerialNumber: big.NewInt(0),
@@ -8,6 +8,7 @@ import (
 	"github.com/andres-erbsen/clock"
 	"github.com/sirupsen/logrus"
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
 	"github.com/spiffe/spire/pkg/common/bundleutil"
 	"github.com/spiffe/spire/pkg/common/telemetry"
 	telemetry_server "github.com/spiffe/spire/pkg/common/telemetry/server"
@@ -25,8 +26,31 @@ const (
 	// configs from the source and reconciles it against the current bundle
 	// updaters.
 	configRefreshInterval = time.Second * 10
+
+	// maxRetryBackoffShift bounds the exponent of the retry backoff so the
+	// backoff computation cannot overflow.
+	maxRetryBackoffShift = 16
 )
 
+// BundleRefreshStatus is the status of the bundle refreshes for a federated
+// trust domain.
+type BundleRefreshStatus struct {
+	// LastAttempt is the time of the last bundle refresh attempt.
+	LastAttempt time.Time
+
+	// LastRefresh is the time of the last successful bundle refresh. It is
+	// zero if the bundle has not been successfully refreshed yet.
+	LastRefresh time.Time
+
+	// LastError is the error of the last bundle refresh attempt, or nil if it
+	// succeeded.
+	LastError error
+
+	// ConsecutiveFailures is the number of bundle refresh attempts that have
+	// failed since the last successful refresh.
+	ConsecutiveFailures int
+}
+
 type TrustDomainConfig struct {
 	// EndpointURL is the URL used to fetch the bundle of the federated
 	// trust domain. Is served by a SPIFFE bundle endpoint server.
@@ -35,6 +59,10 @@ type TrustDomainConfig struct {
 	// EndpointProfile is the bundle endpoint profile used by the
 	// SPIFFE bundle endpoint server.
 	EndpointProfile EndpointProfileInfo
+
+	// RefreshInterval, if set, is how often the bundle is refreshed,
+	// overriding the interval derived from the bundle refresh hint.
+	RefreshInterval time.Duration
 }
 
 type EndpointProfileInfo interface {
@@ -42,7 +70,12 @@ type EndpointProfileInfo interface {
 	Name() string
 }
 
-type HTTPSWebProfile struct{}
+type HTTPSWebProfile struct {
+	// CABundlePath is the path to a PEM file with the root CA certificates
+	// used to authenticate the bundle endpoint server. If unset, the system
+	// roots are used.
+	CABundlePath string
+}
 
 func (p HTTPSWebProfile) Name() string {
 	return "https_web"
@@ -64,6 +97,10 @@ type ManagerConfig struct {
 	Clock     clock.Clock
 	Source    TrustDomainConfigSource
 
+	// SVIDSource provides the X509-SVID presented to bundle endpoints that
+	// require client authentication. Optional.
+	SVIDSource x509svid.Source
+
 	// newBundleUpdater is a test hook to inject updater behavior
 	newBundleUpdater func(BundleUpdaterConfig) BundleUpdater
 
@@ -82,6 +119,7 @@ type Manager struct {
 	clock            clock.Clock
 	ds               datastore.DataStore
 	source           TrustDomainConfigSource
+	svidSource       x509svid.Source
 	configRefreshCh  chan struct{}
 	configRefreshMtx sync.Mutex
 	updatersMtx      sync.RWMutex
@@ -99,6 +137,29 @@ type managedBundleUpdater struct {
 	wg     sync.WaitGroup
 	cancel context.CancelFunc
 	runCh  chan chan error
+
+	statusMtx sync.Mutex
+	status    BundleRefreshStatus
+}
+
+func (m *managedBundleUpdater) Status() BundleRefreshStatus {
+	m.statusMtx.Lock()
+	defer m.statusMtx.Unlock()
+	return m.status
+}
+
+func (m *managedBundleUpdater) recordAttempt(now time.Time, err error) BundleRefreshStatus {
+	m.statusMtx.Lock()
+	defer m.statusMtx.Unlock()
+	m.status.LastAttempt = now
+	m.status.LastError = err
+	if err != nil {
+		m.status.ConsecutiveFailures++
+	} else {
+		m.status.LastRefresh = now
+		m.status.ConsecutiveFailures = 0
+	}
+	return m.status
 }
 
 func (m *managedBundleUpdater) Stop() {
@@ -120,6 +181,7 @@ func NewManager(config ManagerConfig) *Manager {
 		clock:             config.Clock,
 		ds:                config.DataStore,
 		source:            config.Source,
+		svidSource:        config.SVIDSource,
 		newBundleUpdater:  config.newBundleUpdater,
 		configRefreshCh:   make(chan struct{}, 1),
 		configRefreshedCh: config.configRefreshedCh,
@@ -175,9 +237,23 @@ func (m *Manager) RefreshBundleFor(ctx context.Context, td spiffeid.TrustDomain)
 	}
 
 	_, _, err := updater.UpdateBundle(ctx)
+	m.recordAttempt(td, updater, err)
 	return true, err
 }
 
+// RefreshStatuses returns the bundle refresh status of each trust domain
+// managed by the manager.
+func (m *Manager) RefreshStatuses() map[spiffeid.TrustDomain]BundleRefreshStatus {
+	m.updatersMtx.RLock()
+	defer m.updatersMtx.RUnlock()
+
+	statuses := make(map[spiffeid.TrustDomain]BundleRefreshStatus, len(m.updaters))
+	for td, updater := range m.updaters {
+		statuses[td] = updater.Status()
+	}
+	return statuses
+}
+
 func (m *Manager) refreshConfigs(ctx context.Context) error {
 	m.configRefreshMtx.Lock()
 	defer m.configRefreshMtx.Unlock()
@@ -241,6 +317,7 @@ func (m *Manager) refreshConfigs(ctx context.Context) error {
 				TrustDomainConfig: config,
 				TrustDomain:       td,
 				DataStore:         m.ds,
+				SVIDSource:        m.svidSource,
 			}),
 			cancel: cancel,
 			runCh:  make(chan chan error),
@@ -255,7 +332,7 @@ func (m *Manager) refreshConfigs(ctx context.Context) error {
 	return nil
 }
 
-func (m *Manager) runUpdater(ctx context.Context, trustDomain spiffeid.TrustDomain, updater BundleUpdater) {
+func (m *Manager) runUpdater(ctx context.Context, trustDomain spiffeid.TrustDomain, updater *managedBundleUpdater) {
 	// Initialize the timer. The initial duration does not matter since it will
 	// be reset with the actual refresh interval before first use.
 	timer := m.clock.Timer(time.Hour)
@@ -266,17 +343,22 @@ func (m *Manager) runUpdater(ctx context.Context, trustDomain spiffeid.TrustDoma
 		var nextRefresh time.Duration
 		log.Debug("Polling for bundle update")
 		localBundle, endpointBundle, err := updater.UpdateBundle(ctx)
+		if ctx.Err() != nil {
+			log.Info("No longer polling for updates")
+			return
+		}
+		status := m.recordAttempt(trustDomain, updater, err)
 		if err != nil {
-			log.WithError(err).Error("Error updating bundle")
+			log.WithError(err).WithField(telemetry.ConsecutiveFailures, status.ConsecutiveFailures).Error("Error updating bundle")
 		}
 
 		switch {
 		case endpointBundle != nil:
 			telemetry_server.IncrBundleManagerUpdateFederatedBundleCounter(m.metrics, trustDomain.String())
 			log.Info("Bundle refreshed")
-			nextRefresh = calculateNextUpdate(endpointBundle)
+			nextRefresh = calculateNextUpdateFor(updater.GetTrustDomainConfig(), endpointBundle)
 		case localBundle != nil:
-			nextRefresh = calculateNextUpdate(localBundle)
+			nextRefresh = calculateNextUpdateFor(updater.GetTrustDomainConfig(), localBundle)
 		default:
 			// We have no bundle to use to calculate the refresh hint. Since
 			// the endpoint cannot be reached without the local bundle (until
@@ -287,6 +369,13 @@ func (m *Manager) runUpdater(ctx context.Context, trustDomain spiffeid.TrustDoma
 			nextRefresh = bundleutil.MinimumRefreshHint
 		}
 
+		if err != nil {
+			// Retry failed refreshes sooner than the regular refresh
+			// interval, backing off on consecutive failures so an
+			// unavailable endpoint is not hammered.
+			nextRefresh = calculateRetryBackoff(status.ConsecutiveFailures, nextRefresh)
+		}
+
 		log.WithFields(logrus.Fields{
 			"at": m.clock.Now().Add(nextRefresh).UTC().Format(time.RFC3339),
 		}).Debug("Scheduling next bundle refresh")
@@ -305,6 +394,12 @@ func (m *Manager) runUpdater(ctx context.Context, trustDomain spiffeid.TrustDoma
 	}
 }
 
+func (m *Manager) recordAttempt(trustDomain spiffeid.TrustDomain, updater *managedBundleUpdater, err error) BundleRefreshStatus {
+	status := updater.recordAttempt(m.clock.Now(), err)
+	telemetry_server.SetBundleManagerFederatedBundleConsecutiveFailuresGauge(m.metrics, trustDomain.String(), status.ConsecutiveFailures)
+	return status
+}
+
 func (m *Manager) notifyConfigRefreshed(ctx context.Context, nextRefresh time.Duration) {
 	if m.configRefreshedCh != nil {
 		select {
@@ -327,6 +422,34 @@ func calculateNextUpdate(b *bundleutil.Bundle) time.Duration {
 	return bundleutil.CalculateRefreshHint(b) / attemptsPerRefreshHint
 }
 
+// calculateNextUpdateFor returns the refresh interval configured for the
+// trust domain, if any, or otherwise the interval derived from the bundle.
+func calculateNextUpdateFor(config TrustDomainConfig, b *bundleutil.Bundle) time.Duration {
+	if config.RefreshInterval > 0 {
+		return config.RefreshInterval
+	}
+	return calculateNextUpdate(b)
+}
+
+// calculateRetryBackoff returns how long to wait before retrying after the
+// given number of consecutive failures. The backoff starts at the minimum
+// refresh hint and doubles on each failure, but never exceeds the regular
+// refresh interval.
+func calculateRetryBackoff(failures int, refreshInterval time.Duration) time.Duration {
+	shift := failures - 1
+	if shift < 0 {
+		shift = 0
+	}
+	if shift > maxRetryBackoffShift {
+		shift = maxRetryBackoffShift
+	}
+	backoff := bundleutil.MinimumRefreshHint << shift
+	if backoff > refreshInterval {
+		return refreshInterval
+	}
+	return backoff
+}
+
 func cloneTrustDomainConfigs(configs map[spiffeid.TrustDomain]TrustDomainConfig) map[spiffeid.TrustDomain]TrustDomainConfig {
 	clone := make(map[spiffeid.TrustDomain]TrustDomainConfig, len(configs))
 	for k, v := range configs {
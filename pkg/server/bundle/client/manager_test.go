@@ -38,21 +38,25 @@ func TestManagerPeriodicBundleRefresh(t *testing.T) {
 		localBundle    *bundleutil.Bundle
 		endpointBundle *bundleutil.Bundle
 		nextRefresh    time.Duration
+		secondRefresh  time.Duration
 	}{
 		{
-			name:        "update failed to obtain local bundle",
-			nextRefresh: bundleutil.MinimumRefreshHint,
+			name:          "update failed to obtain local bundle",
+			nextRefresh:   bundleutil.MinimumRefreshHint,
+			secondRefresh: bundleutil.MinimumRefreshHint,
 		},
 		{
-			name:        "update failed to obtain endpoint bundle",
-			localBundle: localBundle,
-			nextRefresh: calculateNextUpdate(localBundle),
+			name:          "update failed to obtain endpoint bundle",
+			localBundle:   localBundle,
+			nextRefresh:   bundleutil.MinimumRefreshHint,
+			secondRefresh: bundleutil.MinimumRefreshHint * 2,
 		},
 		{
 			name:           "update obtained endpoint bundle",
 			localBundle:    localBundle,
 			endpointBundle: endpointBundle,
 			nextRefresh:    calculateNextUpdate(endpointBundle),
+			secondRefresh:  calculateNextUpdate(endpointBundle),
 		},
 	}
 
@@ -77,12 +81,155 @@ func TestManagerPeriodicBundleRefresh(t *testing.T) {
 
 			// advance time and make sure another bundle refresh happens
 			test.AdvanceTime(testCase.nextRefresh + time.Millisecond)
-			test.WaitForBundleRefresh(testCase.nextRefresh)
+			test.WaitForBundleRefresh(testCase.secondRefresh)
 			require.Equal(t, 2, test.UpdateCount(trustDomain))
 		})
 	}
 }
 
+func TestManagerBundleRefreshIsIndependentPerTrustDomain(t *testing.T) {
+	healthyTD := spiffeid.RequireTrustDomainFromString("healthy.test")
+	failingTD := spiffeid.RequireTrustDomainFromString("failing.test")
+
+	localBundle := bundleutil.BundleFromRootCA(failingTD, createCACertificate(t, "local"))
+	localBundle.SetRefreshHint(time.Hour)
+	endpointBundle := bundleutil.BundleFromRootCA(healthyTD, createCACertificate(t, "endpoint"))
+	endpointBundle.SetRefreshHint(time.Hour)
+
+	source := TrustDomainConfigMap{
+		healthyTD: TrustDomainConfig{
+			EndpointURL:     "https://healthy.test/bundle",
+			EndpointProfile: HTTPSWebProfile{},
+		},
+		failingTD: TrustDomainConfig{
+			EndpointURL:     "https://failing.test/bundle",
+			EndpointProfile: HTTPSWebProfile{},
+		},
+	}
+
+	// The healthy trust domain endpoint serves its bundle while the failing
+	// one errors out.
+	test := newManagerTest(t, source,
+		func(td spiffeid.TrustDomain) *bundleutil.Bundle {
+			if td == failingTD {
+				return localBundle
+			}
+			return nil
+		},
+		func(td spiffeid.TrustDomain) *bundleutil.Bundle {
+			if td == healthyTD {
+				return endpointBundle
+			}
+			return nil
+		},
+	)
+
+	test.WaitForConfigRefresh()
+	start := test.clock.Now()
+
+	// The healthy trust domain is scheduled at its regular refresh interval
+	// while the failing one is retried sooner.
+	test.WaitForBundleRefreshes(calculateNextUpdate(endpointBundle), bundleutil.MinimumRefreshHint)
+	assert.Equal(t, map[spiffeid.TrustDomain]BundleRefreshStatus{
+		healthyTD: {
+			LastAttempt: start,
+			LastRefresh: start,
+		},
+		failingTD: {
+			LastAttempt:         start,
+			LastError:           errors.New("OHNO"),
+			ConsecutiveFailures: 1,
+		},
+	}, test.manager.RefreshStatuses())
+
+	// The failing trust domain backs off on consecutive failures without
+	// affecting the healthy one.
+	test.AdvanceTime(bundleutil.MinimumRefreshHint + time.Millisecond)
+	test.WaitForBundleRefresh(bundleutil.MinimumRefreshHint * 2)
+	assert.Equal(t, 1, test.UpdateCount(healthyTD))
+	assert.Equal(t, 2, test.UpdateCount(failingTD))
+
+	statuses := test.manager.RefreshStatuses()
+	assert.Equal(t, start, statuses[healthyTD].LastRefresh)
+	assert.NoError(t, statuses[healthyTD].LastError)
+	assert.Equal(t, 2, statuses[failingTD].ConsecutiveFailures)
+	assert.True(t, statuses[failingTD].LastRefresh.IsZero())
+
+	// Once the endpoint recovers, the failure count is reset.
+	test.SetEndpointBundle(failingTD, endpointBundle)
+	test.AdvanceTime(bundleutil.MinimumRefreshHint*2 + time.Millisecond)
+	test.WaitForBundleRefresh(calculateNextUpdate(endpointBundle))
+
+	statuses = test.manager.RefreshStatuses()
+	assert.True(t, statuses[failingTD].LastRefresh.After(start))
+	assert.NoError(t, statuses[failingTD].LastError)
+	assert.Zero(t, statuses[failingTD].ConsecutiveFailures)
+}
+
+func TestManagerBundleRefreshIntervalOverride(t *testing.T) {
+	hourlyTD := spiffeid.RequireTrustDomainFromString("hourly.test")
+	weeklyTD := spiffeid.RequireTrustDomainFromString("weekly.test")
+	hintTD := spiffeid.RequireTrustDomainFromString("hint.test")
+
+	endpointBundle := bundleutil.BundleFromRootCA(hintTD, createCACertificate(t, "endpoint"))
+	endpointBundle.SetRefreshHint(time.Hour * 8)
+
+	source := TrustDomainConfigMap{
+		hourlyTD: TrustDomainConfig{
+			EndpointURL:     "https://hourly.test/bundle",
+			EndpointProfile: HTTPSWebProfile{},
+			RefreshInterval: time.Hour,
+		},
+		weeklyTD: TrustDomainConfig{
+			EndpointURL:     "https://weekly.test/bundle",
+			EndpointProfile: HTTPSWebProfile{},
+			RefreshInterval: time.Hour * 24 * 7,
+		},
+		hintTD: TrustDomainConfig{
+			EndpointURL:     "https://hint.test/bundle",
+			EndpointProfile: HTTPSWebProfile{},
+		},
+	}
+
+	test := newManagerTest(t, source, nil,
+		func(spiffeid.TrustDomain) *bundleutil.Bundle {
+			return endpointBundle
+		},
+	)
+
+	test.WaitForConfigRefresh()
+
+	// Each trust domain is scheduled at its own interval, falling back to the
+	// bundle refresh hint when no interval is configured.
+	test.WaitForBundleRefreshes(time.Hour, time.Hour*24*7, calculateNextUpdate(endpointBundle))
+
+	// Only the hourly trust domain is refreshed after an hour.
+	test.AdvanceTime(time.Hour + time.Millisecond)
+	test.WaitForBundleRefresh(time.Hour)
+	assert.Equal(t, 2, test.UpdateCount(hourlyTD))
+	assert.Equal(t, 1, test.UpdateCount(weeklyTD))
+	assert.Equal(t, 1, test.UpdateCount(hintTD))
+}
+
+func TestCalculateNextUpdateFor(t *testing.T) {
+	b := bundleutil.BundleFromRootCA(trustDomain, createCACertificate(t, "bundle"))
+	b.SetRefreshHint(time.Hour)
+
+	assert.Equal(t, calculateNextUpdate(b), calculateNextUpdateFor(TrustDomainConfig{}, b))
+	assert.Equal(t, time.Hour*24, calculateNextUpdateFor(TrustDomainConfig{RefreshInterval: time.Hour * 24}, b))
+}
+
+func TestCalculateRetryBackoff(t *testing.T) {
+	assert.Equal(t, bundleutil.MinimumRefreshHint, calculateRetryBackoff(1, time.Hour))
+	assert.Equal(t, bundleutil.MinimumRefreshHint*2, calculateRetryBackoff(2, time.Hour))
+	assert.Equal(t, bundleutil.MinimumRefreshHint*4, calculateRetryBackoff(3, time.Hour))
+
+	// The backoff never exceeds the regular refresh interval
+	assert.Equal(t, time.Hour, calculateRetryBackoff(10, time.Hour))
+	assert.Equal(t, time.Hour, calculateRetryBackoff(1000, time.Hour))
+	assert.Equal(t, time.Second*30, calculateRetryBackoff(1, time.Second*30))
+}
+
 func TestManagerOnDemandBundleRefresh(t *testing.T) {
 	trustDomainConfigs := make(TrustDomainConfigMap)
 
@@ -322,6 +469,25 @@ func (test *managerTest) WaitForBundleRefresh(expectNextRefresh time.Duration) {
 	}
 }
 
+func (test *managerTest) WaitForBundleRefreshes(expectNextRefreshes ...time.Duration) {
+	var nextRefreshes []time.Duration
+	for range expectNextRefreshes {
+		select {
+		case d := <-test.bundleRefreshedCh:
+			nextRefreshes = append(nextRefreshes, d)
+		case <-time.After(time.Second * 10):
+			require.Fail(test.t, "timed out waiting for bundle refresh")
+		}
+	}
+	require.ElementsMatch(test.t, expectNextRefreshes, nextRefreshes, "next bundle refreshes not at the expected intervals")
+}
+
+func (test *managerTest) SetEndpointBundle(td spiffeid.TrustDomain, endpointBundle *bundleutil.Bundle) {
+	bundleUpdater, ok := test.bundleUpdaterFor(td)
+	require.True(test.t, ok, "no bundle updater for trust domain")
+	bundleUpdater.SetBundles(test.localBundles(td), endpointBundle)
+}
+
 func (test *managerTest) RefreshBundleFor(td spiffeid.TrustDomain) (bool, error) {
 	return test.manager.RefreshBundleFor(context.Background(), td)
 }
@@ -377,7 +543,10 @@ func (u *fakeBundleUpdater) UpdateBundle(context.Context) (*bundleutil.Bundle, *
 	u.mtx.Lock()
 	defer u.mtx.Unlock()
 	u.updateCount++
-	return u.localBundle, u.endpointBundle, errors.New("OHNO")
+	if u.endpointBundle == nil {
+		return u.localBundle, nil, errors.New("OHNO")
+	}
+	return u.localBundle, u.endpointBundle, nil
 }
 
 func (u *fakeBundleUpdater) GetTrustDomainConfig() TrustDomainConfig {
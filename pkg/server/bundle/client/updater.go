@@ -7,7 +7,9 @@ import (
 	"sync"
 
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
 	"github.com/spiffe/spire/pkg/common/bundleutil"
+	"github.com/spiffe/spire/pkg/common/pemutil"
 	"github.com/spiffe/spire/pkg/server/datastore"
 	"github.com/zeebo/errs"
 )
@@ -18,6 +20,10 @@ type BundleUpdaterConfig struct {
 
 	TrustDomainConfig TrustDomainConfig
 
+	// SVIDSource provides the X509-SVID presented to bundle endpoints that
+	// require client authentication. Optional.
+	SVIDSource x509svid.Source
+
 	// newClientHook is a test hook for injecting client behavior
 	newClientHook func(ClientConfig) (Client, error)
 }
@@ -43,6 +49,7 @@ type BundleUpdater interface {
 type bundleUpdater struct {
 	td            spiffeid.TrustDomain
 	ds            datastore.DataStore
+	svidSource    x509svid.Source
 	newClientHook func(ClientConfig) (Client, error)
 
 	trustDomainConfigMtx sync.Mutex
@@ -56,6 +63,7 @@ func NewBundleUpdater(config BundleUpdaterConfig) BundleUpdater {
 	return &bundleUpdater{
 		td:                config.TrustDomain,
 		ds:                config.DataStore,
+		svidSource:        config.SVIDSource,
 		newClientHook:     config.newClientHook,
 		trustDomainConfig: config.TrustDomainConfig,
 	}
@@ -114,8 +122,9 @@ func (u *bundleUpdater) newClient(ctx context.Context, trustDomainConfig TrustDo
 		EndpointURL: trustDomainConfig.EndpointURL,
 	}
 
-	if spiffeAuth, ok := trustDomainConfig.EndpointProfile.(HTTPSSPIFFEProfile); ok {
-		trustDomain := spiffeAuth.EndpointSPIFFEID.TrustDomain()
+	switch profile := trustDomainConfig.EndpointProfile.(type) {
+	case HTTPSSPIFFEProfile:
+		trustDomain := profile.EndpointSPIFFEID.TrustDomain()
 		localEndpointBundle, err := fetchBundleIfExists(ctx, u.ds, trustDomain)
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch local copy of bundle for %q: %w", trustDomain, err)
@@ -125,16 +134,25 @@ func (u *bundleUpdater) newClient(ctx context.Context, trustDomainConfig TrustDo
 			return nil, errors.New("can't perform SPIFFE Authentication: local copy of bundle not found")
 		}
 		clientConfig.SPIFFEAuth = &SPIFFEAuthConfig{
-			EndpointSpiffeID: spiffeAuth.EndpointSPIFFEID,
+			EndpointSpiffeID: profile.EndpointSPIFFEID,
 			RootCAs:          localEndpointBundle.RootCAs(),
 		}
+		clientConfig.SVIDSource = u.svidSource
+	case HTTPSWebProfile:
+		if profile.CABundlePath != "" {
+			rootCAs, err := pemutil.LoadCertificates(profile.CABundlePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load CA bundle for %q: %w", u.td, err)
+			}
+			clientConfig.WebPKIRootCAs = rootCAs
+		}
 	}
 	return u.newClientHook(clientConfig)
 }
 
 func fetchBundleIfExists(ctx context.Context, ds datastore.DataStore, trustDomain spiffeid.TrustDomain) (*bundleutil.Bundle, error) {
 	// Load the current bundle and extract the root CA certificates
-	bundle, err := ds.FetchBundle(ctx, trustDomain.IDString())
+	bundle, err := ds.FetchBundle(ctx, trustDomain.IDString(), datastore.RequireCurrent)
 	if err != nil {
 		return nil, errs.Wrap(err)
 	}
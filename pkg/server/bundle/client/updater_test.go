@@ -6,11 +6,15 @@ import (
 	"crypto/x509/pkix"
 	"errors"
 	"math/big"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
 	"github.com/spiffe/spire/pkg/common/bundleutil"
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/server/datastore"
 	"github.com/spiffe/spire/test/fakes/fakedatastore"
 	"github.com/spiffe/spire/test/spiretest"
 	"github.com/stretchr/testify/assert"
@@ -120,7 +124,7 @@ func TestBundleUpdaterUpdateBundle(t *testing.T) {
 				require.Nil(t, endpointBundle)
 			}
 
-			bundle, err := ds.FetchBundle(context.Background(), testCase.trustDomain.IDString())
+			bundle, err := ds.FetchBundle(context.Background(), testCase.trustDomain.IDString(), datastore.RequireCurrent)
 			require.NoError(t, err)
 			if testCase.storedBundle != nil {
 				require.NotNil(t, bundle)
@@ -132,6 +136,46 @@ func TestBundleUpdaterUpdateBundle(t *testing.T) {
 	}
 }
 
+func TestBundleUpdaterWebPKICABundle(t *testing.T) {
+	caCert := createCACertificate(t, "web")
+	caBundlePath := filepath.Join(spiretest.TempDir(t), "ca.pem")
+	require.NoError(t, os.WriteFile(caBundlePath, pemutil.EncodeCertificate(caCert), 0600))
+
+	var clientConfig ClientConfig
+	newUpdater := func(caBundlePath string) BundleUpdater {
+		return NewBundleUpdater(BundleUpdaterConfig{
+			DataStore:   fakedatastore.New(t),
+			TrustDomain: trustDomain,
+			TrustDomainConfig: TrustDomainConfig{
+				EndpointURL:     "ENDPOINT_ADDRESS",
+				EndpointProfile: HTTPSWebProfile{CABundlePath: caBundlePath},
+			},
+			newClientHook: func(config ClientConfig) (Client, error) {
+				clientConfig = config
+				return fakeClient{bundle: bundleutil.BundleFromRootCA(trustDomain, caCert)}, nil
+			},
+		})
+	}
+
+	t.Run("CA bundle loaded", func(t *testing.T) {
+		_, _, err := newUpdater(caBundlePath).UpdateBundle(context.Background())
+		require.NoError(t, err)
+		require.Nil(t, clientConfig.SPIFFEAuth)
+		require.Equal(t, []*x509.Certificate{caCert}, clientConfig.WebPKIRootCAs)
+	})
+
+	t.Run("no CA bundle", func(t *testing.T) {
+		_, _, err := newUpdater("").UpdateBundle(context.Background())
+		require.NoError(t, err)
+		require.Nil(t, clientConfig.WebPKIRootCAs)
+	})
+
+	t.Run("CA bundle missing", func(t *testing.T) {
+		_, _, err := newUpdater(filepath.Join(spiretest.TempDir(t), "missing.pem")).UpdateBundle(context.Background())
+		spiretest.RequireErrorContains(t, err, `failed to load CA bundle for "domain.test"`)
+	})
+}
+
 func TestBundleUpdaterConfiguration(t *testing.T) {
 	configs := []TrustDomainConfig{
 		{
@@ -142,6 +186,10 @@ func TestBundleUpdaterConfiguration(t *testing.T) {
 			EndpointURL:     "https://some-domain.test/webB",
 			EndpointProfile: HTTPSWebProfile{},
 		},
+		{
+			EndpointURL:     "https://some-domain.test/webB",
+			EndpointProfile: HTTPSWebProfile{CABundlePath: "/path/to/ca.pem"},
+		},
 		{
 			EndpointURL: "https://some-domain.test/spiffeA",
 			EndpointProfile: HTTPSSPIFFEProfile{
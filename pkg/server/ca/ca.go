@@ -20,6 +20,7 @@ import (
 	"github.com/spiffe/spire/pkg/common/x509util"
 	"github.com/spiffe/spire/pkg/server/api"
 	"github.com/zeebo/errs"
+	"gopkg.in/square/go-jose.v2"
 )
 
 const (
@@ -119,6 +120,23 @@ type Config struct {
 	Clock         clock.Clock
 	CASubject     pkix.Name
 	HealthChecker health.Checker
+
+	// CRLDistributionPoint, if set, is added to the CRL Distribution Points
+	// extension of the X509-SVIDs and downstream CA certificates signed by
+	// the CA.
+	CRLDistributionPoint string
+
+	// JWTAllowedSigningAlgorithms, if set, restricts the algorithms that
+	// JWT-SVIDs can be signed with.
+	JWTAllowedSigningAlgorithms []jose.SignatureAlgorithm
+
+	// MaxX509SVIDTTL, if set, is the maximum TTL of the X509-SVIDs signed by
+	// the CA. Longer requested TTLs are clamped to it.
+	MaxX509SVIDTTL time.Duration
+
+	// MaxJWTSVIDTTL, if set, is the maximum TTL of the JWT-SVIDs signed by
+	// the CA. Longer requested TTLs are clamped to it.
+	MaxJWTSVIDTTL time.Duration
 }
 
 type CA struct {
@@ -145,8 +163,9 @@ func NewCA(config Config) *CA {
 	ca := &CA{
 		c: config,
 		jwtSigner: jwtsvid.NewSigner(jwtsvid.SignerConfig{
-			Clock:  config.Clock,
-			Issuer: config.JWTIssuer,
+			Clock:             config.Clock,
+			Issuer:            config.JWTIssuer,
+			AllowedAlgorithms: config.JWTAllowedSigningAlgorithms,
 		}),
 	}
 
@@ -191,10 +210,11 @@ func (ca *CA) SignX509SVID(ctx context.Context, params X509SVIDParams) ([]*x509.
 	if params.TTL <= 0 {
 		params.TTL = ca.c.X509SVIDTTL
 	}
+	params.TTL = ca.clampTTL(params.TTL, ca.c.MaxX509SVIDTTL, params.SpiffeID, "X509-SVID")
 
 	notBefore, notAfter := ca.capLifetime(params.TTL, x509CA.Certificate.NotAfter)
 
-	x509SVID, err := signX509SVID(ca.c.TrustDomain, x509CA, params, notBefore, notAfter)
+	x509SVID, err := signX509SVID(ca.c.TrustDomain, x509CA, params, notBefore, notAfter, ca.crlDistributionPoints())
 	if err != nil {
 		return nil, err
 	}
@@ -233,6 +253,7 @@ func (ca *CA) SignX509CASVID(ctx context.Context, params X509CASVIDParams) ([]*x
 	// added if the subject and issuer match name matches (unlikely due to the
 	// OU override below, but just to be safe).
 	template.AuthorityKeyId = x509CA.Certificate.SubjectKeyId
+	template.CRLDistributionPoints = ca.crlDistributionPoints()
 
 	cert, err := createCertificate(template, x509CA.Certificate, template.PublicKey, x509CA.Signer)
 	if err != nil {
@@ -258,6 +279,7 @@ func (ca *CA) SignJWTSVID(ctx context.Context, params JWTSVIDParams) (string, er
 	if ttl <= 0 {
 		ttl = ca.c.JWTSVIDTTL
 	}
+	ttl = ca.clampTTL(ttl, ca.c.MaxJWTSVIDTTL, params.SpiffeID, "JWT-SVID")
 	_, expiresAt := ca.capLifetime(ttl, jwtKey.NotAfter)
 
 	token, err := ca.jwtSigner.SignToken(params.SpiffeID, params.Audience, expiresAt, jwtKey.Signer, jwtKey.Kid)
@@ -269,6 +291,20 @@ func (ca *CA) SignJWTSVID(ctx context.Context, params JWTSVIDParams) (string, er
 	return token, nil
 }
 
+// clampTTL returns the given TTL, clamped to the maximum TTL if one is set.
+// A warning is logged when the TTL is clamped.
+func (ca *CA) clampTTL(ttl, maxTTL time.Duration, id spiffeid.ID, svidType string) time.Duration {
+	if maxTTL <= 0 || ttl <= maxTTL {
+		return ttl
+	}
+	ca.c.Log.WithFields(logrus.Fields{
+		telemetry.SPIFFEID: id.String(),
+		telemetry.TTL:      ttl.String(),
+		telemetry.MaxTTL:   maxTTL.String(),
+	}).Warnf("Requested %s TTL exceeds the maximum; clamping to the maximum", svidType)
+	return maxTTL
+}
+
 func (ca *CA) capLifetime(ttl time.Duration, expirationCap time.Time) (notBefore, notAfter time.Time) {
 	now := ca.c.Clock.Now()
 	notBefore = now.Add(-backdate)
@@ -279,7 +315,14 @@ func (ca *CA) capLifetime(ttl time.Duration, expirationCap time.Time) (notBefore
 	return notBefore, notAfter
 }
 
-func signX509SVID(td spiffeid.TrustDomain, x509CA *X509CA, params X509SVIDParams, notBefore, notAfter time.Time) ([]*x509.Certificate, error) {
+func (ca *CA) crlDistributionPoints() []string {
+	if ca.c.CRLDistributionPoint == "" {
+		return nil
+	}
+	return []string{ca.c.CRLDistributionPoint}
+}
+
+func signX509SVID(td spiffeid.TrustDomain, x509CA *X509CA, params X509SVIDParams, notBefore, notAfter time.Time, crlDistributionPoints []string) ([]*x509.Certificate, error) {
 	if x509CA == nil {
 		return nil, errs.New("X509 CA is not available for signing")
 	}
@@ -302,6 +345,7 @@ func signX509SVID(td spiffeid.TrustDomain, x509CA *X509CA, params X509SVIDParams
 	// Explicitly set the AKI on the signed certificate, otherwise it won't be
 	// added if the subject and issuer match name match (however unlikely).
 	template.AuthorityKeyId = x509CA.Certificate.SubjectKeyId
+	template.CRLDistributionPoints = crlDistributionPoints
 
 	// for non-CA certificates, add DNS names to certificate. the first DNS
 	// name is also added as the common name.
@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/sirupsen/logrus"
 	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
 	"github.com/spiffe/spire/pkg/common/health"
@@ -18,8 +19,10 @@ import (
 	"github.com/spiffe/spire/pkg/common/x509util"
 	"github.com/spiffe/spire/test/clock"
 	"github.com/spiffe/spire/test/fakes/fakehealthchecker"
+	"github.com/spiffe/spire/test/spiretest"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"gopkg.in/square/go-jose.v2"
 )
 
 var (
@@ -110,6 +113,18 @@ func (s *CATestSuite) TestSignX509SVID() {
 
 	// Subject is hard coded by the CA and should not be pulled from the CSR.
 	s.Equal("O=SPIRE,C=US", svid.Subject.String())
+
+	// No CRL distribution point is set by default
+	s.Empty(svid.CRLDistributionPoints)
+}
+
+func (s *CATestSuite) TestSignX509SVIDWithCRLDistributionPoint() {
+	s.ca.c.CRLDistributionPoint = "http://crl.example.org/spire.crl"
+
+	svidChain, err := s.ca.SignX509SVID(ctx, s.createX509SVIDParams())
+	s.Require().NoError(err)
+	s.Require().Len(svidChain, 1)
+	s.Equal([]string{"http://crl.example.org/spire.crl"}, svidChain[0].CRLDistributionPoints)
 }
 
 func (s *CATestSuite) TestSignX509SVIDCannotSignTrustDomainID() {
@@ -246,6 +261,38 @@ func (s *CATestSuite) TestSignX509SVIDCapsTTLToCATTL() {
 	s.Require().Equal(s.clock.Now().Add(10*time.Minute), svid[0].NotAfter)
 }
 
+func (s *CATestSuite) TestSignX509SVIDClampsTTLToMaxTTL() {
+	s.ca.c.MaxX509SVIDTTL = 2 * time.Minute
+	params := s.createX509SVIDParams()
+	params.TTL = 5 * time.Minute
+	svid, err := s.ca.SignX509SVID(ctx, params)
+	s.Require().NoError(err)
+	s.Require().Len(svid, 1)
+	s.Require().Equal(s.clock.Now().Add(2*time.Minute), svid[0].NotAfter)
+	spiretest.AssertLogs(s.T(), s.logHook.AllEntries(), []spiretest.LogEntry{
+		{
+			Level:   logrus.WarnLevel,
+			Message: "Requested X509-SVID TTL exceeds the maximum; clamping to the maximum",
+			Data: logrus.Fields{
+				telemetry.SPIFFEID: "spiffe://example.org/workload",
+				telemetry.TTL:      "5m0s",
+				telemetry.MaxTTL:   "2m0s",
+			},
+		},
+	})
+}
+
+func (s *CATestSuite) TestSignX509SVIDDoesNotClampTTLBelowMaxTTL() {
+	s.ca.c.MaxX509SVIDTTL = 2 * time.Minute
+	params := s.createX509SVIDParams()
+	params.TTL = time.Minute
+	svid, err := s.ca.SignX509SVID(ctx, params)
+	s.Require().NoError(err)
+	s.Require().Len(svid, 1)
+	s.Require().Equal(s.clock.Now().Add(time.Minute), svid[0].NotAfter)
+	s.Require().Empty(s.logHook.AllEntries())
+}
+
 func (s *CATestSuite) TestSignX509SVIDValidatesTrustDomain() {
 	_, err := s.ca.SignX509SVID(ctx, s.createX509SVIDParamsInDomain(trustDomainFoo))
 	s.Require().EqualError(err, `"spiffe://foo.com/workload" is not a member of trust domain "example.org"`)
@@ -294,6 +341,36 @@ func (s *CATestSuite) TestSignJWTSVIDCapsTTLToKeyExpiry() {
 	s.Require().Equal(s.clock.Now().Add(10*time.Minute), expiresAt)
 }
 
+func (s *CATestSuite) TestSignJWTSVIDClampsTTLToMaxTTL() {
+	s.ca.c.MaxJWTSVIDTTL = 2 * time.Minute
+	token, err := s.ca.SignJWTSVID(ctx, s.createJWTSVIDParams(trustDomainExample, 5*time.Minute))
+	s.Require().NoError(err)
+	_, expiresAt, err := jwtsvid.GetTokenExpiry(token)
+	s.Require().NoError(err)
+	s.Require().Equal(s.clock.Now().Add(2*time.Minute), expiresAt)
+	spiretest.AssertLogs(s.T(), s.logHook.AllEntries(), []spiretest.LogEntry{
+		{
+			Level:   logrus.WarnLevel,
+			Message: "Requested JWT-SVID TTL exceeds the maximum; clamping to the maximum",
+			Data: logrus.Fields{
+				telemetry.SPIFFEID: "spiffe://example.org/workload",
+				telemetry.TTL:      "5m0s",
+				telemetry.MaxTTL:   "2m0s",
+			},
+		},
+	})
+}
+
+func (s *CATestSuite) TestSignJWTSVIDDoesNotClampTTLBelowMaxTTL() {
+	s.ca.c.MaxJWTSVIDTTL = 2 * time.Minute
+	token, err := s.ca.SignJWTSVID(ctx, s.createJWTSVIDParams(trustDomainExample, time.Minute))
+	s.Require().NoError(err)
+	_, expiresAt, err := jwtsvid.GetTokenExpiry(token)
+	s.Require().NoError(err)
+	s.Require().Equal(s.clock.Now().Add(time.Minute), expiresAt)
+	s.Require().Empty(s.logHook.AllEntries())
+}
+
 func (s *CATestSuite) TestSignJWTSVIDValidatesJSR() {
 	// spiffe id for wrong trust domain
 	_, err := s.ca.SignJWTSVID(ctx, s.createJWTSVIDParams(trustDomainFoo, 0))
@@ -306,6 +383,25 @@ func (s *CATestSuite) TestSignJWTSVIDValidatesJSR() {
 	s.Require().EqualError(err, "unable to sign JWT SVID: audience is required")
 }
 
+func (s *CATestSuite) TestSignJWTSVIDWithAllowedSigningAlgorithm() {
+	s.ca.jwtSigner = jwtsvid.NewSigner(jwtsvid.SignerConfig{
+		Clock:             s.clock,
+		AllowedAlgorithms: []jose.SignatureAlgorithm{jose.RS256, jose.ES256},
+	})
+	token, err := s.ca.SignJWTSVID(ctx, s.createJWTSVIDParams(trustDomainExample, 0))
+	s.Require().NoError(err)
+	s.Require().NotEmpty(token)
+}
+
+func (s *CATestSuite) TestSignJWTSVIDWithDisallowedSigningAlgorithm() {
+	s.ca.jwtSigner = jwtsvid.NewSigner(jwtsvid.SignerConfig{
+		Clock:             s.clock,
+		AllowedAlgorithms: []jose.SignatureAlgorithm{jose.RS256},
+	})
+	_, err := s.ca.SignJWTSVID(ctx, s.createJWTSVIDParams(trustDomainExample, 0))
+	s.Require().EqualError(err, "unable to sign JWT SVID: signing algorithm ES256 is not allowed")
+}
+
 func (s *CATestSuite) TestSignX509CASVIDNoCASet() {
 	s.ca.SetX509CA(nil)
 	_, err := s.ca.SignX509CASVID(ctx, s.createX509CASVIDParams(trustDomainExample))
@@ -335,6 +431,18 @@ func (s *CATestSuite) TestSignX509CASVID() {
 	// Subject is controlled exclusively by the CA and should not be pulled from
 	// the CSR. The DOWNSTREAM OU should be appended.
 	s.Equal("CN=CA,OU=DOWNSTREAM-1", svid.Subject.String())
+
+	// No CRL distribution point is set by default
+	s.Empty(svid.CRLDistributionPoints)
+}
+
+func (s *CATestSuite) TestSignX509CASVIDWithCRLDistributionPoint() {
+	s.ca.c.CRLDistributionPoint = "http://crl.example.org/spire.crl"
+
+	svidChain, err := s.ca.SignX509CASVID(ctx, s.createX509CASVIDParams(trustDomainExample))
+	s.Require().NoError(err)
+	s.Require().Len(svidChain, 1)
+	s.Equal([]string{"http://crl.example.org/spire.crl"}, svidChain[0].CRLDistributionPoints)
 }
 
 func (s *CATestSuite) TestSignX509CASVIDUsesDefaultTTLIfTTLUnspecified() {
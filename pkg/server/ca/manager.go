@@ -824,7 +824,7 @@ func (m *Manager) fetchRequiredBundle(ctx context.Context) (*common.Bundle, erro
 
 func (m *Manager) fetchOptionalBundle(ctx context.Context) (*common.Bundle, error) {
 	ds := m.c.Catalog.GetDataStore()
-	bundle, err := ds.FetchBundle(ctx, m.c.TrustDomain.IDString())
+	bundle, err := ds.FetchBundle(ctx, m.c.TrustDomain.IDString(), datastore.RequireCurrent)
 	if err != nil {
 		return nil, errs.Wrap(err)
 	}
@@ -21,6 +21,7 @@ import (
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
 	"github.com/spiffe/spire/pkg/common/telemetry"
 	telemetry_server "github.com/spiffe/spire/pkg/common/telemetry/server"
+	"github.com/spiffe/spire/pkg/server/datastore"
 	"github.com/spiffe/spire/pkg/server/plugin/keymanager"
 	"github.com/spiffe/spire/pkg/server/plugin/notifier"
 	"github.com/spiffe/spire/pkg/server/plugin/upstreamauthority"
@@ -846,7 +847,7 @@ func (s *ManagerSuite) fetchBundle() *common.Bundle {
 }
 
 func (s *ManagerSuite) fetchBundleForTrustDomain(trustDomain spiffeid.TrustDomain) *common.Bundle {
-	bundle, err := s.ds.FetchBundle(ctx, trustDomain.IDString())
+	bundle, err := s.ds.FetchBundle(ctx, trustDomain.IDString(), datastore.RequireCurrent)
 	s.Require().NoError(err)
 	s.Require().NotNil(bundle, "missing bundle for trust domain %q", trustDomain.IDString())
 	return bundle
@@ -42,7 +42,7 @@ func New(ds datastore.DataStore, clock clock.Clock) *DatastoreCache {
 	}
 }
 
-func (ds *DatastoreCache) FetchBundle(ctx context.Context, trustDomain string) (*common.Bundle, error) {
+func (ds *DatastoreCache) FetchBundle(ctx context.Context, trustDomain string, dataConsistency datastore.DataConsistency) (*common.Bundle, error) {
 	ds.bundlesMu.Lock()
 	entry, ok := ds.bundles[trustDomain]
 	if !ok {
@@ -54,7 +54,7 @@ func (ds *DatastoreCache) FetchBundle(ctx context.Context, trustDomain string) (
 	entry.mu.Lock()
 	defer entry.mu.Unlock()
 	if entry.ts.IsZero() || ds.clock.Now().Sub(entry.ts) >= datastoreCacheExpiry || ctx.Value(useCache{}) == nil {
-		bundle, err := ds.DataStore.FetchBundle(ctx, trustDomain)
+		bundle, err := ds.DataStore.FetchBundle(ctx, trustDomain, dataConsistency)
 		if err != nil {
 			return nil, err
 		}
@@ -29,7 +29,7 @@ func TestFetchBundleCache(t *testing.T) {
 	ctxWithoutCache := context.Background()
 
 	// Assert bundle is missing
-	bundle, err := cache.FetchBundle(ctxWithCache, td)
+	bundle, err := cache.FetchBundle(ctxWithCache, td, datastore.TolerateStale)
 	require.NoError(t, err)
 	require.Nil(t, bundle)
 
@@ -39,7 +39,7 @@ func TestFetchBundleCache(t *testing.T) {
 
 	// Assert that we didn't cache the bundle miss and that the newly added
 	// bundle is there
-	bundle, err = cache.FetchBundle(ctxWithCache, td)
+	bundle, err = cache.FetchBundle(ctxWithCache, td, datastore.TolerateStale)
 	require.NoError(t, err)
 	spiretest.RequireProtoEqual(t, bundle1, bundle)
 
@@ -48,13 +48,13 @@ func TestFetchBundleCache(t *testing.T) {
 	require.NoError(t, err)
 
 	// Assert bundle contents unchanged since cache is still valid
-	bundle, err = cache.FetchBundle(ctxWithCache, td)
+	bundle, err = cache.FetchBundle(ctxWithCache, td, datastore.TolerateStale)
 	require.NoError(t, err)
 	spiretest.RequireProtoEqual(t, bundle1, bundle)
 
 	// If caches expires by time, FetchBundle must fetch a fresh bundle
 	clock.Add(datastoreCacheExpiry)
-	bundle, err = cache.FetchBundle(ctxWithCache, td)
+	bundle, err = cache.FetchBundle(ctxWithCache, td, datastore.TolerateStale)
 	require.NoError(t, err)
 	spiretest.RequireProtoEqual(t, bundle2, bundle)
 
@@ -63,11 +63,11 @@ func TestFetchBundleCache(t *testing.T) {
 	require.NoError(t, err)
 
 	// If a context without cache is used, FetchBundle must fetch a fresh bundle
-	bundle, err = cache.FetchBundle(ctxWithoutCache, td)
+	bundle, err = cache.FetchBundle(ctxWithoutCache, td, datastore.RequireCurrent)
 	require.NoError(t, err)
 	spiretest.RequireProtoEqual(t, bundle1, bundle)
 
-	bundle, err = cache.FetchBundle(ctxWithCache, td)
+	bundle, err = cache.FetchBundle(ctxWithCache, td, datastore.TolerateStale)
 	require.NoError(t, err)
 	spiretest.RequireProtoEqual(t, bundle1, bundle)
 }
@@ -159,7 +159,7 @@ func TestBundleInvalidations(t *testing.T) {
 			require.NoError(t, err)
 
 			// Make an initial fetch call to store the bundle in cache
-			_, err = cache.FetchBundle(context.Background(), td)
+			_, err = cache.FetchBundle(context.Background(), td, datastore.RequireCurrent)
 			require.NoError(t, err)
 
 			// Run the function that invalidates the bundle (Prune, Append, etc)
@@ -176,7 +176,7 @@ func TestBundleInvalidations(t *testing.T) {
 			// If invalidatingFunc fails, we keep the current cache value,
 			// next call to FetchBundle should return bundle1
 			if tt.dsFailure {
-				bundle, err := cache.FetchBundle(ctxWithCache, td)
+				bundle, err := cache.FetchBundle(ctxWithCache, td, datastore.TolerateStale)
 				require.NoError(t, err)
 				spiretest.RequireProtoEqual(t, bundle1, bundle)
 				return
@@ -185,7 +185,7 @@ func TestBundleInvalidations(t *testing.T) {
 			// If invalidatingFunc succeeds, we invalidate the current cache
 			// value, next call to FetchBundle should return the updated
 			// bundle (bundle2)
-			bundle, err := cache.FetchBundle(ctxWithCache, td)
+			bundle, err := cache.FetchBundle(ctxWithCache, td, datastore.TolerateStale)
 			require.NoError(t, err)
 			spiretest.RequireProtoEqual(t, bundle2, bundle)
 		})
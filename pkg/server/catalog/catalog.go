@@ -56,6 +56,14 @@ type Config struct {
 	TrustDomain  spiffeid.TrustDomain
 	PluginConfig HCLPluginConfigMap
 
+	// UpstreamAuthorityOrder is the order in which the UpstreamAuthority
+	// plugins are tried. Required when more than one is configured.
+	UpstreamAuthorityOrder []string
+
+	// DataStoreMaxAttempts is the maximum number of times a datastore
+	// operation failing with a transient error is attempted.
+	DataStoreMaxAttempts int
+
 	Metrics          telemetry.Metrics
 	IdentityProvider *identityprovider.IdentityProvider
 	AgentStore       *agentstore.AgentStore
@@ -120,10 +128,19 @@ func Load(ctx context.Context, config Config) (_ *Repository, err error) {
 		return nil, err
 	}
 
+	if err := repo.selectUpstreamAuthority(config.Log.WithField(telemetry.SubsystemName, "upstream_authority_failover"), config.UpstreamAuthorityOrder); err != nil {
+		repo.Close()
+		return nil, err
+	}
+
 	_ = config.HealthChecker.AddCheck("catalog.datastore", &datastore.Health{
 		DataStore: dataStore,
 	})
 
+	dataStore = datastore.WithRetry(dataStore, datastore.RetryConfig{
+		Log:         config.Log.WithField(telemetry.SubsystemName, telemetry.Datastore),
+		MaxAttempts: config.DataStoreMaxAttempts,
+	})
 	dataStore = ds_telemetry.WithMetrics(dataStore, config.Metrics)
 	dataStore = dscache.New(dataStore, clock.New())
 
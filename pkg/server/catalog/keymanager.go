@@ -6,6 +6,7 @@ import (
 	"github.com/spiffe/spire/pkg/server/plugin/keymanager"
 	"github.com/spiffe/spire/pkg/server/plugin/keymanager/awskms"
 	"github.com/spiffe/spire/pkg/server/plugin/keymanager/disk"
+	"github.com/spiffe/spire/pkg/server/plugin/keymanager/hashicorpvault"
 	"github.com/spiffe/spire/pkg/server/plugin/keymanager/memory"
 )
 
@@ -29,6 +30,7 @@ func (repo *keyManagerRepository) BuiltIns() []catalog.BuiltIn {
 	return []catalog.BuiltIn{
 		awskms.BuiltIn(),
 		disk.BuiltIn(),
+		hashicorpvault.BuiltIn(),
 		memory.BuiltIn(),
 	}
 }
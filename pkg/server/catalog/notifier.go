@@ -5,6 +5,7 @@ import (
 	"github.com/spiffe/spire/pkg/server/plugin/notifier"
 	"github.com/spiffe/spire/pkg/server/plugin/notifier/gcsbundle"
 	"github.com/spiffe/spire/pkg/server/plugin/notifier/k8sbundle"
+	"github.com/spiffe/spire/pkg/server/plugin/notifier/webhookbundle"
 )
 
 type notifierRepository struct {
@@ -29,6 +30,7 @@ func (repo *notifierRepository) BuiltIns() []catalog.BuiltIn {
 	return []catalog.BuiltIn{
 		gcsbundle.BuiltIn(),
 		k8sbundle.BuiltIn(),
+		webhookbundle.BuiltIn(),
 	}
 }
 
@@ -1,6 +1,11 @@
 package catalog
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/andres-erbsen/clock"
+	"github.com/sirupsen/logrus"
 	"github.com/spiffe/spire/pkg/common/catalog"
 	"github.com/spiffe/spire/pkg/server/plugin/upstreamauthority"
 	"github.com/spiffe/spire/pkg/server/plugin/upstreamauthority/awspca"
@@ -17,11 +22,59 @@ type upstreamAuthorityRepository struct {
 }
 
 func (repo *upstreamAuthorityRepository) Binder() interface{} {
-	return repo.SetUpstreamAuthority
+	return repo.AddUpstreamAuthority
 }
 
 func (repo *upstreamAuthorityRepository) Constraints() catalog.Constraints {
-	return catalog.MaybeOne()
+	return catalog.ZeroOrMore()
+}
+
+// selectUpstreamAuthority sets the UpstreamAuthority used by the server from
+// the loaded plugins. When more than one plugin is loaded, the order they are
+// tried in must be provided, and they are used through a failover upstream
+// authority.
+func (repo *upstreamAuthorityRepository) selectUpstreamAuthority(log logrus.FieldLogger, order []string) error {
+	loaded := make(map[string]upstreamauthority.UpstreamAuthority)
+	for _, upstreamAuthority := range repo.UpstreamAuthorities {
+		loaded[upstreamAuthority.Name()] = upstreamAuthority
+	}
+
+	if len(order) == 0 {
+		switch len(repo.UpstreamAuthorities) {
+		case 0:
+		case 1:
+			repo.SetUpstreamAuthority(repo.UpstreamAuthorities[0])
+		default:
+			return fmt.Errorf("upstream_authority_order is required when more than one %s plugin is configured", upstreamAuthorityType)
+		}
+		return nil
+	}
+
+	var ordered []upstreamauthority.UpstreamAuthority
+	for _, name := range order {
+		upstreamAuthority, ok := loaded[name]
+		if !ok {
+			return fmt.Errorf("upstream_authority_order lists %q, which is not a configured %s plugin", name, upstreamAuthorityType)
+		}
+		ordered = append(ordered, upstreamAuthority)
+		delete(loaded, name)
+	}
+	if len(loaded) > 0 {
+		var missing []string
+		for _, upstreamAuthority := range repo.UpstreamAuthorities {
+			if _, ok := loaded[upstreamAuthority.Name()]; ok {
+				missing = append(missing, upstreamAuthority.Name())
+			}
+		}
+		return fmt.Errorf("upstream_authority_order is missing the %s plugins: %s", upstreamAuthorityType, strings.Join(missing, ", "))
+	}
+
+	if len(ordered) == 1 {
+		repo.SetUpstreamAuthority(ordered[0])
+		return nil
+	}
+	repo.SetUpstreamAuthority(upstreamauthority.NewFailover(log, clock.New(), ordered))
+	return nil
 }
 
 func (repo *upstreamAuthorityRepository) Versions() []catalog.Version {
@@ -0,0 +1,100 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiffe/spire/pkg/server/plugin/upstreamauthority"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectUpstreamAuthority(t *testing.T) {
+	log, _ := test.NewNullLogger()
+
+	primary := fakeUpstreamAuthority{name: "primary"}
+	secondary := fakeUpstreamAuthority{name: "secondary"}
+
+	for _, tt := range []struct {
+		name      string
+		loaded    []upstreamauthority.UpstreamAuthority
+		order     []string
+		expectErr string
+		expectUA  string
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:     "one",
+			loaded:   []upstreamauthority.UpstreamAuthority{primary},
+			expectUA: "primary",
+		},
+		{
+			name:     "one with order",
+			loaded:   []upstreamauthority.UpstreamAuthority{primary},
+			order:    []string{"primary"},
+			expectUA: "primary",
+		},
+		{
+			name:     "many with order",
+			loaded:   []upstreamauthority.UpstreamAuthority{secondary, primary},
+			order:    []string{"primary", "secondary"},
+			expectUA: "primary,secondary",
+		},
+		{
+			name:      "many without order",
+			loaded:    []upstreamauthority.UpstreamAuthority{primary, secondary},
+			expectErr: "upstream_authority_order is required when more than one UpstreamAuthority plugin is configured",
+		},
+		{
+			name:      "order with unknown plugin",
+			loaded:    []upstreamauthority.UpstreamAuthority{primary, secondary},
+			order:     []string{"primary", "other"},
+			expectErr: `upstream_authority_order lists "other", which is not a configured UpstreamAuthority plugin`,
+		},
+		{
+			name:      "order with duplicate plugin",
+			loaded:    []upstreamauthority.UpstreamAuthority{primary, secondary},
+			order:     []string{"primary", "primary"},
+			expectErr: `upstream_authority_order lists "primary", which is not a configured UpstreamAuthority plugin`,
+		},
+		{
+			name:      "order missing plugin",
+			loaded:    []upstreamauthority.UpstreamAuthority{primary, secondary},
+			order:     []string{"primary"},
+			expectErr: "upstream_authority_order is missing the UpstreamAuthority plugins: secondary",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(upstreamAuthorityRepository)
+			for _, upstreamAuthority := range tt.loaded {
+				repo.AddUpstreamAuthority(upstreamAuthority)
+			}
+
+			err := repo.selectUpstreamAuthority(log, tt.order)
+			if tt.expectErr != "" {
+				require.EqualError(t, err, tt.expectErr)
+				return
+			}
+			require.NoError(t, err)
+
+			upstreamAuthority, ok := repo.GetUpstreamAuthority()
+			if tt.expectUA == "" {
+				require.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			require.Equal(t, tt.expectUA, upstreamAuthority.Name())
+		})
+	}
+}
+
+type fakeUpstreamAuthority struct {
+	upstreamauthority.UpstreamAuthority
+	name string
+}
+
+func (ua fakeUpstreamAuthority) Name() string {
+	return ua.name
+}
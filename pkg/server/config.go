@@ -10,22 +10,48 @@ import (
 	common "github.com/spiffe/spire/pkg/common/catalog"
 	"github.com/spiffe/spire/pkg/common/health"
 	"github.com/spiffe/spire/pkg/common/telemetry"
+	"github.com/spiffe/spire/pkg/common/tlspolicy"
 	"github.com/spiffe/spire/pkg/server/authpolicy"
 	bundle_client "github.com/spiffe/spire/pkg/server/bundle/client"
 	"github.com/spiffe/spire/pkg/server/endpoints"
 	"github.com/spiffe/spire/pkg/server/endpoints/bundle"
 	"github.com/spiffe/spire/pkg/server/plugin/keymanager"
+	"gopkg.in/square/go-jose.v2"
 )
 
 type Config struct {
 	// Configurations for server plugins
 	PluginConfigs common.HCLPluginConfigMap
 
+	// Order in which the UpstreamAuthority plugins are tried, when more than
+	// one is configured
+	UpstreamAuthorityOrder []string
+
+	// DataStoreMaxAttempts is the maximum number of times a datastore
+	// operation failing with a transient error is attempted. Operations are
+	// not retried when lower than two.
+	DataStoreMaxAttempts int
+
 	Log logrus.FieldLogger
 
 	// If true enables audit logs
 	AuditLogEnabled bool
 
+	// If set, audit logs are written to this logger instead of Log
+	AuditLog logrus.FieldLogger
+
+	// If true registers the gRPC reflection service on the UDS server
+	AdminAPIReflectionEnabled bool
+
+	// Maximum size in bytes of the messages received and sent by the API
+	// servers. Zero means the default.
+	MaxRecvMessageSize int
+	MaxSendMessageSize int
+
+	// TLSPolicy restricts the TLS versions and cipher suites negotiated by
+	// the TCP API server and the bundle endpoint
+	TLSPolicy tlspolicy.Policy
+
 	// Address of SPIRE server
 	BindAddress *net.TCPAddr
 
@@ -55,9 +81,19 @@ type Config struct {
 	// AgentTTL is time-to-live for agent SVIDs
 	AgentTTL time.Duration
 
+	// DeadNodeTTL is how long after its SVID expires an attested node is
+	// pruned. Attested nodes are never pruned when zero.
+	DeadNodeTTL time.Duration
+
 	// SVIDTTL is default time-to-live for SVIDs
 	SVIDTTL time.Duration
 
+	// MaxX509SVIDTTL and MaxJWTSVIDTTL, if set, are the maximum
+	// time-to-live for X509-SVIDs and JWT-SVIDs. Longer requested TTLs are
+	// clamped to the maximum.
+	MaxX509SVIDTTL time.Duration
+	MaxJWTSVIDTTL  time.Duration
+
 	// CATTL is the time-to-live for the server CA. This only applies to
 	// self-signed CA certificates, otherwise it is up to the upstream CA.
 	CATTL time.Duration
@@ -66,9 +102,18 @@ type Config struct {
 	// If unset, the JWT-SVID will not have an issuer claim.
 	JWTIssuer string
 
+	// JWTAllowedSigningAlgorithms, if set, restricts the algorithms that
+	// JWT-SVIDs can be signed with. Minting fails if the algorithm for the
+	// active JWT key is not in the list.
+	JWTAllowedSigningAlgorithms []jose.SignatureAlgorithm
+
 	// CASubject is the subject used in the CA certificate
 	CASubject pkix.Name
 
+	// CRLDistributionPoint is the URL embedded in the CRL Distribution Points
+	// extension of the certificates signed by the server, if set.
+	CRLDistributionPoint string
+
 	// Telemetry provides the configuration for metrics exporting
 	Telemetry telemetry.FileConfig
 
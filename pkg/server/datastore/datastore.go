@@ -17,7 +17,7 @@ type DataStore interface {
 	CountBundles(context.Context) (int32, error)
 	CreateBundle(context.Context, *common.Bundle) (*common.Bundle, error)
 	DeleteBundle(ctx context.Context, trustDomainID string, mode DeleteMode) error
-	FetchBundle(ctx context.Context, trustDomainID string) (*common.Bundle, error)
+	FetchBundle(ctx context.Context, trustDomainID string, dataConsistency DataConsistency) (*common.Bundle, error)
 	ListBundles(context.Context, *ListBundlesRequest) (*ListBundlesResponse, error)
 	PruneBundle(ctx context.Context, trustDomainID string, expiresBefore time.Time) (changed bool, err error)
 	SetBundle(context.Context, *common.Bundle) (*common.Bundle, error)
@@ -0,0 +1,367 @@
+package datastore
+
+import (
+	"context"
+	"time"
+
+	"github.com/andres-erbsen/clock"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
+	"github.com/spiffe/spire/pkg/common/telemetry"
+	"github.com/spiffe/spire/proto/spire/common"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultRetryInitialBackoff = 100 * time.Millisecond
+	defaultRetryMaxBackoff     = 2 * time.Second
+)
+
+// RetryConfig configures the DataStore returned by WithRetry.
+type RetryConfig struct {
+	// Log is used to log the retried errors.
+	Log logrus.FieldLogger
+
+	// MaxAttempts is the maximum number of times an operation is attempted,
+	// including the first attempt. Operations are not retried when lower
+	// than two.
+	MaxAttempts int
+
+	// InitialBackoff is the time to wait before the first retry. It is
+	// doubled on every retry up to MaxBackoff. Defaults to 100ms.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the maximum time to wait between retries. Defaults to 2s.
+	MaxBackoff time.Duration
+
+	// Clock is used to wait between retries. Defaults to the real clock.
+	Clock clock.Clock
+}
+
+// WithRetry wraps a datastore so operations failing with a transient error
+// (see IsTransientError) are retried with an exponential backoff, up to the
+// configured maximum number of attempts. Other errors, like constraint
+// violations, are returned right away.
+func WithRetry(ds DataStore, config RetryConfig) DataStore {
+	if config.MaxAttempts < 2 {
+		return ds
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = defaultRetryInitialBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaultRetryMaxBackoff
+	}
+	if config.Clock == nil {
+		config.Clock = clock.New()
+	}
+	return retryWrapper{ds: ds, c: config}
+}
+
+// IsTransientError returns true if the error returned by a datastore
+// operation is transient (e.g. a connection reset during a database failover
+// or a deadlock), meaning that the operation can be retried. Datastores must
+// only report errors as transient when nothing was committed, since writes
+// are not idempotent.
+func IsTransientError(err error) bool {
+	return status.Code(err) == codes.Unavailable
+}
+
+type retryWrapper struct {
+	ds DataStore
+	c  RetryConfig
+}
+
+func (w retryWrapper) retry(ctx context.Context, op func() error) error {
+	backoff := w.c.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= w.c.MaxAttempts || !IsTransientError(err) {
+			return err
+		}
+
+		w.c.Log.WithError(err).WithFields(logrus.Fields{
+			telemetry.Attempt:       attempt,
+			telemetry.RetryInterval: backoff,
+		}).Warn("Transient datastore error; retrying")
+
+		select {
+		case <-w.c.Clock.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+
+		backoff *= 2
+		if backoff > w.c.MaxBackoff {
+			backoff = w.c.MaxBackoff
+		}
+	}
+}
+
+func (w retryWrapper) AppendBundle(ctx context.Context, bundle *common.Bundle) (resp *common.Bundle, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, err = w.ds.AppendBundle(ctx, bundle)
+		return err
+	})
+	return resp, err
+}
+
+func (w retryWrapper) CountBundles(ctx context.Context) (resp int32, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, err = w.ds.CountBundles(ctx)
+		return err
+	})
+	return resp, err
+}
+
+func (w retryWrapper) CreateBundle(ctx context.Context, bundle *common.Bundle) (resp *common.Bundle, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, err = w.ds.CreateBundle(ctx, bundle)
+		return err
+	})
+	return resp, err
+}
+
+func (w retryWrapper) DeleteBundle(ctx context.Context, trustDomainID string, mode DeleteMode) error {
+	return w.retry(ctx, func() error {
+		return w.ds.DeleteBundle(ctx, trustDomainID, mode)
+	})
+}
+
+func (w retryWrapper) FetchBundle(ctx context.Context, trustDomainID string, dataConsistency DataConsistency) (resp *common.Bundle, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, err = w.ds.FetchBundle(ctx, trustDomainID, dataConsistency)
+		return err
+	})
+	return resp, err
+}
+
+func (w retryWrapper) ListBundles(ctx context.Context, req *ListBundlesRequest) (resp *ListBundlesResponse, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, err = w.ds.ListBundles(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+func (w retryWrapper) PruneBundle(ctx context.Context, trustDomainID string, expiresBefore time.Time) (changed bool, err error) {
+	err = w.retry(ctx, func() (err error) {
+		changed, err = w.ds.PruneBundle(ctx, trustDomainID, expiresBefore)
+		return err
+	})
+	return changed, err
+}
+
+func (w retryWrapper) SetBundle(ctx context.Context, bundle *common.Bundle) (resp *common.Bundle, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, err = w.ds.SetBundle(ctx, bundle)
+		return err
+	})
+	return resp, err
+}
+
+func (w retryWrapper) UpdateBundle(ctx context.Context, bundle *common.Bundle, mask *common.BundleMask) (resp *common.Bundle, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, err = w.ds.UpdateBundle(ctx, bundle, mask)
+		return err
+	})
+	return resp, err
+}
+
+func (w retryWrapper) CountRegistrationEntries(ctx context.Context) (resp int32, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, err = w.ds.CountRegistrationEntries(ctx)
+		return err
+	})
+	return resp, err
+}
+
+func (w retryWrapper) CreateRegistrationEntry(ctx context.Context, entry *common.RegistrationEntry) (resp *common.RegistrationEntry, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, err = w.ds.CreateRegistrationEntry(ctx, entry)
+		return err
+	})
+	return resp, err
+}
+
+func (w retryWrapper) CreateOrReturnRegistrationEntry(ctx context.Context, entry *common.RegistrationEntry) (resp *common.RegistrationEntry, existing bool, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, existing, err = w.ds.CreateOrReturnRegistrationEntry(ctx, entry)
+		return err
+	})
+	return resp, existing, err
+}
+
+func (w retryWrapper) DeleteRegistrationEntry(ctx context.Context, entryID string) (resp *common.RegistrationEntry, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, err = w.ds.DeleteRegistrationEntry(ctx, entryID)
+		return err
+	})
+	return resp, err
+}
+
+func (w retryWrapper) FetchRegistrationEntry(ctx context.Context, entryID string) (resp *common.RegistrationEntry, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, err = w.ds.FetchRegistrationEntry(ctx, entryID)
+		return err
+	})
+	return resp, err
+}
+
+func (w retryWrapper) ListRegistrationEntries(ctx context.Context, req *ListRegistrationEntriesRequest) (resp *ListRegistrationEntriesResponse, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, err = w.ds.ListRegistrationEntries(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+func (w retryWrapper) PruneRegistrationEntries(ctx context.Context, expiresBefore time.Time) error {
+	return w.retry(ctx, func() error {
+		return w.ds.PruneRegistrationEntries(ctx, expiresBefore)
+	})
+}
+
+func (w retryWrapper) UpdateRegistrationEntry(ctx context.Context, entry *common.RegistrationEntry, mask *common.RegistrationEntryMask) (resp *common.RegistrationEntry, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, err = w.ds.UpdateRegistrationEntry(ctx, entry, mask)
+		return err
+	})
+	return resp, err
+}
+
+func (w retryWrapper) CountAttestedNodes(ctx context.Context) (resp int32, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, err = w.ds.CountAttestedNodes(ctx)
+		return err
+	})
+	return resp, err
+}
+
+func (w retryWrapper) CreateAttestedNode(ctx context.Context, node *common.AttestedNode) (resp *common.AttestedNode, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, err = w.ds.CreateAttestedNode(ctx, node)
+		return err
+	})
+	return resp, err
+}
+
+func (w retryWrapper) DeleteAttestedNode(ctx context.Context, spiffeID string) (resp *common.AttestedNode, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, err = w.ds.DeleteAttestedNode(ctx, spiffeID)
+		return err
+	})
+	return resp, err
+}
+
+func (w retryWrapper) FetchAttestedNode(ctx context.Context, spiffeID string) (resp *common.AttestedNode, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, err = w.ds.FetchAttestedNode(ctx, spiffeID)
+		return err
+	})
+	return resp, err
+}
+
+func (w retryWrapper) ListAttestedNodes(ctx context.Context, req *ListAttestedNodesRequest) (resp *ListAttestedNodesResponse, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, err = w.ds.ListAttestedNodes(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+func (w retryWrapper) UpdateAttestedNode(ctx context.Context, node *common.AttestedNode, mask *common.AttestedNodeMask) (resp *common.AttestedNode, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, err = w.ds.UpdateAttestedNode(ctx, node, mask)
+		return err
+	})
+	return resp, err
+}
+
+func (w retryWrapper) GetNodeSelectors(ctx context.Context, spiffeID string, dataConsistency DataConsistency) (resp []*common.Selector, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, err = w.ds.GetNodeSelectors(ctx, spiffeID, dataConsistency)
+		return err
+	})
+	return resp, err
+}
+
+func (w retryWrapper) ListNodeSelectors(ctx context.Context, req *ListNodeSelectorsRequest) (resp *ListNodeSelectorsResponse, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, err = w.ds.ListNodeSelectors(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+func (w retryWrapper) SetNodeSelectors(ctx context.Context, spiffeID string, selectors []*common.Selector) error {
+	return w.retry(ctx, func() error {
+		return w.ds.SetNodeSelectors(ctx, spiffeID, selectors)
+	})
+}
+
+func (w retryWrapper) CreateJoinToken(ctx context.Context, token *JoinToken) error {
+	return w.retry(ctx, func() error {
+		return w.ds.CreateJoinToken(ctx, token)
+	})
+}
+
+func (w retryWrapper) DeleteJoinToken(ctx context.Context, token string) error {
+	return w.retry(ctx, func() error {
+		return w.ds.DeleteJoinToken(ctx, token)
+	})
+}
+
+func (w retryWrapper) FetchJoinToken(ctx context.Context, token string) (resp *JoinToken, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, err = w.ds.FetchJoinToken(ctx, token)
+		return err
+	})
+	return resp, err
+}
+
+func (w retryWrapper) PruneJoinTokens(ctx context.Context, expiresBefore time.Time) error {
+	return w.retry(ctx, func() error {
+		return w.ds.PruneJoinTokens(ctx, expiresBefore)
+	})
+}
+
+func (w retryWrapper) CreateFederationRelationship(ctx context.Context, fr *FederationRelationship) (resp *FederationRelationship, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, err = w.ds.CreateFederationRelationship(ctx, fr)
+		return err
+	})
+	return resp, err
+}
+
+func (w retryWrapper) FetchFederationRelationship(ctx context.Context, td spiffeid.TrustDomain) (resp *FederationRelationship, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, err = w.ds.FetchFederationRelationship(ctx, td)
+		return err
+	})
+	return resp, err
+}
+
+func (w retryWrapper) ListFederationRelationships(ctx context.Context, req *ListFederationRelationshipsRequest) (resp *ListFederationRelationshipsResponse, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, err = w.ds.ListFederationRelationships(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+func (w retryWrapper) DeleteFederationRelationship(ctx context.Context, td spiffeid.TrustDomain) error {
+	return w.retry(ctx, func() error {
+		return w.ds.DeleteFederationRelationship(ctx, td)
+	})
+}
+
+func (w retryWrapper) UpdateFederationRelationship(ctx context.Context, fr *FederationRelationship, mask *types.FederationRelationshipMask) (resp *FederationRelationship, err error) {
+	err = w.retry(ctx, func() (err error) {
+		resp, err = w.ds.UpdateFederationRelationship(ctx, fr, mask)
+		return err
+	})
+	return resp, err
+}
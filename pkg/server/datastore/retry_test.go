@@ -0,0 +1,164 @@
+package datastore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiffe/spire/proto/spire/common"
+	"github.com/spiffe/spire/test/clock"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errTransient = status.Error(codes.Unavailable, "datastore-sql: driver: bad connection")
+
+func TestWithRetryDisabled(t *testing.T) {
+	ds := &failingDataStore{}
+	require.Equal(t, ds, WithRetry(ds, RetryConfig{MaxAttempts: 1}))
+	require.Equal(t, ds, WithRetry(ds, RetryConfig{}))
+}
+
+func TestWithRetryEventuallySucceeds(t *testing.T) {
+	ds := &failingDataStore{failures: 3, err: errTransient}
+	clk := clock.NewMock(t)
+	log, hook := test.NewNullLogger()
+	retryDS := WithRetry(ds, RetryConfig{
+		Log:            log,
+		MaxAttempts:    5,
+		InitialBackoff: time.Second,
+		MaxBackoff:     3 * time.Second,
+		Clock:          clk,
+	})
+
+	type result struct {
+		bundle *common.Bundle
+		err    error
+	}
+	resultCh := make(chan result, 1)
+	go func() {
+		bundle, err := retryDS.FetchBundle(context.Background(), "spiffe://example.org", RequireCurrent)
+		resultCh <- result{bundle: bundle, err: err}
+	}()
+
+	// The backoff doubles on every retry, up to the maximum
+	for _, expected := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		select {
+		case backoff := <-clk.AfterCh():
+			require.Equal(t, expected, backoff)
+			clk.Add(backoff)
+		case <-time.After(time.Minute):
+			require.FailNow(t, "timed out waiting for the retry")
+		}
+	}
+
+	res := <-resultCh
+	require.NoError(t, res.err)
+	require.Equal(t, "spiffe://example.org", res.bundle.TrustDomainId)
+	require.Equal(t, 4, ds.calls)
+
+	spiretest.AssertLogs(t, hook.AllEntries(), []spiretest.LogEntry{
+		{
+			Level:   logrus.WarnLevel,
+			Message: "Transient datastore error; retrying",
+			Data: logrus.Fields{
+				logrus.ErrorKey:  errTransient.Error(),
+				"attempt":        "1",
+				"retry_interval": "1s",
+			},
+		},
+		{
+			Level:   logrus.WarnLevel,
+			Message: "Transient datastore error; retrying",
+			Data: logrus.Fields{
+				logrus.ErrorKey:  errTransient.Error(),
+				"attempt":        "2",
+				"retry_interval": "2s",
+			},
+		},
+		{
+			Level:   logrus.WarnLevel,
+			Message: "Transient datastore error; retrying",
+			Data: logrus.Fields{
+				logrus.ErrorKey:  errTransient.Error(),
+				"attempt":        "3",
+				"retry_interval": "3s",
+			},
+		},
+	})
+}
+
+func TestWithRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	ds := &failingDataStore{failures: 5, err: errTransient}
+	retryDS := WithRetry(ds, RetryConfig{
+		Log:            logrus.New(),
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+	})
+
+	err := retryDS.CreateJoinToken(context.Background(), &JoinToken{Token: "token"})
+	spiretest.RequireGRPCStatus(t, err, codes.Unavailable, "datastore-sql: driver: bad connection")
+	require.Equal(t, 3, ds.calls)
+}
+
+func TestWithRetryDoesNotRetryNonTransientErrors(t *testing.T) {
+	ds := &failingDataStore{failures: 1, err: status.Error(codes.AlreadyExists, "datastore-sql: UNIQUE constraint failed")}
+	retryDS := WithRetry(ds, RetryConfig{
+		Log:            logrus.New(),
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+	})
+
+	err := retryDS.CreateJoinToken(context.Background(), &JoinToken{Token: "token"})
+	spiretest.RequireGRPCStatus(t, err, codes.AlreadyExists, "datastore-sql: UNIQUE constraint failed")
+	require.Equal(t, 1, ds.calls)
+}
+
+func TestWithRetryStopsWhenContextIsDone(t *testing.T) {
+	ds := &failingDataStore{failures: 5, err: errTransient}
+	retryDS := WithRetry(ds, RetryConfig{
+		Log:            logrus.New(),
+		MaxAttempts:    3,
+		InitialBackoff: time.Hour,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := retryDS.CreateJoinToken(ctx, &JoinToken{Token: "token"})
+	spiretest.RequireGRPCStatus(t, err, codes.Unavailable, "datastore-sql: driver: bad connection")
+	require.Equal(t, 1, ds.calls)
+}
+
+// failingDataStore fails the first calls with the configured error and then
+// succeeds.
+type failingDataStore struct {
+	DataStore
+
+	failures int
+	err      error
+	calls    int
+}
+
+func (ds *failingDataStore) FetchBundle(ctx context.Context, trustDomainID string, dataConsistency DataConsistency) (*common.Bundle, error) {
+	if err := ds.fail(); err != nil {
+		return nil, err
+	}
+	return &common.Bundle{TrustDomainId: trustDomainID}, nil
+}
+
+func (ds *failingDataStore) CreateJoinToken(ctx context.Context, token *JoinToken) error {
+	return ds.fail()
+}
+
+func (ds *failingDataStore) fail() error {
+	ds.calls++
+	if ds.calls <= ds.failures {
+		return ds.err
+	}
+	return nil
+}
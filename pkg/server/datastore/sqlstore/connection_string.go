@@ -0,0 +1,136 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"os"
+	"strings"
+)
+
+// connectionStringSource is where a connection string is read from. Exactly
+// one of value, file or env is set. Files and environment variables are read
+// again every time a new connection is established, so that rotated
+// credentials are picked up without reconfiguring the plugin.
+type connectionStringSource struct {
+	// name is the name of the configurable used in errors
+	name string
+
+	value string
+	file  string
+	env   string
+}
+
+func (cfg *configuration) connectionStringSource(isReadOnly bool) connectionStringSource {
+	if isReadOnly {
+		return connectionStringSource{
+			name:  "ro_connection_string",
+			value: cfg.RoConnectionString,
+			file:  cfg.RoConnectionStringFile,
+			env:   cfg.RoConnectionStringEnv,
+		}
+	}
+	return connectionStringSource{
+		name:  "connection_string",
+		value: cfg.ConnectionString,
+		file:  cfg.ConnectionStringFile,
+		env:   cfg.ConnectionStringEnv,
+	}
+}
+
+func (s connectionStringSource) isSet() bool {
+	return s.value != "" || s.file != "" || s.env != ""
+}
+
+func (s connectionStringSource) validate() error {
+	set := 0
+	for _, v := range []string{s.value, s.file, s.env} {
+		if v != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return sqlError.New("only one of %s, %s_file or %s_env can be set", s.name, s.name, s.name)
+	}
+	return nil
+}
+
+// resolve reads the connection string from its source.
+func (s connectionStringSource) resolve() (string, error) {
+	switch {
+	case s.file != "":
+		data, err := os.ReadFile(s.file)
+		if err != nil {
+			return "", sqlError.New("unable to read %s_file: %v", s.name, err)
+		}
+		connectionString := strings.TrimSpace(string(data))
+		if connectionString == "" {
+			return "", sqlError.New("%s_file %q is empty", s.name, s.file)
+		}
+		return connectionString, nil
+	case s.env != "":
+		connectionString, ok := os.LookupEnv(s.env)
+		if !ok || connectionString == "" {
+			return "", sqlError.New("%s_env %q is not set", s.name, s.env)
+		}
+		return connectionString, nil
+	default:
+		return s.value, nil
+	}
+}
+
+// resolveConnectionStrings replaces the connection strings in the
+// configuration with the ones read from their sources, so they can be
+// validated before any connection is attempted.
+func (cfg *configuration) resolveConnectionStrings() error {
+	for _, isReadOnly := range []bool{false, true} {
+		source := cfg.connectionStringSource(isReadOnly)
+		if err := source.validate(); err != nil {
+			return err
+		}
+		if !source.isSet() {
+			continue
+		}
+
+		connectionString, err := source.resolve()
+		if err != nil {
+			return err
+		}
+		if isReadOnly {
+			cfg.RoConnectionString = connectionString
+		} else {
+			cfg.ConnectionString = connectionString
+		}
+	}
+	return nil
+}
+
+// openSQLDB returns a database handle that gets the connection string from
+// dsn every time it establishes a new connection.
+func openSQLDB(drv driver.Driver, dsn func() (string, error)) *sql.DB {
+	return sql.OpenDB(dsnConnector{driver: drv, dsn: dsn})
+}
+
+type dsnConnector struct {
+	driver driver.Driver
+	dsn    func() (string, error)
+}
+
+func (c dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	dsn, err := c.dsn()
+	if err != nil {
+		return nil, err
+	}
+	if driverCtx, ok := c.driver.(driver.DriverContext); ok {
+		connector, err := driverCtx.OpenConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return connector.Connect(ctx)
+	}
+	return c.driver.Open(dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
@@ -0,0 +1,62 @@
+package sqlstore
+
+import (
+	"database/sql/driver"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionStringIsReadOnEveryConnection(t *testing.T) {
+	connectionStringFile := filepath.Join(t.TempDir(), "connection-string")
+	writeConnectionString := func(connectionString string) {
+		require.NoError(t, os.WriteFile(connectionStringFile, []byte(connectionString), 0600))
+	}
+
+	cfg := &configuration{ConnectionStringFile: connectionStringFile}
+	writeConnectionString("user:password1@/spire")
+	require.NoError(t, cfg.resolveConnectionStrings())
+	require.Equal(t, "user:password1@/spire", cfg.ConnectionString)
+
+	drv := new(fakeDriver)
+	db := openSQLDB(drv, cfg.connectionStringSource(false).resolve)
+	defer db.Close()
+
+	// Connections established after the credential rotates use the new one
+	require.NoError(t, db.Ping())
+	writeConnectionString("user:password2@/spire")
+	db.SetMaxIdleConns(0)
+	require.NoError(t, db.Ping())
+	require.Equal(t, []string{"user:password1@/spire", "user:password2@/spire"}, drv.dsns)
+
+	// Connections fail while the connection string cannot be read
+	require.NoError(t, os.Remove(connectionStringFile))
+	spiretest.RequireErrorPrefix(t, db.Ping(), "datastore-sql: unable to read connection_string_file: ")
+}
+
+type fakeDriver struct {
+	dsns []string
+}
+
+func (d *fakeDriver) Open(dsn string) (driver.Conn, error) {
+	d.dsns = append(d.dsns, dsn)
+	return fakeConn{}, nil
+}
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("unimplemented")
+}
+
+func (fakeConn) Close() error {
+	return nil
+}
+
+func (fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("unimplemented")
+}
@@ -1,8 +1,34 @@
 package sqlstore
 
-import "github.com/jinzhu/gorm"
+import (
+	"database/sql/driver"
+	"errors"
+	"io"
+	"syscall"
+
+	"github.com/jinzhu/gorm"
+)
 
 type dialect interface {
 	connect(cfg *configuration, isReadOnly bool) (db *gorm.DB, version string, supportsCTE bool, err error)
 	isConstraintViolation(err error) bool
+
+	// isTransientError returns true if the error guarantees that the
+	// transaction was rolled back (e.g. a deadlock), so that it can be
+	// retried.
+	isTransientError(err error) bool
+
+	// isConnectionError returns true if the error was caused by a broken
+	// connection to the database, e.g. during a failover. Whether the
+	// transaction was rolled back depends on when the connection broke.
+	isConnectionError(err error) bool
+}
+
+// isBrokenConnection returns true if the error was caused by a broken
+// connection to the database, as reported by database/sql or the network.
+func isBrokenConnection(err error) bool {
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
 }
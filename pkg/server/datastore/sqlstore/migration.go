@@ -4,9 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"sort"
 	"strconv"
 
 	"github.com/blang/semver/v4"
+	"github.com/hashicorp/hcl"
 	"github.com/jinzhu/gorm"
 	"github.com/sirupsen/logrus"
 	"github.com/spiffe/spire/pkg/common/telemetry"
@@ -129,8 +131,232 @@ const (
 var (
 	// the current code version
 	codeVersion = semver.MustParse(version.Version())
+
+	// schemaChanges describes what changed in each schema version handled by
+	// migrateVersion. It is used to describe pending migrations and should be
+	// updated alongside the migrations.
+	schemaChanges = map[int]string{
+		18: "Added hint column to entries and can_reattest column to attested nodes",
+	}
+
+	// schemaModels are the models for the tables managed by the datastore.
+	schemaModels = []interface{}{
+		&Bundle{},
+		&AttestedNode{},
+		&NodeSelector{},
+		&RegisteredEntry{},
+		&JoinToken{},
+		&Selector{},
+		&Migration{},
+		&DNSName{},
+		&FederatedTrustDomain{},
+	}
 )
 
+// MigrationPlan describes the schema migrations pending for a database.
+type MigrationPlan struct {
+	// Initialized is false when the database has not been initialized yet.
+	// Such databases are created with the latest schema version and have no
+	// pending migrations.
+	Initialized bool
+
+	// SchemaVersion is the schema version of the database.
+	SchemaVersion int
+
+	// CodeVersion is the version of the SPIRE Server that last migrated the
+	// database, if known.
+	CodeVersion string
+
+	// LatestSchemaVersion is the schema version supported by this code.
+	LatestSchemaVersion int
+
+	// Steps are the pending migration steps, in the order they run.
+	Steps []MigrationStep
+}
+
+// MigrationStep is a single schema migration.
+type MigrationStep struct {
+	// SchemaVersion is the schema version the step migrates to.
+	SchemaVersion int
+
+	// Description describes the schema change.
+	Description string
+
+	// Changes lists the tables and columns added by the step. It is only
+	// populated by DryRunMigrations.
+	Changes []string
+}
+
+// PlanMigrations returns the migrations that SPIRE Server would run against
+// the database described by the given SQL DataStore configuration. The
+// database is not modified.
+func PlanMigrations(log logrus.FieldLogger, hclConfiguration string) (*MigrationPlan, error) {
+	var plan *MigrationPlan
+	err := withMigrationDB(log, hclConfiguration, func(db *gorm.DB, _ *configuration) (err error) {
+		plan, err = planMigrations(db)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// DryRunMigrations runs the pending migrations against the database described
+// by the given SQL DataStore configuration inside of a single transaction
+// that is rolled back, reporting the changes made by each step. Dry runs are
+// not supported for MySQL since it implicitly commits schema changes.
+func DryRunMigrations(log logrus.FieldLogger, hclConfiguration string) (*MigrationPlan, error) {
+	var plan *MigrationPlan
+	err := withMigrationDB(log, hclConfiguration, func(db *gorm.DB, cfg *configuration) (err error) {
+		if cfg.DatabaseType == MySQL {
+			return sqlError.New("dry runs are not supported for MySQL since schema changes cannot be rolled back")
+		}
+
+		plan, err = planMigrations(db)
+		if err != nil || len(plan.Steps) == 0 {
+			return err
+		}
+
+		tx := db.Begin()
+		if err := tx.Error; err != nil {
+			return sqlError.Wrap(err)
+		}
+		for i, step := range plan.Steps {
+			before, err := schemaColumns(tx)
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+			if _, err := migrateVersion(tx, step.SchemaVersion-1, log); err != nil {
+				tx.Rollback()
+				return err
+			}
+			after, err := schemaColumns(tx)
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+			plan.Steps[i].Changes = diffSchemaColumns(before, after)
+		}
+		if err := tx.Rollback().Error; err != nil {
+			return sqlError.Wrap(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func withMigrationDB(log logrus.FieldLogger, hclConfiguration string, fn func(db *gorm.DB, cfg *configuration) error) error {
+	cfg := &configuration{}
+	if err := hcl.Decode(cfg, hclConfiguration); err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	dialect, err := newDialect(cfg.DatabaseType, log)
+	if err != nil {
+		return err
+	}
+	db, _, _, err := dialect.connect(cfg, false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	db.SetLogger(gormLogger{
+		log: log.WithField(telemetry.SubsystemName, "gorm"),
+	})
+	return fn(db, cfg)
+}
+
+func planMigrations(db *gorm.DB) (*MigrationPlan, error) {
+	plan := &MigrationPlan{
+		LatestSchemaVersion: latestSchemaVersion,
+	}
+
+	plan.Initialized = db.HasTable(&Migration{})
+	if err := db.Error; err != nil {
+		return nil, sqlError.Wrap(err)
+	}
+	if !plan.Initialized {
+		return plan, nil
+	}
+
+	migration := new(Migration)
+	if err := db.First(migration).Error; err != nil && !gorm.IsRecordNotFoundError(err) {
+		return nil, sqlError.Wrap(err)
+	}
+	plan.SchemaVersion = migration.Version
+	plan.CodeVersion = migration.CodeVersion
+
+	if plan.SchemaVersion < lastMinorReleaseSchemaVersion {
+		return nil, errMigrationRequiresPreviousRelease(plan.SchemaVersion)
+	}
+	for schemaVersion := plan.SchemaVersion + 1; schemaVersion <= latestSchemaVersion; schemaVersion++ {
+		plan.Steps = append(plan.Steps, MigrationStep{
+			SchemaVersion: schemaVersion,
+			Description:   schemaChanges[schemaVersion],
+		})
+	}
+	return plan, nil
+}
+
+// schemaColumns returns the columns of each of the datastore tables that
+// exist, keyed by table name.
+func schemaColumns(tx *gorm.DB) (map[string][]string, error) {
+	columns := make(map[string][]string)
+	for _, model := range schemaModels {
+		table := tx.NewScope(model).TableName()
+		if !tx.HasTable(table) {
+			continue
+		}
+		rows, err := tx.CommonDB().Query(fmt.Sprintf("SELECT * FROM %s WHERE 1 = 0", tx.Dialect().Quote(table)))
+		if err != nil {
+			return nil, sqlError.Wrap(err)
+		}
+		tableColumns, err := rows.Columns()
+		rows.Close()
+		if err != nil {
+			return nil, sqlError.Wrap(err)
+		}
+		columns[table] = tableColumns
+	}
+	return columns, nil
+}
+
+func diffSchemaColumns(before, after map[string][]string) []string {
+	var changes []string
+	for table, columns := range after {
+		existing, ok := before[table]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("created table %s", table))
+			continue
+		}
+		for _, column := range columns {
+			if !containsString(existing, column) {
+				changes = append(changes, fmt.Sprintf("added column %s.%s", table, column))
+			}
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
 func migrateDB(db *gorm.DB, dbType string, disableMigration bool, log logrus.FieldLogger) (err error) {
 	// The version comparison logic in this package supports only 0.x and 1.x versioning semantics.
 	// It will need to be updated prior to releasing 2.x. Ensure that we're still building a pre-2.0
@@ -270,19 +496,7 @@ func initDB(db *gorm.DB, dbType string, log logrus.FieldLogger) (err error) {
 		return sqlError.Wrap(err)
 	}
 
-	tables := []interface{}{
-		&Bundle{},
-		&AttestedNode{},
-		&NodeSelector{},
-		&RegisteredEntry{},
-		&JoinToken{},
-		&Selector{},
-		&Migration{},
-		&DNSName{},
-		&FederatedTrustDomain{},
-	}
-
-	if err := tableOptionsForDialect(tx, dbType).AutoMigrate(tables...).Error; err != nil {
+	if err := tableOptionsForDialect(tx, dbType).AutoMigrate(schemaModels...).Error; err != nil {
 		tx.Rollback()
 		return sqlError.Wrap(err)
 	}
@@ -328,7 +542,7 @@ func migrateVersion(tx *gorm.DB, currVersion int, log logrus.FieldLogger) (versi
 	}
 
 	if currVersion < lastMinorReleaseSchemaVersion {
-		return 0, sqlError.New("migrating from schema version %d requires a previous SPIRE release; please follow the upgrade strategy at doc/upgrading.md", currVersion)
+		return 0, errMigrationRequiresPreviousRelease(currVersion)
 	}
 
 	// Place all migrations handled by the current minor release here. This
@@ -369,3 +583,7 @@ func addFederatedRegistrationEntriesRegisteredEntryIDIndex(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+func errMigrationRequiresPreviousRelease(schemaVersion int) error {
+	return sqlError.New("migrating from schema version %d requires a previous SPIRE release; please follow the upgrade strategy at doc/upgrading.md", schemaVersion)
+}
@@ -2,8 +2,11 @@ package sqlstore
 
 import (
 	"database/sql"
+	"fmt"
+	"path/filepath"
 	"testing"
 
+	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -63,6 +66,65 @@ func dumpDB(t *testing.T, path string, statements string) {
 	require.NoError(t, err)
 }
 
+func TestDryRunMigrations(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "v17.sqlite3")
+	dumpDB(t, dbPath, migrationDumps[17])
+	config := fmt.Sprintf(`
+		database_type = "sqlite3"
+		connection_string = %q
+	`, dbPath)
+	log, _ := test.NewNullLogger()
+
+	expectedPlan := &MigrationPlan{
+		Initialized:         true,
+		SchemaVersion:       17,
+		CodeVersion:         "1.1.2-dev-11c02e9",
+		LatestSchemaVersion: latestSchemaVersion,
+		Steps: []MigrationStep{
+			{
+				SchemaVersion: 18,
+				Description:   "Added hint column to entries and can_reattest column to attested nodes",
+			},
+		},
+	}
+
+	plan, err := PlanMigrations(log, config)
+	require.NoError(t, err)
+	require.Equal(t, expectedPlan, plan)
+
+	expectedPlan.Steps[0].Changes = []string{
+		"added column attested_node_entries.can_reattest",
+		"added column registered_entries.hint",
+	}
+	plan, err = DryRunMigrations(log, config)
+	require.NoError(t, err)
+	require.Equal(t, expectedPlan, plan)
+
+	// The dry run must leave the database untouched
+	db, err := sql.Open("sqlite3", dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var schemaVersion int
+	require.NoError(t, db.QueryRow("SELECT version FROM migrations").Scan(&schemaVersion))
+	require.Equal(t, 17, schemaVersion)
+
+	_, err = db.Exec("SELECT hint FROM registered_entries")
+	require.Error(t, err)
+	_, err = db.Exec("SELECT can_reattest FROM attested_node_entries")
+	require.Error(t, err)
+}
+
+func TestPlanMigrationsUninitializedDatabase(t *testing.T) {
+	log, _ := test.NewNullLogger()
+	plan, err := PlanMigrations(log, fmt.Sprintf(`
+		database_type = "sqlite3"
+		connection_string = %q
+	`, filepath.Join(t.TempDir(), "new.sqlite3")))
+	require.NoError(t, err)
+	require.Equal(t, &MigrationPlan{LatestSchemaVersion: latestSchemaVersion}, plan)
+}
+
 func TestGetDBCodeVersion(t *testing.T) {
 	tests := []struct {
 		desc            string
@@ -24,13 +24,17 @@ const (
 )
 
 func (my mysqlDB) connect(cfg *configuration, isReadOnly bool) (db *gorm.DB, version string, supportsCTE bool, err error) {
-	connString, err := configureConnection(cfg, isReadOnly)
-	if err != nil {
-		return nil, "", false, err
-	}
-
-	db, err = gorm.Open("mysql", connString)
+	source := cfg.connectionStringSource(isReadOnly)
+	sqlDB := openSQLDB(mysql.MySQLDriver{}, func() (string, error) {
+		connectionString, err := source.resolve()
+		if err != nil {
+			return "", err
+		}
+		return configureConnection(cfg, connectionString)
+	})
+	db, err = gorm.Open("mysql", sqlDB)
 	if err != nil {
+		sqlDB.Close()
 		return nil, "", false, err
 	}
 
@@ -76,10 +80,27 @@ func (my mysqlDB) isConstraintViolation(err error) bool {
 	return ok && e.Number == 1062 // ER_DUP_ENTRY
 }
 
+func (my mysqlDB) isTransientError(err error) bool {
+	var e *mysql.MySQLError
+	if !errors.As(err, &e) {
+		return false
+	}
+	switch e.Number {
+	case 1205, // ER_LOCK_WAIT_TIMEOUT
+		1213: // ER_LOCK_DEADLOCK
+		return true
+	default:
+		return false
+	}
+}
+
+func (my mysqlDB) isConnectionError(err error) bool {
+	return errors.Is(err, mysql.ErrInvalidConn) || isBrokenConnection(err)
+}
+
 // configureConnection modifies the connection string to support features that
 // normally require code changes, like custom Root CAs or client certificates
-func configureConnection(cfg *configuration, isReadOnly bool) (string, error) {
-	connectionString := getConnectionString(cfg, isReadOnly)
+func configureConnection(cfg *configuration, connectionString string) (string, error) {
 	if !hasTLSConfig(cfg) {
 		// connection string doesn't have to be modified
 		return connectionString, nil
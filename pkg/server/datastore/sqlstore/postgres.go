@@ -13,8 +13,11 @@ import (
 type postgresDB struct{}
 
 func (p postgresDB) connect(cfg *configuration, isReadOnly bool) (db *gorm.DB, version string, supportsCTE bool, err error) {
-	db, err = gorm.Open("postgres", getConnectionString(cfg, isReadOnly))
+	source := cfg.connectionStringSource(isReadOnly)
+	sqlDB := openSQLDB(pq.Driver{}, source.resolve)
+	db, err = gorm.Open("postgres", sqlDB)
 	if err != nil {
+		sqlDB.Close()
 		return nil, "", false, sqlError.Wrap(err)
 	}
 
@@ -34,3 +37,25 @@ func (p postgresDB) isConstraintViolation(err error) bool {
 	// "23xxx" is the constraint violation class for PostgreSQL
 	return ok && e.Code.Class() == "23"
 }
+
+func (p postgresDB) isTransientError(err error) bool {
+	var e *pq.Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	switch e.Code {
+	case "40001", // serialization_failure
+		"40P01": // deadlock_detected
+		return true
+	default:
+		return false
+	}
+}
+
+func (p postgresDB) isConnectionError(err error) bool {
+	var e *pq.Error
+	if errors.As(err, &e) {
+		return e.Code.Class() == "08" // connection exception
+	}
+	return isBrokenConnection(err)
+}
@@ -49,6 +49,19 @@ func (s sqliteDB) isConstraintViolation(err error) bool {
 	return ok && e.Code == sqlite3.ErrConstraint
 }
 
+func (s sqliteDB) isTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var e sqlite3.Error
+	ok := errors.As(err, &e)
+	return ok && (e.Code == sqlite3.ErrBusy || e.Code == sqlite3.ErrLocked)
+}
+
+func (s sqliteDB) isConnectionError(err error) bool {
+	return false
+}
+
 func openSQLite3(connString string) (*gorm.DB, error) {
 	embellished, err := embellishSQLite3ConnString(connString)
 	if err != nil {
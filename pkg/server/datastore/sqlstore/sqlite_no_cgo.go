@@ -20,3 +20,11 @@ func (s sqliteDB) connect(cfg *configuration, isReadOnly bool) (db *gorm.DB, ver
 func (s sqliteDB) isConstraintViolation(err error) bool {
 	return false
 }
+
+func (s sqliteDB) isTransientError(err error) bool {
+	return false
+}
+
+func (s sqliteDB) isConnectionError(err error) bool {
+	return false
+}
@@ -48,16 +48,21 @@ const (
 // Configuration for the sql datastore implementation.
 // Pointer values are used to distinguish between "unset" and "zero" values.
 type configuration struct {
-	DatabaseType       string  `hcl:"database_type" json:"database_type"`
-	ConnectionString   string  `hcl:"connection_string" json:"connection_string"`
-	RoConnectionString string  `hcl:"ro_connection_string" json:"ro_connection_string"`
-	RootCAPath         string  `hcl:"root_ca_path" json:"root_ca_path"`
-	ClientCertPath     string  `hcl:"client_cert_path" json:"client_cert_path"`
-	ClientKeyPath      string  `hcl:"client_key_path" json:"client_key_path"`
-	ConnMaxLifetime    *string `hcl:"conn_max_lifetime" json:"conn_max_lifetime"`
-	MaxOpenConns       *int    `hcl:"max_open_conns" json:"max_open_conns"`
-	MaxIdleConns       *int    `hcl:"max_idle_conns" json:"max_idle_conns"`
-	DisableMigration   bool    `hcl:"disable_migration" json:"disable_migration"`
+	DatabaseType           string  `hcl:"database_type" json:"database_type"`
+	ConnectionString       string  `hcl:"connection_string" json:"connection_string"`
+	ConnectionStringFile   string  `hcl:"connection_string_file" json:"connection_string_file"`
+	ConnectionStringEnv    string  `hcl:"connection_string_env" json:"connection_string_env"`
+	RoConnectionString     string  `hcl:"ro_connection_string" json:"ro_connection_string"`
+	RoConnectionStringFile string  `hcl:"ro_connection_string_file" json:"ro_connection_string_file"`
+	RoConnectionStringEnv  string  `hcl:"ro_connection_string_env" json:"ro_connection_string_env"`
+	RootCAPath             string  `hcl:"root_ca_path" json:"root_ca_path"`
+	ClientCertPath         string  `hcl:"client_cert_path" json:"client_cert_path"`
+	ClientKeyPath          string  `hcl:"client_key_path" json:"client_key_path"`
+	ConnMaxLifetime        *string `hcl:"conn_max_lifetime" json:"conn_max_lifetime"`
+	MaxOpenConns           *int    `hcl:"max_open_conns" json:"max_open_conns"`
+	MaxIdleConns           *int    `hcl:"max_idle_conns" json:"max_idle_conns"`
+	ReadOnlyMaxOpenConns   *int    `hcl:"read_only_max_open_conns" json:"read_only_max_open_conns"`
+	DisableMigration       bool    `hcl:"disable_migration" json:"disable_migration"`
 
 	// Undocumented flags
 	LogSQL bool `hcl:"log_sql" json:"log_sql"`
@@ -154,8 +159,8 @@ func (ds *Plugin) DeleteBundle(ctx context.Context, trustDomainID string, mode d
 }
 
 // FetchBundle returns the bundle matching the specified Trust Domain.
-func (ds *Plugin) FetchBundle(ctx context.Context, trustDomainID string) (resp *common.Bundle, err error) {
-	if err = ds.withReadTx(ctx, func(tx *gorm.DB) (err error) {
+func (ds *Plugin) FetchBundle(ctx context.Context, trustDomainID string, dataConsistency datastore.DataConsistency) (resp *common.Bundle, err error) {
+	if err = ds.withConsistentReadTx(ctx, dataConsistency, func(tx *gorm.DB) (err error) {
 		resp, err = fetchBundle(tx, trustDomainID)
 		return err
 	}); err != nil {
@@ -513,6 +518,10 @@ func (ds *Plugin) Configure(hclConfiguration string) error {
 		return err
 	}
 
+	if err := config.resolveConnectionStrings(); err != nil {
+		return err
+	}
+
 	if err := config.Validate(); err != nil {
 		return err
 	}
@@ -627,11 +636,29 @@ func (ds *Plugin) withReadTx(ctx context.Context, op func(tx *gorm.DB) error) er
 	return ds.withTx(ctx, op, true)
 }
 
+// withConsistentReadTx wraps the operation in a transaction appropriate for
+// operations that only read rows. The transaction runs against the read-only
+// database when one is configured and the caller tolerates stale data.
+func (ds *Plugin) withConsistentReadTx(ctx context.Context, dataConsistency datastore.DataConsistency, op func(tx *gorm.DB) error) error {
+	ds.mu.Lock()
+	db := ds.db
+	if dataConsistency == datastore.TolerateStale && ds.roDb != nil {
+		db = ds.roDb
+	}
+	ds.mu.Unlock()
+
+	return ds.withTxOn(ctx, db, op, true)
+}
+
 func (ds *Plugin) withTx(ctx context.Context, op func(tx *gorm.DB) error, readOnly bool) error {
 	ds.mu.Lock()
 	db := ds.db
 	ds.mu.Unlock()
 
+	return ds.withTxOn(ctx, db, op, readOnly)
+}
+
+func (ds *Plugin) withTxOn(ctx context.Context, db *sqlDB, op func(tx *gorm.DB) error, readOnly bool) error {
 	if db.databaseType == SQLite && !readOnly {
 		// sqlite3 can only have one writer at a time. since we're in WAL mode,
 		// there can be concurrent reads and writes, so no lock is necessary
@@ -642,7 +669,7 @@ func (ds *Plugin) withTx(ctx context.Context, op func(tx *gorm.DB) error, readOn
 
 	tx := db.BeginTx(ctx, nil)
 	if err := tx.Error; err != nil {
-		return sqlError.Wrap(err)
+		return transientToGRPCStatus(db.dialect, sqlError.Wrap(err), true)
 	}
 
 	if err := op(tx); err != nil {
@@ -656,7 +683,26 @@ func (ds *Plugin) withTx(ctx context.Context, op func(tx *gorm.DB) error, readOn
 		// writes won't be committed.
 		return sqlError.Wrap(tx.Rollback().Error)
 	}
-	return sqlError.Wrap(tx.Commit().Error)
+	// The commit may have been applied if the connection broke while
+	// committing, so only errors that guarantee a rollback can be retried.
+	return transientToGRPCStatus(db.dialect, sqlError.Wrap(tx.Commit().Error), false)
+}
+
+// transientToGRPCStatus converts errors that guarantee that the transaction
+// was not committed to a gRPC error with the Unavailable code, so they can be
+// retried by callers. Those are transient errors (e.g. a deadlock) and, if
+// beforeCommit is true, connection errors, since a transaction is rolled back
+// when its connection breaks before it is committed. Other errors are
+// returned unmodified.
+func transientToGRPCStatus(d dialect, err error, beforeCommit bool) error {
+	if err != nil && isRetryable(d, errs.Unwrap(err), beforeCommit) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return err
+}
+
+func isRetryable(d dialect, err error, beforeCommit bool) bool {
+	return d.isTransientError(err) || (beforeCommit && d.isConnectionError(err))
 }
 
 // gormToGRPCStatus takes an error, and converts it to a GRPC error.  If the
@@ -675,6 +721,9 @@ func (ds *Plugin) gormToGRPCStatus(err error) error {
 		code = codes.NotFound
 	case ds.db.dialect.isConstraintViolation(unwrapped):
 		code = codes.AlreadyExists
+	case isRetryable(ds.db.dialect, unwrapped, true):
+		// The operation failed before the transaction was committed
+		code = codes.Unavailable
 	default:
 	}
 
@@ -682,18 +731,10 @@ func (ds *Plugin) gormToGRPCStatus(err error) error {
 }
 
 func (ds *Plugin) openDB(cfg *configuration, isReadOnly bool) (*gorm.DB, string, bool, dialect, error) {
-	var dialect dialect
-
 	ds.log.WithField(telemetry.DatabaseType, cfg.DatabaseType).Info("Opening SQL database")
-	switch cfg.DatabaseType {
-	case SQLite:
-		dialect = sqliteDB{log: ds.log}
-	case PostgreSQL:
-		dialect = postgresDB{}
-	case MySQL:
-		dialect = mysqlDB{}
-	default:
-		return nil, "", false, nil, sqlError.New("unsupported database_type: %v", cfg.DatabaseType)
+	dialect, err := newDialect(cfg.DatabaseType, ds.log)
+	if err != nil {
+		return nil, "", false, nil, err
 	}
 
 	db, version, supportsCTE, err := dialect.connect(cfg, isReadOnly)
@@ -704,7 +745,10 @@ func (ds *Plugin) openDB(cfg *configuration, isReadOnly bool) (*gorm.DB, string,
 	db.SetLogger(gormLogger{
 		log: ds.log.WithField(telemetry.SubsystemName, "gorm"),
 	})
-	if cfg.MaxOpenConns != nil {
+	switch {
+	case isReadOnly && cfg.ReadOnlyMaxOpenConns != nil:
+		db.DB().SetMaxOpenConns(*cfg.ReadOnlyMaxOpenConns)
+	case cfg.MaxOpenConns != nil:
 		db.DB().SetMaxOpenConns(*cfg.MaxOpenConns)
 	}
 	if cfg.MaxIdleConns != nil {
@@ -728,6 +772,19 @@ func (ds *Plugin) openDB(cfg *configuration, isReadOnly bool) (*gorm.DB, string,
 	return db, version, supportsCTE, dialect, nil
 }
 
+func newDialect(databaseType string, log logrus.FieldLogger) (dialect, error) {
+	switch databaseType {
+	case SQLite:
+		return sqliteDB{log: log}, nil
+	case PostgreSQL:
+		return postgresDB{}, nil
+	case MySQL:
+		return mysqlDB{}, nil
+	default:
+		return nil, sqlError.New("unsupported database_type: %v", databaseType)
+	}
+}
+
 type gormLogger struct {
 	log logrus.FieldLogger
 }
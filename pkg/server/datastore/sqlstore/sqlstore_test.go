@@ -4,19 +4,26 @@ import (
 	"context"
 	"crypto/x509"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
@@ -184,6 +191,72 @@ func (s *PluginSuite) newPlugin() *Plugin {
 	return ds
 }
 
+// openBrokenCommitDB opens the sqlite3 database of the plugin through a
+// driver whose connections break right after applying a commit.
+func (s *PluginSuite) openBrokenCommitDB() *gorm.DB {
+	registerBrokenCommitDriver.Do(func() {
+		db, err := sql.Open("sqlite3", "")
+		s.Require().NoError(err)
+		sql.Register("sqlite3-broken-commit", brokenCommitDriver{Driver: db.Driver()})
+		s.Require().NoError(db.Close())
+	})
+
+	connString, err := embellishSQLite3ConnString(s.ds.db.connectionString)
+	s.Require().NoError(err)
+	db, err := sql.Open("sqlite3-broken-commit", connString)
+	s.Require().NoError(err)
+	gormDB, err := gorm.Open("sqlite3", db)
+	s.Require().NoError(err)
+	return gormDB
+}
+
+var registerBrokenCommitDriver sync.Once
+
+type brokenCommitDriver struct {
+	driver.Driver
+}
+
+func (d brokenCommitDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return brokenCommitConn{Conn: conn}, nil
+}
+
+type brokenCommitConn struct {
+	driver.Conn
+}
+
+func (c brokenCommitConn) Begin() (driver.Tx, error) {
+	tx, err := c.Conn.Begin() //nolint: staticcheck // the context-aware interfaces are not wrapped
+	if err != nil {
+		return nil, err
+	}
+	return brokenCommitTx{Tx: tx}, nil
+}
+
+type brokenCommitTx struct {
+	driver.Tx
+}
+
+func (tx brokenCommitTx) Commit() error {
+	if err := tx.Tx.Commit(); err != nil {
+		return err
+	}
+	return io.ErrUnexpectedEOF
+}
+
+// brokenConnectionDialect reports broken connections as connection errors,
+// as the dialects of the network databases do.
+type brokenConnectionDialect struct {
+	dialect
+}
+
+func (d brokenConnectionDialect) isConnectionError(err error) bool {
+	return isBrokenConnection(err)
+}
+
 func (s *PluginSuite) TestInvalidPluginConfiguration() {
 	err := s.ds.Configure(`
 		database_type = "wrong"
@@ -211,11 +284,205 @@ func (s *PluginSuite) TestInvalidMySQLConfiguration() {
 	s.RequireErrorContains(err, "datastore-sql: connection_string must be set")
 }
 
+func (s *PluginSuite) TestConnectionStringFile() {
+	if TestDialect != "" {
+		s.T().Skip()
+	}
+
+	dbPath := filepath.ToSlash(filepath.Join(s.dir, "test-datastore-connection-string-file.sqlite3"))
+	connectionStringFile := filepath.Join(s.dir, "connection-string")
+	s.Require().NoError(os.WriteFile(connectionStringFile, []byte(dbPath+"\n"), 0600))
+
+	log, _ := test.NewNullLogger()
+	ds := New(log)
+	defer ds.closeDB()
+	err := ds.Configure(fmt.Sprintf(`
+		database_type = "sqlite3"
+		connection_string_file = "%s"
+	`, filepath.ToSlash(connectionStringFile)))
+	s.Require().NoError(err)
+	s.Require().Equal(dbPath, ds.db.connectionString)
+
+	_, err = ds.CountBundles(ctx)
+	s.Require().NoError(err)
+}
+
+func (s *PluginSuite) TestInvalidConnectionStringSource() {
+	s.T().Setenv("SPIRE_TEST_EMPTY_CONNECTION_STRING", "")
+	emptyFile := filepath.Join(s.dir, "empty-connection-string")
+	s.Require().NoError(os.WriteFile(emptyFile, nil, 0600))
+
+	for _, tt := range []struct {
+		name      string
+		config    string
+		expectErr string
+	}{
+		{
+			name: "missing file",
+			config: fmt.Sprintf(`
+				database_type = "sqlite3"
+				connection_string_file = "%s"
+			`, filepath.ToSlash(filepath.Join(s.dir, "missing"))),
+			expectErr: "datastore-sql: unable to read connection_string_file: open",
+		},
+		{
+			name: "empty file",
+			config: fmt.Sprintf(`
+				database_type = "sqlite3"
+				connection_string_file = "%s"
+			`, filepath.ToSlash(emptyFile)),
+			expectErr: "datastore-sql: connection_string_file",
+		},
+		{
+			name: "unset environment variable",
+			config: `
+				database_type = "sqlite3"
+				connection_string_env = "SPIRE_TEST_UNSET_CONNECTION_STRING"
+			`,
+			expectErr: `datastore-sql: connection_string_env "SPIRE_TEST_UNSET_CONNECTION_STRING" is not set`,
+		},
+		{
+			name: "empty environment variable",
+			config: `
+				database_type = "sqlite3"
+				connection_string_env = "SPIRE_TEST_EMPTY_CONNECTION_STRING"
+			`,
+			expectErr: `datastore-sql: connection_string_env "SPIRE_TEST_EMPTY_CONNECTION_STRING" is not set`,
+		},
+		{
+			name: "more than one source",
+			config: `
+				database_type = "sqlite3"
+				connection_string = "spire.sqlite3"
+				connection_string_env = "SPIRE_TEST_CONNECTION_STRING"
+			`,
+			expectErr: "datastore-sql: only one of connection_string, connection_string_file or connection_string_env can be set",
+		},
+		{
+			name: "more than one read-only source",
+			config: `
+				database_type = "sqlite3"
+				connection_string = "spire.sqlite3"
+				ro_connection_string = "spire.sqlite3"
+				ro_connection_string_env = "SPIRE_TEST_CONNECTION_STRING"
+			`,
+			expectErr: "datastore-sql: only one of ro_connection_string, ro_connection_string_file or ro_connection_string_env can be set",
+		},
+	} {
+		tt := tt
+		s.T().Run(tt.name, func(t *testing.T) {
+			log, _ := test.NewNullLogger()
+			err := New(log).Configure(tt.config)
+			spiretest.RequireErrorPrefix(t, err, tt.expectErr)
+		})
+	}
+}
+
+func (s *PluginSuite) TestReadOnlyMaxOpenConns() {
+	if TestDialect != "" {
+		s.T().Skip("only tested against sqlite3")
+	}
+
+	maxOpenConns := 2
+	readOnlyMaxOpenConns := 5
+	cfg := &configuration{
+		DatabaseType:         SQLite,
+		ConnectionString:     filepath.ToSlash(filepath.Join(s.dir, "max_open_conns.sqlite3")),
+		MaxOpenConns:         &maxOpenConns,
+		ReadOnlyMaxOpenConns: &readOnlyMaxOpenConns,
+	}
+
+	db, _, _, _, err := s.ds.openDB(cfg, false)
+	s.Require().NoError(err)
+	defer db.Close()
+	s.Require().Equal(maxOpenConns, db.DB().Stats().MaxOpenConnections)
+
+	roDB, _, _, _, err := s.ds.openDB(cfg, true)
+	s.Require().NoError(err)
+	defer roDB.Close()
+	s.Require().Equal(readOnlyMaxOpenConns, roDB.DB().Stats().MaxOpenConnections)
+
+	// max_open_conns applies to the read-only connection when not overridden
+	cfg.ReadOnlyMaxOpenConns = nil
+	roDB2, _, _, _, err := s.ds.openDB(cfg, true)
+	s.Require().NoError(err)
+	defer roDB2.Close()
+	s.Require().Equal(maxOpenConns, roDB2.DB().Stats().MaxOpenConnections)
+}
+
+func (s *PluginSuite) TestReadOnlyRouting() {
+	if TestDialect != "" {
+		s.T().Skip("only tested against sqlite3")
+	}
+
+	// Simulate a primary/replica split by pointing the read-only connection
+	// at a second, independent sqlite3 database. Anything written only to
+	// the "replica" shows which connection served a read.
+	replica := s.newPlugin()
+	defer replica.closeDB()
+	s.ds.roDb = replica.db
+	defer func() { s.ds.roDb = nil }()
+
+	// Bundles
+	primaryBundle := bundleutil.BundleProtoFromRootCA("spiffe://foo", s.cert)
+	_, err := s.ds.CreateBundle(ctx, primaryBundle)
+	s.Require().NoError(err)
+	replicaBundle := bundleutil.BundleProtoFromRootCA("spiffe://foo", s.cacert)
+	_, err = replica.CreateBundle(ctx, replicaBundle)
+	s.Require().NoError(err)
+
+	bundle, err := s.ds.FetchBundle(ctx, "spiffe://foo", datastore.RequireCurrent)
+	s.Require().NoError(err)
+	s.AssertProtoEqual(primaryBundle, bundle)
+
+	bundle, err = s.ds.FetchBundle(ctx, "spiffe://foo", datastore.TolerateStale)
+	s.Require().NoError(err)
+	s.AssertProtoEqual(replicaBundle, bundle)
+
+	// Node selectors
+	primarySelectors := []*common.Selector{{Type: "FOO", Value: "primary"}}
+	replicaSelectors := []*common.Selector{{Type: "FOO", Value: "replica"}}
+	s.setNodeSelectors("spiffe://foo/agent", primarySelectors)
+	s.Require().NoError(replica.SetNodeSelectors(ctx, "spiffe://foo/agent", replicaSelectors))
+
+	s.RequireProtoListEqual(primarySelectors, s.getNodeSelectors("spiffe://foo/agent", datastore.RequireCurrent))
+	s.RequireProtoListEqual(replicaSelectors, s.getNodeSelectors("spiffe://foo/agent", datastore.TolerateStale))
+
+	// Registration entries
+	entry := s.createRegistrationEntry(&common.RegistrationEntry{
+		Selectors: []*common.Selector{{Type: "FOO", Value: "BAR"}},
+		SpiffeId:  "spiffe://foo/workload",
+		ParentId:  "spiffe://foo/agent",
+	})
+
+	resp, err := s.ds.ListRegistrationEntries(ctx, &datastore.ListRegistrationEntriesRequest{
+		DataConsistency: datastore.RequireCurrent,
+	})
+	s.Require().NoError(err)
+	s.RequireProtoListEqual([]*common.RegistrationEntry{entry}, resp.Entries)
+
+	resp, err = s.ds.ListRegistrationEntries(ctx, &datastore.ListRegistrationEntriesRequest{
+		DataConsistency: datastore.TolerateStale,
+	})
+	s.Require().NoError(err)
+	s.Require().Empty(resp.Entries)
+
+	// Operations that read their own writes stay on the primary
+	appended, err := s.ds.AppendBundle(ctx, bundleutil.BundleProtoFromRootCA("spiffe://foo", s.cacert))
+	s.Require().NoError(err)
+	s.Require().Len(appended.RootCas, 2)
+	s.Require().Equal(s.cert.Raw, appended.RootCas[0].DerBytes)
+
+	fetched, err := s.ds.FetchRegistrationEntry(ctx, entry.EntryId)
+	s.Require().NoError(err)
+	s.AssertProtoEqual(entry, fetched)
+}
+
 func (s *PluginSuite) TestBundleCRUD() {
 	bundle := bundleutil.BundleProtoFromRootCA("spiffe://foo", s.cert)
 
 	// fetch non-existent
-	fb, err := s.ds.FetchBundle(ctx, "spiffe://foo")
+	fb, err := s.ds.FetchBundle(ctx, "spiffe://foo", datastore.RequireCurrent)
 	s.Require().NoError(err)
 	s.Require().Nil(fb)
 
@@ -236,7 +503,7 @@ func (s *PluginSuite) TestBundleCRUD() {
 	s.Equal(status.Code(err), codes.AlreadyExists)
 
 	// fetch
-	fb, err = s.ds.FetchBundle(ctx, "spiffe://foo")
+	fb, err = s.ds.FetchBundle(ctx, "spiffe://foo", datastore.RequireCurrent)
 	s.Require().NoError(err)
 	s.AssertProtoEqual(bundle, fb)
 
@@ -586,7 +853,7 @@ func (s *PluginSuite) TestBundlePrune() {
 	// Fetch and verify pruned bundle is the expected
 	expectedPrunedBundle := bundleutil.BundleProtoFromRootCAs("spiffe://foo", []*x509.Certificate{s.cert})
 	expectedPrunedBundle.JwtSigningKeys = []*common.PublicKey{{NotAfter: nonExpiredKeyTime.Unix()}}
-	fb, err := s.ds.FetchBundle(ctx, "spiffe://foo")
+	fb, err := s.ds.FetchBundle(ctx, "spiffe://foo", datastore.RequireCurrent)
 	s.Require().NoError(err)
 	s.AssertProtoEqual(expectedPrunedBundle, fb)
 }
@@ -1295,6 +1562,40 @@ func (s *PluginSuite) TestCreateInvalidRegistrationEntry() {
 	// TODO: Check that no entries have been created
 }
 
+func (s *PluginSuite) TestCreateRegistrationEntryIsNotRetriedAfterAmbiguousCommit() {
+	if TestDialect != "" {
+		s.T().Skip("the broken connection is simulated with the sqlite3 driver")
+	}
+
+	// Commit through a connection that breaks right after the commit is
+	// applied, so the outcome of the commit is unknown to the datastore
+	gormDB, dialect := s.ds.db.DB, s.ds.db.dialect
+	defer func() {
+		s.ds.db.DB.Close()
+		s.ds.db.DB, s.ds.db.dialect = gormDB, dialect
+	}()
+	s.ds.db.DB = s.openBrokenCommitDB()
+	s.ds.db.dialect = brokenConnectionDialect{dialect: dialect}
+
+	retryDS := datastore.WithRetry(s.ds, datastore.RetryConfig{
+		Log:            logrus.New(),
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+	})
+	_, err := retryDS.CreateRegistrationEntry(ctx, &common.RegistrationEntry{
+		Selectors: []*common.Selector{{Type: "unix", Value: "uid:1000"}},
+		SpiffeId:  "spiffe://example.org/foo",
+		ParentId:  "spiffe://example.org/bar",
+	})
+	s.Require().EqualError(err, "datastore-sql: unexpected EOF")
+	s.Require().Equal(codes.Unknown, status.Code(err))
+
+	// The entry was created once, since the create was not retried
+	resp, err := s.ds.ListRegistrationEntries(ctx, &datastore.ListRegistrationEntriesRequest{})
+	s.Require().NoError(err)
+	s.Require().Len(resp.Entries, 1)
+}
+
 func (s *PluginSuite) TestFetchRegistrationEntry() {
 	for _, tt := range []struct {
 		name  string
@@ -3513,7 +3814,7 @@ func (s *PluginSuite) TestCreateFederationRelationship() {
 
 			if fr.TrustDomainBundle != nil {
 				// Assert bundle is updated
-				bundle, err := s.ds.FetchBundle(ctx, fr.TrustDomain.IDString())
+				bundle, err := s.ds.FetchBundle(ctx, fr.TrustDomain.IDString(), datastore.RequireCurrent)
 				require.NoError(t, err)
 				spiretest.RequireProtoEqual(t, bundle, fr.TrustDomainBundle)
 			}
@@ -3842,7 +4143,7 @@ func (s *PluginSuite) TestUpdateFederationRelationship() {
 			case datastore.BundleEndpointWeb:
 			case datastore.BundleEndpointSPIFFE:
 				// Assert bundle is updated
-				bundle, err := s.ds.FetchBundle(ctx, tt.expFR.TrustDomain.IDString())
+				bundle, err := s.ds.FetchBundle(ctx, tt.expFR.TrustDomain.IDString(), datastore.RequireCurrent)
 				s.Require().NoError(err)
 				s.RequireProtoEqual(bundle, updatedFR.TrustDomainBundle)
 
@@ -3960,7 +4261,7 @@ func (s *PluginSuite) getTestDataFromJSONFile(filePath string, jsonValue interfa
 }
 
 func (s *PluginSuite) fetchBundle(trustDomainID string) *common.Bundle {
-	bundle, err := s.ds.FetchBundle(ctx, trustDomainID)
+	bundle, err := s.ds.FetchBundle(ctx, trustDomainID, datastore.RequireCurrent)
 	s.Require().NoError(err)
 	return bundle
 }
@@ -4240,3 +4541,100 @@ func assertFederationRelationship(t *testing.T, exp, actual *datastore.Federatio
 	assert.Equal(t, exp.TrustDomain, actual.TrustDomain)
 	spiretest.AssertProtoEqual(t, exp.TrustDomainBundle, actual.TrustDomainBundle)
 }
+
+func TestTransientToGRPCStatus(t *testing.T) {
+	for _, tt := range []struct {
+		name            string
+		dialect         dialect
+		err             error
+		committing      bool
+		expectTransient bool
+	}{
+		{
+			name:    "no error",
+			dialect: postgresDB{},
+		},
+		{
+			name:            "bad connection",
+			dialect:         postgresDB{},
+			err:             sqlError.Wrap(driver.ErrBadConn),
+			expectTransient: true,
+		},
+		{
+			name:            "connection reset",
+			dialect:         mysqlDB{},
+			err:             sqlError.Wrap(fmt.Errorf("read: %w", syscall.ECONNRESET)),
+			expectTransient: true,
+		},
+		{
+			name:            "PostgreSQL deadlock",
+			dialect:         postgresDB{},
+			err:             sqlError.Wrap(&pq.Error{Code: "40P01", Message: "deadlock detected"}),
+			expectTransient: true,
+		},
+		{
+			name:            "PostgreSQL connection failure",
+			dialect:         postgresDB{},
+			err:             sqlError.Wrap(&pq.Error{Code: "08006", Message: "connection failure"}),
+			expectTransient: true,
+		},
+		{
+			name:    "PostgreSQL constraint violation",
+			dialect: postgresDB{},
+			err:     sqlError.Wrap(&pq.Error{Code: "23505", Message: "duplicate key"}),
+		},
+		{
+			name:            "MySQL deadlock",
+			dialect:         mysqlDB{},
+			err:             sqlError.Wrap(&mysql.MySQLError{Number: 1213, Message: "Deadlock found"}),
+			expectTransient: true,
+		},
+		{
+			name:            "MySQL invalid connection",
+			dialect:         mysqlDB{},
+			err:             sqlError.Wrap(mysql.ErrInvalidConn),
+			expectTransient: true,
+		},
+		{
+			name:    "MySQL constraint violation",
+			dialect: mysqlDB{},
+			err:     sqlError.Wrap(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}),
+		},
+		{
+			name:       "bad connection while committing",
+			dialect:    postgresDB{},
+			err:        sqlError.Wrap(driver.ErrBadConn),
+			committing: true,
+		},
+		{
+			name:       "unexpected EOF while committing",
+			dialect:    mysqlDB{},
+			err:        sqlError.Wrap(io.ErrUnexpectedEOF),
+			committing: true,
+		},
+		{
+			name:       "PostgreSQL connection failure while committing",
+			dialect:    postgresDB{},
+			err:        sqlError.Wrap(&pq.Error{Code: "08006", Message: "connection failure"}),
+			committing: true,
+		},
+		{
+			name:            "PostgreSQL serialization failure while committing",
+			dialect:         postgresDB{},
+			err:             sqlError.Wrap(&pq.Error{Code: "40001", Message: "could not serialize access"}),
+			committing:      true,
+			expectTransient: true,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := transientToGRPCStatus(tt.dialect, tt.err, !tt.committing)
+			if !tt.expectTransient {
+				// other errors are returned unmodified
+				require.Equal(t, tt.err, err)
+				return
+			}
+			spiretest.RequireGRPCStatus(t, err, codes.Unavailable, tt.err.Error())
+		})
+	}
+}
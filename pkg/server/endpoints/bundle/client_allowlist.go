@@ -0,0 +1,116 @@
+package bundle
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+	"github.com/zeebo/errs"
+)
+
+// ClientAllowList holds the SPIFFE IDs of the clients allowed to fetch the
+// bundle from an endpoint using SPIFFE authentication. The IDs are read from
+// a file, one per line, which is read again whenever it changes so that the
+// list can be updated without restarting the server. Blank lines and lines
+// starting with # are ignored. An empty list allows any client.
+type ClientAllowList struct {
+	log     logrus.FieldLogger
+	path    string
+	bundles x509bundle.Source
+
+	mu      sync.Mutex
+	loaded  bool
+	modTime time.Time
+	size    int64
+	ids     map[spiffeid.ID]struct{}
+}
+
+// NewClientAllowList returns an allow-list read from the file at path.
+// Client X509-SVIDs are verified against the bundles from the given source.
+func NewClientAllowList(log logrus.FieldLogger, path string, bundles x509bundle.Source) *ClientAllowList {
+	return &ClientAllowList{
+		log:     log,
+		path:    path,
+		bundles: bundles,
+	}
+}
+
+// allowedIDs returns the allowed SPIFFE IDs, reading the file again if it
+// changed. If the file cannot be read, the last list read is kept. An error
+// is returned if the list has never been read, so that clients are not
+// allowed by mistake.
+func (l *ClientAllowList) allowedIDs() (map[spiffeid.ID]struct{}, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	info, err := os.Stat(l.path)
+	if err == nil && l.loaded && info.ModTime().Equal(l.modTime) && info.Size() == l.size {
+		return l.ids, nil
+	}
+	if err == nil {
+		var ids map[spiffeid.ID]struct{}
+		ids, err = readClientAllowList(l.path)
+		if err == nil {
+			if l.loaded {
+				l.log.WithField("count", len(ids)).Info("Reloaded bundle endpoint client allow-list")
+			}
+			l.loaded = true
+			l.modTime = info.ModTime()
+			l.size = info.Size()
+			l.ids = ids
+			return l.ids, nil
+		}
+	}
+
+	if !l.loaded {
+		return nil, errs.New("unable to load bundle endpoint client allow-list: %v", err)
+	}
+	l.log.WithError(err).Warn("Unable to reload bundle endpoint client allow-list; keeping the previous one")
+	return l.ids, nil
+}
+
+// verifyClient verifies the X509-SVID presented by the client and checks
+// that its SPIFFE ID is allowed.
+func (l *ClientAllowList) verifyClient(rawCerts [][]byte, allowed map[spiffeid.ID]struct{}) error {
+	id, _, err := x509svid.ParseAndVerify(rawCerts, l.bundles)
+	if err != nil {
+		return errs.New("unable to verify client X509-SVID: %v", err)
+	}
+	if _, ok := allowed[id]; !ok {
+		return errs.New("client %q is not allowed", id)
+	}
+	return nil
+}
+
+func readClientAllowList(path string) (map[spiffeid.ID]struct{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[spiffeid.ID]struct{})
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, err := spiffeid.FromString(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SPIFFE ID on line %d: %w", lineNum, err)
+		}
+		ids[id] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
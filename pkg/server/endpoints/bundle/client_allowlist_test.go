@@ -0,0 +1,109 @@
+package bundle
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/spire/pkg/common/bundleutil"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/spiffe/spire/test/testca"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSPIFFEAuthClientAllowList(t *testing.T) {
+	serverCert, serverKey := createServerCertificate(t)
+	td := spiffeid.RequireTrustDomainFromString("domain.test")
+	bundle := bundleutil.New(td)
+	bundle.AppendRootCA(serverCert)
+
+	ca := testca.New(t, td)
+	allowedID := spiffeid.RequireFromPath(td, "/allowed")
+	otherID := spiffeid.RequireFromPath(td, "/other")
+
+	path := filepath.Join(spiretest.TempDir(t), "clients")
+	log, _ := test.NewNullLogger()
+	clients := NewClientAllowList(log, path, ca.X509Bundle())
+
+	addr, done := newTestServer(t, testGetter(bundle), SPIFFEAuth(func() ([]*x509.Certificate, crypto.PrivateKey, error) {
+		return []*x509.Certificate{serverCert}, serverKey, nil
+	}, clients))
+	defer done()
+
+	fetch := func(id *spiffeid.ID) error {
+		tlsConfig := &tls.Config{
+			RootCAs:    x509.NewCertPool(),
+			MinVersion: tls.VersionTLS12,
+		}
+		tlsConfig.RootCAs.AddCert(serverCert)
+		if id != nil {
+			svid := ca.CreateX509SVID(*id)
+			certs, _, err := svid.MarshalRaw()
+			require.NoError(t, err)
+			tlsConfig.Certificates = []tls.Certificate{{
+				Certificate: [][]byte{certs},
+				PrivateKey:  svid.PrivateKey,
+			}}
+		}
+		client := http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+		defer client.CloseIdleConnections()
+
+		resp, err := client.Get(fmt.Sprintf("https://%s", addr))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return nil
+	}
+
+	writeClients := func(content string, modTime time.Time) {
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		// Make sure the change is noticed even if the file is rewritten
+		// within the timestamp granularity of the filesystem
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+
+	t.Run("clients are rejected until the list is loaded", func(t *testing.T) {
+		require.Error(t, fetch(&allowedID))
+		require.Error(t, fetch(nil))
+	})
+
+	now := time.Now()
+	writeClients("# Federation partners\n"+allowedID.String()+"\n", now)
+
+	t.Run("allowed client", func(t *testing.T) {
+		require.NoError(t, fetch(&allowedID))
+	})
+
+	t.Run("denied client", func(t *testing.T) {
+		require.Error(t, fetch(&otherID))
+		require.Error(t, fetch(nil))
+	})
+
+	t.Run("live reload", func(t *testing.T) {
+		writeClients(otherID.String()+"\n", now.Add(time.Minute))
+		require.NoError(t, fetch(&otherID))
+		require.Error(t, fetch(&allowedID))
+	})
+
+	t.Run("invalid list keeps the previous one", func(t *testing.T) {
+		writeClients("not-a-spiffe-id\n", now.Add(2*time.Minute))
+		require.NoError(t, fetch(&otherID))
+		require.Error(t, fetch(&allowedID))
+	})
+
+	t.Run("empty list allows any client", func(t *testing.T) {
+		writeClients("", now.Add(3*time.Minute))
+		require.NoError(t, fetch(&allowedID))
+		require.NoError(t, fetch(nil))
+	})
+}
@@ -4,9 +4,19 @@ import "net"
 
 type EndpointConfig struct {
 	// Address is the address on which to serve the federation bundle endpoint.
-	Address *net.TCPAddr
+	// It is either a *net.TCPAddr or a *net.UnixAddr. The bundle endpoint
+	// is served over plain HTTP on Unix domain sockets since they are
+	// local-only.
+	Address net.Addr
 
 	// ACME is the ACME configuration for the bundle endpoint.
-	// If unset, the bundle endpoint will use SPIFFE auth.
+	// If unset, the bundle endpoint will use SPIFFE auth. It does not apply
+	// to Unix domain sockets.
 	ACME *ACMEConfig
+
+	// AllowedClientIDsFile is the path to a file with the SPIFFE IDs of the
+	// clients allowed to fetch the bundle using SPIFFE authentication, one
+	// per line. The file is read again when it changes. If unset or empty,
+	// any client is allowed. It does not apply to ACME or Unix domain sockets.
+	AllowedClientIDsFile string
 }
@@ -6,9 +6,11 @@ import (
 	"crypto/x509"
 	"net"
 	"net/http"
+	"os"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spiffe/spire/pkg/common/bundleutil"
+	"github.com/spiffe/spire/pkg/common/tlspolicy"
 	"github.com/zeebo/errs"
 )
 
@@ -27,11 +29,23 @@ type ServerAuth interface {
 }
 
 type ServerConfig struct {
-	Log        logrus.FieldLogger
-	Address    string
-	Getter     Getter
+	Log logrus.FieldLogger
+
+	// Network is the network the server listens on (i.e. "tcp" or "unix").
+	// Defaults to "tcp".
+	Network string
+	Address string
+	Getter  Getter
+
+	// ServerAuth provides the TLS configuration for the server. If nil, the
+	// bundle is served over plain HTTP, which is only suitable for local-only
+	// listeners like Unix domain sockets.
 	ServerAuth ServerAuth
 
+	// TLSPolicy restricts the TLS versions and cipher suites negotiated when
+	// ServerAuth is set.
+	TLSPolicy tlspolicy.Policy
+
 	// test hooks
 	listen func(network, address string) (net.Listener, error)
 }
@@ -41,6 +55,9 @@ type Server struct {
 }
 
 func NewServer(config ServerConfig) *Server {
+	if config.Network == "" {
+		config.Network = "tcp"
+	}
 	if config.listen == nil {
 		config.listen = net.Listen
 	}
@@ -52,23 +69,34 @@ func NewServer(config ServerConfig) *Server {
 func (s *Server) ListenAndServe(ctx context.Context) error {
 	// create the listener explicitly instead of using ListenAndServeTLS since
 	// it gives us the ability to use/inspect an ephemeral port during testing.
-	listener, err := s.c.listen("tcp", s.c.Address)
+	if s.c.Network == "unix" {
+		// Remove the socket left behind by a previous run, if any.
+		os.Remove(s.c.Address)
+	}
+	listener, err := s.c.listen(s.c.Network, s.c.Address)
 	if err != nil {
 		return errs.Wrap(err)
 	}
 
-	// Set up the TLS config, setting TLS 1.2 as the minimum.
-	tlsConfig := s.c.ServerAuth.GetTLSConfig()
-	tlsConfig.MinVersion = tls.VersionTLS12
-
 	server := &http.Server{
-		Handler:   http.HandlerFunc(s.serveHTTP),
-		TLSConfig: tlsConfig,
+		Handler: http.HandlerFunc(s.serveHTTP),
+	}
+
+	serve := func() error {
+		return server.Serve(listener)
+	}
+	if s.c.ServerAuth != nil {
+		// Set up the TLS config, restricted by the TLS policy.
+		server.TLSConfig = s.c.ServerAuth.GetTLSConfig()
+		s.c.TLSPolicy.Apply(server.TLSConfig)
+		serve = func() error {
+			return server.ServeTLS(listener, "", "")
+		}
 	}
 
 	errCh := make(chan error, 1)
 	go func() {
-		errCh <- errs.Wrap(server.ServeTLS(listener, "", ""))
+		errCh <- errs.Wrap(serve())
 	}()
 
 	select {
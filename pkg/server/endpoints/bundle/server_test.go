@@ -13,6 +13,8 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -20,6 +22,7 @@ import (
 	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
 	"github.com/spiffe/spire/pkg/common/bundleutil"
+	"github.com/spiffe/spire/pkg/common/tlspolicy"
 	"github.com/spiffe/spire/pkg/server/endpoints/bundle/internal/acmetest"
 	"github.com/spiffe/spire/test/fakes/fakeserverkeymanager"
 	"github.com/spiffe/spire/test/spiretest"
@@ -275,7 +278,123 @@ func TestACMEAuth(t *testing.T) {
 	})
 }
 
+func TestServerUnixSocket(t *testing.T) {
+	serverCert, _ := createServerCertificate(t)
+	trustDomain := spiffeid.RequireTrustDomainFromString("domain.test")
+	bundle := bundleutil.New(trustDomain)
+	bundle.AppendRootCA(serverCert)
+
+	socketPath := filepath.Join(spiretest.TempDir(t), "bundle.sock")
+	// Leave a stale file behind to make sure it is replaced.
+	require.NoError(t, os.WriteFile(socketPath, nil, 0600))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log, _ := test.NewNullLogger()
+	server := NewServer(ServerConfig{
+		Log:     log,
+		Network: "unix",
+		Address: socketPath,
+		Getter:  testGetter(bundle),
+	})
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe(ctx)
+	}()
+
+	client := http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+
+	var resp *http.Response
+	require.Eventually(t, func() bool {
+		var err error
+		resp, err = client.Get("http://localhost/")
+		return err == nil
+	}, time.Minute, 10*time.Millisecond)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	actual, err := bundleutil.Decode(trustDomain, resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, bundle.RootCAs(), actual.RootCAs())
+
+	cancel()
+	require.NoError(t, <-errCh)
+}
+
+func TestServerTLSPolicy(t *testing.T) {
+	serverCert, serverKey := createServerCertificate(t)
+	td := spiffeid.RequireTrustDomainFromString("domain.test")
+	bundle := bundleutil.New(td)
+	bundle.AppendRootCA(serverCert)
+	getter := func() ([]*x509.Certificate, crypto.PrivateKey, error) {
+		return []*x509.Certificate{serverCert}, serverKey, nil
+	}
+
+	// An empty allow-list, so that the TLS configuration is returned by the
+	// GetConfigForClient hook
+	clientsPath := filepath.Join(spiretest.TempDir(t), "clients")
+	require.NoError(t, os.WriteFile(clientsPath, nil, 0600))
+	log, _ := test.NewNullLogger()
+	clients := NewClientAllowList(log, clientsPath, nil)
+
+	for _, tt := range []struct {
+		name       string
+		serverAuth ServerAuth
+	}{
+		{
+			name:       "without allow-list",
+			serverAuth: SPIFFEAuth(getter, nil),
+		},
+		{
+			name:       "with allow-list",
+			serverAuth: SPIFFEAuth(getter, clients),
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			addr, done := newTestServerWithPolicy(t, testGetter(bundle), tt.serverAuth, tlspolicy.Policy{MinVersion: tls.VersionTLS13})
+			defer done()
+
+			get := func(maxVersion uint16) error {
+				rootCAs := x509.NewCertPool()
+				rootCAs.AddCert(serverCert)
+				client := http.Client{
+					Transport: &http.Transport{
+						TLSClientConfig: &tls.Config{
+							RootCAs:    rootCAs,
+							MinVersion: tls.VersionTLS12,
+							MaxVersion: maxVersion,
+						},
+					},
+				}
+				defer client.CloseIdleConnections()
+				resp, err := client.Get(fmt.Sprintf("https://%s", addr))
+				if err != nil {
+					return err
+				}
+				return resp.Body.Close()
+			}
+
+			require.Error(t, get(tls.VersionTLS12))
+			require.NoError(t, get(tls.VersionTLS13))
+		})
+	}
+}
+
 func newTestServer(t *testing.T, getter Getter, serverAuth ServerAuth) (net.Addr, func()) {
+	return newTestServerWithPolicy(t, getter, serverAuth, tlspolicy.Policy{})
+}
+
+func newTestServerWithPolicy(t *testing.T, getter Getter, serverAuth ServerAuth, tlsPolicy tlspolicy.Policy) (net.Addr, func()) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	addrCh := make(chan net.Addr, 1)
@@ -294,6 +413,7 @@ func newTestServer(t *testing.T, getter Getter, serverAuth ServerAuth) (net.Addr
 		Address:    "localhost:0",
 		Getter:     getter,
 		ServerAuth: serverAuth,
+		TLSPolicy:  tlsPolicy,
 		listen:     listen,
 	})
 
@@ -332,7 +452,7 @@ func testSPIFFEAuth(cert *x509.Certificate, key crypto.Signer) ServerAuth {
 			return nil, nil, errors.New("no server certificate")
 		}
 		return []*x509.Certificate{cert}, key, nil
-	})
+	}, nil)
 }
 
 func createServerCertificate(t *testing.T) (*x509.Certificate, crypto.Signer) {
@@ -6,23 +6,55 @@ import (
 	"crypto/x509"
 )
 
-func SPIFFEAuth(getter func() ([]*x509.Certificate, crypto.PrivateKey, error)) ServerAuth {
+// SPIFFEAuth returns server authentication using the SVID returned by the
+// getter. If clients is set, only the clients in the allow-list can fetch the
+// bundle while the list is not empty.
+func SPIFFEAuth(getter func() ([]*x509.Certificate, crypto.PrivateKey, error), clients *ClientAllowList) ServerAuth {
 	return &spiffeAuth{
-		getter: getter,
+		getter:  getter,
+		clients: clients,
 	}
 }
 
 type spiffeAuth struct {
-	getter func() ([]*x509.Certificate, crypto.PrivateKey, error)
+	getter  func() ([]*x509.Certificate, crypto.PrivateKey, error)
+	clients *ClientAllowList
 }
 
 func (s *spiffeAuth) GetTLSConfig() *tls.Config {
+	config := s.newTLSConfig()
+	if s.clients != nil {
+		config.GetConfigForClient = s.getConfigForClient
+	}
+	return config
+}
+
+func (s *spiffeAuth) newTLSConfig() *tls.Config {
 	return &tls.Config{
 		GetCertificate: s.getCertificate,
 		MinVersion:     tls.VersionTLS12,
 	}
 }
 
+// getConfigForClient requires clients to authenticate with an allowed
+// X509-SVID. The allow-list is consulted on every handshake so that changes
+// to it apply to new connections right away.
+func (s *spiffeAuth) getConfigForClient(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+	allowed, err := s.clients.allowedIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	config := s.newTLSConfig()
+	if len(allowed) > 0 {
+		config.ClientAuth = tls.RequireAnyClientCert
+		config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return s.clients.verifyClient(rawCerts, allowed)
+		}
+	}
+	return config, nil
+}
+
 func (s *spiffeAuth) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
 	chain, privateKey, err := s.getter()
 	if err != nil {
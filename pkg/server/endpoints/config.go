@@ -4,14 +4,17 @@ import (
 	"crypto"
 	"crypto/x509"
 	"errors"
+	"fmt"
 	"net"
 	"time"
 
 	"github.com/andres-erbsen/clock"
 	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
 	"github.com/spiffe/spire/pkg/common/bundleutil"
 	"github.com/spiffe/spire/pkg/common/telemetry"
+	"github.com/spiffe/spire/pkg/common/tlspolicy"
 	"github.com/spiffe/spire/pkg/server/api"
 	agentv1 "github.com/spiffe/spire/pkg/server/api/agent/v1"
 	bundlev1 "github.com/spiffe/spire/pkg/server/api/bundle/v1"
@@ -25,6 +28,7 @@ import (
 	"github.com/spiffe/spire/pkg/server/ca"
 	"github.com/spiffe/spire/pkg/server/cache/dscache"
 	"github.com/spiffe/spire/pkg/server/catalog"
+	"github.com/spiffe/spire/pkg/server/datastore"
 	"github.com/spiffe/spire/pkg/server/endpoints/bundle"
 	"github.com/spiffe/spire/pkg/server/svid"
 	"golang.org/x/net/context"
@@ -77,11 +81,32 @@ type Config struct {
 
 	AuditLogEnabled bool
 
+	// AuditLog, if set, receives the audit records instead of the server
+	// log. Only used when AuditLogEnabled is true.
+	AuditLog logrus.FieldLogger
+
 	// AdminIDs are a list of fixed IDs that when presented by a caller in an
 	// X509-SVID, are granted admin rights.
 	AdminIDs []spiffeid.ID
 
 	BundleManager *bundle_client.Manager
+
+	// EnableReflection registers the gRPC reflection service on the UDS
+	// server. It is never registered on the TCP server.
+	EnableReflection bool
+
+	// MaxRecvMessageSize is the maximum size in bytes of the messages
+	// received by the API servers. Defaults to the gRPC default (4 MiB) when
+	// zero.
+	MaxRecvMessageSize int
+
+	// MaxSendMessageSize is the maximum size in bytes of the messages sent by
+	// the API servers. Defaults to the gRPC default when zero.
+	MaxSendMessageSize int
+
+	// TLSPolicy restricts the TLS versions and cipher suites negotiated by
+	// the TCP API server and the bundle endpoint.
+	TLSPolicy tlspolicy.Policy
 }
 
 func (c *Config) maybeMakeBundleEndpointServer() Server {
@@ -91,21 +116,34 @@ func (c *Config) maybeMakeBundleEndpointServer() Server {
 	c.Log.WithField("addr", c.BundleEndpoint.Address).Info("Serving bundle endpoint")
 
 	var serverAuth bundle.ServerAuth
-	if c.BundleEndpoint.ACME != nil {
+	switch {
+	case c.BundleEndpoint.Address.Network() == "unix":
+		// Unix domain sockets are local-only, so the bundle is served
+		// without TLS.
+	case c.BundleEndpoint.ACME != nil:
 		serverAuth = bundle.ACMEAuth(c.Log.WithField(telemetry.SubsystemName, "bundle_acme"), c.Catalog.GetKeyManager(), *c.BundleEndpoint.ACME)
-	} else {
+	default:
+		var clients *bundle.ClientAllowList
+		if c.BundleEndpoint.AllowedClientIDsFile != "" {
+			clients = bundle.NewClientAllowList(
+				c.Log.WithField(telemetry.SubsystemName, "bundle_endpoint"),
+				c.BundleEndpoint.AllowedClientIDsFile,
+				dataStoreBundleSource{ds: c.Catalog.GetDataStore()},
+			)
+		}
 		serverAuth = bundle.SPIFFEAuth(func() ([]*x509.Certificate, crypto.PrivateKey, error) {
 			state := c.SVIDObserver.State()
 			return state.SVID, state.Key, nil
-		})
+		}, clients)
 	}
 
 	ds := c.Catalog.GetDataStore()
 	return bundle.NewServer(bundle.ServerConfig{
 		Log:     c.Log.WithField(telemetry.SubsystemName, "bundle_endpoint"),
+		Network: c.BundleEndpoint.Address.Network(),
 		Address: c.BundleEndpoint.Address.String(),
 		Getter: bundle.GetterFunc(func(ctx context.Context) (*bundleutil.Bundle, error) {
-			commonBundle, err := ds.FetchBundle(dscache.WithCache(ctx), c.TrustDomain.IDString())
+			commonBundle, err := ds.FetchBundle(dscache.WithCache(ctx), c.TrustDomain.IDString(), datastore.TolerateStale)
 			if err != nil {
 				return nil, err
 			}
@@ -115,6 +153,7 @@ func (c *Config) maybeMakeBundleEndpointServer() Server {
 			return bundleutil.BundleFromProto(commonBundle)
 		}),
 		ServerAuth: serverAuth,
+		TLSPolicy:  c.TLSPolicy,
 	})
 }
 
@@ -165,3 +204,24 @@ func (c *Config) makeAPIServers(entryFetcher api.AuthorizedEntryFetcher) APIServ
 		}),
 	}
 }
+
+// dataStoreBundleSource is a source of the X.509 bundles stored in the
+// datastore, used to authenticate the clients of the bundle endpoint.
+type dataStoreBundleSource struct {
+	ds datastore.DataStore
+}
+
+func (s dataStoreBundleSource) GetX509BundleForTrustDomain(td spiffeid.TrustDomain) (*x509bundle.Bundle, error) {
+	commonBundle, err := s.ds.FetchBundle(dscache.WithCache(context.Background()), td.IDString(), datastore.TolerateStale)
+	if err != nil {
+		return nil, err
+	}
+	if commonBundle == nil {
+		return nil, fmt.Errorf("no bundle for trust domain %q", td)
+	}
+	bundle, err := bundleutil.BundleFromProto(commonBundle)
+	if err != nil {
+		return nil, err
+	}
+	return x509bundle.FromX509Authorities(td, bundle.RootCAs()), nil
+}
@@ -17,6 +17,7 @@ import (
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
 
 	"github.com/andres-erbsen/clock"
 	"github.com/sirupsen/logrus"
@@ -30,6 +31,7 @@ import (
 	"github.com/spiffe/spire/pkg/common/auth"
 	"github.com/spiffe/spire/pkg/common/peertracker"
 	"github.com/spiffe/spire/pkg/common/telemetry"
+	"github.com/spiffe/spire/pkg/common/tlspolicy"
 	"github.com/spiffe/spire/pkg/common/util"
 	"github.com/spiffe/spire/pkg/server/api/middleware"
 	"github.com/spiffe/spire/pkg/server/authpolicy"
@@ -71,8 +73,13 @@ type Endpoints struct {
 	RateLimit                    RateLimitConfig
 	EntryFetcherCacheRebuildTask func(context.Context) error
 	AuditLogEnabled              bool
+	AuditLog                     logrus.FieldLogger
 	AuthPolicyEngine             *authpolicy.Engine
 	AdminIDs                     []spiffeid.ID
+	EnableReflection             bool
+	MaxRecvMessageSize           int
+	MaxSendMessageSize           int
+	TLSPolicy                    tlspolicy.Policy
 }
 
 type APIServers struct {
@@ -132,8 +139,13 @@ func New(ctx context.Context, c Config) (*Endpoints, error) {
 		RateLimit:                    c.RateLimit,
 		EntryFetcherCacheRebuildTask: ef.RunRebuildCacheTask,
 		AuditLogEnabled:              c.AuditLogEnabled,
+		AuditLog:                     c.AuditLog,
 		AuthPolicyEngine:             c.AuthPolicyEngine,
 		AdminIDs:                     c.AdminIDs,
+		EnableReflection:             c.EnableReflection,
+		MaxRecvMessageSize:           c.MaxRecvMessageSize,
+		MaxSendMessageSize:           c.MaxSendMessageSize,
+		TLSPolicy:                    c.TLSPolicy,
 	}, nil
 }
 
@@ -164,6 +176,13 @@ func (e *Endpoints) ListenAndServe(ctx context.Context) error {
 	grpc_health_v1.RegisterHealthServer(udsServer, e.APIServers.HealthServer)
 	debugv1_pb.RegisterDebugServer(udsServer, e.APIServers.DebugServer)
 
+	// Reflection is only ever served on the UDS server so the APIs can't be
+	// introspected by remote callers
+	if e.EnableReflection {
+		e.Log.Info("Serving gRPC reflection on the UDS server")
+		reflection.Register(udsServer)
+	}
+
 	tasks := []func(context.Context) error{
 		func(ctx context.Context) error {
 			return e.runTCPServer(ctx, tcpServer)
@@ -190,14 +209,17 @@ func (e *Endpoints) createTCPServer(ctx context.Context, unaryInterceptor grpc.U
 		GetConfigForClient: e.getTLSConfig(ctx),
 	}
 
-	return grpc.NewServer(
+	options := []grpc.ServerOption{
 		grpc.UnaryInterceptor(unaryInterceptor),
 		grpc.StreamInterceptor(streamInterceptor),
 		grpc.Creds(credentials.NewTLS(tlsConfig)),
 		grpc.KeepaliveParams(keepalive.ServerParameters{
 			MaxConnectionAge: defaultMaxConnectionAge,
 		}),
-	)
+	}
+	options = append(options, e.messageSizeOptions()...)
+
+	return grpc.NewServer(options...)
 }
 
 func (e *Endpoints) createUDSServer(unaryInterceptor grpc.UnaryServerInterceptor, streamInterceptor grpc.StreamServerInterceptor) *grpc.Server {
@@ -211,10 +233,25 @@ func (e *Endpoints) createUDSServer(unaryInterceptor grpc.UnaryServerInterceptor
 	} else {
 		options = append(options, grpc.Creds(auth.UntrackedUDSCredentials()))
 	}
+	options = append(options, e.messageSizeOptions()...)
 
 	return grpc.NewServer(options...)
 }
 
+// messageSizeOptions returns the server options limiting the size of the
+// messages received and sent by the API servers. The gRPC defaults apply to
+// the limits that are not configured.
+func (e *Endpoints) messageSizeOptions() []grpc.ServerOption {
+	var options []grpc.ServerOption
+	if e.MaxRecvMessageSize > 0 {
+		options = append(options, grpc.MaxRecvMsgSize(e.MaxRecvMessageSize))
+	}
+	if e.MaxSendMessageSize > 0 {
+		options = append(options, grpc.MaxSendMsgSize(e.MaxSendMessageSize))
+	}
+	return options
+}
+
 // runTCPServer will start the server and block until it exits or we are dying.
 func (e *Endpoints) runTCPServer(ctx context.Context, server *grpc.Server) error {
 	l, err := net.Listen(e.TCPAddr.Network(), e.TCPAddr.String())
@@ -293,7 +330,7 @@ func (e *Endpoints) getTLSConfig(ctx context.Context) func(*tls.ClientHelloInfo)
 			return nil, err
 		}
 
-		return &tls.Config{
+		config := &tls.Config{
 			// Not all server APIs required a client certificate. Though if one
 			// is presented, verify it.
 			ClientAuth: tls.VerifyClientCertIfGiven,
@@ -304,14 +341,16 @@ func (e *Endpoints) getTLSConfig(ctx context.Context) func(*tls.ClientHelloInfo)
 			MinVersion: tls.VersionTLS12,
 
 			NextProtos: []string{http2.NextProtoTLS},
-		}, nil
+		}
+		e.TLSPolicy.Apply(config)
+		return config, nil
 	}
 }
 
 // getCerts queries the datastore and returns a TLS serving certificate(s) plus
 // the current CA root bundle.
 func (e *Endpoints) getCerts(ctx context.Context) ([]tls.Certificate, *x509.CertPool, error) {
-	bundle, err := e.DataStore.FetchBundle(dscache.WithCache(ctx), e.TrustDomain.IDString())
+	bundle, err := e.DataStore.FetchBundle(dscache.WithCache(ctx), e.TrustDomain.IDString(), datastore.TolerateStale)
 	if err != nil {
 		return nil, nil, fmt.Errorf("get bundle from datastore: %w", err)
 	}
@@ -351,5 +390,5 @@ func (e *Endpoints) getCerts(ctx context.Context) ([]tls.Certificate, *x509.Cert
 func (e *Endpoints) makeInterceptors() (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
 	log := e.Log.WithField(telemetry.SubsystemName, "api")
 
-	return middleware.Interceptors(Middleware(log, e.Metrics, e.DataStore, clock.New(), e.RateLimit, e.AuthPolicyEngine, e.AuditLogEnabled, e.AdminIDs))
+	return middleware.Interceptors(Middleware(log, e.Metrics, e.DataStore, clock.New(), e.RateLimit, e.AuthPolicyEngine, e.AuditLogEnabled, e.AuditLog, e.AdminIDs))
 }
@@ -4,9 +4,11 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"net"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -21,6 +23,8 @@ import (
 	entryv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/entry/v1"
 	svidv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/svid/v1"
 	trustdomainv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/trustdomain/v1"
+	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
+	"github.com/spiffe/spire/pkg/common/tlspolicy"
 	"github.com/spiffe/spire/pkg/server/authpolicy"
 	"github.com/spiffe/spire/pkg/server/ca"
 	"github.com/spiffe/spire/pkg/server/cache/entrycache"
@@ -43,6 +47,7 @@ import (
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 	"google.golang.org/grpc/status"
 )
 
@@ -230,6 +235,7 @@ func TestListenAndServe(t *testing.T) {
 		RateLimit:                    rateLimit,
 		EntryFetcherCacheRebuildTask: ef.RunRebuildCacheTask,
 		AuthPolicyEngine:             pe,
+		EnableReflection:             true,
 	}
 
 	// Prime the datastore with the:
@@ -309,6 +315,9 @@ func TestListenAndServe(t *testing.T) {
 	t.Run("TrustDomain", func(t *testing.T) {
 		testTrustDomainAPI(ctx, t, udsConn, noauthConn, agentConn, adminConn, downstreamConn)
 	})
+	t.Run("Reflection", func(t *testing.T) {
+		testReflection(ctx, t, udsConn, adminConn)
+	})
 
 	// Assert that the bundle endpoint server was called to listen and serve
 	require.True(t, bundleEndpointServer.Used(), "bundle server was not called to listen and serve")
@@ -324,6 +333,106 @@ func TestListenAndServe(t *testing.T) {
 	}
 }
 
+func TestMessageSizeLimits(t *testing.T) {
+	// Build a request larger than the gRPC default limit of 4 MiB
+	var entries []*types.Entry
+	for i := 0; i < 6*1024; i++ {
+		entries = append(entries, &types.Entry{
+			SpiffeId:  &types.SPIFFEID{TrustDomain: "domain.test", Path: fmt.Sprintf("/workload%d", i)},
+			Selectors: []*types.Selector{{Type: "unix", Value: strings.Repeat("x", 1024)}},
+		})
+	}
+
+	for _, tt := range []struct {
+		name               string
+		maxRecvMessageSize int
+		expectCode         codes.Code
+	}{
+		{
+			name:       "default",
+			expectCode: codes.ResourceExhausted,
+		},
+		{
+			name:               "configured",
+			maxRecvMessageSize: 8 * 1024 * 1024,
+		},
+		{
+			name:               "too small",
+			maxRecvMessageSize: 4 * 1024 * 1024,
+			expectCode:         codes.ResourceExhausted,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Endpoints{MaxRecvMessageSize: tt.maxRecvMessageSize}
+			server := e.createUDSServer(nil, nil)
+			entryv1.RegisterEntryServer(server, batchCreateEntryServer{})
+			socketPath := spiretest.ServeGRPCServerOnTempSocket(t, server)
+
+			conn, err := grpc.Dial("unix://"+socketPath, grpc.WithTransportCredentials(insecure.NewCredentials()))
+			require.NoError(t, err)
+			defer conn.Close()
+
+			resp, err := entryv1.NewEntryClient(conn).BatchCreateEntry(context.Background(), &entryv1.BatchCreateEntryRequest{
+				Entries: entries,
+			})
+			if tt.expectCode != codes.OK {
+				require.Equal(t, tt.expectCode, status.Code(err))
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, resp.Results, len(entries))
+		})
+	}
+}
+
+func TestTLSPolicy(t *testing.T) {
+	ca := testca.New(t, testTD)
+	serverSVID := ca.CreateX509SVID(serverID)
+
+	ds := fakedatastore.New(t)
+	prepareDataStore(t, ds, ca, ca.CreateX509SVID(agentID))
+
+	log, _ := test.NewNullLogger()
+	e := &Endpoints{
+		SVIDObserver: newSVIDObserver(serverSVID),
+		TrustDomain:  testTD,
+		DataStore:    ds,
+		Log:          log,
+		TLSPolicy:    tlspolicy.Policy{MinVersion: tls.VersionTLS13},
+	}
+
+	handshake := func(maxVersion uint16) error {
+		clientConn, serverConn := net.Pipe()
+		defer clientConn.Close()
+		defer serverConn.Close()
+
+		server := tls.Server(serverConn, &tls.Config{ //nolint: gosec // MinVersion is set by getTLSConfig
+			GetConfigForClient: e.getTLSConfig(context.Background()),
+		})
+		serverErrCh := make(chan error, 1)
+		go func() {
+			serverErrCh <- server.Handshake()
+			server.Close()
+		}()
+
+		clientConfig := tlsconfig.TLSClientConfig(ca.X509Bundle(), tlsconfig.AuthorizeID(serverID))
+		clientConfig.MaxVersion = maxVersion
+		clientErr := tls.Client(clientConn, clientConfig).Handshake()
+		serverErr := <-serverErrCh
+		if serverErr != nil {
+			return serverErr
+		}
+		return clientErr
+	}
+
+	err := handshake(tls.VersionTLS12)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unsupported versions")
+
+	require.NoError(t, handshake(tls.VersionTLS13))
+}
+
 func prepareDataStore(t *testing.T, ds datastore.DataStore, ca *testca.CA, agentSVID *x509svid.SVID) {
 	// Prepare the bundle
 	_, err := ds.CreateBundle(context.Background(), makeBundle(ca))
@@ -739,6 +848,53 @@ func testTrustDomainAPI(ctx context.Context, t *testing.T, udsConn, noauthConn,
 	})
 }
 
+func testReflection(ctx context.Context, t *testing.T, udsConn, adminConn *grpc.ClientConn) {
+	listServices := func(conn *grpc.ClientConn) ([]string, error) {
+		stream, err := grpc_reflection_v1alpha.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = stream.CloseSend() }()
+
+		if err := stream.Send(&grpc_reflection_v1alpha.ServerReflectionRequest{
+			MessageRequest: &grpc_reflection_v1alpha.ServerReflectionRequest_ListServices{},
+		}); err != nil {
+			return nil, err
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+
+		var services []string
+		for _, service := range resp.GetListServicesResponse().Service {
+			services = append(services, service.Name)
+		}
+		return services, nil
+	}
+
+	t.Run("UDS", func(t *testing.T) {
+		services, err := listServices(udsConn)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{
+			"grpc.health.v1.Health",
+			"grpc.reflection.v1alpha.ServerReflection",
+			"spire.api.server.agent.v1.Agent",
+			"spire.api.server.bundle.v1.Bundle",
+			"spire.api.server.debug.v1.Debug",
+			"spire.api.server.entry.v1.Entry",
+			"spire.api.server.svid.v1.SVID",
+			"spire.api.server.trustdomain.v1.TrustDomain",
+		}, services)
+	})
+
+	t.Run("TCP", func(t *testing.T) {
+		// Reflection is never registered on the TCP server
+		_, err := listServices(adminConn)
+		spiretest.RequireGRPCStatusContains(t, err, codes.Unimplemented, "unknown service grpc.reflection.v1alpha.ServerReflection")
+	})
+}
+
 // testAuthorization makes an RPC for each method on the client interface and
 // asserts that the RPC was authorized or not. If a method is not represented
 // in the expectedAuthResults, or a method in expectedAuthResults does not
@@ -812,6 +968,20 @@ func callRPC(ctx context.Context, t *testing.T, mv reflect.Value) []reflect.Valu
 	return out
 }
 
+type batchCreateEntryServer struct {
+	entryv1.UnimplementedEntryServer
+}
+
+func (batchCreateEntryServer) BatchCreateEntry(ctx context.Context, req *entryv1.BatchCreateEntryRequest) (*entryv1.BatchCreateEntryResponse, error) {
+	resp := &entryv1.BatchCreateEntryResponse{}
+	for range req.Entries {
+		resp.Results = append(resp.Results, &entryv1.BatchCreateEntryResponse_Result{
+			Status: &types.Status{},
+		})
+	}
+	return resp, nil
+}
+
 type bundleEndpointServer struct {
 	mtx  sync.Mutex
 	used bool
@@ -22,19 +22,23 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-func Middleware(log logrus.FieldLogger, metrics telemetry.Metrics, ds datastore.DataStore, clk clock.Clock, rlConf RateLimitConfig, policyEngine *authpolicy.Engine, auditLogEnabled bool, adminIDs []spiffeid.ID) middleware.Middleware {
+func Middleware(log logrus.FieldLogger, metrics telemetry.Metrics, ds datastore.DataStore, clk clock.Clock, rlConf RateLimitConfig, policyEngine *authpolicy.Engine, auditLogEnabled bool, auditLog logrus.FieldLogger, adminIDs []spiffeid.ID) middleware.Middleware {
 	chain := []middleware.Middleware{
 		middleware.WithLogger(log),
 		middleware.WithMetrics(metrics),
-		middleware.WithAuthorization(policyEngine, EntryFetcher(ds), AgentAuthorizer(log, ds, clk), adminIDs),
-		middleware.WithRateLimits(RateLimits(rlConf), metrics),
 	}
 
 	if auditLogEnabled {
-		// Add audit log with UDS tracking enabled
-		chain = append(chain, middleware.WithAuditLog(true))
+		// Add audit log with UDS tracking enabled. It goes ahead of
+		// authorization and rate limiting so rejected calls are audited too.
+		chain = append(chain, middleware.WithAuditLogSink(true, auditLog))
 	}
 
+	chain = append(chain,
+		middleware.WithAuthorization(policyEngine, EntryFetcher(ds), AgentAuthorizer(log, ds, clk), adminIDs),
+		middleware.WithRateLimits(RateLimits(rlConf), metrics),
+	)
+
 	return middleware.Chain(
 		chain...,
 	)
@@ -176,5 +180,6 @@ func RateLimits(config RateLimitConfig) map[string]api.RateLimiter {
 		"/spire.api.server.trustdomain.v1.TrustDomain/RefreshBundle":                     noLimit,
 		"/grpc.health.v1.Health/Check":                                                   noLimit,
 		"/grpc.health.v1.Health/Watch":                                                   noLimit,
+		"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo":                 noLimit,
 	}
 }
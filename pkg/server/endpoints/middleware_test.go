@@ -2,28 +2,36 @@ package endpoints
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"net"
 	"testing"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	entryv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/entry/v1"
 	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
 	"github.com/spiffe/spire/pkg/common/telemetry"
 	"github.com/spiffe/spire/pkg/server/api"
+	"github.com/spiffe/spire/pkg/server/api/entry/v1"
 	"github.com/spiffe/spire/pkg/server/api/rpccontext"
+	"github.com/spiffe/spire/pkg/server/authpolicy"
 	"github.com/spiffe/spire/pkg/server/cache/entrycache"
 	"github.com/spiffe/spire/pkg/server/datastore"
 	"github.com/spiffe/spire/proto/spire/common"
 	"github.com/spiffe/spire/test/clock"
 	"github.com/spiffe/spire/test/fakes/fakedatastore"
+	"github.com/spiffe/spire/test/fakes/fakemetrics"
 	"github.com/spiffe/spire/test/spiretest"
 	"github.com/spiffe/spire/test/testca"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 )
@@ -280,6 +288,85 @@ func TestAgentAuthorizer(t *testing.T) {
 	}
 }
 
+func TestMiddlewareAuditLog(t *testing.T) {
+	ctx := context.Background()
+	ca := testca.New(t, testTD)
+	ds := fakedatastore.New(t)
+	log, logHook := test.NewNullLogger()
+	auditLog, auditHook := test.NewNullLogger()
+
+	policyEngine, err := authpolicy.DefaultAuthPolicy(ctx)
+	require.NoError(t, err)
+
+	m := Middleware(log, fakemetrics.New(), ds, clock.NewMock(t), rateLimit, policyEngine, true, auditLog, []spiffeid.ID{adminID})
+	service := entry.New(entry.Config{
+		TrustDomain: testTD,
+		DataStore:   ds,
+	})
+
+	const method = "/spire.api.server.entry.v1.Entry/BatchCreateEntry"
+	req := &entryv1.BatchCreateEntryRequest{
+		Entries: []*types.Entry{
+			{
+				ParentId:  &types.SPIFFEID{TrustDomain: testTD.String(), Path: "/parent"},
+				SpiffeId:  &types.SPIFFEID{TrustDomain: testTD.String(), Path: "/workload"},
+				Selectors: []*types.Selector{{Type: "unix", Value: "uid:1000"}},
+			},
+		},
+	}
+
+	batchCreateEntry := func(callerID spiffeid.ID) {
+		ctx := peer.NewContext(ctx, &peer.Peer{
+			Addr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 443},
+			AuthInfo: credentials.TLSInfo{
+				State: tls.ConnectionState{
+					HandshakeComplete: true,
+					PeerCertificates:  ca.CreateX509SVID(callerID).Certificates,
+				},
+			},
+		})
+
+		ctx, err := m.Preprocess(ctx, method, req)
+		if err != nil {
+			return
+		}
+		_, err = service.BatchCreateEntry(ctx, req)
+		m.Postprocess(ctx, method, true, err)
+	}
+
+	t.Run("entry created", func(t *testing.T) {
+		auditHook.Reset()
+		batchCreateEntry(adminID)
+
+		require.Len(t, auditHook.AllEntries(), 1)
+		record := auditHook.LastEntry()
+		assert.Equal(t, "API accessed", record.Message)
+		assert.Equal(t, "audit", record.Data[telemetry.Type])
+		assert.Equal(t, "success", record.Data[telemetry.Status])
+		assert.Equal(t, adminID.String(), record.Data[telemetry.CallerID])
+		assert.Equal(t, "BatchCreateEntry", record.Data[telemetry.Method])
+		assert.Equal(t, "spiffe://domain.test/workload", record.Data[telemetry.SPIFFEID])
+		assert.Equal(t, "spiffe://domain.test/parent", record.Data[telemetry.ParentID])
+	})
+
+	t.Run("access denied", func(t *testing.T) {
+		auditHook.Reset()
+		batchCreateEntry(spiffeid.RequireFromPath(testTD, "/workload"))
+
+		require.Len(t, auditHook.AllEntries(), 1)
+		record := auditHook.LastEntry()
+		assert.Equal(t, "error", record.Data[telemetry.Status])
+		assert.Equal(t, codes.PermissionDenied, record.Data[telemetry.StatusCode])
+		assert.Equal(t, "spiffe://domain.test/workload", record.Data[telemetry.CallerID])
+		assert.Equal(t, "BatchCreateEntry", record.Data[telemetry.Method])
+	})
+
+	// Audit records only go to the audit log
+	for _, entry := range logHook.AllEntries() {
+		assert.NotEqual(t, "audit", entry.Data[telemetry.Type])
+	}
+}
+
 func createEntry(t testing.TB, ds datastore.DataStore, entryIn *common.RegistrationEntry) *types.Entry {
 	registrationEntry, err := ds.CreateRegistrationEntry(context.Background(), entryIn)
 	require.NoError(t, err)
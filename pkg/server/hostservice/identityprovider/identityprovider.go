@@ -97,7 +97,7 @@ func (v1 *identityProviderV1) FetchX509Identity(ctx context.Context, req *identi
 		return nil, err
 	}
 
-	bundle, err := deps.DataStore.FetchBundle(ctx, v1.s.config.TrustDomain.IDString())
+	bundle, err := deps.DataStore.FetchBundle(ctx, v1.s.config.TrustDomain.IDString(), datastore.RequireCurrent)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,564 @@
+package hashicorpvault
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl"
+	keymanagerv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/server/keymanager/v1"
+	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
+	"github.com/spiffe/spire/pkg/common/catalog"
+	"github.com/spiffe/spire/pkg/common/diskutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	pluginName = "hashicorp_vault"
+
+	keyNamePrefix = "spire-"
+
+	keyIDTag   = "key_id"
+	keyNameTag = "key_name"
+	reasonTag  = "reason"
+)
+
+func BuiltIn() catalog.BuiltIn {
+	return builtin(New())
+}
+
+func builtin(p *Plugin) catalog.BuiltIn {
+	return catalog.MakeBuiltIn(pluginName,
+		keymanagerv1.KeyManagerPluginServer(p),
+		configv1.ConfigServiceServer(p),
+	)
+}
+
+// Config provides configuration context for the plugin
+type Config struct {
+	// A URL of Vault server. (e.g., https://vault.example.com:8443/)
+	VaultAddr string `hcl:"vault_addr" json:"vault_addr"`
+	// Name of the Vault namespace
+	Namespace string `hcl:"namespace" json:"namespace"`
+	// Path where the Transit secrets engine is mounted. (e.g., /<transit_engine_path>/keys/<name>)
+	TransitEnginePath string `hcl:"transit_engine_path" json:"transit_engine_path"`
+	// Path to a CA certificate file that the client verifies the server certificate.
+	// Only PEM format is supported.
+	CACertPath string `hcl:"ca_cert_path" json:"ca_cert_path"`
+	// If true, vault client accepts any server certificates.
+	// It should be used only test environment so on.
+	InsecureSkipVerify bool `hcl:"insecure_skip_verify" json:"insecure_skip_verify"`
+	// Path to the file where the mapping of SPIRE key IDs to Vault key names is persisted
+	KeyMetadataFile string `hcl:"key_metadata_file" json:"key_metadata_file"`
+	// Number of times a request is retried while Vault is sealed or otherwise unavailable
+	MaxRetries *int `hcl:"max_retries" json:"max_retries"`
+	// Configuration for the Token authentication method
+	TokenAuth *TokenAuthConfig `hcl:"token_auth" json:"token_auth,omitempty"`
+	// Configuration for the Kubernetes authentication method
+	K8sAuth *K8sAuthConfig `hcl:"k8s_auth" json:"k8s_auth,omitempty"`
+}
+
+// TokenAuthConfig represents parameters for token auth method
+type TokenAuthConfig struct {
+	// Token string to set into "X-Vault-Token" header
+	Token string `hcl:"token" json:"token"`
+}
+
+// K8sAuthConfig represents parameters for Kubernetes auth method.
+type K8sAuthConfig struct {
+	// Name of the mount point where Kubernetes auth method is mounted. (e.g., /auth/<mount_point>/login)
+	// If the value is empty, use default mount point (/auth/kubernetes)
+	K8sAuthMountPoint string `hcl:"k8s_auth_mount_point" json:"k8s_auth_mount_point"`
+	// Name of the Vault role.
+	// The plugin authenticates against the named role.
+	K8sAuthRoleName string `hcl:"k8s_auth_role_name" json:"k8s_auth_role_name"`
+	// Path to the Kubernetes Service Account Token to use authentication with the Vault.
+	TokenPath string `hcl:"token_path" json:"token_path"`
+}
+
+// keyMetadata is persisted in the key metadata file
+type keyMetadata struct {
+	// Keys maps SPIRE key IDs to the name of the key in Vault
+	Keys map[string]string `json:"keys"`
+}
+
+type keyEntry struct {
+	KeyName   string
+	PublicKey *keymanagerv1.PublicKey
+}
+
+// Plugin is the main representation of this keymanager plugin
+type Plugin struct {
+	keymanagerv1.UnsafeKeyManagerServer
+	configv1.UnsafeConfigServer
+
+	log     hclog.Logger
+	mu      sync.RWMutex
+	entries map[string]keyEntry
+
+	keyMetadataFile string
+	authMethod      AuthMethod
+	cp              *ClientParams
+
+	vcMu sync.Mutex
+	vc   *Client
+
+	hooks struct {
+		lookupEnv  func(string) (string, bool)
+		newKeyName func() (string, error)
+		// retryWait overrides the wait between retries; just for testing
+		retryWait time.Duration
+	}
+}
+
+// New returns an instantiated plugin
+func New() *Plugin {
+	p := &Plugin{
+		entries: make(map[string]keyEntry),
+	}
+	p.hooks.lookupEnv = os.LookupEnv
+	p.hooks.newKeyName = newKeyName
+	return p
+}
+
+// SetLogger sets a logger
+func (p *Plugin) SetLogger(log hclog.Logger) {
+	p.log = log
+}
+
+// Configure sets up the plugin
+func (p *Plugin) Configure(ctx context.Context, req *configv1.ConfigureRequest) (*configv1.ConfigureResponse, error) {
+	config := new(Config)
+	if err := hcl.Decode(config, req.HclConfiguration); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unable to decode configuration: %v", err)
+	}
+
+	if config.KeyMetadataFile == "" {
+		return nil, status.Error(codes.InvalidArgument, "configuration is missing the key metadata file path")
+	}
+
+	am, err := parseAuthMethod(config)
+	if err != nil {
+		return nil, err
+	}
+	cp, err := p.genClientParams(am, config)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := loadKeyMetadata(config.KeyMetadataFile)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.vcMu.Lock()
+	p.authMethod = am
+	p.cp = cp
+	p.vc = nil
+	p.vcMu.Unlock()
+
+	vc, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]keyEntry)
+	for spireKeyID, keyName := range metadata.Keys {
+		log := p.log.With(keyIDTag, spireKeyID, keyNameTag, keyName)
+		keyType, pkixData, err := vc.GetPublicKey(keyName)
+		switch status.Code(err) {
+		case codes.OK:
+		case codes.NotFound:
+			log.Warn("Key not found in Vault; it will be regenerated when needed")
+			continue
+		default:
+			return nil, p.handleClientError(err)
+		}
+
+		entries[spireKeyID] = makeKeyEntry(spireKeyID, keyName, keyType, pkixData)
+		log.Debug("Key loaded")
+	}
+
+	p.entries = entries
+	p.keyMetadataFile = config.KeyMetadataFile
+
+	return &configv1.ConfigureResponse{}, nil
+}
+
+// GenerateKey creates a key in Vault. If a key already exists for the SPIRE
+// key ID, it is replaced and the previous key is deleted from Vault.
+func (p *Plugin) GenerateKey(ctx context.Context, req *keymanagerv1.GenerateKeyRequest) (*keymanagerv1.GenerateKeyResponse, error) {
+	if req.KeyId == "" {
+		return nil, status.Error(codes.InvalidArgument, "key id is required")
+	}
+	if req.KeyType == keymanagerv1.KeyType_UNSPECIFIED_KEY_TYPE {
+		return nil, status.Error(codes.InvalidArgument, "key type is required")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	vc, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	keyName, err := p.hooks.newKeyName()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to generate key name: %v", err)
+	}
+
+	if err := vc.CreateKey(keyName, req.KeyType); err != nil {
+		return nil, p.handleClientError(err)
+	}
+	p.log.Debug("Key created", keyIDTag, req.KeyId, keyNameTag, keyName)
+
+	keyType, pkixData, err := vc.GetPublicKey(keyName)
+	if err != nil {
+		return nil, p.handleClientError(err)
+	}
+
+	newEntry := makeKeyEntry(req.KeyId, keyName, keyType, pkixData)
+	oldEntry, hasOldEntry := p.entries[req.KeyId]
+
+	entries := make(map[string]keyEntry, len(p.entries)+1)
+	for id, entry := range p.entries {
+		entries[id] = entry
+	}
+	entries[req.KeyId] = newEntry
+	if err := writeKeyMetadata(p.keyMetadataFile, entries); err != nil {
+		return nil, err
+	}
+	p.entries = entries
+
+	if hasOldEntry {
+		if err := vc.DeleteKey(oldEntry.KeyName); err != nil {
+			p.log.Warn("Failed to delete replaced key", keyIDTag, req.KeyId, keyNameTag, oldEntry.KeyName, reasonTag, err)
+		} else {
+			p.log.Debug("Replaced key deleted", keyIDTag, req.KeyId, keyNameTag, oldEntry.KeyName)
+		}
+	}
+
+	return &keymanagerv1.GenerateKeyResponse{
+		PublicKey: newEntry.PublicKey,
+	}, nil
+}
+
+// SignData creates a digital signature for the data to be signed
+func (p *Plugin) SignData(ctx context.Context, req *keymanagerv1.SignDataRequest) (*keymanagerv1.SignDataResponse, error) {
+	if req.KeyId == "" {
+		return nil, status.Error(codes.InvalidArgument, "key id is required")
+	}
+	if req.SignerOpts == nil {
+		return nil, status.Error(codes.InvalidArgument, "signer opts is required")
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	entry, ok := p.entries[req.KeyId]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "key %q not found", req.KeyId)
+	}
+
+	hashAlgo, signatureAlgo, saltLength, err := signingParamsForTransit(entry.PublicKey.Type, req.SignerOpts)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	vc, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := vc.SignData(entry.KeyName, req.Data, hashAlgo, signatureAlgo, saltLength)
+	if err != nil {
+		return nil, p.handleClientError(err)
+	}
+
+	return &keymanagerv1.SignDataResponse{
+		Signature:      signature,
+		KeyFingerprint: entry.PublicKey.Fingerprint,
+	}, nil
+}
+
+// GetPublicKey returns the public key for a given key
+func (p *Plugin) GetPublicKey(ctx context.Context, req *keymanagerv1.GetPublicKeyRequest) (*keymanagerv1.GetPublicKeyResponse, error) {
+	if req.KeyId == "" {
+		return nil, status.Error(codes.InvalidArgument, "key id is required")
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	entry, ok := p.entries[req.KeyId]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "key %q not found", req.KeyId)
+	}
+
+	return &keymanagerv1.GetPublicKeyResponse{
+		PublicKey: entry.PublicKey,
+	}, nil
+}
+
+// GetPublicKeys return the publicKey for all the keys
+func (p *Plugin) GetPublicKeys(context.Context, *keymanagerv1.GetPublicKeysRequest) (*keymanagerv1.GetPublicKeysResponse, error) {
+	var keys []*keymanagerv1.PublicKey
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, entry := range p.entries {
+		keys = append(keys, entry.PublicKey)
+	}
+
+	return &keymanagerv1.GetPublicKeysResponse{PublicKeys: keys}, nil
+}
+
+// getClient returns an authenticated Vault client, authenticating if there
+// is no client or the token of the previous one could not be renewed.
+func (p *Plugin) getClient() (*Client, error) {
+	p.vcMu.Lock()
+	defer p.vcMu.Unlock()
+
+	if p.cp == nil {
+		return nil, status.Error(codes.FailedPrecondition, "plugin not configured")
+	}
+	if p.vc != nil {
+		return p.vc, nil
+	}
+
+	renewCh := make(chan struct{})
+	vc, err := NewAuthenticatedClient(p.cp, p.authMethod, renewCh, p.log)
+	if err != nil {
+		st := status.Convert(err)
+		return nil, status.Errorf(st.Code(), "failed to prepare authenticated client: %s", st.Message())
+	}
+	p.vc = vc
+
+	// if renewCh has been closed, the token can not be renewed and may expire,
+	// it needs to re-authenticate to the Vault.
+	go func() {
+		<-renewCh
+		p.resetClient(vc)
+		p.log.Debug("Going to re-authenticate to the Vault at the next request")
+	}()
+
+	return vc, nil
+}
+
+// resetClient drops the given client so the next request re-authenticates.
+func (p *Plugin) resetClient(vc *Client) {
+	p.vcMu.Lock()
+	defer p.vcMu.Unlock()
+	if p.vc == vc {
+		p.vc = nil
+	}
+}
+
+// handleClientError drops the current client when Vault rejects its token,
+// e.g. because it was revoked, so that the next request re-authenticates.
+func (p *Plugin) handleClientError(err error) error {
+	if status.Code(err) == codes.PermissionDenied {
+		p.vcMu.Lock()
+		p.vc = nil
+		p.vcMu.Unlock()
+	}
+	return err
+}
+
+func (p *Plugin) genClientParams(method AuthMethod, config *Config) (*ClientParams, error) {
+	cp := &ClientParams{
+		VaultAddr:         p.getEnvOrDefault(envVaultAddr, config.VaultAddr),
+		Namespace:         p.getEnvOrDefault(envVaultNamespace, config.Namespace),
+		TransitEnginePath: config.TransitEnginePath,
+		CACertPath:        p.getEnvOrDefault(envVaultCACert, config.CACertPath),
+		TLSSkipVerify:     config.InsecureSkipVerify,
+		MaxRetries:        defaultMaxRetries,
+		MinRetryWait:      p.hooks.retryWait,
+		MaxRetryWait:      p.hooks.retryWait,
+	}
+	if cp.VaultAddr == "" {
+		return nil, status.Error(codes.InvalidArgument, "configuration is missing the Vault address")
+	}
+	if cp.TransitEnginePath == "" {
+		cp.TransitEnginePath = defaultTransitEnginePath
+	}
+	if config.MaxRetries != nil {
+		if *config.MaxRetries < 0 {
+			return nil, status.Error(codes.InvalidArgument, "max_retries must not be negative")
+		}
+		cp.MaxRetries = *config.MaxRetries
+	}
+
+	switch method {
+	case TOKEN:
+		cp.Token = p.getEnvOrDefault(envVaultToken, config.TokenAuth.Token)
+		if cp.Token == "" {
+			return nil, status.Error(codes.InvalidArgument, "token is required")
+		}
+	case K8S:
+		if config.K8sAuth.K8sAuthRoleName == "" {
+			return nil, status.Error(codes.InvalidArgument, "k8s_auth_role_name is required")
+		}
+		if config.K8sAuth.TokenPath == "" {
+			return nil, status.Error(codes.InvalidArgument, "token_path is required")
+		}
+		cp.K8sAuthMountPoint = config.K8sAuth.K8sAuthMountPoint
+		if cp.K8sAuthMountPoint == "" {
+			cp.K8sAuthMountPoint = defaultK8sMountPoint
+		}
+		cp.K8sAuthRoleName = config.K8sAuth.K8sAuthRoleName
+		cp.K8sAuthTokenPath = config.K8sAuth.TokenPath
+	}
+
+	return cp, nil
+}
+
+func (p *Plugin) getEnvOrDefault(envKey, fallback string) string {
+	if value, ok := p.hooks.lookupEnv(envKey); ok {
+		return value
+	}
+	return fallback
+}
+
+func parseAuthMethod(config *Config) (AuthMethod, error) {
+	switch {
+	case config.TokenAuth != nil && config.K8sAuth != nil:
+		return 0, status.Error(codes.InvalidArgument, "only one authentication method can be configured")
+	case config.TokenAuth != nil:
+		return TOKEN, nil
+	case config.K8sAuth != nil:
+		return K8S, nil
+	default:
+		return 0, status.Error(codes.InvalidArgument, "must be configured one of these authentication method 'Token or Kubernetes'")
+	}
+}
+
+func loadKeyMetadata(path string) (*keyMetadata, error) {
+	metadata := new(keyMetadata)
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return metadata, nil
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "failed to read key metadata file: %v", err)
+	}
+
+	if err := json.Unmarshal(data, metadata); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to parse key metadata file: %v", err)
+	}
+	return metadata, nil
+}
+
+func writeKeyMetadata(path string, entries map[string]keyEntry) error {
+	metadata := &keyMetadata{
+		Keys: make(map[string]string, len(entries)),
+	}
+	for spireKeyID, entry := range entries {
+		metadata.Keys[spireKeyID] = entry.KeyName
+	}
+
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to marshal key metadata: %v", err)
+	}
+
+	if err := diskutil.AtomicWriteFile(path, data, 0600); err != nil {
+		return status.Errorf(codes.Internal, "failed to write key metadata file: %v", err)
+	}
+	return nil
+}
+
+func makeKeyEntry(spireKeyID, keyName string, keyType keymanagerv1.KeyType, pkixData []byte) keyEntry {
+	return keyEntry{
+		KeyName: keyName,
+		PublicKey: &keymanagerv1.PublicKey{
+			Id:          spireKeyID,
+			Type:        keyType,
+			PkixData:    pkixData,
+			Fingerprint: makeFingerprint(pkixData),
+		},
+	}
+}
+
+func signingParamsForTransit(keyType keymanagerv1.KeyType, signerOpts interface{}) (hashAlgo, signatureAlgo, saltLength string, err error) {
+	var (
+		hashAlgorithm keymanagerv1.HashAlgorithm
+		pssOptions    *keymanagerv1.SignDataRequest_PSSOptions
+	)
+
+	switch opts := signerOpts.(type) {
+	case *keymanagerv1.SignDataRequest_HashAlgorithm:
+		hashAlgorithm = opts.HashAlgorithm
+	case *keymanagerv1.SignDataRequest_PssOptions:
+		if opts.PssOptions == nil {
+			return "", "", "", errors.New("PSS options are required")
+		}
+		hashAlgorithm = opts.PssOptions.HashAlgorithm
+		pssOptions = opts.PssOptions
+	default:
+		return "", "", "", fmt.Errorf("unsupported signer opts type %T", opts)
+	}
+
+	switch hashAlgorithm {
+	case keymanagerv1.HashAlgorithm_UNSPECIFIED_HASH_ALGORITHM:
+		return "", "", "", errors.New("hash algorithm is required")
+	case keymanagerv1.HashAlgorithm_SHA256:
+		hashAlgo = "sha2-256"
+	case keymanagerv1.HashAlgorithm_SHA384:
+		hashAlgo = "sha2-384"
+	case keymanagerv1.HashAlgorithm_SHA512:
+		hashAlgo = "sha2-512"
+	default:
+		return "", "", "", fmt.Errorf("unsupported hash algorithm: %v", hashAlgorithm)
+	}
+
+	switch keyType {
+	case keymanagerv1.KeyType_EC_P256, keymanagerv1.KeyType_EC_P384:
+		if pssOptions != nil {
+			return "", "", "", fmt.Errorf("PSS options are not supported for key type %v", keyType)
+		}
+		return hashAlgo, "", "", nil
+	case keymanagerv1.KeyType_RSA_2048, keymanagerv1.KeyType_RSA_4096:
+		if pssOptions == nil {
+			return hashAlgo, "pkcs1v15", "", nil
+		}
+		switch pssOptions.SaltLength {
+		case rsa.PSSSaltLengthAuto:
+			saltLength = "auto"
+		case rsa.PSSSaltLengthEqualsHash:
+			saltLength = "hash"
+		default:
+			saltLength = strconv.Itoa(int(pssOptions.SaltLength))
+		}
+		return hashAlgo, "pss", saltLength, nil
+	default:
+		return "", "", "", fmt.Errorf("unsupported key type: %v", keyType)
+	}
+}
+
+func newKeyName() (string, error) {
+	u, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+	return keyNamePrefix + u.String(), nil
+}
+
+func makeFingerprint(pkixData []byte) string {
+	s := sha256.Sum256(pkixData)
+	return hex.EncodeToString(s[:])
+}
@@ -0,0 +1,357 @@
+package hashicorpvault
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiffe/spire/pkg/server/plugin/keymanager"
+	keymanagertest "github.com/spiffe/spire/pkg/server/plugin/keymanager/test"
+	"github.com/spiffe/spire/test/plugintest"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	ctx = context.Background()
+)
+
+func TestKeyManagerContract(t *testing.T) {
+	create := func(t *testing.T) keymanager.KeyManager {
+		_, addr := newFakeVaultServer(t)
+		km, _ := loadPlugin(t, newTestPlugin(), tokenAuthConfig(addr, filepath.Join(spiretest.TempDir(t), "metadata.json")))
+		return km
+	}
+
+	keymanagertest.Test(t, keymanagertest.Config{
+		Create: create,
+	})
+}
+
+func TestConfigure(t *testing.T) {
+	_, addr := newFakeVaultServer(t)
+	dir := spiretest.TempDir(t)
+	metadataFile := filepath.Join(dir, "metadata.json")
+	k8sTokenFile := filepath.Join(dir, "k8s-token")
+	require.NoError(t, os.WriteFile(k8sTokenFile, []byte(fakeK8sJWT), 0600))
+
+	for _, tt := range []struct {
+		name    string
+		config  string
+		env     map[string]string
+		expCode codes.Code
+		expMsg  string
+	}{
+		{
+			name:   "token auth",
+			config: tokenAuthConfig(addr, metadataFile),
+		},
+		{
+			name: "kubernetes auth",
+			config: fmt.Sprintf(`
+				vault_addr = %q
+				key_metadata_file = %q
+				k8s_auth {
+					k8s_auth_role_name = %q
+					token_path = %q
+				}`, addr, metadataFile, fakeK8sRole, k8sTokenFile),
+		},
+		{
+			name: "vault address and token from environment",
+			config: fmt.Sprintf(`
+				key_metadata_file = %q
+				token_auth {}`, metadataFile),
+			env: map[string]string{
+				envVaultAddr:  addr,
+				envVaultToken: fakeToken,
+			},
+		},
+		{
+			name:    "malformed configuration",
+			config:  "{{",
+			expCode: codes.InvalidArgument,
+			expMsg:  "unable to decode configuration",
+		},
+		{
+			name: "missing key metadata file",
+			config: fmt.Sprintf(`
+				vault_addr = %q
+				token_auth { token = %q }`, addr, fakeToken),
+			expCode: codes.InvalidArgument,
+			expMsg:  "configuration is missing the key metadata file path",
+		},
+		{
+			name: "missing vault address",
+			config: fmt.Sprintf(`
+				key_metadata_file = %q
+				token_auth { token = %q }`, metadataFile, fakeToken),
+			expCode: codes.InvalidArgument,
+			expMsg:  "configuration is missing the Vault address",
+		},
+		{
+			name: "no authentication method",
+			config: fmt.Sprintf(`
+				vault_addr = %q
+				key_metadata_file = %q`, addr, metadataFile),
+			expCode: codes.InvalidArgument,
+			expMsg:  "must be configured one of these authentication method 'Token or Kubernetes'",
+		},
+		{
+			name: "multiple authentication methods",
+			config: fmt.Sprintf(`
+				vault_addr = %q
+				key_metadata_file = %q
+				token_auth { token = %q }
+				k8s_auth {
+					k8s_auth_role_name = %q
+					token_path = %q
+				}`, addr, metadataFile, fakeToken, fakeK8sRole, k8sTokenFile),
+			expCode: codes.InvalidArgument,
+			expMsg:  "only one authentication method can be configured",
+		},
+		{
+			name: "missing token",
+			config: fmt.Sprintf(`
+				vault_addr = %q
+				key_metadata_file = %q
+				token_auth {}`, addr, metadataFile),
+			expCode: codes.InvalidArgument,
+			expMsg:  "token is required",
+		},
+		{
+			name: "missing kubernetes role",
+			config: fmt.Sprintf(`
+				vault_addr = %q
+				key_metadata_file = %q
+				k8s_auth { token_path = %q }`, addr, metadataFile, k8sTokenFile),
+			expCode: codes.InvalidArgument,
+			expMsg:  "k8s_auth_role_name is required",
+		},
+		{
+			name: "missing kubernetes token path",
+			config: fmt.Sprintf(`
+				vault_addr = %q
+				key_metadata_file = %q
+				k8s_auth { k8s_auth_role_name = %q }`, addr, metadataFile, fakeK8sRole),
+			expCode: codes.InvalidArgument,
+			expMsg:  "token_path is required",
+		},
+		{
+			name: "negative max retries",
+			config: fmt.Sprintf(`
+				vault_addr = %q
+				key_metadata_file = %q
+				max_retries = -1
+				token_auth { token = %q }`, addr, metadataFile, fakeToken),
+			expCode: codes.InvalidArgument,
+			expMsg:  "max_retries must not be negative",
+		},
+		{
+			name: "invalid token",
+			config: fmt.Sprintf(`
+				vault_addr = %q
+				key_metadata_file = %q
+				token_auth { token = "bad-token" }`, addr, metadataFile),
+			expCode: codes.PermissionDenied,
+			expMsg:  "failed to prepare authenticated client: token lookup failed",
+		},
+		{
+			name: "invalid kubernetes role",
+			config: fmt.Sprintf(`
+				vault_addr = %q
+				key_metadata_file = %q
+				k8s_auth {
+					k8s_auth_role_name = "bad-role"
+					token_path = %q
+				}`, addr, metadataFile, k8sTokenFile),
+			expCode: codes.Unauthenticated,
+			expMsg:  "failed to prepare authenticated client: authentication failed auth/kubernetes/login",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPlugin()
+			p.hooks.lookupEnv = func(key string) (string, bool) {
+				value, ok := tt.env[key]
+				return value, ok
+			}
+
+			var err error
+			plugintest.Load(t, builtin(p), new(keymanager.V1),
+				plugintest.Configure(tt.config),
+				plugintest.CaptureConfigureError(&err),
+			)
+			if tt.expCode != codes.OK {
+				spiretest.RequireGRPCStatusContains(t, err, tt.expCode, tt.expMsg)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestKeysArePersisted(t *testing.T) {
+	fakeVault, addr := newFakeVaultServer(t)
+	metadataFile := filepath.Join(spiretest.TempDir(t), "metadata.json")
+	config := tokenAuthConfig(addr, metadataFile)
+
+	km, _ := loadPlugin(t, newTestPlugin(), config)
+	key, err := km.GenerateKey(ctx, "x509-CA-A", keymanager.ECP256)
+	require.NoError(t, err)
+	_, err = km.GenerateKey(ctx, "JWT-Signer-A", keymanager.RSA2048)
+	require.NoError(t, err)
+
+	metadata := readKeyMetadata(t, metadataFile)
+	require.Len(t, metadata.Keys, 2)
+	require.True(t, fakeVault.hasKey(metadata.Keys["x509-CA-A"]))
+	require.True(t, fakeVault.hasKey(metadata.Keys["JWT-Signer-A"]))
+
+	// Keys are loaded from Vault when a new plugin is configured with the
+	// same metadata file.
+	km, _ = loadPlugin(t, newTestPlugin(), config)
+	reloaded, err := km.GetKey(ctx, "x509-CA-A")
+	require.NoError(t, err)
+	require.Equal(t, key.Public(), reloaded.Public())
+	requireSign(t, reloaded)
+
+	// Keys missing from Vault are dropped
+	fakeVault.deleteKey(metadata.Keys["JWT-Signer-A"])
+	km, logHook := loadPlugin(t, newTestPlugin(), config)
+	_, err = km.GetKey(ctx, "JWT-Signer-A")
+	spiretest.RequireGRPCStatusContains(t, err, codes.NotFound, `key "JWT-Signer-A" not found`)
+	spiretest.AssertLogsContainEntries(t, logHook.AllEntries(), []spiretest.LogEntry{
+		{
+			Level:   logrus.WarnLevel,
+			Message: "Key not found in Vault; it will be regenerated when needed",
+			Data: logrus.Fields{
+				keyIDTag:   "JWT-Signer-A",
+				keyNameTag: metadata.Keys["JWT-Signer-A"],
+			},
+		},
+	})
+}
+
+func TestGenerateKeyReplacesKey(t *testing.T) {
+	fakeVault, addr := newFakeVaultServer(t)
+	metadataFile := filepath.Join(spiretest.TempDir(t), "metadata.json")
+	km, _ := loadPlugin(t, newTestPlugin(), tokenAuthConfig(addr, metadataFile))
+
+	_, err := km.GenerateKey(ctx, "x509-CA-A", keymanager.ECP256)
+	require.NoError(t, err)
+	oldKeyName := readKeyMetadata(t, metadataFile).Keys["x509-CA-A"]
+
+	_, err = km.GenerateKey(ctx, "x509-CA-A", keymanager.ECP384)
+	require.NoError(t, err)
+	newKeyName := readKeyMetadata(t, metadataFile).Keys["x509-CA-A"]
+
+	require.NotEqual(t, oldKeyName, newKeyName)
+	require.False(t, fakeVault.hasKey(oldKeyName), "replaced key was not deleted")
+	require.True(t, fakeVault.hasKey(newKeyName))
+}
+
+func TestVaultUnavailable(t *testing.T) {
+	fakeVault, addr := newFakeVaultServer(t)
+	metadataFile := filepath.Join(spiretest.TempDir(t), "metadata.json")
+	km, _ := loadPlugin(t, newTestPlugin(), fmt.Sprintf(`
+		vault_addr = %q
+		key_metadata_file = %q
+		max_retries = 2
+		token_auth { token = %q }`, addr, metadataFile, fakeToken))
+
+	key, err := km.GenerateKey(ctx, "x509-CA-A", keymanager.ECP256)
+	require.NoError(t, err)
+
+	// Requests are retried while Vault is sealed
+	fakeVault.setUnavailable(2)
+	requireSign(t, key)
+
+	// Once the retries are exhausted, the error is reported as unavailable
+	fakeVault.setUnavailable(3)
+	digest := sha256.Sum256([]byte("DATA"))
+	_, err = key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	spiretest.RequireGRPCStatusContains(t, err, codes.Unavailable, "failed to sign")
+
+	// The plugin recovers once Vault is available again
+	requireSign(t, key)
+}
+
+func TestReauthenticatesWhenTokenIsRevoked(t *testing.T) {
+	fakeVault, addr := newFakeVaultServer(t)
+	dir := spiretest.TempDir(t)
+	k8sTokenFile := filepath.Join(dir, "k8s-token")
+	require.NoError(t, os.WriteFile(k8sTokenFile, []byte(fakeK8sJWT), 0600))
+
+	km, _ := loadPlugin(t, newTestPlugin(), fmt.Sprintf(`
+		vault_addr = %q
+		key_metadata_file = %q
+		k8s_auth {
+			k8s_auth_role_name = %q
+			token_path = %q
+		}`, addr, filepath.Join(dir, "metadata.json"), fakeK8sRole, k8sTokenFile))
+
+	key, err := km.GenerateKey(ctx, "x509-CA-A", keymanager.ECP256)
+	require.NoError(t, err)
+	assert.Equal(t, 1, fakeVault.k8sLogins)
+
+	// Revoke the token issued to the plugin
+	fakeVault.setToken("new-token")
+
+	digest := sha256.Sum256([]byte("DATA"))
+	_, err = key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	spiretest.RequireGRPCStatusContains(t, err, codes.PermissionDenied, "failed to sign")
+
+	// The next request logs in again and succeeds
+	requireSign(t, key)
+	assert.Equal(t, 2, fakeVault.k8sLogins)
+}
+
+func newTestPlugin() *Plugin {
+	p := New()
+	p.hooks.lookupEnv = func(string) (string, bool) { return "", false }
+	p.hooks.retryWait = time.Millisecond
+	return p
+}
+
+func loadPlugin(t *testing.T, p *Plugin, config string) (keymanager.KeyManager, *test.Hook) {
+	log, logHook := test.NewNullLogger()
+	log.Level = logrus.DebugLevel
+
+	km := new(keymanager.V1)
+	plugintest.Load(t, builtin(p), km,
+		plugintest.Log(log),
+		plugintest.Configure(config),
+	)
+	return km, logHook
+}
+
+func tokenAuthConfig(addr, metadataFile string) string {
+	return fmt.Sprintf(`
+		vault_addr = %q
+		key_metadata_file = %q
+		token_auth { token = %q }`, addr, metadataFile, fakeToken)
+}
+
+func readKeyMetadata(t *testing.T, path string) *keyMetadata {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	metadata := new(keyMetadata)
+	require.NoError(t, json.Unmarshal(data, metadata))
+	return metadata
+}
+
+func requireSign(t *testing.T, key keymanager.Key) {
+	digest := sha256.Sum256([]byte("DATA"))
+	_, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+}
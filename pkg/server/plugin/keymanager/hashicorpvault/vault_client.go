@@ -0,0 +1,444 @@
+package hashicorpvault
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	vapi "github.com/hashicorp/vault/api"
+	keymanagerv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/server/keymanager/v1"
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	envVaultAddr      = "VAULT_ADDR"
+	envVaultToken     = "VAULT_TOKEN"
+	envVaultCACert    = "VAULT_CACERT"
+	envVaultNamespace = "VAULT_NAMESPACE"
+
+	defaultTransitEnginePath = "transit"
+	defaultK8sMountPoint     = "kubernetes"
+	defaultMaxRetries        = 5
+)
+
+type AuthMethod int
+
+const (
+	_ AuthMethod = iota
+	TOKEN
+	K8S
+)
+
+// ClientParams represents configuration parameters for the Vault client
+type ClientParams struct {
+	// A URL of Vault server. (e.g., https://vault.example.com:8443/)
+	VaultAddr string
+	// Name of the Vault namespace
+	Namespace string
+	// Path where the Transit secrets engine is mounted. (e.g., /<transit_engine_path>/keys/<name>)
+	TransitEnginePath string
+	// Path to a CA certificate file to be used when client verifies a server certificate
+	CACertPath string
+	// If true, client accepts any certificates.
+	// It should be used only test environment so on.
+	TLSSkipVerify bool
+	// MaxRetries controls the number of times a request is retried when
+	// Vault is unavailable (e.g. sealed) or returns a server error.
+	MaxRetries int
+	// MinRetryWait and MaxRetryWait bound the wait between retries.
+	// If zero, the defaults in hashicorp/vault/api are used.
+	MinRetryWait time.Duration
+	MaxRetryWait time.Duration
+	// Token string to use when auth method is 'token'
+	Token string
+	// Name of the mount point where Kubernetes auth method is mounted. (e.g., /auth/<mount_point>/login)
+	K8sAuthMountPoint string
+	// Name of the Vault role.
+	// The plugin authenticates against the named role.
+	K8sAuthRoleName string
+	// Path to a K8s Service Account Token to be used when auth method is 'k8s'
+	K8sAuthTokenPath string
+}
+
+// Client wraps a Vault client authenticated to use the Transit secrets engine
+type Client struct {
+	vaultClient  *vapi.Client
+	clientParams *ClientParams
+}
+
+// NewAuthenticatedClient returns a new authenticated Vault client using the given authentication method.
+// If the token can not be renewed, renewCh is closed when the token can no longer be used.
+func NewAuthenticatedClient(cp *ClientParams, method AuthMethod, renewCh chan struct{}, logger hclog.Logger) (*Client, error) {
+	config := vapi.DefaultConfig()
+	config.Address = cp.VaultAddr
+	config.MaxRetries = cp.MaxRetries
+	if cp.MinRetryWait != 0 {
+		config.MinRetryWait = cp.MinRetryWait
+	}
+	if cp.MaxRetryWait != 0 {
+		config.MaxRetryWait = cp.MaxRetryWait
+	}
+
+	if err := configureTLS(config, cp); err != nil {
+		return nil, err
+	}
+	vc, err := vapi.NewClient(config)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to create Vault client: %v", err)
+	}
+
+	if cp.Namespace != "" {
+		vc.SetNamespace(cp.Namespace)
+	}
+
+	client := &Client{
+		vaultClient:  vc,
+		clientParams: cp,
+	}
+
+	var sec *vapi.Secret
+	switch method {
+	case TOKEN:
+		sec, err = client.lookupSelf(cp.Token)
+		if err != nil {
+			return nil, err
+		}
+	case K8S:
+		b, err := os.ReadFile(cp.K8sAuthTokenPath)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to read k8s service account token: %v", err)
+		}
+		path := fmt.Sprintf("auth/%s/login", cp.K8sAuthMountPoint)
+		sec, err = client.auth(path, map[string]interface{}{
+			"role": cp.K8sAuthRoleName,
+			"jwt":  string(b),
+		})
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, status.Errorf(codes.Internal, "unsupported authentication method: %v", method)
+	}
+
+	if err := handleRenewToken(vc, sec, renewCh, logger); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// handleRenewToken handles renewing the vault token.
+// if the token is non-renewable or renew failed, renewCh will be closed.
+func handleRenewToken(vc *vapi.Client, sec *vapi.Secret, renewCh chan struct{}, logger hclog.Logger) error {
+	if sec == nil || sec.Auth == nil {
+		return status.Error(codes.Internal, "authentication response is nil")
+	}
+
+	if sec.Auth.LeaseDuration == 0 {
+		logger.Debug("Token will never expire")
+		return nil
+	}
+	if !sec.Auth.Renewable {
+		logger.Debug("Token is not renewable")
+		close(renewCh)
+		return nil
+	}
+
+	watcher, err := vc.NewLifetimeWatcher(&vapi.LifetimeWatcherInput{
+		Secret:        sec,
+		RenewBehavior: vapi.RenewBehaviorIgnoreErrors,
+	})
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to initialize renewer: %v", err)
+	}
+
+	go func() {
+		defer close(renewCh)
+		go watcher.Start()
+		defer watcher.Stop()
+
+		for {
+			select {
+			case err := <-watcher.DoneCh():
+				if err != nil {
+					logger.Error("Failed to renew auth token", "err", err)
+					return
+				}
+				logger.Error("Failed to renew auth token. Retries may have exceeded the lease time threshold")
+				return
+			case renewal := <-watcher.RenewCh():
+				logger.Debug("Successfully renew auth token", "request_id", renewal.Secret.RequestID, "lease_duration", renewal.Secret.Auth.LeaseDuration)
+			}
+		}
+	}()
+
+	logger.Debug("Token will be renewed")
+	return nil
+}
+
+func configureTLS(vc *vapi.Config, cp *ClientParams) error {
+	if vc.HttpClient == nil {
+		vc.HttpClient = vapi.DefaultConfig().HttpClient
+	}
+	clientTLSConfig := vc.HttpClient.Transport.(*http.Transport).TLSClientConfig
+
+	if cp.CACertPath != "" {
+		certs, err := pemutil.LoadCertificates(cp.CACertPath)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "failed to load CA certificate: %v", err)
+		}
+		pool := x509.NewCertPool()
+		for _, cert := range certs {
+			pool.AddCert(cert)
+		}
+		clientTLSConfig.RootCAs = pool
+	}
+
+	if cp.TLSSkipVerify {
+		clientTLSConfig.InsecureSkipVerify = true
+	}
+
+	return nil
+}
+
+func (c *Client) auth(path string, body map[string]interface{}) (*vapi.Secret, error) {
+	c.vaultClient.ClearToken()
+	secret, err := c.vaultClient.Logical().Write(path, body)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "authentication failed %v: %v", path, err)
+	}
+	if secret == nil {
+		return nil, status.Error(codes.Internal, "authentication response is nil")
+	}
+
+	tokenID, err := secret.TokenID()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "authentication is successful, but could not get token: %v", err)
+	}
+	c.vaultClient.SetToken(tokenID)
+	return secret, nil
+}
+
+func (c *Client) lookupSelf(token string) (*vapi.Secret, error) {
+	if token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is empty")
+	}
+	c.vaultClient.SetToken(token)
+
+	secret, err := c.vaultClient.Logical().Read("auth/token/lookup-self")
+	if err != nil {
+		return nil, status.Errorf(codeFromError(err), "token lookup failed: %v", err)
+	}
+	if secret == nil {
+		return nil, status.Error(codes.Internal, "lookup self response is nil")
+	}
+
+	id, err := secret.TokenID()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to get TokenID: %v", err)
+	}
+	renewable, err := secret.TokenIsRenewable()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to determine if token is renewable: %v", err)
+	}
+	ttl, err := secret.TokenTTL()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to get token ttl: %v", err)
+	}
+	secret.Auth = &vapi.SecretAuth{
+		ClientToken:   id,
+		Renewable:     renewable,
+		LeaseDuration: int(ttl.Seconds()),
+	}
+	return secret, nil
+}
+
+// CreateKey creates a new key in the Transit secrets engine.
+// see: https://www.vaultproject.io/api/secret/transit#create-key
+func (c *Client) CreateKey(name string, keyType keymanagerv1.KeyType) error {
+	transitKeyType, ok := transitKeyTypeFromKeyType(keyType)
+	if !ok {
+		return status.Errorf(codes.Internal, "unsupported key type: %v", keyType)
+	}
+
+	_, err := c.vaultClient.Logical().Write(c.keyPath(name), map[string]interface{}{
+		"type": transitKeyType,
+	})
+	if err != nil {
+		return status.Errorf(codeFromError(err), "failed to create key: %v", err)
+	}
+	return nil
+}
+
+// GetPublicKey returns the type and the PKIX encoded public key of the
+// latest version of a key. A NotFound status is returned if the key does not
+// exist.
+// see: https://www.vaultproject.io/api/secret/transit#read-key
+func (c *Client) GetPublicKey(name string) (keymanagerv1.KeyType, []byte, error) {
+	s, err := c.vaultClient.Logical().Read(c.keyPath(name))
+	switch {
+	case err != nil:
+		return keymanagerv1.KeyType_UNSPECIFIED_KEY_TYPE, nil, status.Errorf(codeFromError(err), "failed to read key: %v", err)
+	case s == nil:
+		return keymanagerv1.KeyType_UNSPECIFIED_KEY_TYPE, nil, status.Errorf(codes.NotFound, "key %q not found", name)
+	}
+
+	transitKeyType, ok := s.Data["type"].(string)
+	if !ok {
+		return keymanagerv1.KeyType_UNSPECIFIED_KEY_TYPE, nil, status.Errorf(codes.Internal, "expected key type data type %T but got %T", transitKeyType, s.Data["type"])
+	}
+	keyType, ok := keyTypeFromTransitKeyType(transitKeyType)
+	if !ok {
+		return keymanagerv1.KeyType_UNSPECIFIED_KEY_TYPE, nil, status.Errorf(codes.Internal, "unsupported transit key type: %q", transitKeyType)
+	}
+
+	latestVersion := fmt.Sprint(s.Data["latest_version"])
+	keys, ok := s.Data["keys"].(map[string]interface{})
+	if !ok {
+		return keymanagerv1.KeyType_UNSPECIFIED_KEY_TYPE, nil, status.Errorf(codes.Internal, "expected keys data type %T but got %T", keys, s.Data["keys"])
+	}
+	version, ok := keys[latestVersion].(map[string]interface{})
+	if !ok {
+		return keymanagerv1.KeyType_UNSPECIFIED_KEY_TYPE, nil, status.Errorf(codes.Internal, "key version %q not found in response", latestVersion)
+	}
+	publicKeyPEM, ok := version["public_key"].(string)
+	if !ok {
+		return keymanagerv1.KeyType_UNSPECIFIED_KEY_TYPE, nil, status.Errorf(codes.Internal, "expected public_key data type %T but got %T", publicKeyPEM, version["public_key"])
+	}
+
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return keymanagerv1.KeyType_UNSPECIFIED_KEY_TYPE, nil, status.Error(codes.Internal, "failed to decode public key PEM")
+	}
+	if _, err := x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+		return keymanagerv1.KeyType_UNSPECIFIED_KEY_TYPE, nil, status.Errorf(codes.Internal, "failed to parse public key: %v", err)
+	}
+
+	return keyType, block.Bytes, nil
+}
+
+// SignData signs a digest with the latest version of a key.
+// see: https://www.vaultproject.io/api/secret/transit#sign-data
+func (c *Client) SignData(name string, digest []byte, hashAlgorithm, signatureAlgorithm, saltLength string) ([]byte, error) {
+	body := map[string]interface{}{
+		"input":                base64.StdEncoding.EncodeToString(digest),
+		"prehashed":            true,
+		"hash_algorithm":       hashAlgorithm,
+		"marshaling_algorithm": "asn1",
+	}
+	if signatureAlgorithm != "" {
+		body["signature_algorithm"] = signatureAlgorithm
+	}
+	if saltLength != "" {
+		body["salt_length"] = saltLength
+	}
+
+	s, err := c.vaultClient.Logical().Write(fmt.Sprintf("%s/sign/%s", c.clientParams.TransitEnginePath, name), body)
+	if err != nil {
+		return nil, status.Errorf(codeFromError(err), "failed to sign: %v", err)
+	}
+	if s == nil {
+		return nil, status.Error(codes.Internal, "sign response is nil")
+	}
+
+	signature, ok := s.Data["signature"].(string)
+	if !ok {
+		return nil, status.Errorf(codes.Internal, "expected signature data type %T but got %T", signature, s.Data["signature"])
+	}
+
+	// Signatures have the form "vault:v<version>:<base64 signature>"
+	parts := strings.Split(signature, ":")
+	if len(parts) != 3 {
+		return nil, status.Errorf(codes.Internal, "malformed signature %q", signature)
+	}
+	signatureBytes, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to decode signature: %v", err)
+	}
+	return signatureBytes, nil
+}
+
+// DeleteKey deletes a key. Transit keys can only be deleted once they are
+// configured to allow deletion, so the key is updated first.
+// see: https://www.vaultproject.io/api/secret/transit#delete-key
+func (c *Client) DeleteKey(name string) error {
+	_, err := c.vaultClient.Logical().Write(c.keyPath(name)+"/config", map[string]interface{}{
+		"deletion_allowed": true,
+	})
+	if err != nil {
+		return status.Errorf(codeFromError(err), "failed to allow key deletion: %v", err)
+	}
+
+	if _, err := c.vaultClient.Logical().Delete(c.keyPath(name)); err != nil {
+		return status.Errorf(codeFromError(err), "failed to delete key: %v", err)
+	}
+	return nil
+}
+
+func (c *Client) keyPath(name string) string {
+	return fmt.Sprintf("%s/keys/%s", c.clientParams.TransitEnginePath, name)
+}
+
+// codeFromError maps errors returned by Vault to a gRPC status code. Errors
+// caused by Vault being sealed or unreachable are reported as Unavailable so
+// callers can retry later.
+func codeFromError(err error) codes.Code {
+	var respErr *vapi.ResponseError
+	if errors.As(err, &respErr) {
+		switch respErr.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return codes.Unavailable
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return codes.PermissionDenied
+		case http.StatusNotFound:
+			return codes.NotFound
+		}
+		return codes.Internal
+	}
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return codes.Unavailable
+	}
+	return codes.Internal
+}
+
+func transitKeyTypeFromKeyType(keyType keymanagerv1.KeyType) (string, bool) {
+	switch keyType {
+	case keymanagerv1.KeyType_EC_P256:
+		return "ecdsa-p256", true
+	case keymanagerv1.KeyType_EC_P384:
+		return "ecdsa-p384", true
+	case keymanagerv1.KeyType_RSA_2048:
+		return "rsa-2048", true
+	case keymanagerv1.KeyType_RSA_4096:
+		return "rsa-4096", true
+	default:
+		return "", false
+	}
+}
+
+func keyTypeFromTransitKeyType(transitKeyType string) (keymanagerv1.KeyType, bool) {
+	switch transitKeyType {
+	case "ecdsa-p256":
+		return keymanagerv1.KeyType_EC_P256, true
+	case "ecdsa-p384":
+		return keymanagerv1.KeyType_EC_P384, true
+	case "rsa-2048":
+		return keymanagerv1.KeyType_RSA_2048, true
+	case "rsa-4096":
+		return keymanagerv1.KeyType_RSA_4096, true
+	default:
+		return keymanagerv1.KeyType_UNSPECIFIED_KEY_TYPE, false
+	}
+}
@@ -0,0 +1,335 @@
+package hashicorpvault
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	fakeToken   = "test-token"
+	fakeK8sRole = "test-role"
+	fakeK8sJWT  = "test-jwt"
+)
+
+type fakeKey struct {
+	keyType         string
+	signer          crypto.Signer
+	deletionAllowed bool
+}
+
+// fakeVaultServer implements the subset of the Vault HTTP API used by the
+// plugin: token lookup, Kubernetes login and the Transit secrets engine
+// mounted at the default path.
+type fakeVaultServer struct {
+	t *testing.T
+
+	mu sync.Mutex
+	// token is the only token accepted by the server
+	token string
+	// unavailable is the number of upcoming requests answered as if Vault was sealed
+	unavailable int
+	keys        map[string]*fakeKey
+	k8sLogins   int
+}
+
+func newFakeVaultServer(t *testing.T) (*fakeVaultServer, string) {
+	s := &fakeVaultServer{
+		t:     t,
+		token: fakeToken,
+		keys:  make(map[string]*fakeKey),
+	}
+	server := httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(server.Close)
+	return s, server.URL
+}
+
+func (s *fakeVaultServer) setUnavailable(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unavailable = n
+}
+
+func (s *fakeVaultServer) setToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = token
+}
+
+func (s *fakeVaultServer) hasKey(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.keys[name]
+	return ok
+}
+
+func (s *fakeVaultServer) deleteKey(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, name)
+}
+
+func (s *fakeVaultServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.unavailable > 0 {
+		s.unavailable--
+		writeErrors(w, http.StatusServiceUnavailable, "Vault is sealed")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	if path == "auth/kubernetes/login" {
+		s.k8sLogins++
+		var body struct {
+			Role string `json:"role"`
+			JWT  string `json:"jwt"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Role != fakeK8sRole || body.JWT != fakeK8sJWT {
+			writeErrors(w, http.StatusBadRequest, "invalid role or service account token")
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"auth": map[string]interface{}{
+				"client_token":   s.token,
+				"renewable":      false,
+				"lease_duration": 0,
+			},
+		})
+		return
+	}
+
+	if r.Header.Get("X-Vault-Token") != s.token {
+		writeErrors(w, http.StatusForbidden, "permission denied")
+		return
+	}
+
+	switch {
+	case path == "auth/token/lookup-self":
+		writeJSON(w, map[string]interface{}{
+			"data": map[string]interface{}{
+				"id":        s.token,
+				"renewable": false,
+				"ttl":       0,
+			},
+		})
+	case strings.HasPrefix(path, "transit/keys/") && strings.HasSuffix(path, "/config"):
+		s.configKey(w, r, strings.TrimSuffix(strings.TrimPrefix(path, "transit/keys/"), "/config"))
+	case strings.HasPrefix(path, "transit/keys/"):
+		name := strings.TrimPrefix(path, "transit/keys/")
+		switch r.Method {
+		case http.MethodGet:
+			s.readKey(w, name)
+		case http.MethodPut, http.MethodPost:
+			s.createKey(w, r, name)
+		case http.MethodDelete:
+			s.deleteKeyHandler(w, name)
+		default:
+			writeErrors(w, http.StatusMethodNotAllowed, "unsupported method")
+		}
+	case strings.HasPrefix(path, "transit/sign/"):
+		s.sign(w, r, strings.TrimPrefix(path, "transit/sign/"))
+	default:
+		writeErrors(w, http.StatusNotFound, "no handler for route")
+	}
+}
+
+func (s *fakeVaultServer) createKey(w http.ResponseWriter, r *http.Request, name string) {
+	var body struct {
+		Type string `json:"type"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeErrors(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var (
+		signer crypto.Signer
+		err    error
+	)
+	switch body.Type {
+	case "ecdsa-p256":
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "ecdsa-p384":
+		signer, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case "rsa-2048":
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "rsa-4096":
+		signer, err = rsa.GenerateKey(rand.Reader, 4096)
+	default:
+		writeErrors(w, http.StatusBadRequest, fmt.Sprintf("unknown key type %q", body.Type))
+		return
+	}
+	require.NoError(s.t, err)
+
+	s.keys[name] = &fakeKey{keyType: body.Type, signer: signer}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *fakeVaultServer) readKey(w http.ResponseWriter, name string) {
+	key, ok := s.keys[name]
+	if !ok {
+		writeErrors(w, http.StatusNotFound)
+		return
+	}
+
+	pkixData, err := x509.MarshalPKIXPublicKey(key.signer.Public())
+	require.NoError(s.t, err)
+
+	writeJSON(w, map[string]interface{}{
+		"data": map[string]interface{}{
+			"name":           name,
+			"type":           key.keyType,
+			"latest_version": 1,
+			"keys": map[string]interface{}{
+				"1": map[string]interface{}{
+					"name":       key.keyType,
+					"public_key": string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pkixData})),
+				},
+			},
+		},
+	})
+}
+
+func (s *fakeVaultServer) configKey(w http.ResponseWriter, r *http.Request, name string) {
+	key, ok := s.keys[name]
+	if !ok {
+		writeErrors(w, http.StatusNotFound)
+		return
+	}
+
+	var body struct {
+		DeletionAllowed bool `json:"deletion_allowed"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeErrors(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	key.deletionAllowed = body.DeletionAllowed
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *fakeVaultServer) deleteKeyHandler(w http.ResponseWriter, name string) {
+	key, ok := s.keys[name]
+	if !ok {
+		writeErrors(w, http.StatusNotFound)
+		return
+	}
+	if !key.deletionAllowed {
+		writeErrors(w, http.StatusBadRequest, "deletion is not allowed for this key")
+		return
+	}
+	delete(s.keys, name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *fakeVaultServer) sign(w http.ResponseWriter, r *http.Request, name string) {
+	key, ok := s.keys[name]
+	if !ok {
+		writeErrors(w, http.StatusBadRequest, "signing key not found")
+		return
+	}
+
+	var body struct {
+		Input               string `json:"input"`
+		Prehashed           bool   `json:"prehashed"`
+		HashAlgorithm       string `json:"hash_algorithm"`
+		SignatureAlgorithm  string `json:"signature_algorithm"`
+		MarshalingAlgorithm string `json:"marshaling_algorithm"`
+		SaltLength          string `json:"salt_length"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeErrors(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !body.Prehashed || body.MarshalingAlgorithm != "asn1" {
+		writeErrors(w, http.StatusBadRequest, "expected a prehashed, asn1 marshaled signature request")
+		return
+	}
+
+	digest, err := base64.StdEncoding.DecodeString(body.Input)
+	if err != nil {
+		writeErrors(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var hash crypto.Hash
+	switch body.HashAlgorithm {
+	case "sha2-256":
+		hash = crypto.SHA256
+	case "sha2-384":
+		hash = crypto.SHA384
+	case "sha2-512":
+		hash = crypto.SHA512
+	default:
+		writeErrors(w, http.StatusBadRequest, fmt.Sprintf("unsupported hash algorithm %q", body.HashAlgorithm))
+		return
+	}
+
+	var signerOpts crypto.SignerOpts = hash
+	if _, isRSA := key.signer.(*rsa.PrivateKey); isRSA {
+		switch body.SignatureAlgorithm {
+		case "pkcs1v15":
+		case "pss":
+			saltLength := rsa.PSSSaltLengthAuto
+			switch body.SaltLength {
+			case "", "auto":
+			case "hash":
+				saltLength = rsa.PSSSaltLengthEqualsHash
+			default:
+				saltLength, err = strconv.Atoi(body.SaltLength)
+				if err != nil {
+					writeErrors(w, http.StatusBadRequest, err.Error())
+					return
+				}
+			}
+			signerOpts = &rsa.PSSOptions{Hash: hash, SaltLength: saltLength}
+		default:
+			writeErrors(w, http.StatusBadRequest, fmt.Sprintf("unsupported signature algorithm %q", body.SignatureAlgorithm))
+			return
+		}
+	}
+
+	signature, err := key.signer.Sign(rand.Reader, digest, signerOpts)
+	if err != nil {
+		writeErrors(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"data": map[string]interface{}{
+			"signature":   "vault:v1:" + base64.StdEncoding.EncodeToString(signature),
+			"key_version": 1,
+		},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeErrors(w http.ResponseWriter, code int, errs ...string) {
+	if errs == nil {
+		errs = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"errors": errs})
+}
@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/andres-erbsen/clock"
 	"github.com/hashicorp/hcl"
 
 	hclog "github.com/hashicorp/go-hclog"
@@ -62,6 +63,17 @@ type IITAttestorPlugin struct {
 	mtx           sync.Mutex
 	jwksRetriever jwksRetriever
 	client        computeEngineClient
+	clock         clock.Clock
+
+	instanceCacheMtx sync.Mutex
+	instanceCache    map[string]cachedInstance
+}
+
+// cachedInstance is an instance fetched from the compute API, along with
+// the time after which it must be fetched again.
+type cachedInstance struct {
+	instance  *compute.Instance
+	expiresAt time.Time
 }
 
 // IITAttestorConfig is the config for IITAttestorPlugin.
@@ -70,6 +82,7 @@ type IITAttestorConfig struct {
 	trustDomain         spiffeid.TrustDomain
 	allowedLabelKeys    map[string]bool
 	allowedMetadataKeys map[string]bool
+	instanceCacheTTL    time.Duration
 
 	ProjectIDAllowList   []string `hcl:"projectid_allow_list"`
 	AgentPathTemplate    string   `hcl:"agent_path_template"`
@@ -78,6 +91,7 @@ type IITAttestorConfig struct {
 	AllowedMetadataKeys  []string `hcl:"allowed_metadata_keys"`
 	MaxMetadataValueSize int      `hcl:"max_metadata_value_size"`
 	ServiceAccountFile   string   `hcl:"service_account_file"`
+	InstanceCacheTTL     string   `hcl:"instance_metadata_cache_ttl"`
 }
 
 // New creates a new IITAttestorPlugin.
@@ -85,6 +99,8 @@ func New() *IITAttestorPlugin {
 	return &IITAttestorPlugin{
 		jwksRetriever: newGooglePublicKeyRetriever(googleCertURL),
 		client:        googleComputeEngineClient{},
+		clock:         clock.New(),
+		instanceCache: make(map[string]cachedInstance),
 	}
 }
 
@@ -136,7 +152,7 @@ func (p *IITAttestorPlugin) Attest(stream nodeattestorv1.NodeAttestor_AttestServ
 
 	var instance *compute.Instance
 	if c.UseInstanceMetadata {
-		instance, err = p.client.fetchInstanceMetadata(stream.Context(), identityMetadata.ProjectID, identityMetadata.Zone, identityMetadata.InstanceName, c.ServiceAccountFile)
+		instance, err = p.fetchInstanceMetadata(stream.Context(), c, identityMetadata)
 		if err != nil {
 			return status.Errorf(codes.Internal, "failed to fetch instance metadata: %v", err)
 		}
@@ -216,6 +232,16 @@ func (p *IITAttestorPlugin) Configure(ctx context.Context, req *configv1.Configu
 		hclConfig.MaxMetadataValueSize = defaultMaxMetadataValueSize
 	}
 
+	if hclConfig.InstanceCacheTTL != "" {
+		hclConfig.instanceCacheTTL, err = time.ParseDuration(hclConfig.InstanceCacheTTL)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "failed to parse instance_metadata_cache_ttl: %v", err)
+		}
+		if hclConfig.instanceCacheTTL < 0 {
+			return nil, status.Error(codes.InvalidArgument, "instance_metadata_cache_ttl cannot be negative")
+		}
+	}
+
 	hclConfig.idPathTemplate = tmpl
 	hclConfig.trustDomain = trustDomain
 
@@ -224,6 +250,11 @@ func (p *IITAttestorPlugin) Configure(ctx context.Context, req *configv1.Configu
 
 	p.config = hclConfig
 
+	// Drop instances cached under the previous configuration
+	p.instanceCacheMtx.Lock()
+	p.instanceCache = make(map[string]cachedInstance)
+	p.instanceCacheMtx.Unlock()
+
 	return &configv1.ConfigureResponse{}, nil
 }
 
@@ -237,6 +268,43 @@ func (p *IITAttestorPlugin) getConfig() (*IITAttestorConfig, error) {
 	return p.config, nil
 }
 
+// fetchInstanceMetadata fetches the instance from the compute API. When
+// instance_metadata_cache_ttl is set, instances are cached for that long so
+// that bursts of attestations do not exhaust the compute API rate limits.
+func (p *IITAttestorPlugin) fetchInstanceMetadata(ctx context.Context, c *IITAttestorConfig, identityMetadata gcp.ComputeEngine) (*compute.Instance, error) {
+	if c.instanceCacheTTL == 0 {
+		return p.client.fetchInstanceMetadata(ctx, identityMetadata.ProjectID, identityMetadata.Zone, identityMetadata.InstanceName, c.ServiceAccountFile)
+	}
+
+	key := identityMetadata.ProjectID + "/" + identityMetadata.Zone + "/" + identityMetadata.InstanceName
+	now := p.clock.Now()
+
+	p.instanceCacheMtx.Lock()
+	cached, ok := p.instanceCache[key]
+	p.instanceCacheMtx.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.instance, nil
+	}
+
+	instance, err := p.client.fetchInstanceMetadata(ctx, identityMetadata.ProjectID, identityMetadata.Zone, identityMetadata.InstanceName, c.ServiceAccountFile)
+	if err != nil {
+		return nil, err
+	}
+
+	p.instanceCacheMtx.Lock()
+	defer p.instanceCacheMtx.Unlock()
+	for k, v := range p.instanceCache {
+		if !now.Before(v.expiresAt) {
+			delete(p.instanceCache, k)
+		}
+	}
+	p.instanceCache[key] = cachedInstance{
+		instance:  instance,
+		expiresAt: now.Add(c.instanceCacheTTL),
+	}
+	return instance, nil
+}
+
 func getInstanceSelectorValues(config *IITAttestorConfig, instance *compute.Instance) ([]string, error) {
 	metadata, err := getInstanceMetadata(instance, config.allowedMetadataKeys, config.MaxMetadataValueSize)
 	if err != nil {
@@ -250,6 +318,9 @@ func getInstanceSelectorValues(config *IITAttestorConfig, instance *compute.Inst
 	for _, serviceAccount := range getInstanceServiceAccounts(instance) {
 		selectorValues = append(selectorValues, makeSelectorValue("sa", serviceAccount))
 	}
+	for _, scope := range getInstanceServiceAccountScopes(instance) {
+		selectorValues = append(selectorValues, makeSelectorValue("sa-scope", scope))
+	}
 	for _, label := range getInstanceLabels(instance, config.allowedLabelKeys) {
 		selectorValues = append(selectorValues, makeSelectorValue("label", label.key, label.value))
 	}
@@ -310,6 +381,20 @@ func getInstanceServiceAccounts(instance *compute.Instance) []string {
 	return sa
 }
 
+func getInstanceServiceAccountScopes(instance *compute.Instance) []string {
+	var scopes []string
+	seen := make(map[string]bool)
+	for _, serviceAccount := range instance.ServiceAccounts {
+		for _, scope := range serviceAccount.Scopes {
+			if !seen[scope] {
+				seen[scope] = true
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	return scopes
+}
+
 func getInstanceLabels(instance *compute.Instance, allowedKeys map[string]bool) []keyValue {
 	var labels []keyValue
 	for k, v := range instance.Labels {
@@ -17,6 +17,7 @@ import (
 	"github.com/spiffe/spire/pkg/common/util"
 	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor"
 	"github.com/spiffe/spire/proto/spire/common"
+	"github.com/spiffe/spire/test/clock"
 	"github.com/spiffe/spire/test/fakes/fakeagentstore"
 	"github.com/spiffe/spire/test/plugintest"
 	"github.com/spiffe/spire/test/spiretest"
@@ -53,11 +54,13 @@ type IITAttestorSuite struct {
 	attestor   nodeattestor.NodeAttestor
 
 	client *fakeComputeEngineClient
+	clock  *clock.Mock
 }
 
 func (s *IITAttestorSuite) SetupTest() {
 	s.agentStore = fakeagentstore.New()
 	s.client = newFakeComputeEngineClient()
+	s.clock = clock.NewMock(s.T())
 	s.attestor = s.loadPlugin()
 }
 
@@ -158,8 +161,14 @@ func (s *IITAttestorSuite) TestAttestSuccessWithInstanceMetadata() {
 			Items: []string{"tag-1", "tag-2"},
 		},
 		ServiceAccounts: []*compute.ServiceAccount{
-			{Email: "service-account-1"},
-			{Email: "service-account-2"},
+			{
+				Email:  "service-account-1",
+				Scopes: []string{"https://www.googleapis.com/auth/cloud-platform"},
+			},
+			{
+				Email:  "service-account-2",
+				Scopes: []string{"https://www.googleapis.com/auth/cloud-platform", "https://www.googleapis.com/auth/logging.write"},
+			},
 		},
 		Labels: map[string]string{
 			"allowed":          "ALLOWED",
@@ -191,6 +200,8 @@ func (s *IITAttestorSuite) TestAttestSuccessWithInstanceMetadata() {
 		{Type: "gcp_iit", Value: "tag:tag-2"},
 		{Type: "gcp_iit", Value: "sa:service-account-1"},
 		{Type: "gcp_iit", Value: "sa:service-account-2"},
+		{Type: "gcp_iit", Value: "sa-scope:https://www.googleapis.com/auth/cloud-platform"},
+		{Type: "gcp_iit", Value: "sa-scope:https://www.googleapis.com/auth/logging.write"},
 		{Type: "gcp_iit", Value: "metadata:allowed:ALLOWED"},
 		{Type: "gcp_iit", Value: "metadata:allowed-no-value:"},
 		{Type: "gcp_iit", Value: "label:allowed:ALLOWED"},
@@ -207,6 +218,51 @@ func (s *IITAttestorSuite) TestAttestSuccessWithInstanceMetadata() {
 	s.Require().Equal(testAgentID, result.AgentID)
 }
 
+func (s *IITAttestorSuite) TestAttestCachesInstanceMetadata() {
+	s.client.setInstance(&compute.Instance{
+		Tags: &compute.Tags{Items: []string{"frontend"}},
+	})
+	s.attestor = s.loadPluginWithConfig(`
+projectid_allow_list = ["test-project"]
+use_instance_metadata = true
+service_account_file = "test_sa.json"
+instance_metadata_cache_ttl = "1m"
+`)
+
+	requireTagSelector := func(tag string) {
+		result, err := s.attestor.Attest(context.Background(), s.signDefaultToken(), expectNoChallenge)
+		s.Require().NoError(err)
+		s.Require().Contains(result.Selectors, &common.Selector{Type: "gcp_iit", Value: "tag:" + tag})
+	}
+
+	// The first attestation fetches the instance from the compute API
+	requireTagSelector("frontend")
+	s.Require().Equal(1, s.client.fetchCount())
+
+	// The instance is served from the cache within the cache window
+	s.client.setInstance(&compute.Instance{
+		Tags: &compute.Tags{Items: []string{"backend"}},
+	})
+	s.clock.Add(time.Minute - time.Second)
+	requireTagSelector("frontend")
+	s.Require().Equal(1, s.client.fetchCount())
+
+	// The instance is fetched again once the cache entry expires
+	s.clock.Add(time.Second)
+	requireTagSelector("backend")
+	s.Require().Equal(2, s.client.fetchCount())
+}
+
+func (s *IITAttestorSuite) TestAttestDoesNotCacheInstanceMetadataByDefault() {
+	s.attestor = s.loadPluginForInstanceMetadata(&compute.Instance{})
+
+	for i := 1; i <= 2; i++ {
+		_, err := s.attestor.Attest(context.Background(), s.signDefaultToken(), expectNoChallenge)
+		s.Require().NoError(err)
+		s.Require().Equal(i, s.client.fetchCount())
+	}
+}
+
 func (s *IITAttestorSuite) TestAttestFailsIfInstanceMetadataValueExceedsLimit() {
 	s.attestor = s.loadPluginForInstanceMetadata(&compute.Instance{
 		Metadata: &compute.Metadata{
@@ -298,6 +354,22 @@ agent_path_template = "/{{ .InstanceID "
 		spiretest.AssertGRPCStatusContains(t, err, codes.InvalidArgument, "failed to parse agent path template")
 	})
 
+	s.T().Run("bad instance metadata cache TTL", func(t *testing.T) {
+		err := doConfig(t, coreConfig, `
+projectid_allow_list = ["test-project"]
+instance_metadata_cache_ttl = "soon"
+`)
+		spiretest.AssertGRPCStatusContains(t, err, codes.InvalidArgument, "failed to parse instance_metadata_cache_ttl")
+	})
+
+	s.T().Run("negative instance metadata cache TTL", func(t *testing.T) {
+		err := doConfig(t, coreConfig, `
+projectid_allow_list = ["test-project"]
+instance_metadata_cache_ttl = "-1m"
+`)
+		spiretest.AssertGRPCStatusContains(t, err, codes.InvalidArgument, "instance_metadata_cache_ttl cannot be negative")
+	})
+
 	s.T().Run("success", func(t *testing.T) {
 		err := doConfig(t, coreConfig, `
 projectid_allow_list = ["bar"]
@@ -336,6 +408,7 @@ func (s *IITAttestorSuite) newPlugin() *IITAttestorPlugin {
 	p := New()
 	p.jwksRetriever = testKeyRetriever{}
 	p.client = s.client
+	p.clock = s.clock
 	return p
 }
 
@@ -411,6 +484,7 @@ func buildDefaultClaims() gcp.IdentityToken {
 type fakeComputeEngineClient struct {
 	mu       sync.Mutex
 	instance *compute.Instance
+	fetches  int
 }
 
 func newFakeComputeEngineClient() *fakeComputeEngineClient {
@@ -423,9 +497,16 @@ func (c *fakeComputeEngineClient) setInstance(instance *compute.Instance) {
 	c.instance = instance
 }
 
+func (c *fakeComputeEngineClient) fetchCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fetches
+}
+
 func (c *fakeComputeEngineClient) fetchInstanceMetadata(ctx context.Context, projectID, zone, instanceName string, serviceAccountFile string) (*compute.Instance, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.fetches++
 	switch {
 	case projectID != testProject:
 		return nil, fmt.Errorf("expected project %q; got %q", testProject, projectID)
@@ -3,9 +3,31 @@ package tpmdevid
 import (
 	"crypto/sha1" //nolint: gosec // SHA1 use is according to specification
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
 	"encoding/hex"
 )
 
+var (
+	// oidSubjectAltName is the OID of the Subject Alternative Name extension
+	oidSubjectAltName = asn1.ObjectIdentifier{2, 5, 29, 17}
+
+	// oidHardwareModuleName is the OID of the hardwareModuleName otherName
+	// (RFC 4108) used by IEEE 802.1AR DevIDs to identify the device
+	oidHardwareModuleName = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 8, 4}
+
+	// TPM attributes carried in the directoryName of the Subject Alternative
+	// Name, as defined by the TCG EK Credential Profile
+	oidTPMManufacturer = asn1.ObjectIdentifier{2, 23, 133, 2, 1}
+	oidTPMModel        = asn1.ObjectIdentifier{2, 23, 133, 2, 2}
+	oidTPMVersion      = asn1.ObjectIdentifier{2, 23, 133, 2, 3}
+)
+
+const (
+	sanOtherNameTag     = 0
+	sanDirectoryNameTag = 4
+)
+
 func buildSelectorValues(leaf *x509.Certificate, chains [][]*x509.Certificate) []string {
 	selectorValues := []string{}
 
@@ -13,10 +35,24 @@ func buildSelectorValues(leaf *x509.Certificate, chains [][]*x509.Certificate) [
 		selectorValues = append(selectorValues, "subject:cn:"+leaf.Subject.CommonName)
 	}
 
+	if leaf.Subject.SerialNumber != "" {
+		selectorValues = append(selectorValues, "subject:serialnumber:"+leaf.Subject.SerialNumber)
+	}
+
+	for _, organization := range leaf.Subject.Organization {
+		selectorValues = append(selectorValues, "subject:o:"+organization)
+	}
+
 	if leaf.Issuer.CommonName != "" {
 		selectorValues = append(selectorValues, "issuer:cn:"+leaf.Issuer.CommonName)
 	}
 
+	for _, organization := range leaf.Issuer.Organization {
+		selectorValues = append(selectorValues, "issuer:o:"+organization)
+	}
+
+	selectorValues = append(selectorValues, buildSANSelectorValues(leaf)...)
+
 	// Used to avoid duplicating selectors.
 	fingerprints := map[string]*x509.Certificate{}
 	for _, chain := range chains {
@@ -37,6 +73,102 @@ func buildSelectorValues(leaf *x509.Certificate, chains [][]*x509.Certificate) [
 	return selectorValues
 }
 
+// buildSANSelectorValues builds selectors for the platform attributes found in
+// the Subject Alternative Name of the DevID certificate: the TPM manufacturer,
+// model and version, and the hardware module name. Attributes that are absent
+// or cannot be parsed are skipped, since they are not required to identify
+// the DevID.
+func buildSANSelectorValues(leaf *x509.Certificate) []string {
+	var rawSAN []byte
+	for _, ext := range leaf.Extensions {
+		if ext.Id.Equal(oidSubjectAltName) {
+			rawSAN = ext.Value
+			break
+		}
+	}
+	if rawSAN == nil {
+		return nil
+	}
+
+	var generalNames []asn1.RawValue
+	if rest, err := asn1.Unmarshal(rawSAN, &generalNames); err != nil || len(rest) != 0 {
+		return nil
+	}
+
+	var selectorValues []string
+	for _, generalName := range generalNames {
+		if generalName.Class != asn1.ClassContextSpecific {
+			continue
+		}
+		switch generalName.Tag {
+		case sanDirectoryNameTag:
+			selectorValues = append(selectorValues, tpmAttributeSelectorValues(generalName.Bytes)...)
+		case sanOtherNameTag:
+			selectorValues = append(selectorValues, hardwareModuleSelectorValues(generalName.Bytes)...)
+		}
+	}
+	return selectorValues
+}
+
+func tpmAttributeSelectorValues(rawName []byte) []string {
+	var name pkix.RDNSequence
+	if rest, err := asn1.Unmarshal(rawName, &name); err != nil || len(rest) != 0 {
+		return nil
+	}
+
+	var selectorValues []string
+	for _, rdn := range name {
+		for _, attr := range rdn {
+			value, ok := attr.Value.(string)
+			if !ok || value == "" {
+				continue
+			}
+			switch {
+			case attr.Type.Equal(oidTPMManufacturer):
+				selectorValues = append(selectorValues, "san:tpm_manufacturer:"+value)
+			case attr.Type.Equal(oidTPMModel):
+				selectorValues = append(selectorValues, "san:tpm_model:"+value)
+			case attr.Type.Equal(oidTPMVersion):
+				selectorValues = append(selectorValues, "san:tpm_version:"+value)
+			}
+		}
+	}
+	return selectorValues
+}
+
+// hardwareModuleName is the value of the hardwareModuleName otherName
+type hardwareModuleName struct {
+	HWType      asn1.ObjectIdentifier
+	HWSerialNum []byte
+}
+
+func hardwareModuleSelectorValues(rawOtherName []byte) []string {
+	var typeID asn1.ObjectIdentifier
+	rest, err := asn1.Unmarshal(rawOtherName, &typeID)
+	if err != nil || !typeID.Equal(oidHardwareModuleName) {
+		return nil
+	}
+
+	// The value is wrapped in an explicit [0] tag
+	var wrapped asn1.RawValue
+	if rest, err = asn1.Unmarshal(rest, &wrapped); err != nil || len(rest) != 0 {
+		return nil
+	}
+	var hwName hardwareModuleName
+	if rest, err = asn1.Unmarshal(wrapped.Bytes, &hwName); err != nil || len(rest) != 0 {
+		return nil
+	}
+
+	var selectorValues []string
+	if len(hwName.HWType) > 0 {
+		selectorValues = append(selectorValues, "san:hw_type:"+hwName.HWType.String())
+	}
+	if len(hwName.HWSerialNum) > 0 {
+		selectorValues = append(selectorValues, "san:hw_serial:"+hex.EncodeToString(hwName.HWSerialNum))
+	}
+	return selectorValues
+}
+
 func Fingerprint(cert *x509.Certificate) string {
 	sum := sha1.Sum(cert.Raw) //nolint: gosec // SHA1 use is according to specification
 	return hex.EncodeToString(sum[:])
@@ -0,0 +1,153 @@
+package tpmdevid
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/test/testkey"
+	"github.com/stretchr/testify/require"
+)
+
+var key = testkey.MustEC256()
+
+func TestBuildSelectorValues(t *testing.T) {
+	root := createCertificate(t, &x509.Certificate{
+		Subject: pkix.Name{
+			CommonName:   "root",
+			Organization: []string{"Manufacturer"},
+		},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}, nil)
+
+	tpmAttributesSAN := marshalSAN(t,
+		directoryName(t, pkix.RDNSequence{
+			{{Type: oidTPMManufacturer, Value: "id:53544D20"}},
+			{{Type: oidTPMModel, Value: "ST33HTPHAHD4"}},
+			{{Type: oidTPMVersion, Value: "id:00010102"}},
+		}),
+		hardwareModule(t, hardwareModuleName{
+			HWType:      asn1.ObjectIdentifier{1, 2, 3, 4},
+			HWSerialNum: []byte{0xca, 0xfe},
+		}),
+	)
+
+	for _, tt := range []struct {
+		name     string
+		tmpl     *x509.Certificate
+		expected []string
+	}{
+		{
+			name: "all attributes",
+			tmpl: &x509.Certificate{
+				Subject: pkix.Name{
+					CommonName:   "devid-leaf",
+					SerialNumber: "1234",
+					Organization: []string{"Platform Vendor"},
+				},
+				ExtraExtensions: []pkix.Extension{{Id: oidSubjectAltName, Value: tpmAttributesSAN}},
+			},
+			expected: []string{
+				"subject:cn:devid-leaf",
+				"subject:serialnumber:1234",
+				"subject:o:Platform Vendor",
+				"issuer:cn:root",
+				"issuer:o:Manufacturer",
+				"san:tpm_manufacturer:id:53544D20",
+				"san:tpm_model:ST33HTPHAHD4",
+				"san:tpm_version:id:00010102",
+				"san:hw_type:1.2.3.4",
+				"san:hw_serial:cafe",
+				"ca:fingerprint:" + Fingerprint(root),
+			},
+		},
+		{
+			name: "absent attributes",
+			tmpl: &x509.Certificate{},
+			expected: []string{
+				"issuer:cn:root",
+				"issuer:o:Manufacturer",
+				"ca:fingerprint:" + Fingerprint(root),
+			},
+		},
+		{
+			name: "SAN without platform attributes",
+			tmpl: &x509.Certificate{
+				Subject:  pkix.Name{CommonName: "devid-leaf"},
+				DNSNames: []string{"device.example.org"},
+			},
+			expected: []string{
+				"subject:cn:devid-leaf",
+				"issuer:cn:root",
+				"issuer:o:Manufacturer",
+				"ca:fingerprint:" + Fingerprint(root),
+			},
+		},
+		{
+			name: "malformed platform attributes",
+			tmpl: &x509.Certificate{
+				Subject: pkix.Name{CommonName: "devid-leaf"},
+				ExtraExtensions: []pkix.Extension{{Id: oidSubjectAltName, Value: marshalSAN(t,
+					asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: sanDirectoryNameTag, IsCompound: true, Bytes: []byte("not a name")},
+					asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: sanOtherNameTag, IsCompound: true, Bytes: []byte("not an other name")},
+					asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2, Bytes: []byte("device.example.org")},
+				)}},
+			},
+			expected: []string{
+				"subject:cn:devid-leaf",
+				"issuer:cn:root",
+				"issuer:o:Manufacturer",
+				"ca:fingerprint:" + Fingerprint(root),
+			},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			leaf := createCertificate(t, tt.tmpl, root)
+			selectorValues := buildSelectorValues(leaf, [][]*x509.Certificate{{leaf, root}})
+			require.Equal(t, tt.expected, selectorValues)
+		})
+	}
+}
+
+// createCertificate creates a certificate signed by parent, or self-signed if
+// parent is nil. The same key is used for all the certificates.
+func createCertificate(t *testing.T, tmpl, parent *x509.Certificate) *x509.Certificate {
+	tmpl.SerialNumber = big.NewInt(1)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	if parent == nil {
+		parent = tmpl
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, tmpl, parent, key.Public(), key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(certDER)
+	require.NoError(t, err)
+	return cert
+}
+
+func marshalSAN(t *testing.T, generalNames ...asn1.RawValue) []byte {
+	san, err := asn1.Marshal(generalNames)
+	require.NoError(t, err)
+	return san
+}
+
+func directoryName(t *testing.T, name pkix.RDNSequence) asn1.RawValue {
+	rawName, err := asn1.Marshal(name)
+	require.NoError(t, err)
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: sanDirectoryNameTag, IsCompound: true, Bytes: rawName}
+}
+
+func hardwareModule(t *testing.T, hwName hardwareModuleName) asn1.RawValue {
+	typeID, err := asn1.Marshal(oidHardwareModuleName)
+	require.NoError(t, err)
+	value, err := asn1.Marshal(hwName)
+	require.NoError(t, err)
+	wrapped, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: value})
+	require.NoError(t, err)
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: sanOtherNameTag, IsCompound: true, Bytes: append(typeID, wrapped...)}
+}
@@ -16,7 +16,7 @@ import (
 // needs to do its job.
 type apiClient interface {
 	SubscriptionID() string
-	GetVirtualMachineResourceID(ctx context.Context, principalID string) (string, error)
+	GetVirtualMachineResourceID(ctx context.Context, principalID, region string) (string, error)
 	GetVirtualMachine(ctx context.Context, resourceGroup string, name string) (*compute.VirtualMachine, error)
 	GetNetworkInterface(ctx context.Context, resourceGroup string, name string) (*network.Interface, error)
 }
@@ -51,8 +51,11 @@ func (c *azureClient) SubscriptionID() string {
 	return c.subscriptionID
 }
 
-func (c *azureClient) GetVirtualMachineResourceID(ctx context.Context, principalID string) (string, error) {
+func (c *azureClient) GetVirtualMachineResourceID(ctx context.Context, principalID, region string) (string, error) {
 	filter := fmt.Sprintf("resourceType eq 'Microsoft.Compute/virtualMachines' and identity/principalId eq '%s'", principalID)
+	if region != "" {
+		filter += fmt.Sprintf(" and location eq '%s'", region)
+	}
 	result, err := c.r.List(ctx, filter, "", nil)
 	if err != nil {
 		return "", status.Errorf(codes.Internal, "unable to list virtual machine by principal: %v", err)
@@ -8,11 +8,13 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/profiles/latest/compute/mgmt/compute"
 	"github.com/Azure/azure-sdk-for-go/profiles/latest/network/mgmt/network"
 	"github.com/Azure/go-autorest/autorest"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
+	"github.com/andres-erbsen/clock"
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/hcl"
 	"google.golang.org/grpc/codes"
@@ -29,6 +31,10 @@ import (
 
 const (
 	pluginName = "azure_msi"
+
+	// defaultCacheTTL is how long the selectors resolved for an agent are
+	// cached to avoid being throttled by the Azure Resource Manager API.
+	defaultCacheTTL = 5 * time.Minute
 )
 
 var (
@@ -57,10 +63,13 @@ type TenantConfig struct {
 }
 
 type MSIResolverConfig struct {
-	UseMSI  bool                    `hcl:"use_msi" json:"use_msi"`
-	Tenants map[string]TenantConfig `hcl:"tenants" json:"tenants"`
+	UseMSI   bool                    `hcl:"use_msi" json:"use_msi"`
+	Tenants  map[string]TenantConfig `hcl:"tenants" json:"tenants"`
+	Region   string                  `hcl:"region" json:"region"`
+	CacheTTL string                  `hcl:"cache_ttl" json:"cache_ttl"`
 
 	td            spiffeid.TrustDomain
+	cacheTTL      time.Duration
 	msiClient     apiClient
 	tenantClients map[string]apiClient
 }
@@ -88,15 +97,26 @@ type MSIResolverPlugin struct {
 	mu     sync.RWMutex
 	config *MSIResolverConfig
 
+	cacheMtx sync.Mutex
+	// cache holds the selectors resolved for each agent ID
+	cache map[string]selectorsCacheEntry
+
 	hooks struct {
+		clock                 clock.Clock
 		newClient             func(string, autorest.Authorizer) apiClient
 		fetchInstanceMetadata func(context.Context, azure.HTTPClient) (*azure.InstanceMetadata, error)
 		msiAuthorizer         func() (autorest.Authorizer, error)
 	}
 }
 
+type selectorsCacheEntry struct {
+	selectorValues []string
+	expiresAt      time.Time
+}
+
 func New() *MSIResolverPlugin {
 	p := &MSIResolverPlugin{}
+	p.hooks.clock = clock.New()
 	p.hooks.newClient = newAzureClient
 	p.hooks.fetchInstanceMetadata = azure.FetchInstanceMetadata
 	p.hooks.msiAuthorizer = func() (autorest.Authorizer, error) {
@@ -132,6 +152,17 @@ func (p *MSIResolverPlugin) Configure(ctx context.Context, req *configv1.Configu
 		return nil, status.Errorf(codes.InvalidArgument, err.Error())
 	}
 
+	cacheTTL := defaultCacheTTL
+	if config.CacheTTL != "" {
+		cacheTTL, err = time.ParseDuration(config.CacheTTL)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid cache_ttl %q: %v", config.CacheTTL, err)
+		}
+		if cacheTTL < 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid cache_ttl %q: must not be negative", config.CacheTTL)
+		}
+	}
+
 	var msiClient apiClient
 	var tenantClients map[string]apiClient
 
@@ -172,10 +203,12 @@ func (p *MSIResolverPlugin) Configure(ctx context.Context, req *configv1.Configu
 	}
 
 	config.td = td
+	config.cacheTTL = cacheTTL
 	config.msiClient = msiClient
 	config.tenantClients = tenantClients
 
 	p.setConfig(config)
+	p.resetCache()
 	return &configv1.ConfigureResponse{}, nil
 }
 
@@ -194,6 +227,44 @@ func (p *MSIResolverPlugin) setConfig(config *MSIResolverConfig) {
 	p.config = config
 }
 
+func (p *MSIResolverPlugin) resetCache() {
+	p.cacheMtx.Lock()
+	defer p.cacheMtx.Unlock()
+	p.cache = make(map[string]selectorsCacheEntry)
+}
+
+func (p *MSIResolverPlugin) getCachedSelectorValues(agentID string) ([]string, bool) {
+	p.cacheMtx.Lock()
+	defer p.cacheMtx.Unlock()
+	entry, ok := p.cache[agentID]
+	if !ok || !p.hooks.clock.Now().Before(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.selectorValues, true
+}
+
+func (p *MSIResolverPlugin) setCachedSelectorValues(agentID string, selectorValues []string, ttl time.Duration) {
+	if ttl == 0 {
+		return
+	}
+
+	now := p.hooks.clock.Now()
+
+	p.cacheMtx.Lock()
+	defer p.cacheMtx.Unlock()
+	// Drop the expired entries so that agents that are gone do not pile up
+	// in the cache.
+	for id, entry := range p.cache {
+		if !now.Before(entry.expiresAt) {
+			delete(p.cache, id)
+		}
+	}
+	p.cache[agentID] = selectorsCacheEntry{
+		selectorValues: selectorValues,
+		expiresAt:      now.Add(ttl),
+	}
+}
+
 func (p *MSIResolverPlugin) resolve(ctx context.Context, agentID string) ([]string, error) {
 	config, err := p.getConfig()
 	if err != nil {
@@ -217,8 +288,12 @@ func (p *MSIResolverPlugin) resolve(ctx context.Context, agentID string) ([]stri
 		return nil, err
 	}
 
+	if selectorValues, ok := p.getCachedSelectorValues(agentID); ok {
+		return selectorValues, nil
+	}
+
 	// Retrieve the resource belonging to the principal id.
-	vmResourceID, err := client.GetVirtualMachineResourceID(ctx, principalID)
+	vmResourceID, err := client.GetVirtualMachineResourceID(ctx, principalID, config.Region)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "unable to get resource for principal %q: %v", principalID, err)
 	}
@@ -246,7 +321,8 @@ func (p *MSIResolverPlugin) resolve(ctx context.Context, agentID string) ([]stri
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "unable to get virtual machine %q: %v", resourceGroupName(vmResourceGroup, vmName), err)
 	}
-	if vm.NetworkProfile != nil {
+	addSelectors(getTagSelectors(vm.Tags))
+	if vm.VirtualMachineProperties != nil && vm.NetworkProfile != nil {
 		networkProfileSelectors, err := getNetworkProfileSelectors(ctx, client, vm.NetworkProfile)
 		if err != nil {
 			return nil, err
@@ -261,9 +337,22 @@ func (p *MSIResolverPlugin) resolve(ctx context.Context, agentID string) ([]stri
 	}
 	sort.Strings(selectorValues)
 
+	p.setCachedSelectorValues(agentID, selectorValues, config.cacheTTL)
 	return selectorValues, nil
 }
 
+func getTagSelectors(tags map[string]*string) []string {
+	selectors := make([]string, 0, len(tags))
+	for key, value := range tags {
+		tagValue := ""
+		if value != nil {
+			tagValue = *value
+		}
+		selectors = append(selectors, selectorValue("tag", key, tagValue))
+	}
+	return selectors
+}
+
 func getNetworkProfileSelectors(ctx context.Context, client apiClient, networkProfile *compute.NetworkProfile) ([]string, error) {
 	if networkProfile.NetworkInterfaces == nil {
 		return nil, nil
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/profiles/latest/compute/mgmt/compute"
 	"github.com/Azure/azure-sdk-for-go/profiles/latest/network/mgmt/network"
@@ -14,6 +15,7 @@ import (
 	"github.com/spiffe/spire/pkg/common/plugin/azure"
 	"github.com/spiffe/spire/pkg/server/plugin/noderesolver"
 	"github.com/spiffe/spire/proto/spire/common"
+	"github.com/spiffe/spire/test/clock"
 	"github.com/spiffe/spire/test/plugintest"
 	"github.com/spiffe/spire/test/spiretest"
 	"google.golang.org/grpc/codes"
@@ -52,12 +54,14 @@ func TestMSIResolver(t *testing.T) {
 type MSIResolverSuite struct {
 	spiretest.Suite
 
-	api *fakeAPIClient
+	api   *fakeAPIClient
+	clock *clock.Mock
 }
 
 func (s *MSIResolverSuite) SetupTest() {
 	// set up the API with an initial view of the virtual machine
 	s.api = newFakeAPIClient(s.T())
+	s.clock = clock.NewMock(s.T())
 }
 
 func (s *MSIResolverSuite) TestResolveWithIDFromAnotherTrustDomain() {
@@ -183,6 +187,90 @@ func (s *MSIResolverSuite) TestResolveVirtualMachine() {
 	s.assertResolveSuccess(nr, vmSelectors, niSelectors)
 }
 
+func (s *MSIResolverSuite) TestResolveVirtualMachineTags() {
+	nr := s.loadPluginWithTenant()
+
+	// no tags
+	vm := &compute.VirtualMachine{
+		VirtualMachineProperties: &compute.VirtualMachineProperties{},
+	}
+	s.setVirtualMachine(vm)
+	s.assertResolveSuccess(nr, vmSelectors)
+
+	// tags, including one without a value
+	vm.Tags = map[string]*string{
+		"env":  stringPtr("prod"),
+		"team": stringPtr("payments"),
+		"role": nil,
+	}
+	s.assertResolveSuccess(nr, vmSelectors, []string{
+		"tag:env:prod",
+		"tag:role:",
+		"tag:team:payments",
+	})
+}
+
+func (s *MSIResolverSuite) TestResolveCachesSelectors() {
+	nr := s.loadPlugin(
+		plugintest.CoreConfig(catalog.CoreConfig{
+			TrustDomain: trustDomain,
+		}),
+		plugintest.Configure(`
+		cache_ttl = "1m"
+		tenants = {
+			TENANT = {
+				subscription_id = "SUBSCRIPTION"
+				app_id = "APPID"
+				app_secret = "APPSECRET"
+			}
+		}`))
+
+	// failures are not cached
+	s.assertResolveFailure(nr, azureAgentID,
+		codes.Internal,
+		`noderesolver(azure_msi): unable to get resource for principal "PRINCIPAL": not found`)
+
+	vm := &compute.VirtualMachine{
+		Tags:                     map[string]*string{"env": stringPtr("prod")},
+		VirtualMachineProperties: &compute.VirtualMachineProperties{},
+	}
+	s.setVirtualMachine(vm)
+	s.requireResolve(nr, vmSelectors, []string{"tag:env:prod"})
+	s.Require().Equal(2, s.api.resourceIDLookups)
+
+	// the selectors are served from the cache until it expires
+	vm.Tags["env"] = stringPtr("staging")
+	s.clock.Add(time.Minute - time.Second)
+	s.requireResolve(nr, vmSelectors, []string{"tag:env:prod"})
+	s.Require().Equal(2, s.api.resourceIDLookups)
+
+	s.clock.Add(time.Second)
+	s.requireResolve(nr, vmSelectors, []string{"tag:env:staging"})
+	s.Require().Equal(3, s.api.resourceIDLookups)
+}
+
+func (s *MSIResolverSuite) TestResolveWithRegion() {
+	nr := s.loadPlugin(
+		plugintest.CoreConfig(catalog.CoreConfig{
+			TrustDomain: trustDomain,
+		}),
+		plugintest.Configure(`
+		region = "westus2"
+		tenants = {
+			TENANT = {
+				subscription_id = "SUBSCRIPTION"
+				app_id = "APPID"
+				app_secret = "APPSECRET"
+			}
+		}`))
+
+	s.setVirtualMachine(&compute.VirtualMachine{
+		VirtualMachineProperties: &compute.VirtualMachineProperties{},
+	})
+	s.assertResolveSuccess(nr, vmSelectors)
+	s.Require().Equal("westus2", s.api.lastRegion)
+}
+
 func (s *MSIResolverSuite) TestConfigure() {
 	var err error
 
@@ -257,6 +345,26 @@ func (s *MSIResolverSuite) TestConfigure() {
 	)
 	s.RequireGRPCStatus(err, codes.InvalidArgument, "configuration cannot have tenants when using MSI")
 
+	// malformed cache TTL
+	s.loadPlugin(plugintest.CaptureConfigureError(&err),
+		plugintest.CoreConfig(coreConfig),
+		plugintest.Configure(`
+			use_msi = true
+			cache_ttl = "forever"
+		`),
+	)
+	s.RequireGRPCStatusContains(err, codes.InvalidArgument, `invalid cache_ttl "forever"`)
+
+	// negative cache TTL
+	s.loadPlugin(plugintest.CaptureConfigureError(&err),
+		plugintest.CoreConfig(coreConfig),
+		plugintest.Configure(`
+			use_msi = true
+			cache_ttl = "-1m"
+		`),
+	)
+	s.RequireGRPCStatus(err, codes.InvalidArgument, `invalid cache_ttl "-1m": must not be negative`)
+
 	// MSI only
 	s.loadPlugin(
 		plugintest.CoreConfig(coreConfig),
@@ -281,6 +389,7 @@ func (s *MSIResolverSuite) loadPluginWithTenant() noderesolver.NodeResolver {
 
 func (s *MSIResolverSuite) loadPlugin(options ...plugintest.Option) noderesolver.NodeResolver {
 	resolver := New()
+	resolver.hooks.clock = s.clock
 	resolver.hooks.newClient = func(string, autorest.Authorizer) apiClient {
 		return s.api
 	}
@@ -302,6 +411,12 @@ func (s *MSIResolverSuite) loadPlugin(options ...plugintest.Option) noderesolver
 }
 
 func (s *MSIResolverSuite) assertResolveSuccess(nr noderesolver.NodeResolver, selectorValueSets ...[]string) {
+	// expire the cached selectors so the virtual machine is resolved again
+	s.clock.Add(defaultCacheTTL)
+	s.requireResolve(nr, selectorValueSets...)
+}
+
+func (s *MSIResolverSuite) requireResolve(nr noderesolver.NodeResolver, selectorValueSets ...[]string) {
 	var selectorValues []string
 	for _, values := range selectorValueSets {
 		selectorValues = append(selectorValues, values...)
@@ -322,6 +437,8 @@ func (s *MSIResolverSuite) assertResolveSuccess(nr noderesolver.NodeResolver, se
 }
 
 func (s *MSIResolverSuite) assertResolveFailure(nr noderesolver.NodeResolver, agentID string, code codes.Code, containsMsg string) {
+	// expire the cached selectors so the virtual machine is resolved again
+	s.clock.Add(defaultCacheTTL)
 	selectors, err := nr.Resolve(context.Background(), agentID)
 	s.RequireGRPCStatusContains(err, code, containsMsg)
 	s.Require().Empty(selectors)
@@ -342,6 +459,9 @@ type fakeAPIClient struct {
 	vmResourceIDs     map[string]string
 	virtualMachines   map[string]*compute.VirtualMachine
 	networkInterfaces map[string]*network.Interface
+
+	resourceIDLookups int
+	lastRegion        string
 }
 
 func newFakeAPIClient(t testing.TB) *fakeAPIClient {
@@ -361,7 +481,9 @@ func (c *fakeAPIClient) SetVirtualMachineResourceID(principalID, resourceID stri
 	c.vmResourceIDs[principalID] = resourceID
 }
 
-func (c *fakeAPIClient) GetVirtualMachineResourceID(ctx context.Context, principalID string) (string, error) {
+func (c *fakeAPIClient) GetVirtualMachineResourceID(ctx context.Context, principalID, region string) (string, error) {
+	c.resourceIDLookups++
+	c.lastRegion = region
 	id := c.vmResourceIDs[principalID]
 	if id == "" {
 		return "", errors.New("not found")
@@ -392,3 +514,7 @@ func (c *fakeAPIClient) GetNetworkInterface(ctx context.Context, resourceGroup s
 	}
 	return ni, nil
 }
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -59,6 +59,7 @@ type cluster struct {
 	WebhookLabel       string `hcl:"webhook_label"`
 	APIServiceLabel    string `hcl:"api_service_label"`
 	KubeConfigFilePath string `hcl:"kube_config_file_path"`
+	KubeConfigContext  string `hcl:"kube_config_context"`
 }
 
 type pluginConfig struct {
@@ -139,8 +140,8 @@ func (p *Plugin) Configure(ctx context.Context, req *configv1.ConfigureRequest)
 		setDefaultValues(&config.cluster)
 	}
 	for i := range config.Clusters {
-		if config.Clusters[i].KubeConfigFilePath == "" {
-			return nil, status.Error(codes.InvalidArgument, "cluster configuration is missing kube_config_file_path")
+		if config.Clusters[i].KubeConfigFilePath == "" && config.Clusters[i].KubeConfigContext == "" {
+			return nil, status.Error(codes.InvalidArgument, "cluster configuration is missing kube_config_file_path or kube_config_context")
 		}
 		setDefaultValues(&config.Clusters[i])
 	}
@@ -203,12 +204,14 @@ func (p *Plugin) setConfig(config *pluginConfig) error {
 // If an error is an encountered updating the bundle for an object, we record the
 // error and continue on to the next object
 func (p *Plugin) updateBundles(ctx context.Context, c *pluginConfig) (err error) {
+	// The clients for the clusters that could be reached are still updated
+	// when the clients for other clusters cannot be created.
+	var updateErrs string
 	clients, err := p.hooks.newKubeClients(c)
 	if err != nil {
-		return status.Errorf(codes.Internal, "failed to create kube clients: %v", err)
+		updateErrs += fmt.Sprintf("failed to create kube clients: %v, ", err)
 	}
 
-	var updateErrs string
 	for _, client := range clients {
 		list, err := client.GetList(ctx)
 		if err != nil {
@@ -274,34 +277,43 @@ func (p *Plugin) updateBundle(ctx context.Context, client kubeClient, namespace,
 	})
 }
 
+// newKubeClients creates the clients for all the configured clusters. If the
+// clients for some of the clusters cannot be created, the clients for the
+// remaining clusters are returned along with an error naming the clusters
+// that failed.
 func newKubeClients(c *pluginConfig) ([]kubeClient, error) {
-	clients := []kubeClient{}
-
+	clusters := c.Clusters
 	if hasRootCluster(&c.cluster) {
-		clusterClients, err := newClientsForCluster(c.cluster)
-		if err != nil {
-			return nil, err
-		}
-		clients = append(clients, clusterClients...)
+		clusters = append([]cluster{c.cluster}, clusters...)
 	}
 
-	for _, cluster := range c.Clusters {
+	clients := []kubeClient{}
+	var clusterErrs []string
+	for _, cluster := range clusters {
 		clusterClients, err := newClientsForCluster(cluster)
 		if err != nil {
-			return nil, err
+			clusterErrs = append(clusterErrs, fmt.Sprintf("%s: %v", clusterName(cluster), err))
+			continue
 		}
 		clients = append(clients, clusterClients...)
 	}
 
+	if len(clusterErrs) > 0 {
+		return clients, errors.New(strings.Join(clusterErrs, ", "))
+	}
 	return clients, nil
 }
 
 func newClientsForCluster(c cluster) ([]kubeClient, error) {
-	clientset, err := newKubeClientset(c.KubeConfigFilePath)
+	config, err := getKubeConfig(c.KubeConfigFilePath, c.KubeConfigContext)
 	if err != nil {
 		return nil, err
 	}
-	aggregatorClientset, err := newAggregatorClientset(c.KubeConfigFilePath)
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	aggregatorClientset, err := aggregator.NewForConfig(config)
 	if err != nil {
 		return nil, err
 	}
@@ -336,37 +348,33 @@ func newClientsForCluster(c cluster) ([]kubeClient, error) {
 	return clients, nil
 }
 
-func newKubeClientset(configPath string) (*kubernetes.Clientset, error) {
-	config, err := getKubeConfig(configPath)
-	if err != nil {
-		return nil, err
-	}
-
-	client, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		return nil, err
+func getKubeConfig(configPath, configContext string) (*rest.Config, error) {
+	switch {
+	case configContext != "":
+		// An empty path falls back to the default kubeconfig loading rules
+		loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+		loadingRules.ExplicitPath = configPath
+		return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			loadingRules,
+			&clientcmd.ConfigOverrides{CurrentContext: configContext},
+		).ClientConfig()
+	case configPath != "":
+		return clientcmd.BuildConfigFromFlags("", configPath)
+	default:
+		return rest.InClusterConfig()
 	}
-	return client, nil
 }
 
-func newAggregatorClientset(configPath string) (*aggregator.Clientset, error) {
-	config, err := getKubeConfig(configPath)
-	if err != nil {
-		return nil, err
+// clusterName returns a name for the cluster to be used in error messages
+func clusterName(c cluster) string {
+	name := c.KubeConfigFilePath
+	if name == "" {
+		name = "in-cluster"
 	}
-
-	client, err := aggregator.NewForConfig(config)
-	if err != nil {
-		return nil, err
-	}
-	return client, nil
-}
-
-func getKubeConfig(configPath string) (*rest.Config, error) {
-	if configPath != "" {
-		return clientcmd.BuildConfigFromFlags("", configPath)
+	if c.KubeConfigContext != "" {
+		name += fmt.Sprintf(" (context %q)", c.KubeConfigContext)
 	}
-	return rest.InClusterConfig()
+	return name
 }
 
 // kubeClient encapsulates the Kubenetes API for config maps, validating webhooks, and mutating webhooks
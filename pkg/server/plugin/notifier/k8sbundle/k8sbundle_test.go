@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
 	"runtime/debug"
 	"strconv"
 	"sync"
@@ -85,7 +86,7 @@ func TestNotifyAndAdviseFailsIfNotConfigured(t *testing.T) {
 func TestBundleLoadedWhenCannotCreateClient(t *testing.T) {
 	test := setupTest(t, withKubeClientError())
 	err := test.notifier.NotifyAndAdviseBundleLoaded(context.Background(), commonBundle)
-	spiretest.RequireGRPCStatus(t, err, codes.Internal, "notifier(k8sbundle): failed to create kube clients: kube client not configured")
+	spiretest.RequireGRPCStatus(t, err, codes.Internal, "notifier(k8sbundle): unable to update: failed to create kube clients: kube client not configured")
 }
 
 func TestBundleLoadedConfigMapGetFailure(t *testing.T) {
@@ -220,7 +221,7 @@ clusters  = [
 func TestBundleUpdatedWhenCannotCreateClient(t *testing.T) {
 	test := setupTest(t, withKubeClientError())
 	err := test.notifier.NotifyBundleUpdated(context.Background(), commonBundle)
-	spiretest.RequireGRPCStatus(t, err, codes.Internal, "notifier(k8sbundle): failed to create kube clients: kube client not configured")
+	spiretest.RequireGRPCStatus(t, err, codes.Internal, "notifier(k8sbundle): unable to update: failed to create kube clients: kube client not configured")
 }
 
 func TestBundleUpdatedConfigMapGetFailure(t *testing.T) {
@@ -353,6 +354,93 @@ clusters  = [
 	}, test.kubeClient.getConfigMap("NAMESPACE2", "CONFIGMAP2"))
 }
 
+func TestBundleUpdatedWithMultipleClustersPartialFailure(t *testing.T) {
+	config := `
+clusters  = [
+	{
+		kube_config_file_path = "/cluster1/kubeconfig"
+	},
+	{
+		kube_config_file_path = "/cluster2/kubeconfig"
+	},
+	{
+		kube_config_file_path = "/cluster3/kubeconfig"
+	}
+]
+`
+	clusterConfig := &pluginConfig{cluster: cluster{ConfigMapKey: defaultConfigMapKey}}
+	cluster1 := newFakeKubeClient(clusterConfig, newConfigMap())
+	cluster2 := newFakeKubeClient(clusterConfig, newConfigMap())
+
+	// The clients for the third cluster cannot be created
+	test := setupTest(t, withPlainConfig(config), withNewKubeClients(func(c *pluginConfig) ([]kubeClient, error) {
+		return []kubeClient{cluster1, cluster2}, errors.New("/cluster3/kubeconfig: unreachable")
+	}))
+	test.identityProvider.AppendBundle(testBundle)
+	test.identityProvider.AppendBundle(testBundle)
+
+	err := test.notifier.NotifyBundleUpdated(context.Background(), commonBundle)
+	spiretest.RequireGRPCStatus(t, err, codes.Internal, "notifier(k8sbundle): unable to update: failed to create kube clients: /cluster3/kubeconfig: unreachable")
+
+	// The config maps of the other clusters are still updated
+	for _, client := range []*fakeKubeClient{cluster1, cluster2} {
+		require.Equal(t, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "spire",
+				Name:            "spire-bundle",
+				ResourceVersion: "2",
+			},
+			Data: map[string]string{
+				"bundle.crt": testBundleData,
+			},
+		}, client.getConfigMap("spire", "spire-bundle"))
+	}
+}
+
+func TestNewKubeClients(t *testing.T) {
+	kubeConfigPath := filepath.Join(t.TempDir(), "kubeconfig")
+	require.NoError(t, os.WriteFile(kubeConfigPath, []byte(`
+apiVersion: v1
+kind: Config
+clusters:
+- name: cluster-a
+  cluster:
+    server: https://cluster-a.test
+- name: cluster-b
+  cluster:
+    server: https://cluster-b.test
+contexts:
+- name: context-a
+  context:
+    cluster: cluster-a
+- name: context-b
+  context:
+    cluster: cluster-b
+current-context: context-a
+`), 0600))
+
+	clients, err := newKubeClients(&pluginConfig{
+		Clusters: []cluster{
+			{KubeConfigFilePath: kubeConfigPath, KubeConfigContext: "context-b"},
+			{KubeConfigFilePath: kubeConfigPath},
+			{KubeConfigFilePath: kubeConfigPath, KubeConfigContext: "context-c"},
+			{KubeConfigFilePath: filepath.Join(t.TempDir(), "missing")},
+		},
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), fmt.Sprintf(`%s (context "context-c"): `, kubeConfigPath))
+	require.Contains(t, err.Error(), "missing: ")
+
+	// The clients for the clusters that could be configured are returned
+	var hosts []string
+	for _, client := range clients {
+		configMapClient, ok := client.(configMapClient)
+		require.True(t, ok)
+		hosts = append(hosts, configMapClient.CoreV1().RESTClient().Get().URL().Host)
+	}
+	require.Equal(t, []string{"cluster-b.test", "cluster-a.test"}, hosts)
+}
+
 func TestConfigureWithMalformedConfiguration(t *testing.T) {
 	configuration := "blah"
 	test := setupTest(t, withNoConfigure())
@@ -493,9 +581,40 @@ func TestConfigure(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "clusters with kubeconfig contexts",
+			configuration: `
+			clusters  = [
+			{
+				kube_config_file_path = "/clusters/file/path"
+				kube_config_context = "cluster1"
+			},
+			{
+				kube_config_context = "cluster2"
+			},
+			]
+			`,
+			expectedConfig: &pluginConfig{
+				Clusters: []cluster{
+					{
+						Namespace:          "spire",
+						ConfigMap:          "spire-bundle",
+						ConfigMapKey:       "bundle.crt",
+						KubeConfigFilePath: "/clusters/file/path",
+						KubeConfigContext:  "cluster1",
+					},
+					{
+						Namespace:         "spire",
+						ConfigMap:         "spire-bundle",
+						ConfigMapKey:      "bundle.crt",
+						KubeConfigContext: "cluster2",
+					},
+				},
+			},
+		},
 		{
 			name:         "clusters only missing kube_config_file_path",
-			expectedErr:  "cluster configuration is missing kube_config_file_path",
+			expectedErr:  "cluster configuration is missing kube_config_file_path or kube_config_context",
 			expectedCode: codes.InvalidArgument,
 			configuration: `
 			clusters  = [
@@ -675,6 +794,7 @@ type testOptions struct {
 	plainConfig     string
 	kubeClientError bool
 	doConfigure     bool
+	newKubeClients  func(c *pluginConfig) ([]kubeClient, error)
 }
 
 type testOption func(*testOptions)
@@ -691,6 +811,12 @@ func withKubeClientError() testOption {
 	}
 }
 
+func withNewKubeClients(newKubeClients func(c *pluginConfig) ([]kubeClient, error)) testOption {
+	return func(args *testOptions) {
+		args.newKubeClients = newKubeClients
+	}
+}
+
 func withNoConfigure() testOption {
 	return func(args *testOptions) {
 		args.doConfigure = false
@@ -744,6 +870,9 @@ func setupTest(t *testing.T, options ...testOption) *test {
 
 		return test.clients, nil
 	}
+	if args.newKubeClients != nil {
+		raw.hooks.newKubeClients = args.newKubeClients
+	}
 
 	if args.doConfigure {
 		plugintest.Load(
@@ -0,0 +1,347 @@
+package webhookbundle
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl"
+	notifierv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/server/notifier/v1"
+	plugintypes "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/types"
+	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
+	"github.com/spiffe/spire/pkg/common/bundleutil"
+	"github.com/spiffe/spire/pkg/common/catalog"
+	"github.com/spiffe/spire/pkg/common/coretypes/bundle"
+	"github.com/spiffe/spire/pkg/common/telemetry"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	formatPEM    = "pem"
+	formatSPIFFE = "spiffe"
+
+	defaultMaxRetries   = 3
+	defaultRetryBackoff = time.Second
+	defaultTimeout      = 10 * time.Second
+
+	// SignatureHeader carries the hex encoded HMAC-SHA256 of the request body
+	// when a secret is configured.
+	SignatureHeader = "X-SPIRE-Signature"
+	// TrustDomainHeader carries the trust domain of the bundle being posted.
+	TrustDomainHeader = "X-SPIRE-Trust-Domain"
+)
+
+func BuiltIn() catalog.BuiltIn {
+	return builtIn(New())
+}
+
+func builtIn(p *Plugin) catalog.BuiltIn {
+	return catalog.MakeBuiltIn("webhook_bundle",
+		notifierv1.NotifierPluginServer(p),
+		configv1.ConfigServiceServer(p),
+	)
+}
+
+type pluginConfig struct {
+	URLs         []string `hcl:"urls"`
+	Format       string   `hcl:"format"`
+	HMACSecret   string   `hcl:"hmac_secret"`
+	MaxRetries   *int     `hcl:"max_retries"`
+	RetryBackoff string   `hcl:"retry_backoff"`
+	Timeout      string   `hcl:"timeout"`
+
+	maxRetries   int
+	retryBackoff time.Duration
+	timeout      time.Duration
+}
+
+type Plugin struct {
+	notifierv1.UnsafeNotifierServer
+	configv1.UnsafeConfigServer
+
+	mu     sync.RWMutex
+	log    hclog.Logger
+	config *pluginConfig
+	client *http.Client
+
+	// loadedMu guards cancelLoaded, which cancels the posting of the loaded
+	// bundle if still in progress.
+	loadedMu     sync.Mutex
+	cancelLoaded context.CancelFunc
+}
+
+func New() *Plugin {
+	return &Plugin{}
+}
+
+func (p *Plugin) SetLogger(log hclog.Logger) {
+	p.log = log
+}
+
+func (p *Plugin) Notify(ctx context.Context, req *notifierv1.NotifyRequest) (*notifierv1.NotifyResponse, error) {
+	config, client, err := p.getConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if event, ok := req.Event.(*notifierv1.NotifyRequest_BundleUpdated); ok {
+		// The updated bundle supersedes the loaded one, which must not be
+		// posted after it.
+		p.setCancelLoaded(nil)
+		if err := p.postBundle(ctx, config, client, event.BundleUpdated.Bundle); err != nil {
+			return nil, err
+		}
+	}
+	return &notifierv1.NotifyResponse{}, nil
+}
+
+func (p *Plugin) NotifyAndAdvise(ctx context.Context, req *notifierv1.NotifyAndAdviseRequest) (*notifierv1.NotifyAndAdviseResponse, error) {
+	config, client, err := p.getConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if event, ok := req.Event.(*notifierv1.NotifyAndAdviseRequest_BundleLoaded); ok {
+		// Webhook receivers are outside of SPIRE's control; an unreachable
+		// receiver must not delay the server startup, so the bundle is posted
+		// in the background and failures are only logged.
+		ctx, cancel := context.WithCancel(context.Background())
+		p.setCancelLoaded(cancel)
+		go func() {
+			defer cancel()
+			if err := p.postBundle(ctx, config, client, event.BundleLoaded.Bundle); err != nil && ctx.Err() == nil {
+				p.log.Warn("Failed to notify webhooks of loaded bundle", telemetry.Error, status.Convert(err).Message())
+			}
+		}()
+	}
+	return &notifierv1.NotifyAndAdviseResponse{}, nil
+}
+
+// setCancelLoaded cancels the posting of the loaded bundle in progress, if
+// any, and keeps the given function to cancel the next one.
+func (p *Plugin) setCancelLoaded(cancel context.CancelFunc) {
+	p.loadedMu.Lock()
+	defer p.loadedMu.Unlock()
+	if p.cancelLoaded != nil {
+		p.cancelLoaded()
+	}
+	p.cancelLoaded = cancel
+}
+
+func (p *Plugin) Configure(ctx context.Context, req *configv1.ConfigureRequest) (*configv1.ConfigureResponse, error) {
+	config := new(pluginConfig)
+	if err := hcl.Decode(config, req.HclConfiguration); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unable to decode configuration: %v", err)
+	}
+
+	if len(config.URLs) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "urls must be set")
+	}
+	for _, rawURL := range config.URLs {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid url %q: %v", rawURL, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid url %q: scheme must be http or https", rawURL)
+		}
+	}
+
+	switch config.Format {
+	case "":
+		config.Format = formatPEM
+	case formatPEM, formatSPIFFE:
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "format must be %q or %q", formatPEM, formatSPIFFE)
+	}
+
+	config.maxRetries = defaultMaxRetries
+	if config.MaxRetries != nil {
+		if *config.MaxRetries < 0 {
+			return nil, status.Error(codes.InvalidArgument, "max_retries cannot be negative")
+		}
+		config.maxRetries = *config.MaxRetries
+	}
+
+	var err error
+	if config.retryBackoff, err = parseDuration(config.RetryBackoff, defaultRetryBackoff); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid retry_backoff: %v", err)
+	}
+	if config.timeout, err = parseDuration(config.Timeout, defaultTimeout); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid timeout: %v", err)
+	}
+
+	p.setConfig(config, &http.Client{Timeout: config.timeout})
+	return &configv1.ConfigureResponse{}, nil
+}
+
+func (p *Plugin) getConfig() (*pluginConfig, *http.Client, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, nil, status.Error(codes.FailedPrecondition, "not configured")
+	}
+	return p.config, p.client, nil
+}
+
+func (p *Plugin) setConfig(config *pluginConfig, client *http.Client) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+	p.client = client
+}
+
+// postBundle posts the bundle to every configured webhook. Every webhook is
+// attempted even if a previous one fails.
+func (p *Plugin) postBundle(ctx context.Context, c *pluginConfig, client *http.Client, b *plugintypes.Bundle) error {
+	body, contentType, err := encodeBundle(c.Format, b)
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to encode bundle: %v", err)
+	}
+
+	var failed []string
+	for _, webhookURL := range c.URLs {
+		if err := p.postWithRetries(ctx, c, client, webhookURL, b.TrustDomain, contentType, body); err != nil {
+			p.log.Error("Failed to notify webhook", telemetry.Address, webhookURL, telemetry.Error, err)
+			failed = append(failed, fmt.Sprintf("%s: %v", webhookURL, err))
+			continue
+		}
+		p.log.Debug("Webhook notified", telemetry.Address, webhookURL)
+	}
+
+	if len(failed) > 0 {
+		return status.Errorf(codes.Unavailable, "unable to notify webhooks: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+func (p *Plugin) postWithRetries(ctx context.Context, c *pluginConfig, client *http.Client, webhookURL, trustDomain, contentType string, body []byte) error {
+	backoff := c.retryBackoff
+	for attempt := 0; ; attempt++ {
+		err := post(ctx, client, webhookURL, trustDomain, contentType, c.HMACSecret, body)
+		if err == nil {
+			return nil
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) || attempt == c.maxRetries {
+			return err
+		}
+
+		p.log.Debug("Webhook notification failed; retrying", telemetry.Address, webhookURL, telemetry.Error, err, telemetry.RetryInterval, backoff)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// permanentError wraps errors that will not go away if the request is retried.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+func post(ctx context.Context, client *http.Client, webhookURL, trustDomain, contentType, secret string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return &permanentError{err: err}
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set(TrustDomainHeader, trustDomain)
+	if secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign([]byte(secret), body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	default:
+		return &permanentError{err: fmt.Errorf("unexpected status code %d", resp.StatusCode)}
+	}
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of the body using the given
+// secret. Webhook receivers can use it to verify the signature header.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func encodeBundle(format string, b *plugintypes.Bundle) ([]byte, string, error) {
+	switch format {
+	case formatSPIFFE:
+		commonBundle, err := bundle.ToCommonFromPluginProto(b)
+		if err != nil {
+			return nil, "", err
+		}
+		spiffeBundle, err := bundleutil.BundleFromProto(commonBundle)
+		if err != nil {
+			return nil, "", err
+		}
+		data, err := bundleutil.Marshal(spiffeBundle)
+		if err != nil {
+			return nil, "", err
+		}
+		return data, "application/json", nil
+	default:
+		bundleData := new(bytes.Buffer)
+		for _, x509Authority := range b.X509Authorities {
+			// no need to check the error since we're encoding into a memory buffer
+			_ = pem.Encode(bundleData, &pem.Block{
+				Type:  "CERTIFICATE",
+				Bytes: x509Authority.Asn1,
+			})
+		}
+		return bundleData.Bytes(), "application/x-pem-file", nil
+	}
+}
+
+func parseDuration(s string, defaultValue time.Duration) (time.Duration, error) {
+	if s == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, errors.New("must be positive")
+	}
+	return d, nil
+}
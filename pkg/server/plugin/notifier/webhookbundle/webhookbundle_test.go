@@ -0,0 +1,368 @@
+package webhookbundle
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/spire/pkg/common/bundleutil"
+	"github.com/spiffe/spire/pkg/server/plugin/notifier"
+	"github.com/spiffe/spire/proto/spire/common"
+	"github.com/spiffe/spire/test/plugintest"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/spiffe/spire/test/testca"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+var trustDomain = spiffeid.RequireTrustDomainFromString("example.org")
+
+func TestConfigure(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		config string
+		code   codes.Code
+		desc   string
+	}{
+		{
+			name:   "malformed",
+			config: "MALFORMED",
+			code:   codes.InvalidArgument,
+			desc:   "unable to decode configuration",
+		},
+		{
+			name:   "missing urls",
+			config: ``,
+			code:   codes.InvalidArgument,
+			desc:   "urls must be set",
+		},
+		{
+			name:   "invalid url scheme",
+			config: `urls = ["ftp://example.org"]`,
+			code:   codes.InvalidArgument,
+			desc:   `invalid url "ftp://example.org": scheme must be http or https`,
+		},
+		{
+			name: "invalid format",
+			config: `
+				urls = ["https://example.org"]
+				format = "der"
+			`,
+			code: codes.InvalidArgument,
+			desc: `format must be "pem" or "spiffe"`,
+		},
+		{
+			name: "negative max retries",
+			config: `
+				urls = ["https://example.org"]
+				max_retries = -1
+			`,
+			code: codes.InvalidArgument,
+			desc: "max_retries cannot be negative",
+		},
+		{
+			name: "invalid retry backoff",
+			config: `
+				urls = ["https://example.org"]
+				retry_backoff = "soon"
+			`,
+			code: codes.InvalidArgument,
+			desc: "invalid retry_backoff",
+		},
+		{
+			name: "invalid timeout",
+			config: `
+				urls = ["https://example.org"]
+				timeout = "0s"
+			`,
+			code: codes.InvalidArgument,
+			desc: "invalid timeout: must be positive",
+		},
+		{
+			name: "success",
+			config: `
+				urls = ["https://example.org", "http://localhost:8080/bundle"]
+				format = "spiffe"
+				hmac_secret = "secret"
+				max_retries = 0
+				retry_backoff = "500ms"
+				timeout = "5s"
+			`,
+			code: codes.OK,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			plugintest.Load(t, BuiltIn(), nil,
+				plugintest.Configure(tt.config),
+				plugintest.CaptureConfigureError(&err))
+			if tt.code != codes.OK {
+				spiretest.RequireGRPCStatusContains(t, err, tt.code, tt.desc)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestNotifyNotConfigured(t *testing.T) {
+	plugin := new(notifier.V1)
+	plugintest.Load(t, BuiltIn(), plugin)
+
+	err := plugin.NotifyBundleUpdated(context.Background(), &common.Bundle{TrustDomainId: "spiffe://example.org"})
+	spiretest.RequireGRPCStatus(t, err, codes.FailedPrecondition, "notifier(webhook_bundle): not configured")
+}
+
+func TestNotifyBundleUpdatedPEM(t *testing.T) {
+	ca := testca.New(t, trustDomain)
+	webhook := newFakeWebhook(t)
+	plugin := loadPlugin(t, fmt.Sprintf(`urls = [%q]`, webhook.url))
+
+	err := plugin.NotifyBundleUpdated(context.Background(), bundleutil.BundleProtoFromRootCAs(trustDomain.IDString(), ca.X509Authorities()))
+	require.NoError(t, err)
+
+	requests := webhook.Requests()
+	require.Len(t, requests, 1)
+	require.Equal(t, "application/x-pem-file", requests[0].contentType)
+	require.Equal(t, "example.org", requests[0].trustDomain)
+	require.Empty(t, requests[0].signature)
+
+	block, rest := pem.Decode(requests[0].body)
+	require.NotNil(t, block)
+	require.Empty(t, rest)
+	require.Equal(t, ca.X509Authorities()[0].Raw, block.Bytes)
+}
+
+func TestNotifyBundleUpdatedSPIFFE(t *testing.T) {
+	ca := testca.New(t, trustDomain)
+	webhook := newFakeWebhook(t)
+	plugin := loadPlugin(t, fmt.Sprintf(`
+		urls = [%q]
+		format = "spiffe"
+	`, webhook.url))
+
+	err := plugin.NotifyBundleUpdated(context.Background(), bundleutil.BundleProtoFromRootCAs(trustDomain.IDString(), ca.X509Authorities()))
+	require.NoError(t, err)
+
+	requests := webhook.Requests()
+	require.Len(t, requests, 1)
+	require.Equal(t, "application/json", requests[0].contentType)
+
+	received, err := bundleutil.Unmarshal(trustDomain, requests[0].body)
+	require.NoError(t, err)
+	require.Equal(t, ca.X509Authorities(), received.RootCAs())
+}
+
+func TestNotifyBundleUpdatedSignsRequests(t *testing.T) {
+	ca := testca.New(t, trustDomain)
+	webhook := newFakeWebhook(t)
+	plugin := loadPlugin(t, fmt.Sprintf(`
+		urls = [%q]
+		hmac_secret = "the-secret"
+	`, webhook.url))
+
+	err := plugin.NotifyBundleUpdated(context.Background(), bundleutil.BundleProtoFromRootCAs(trustDomain.IDString(), ca.X509Authorities()))
+	require.NoError(t, err)
+
+	requests := webhook.Requests()
+	require.Len(t, requests, 1)
+	require.Equal(t, "sha256="+Sign([]byte("the-secret"), requests[0].body), requests[0].signature)
+	require.NotEqual(t, "sha256="+Sign([]byte("another-secret"), requests[0].body), requests[0].signature)
+}
+
+func TestNotifyBundleUpdatedRetries(t *testing.T) {
+	ca := testca.New(t, trustDomain)
+	bundle := bundleutil.BundleProtoFromRootCAs(trustDomain.IDString(), ca.X509Authorities())
+
+	for _, tt := range []struct {
+		name             string
+		statusCodes      []int
+		expectedRequests int
+		code             codes.Code
+		desc             string
+	}{
+		{
+			name:             "succeeds after transient failures",
+			statusCodes:      []int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+			expectedRequests: 3,
+			code:             codes.OK,
+		},
+		{
+			name:             "gives up after max retries",
+			statusCodes:      []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError},
+			expectedRequests: 3,
+			code:             codes.Unavailable,
+			desc:             "unexpected status code 500",
+		},
+		{
+			name:             "does not retry client errors",
+			statusCodes:      []int{http.StatusBadRequest},
+			expectedRequests: 1,
+			code:             codes.Unavailable,
+			desc:             "unexpected status code 400",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			webhook := newFakeWebhook(t)
+			webhook.SetStatusCodes(tt.statusCodes...)
+			plugin := loadPlugin(t, fmt.Sprintf(`
+				urls = [%q]
+				max_retries = 2
+				retry_backoff = "1ms"
+			`, webhook.url))
+
+			err := plugin.NotifyBundleUpdated(context.Background(), bundle)
+			require.Len(t, webhook.Requests(), tt.expectedRequests)
+			if tt.code != codes.OK {
+				spiretest.RequireGRPCStatusContains(t, err, tt.code, tt.desc)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestNotifyBundleUpdatedNotifiesAllWebhooks(t *testing.T) {
+	ca := testca.New(t, trustDomain)
+	failing := newFakeWebhook(t)
+	failing.SetStatusCodes(http.StatusForbidden)
+	webhook := newFakeWebhook(t)
+	plugin := loadPlugin(t, fmt.Sprintf(`urls = [%q, %q]`, failing.url, webhook.url))
+
+	err := plugin.NotifyBundleUpdated(context.Background(), bundleutil.BundleProtoFromRootCAs(trustDomain.IDString(), ca.X509Authorities()))
+	spiretest.RequireGRPCStatusContains(t, err, codes.Unavailable, failing.url+": unexpected status code 403")
+	require.Len(t, failing.Requests(), 1)
+	require.Len(t, webhook.Requests(), 1)
+}
+
+func TestNotifyAndAdviseBundleLoaded(t *testing.T) {
+	ca := testca.New(t, trustDomain)
+	bundle := bundleutil.BundleProtoFromRootCAs(trustDomain.IDString(), ca.X509Authorities())
+
+	t.Run("success", func(t *testing.T) {
+		webhook := newFakeWebhook(t)
+		plugin := loadPlugin(t, fmt.Sprintf(`urls = [%q]`, webhook.url))
+
+		require.NoError(t, plugin.NotifyAndAdviseBundleLoaded(context.Background(), bundle))
+		require.Eventually(t, func() bool {
+			return len(webhook.Requests()) == 1
+		}, time.Minute, 10*time.Millisecond)
+	})
+
+	t.Run("failures do not block", func(t *testing.T) {
+		// The webhook fails and the retry is far off, so returning at all
+		// means the retries do not block.
+		webhook := newFakeWebhook(t)
+		webhook.SetStatusCodes(http.StatusBadGateway)
+		plugin := loadPlugin(t, fmt.Sprintf(`
+			urls = [%q]
+			retry_backoff = "1h"
+		`, webhook.url))
+
+		require.NoError(t, plugin.NotifyAndAdviseBundleLoaded(context.Background(), bundle))
+		require.Eventually(t, func() bool {
+			return len(webhook.Requests()) == 1
+		}, time.Minute, 10*time.Millisecond)
+
+		// A bundle update cancels the pending retries, so the loaded bundle
+		// is never posted after the updated one.
+		require.NoError(t, plugin.NotifyBundleUpdated(context.Background(), bundle))
+		require.Len(t, webhook.Requests(), 2)
+	})
+
+	t.Run("unresponsive webhooks do not block", func(t *testing.T) {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer server.Close()
+		defer close(release)
+		plugin := loadPlugin(t, fmt.Sprintf(`urls = [%q]`, server.URL))
+
+		done := make(chan error, 1)
+		go func() {
+			done <- plugin.NotifyAndAdviseBundleLoaded(context.Background(), bundle)
+		}()
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(time.Minute):
+			require.FailNow(t, "notifying the loaded bundle blocked on the webhook")
+		}
+	})
+}
+
+func loadPlugin(t *testing.T, config string) *notifier.V1 {
+	plugin := new(notifier.V1)
+	plugintest.Load(t, BuiltIn(), plugin, plugintest.Configure(config))
+	return plugin
+}
+
+type webhookRequest struct {
+	contentType string
+	trustDomain string
+	signature   string
+	body        []byte
+}
+
+type fakeWebhook struct {
+	t   *testing.T
+	url string
+
+	mu          sync.Mutex
+	statusCodes []int
+	requests    []webhookRequest
+}
+
+func newFakeWebhook(t *testing.T) *fakeWebhook {
+	w := &fakeWebhook{t: t}
+	server := httptest.NewServer(http.HandlerFunc(w.serveHTTP))
+	t.Cleanup(server.Close)
+	w.url = server.URL
+	return w
+}
+
+// SetStatusCodes sets the status codes returned by the upcoming requests.
+// Requests beyond those succeed.
+func (w *fakeWebhook) SetStatusCodes(statusCodes ...int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.statusCodes = statusCodes
+}
+
+func (w *fakeWebhook) Requests() []webhookRequest {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]webhookRequest(nil), w.requests...)
+}
+
+func (w *fakeWebhook) serveHTTP(rw http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	require.NoError(w.t, err)
+	require.Equal(w.t, http.MethodPost, r.Method)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.requests = append(w.requests, webhookRequest{
+		contentType: r.Header.Get("Content-Type"),
+		trustDomain: r.Header.Get(TrustDomainHeader),
+		signature:   r.Header.Get(SignatureHeader),
+		body:        body,
+	})
+
+	if len(w.statusCodes) > 0 {
+		rw.WriteHeader(w.statusCodes[0])
+		w.statusCodes = w.statusCodes[1:]
+		return
+	}
+	rw.WriteHeader(http.StatusOK)
+}
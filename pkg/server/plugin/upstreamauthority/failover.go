@@ -0,0 +1,184 @@
+package upstreamauthority
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andres-erbsen/clock"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/spire/pkg/common/telemetry"
+	"github.com/spiffe/spire/proto/spire/common"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// failoverMinBackoff is how long an upstream authority is deprioritized
+	// after its first consecutive failure. The backoff doubles with each
+	// consecutive failure, up to failoverMaxBackoff.
+	failoverMinBackoff = 30 * time.Second
+	failoverMaxBackoff = 30 * time.Minute
+)
+
+// Failover is an UpstreamAuthority that delegates to an ordered list of
+// upstream authorities, trying each in order until one succeeds. Upstream
+// authorities that fail are backed off: they are tried after the healthy
+// ones until their backoff period elapses.
+type Failover struct {
+	log      logrus.FieldLogger
+	clk      clock.Clock
+	backends []*failoverBackend
+
+	mu sync.Mutex
+}
+
+type failoverBackend struct {
+	UpstreamAuthority
+
+	consecutiveFailures int
+	backoffUntil        time.Time
+}
+
+// NewFailover returns a Failover over the given upstream authorities, in
+// order of preference.
+func NewFailover(log logrus.FieldLogger, clk clock.Clock, upstreamAuthorities []UpstreamAuthority) *Failover {
+	backends := make([]*failoverBackend, 0, len(upstreamAuthorities))
+	for _, upstreamAuthority := range upstreamAuthorities {
+		backends = append(backends, &failoverBackend{UpstreamAuthority: upstreamAuthority})
+	}
+	return &Failover{
+		log:      log,
+		clk:      clk,
+		backends: backends,
+	}
+}
+
+// Name returns the names of the upstream authorities, in order of
+// preference.
+func (f *Failover) Name() string {
+	names := make([]string, 0, len(f.backends))
+	for _, backend := range f.backends {
+		names = append(names, backend.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+func (f *Failover) Type() string {
+	return "UpstreamAuthority"
+}
+
+// MintX509CA mints the X509 CA with the first upstream authority that
+// succeeds.
+func (f *Failover) MintX509CA(ctx context.Context, csr []byte, preferredTTL time.Duration) ([]*x509.Certificate, []*x509.Certificate, UpstreamX509AuthorityStream, error) {
+	var failures []string
+	for _, backend := range f.candidates() {
+		x509CA, upstreamX509Authorities, stream, err := backend.MintX509CA(ctx, csr, preferredTTL)
+		if err == nil {
+			f.recordSuccess(backend, len(failures) > 0)
+			return x509CA, upstreamX509Authorities, stream, nil
+		}
+		f.recordFailure(backend, err)
+		failures = append(failures, fmt.Sprintf("%s: %v", backend.Name(), err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, nil, nil, status.Errorf(codes.Unavailable, "all upstream authorities failed to mint the X509 CA: %s", strings.Join(failures, "; "))
+}
+
+// PublishJWTKey publishes the JWT key with the first upstream authority that
+// succeeds. Upstream authorities that do not support publishing JWT keys are
+// skipped.
+func (f *Failover) PublishJWTKey(ctx context.Context, jwtKey *common.PublicKey) ([]*common.PublicKey, UpstreamJWTAuthorityStream, error) {
+	var failures []string
+	for _, backend := range f.candidates() {
+		jwtAuthorities, stream, err := backend.PublishJWTKey(ctx, jwtKey)
+		switch {
+		case err == nil:
+			f.recordSuccess(backend, len(failures) > 0)
+			return jwtAuthorities, stream, nil
+		case status.Code(err) == codes.Unimplemented:
+			continue
+		}
+		f.recordFailure(backend, err)
+		failures = append(failures, fmt.Sprintf("%s: %v", backend.Name(), err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if len(failures) == 0 {
+		return nil, nil, status.Error(codes.Unimplemented, "no upstream authority supports publishing JWT keys")
+	}
+	return nil, nil, status.Errorf(codes.Unavailable, "all upstream authorities failed to publish the JWT key: %s", strings.Join(failures, "; "))
+}
+
+// candidates returns the upstream authorities in the order they should be
+// tried: the ones that are not backing off first, then the rest. Each group
+// keeps the configured order.
+func (f *Failover) candidates() []*failoverBackend {
+	now := f.clk.Now()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	candidates := make([]*failoverBackend, 0, len(f.backends))
+	var backingOff []*failoverBackend
+	for _, backend := range f.backends {
+		if now.Before(backend.backoffUntil) {
+			backingOff = append(backingOff, backend)
+			continue
+		}
+		candidates = append(candidates, backend)
+	}
+	return append(candidates, backingOff...)
+}
+
+func (f *Failover) recordSuccess(backend *failoverBackend, failedOver bool) {
+	f.mu.Lock()
+	recovered := backend.consecutiveFailures > 0
+	backend.consecutiveFailures = 0
+	backend.backoffUntil = time.Time{}
+	f.mu.Unlock()
+
+	log := f.log.WithField(telemetry.PluginName, backend.Name())
+	switch {
+	case failedOver:
+		log.Warn("Failed over to a fallback upstream authority")
+	case recovered:
+		log.Info("Upstream authority recovered")
+	}
+}
+
+func (f *Failover) recordFailure(backend *failoverBackend, err error) {
+	now := f.clk.Now()
+
+	f.mu.Lock()
+	backend.consecutiveFailures++
+	backoff := failoverBackoff(backend.consecutiveFailures)
+	backend.backoffUntil = now.Add(backoff)
+	consecutiveFailures := backend.consecutiveFailures
+	f.mu.Unlock()
+
+	f.log.WithError(err).WithFields(logrus.Fields{
+		telemetry.PluginName:          backend.Name(),
+		telemetry.ConsecutiveFailures: consecutiveFailures,
+		telemetry.RetryInterval:       backoff,
+	}).Warn("Upstream authority failed; backing off")
+}
+
+// failoverBackoff returns how long an upstream authority is backed off after
+// the given number of consecutive failures.
+func failoverBackoff(consecutiveFailures int) time.Duration {
+	backoff := failoverMinBackoff
+	for i := 1; i < consecutiveFailures && backoff < failoverMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > failoverMaxBackoff {
+		backoff = failoverMaxBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,171 @@
+package upstreamauthority
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiffe/spire/proto/spire/common"
+	"github.com/spiffe/spire/test/clock"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFailoverMintX509CA(t *testing.T) {
+	clk := clock.NewMock(t)
+	log, _ := test.NewNullLogger()
+
+	primary := &fakeFailoverUpstreamAuthority{name: "primary", x509CA: &x509.Certificate{SerialNumber: big.NewInt(1)}}
+	secondary := &fakeFailoverUpstreamAuthority{name: "secondary", x509CA: &x509.Certificate{SerialNumber: big.NewInt(2)}}
+	failover := NewFailover(log, clk, []UpstreamAuthority{primary, secondary})
+	require.Equal(t, "primary,secondary", failover.Name())
+
+	mint := func() *x509.Certificate {
+		x509CA, _, stream, err := failover.MintX509CA(context.Background(), nil, time.Hour)
+		require.NoError(t, err)
+		stream.Close()
+		return x509CA[0]
+	}
+
+	// The primary is used while it is healthy
+	require.Equal(t, primary.x509CA, mint())
+	require.Equal(t, 1, primary.mintCalls)
+	require.Equal(t, 0, secondary.mintCalls)
+
+	// The secondary mints when the primary fails
+	primary.err = errors.New("oh no")
+	require.Equal(t, secondary.x509CA, mint())
+	require.Equal(t, 2, primary.mintCalls)
+	require.Equal(t, 1, secondary.mintCalls)
+
+	// The primary is not tried while backing off, even if it recovered
+	primary.err = nil
+	require.Equal(t, secondary.x509CA, mint())
+	require.Equal(t, 2, primary.mintCalls)
+	require.Equal(t, 2, secondary.mintCalls)
+
+	// The primary is preferred again once the backoff elapses
+	clk.Add(failoverMinBackoff)
+	require.Equal(t, primary.x509CA, mint())
+	require.Equal(t, 3, primary.mintCalls)
+	require.Equal(t, 2, secondary.mintCalls)
+
+	// Upstream authorities backing off are still tried when the healthy ones
+	// fail
+	primary.err = errors.New("oh no")
+	require.Equal(t, secondary.x509CA, mint())
+	require.Equal(t, []string{"secondary", "primary"}, names(failover.candidates()))
+	secondary.err = errors.New("oh no")
+	primary.err = nil
+	require.Equal(t, primary.x509CA, mint())
+	require.Equal(t, []string{"primary", "secondary"}, names(failover.candidates()))
+
+	// All the failures are reported when every upstream authority fails
+	primary.err = errors.New("primary is down")
+	secondary.err = errors.New("secondary is down")
+	_, _, _, err := failover.MintX509CA(context.Background(), nil, time.Hour)
+	spiretest.RequireGRPCStatus(t, err, codes.Unavailable, "all upstream authorities failed to mint the X509 CA: primary: primary is down; secondary: secondary is down")
+}
+
+func TestFailoverPublishJWTKey(t *testing.T) {
+	clk := clock.NewMock(t)
+	log, _ := test.NewNullLogger()
+
+	jwtKey := &common.PublicKey{Kid: "KEYID"}
+
+	unimplemented := &fakeFailoverUpstreamAuthority{name: "unimplemented", err: status.Error(codes.Unimplemented, "not supported")}
+	primary := &fakeFailoverUpstreamAuthority{name: "primary"}
+	secondary := &fakeFailoverUpstreamAuthority{name: "secondary"}
+
+	// Upstream authorities that do not support publishing are skipped
+	failover := NewFailover(log, clk, []UpstreamAuthority{unimplemented, primary, secondary})
+	jwtAuthorities, stream, err := failover.PublishJWTKey(context.Background(), jwtKey)
+	require.NoError(t, err)
+	stream.Close()
+	spiretest.RequireProtoListEqual(t, []*common.PublicKey{jwtKey}, jwtAuthorities)
+	require.Equal(t, 1, primary.publishCalls)
+	require.Equal(t, 0, secondary.publishCalls)
+	require.Equal(t, []string{"unimplemented", "primary", "secondary"}, names(failover.candidates()))
+
+	// The secondary publishes when the primary fails
+	primary.err = errors.New("oh no")
+	_, stream, err = failover.PublishJWTKey(context.Background(), jwtKey)
+	require.NoError(t, err)
+	stream.Close()
+	require.Equal(t, 2, primary.publishCalls)
+	require.Equal(t, 1, secondary.publishCalls)
+
+	// Unimplemented is returned when no upstream authority supports it
+	failover = NewFailover(log, clk, []UpstreamAuthority{unimplemented})
+	_, _, err = failover.PublishJWTKey(context.Background(), jwtKey)
+	spiretest.RequireGRPCStatus(t, err, codes.Unimplemented, "no upstream authority supports publishing JWT keys")
+}
+
+func TestFailoverBackoff(t *testing.T) {
+	require.Equal(t, 30*time.Second, failoverBackoff(1))
+	require.Equal(t, time.Minute, failoverBackoff(2))
+	require.Equal(t, 2*time.Minute, failoverBackoff(3))
+	require.Equal(t, 16*time.Minute, failoverBackoff(6))
+	require.Equal(t, 30*time.Minute, failoverBackoff(7))
+	require.Equal(t, 30*time.Minute, failoverBackoff(1000))
+}
+
+func names(backends []*failoverBackend) []string {
+	var names []string
+	for _, backend := range backends {
+		names = append(names, backend.Name())
+	}
+	return names
+}
+
+type fakeFailoverUpstreamAuthority struct {
+	name   string
+	x509CA *x509.Certificate
+	err    error
+
+	mintCalls    int
+	publishCalls int
+}
+
+func (ua *fakeFailoverUpstreamAuthority) Name() string {
+	return ua.name
+}
+
+func (ua *fakeFailoverUpstreamAuthority) Type() string {
+	return "UpstreamAuthority"
+}
+
+func (ua *fakeFailoverUpstreamAuthority) MintX509CA(ctx context.Context, csr []byte, preferredTTL time.Duration) ([]*x509.Certificate, []*x509.Certificate, UpstreamX509AuthorityStream, error) {
+	ua.mintCalls++
+	if ua.err != nil {
+		return nil, nil, nil, ua.err
+	}
+	return []*x509.Certificate{ua.x509CA}, nil, fakeFailoverStream{}, nil
+}
+
+func (ua *fakeFailoverUpstreamAuthority) PublishJWTKey(ctx context.Context, jwtKey *common.PublicKey) ([]*common.PublicKey, UpstreamJWTAuthorityStream, error) {
+	ua.publishCalls++
+	if ua.err != nil {
+		return nil, nil, ua.err
+	}
+	return []*common.PublicKey{jwtKey}, fakeFailoverStream{}, nil
+}
+
+type fakeFailoverStream struct{}
+
+func (fakeFailoverStream) RecvUpstreamX509Authorities() ([]*x509.Certificate, error) {
+	return nil, io.EOF
+}
+
+func (fakeFailoverStream) RecvUpstreamJWTAuthorities() ([]*common.PublicKey, error) {
+	return nil, io.EOF
+}
+
+func (fakeFailoverStream) Close() {}
@@ -2,6 +2,10 @@ package upstreamauthority
 
 type Repository struct {
 	UpstreamAuthority UpstreamAuthority
+
+	// UpstreamAuthorities holds every loaded UpstreamAuthority plugin, in
+	// load order. UpstreamAuthority is chosen from them once loading is done.
+	UpstreamAuthorities []UpstreamAuthority
 }
 
 func (repo *Repository) GetUpstreamAuthority() (UpstreamAuthority, bool) {
@@ -12,10 +16,15 @@ func (repo *Repository) SetUpstreamAuthority(upstreamAuthority UpstreamAuthority
 	repo.UpstreamAuthority = upstreamAuthority
 }
 
+func (repo *Repository) AddUpstreamAuthority(upstreamAuthority UpstreamAuthority) {
+	repo.UpstreamAuthorities = append(repo.UpstreamAuthorities, upstreamAuthority)
+}
+
 func (repo *Repository) ClearUpstreamAuthority() {
 	repo.UpstreamAuthority = nil
 }
 
 func (repo *Repository) Clear() {
 	repo.UpstreamAuthority = nil
+	repo.UpstreamAuthorities = nil
 }
@@ -9,6 +9,8 @@ import (
 	"github.com/spiffe/spire/pkg/common/telemetry"
 	telemetry_server "github.com/spiffe/spire/pkg/common/telemetry/server"
 	"github.com/spiffe/spire/pkg/server/datastore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
@@ -23,6 +25,10 @@ type ManagerConfig struct {
 	Metrics telemetry.Metrics
 
 	Clock clock.Clock
+
+	// DeadNodeTTL is how long after its SVID expires an attested node is
+	// considered dead and pruned. Dead nodes are not pruned when zero.
+	DeadNodeTTL time.Duration
 }
 
 // Manager is the manager of registrations
@@ -61,6 +67,11 @@ func (m *Manager) pruneEvery(ctx context.Context) error {
 			if err := m.prune(ctx); err != nil && ctx.Err() == nil {
 				m.log.WithError(err).Error("Failed pruning registration entries")
 			}
+			if m.c.DeadNodeTTL > 0 {
+				if err := m.pruneNodes(ctx); err != nil && ctx.Err() == nil {
+					m.log.WithError(err).Error("Failed pruning dead attested nodes")
+				}
+			}
 		case <-ctx.Done():
 			return nil
 		}
@@ -74,3 +85,44 @@ func (m *Manager) prune(ctx context.Context) (err error) {
 	err = m.c.DataStore.PruneRegistrationEntries(ctx, m.c.Clock.Now())
 	return err
 }
+
+// pruneNodes deletes the attested nodes whose SVID expired more than
+// DeadNodeTTL ago. Agents can't authenticate with an expired SVID, so these
+// nodes can't be connected to the server. Nodes with a pending SVID that
+// has not expired that long ago are kept, as are banned nodes, so that they
+// stay banned.
+func (m *Manager) pruneNodes(ctx context.Context) (err error) {
+	counter := telemetry_server.StartRegistrationManagerPruneNodeCall(m.c.Metrics)
+	defer counter.Done(&err)
+
+	expiresBefore := m.c.Clock.Now().Add(-m.c.DeadNodeTTL)
+	banned := false
+	resp, err := m.c.DataStore.ListAttestedNodes(ctx, &datastore.ListAttestedNodesRequest{
+		ByBanned:        &banned,
+		ByExpiresBefore: expiresBefore,
+	})
+	if err != nil {
+		return err
+	}
+
+	pruned := 0
+	for _, node := range resp.Nodes {
+		if node.NewCertNotAfter >= expiresBefore.Unix() {
+			continue
+		}
+
+		_, err := m.c.DataStore.DeleteAttestedNode(ctx, node.SpiffeId)
+		switch status.Code(err) {
+		case codes.OK:
+			m.log.WithField(telemetry.SPIFFEID, node.SpiffeId).Info("Pruned dead attested node")
+			pruned++
+		case codes.NotFound:
+			// Deleted since it was listed
+		default:
+			return err
+		}
+	}
+
+	telemetry_server.SetRegistrationManagerPrunedNodesGauge(m.c.Metrics, pruned)
+	return nil
+}
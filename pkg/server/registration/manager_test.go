@@ -2,6 +2,7 @@ package registration
 
 import (
 	"context"
+	"reflect"
 	"testing"
 	"time"
 
@@ -126,6 +127,78 @@ func (s *ManagerSuite) TestPruning() {
 	s.Empty(listResp.Entries)
 }
 
+func (s *ManagerSuite) TestPruneNodes() {
+	ctx := context.Background()
+	s.m = NewManager(ManagerConfig{
+		Clock:       s.clock,
+		DataStore:   s.ds,
+		Log:         s.log,
+		Metrics:     s.metrics,
+		DeadNodeTTL: time.Hour,
+	})
+
+	now := s.clock.Now()
+	createNode := func(id, serial string, notAfter, newNotAfter time.Time) {
+		node := &common.AttestedNode{
+			SpiffeId:         id,
+			CertSerialNumber: serial,
+			CertNotAfter:     notAfter.Unix(),
+		}
+		if !newNotAfter.IsZero() {
+			node.NewCertSerialNumber = "new-" + serial
+			node.NewCertNotAfter = newNotAfter.Unix()
+		}
+		_, err := s.ds.CreateAttestedNode(ctx, node)
+		s.Require().NoError(err)
+	}
+
+	createNode("spiffe://test.test/expired", "1", now.Add(-time.Minute), time.Time{})
+	createNode("spiffe://test.test/valid", "2", now.Add(time.Hour), time.Time{})
+	createNode("spiffe://test.test/rotating", "3", now.Add(-time.Minute), now.Add(time.Hour))
+	createNode("spiffe://test.test/banned", "", now.Add(-time.Minute), time.Time{})
+
+	// Nodes are not pruned before the TTL elapses
+	s.Require().NoError(s.m.pruneNodes(ctx))
+	s.assertNodes("spiffe://test.test/banned", "spiffe://test.test/expired", "spiffe://test.test/rotating", "spiffe://test.test/valid")
+	s.assertPrunedNodesGauge(0)
+
+	// The expired node is pruned once the TTL elapses. The node with a
+	// pending SVID may still be connected.
+	s.clock.Add(time.Hour)
+	s.Require().NoError(s.m.pruneNodes(ctx))
+	s.assertNodes("spiffe://test.test/banned", "spiffe://test.test/rotating", "spiffe://test.test/valid")
+	s.assertPrunedNodesGauge(1)
+
+	// Banned nodes are never pruned
+	s.clock.Add(2 * time.Hour)
+	s.Require().NoError(s.m.pruneNodes(ctx))
+	s.assertNodes("spiffe://test.test/banned")
+	s.assertPrunedNodesGauge(2)
+}
+
+func (s *ManagerSuite) assertNodes(expectedIDs ...string) {
+	resp, err := s.ds.ListAttestedNodes(context.Background(), &datastore.ListAttestedNodesRequest{})
+	s.Require().NoError(err)
+
+	var ids []string
+	for _, node := range resp.Nodes {
+		ids = append(ids, node.SpiffeId)
+	}
+	s.ElementsMatch(expectedIDs, ids)
+}
+
+func (s *ManagerSuite) assertPrunedNodesGauge(expected float32) {
+	var gauge *fakemetrics.MetricItem
+	for _, metric := range s.metrics.AllMetrics() {
+		metric := metric
+		if metric.Type == fakemetrics.SetGaugeType && reflect.DeepEqual(metric.Key, []string{"node", "manager", "pruned"}) {
+			gauge = &metric
+		}
+	}
+	s.Require().NotNil(gauge)
+	s.Equal(expected, gauge.Val)
+}
+
 func (s *ManagerSuite) setupAndRunManager() func() {
 	s.m = NewManager(ManagerConfig{
 		Clock:     s.clock,
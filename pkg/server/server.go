@@ -138,7 +138,7 @@ func (s *Server) run(ctx context.Context) (err error) {
 		return fmt.Errorf("unable to obtain authpolicy engine: %w", err)
 	}
 
-	bundleManager := s.newBundleManager(cat, metrics)
+	bundleManager := s.newBundleManager(cat, svidRotator, metrics)
 
 	endpointsServer, err := s.newEndpointsServer(ctx, cat, svidRotator, serverCA, metrics, caManager, authPolicyEngine, bundleManager)
 	if err != nil {
@@ -255,17 +255,26 @@ func (s *Server) loadCatalog(ctx context.Context, metrics telemetry.Metrics, ide
 		IdentityProvider: identityProvider,
 		AgentStore:       agentStore,
 		HealthChecker:    healthChecker,
+
+		UpstreamAuthorityOrder: s.config.UpstreamAuthorityOrder,
+		DataStoreMaxAttempts:   s.config.DataStoreMaxAttempts,
 	})
 }
 
 func (s *Server) newCA(metrics telemetry.Metrics, healthChecker health.Checker) *ca.CA {
 	return ca.NewCA(ca.Config{
+		Log:           s.config.Log.WithField(telemetry.SubsystemName, telemetry.CA),
 		Metrics:       metrics,
 		X509SVIDTTL:   s.config.SVIDTTL,
 		JWTIssuer:     s.config.JWTIssuer,
 		TrustDomain:   s.config.TrustDomain,
 		CASubject:     s.config.CASubject,
 		HealthChecker: healthChecker,
+
+		CRLDistributionPoint:        s.config.CRLDistributionPoint,
+		JWTAllowedSigningAlgorithms: s.config.JWTAllowedSigningAlgorithms,
+		MaxX509SVIDTTL:              s.config.MaxX509SVIDTTL,
+		MaxJWTSVIDTTL:               s.config.MaxJWTSVIDTTL,
 	})
 }
 
@@ -291,9 +300,10 @@ func (s *Server) newCAManager(ctx context.Context, cat catalog.Catalog, metrics
 
 func (s *Server) newRegistrationManager(cat catalog.Catalog, metrics telemetry.Metrics) *registration.Manager {
 	registrationManager := registration.NewManager(registration.ManagerConfig{
-		DataStore: cat.GetDataStore(),
-		Log:       s.config.Log.WithField(telemetry.SubsystemName, telemetry.RegistrationManager),
-		Metrics:   metrics,
+		DataStore:   cat.GetDataStore(),
+		Log:         s.config.Log.WithField(telemetry.SubsystemName, telemetry.RegistrationManager),
+		Metrics:     metrics,
+		DeadNodeTTL: s.config.DeadNodeTTL,
 	})
 	return registrationManager
 }
@@ -329,18 +339,24 @@ func (s *Server) newEndpointsServer(ctx context.Context, catalog catalog.Catalog
 		Clock:               clock.New(),
 		CacheReloadInterval: s.config.CacheReloadInterval,
 		AuditLogEnabled:     s.config.AuditLogEnabled,
+		AuditLog:            s.config.AuditLog,
 		AuthPolicyEngine:    authPolicyEngine,
 		BundleManager:       bundleManager,
 		AdminIDs:            s.config.AdminIDs,
+		EnableReflection:    s.config.AdminAPIReflectionEnabled,
+		MaxRecvMessageSize:  s.config.MaxRecvMessageSize,
+		MaxSendMessageSize:  s.config.MaxSendMessageSize,
+		TLSPolicy:           s.config.TLSPolicy,
 	}
 	if s.config.Federation.BundleEndpoint != nil {
 		config.BundleEndpoint.Address = s.config.Federation.BundleEndpoint.Address
 		config.BundleEndpoint.ACME = s.config.Federation.BundleEndpoint.ACME
+		config.BundleEndpoint.AllowedClientIDsFile = s.config.Federation.BundleEndpoint.AllowedClientIDsFile
 	}
 	return endpoints.New(ctx, config)
 }
 
-func (s *Server) newBundleManager(cat catalog.Catalog, metrics telemetry.Metrics) *bundle_client.Manager {
+func (s *Server) newBundleManager(cat catalog.Catalog, svidObserver svid.Observer, metrics telemetry.Metrics) *bundle_client.Manager {
 	log := s.config.Log.WithField(telemetry.SubsystemName, "bundle_client")
 	return bundle_client.NewManager(bundle_client.ManagerConfig{
 		Log:       log,
@@ -350,6 +366,7 @@ func (s *Server) newBundleManager(cat catalog.Catalog, metrics telemetry.Metrics
 			bundle_client.TrustDomainConfigMap(s.config.Federation.FederatesWith),
 			bundle_client.DataStoreTrustDomainConfigSource(log, cat.GetDataStore()),
 		),
+		SVIDSource: svid.X509SVIDSource{Observer: svidObserver},
 	})
 }
 
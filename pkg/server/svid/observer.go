@@ -1,5 +1,11 @@
 package svid
 
+import (
+	"errors"
+
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+)
+
 // Observer is a convenience interface for subsystems that only want to
 // observer the current SVID state but don't care about other rotator
 // methods.
@@ -12,3 +18,25 @@ type ObserverFunc func() State
 func (fn ObserverFunc) State() State {
 	return fn()
 }
+
+// X509SVIDSource adapts an Observer to an x509svid.Source, for subsystems
+// that authenticate with the server SVID using go-spiffe.
+type X509SVIDSource struct {
+	Observer Observer
+}
+
+func (s X509SVIDSource) GetX509SVID() (*x509svid.SVID, error) {
+	state := s.Observer.State()
+	if len(state.SVID) == 0 {
+		return nil, errors.New("no server SVID available")
+	}
+	id, err := x509svid.IDFromCert(state.SVID[0])
+	if err != nil {
+		return nil, err
+	}
+	return &x509svid.SVID{
+		ID:           id,
+		Certificates: state.SVID,
+		PrivateKey:   state.Key,
+	}, nil
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andres-erbsen/clock"
+	"github.com/sirupsen/logrus"
+	"github.com/zeebo/errs"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+const (
+	acmeChallengeTLSALPN01 = "tls-alpn-01"
+	acmeChallengeDNS01     = "dns-01"
+
+	// acmeAccountKeyName is the cache key of the ACME account key. It is the
+	// same one used by autocert so that the account is kept when switching
+	// challenge types.
+	acmeAccountKeyName = "acme_account+key"
+
+	// dns01ObtainTimeout bounds obtaining a certificate, which includes
+	// waiting for the DNS provider to apply the challenge records.
+	dns01ObtainTimeout = 10 * time.Minute
+
+	// dns01CleanUpTimeout bounds removing the challenge records, which is
+	// done even if obtaining the certificate timed out or was canceled.
+	dns01CleanUpTimeout = time.Minute
+)
+
+// acmeClient is the subset of the ACME client used to obtain certificates
+// with dns-01 challenges.
+type acmeClient interface {
+	Register(ctx context.Context, acct *acme.Account, prompt func(tosURL string) bool) (*acme.Account, error)
+	AuthorizeOrder(ctx context.Context, id []acme.AuthzID, opt ...acme.OrderOption) (*acme.Order, error)
+	GetAuthorization(ctx context.Context, url string) (*acme.Authorization, error)
+	DNS01ChallengeRecord(token string) (string, error)
+	Accept(ctx context.Context, chal *acme.Challenge) (*acme.Challenge, error)
+	WaitAuthorization(ctx context.Context, url string) (*acme.Authorization, error)
+	WaitOrder(ctx context.Context, url string) (*acme.Order, error)
+	CreateOrderCert(ctx context.Context, url string, csr []byte, bundle bool) (der [][]byte, certURL string, err error)
+}
+
+type dns01ManagerConfig struct {
+	Log         logrus.FieldLogger
+	Client      acmeClient
+	Cache       autocert.Cache
+	Email       string
+	Prompt      func(tosURL string) bool
+	HostPolicy  autocert.HostPolicy
+	RenewBefore time.Duration
+	Provider    dnsProvider
+	Clock       clock.Clock
+}
+
+// dns01Manager obtains certificates from an ACME CA answering dns-01
+// challenges through a DNS provider, which autocert does not support. Like
+// autocert, a certificate is obtained for each domain requested via SNI,
+// and is renewed in the background once it is due.
+type dns01Manager struct {
+	c dns01ManagerConfig
+
+	registerMu sync.Mutex
+	registered bool
+
+	mu       sync.Mutex
+	certs    map[string]*tls.Certificate
+	renewing map[string]bool
+
+	// obtainLocks serialize obtaining the certificate of each domain, so
+	// that a slow issuance only holds up the handshakes for its own domain.
+	obtainLocks map[string]*sync.Mutex
+}
+
+func newDNS01Manager(config dns01ManagerConfig) *dns01Manager {
+	if config.Clock == nil {
+		config.Clock = clock.New()
+	}
+	if config.RenewBefore <= 0 {
+		config.RenewBefore = defaultACMERenewBefore
+	}
+	return &dns01Manager{
+		c:           config,
+		certs:       make(map[string]*tls.Certificate),
+		renewing:    make(map[string]bool),
+		obtainLocks: make(map[string]*sync.Mutex),
+	}
+}
+
+func (m *dns01Manager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	domain := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+	if domain == "" {
+		return nil, errs.New("missing server name")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dns01ObtainTimeout)
+	defer cancel()
+
+	if err := m.c.HostPolicy(ctx, domain); err != nil {
+		return nil, err
+	}
+
+	cert := m.cachedCert(ctx, domain)
+	if cert != nil {
+		now := m.c.Clock.Now()
+		if now.Before(cert.Leaf.NotAfter.Add(-m.c.RenewBefore)) {
+			return cert, nil
+		}
+		if now.Before(cert.Leaf.NotAfter) {
+			m.renewInBackground(domain)
+			return cert, nil
+		}
+	}
+
+	return m.obtain(ctx, domain)
+}
+
+func (m *dns01Manager) renewBefore() time.Duration {
+	return m.c.RenewBefore
+}
+
+// cachedCert returns the certificate for the domain held in memory or, if
+// there is none, the one persisted in the cache.
+func (m *dns01Manager) cachedCert(ctx context.Context, domain string) *tls.Certificate {
+	m.mu.Lock()
+	cert, ok := m.certs[domain]
+	m.mu.Unlock()
+	if ok || m.c.Cache == nil {
+		return cert
+	}
+
+	data, err := m.c.Cache.Get(ctx, domain)
+	if err != nil {
+		if !errors.Is(err, autocert.ErrCacheMiss) {
+			m.c.Log.WithError(err).WithField("domain", domain).Warn("Failed to load cached ACME certificate")
+		}
+		return nil
+	}
+	cert, err = decodeCertificate(data)
+	if err != nil {
+		m.c.Log.WithError(err).WithField("domain", domain).Warn("Ignoring invalid cached ACME certificate")
+		return nil
+	}
+
+	m.mu.Lock()
+	m.certs[domain] = cert
+	m.mu.Unlock()
+	return cert
+}
+
+func (m *dns01Manager) renewInBackground(domain string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.renewing[domain] {
+		return
+	}
+	m.renewing[domain] = true
+
+	go func() {
+		defer func() {
+			m.mu.Lock()
+			delete(m.renewing, domain)
+			m.mu.Unlock()
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), dns01ObtainTimeout)
+		defer cancel()
+		if _, err := m.obtain(ctx, domain); err != nil {
+			m.c.Log.WithError(err).WithField("domain", domain).Warn("Failed to renew ACME certificate")
+		}
+	}()
+}
+
+// obtainLock returns the lock serializing obtaining the certificate of the
+// domain. Domains are restricted by the host policy, so the locks are kept.
+func (m *dns01Manager) obtainLock(domain string) *sync.Mutex {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lock, ok := m.obtainLocks[domain]
+	if !ok {
+		lock = new(sync.Mutex)
+		m.obtainLocks[domain] = lock
+	}
+	return lock
+}
+
+func (m *dns01Manager) obtain(ctx context.Context, domain string) (*tls.Certificate, error) {
+	lock := m.obtainLock(domain)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// The certificate may have been obtained while waiting for the lock
+	if cert := m.cachedCert(ctx, domain); cert != nil && m.c.Clock.Now().Before(cert.Leaf.NotAfter.Add(-m.c.RenewBefore)) {
+		return cert, nil
+	}
+
+	if err := m.register(ctx); err != nil {
+		return nil, err
+	}
+
+	order, err := m.c.Client.AuthorizeOrder(ctx, acme.DomainIDs(domain))
+	if err != nil {
+		return nil, errs.New("failed to create ACME order for %q: %v", domain, err)
+	}
+	for _, authzURL := range order.AuthzURLs {
+		if err := m.authorize(ctx, authzURL); err != nil {
+			return nil, err
+		}
+	}
+	order, err = m.c.Client.WaitOrder(ctx, order.URI)
+	if err != nil {
+		return nil, errs.New("ACME order for %q failed: %v", domain, err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: domain},
+		DNSNames: []string{domain},
+	}, key)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	der, _, err := m.c.Client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, errs.New("failed to finalize ACME order for %q: %v", domain, err)
+	}
+	if len(der) == 0 {
+		return nil, errs.New("ACME CA returned no certificate for %q", domain)
+	}
+	leaf, err := x509.ParseCertificate(der[0])
+	if err != nil {
+		return nil, errs.New("ACME CA returned an invalid certificate for %q: %v", domain, err)
+	}
+	cert := &tls.Certificate{
+		Certificate: der,
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}
+
+	if m.c.Cache != nil {
+		data, err := encodeCertificate(cert)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.c.Cache.Put(ctx, domain, data); err != nil {
+			m.c.Log.WithError(err).WithField("domain", domain).Warn("Failed to cache ACME certificate")
+		}
+	}
+
+	m.mu.Lock()
+	m.certs[domain] = cert
+	m.mu.Unlock()
+	return cert, nil
+}
+
+// authorize answers the dns-01 challenge of a pending authorization.
+func (m *dns01Manager) authorize(ctx context.Context, authzURL string) error {
+	authz, err := m.c.Client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return errs.New("failed to get ACME authorization: %v", err)
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+
+	var chal *acme.Challenge
+	for _, c := range authz.Challenges {
+		if c.Type == acmeChallengeDNS01 {
+			chal = c
+			break
+		}
+	}
+	if chal == nil {
+		return errs.New("ACME CA did not offer a dns-01 challenge for %q", authz.Identifier.Value)
+	}
+
+	value, err := m.c.Client.DNS01ChallengeRecord(chal.Token)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	fqdn := "_acme-challenge." + authz.Identifier.Value + "."
+	if err := m.c.Provider.Present(ctx, fqdn, value); err != nil {
+		return errs.New("failed to present dns-01 challenge record: %v", err)
+	}
+	defer func() {
+		// The records are cleaned up with a context of their own, since ctx
+		// is already done if obtaining the certificate timed out or was
+		// canceled.
+		ctx, cancel := context.WithTimeout(context.Background(), dns01CleanUpTimeout)
+		defer cancel()
+		if err := m.c.Provider.CleanUp(ctx, fqdn, value); err != nil {
+			m.c.Log.WithError(err).WithField("record", fqdn).Warn("Failed to clean up dns-01 challenge record")
+		}
+	}()
+
+	if _, err := m.c.Client.Accept(ctx, chal); err != nil {
+		return errs.New("failed to accept dns-01 challenge: %v", err)
+	}
+	if _, err := m.c.Client.WaitAuthorization(ctx, authz.URI); err != nil {
+		return errs.New("ACME authorization for %q failed: %v", authz.Identifier.Value, err)
+	}
+	return nil
+}
+
+// register registers the ACME account the first time a certificate is
+// obtained. Accounts that already exist are reused.
+func (m *dns01Manager) register(ctx context.Context) error {
+	m.registerMu.Lock()
+	defer m.registerMu.Unlock()
+	if m.registered {
+		return nil
+	}
+	var contact []string
+	if m.c.Email != "" {
+		contact = []string{"mailto:" + m.c.Email}
+	}
+	if _, err := m.c.Client.Register(ctx, &acme.Account{Contact: contact}, m.c.Prompt); err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return errs.New("failed to register ACME account: %v", err)
+	}
+	m.registered = true
+	return nil
+}
+
+// acmeAccountKey loads the ACME account key from the cache, generating and
+// caching a new one if there is none.
+func acmeAccountKey(ctx context.Context, cache autocert.Cache) (crypto.Signer, error) {
+	if cache != nil {
+		data, err := cache.Get(ctx, acmeAccountKeyName)
+		switch {
+		case err == nil:
+			block, _ := pem.Decode(data)
+			if block == nil || block.Type != "EC PRIVATE KEY" {
+				return nil, errs.New("invalid cached ACME account key")
+			}
+			return x509.ParseECPrivateKey(block.Bytes)
+		case !errors.Is(err, autocert.ErrCacheMiss):
+			return nil, errs.New("failed to load ACME account key: %v", err)
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	if cache != nil {
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, errs.Wrap(err)
+		}
+		if err := cache.Put(ctx, acmeAccountKeyName, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})); err != nil {
+			return nil, errs.New("failed to cache ACME account key: %v", err)
+		}
+	}
+	return key, nil
+}
+
+// encodeCertificate encodes the certificate as autocert does: the PEM
+// encoded private key followed by the PEM encoded chain.
+func encodeCertificate(cert *tls.Certificate) ([]byte, error) {
+	keyDER, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	buf := new(bytes.Buffer)
+	if err := pem.Encode(buf, &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}); err != nil {
+		return nil, errs.Wrap(err)
+	}
+	for _, der := range cert.Certificate {
+		if err := pem.Encode(buf, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+			return nil, errs.Wrap(err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeCertificate(data []byte) (*tls.Certificate, error) {
+	block, rest := pem.Decode(data)
+	if block == nil || block.Type != "EC PRIVATE KEY" {
+		return nil, errs.New("missing private key")
+	}
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+
+	cert := &tls.Certificate{PrivateKey: key}
+	for {
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		cert.Certificate = append(cert.Certificate, block.Bytes)
+	}
+	if len(cert.Certificate) == 0 {
+		return nil, errs.New("missing certificate")
+	}
+	cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	if !key.PublicKey.Equal(cert.Leaf.PublicKey) {
+		return nil, errs.New("private key does not match the certificate")
+	}
+	return cert, nil
+}
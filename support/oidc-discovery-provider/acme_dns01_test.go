@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiffe/spire/test/clock"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/spiffe/spire/test/testkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+func TestDNS01ManagerObtainsCertificate(t *testing.T) {
+	test := setupDNS01Manager(t)
+
+	cert, err := test.manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "domain.test"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"domain.test"}, cert.Leaf.DNSNames)
+
+	// The challenge record was presented before the challenge was accepted
+	// and cleaned up afterwards
+	assert.Equal(t, []string{
+		"present _acme-challenge.domain.test. record-token-domain.test",
+		"cleanup _acme-challenge.domain.test. record-token-domain.test",
+	}, test.provider.calls)
+	assert.True(t, test.client.registered)
+
+	// The certificate is served from memory until it is due for renewal
+	again, err := test.manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "DOMAIN.test."})
+	require.NoError(t, err)
+	require.Same(t, cert, again)
+	require.Equal(t, 1, test.client.issued)
+
+	// The certificate is cached, so a new manager does not obtain another one
+	other := newDNS01Manager(test.config)
+	cached, err := other.GetCertificate(&tls.ClientHelloInfo{ServerName: "domain.test"})
+	require.NoError(t, err)
+	require.Equal(t, cert.Certificate, cached.Certificate)
+	require.Equal(t, 1, test.client.issued)
+}
+
+func TestDNS01ManagerRenewsCertificate(t *testing.T) {
+	test := setupDNS01Manager(t)
+
+	cert, err := test.manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "domain.test"})
+	require.NoError(t, err)
+
+	// Once due, the current certificate is served while it is renewed in the
+	// background
+	test.clk.Set(cert.Leaf.NotAfter.Add(-time.Hour))
+	due, err := test.manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "domain.test"})
+	require.NoError(t, err)
+	require.Same(t, cert, due)
+
+	require.Eventually(t, func() bool {
+		renewed, err := test.manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "domain.test"})
+		return err == nil && renewed.Leaf.NotAfter.After(cert.Leaf.NotAfter)
+	}, time.Minute, 10*time.Millisecond)
+}
+
+func TestDNS01ManagerFailures(t *testing.T) {
+	t.Run("host not allowed", func(t *testing.T) {
+		test := setupDNS01Manager(t)
+		_, err := test.manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "other.test"})
+		require.EqualError(t, err, `acme/autocert: host "other.test" not configured in HostWhitelist`)
+		require.Empty(t, test.provider.calls)
+	})
+
+	t.Run("missing server name", func(t *testing.T) {
+		test := setupDNS01Manager(t)
+		_, err := test.manager.GetCertificate(&tls.ClientHelloInfo{})
+		require.EqualError(t, err, "missing server name")
+	})
+
+	t.Run("DNS provider fails", func(t *testing.T) {
+		test := setupDNS01Manager(t)
+		test.provider.presentErr = errors.New("oh no")
+		_, err := test.manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "domain.test"})
+		require.EqualError(t, err, "failed to present dns-01 challenge record: oh no")
+	})
+
+	t.Run("no dns-01 challenge offered", func(t *testing.T) {
+		test := setupDNS01Manager(t)
+		test.client.challengeType = acmeChallengeTLSALPN01
+		_, err := test.manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "domain.test"})
+		require.EqualError(t, err, `ACME CA did not offer a dns-01 challenge for "domain.test"`)
+		require.Empty(t, test.provider.calls)
+	})
+}
+
+func TestDNS01ManagerCleansUpAfterTimeout(t *testing.T) {
+	test := setupDNS01Manager(t)
+	test.client.waitAuthorization = func(ctx context.Context, domain string) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := test.manager.obtain(ctx, "domain.test")
+	require.EqualError(t, err, `ACME authorization for "domain.test" failed: context deadline exceeded`)
+
+	// The challenge record is removed with a context that is not done yet
+	assert.Equal(t, []string{
+		"present _acme-challenge.domain.test. record-token-domain.test",
+		"cleanup _acme-challenge.domain.test. record-token-domain.test",
+	}, test.provider.calls)
+	assert.Equal(t, []error{nil}, test.provider.cleanUpErrs)
+	assert.Empty(t, test.provider.record("_acme-challenge.domain.test."))
+}
+
+func TestDNS01ManagerObtainsDomainsConcurrently(t *testing.T) {
+	test := setupDNS01Manager(t)
+	test.config.HostPolicy = autocert.HostWhitelist("domain.test", "other.test")
+	test.manager = newDNS01Manager(test.config)
+
+	blocked := make(chan struct{})
+	release := make(chan struct{})
+	test.client.waitAuthorization = func(ctx context.Context, domain string) error {
+		if domain == "domain.test" {
+			close(blocked)
+			<-release
+		}
+		return nil
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := test.manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "domain.test"})
+		errCh <- err
+	}()
+	<-blocked
+
+	// The handshakes for other domains are not held up while the
+	// certificate of domain.test is being obtained
+	cert, err := test.manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "other.test"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"other.test"}, cert.Leaf.DNSNames)
+
+	close(release)
+	require.NoError(t, <-errCh)
+}
+
+func TestACMEAccountKey(t *testing.T) {
+	cache := autocert.DirCache(spiretest.TempDir(t))
+
+	key, err := acmeAccountKey(context.Background(), cache)
+	require.NoError(t, err)
+
+	// The key is loaded from the cache afterwards
+	cached, err := acmeAccountKey(context.Background(), cache)
+	require.NoError(t, err)
+	require.Equal(t, key, cached)
+}
+
+type dns01ManagerTest struct {
+	clk      *clock.Mock
+	client   *fakeACMEClient
+	provider *fakeDNSProvider
+	config   dns01ManagerConfig
+	manager  *dns01Manager
+}
+
+func setupDNS01Manager(t *testing.T) *dns01ManagerTest {
+	log, _ := test.NewNullLogger()
+	clk := clock.NewMock(t)
+	provider := new(fakeDNSProvider)
+	client := &fakeACMEClient{
+		t:             t,
+		clk:           clk,
+		provider:      provider,
+		challengeType: acmeChallengeDNS01,
+	}
+	config := dns01ManagerConfig{
+		Log:         log,
+		Client:      client,
+		Cache:       autocert.DirCache(spiretest.TempDir(t)),
+		Email:       "admin@domain.test",
+		Prompt:      autocert.AcceptTOS,
+		HostPolicy:  autocert.HostWhitelist("domain.test"),
+		RenewBefore: 30 * 24 * time.Hour,
+		Provider:    provider,
+		Clock:       clk,
+	}
+	return &dns01ManagerTest{
+		clk:      clk,
+		client:   client,
+		provider: provider,
+		config:   config,
+		manager:  newDNS01Manager(config),
+	}
+}
+
+type fakeDNSProvider struct {
+	mu         sync.Mutex
+	calls      []string
+	records    map[string]string
+	presentErr error
+
+	// cleanUpErrs holds the error of the context of each clean up, if any
+	cleanUpErrs []error
+}
+
+func (p *fakeDNSProvider) Present(ctx context.Context, fqdn, value string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.presentErr != nil {
+		return p.presentErr
+	}
+	p.calls = append(p.calls, "present "+fqdn+" "+value)
+	if p.records == nil {
+		p.records = make(map[string]string)
+	}
+	p.records[fqdn] = value
+	return nil
+}
+
+func (p *fakeDNSProvider) CleanUp(ctx context.Context, fqdn, value string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, "cleanup "+fqdn+" "+value)
+	p.cleanUpErrs = append(p.cleanUpErrs, ctx.Err())
+	delete(p.records, fqdn)
+	return nil
+}
+
+func (p *fakeDNSProvider) record(fqdn string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.records[fqdn]
+}
+
+// fakeACMEClient issues certificates for the single domain of each order
+// once the challenge record has been presented through the DNS provider.
+// The domain of an order is carried by its URLs, so that orders for several
+// domains can be in progress at once.
+type fakeACMEClient struct {
+	t             *testing.T
+	clk           *clock.Mock
+	provider      *fakeDNSProvider
+	challengeType string
+
+	// waitAuthorization, if set, is called while waiting for the
+	// authorization of the domain to be validated.
+	waitAuthorization func(ctx context.Context, domain string) error
+
+	mu         sync.Mutex
+	registered bool
+	issued     int
+}
+
+func (c *fakeACMEClient) Register(ctx context.Context, acct *acme.Account, prompt func(tosURL string) bool) (*acme.Account, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	assert.Equal(c.t, []string{"mailto:admin@domain.test"}, acct.Contact)
+	if !prompt("https://tos.test") {
+		return nil, errors.New("terms of service not accepted")
+	}
+	c.registered = true
+	return acct, nil
+}
+
+func (c *fakeACMEClient) AuthorizeOrder(ctx context.Context, ids []acme.AuthzID, opt ...acme.OrderOption) (*acme.Order, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	require.Len(c.t, ids, 1)
+	domain := ids[0].Value
+	return &acme.Order{
+		URI:         "order-" + domain,
+		AuthzURLs:   []string{"authz-" + domain},
+		FinalizeURL: "finalize-" + domain,
+	}, nil
+}
+
+func (c *fakeACMEClient) GetAuthorization(ctx context.Context, url string) (*acme.Authorization, error) {
+	domain := strings.TrimPrefix(url, "authz-")
+	return &acme.Authorization{
+		URI:        url,
+		Status:     acme.StatusPending,
+		Identifier: acme.AuthzID{Type: "dns", Value: domain},
+		Challenges: []*acme.Challenge{
+			{Type: c.challengeType, Token: "token-" + domain},
+		},
+	}, nil
+}
+
+func (c *fakeACMEClient) DNS01ChallengeRecord(token string) (string, error) {
+	return "record-" + token, nil
+}
+
+func (c *fakeACMEClient) Accept(ctx context.Context, chal *acme.Challenge) (*acme.Challenge, error) {
+	fqdn := "_acme-challenge." + strings.TrimPrefix(chal.Token, "token-") + "."
+	if c.provider.record(fqdn) != "record-"+chal.Token {
+		return nil, errors.New("challenge record not presented")
+	}
+	return chal, nil
+}
+
+func (c *fakeACMEClient) WaitAuthorization(ctx context.Context, url string) (*acme.Authorization, error) {
+	if c.waitAuthorization != nil {
+		if err := c.waitAuthorization(ctx, strings.TrimPrefix(url, "authz-")); err != nil {
+			return nil, err
+		}
+	}
+	return &acme.Authorization{URI: url, Status: acme.StatusValid}, nil
+}
+
+func (c *fakeACMEClient) WaitOrder(ctx context.Context, url string) (*acme.Order, error) {
+	return &acme.Order{
+		URI:         url,
+		Status:      acme.StatusReady,
+		FinalizeURL: "finalize-" + strings.TrimPrefix(url, "order-"),
+	}, nil
+}
+
+func (c *fakeACMEClient) CreateOrderCert(ctx context.Context, url string, csrDER []byte, bundle bool) ([][]byte, string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	csr, err := x509.ParseCertificateRequest(csrDER)
+	if err != nil {
+		return nil, "", err
+	}
+	assert.Equal(c.t, []string{strings.TrimPrefix(url, "finalize-")}, csr.DNSNames)
+
+	c.issued++
+	now := c.clk.Now()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(int64(c.issued)),
+		DNSNames:     csr.DNSNames,
+		NotBefore:    now,
+		NotAfter:     now.Add(90 * 24 * time.Hour),
+	}
+	caKey := testkey.MustEC256()
+	der, err := x509.CreateCertificate(rand.Reader, template, template, csr.PublicKey, caKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return [][]byte{der}, "cert", nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"time"
+
+	"github.com/andres-erbsen/clock"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// acmeRenewalCheckInterval is how often the ACME certificates are
+	// refreshed in the background to detect renewals.
+	acmeRenewalCheckInterval = 10 * time.Minute
+
+	// defaultACMERenewBefore is how long before expiration autocert renews
+	// certificates when renew_before is not configured.
+	defaultACMERenewBefore = 30 * 24 * time.Hour
+
+	// minACMERenewBefore is the renewal jitter used by autocert. Smaller
+	// renew_before values are ignored by autocert in favor of the default.
+	minACMERenewBefore = time.Hour
+)
+
+// acmeCertificateGetter obtains the ACME certificates of the domains.
+type acmeCertificateGetter interface {
+	GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error)
+	RenewBefore(domain string) time.Duration
+}
+
+// acmeRenewalMonitor periodically refreshes the ACME certificate of each
+// domain in the background, which obtains it if it is not cached yet. Since
+// autocert renews certificates on its own without reporting the outcome, a
+// renewal is detected by the certificate expiration moving forward, and a
+// renewal failure by the certificate not being renewed once it is due.
+type acmeRenewalMonitor struct {
+	log    logrus.FieldLogger
+	clock  clock.Clock
+	getter acmeCertificateGetter
+
+	// domains returns the domains to check. It is called on every check so
+	// that changes to the domains are honored when the configuration is
+	// reloaded.
+	domains func() []string
+
+	// expirations holds the expiration of the certificate last seen for
+	// each domain.
+	expirations map[string]time.Time
+}
+
+func newACMERenewalMonitor(log logrus.FieldLogger, clk clock.Clock, getter acmeCertificateGetter, domains func() []string) *acmeRenewalMonitor {
+	if clk == nil {
+		clk = clock.New()
+	}
+	return &acmeRenewalMonitor{
+		log:         log,
+		clock:       clk,
+		getter:      getter,
+		domains:     domains,
+		expirations: make(map[string]time.Time),
+	}
+}
+
+// Run refreshes the certificates until the context is canceled.
+func (m *acmeRenewalMonitor) Run(ctx context.Context) {
+	for {
+		m.check()
+		select {
+		case <-m.clock.After(acmeRenewalCheckInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (m *acmeRenewalMonitor) check() {
+	domains := m.domains()
+
+	// Forget the domains that are no longer configured, so that a renewal
+	// is not reported if one is configured again later.
+	current := make(map[string]bool, len(domains))
+	for _, domain := range domains {
+		current[domain] = true
+	}
+	for domain := range m.expirations {
+		if !current[domain] {
+			delete(m.expirations, domain)
+		}
+	}
+
+	for _, domain := range domains {
+		m.checkDomain(domain)
+	}
+}
+
+func (m *acmeRenewalMonitor) checkDomain(domain string) {
+	log := m.log.WithField("domain", domain)
+
+	cert, err := m.getter.GetCertificate(&tls.ClientHelloInfo{
+		ServerName: domain,
+		// Advertise ECDSA support so that the certificate served to modern
+		// clients is refreshed, instead of the RSA fallback.
+		CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+	})
+	if err != nil {
+		log.WithError(err).Warn("Failed to obtain ACME certificate")
+		return
+	}
+
+	leaf := cert.Leaf
+	if leaf == nil {
+		leaf, err = x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			log.WithError(err).Warn("Failed to parse ACME certificate")
+			return
+		}
+	}
+	log = log.WithField("expiration", leaf.NotAfter.Format(time.RFC3339))
+
+	previous, seen := m.expirations[domain]
+	m.expirations[domain] = leaf.NotAfter
+
+	// autocert attempts the renewal by the time the certificate is due, so
+	// give the attempt a check interval to complete before reporting it.
+	due := leaf.NotAfter.Add(-m.getter.RenewBefore(domain))
+	switch {
+	case seen && leaf.NotAfter.After(previous):
+		log.Info("ACME certificate renewed")
+	case m.clock.Now().After(due.Add(acmeRenewalCheckInterval)):
+		log.Warn("ACME certificate renewal failed; the certificate is due for renewal but has not been renewed")
+	}
+}
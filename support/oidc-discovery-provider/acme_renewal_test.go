@@ -0,0 +1,158 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiffe/spire/pkg/common/log"
+	"github.com/spiffe/spire/test/clock"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestACMERenewalMonitor(t *testing.T) {
+	log, hook := test.NewNullLogger()
+	clk := clock.NewMock(t)
+	getter := &fakeACMECertificateGetter{
+		renewBefore: 30 * 24 * time.Hour,
+	}
+	monitor := newACMERenewalMonitor(log, clk, getter, func() []string { return []string{"domain.test"} })
+
+	expiration := clk.Now().Add(60 * 24 * time.Hour)
+	renewedExpiration := expiration.Add(60 * 24 * time.Hour)
+
+	// Nothing is logged while the certificate is not due for renewal.
+	getter.setCertificate(expiration, nil)
+	monitor.check()
+	spiretest.AssertLogs(t, hook.AllEntries(), nil)
+
+	// Once the certificate is due, a renewal attempt has a check interval to
+	// complete before it is reported as failed.
+	clk.Add(30 * 24 * time.Hour)
+	monitor.check()
+	spiretest.AssertLogs(t, hook.AllEntries(), nil)
+
+	clk.Add(acmeRenewalCheckInterval + time.Second)
+	monitor.check()
+	spiretest.AssertLogs(t, hook.AllEntries(), []spiretest.LogEntry{
+		{
+			Level:   logrus.WarnLevel,
+			Message: "ACME certificate renewal failed; the certificate is due for renewal but has not been renewed",
+			Data: logrus.Fields{
+				"domain":     "domain.test",
+				"expiration": expiration.Format(time.RFC3339),
+			},
+		},
+	})
+
+	// The renewal is logged once the certificate expiration moves forward.
+	hook.Reset()
+	getter.setCertificate(renewedExpiration, nil)
+	monitor.check()
+	spiretest.AssertLogs(t, hook.AllEntries(), []spiretest.LogEntry{
+		{
+			Level:   logrus.InfoLevel,
+			Message: "ACME certificate renewed",
+			Data: logrus.Fields{
+				"domain":     "domain.test",
+				"expiration": renewedExpiration.Format(time.RFC3339),
+			},
+		},
+	})
+
+	// Failing to obtain the certificate is reported.
+	hook.Reset()
+	getter.setCertificate(time.Time{}, errors.New("oh no"))
+	monitor.check()
+	spiretest.AssertLogs(t, hook.AllEntries(), []spiretest.LogEntry{
+		{
+			Level:   logrus.WarnLevel,
+			Message: "Failed to obtain ACME certificate",
+			Data: logrus.Fields{
+				"domain":        "domain.test",
+				logrus.ErrorKey: "oh no",
+			},
+		},
+	})
+}
+
+func TestACMERenewalMonitorFollowsReloadedDomains(t *testing.T) {
+	dir := spiretest.TempDir(t)
+	configPath := filepath.Join(dir, "test.conf")
+
+	writeConfig := func(domains string) {
+		require.NoError(t, os.WriteFile(configPath, []byte(`
+			domains = `+domains+`
+			insecure_addr = ":8080"
+			server_api {
+				address = "unix:///some/socket/path"
+			}
+		`), 0600))
+	}
+
+	writeConfig(`["domain.test"]`)
+	config, err := LoadConfig(configPath)
+	require.NoError(t, err)
+	logger, err := log.NewLogger()
+	require.NoError(t, err)
+	reloader := NewReloader(logger, configPath, config, NewDynamicDomainPolicy(domainAllowlist(t, config.Domains...)), new(fakePollIntervalSource))
+
+	monitorLog, hook := test.NewNullLogger()
+	getter := &fakeACMECertificateGetter{
+		renewBefore: 30 * 24 * time.Hour,
+	}
+	getter.setCertificate(time.Time{}, errors.New("oh no"))
+	monitor := newACMERenewalMonitor(monitorLog, clock.NewMock(t), getter, reloader.Domains)
+
+	failedDomains := func() []string {
+		var domains []string
+		for _, entry := range hook.AllEntries() {
+			domains = append(domains, entry.Data["domain"].(string))
+		}
+		hook.Reset()
+		return domains
+	}
+
+	monitor.check()
+	require.Equal(t, []string{"domain.test"}, failedDomains())
+
+	// The domains of the reloaded configuration are checked from then on.
+	writeConfig(`["other.domain.test", "another.domain.test"]`)
+	require.NoError(t, reloader.Reload())
+	monitor.check()
+	require.Equal(t, []string{"other.domain.test", "another.domain.test"}, failedDomains())
+}
+
+type fakeACMECertificateGetter struct {
+	renewBefore time.Duration
+	expiration  time.Time
+	err         error
+}
+
+func (g *fakeACMECertificateGetter) setCertificate(expiration time.Time, err error) {
+	g.expiration = expiration
+	g.err = err
+}
+
+func (g *fakeACMECertificateGetter) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if g.err != nil {
+		return nil, g.err
+	}
+	return &tls.Certificate{
+		Leaf: &x509.Certificate{
+			DNSNames: []string{hello.ServerName},
+			NotAfter: g.expiration,
+		},
+	}, nil
+}
+
+func (g *fakeACMECertificateGetter) RenewBefore(domain string) time.Duration {
+	return g.renewBefore
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+)
+
+const (
+	DefaultPollBackoffBase = time.Second
+)
+
+// pollBackoff computes how long a source waits before polling again after
+// consecutive failures. The delay doubles with every failure starting from
+// the base, is capped at the maximum (the poll interval, unless configured)
+// and is jittered so that many providers reconnecting at once do not poll in
+// lockstep.
+type pollBackoff struct {
+	base time.Duration
+	max  time.Duration
+
+	// int63n is used to jitter the delay. It is overridden by tests.
+	int63n func(int64) int64
+
+	failures int
+}
+
+func newPollBackoff(base, max time.Duration) *pollBackoff {
+	if base <= 0 {
+		base = DefaultPollBackoffBase
+	}
+	return &pollBackoff{
+		base:   base,
+		max:    max,
+		int63n: rand.Int63n,
+	}
+}
+
+// Next returns the delay before the next poll after a failed poll.
+func (b *pollBackoff) Next(pollInterval time.Duration) time.Duration {
+	max := b.max
+	if max <= 0 {
+		max = pollInterval
+	}
+
+	b.failures++
+	delay := b.base
+	for i := 1; i < b.failures && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+
+	// Wait for at least half of the delay, plus a random part of the rest
+	half := delay / 2
+	return half + time.Duration(b.int63n(int64(delay-half)+1))
+}
+
+// Reset resets the backoff after a successful poll.
+func (b *pollBackoff) Reset() {
+	b.failures = 0
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/test/clock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPollBackoff(t *testing.T) {
+	const pollInterval = 10 * time.Second
+
+	t.Run("grows exponentially up to the poll interval", func(t *testing.T) {
+		b := newPollBackoff(time.Second, 0)
+		b.int63n = maxJitter
+
+		require.Equal(t, time.Second, b.Next(pollInterval))
+		require.Equal(t, 2*time.Second, b.Next(pollInterval))
+		require.Equal(t, 4*time.Second, b.Next(pollInterval))
+		require.Equal(t, 8*time.Second, b.Next(pollInterval))
+		require.Equal(t, pollInterval, b.Next(pollInterval))
+		require.Equal(t, pollInterval, b.Next(pollInterval))
+	})
+
+	t.Run("capped at the configured maximum", func(t *testing.T) {
+		b := newPollBackoff(time.Second, 3*time.Second)
+		b.int63n = maxJitter
+
+		require.Equal(t, time.Second, b.Next(pollInterval))
+		require.Equal(t, 2*time.Second, b.Next(pollInterval))
+		require.Equal(t, 3*time.Second, b.Next(pollInterval))
+		require.Equal(t, 3*time.Second, b.Next(pollInterval))
+	})
+
+	t.Run("jittered down to half the delay", func(t *testing.T) {
+		b := newPollBackoff(time.Second, 0)
+		b.int63n = minJitter
+
+		require.Equal(t, 500*time.Millisecond, b.Next(pollInterval))
+		require.Equal(t, time.Second, b.Next(pollInterval))
+	})
+
+	t.Run("reset on success", func(t *testing.T) {
+		b := newPollBackoff(time.Second, 0)
+		b.int63n = maxJitter
+
+		require.Equal(t, time.Second, b.Next(pollInterval))
+		require.Equal(t, 2*time.Second, b.Next(pollInterval))
+		b.Reset()
+		require.Equal(t, time.Second, b.Next(pollInterval))
+	})
+
+	t.Run("default base", func(t *testing.T) {
+		b := newPollBackoff(0, 0)
+		b.int63n = maxJitter
+
+		require.Equal(t, DefaultPollBackoffBase, b.Next(pollInterval))
+	})
+
+	t.Run("random jitter stays within bounds", func(t *testing.T) {
+		b := newPollBackoff(time.Second, 0)
+		for i := 0; i < 100; i++ {
+			delay := b.Next(pollInterval)
+			require.GreaterOrEqual(t, delay, time.Second/2)
+			require.LessOrEqual(t, delay, pollInterval)
+		}
+	})
+}
+
+func minJitter(int64) int64 {
+	return 0
+}
+
+func maxJitter(n int64) int64 {
+	return n - 1
+}
+
+// waitForBackoff waits for a source to schedule the next poll after a failed
+// poll and returns the scheduled delay.
+func waitForBackoff(t *testing.T, clock *clock.Mock, pollInterval time.Duration) time.Duration {
+	select {
+	case retryAfter := <-clock.AfterCh():
+		require.GreaterOrEqual(t, retryAfter, DefaultPollBackoffBase/2)
+		require.LessOrEqual(t, retryAfter, pollInterval)
+		return retryAfter
+	case <-time.After(time.Minute):
+		require.FailNow(t, "failed to wait for the poll timer")
+		return 0
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/andres-erbsen/clock"
+	"github.com/sirupsen/logrus"
+	"github.com/zeebo/errs"
+)
+
+const (
+	DefaultFileSyncInterval = time.Minute
+)
+
+type CertManagerConfig struct {
+	Log              logrus.FieldLogger
+	CertFilePath     string
+	KeyFilePath      string
+	FileSyncInterval time.Duration
+	Clock            clock.Clock
+}
+
+// CertManager serves a TLS certificate loaded from disk. The certificate and
+// key files are periodically checked for changes and reloaded, so that they
+// can be rotated without restarting the provider.
+type CertManager struct {
+	log          logrus.FieldLogger
+	clock        clock.Clock
+	certFilePath string
+	keyFilePath  string
+	cancel       context.CancelFunc
+
+	mu      sync.RWMutex
+	wg      sync.WaitGroup
+	certPEM []byte
+	keyPEM  []byte
+	cert    *tls.Certificate
+}
+
+func NewCertManager(config CertManagerConfig) (*CertManager, error) {
+	if config.FileSyncInterval <= 0 {
+		config.FileSyncInterval = DefaultFileSyncInterval
+	}
+	if config.Clock == nil {
+		config.Clock = clock.New()
+	}
+
+	m := &CertManager{
+		log:          config.Log,
+		clock:        config.Clock,
+		certFilePath: config.CertFilePath,
+		keyFilePath:  config.KeyFilePath,
+	}
+
+	// The initial load must succeed; failures on subsequent reloads are
+	// logged and the previously loaded certificate keeps being served.
+	if err := m.loadCertificate(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	m.wg.Add(1)
+	go m.syncEvery(ctx, config.FileSyncInterval)
+	return m, nil
+}
+
+func (m *CertManager) Close() error {
+	m.cancel()
+	m.wg.Wait()
+	return nil
+}
+
+// GetCertificate is meant to be used as the GetCertificate callback of a
+// tls.Config.
+func (m *CertManager) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.cert, nil
+}
+
+func (m *CertManager) syncEvery(ctx context.Context, interval time.Duration) {
+	defer m.wg.Done()
+
+	m.log.WithField("interval", interval).Debug("Certificate file sync started")
+	for {
+		select {
+		case <-ctx.Done():
+			m.log.WithError(ctx.Err()).Debug("Certificate file sync done")
+			return
+		case <-m.clock.After(interval):
+		}
+		if err := m.loadCertificate(); err != nil {
+			m.log.WithError(err).Warn("Failed to reload serving certificate")
+		}
+	}
+}
+
+func (m *CertManager) loadCertificate() error {
+	certPEM, err := os.ReadFile(m.certFilePath)
+	if err != nil {
+		return errs.New("failed to read certificate file: %v", err)
+	}
+	keyPEM, err := os.ReadFile(m.keyFilePath)
+	if err != nil {
+		return errs.New("failed to read key file: %v", err)
+	}
+
+	// If the files haven't changed, don't bother continuing
+	m.mu.RLock()
+	unchanged := bytes.Equal(m.certPEM, certPEM) && bytes.Equal(m.keyPEM, keyPEM)
+	m.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return errs.New("failed to load key pair: %v", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.certPEM = certPEM
+	m.keyPEM = keyPEM
+	m.cert = &cert
+
+	m.log.WithFields(logrus.Fields{
+		"cert_file_path": m.certFilePath,
+		"key_file_path":  m.keyFilePath,
+	}).Info("Loaded serving certificate")
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/test/clock"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/spiffe/spire/test/testca"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertManager(t *testing.T) {
+	const fileSyncInterval = time.Minute
+
+	dir := spiretest.TempDir(t)
+	certFilePath := filepath.Join(dir, "cert.pem")
+	keyFilePath := filepath.Join(dir, "key.pem")
+
+	log, _ := test.NewNullLogger()
+	clock := clock.NewMock(t)
+
+	config := CertManagerConfig{
+		Log:              log,
+		CertFilePath:     certFilePath,
+		KeyFilePath:      keyFilePath,
+		FileSyncInterval: fileSyncInterval,
+		Clock:            clock,
+	}
+
+	// Creating the manager fails when the files cannot be loaded
+	_, err := NewCertManager(config)
+	require.EqualError(t, err, "failed to read certificate file: open "+certFilePath+": no such file or directory")
+
+	cert1 := writeKeyPair(t, certFilePath, keyFilePath)
+	manager, err := NewCertManager(config)
+	require.NoError(t, err)
+	defer manager.Close()
+	requireCertificate(t, manager, cert1)
+
+	// Rotate the files and assert the new certificate is picked up on the
+	// next sync.
+	cert2 := writeKeyPair(t, certFilePath, keyFilePath)
+	clock.WaitForAfter(time.Minute, "failed to wait for the sync timer")
+	requireCertificate(t, manager, cert1)
+	clock.Add(fileSyncInterval)
+	clock.WaitForAfter(time.Minute, "failed to wait for the sync timer")
+	requireCertificate(t, manager, cert2)
+
+	// Corrupt the key file and assert the previous certificate is retained.
+	require.NoError(t, os.WriteFile(keyFilePath, []byte("not a key"), 0600))
+	clock.Add(fileSyncInterval)
+	clock.WaitForAfter(time.Minute, "failed to wait for the sync timer")
+	requireCertificate(t, manager, cert2)
+}
+
+func writeKeyPair(t *testing.T, certFilePath, keyFilePath string) []byte {
+	ca := testca.New(t, spiffeid.RequireTrustDomainFromString("domain.test"))
+	certs, key := ca.CreateX509Certificate()
+	keyPEM, err := pemutil.EncodePKCS8PrivateKey(key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(certFilePath, pemutil.EncodeCertificates(certs), 0600))
+	require.NoError(t, os.WriteFile(keyFilePath, keyPEM, 0600))
+	return certs[0].Raw
+}
+
+func requireCertificate(t *testing.T, manager *CertManager, expected []byte) {
+	cert, err := manager.GetCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	require.NotNil(t, cert)
+	require.Equal(t, expected, cert.Certificate[0])
+}
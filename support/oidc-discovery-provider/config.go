@@ -1,11 +1,19 @@
 package main
 
 import (
+	"encoding/json"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/spire/pkg/common/telemetry"
 	"github.com/zeebo/errs"
 )
 
@@ -13,6 +21,19 @@ const (
 	defaultLogLevel     = "info"
 	defaultPollInterval = time.Second * 10
 	defaultCacheDir     = "./.acme-cache"
+
+	defaultHealthChecksBindAddress = "localhost"
+	defaultHealthChecksBindPort    = 8008
+
+	// defaultReadyTimeoutFactor is the number of poll intervals that can
+	// elapse without a successful poll before the provider is reported as
+	// not ready, when ready_timeout is not configured.
+	defaultReadyTimeoutFactor = 5
+
+	defaultListenSocketMode = os.ModePerm
+
+	defaultServingCertFileAddr         = ":443"
+	defaultServingCertFileSyncInterval = time.Minute
 )
 
 type Config struct {
@@ -20,17 +41,68 @@ type Config struct {
 	LogLevel  string `hcl:"log_level"`
 	LogPath   string `hcl:"log_path"`
 
-	// LogRequests is a debug option that logs all incoming requests
+	// LogRequests, if true, logs an access log entry for every request
 	LogRequests bool `hcl:"log_requests"`
 
+	// Telemetry is the telemetry configuration, shared with SPIRE Server and
+	// Agent. It can be used to expose a Prometheus metrics endpoint.
+	Telemetry telemetry.FileConfig `hcl:"telemetry"`
+
 	// Domains are the domains this provider will be hosted under. Incoming requests
 	// that are not received on (or proxied through) one of the domains specified by this list
 	// are rejected.
 	Domains []string `hcl:"domains"`
 
+	// AllowedOrigins is the list of origins allowed to make cross-origin
+	// requests to the discovery document and JWKS endpoints. An origin is
+	// either of the form scheme://host[:port], or "*" to allow any origin.
+	// CORS headers are not emitted unless set.
+	AllowedOrigins []string `hcl:"allowed_origins"`
+
+	// DiscoveryDocument holds additional top-level claims merged into the
+	// discovery document (e.g. scopes_supported). The claims generated by
+	// the provider, such as issuer and jwks_uri, cannot be overridden.
+	DiscoveryDocument map[string]interface{} `hcl:"discovery_document"`
+
+	// IDTokenSigningAlgs, if set, overrides the id_token_signing_alg_values_supported
+	// claim of the discovery document, which is otherwise derived from the
+	// keys in the JWKS.
+	IDTokenSigningAlgs []string `hcl:"id_token_signing_alg_values"`
+
 	// Set the 'use' field on all keys. Required for some non-conformant JWKS clients.
 	SetKeyUse bool `hcl:"set_key_use"`
 
+	// PublishKeyUse, if set, restricts the keys published in the JWKS to those
+	// with one of the given 'use' values ("sig" or "enc"). It is applied after
+	// the use is set on the keys by SetKeyUse.
+	PublishKeyUse []string `hcl:"publish_key_use"`
+
+	// JWKSCacheMaxAge, if set, allows clients to cache the JWKS response for
+	// the given duration. When unset, caching of the JWKS response is
+	// disabled. This value is calculated by LoadConfig()/ParseConfig() from
+	// RawJWKSCacheMaxAge.
+	JWKSCacheMaxAge time.Duration `hcl:"-"`
+
+	// RawJWKSCacheMaxAge holds the string version of the JWKSCacheMaxAge.
+	// Consumers should use JWKSCacheMaxAge instead.
+	RawJWKSCacheMaxAge string `hcl:"jwks_cache_max_age"`
+
+	// Issuer, if set, overrides the issuer advertised in the discovery
+	// document, which is otherwise derived from the domain the request was
+	// received on. The jwks_uri is constructed relative to it. This value is
+	// calculated by LoadConfig()/ParseConfig() from RawIssuer.
+	Issuer *url.URL `hcl:"-"`
+
+	// RawIssuer holds the string version of the Issuer. Consumers should use
+	// Issuer instead.
+	RawIssuer string `hcl:"issuer"`
+
+	// PathPrefix, if set, prefixes the paths of all the served endpoints
+	// (e.g. "/spire" serves the JWKS on "/spire/keys"), for deployments
+	// sharing a hostname with other services through path routing. Trailing
+	// slashes are removed by LoadConfig()/ParseConfig().
+	PathPrefix string `hcl:"path_prefix"`
+
 	// AllowInsecureScheme, if true, causes HTTP URLs to be rendered in the
 	// returned discovery document. This option should only be used for testing purposes as HTTP does
 	// not provide the security guarantees necessary for conveying trusted public key material. In general this
@@ -45,13 +117,60 @@ type Config struct {
 	// going to be deployed behind an HTTPS proxy.
 	InsecureAddr string `hcl:"insecure_addr"`
 
+	// ListenNetwork is the network of the TCP listener serving on
+	// InsecureAddr, the ServingCertFile address or the ACME port. It is one
+	// of "tcp", "tcp4" or "tcp6", and defaults to "tcp", which listens on both
+	// IPv4 and IPv6 on dual-stack hosts.
+	ListenNetwork string `hcl:"listen_network"`
+
+	// UseProxyProtocol, if true, expects every connection to the TCP
+	// listener to begin with a PROXY protocol (v1 or v2) header, such as
+	// prepended by an L4 load balancer, and uses the client address it
+	// carries as the remote address. Connections without the header are
+	// rejected. It does not apply to the ListenSocketPath listener.
+	UseProxyProtocol bool `hcl:"use_proxy_protocol"`
+
 	// ListenSocketPath specifies a unix socket to listen for plaintext HTTP
-	// on, for when deployed behind another webserver or sidecar.
+	// on, for when deployed behind another webserver or sidecar. It can be
+	// combined with ACME or ServingCertFile to serve on both listeners at
+	// the same time.
 	ListenSocketPath string `hcl:"listen_socket_path"`
 
-	// ACME is the ACME configuration. It is required unless InsecureAddr or
-	// ListenSocketPath is set.
-	ACME *ACMEConfig `hcl:"acme"`
+	// ListenSocketMode is the file mode applied to the socket created at
+	// ListenSocketPath. It defaults to 0777. This value is calculated by
+	// LoadConfig()/ParseConfig() from RawListenSocketMode.
+	ListenSocketMode os.FileMode `hcl:"-"`
+
+	// RawListenSocketMode holds the octal string version of the
+	// ListenSocketMode. Consumers should use ListenSocketMode instead.
+	RawListenSocketMode string `hcl:"listen_socket_mode"`
+
+	// ListenSocketOwner and ListenSocketGroup, if set, are the user and group
+	// (either names or numeric IDs) that the socket created at
+	// ListenSocketPath is changed to be owned by.
+	ListenSocketOwner string `hcl:"listen_socket_owner"`
+	ListenSocketGroup string `hcl:"listen_socket_group"`
+
+	// ACME is the ACME configuration. It is required unless ServingCertFile,
+	// InsecureAddr or ListenSocketPath is set. The section can be repeated, labeled with a
+	// domain (e.g. acme "example.org" { ... }), to obtain an independent
+	// certificate per domain. This value is calculated in
+	// LoadConfig()/ParseConfig().
+	ACME []*ACMEConfig `hcl:"-"`
+
+	// ServingCertFile is the configuration for serving HTTPS using a
+	// certificate and key loaded from disk. It is mutually exclusive with
+	// ACME and InsecureAddr.
+	ServingCertFile *ServingCertFileConfig `hcl:"serving_cert_file"`
+
+	// HealthChecks is the configuration for the liveness and readiness
+	// probe endpoints. They are not served unless configured.
+	HealthChecks *HealthChecksConfig `hcl:"health_checks"`
+
+	// ProtectedResource is the configuration for the OAuth 2.0 Protected
+	// Resource Metadata document (RFC 9728). It is not served unless
+	// configured.
+	ProtectedResource *ProtectedResourceConfig `hcl:"protected_resource"`
 
 	// ServerAPI is the configuration for using the SPIRE Server API as the
 	// source for the public keys. Only one source can be configured.
@@ -60,9 +179,49 @@ type Config struct {
 	// Workload API is the configuration for using the SPIFFE Workload API
 	// as the source for the public keys. Only one source can be configured.
 	WorkloadAPI *WorkloadAPIConfig `hcl:"workload_api"`
+
+	// File is the configuration for using a JWKS file on disk as the source
+	// for the public keys. Only one source can be configured.
+	File *FileConfig `hcl:"file"`
+}
+
+type HealthChecksConfig struct {
+	// BindAddress is the address the health check endpoints are served on.
+	// Defaults to "localhost".
+	BindAddress string `hcl:"bind_address"`
+
+	// BindPort is the port the health check endpoints are served on.
+	// Defaults to 8008.
+	BindPort int `hcl:"bind_port"`
+
+	// ReadyTimeout is how long polling the source can fail before the
+	// provider is reported as not ready. This value is calculated by
+	// LoadConfig()/ParseConfig() from RawReadyTimeout and defaults to five
+	// times the poll interval of the configured source.
+	ReadyTimeout time.Duration `hcl:"-"`
+
+	// RawReadyTimeout holds the string version of the ReadyTimeout. Consumers
+	// should use ReadyTimeout instead.
+	RawReadyTimeout string `hcl:"ready_timeout"`
+}
+
+type ProtectedResourceConfig struct {
+	// Resource is the resource identifier of the protected resource. When
+	// unset, the issuer is used.
+	Resource string `hcl:"resource"`
+
+	// AuthorizationServers are the issuer identifiers of the authorization
+	// servers that can be used with the protected resource.
+	AuthorizationServers []string `hcl:"authorization_servers"`
 }
 
 type ACMEConfig struct {
+	// Domain is the domain this section obtains a certificate for, taken
+	// from the section label. If unset, the section applies to all domains
+	// not covered by another section and a single certificate is obtained
+	// for them.
+	Domain string `hcl:"-"`
+
 	// DirectoryURL is the ACME directory URL. If unset, the LetsEncrypt
 	// directory is used.
 	DirectoryURL string `hcl:"directory_url"`
@@ -81,14 +240,84 @@ type ACMEConfig struct {
 	// RawCacheDir is used to determine whether the cache was explicitly disabled
 	// (by setting to an empty) string. Consumers should use CacheDir instead.
 	RawCacheDir *string `hcl:"cache_dir"`
+
+	// RenewBefore is how long before expiration the certificate is renewed.
+	// This value is calculated in LoadConfig()/ParseConfig() from
+	// RawRenewBefore. When unset, the autocert default (30 days) is used.
+	RenewBefore time.Duration `hcl:"-"`
+
+	// RawRenewBefore holds the string version of the RenewBefore. Consumers
+	// should use RenewBefore instead.
+	RawRenewBefore string `hcl:"renew_before"`
+
+	// ChallengeType is the ACME challenge used to prove control of the
+	// domains, either tls-alpn-01 (the default) or dns-01.
+	ChallengeType string `hcl:"challenge_type"`
+
+	// Route53 configures Amazon Route 53 as the DNS provider used to answer
+	// dns-01 challenges.
+	Route53 *Route53Config `hcl:"route53"`
+
+	// CloudDNS configures Google Cloud DNS as the DNS provider used to
+	// answer dns-01 challenges.
+	CloudDNS *CloudDNSConfig `hcl:"cloud_dns"`
+}
+
+type Route53Config struct {
+	// HostedZoneID is the ID of the hosted zone the challenge records are
+	// created in.
+	HostedZoneID string `hcl:"hosted_zone_id"`
+
+	// AccessKeyID and SecretAccessKey are the AWS credentials. If unset, the
+	// default AWS credential chain is used.
+	AccessKeyID     string `hcl:"access_key_id"`
+	SecretAccessKey string `hcl:"secret_access_key"`
+}
+
+type CloudDNSConfig struct {
+	// ProjectID is the Google Cloud project the managed zone belongs to.
+	ProjectID string `hcl:"project_id"`
+
+	// ManagedZone is the name of the managed zone the challenge records are
+	// created in.
+	ManagedZone string `hcl:"managed_zone"`
+
+	// ServiceAccountFile is the path to the service account credentials. If
+	// unset, the application default credentials are used.
+	ServiceAccountFile string `hcl:"service_account_file"`
+}
+
+type ServingCertFileConfig struct {
+	// CertFilePath is the path to the PEM encoded certificate chain.
+	CertFilePath string `hcl:"cert_file_path"`
+
+	// KeyFilePath is the path to the PEM encoded private key.
+	KeyFilePath string `hcl:"key_file_path"`
+
+	// Addr is the address to serve HTTPS on. Defaults to ":443".
+	Addr string `hcl:"addr"`
+
+	// FileSyncInterval controls how frequently the certificate and key files
+	// are checked for changes. This value is calculated by
+	// LoadConfig()/ParseConfig() from RawFileSyncInterval.
+	FileSyncInterval time.Duration `hcl:"-"`
+
+	// RawFileSyncInterval holds the string version of the FileSyncInterval.
+	// Consumers should use FileSyncInterval instead.
+	RawFileSyncInterval string `hcl:"file_sync_interval"`
 }
 
 type ServerAPIConfig struct {
 	// Address is the target address of the SPIRE Server API as defined in
 	// https://github.com/grpc/grpc/blob/master/doc/naming.md. Only the unix
-	// name system is supported.
+	// name system is supported, along with tcp://host:port addresses, which
+	// require TLS.
 	Address string `hcl:"address"`
 
+	// TLS configures mTLS authentication with the Server API. It is
+	// required for, and only allowed with, tcp addresses.
+	TLS *ServerAPITLSConfig `hcl:"tls"`
+
 	// PollInterval controls how frequently the service polls the Server API
 	// for the bundle containing the JWT public keys. This value is calculated
 	// by LoadConfig()/ParseConfig() from RawPollInterval.
@@ -97,6 +326,78 @@ type ServerAPIConfig struct {
 	// RawPollInterval holds the string version of the PollInterval. Consumers
 	// should use PollInterval instead.
 	RawPollInterval string `hcl:"poll_interval"`
+	// BackoffBase is the delay before polling again after a failure, which
+	// doubles with every consecutive failure up to BackoffMax. This value is
+	// calculated by LoadConfig()/ParseConfig() from RawBackoffBase. When
+	// unset, the source default is used.
+	BackoffBase time.Duration `hcl:"-"`
+
+	// RawBackoffBase holds the string version of the BackoffBase. Consumers
+	// should use BackoffBase instead.
+	RawBackoffBase string `hcl:"backoff_base"`
+
+	// BackoffMax caps the delay before polling again after consecutive
+	// failures. This value is calculated by LoadConfig()/ParseConfig() from
+	// RawBackoffMax. When unset, the poll interval is used.
+	BackoffMax time.Duration `hcl:"-"`
+
+	// RawBackoffMax holds the string version of the BackoffMax. Consumers
+	// should use BackoffMax instead.
+	RawBackoffMax string `hcl:"backoff_max"`
+
+	// DialTimeout bounds how long each poll of the Server API may take,
+	// including establishing the connection. This value is calculated by
+	// LoadConfig()/ParseConfig() from RawDialTimeout. When unset,
+	// DefaultServerAPIDialTimeout is used.
+	DialTimeout time.Duration `hcl:"-"`
+
+	// RawDialTimeout holds the string version of the DialTimeout. Consumers
+	// should use DialTimeout instead.
+	RawDialTimeout string `hcl:"dial_timeout"`
+
+	// KeepaliveTime is how long the connection to the Server API may be idle
+	// while a request is outstanding before it is probed with a keepalive
+	// ping. This value is calculated by LoadConfig()/ParseConfig() from
+	// RawKeepaliveTime. When unset, keepalive pings are not sent.
+	KeepaliveTime time.Duration `hcl:"-"`
+
+	// RawKeepaliveTime holds the string version of the KeepaliveTime.
+	// Consumers should use KeepaliveTime instead.
+	RawKeepaliveTime string `hcl:"keepalive_time"`
+
+	// KeepaliveTimeout is how long to wait for a keepalive ping to be
+	// acknowledged before the connection is closed. This value is calculated
+	// by LoadConfig()/ParseConfig() from RawKeepaliveTimeout. When unset, the
+	// gRPC default (20s) is used.
+	KeepaliveTimeout time.Duration `hcl:"-"`
+
+	// RawKeepaliveTimeout holds the string version of the KeepaliveTimeout.
+	// Consumers should use KeepaliveTimeout instead.
+	RawKeepaliveTimeout string `hcl:"keepalive_timeout"`
+
+	// MaxJWKSSize caps the size, in bytes, of the bundle fetched from the
+	// Server API. When unset, the gRPC default (4MiB) is used.
+	MaxJWKSSize int `hcl:"max_jwks_size"`
+}
+
+type ServerAPITLSConfig struct {
+	// ServerID is the SPIFFE ID the SPIRE Server is expected to present.
+	// This value is calculated by LoadConfig()/ParseConfig() from
+	// RawServerID.
+	ServerID spiffeid.ID `hcl:"-"`
+
+	// RawServerID holds the string version of the ServerID. Consumers should
+	// use ServerID instead.
+	RawServerID string `hcl:"server_id"`
+
+	// WorkloadAPISocketPath is the path to the Workload API Unix Domain
+	// socket the provider obtains its own X509-SVID from.
+	WorkloadAPISocketPath string `hcl:"workload_api_socket_path"`
+
+	// BundlePath is the path to a SPIFFE bundle used to authenticate the
+	// SPIRE Server. When unset, the bundles obtained from the Workload API
+	// are used.
+	BundlePath string `hcl:"bundle_path"`
 }
 
 type WorkloadAPIConfig struct {
@@ -107,11 +408,56 @@ type WorkloadAPIConfig struct {
 	// Workload API response.
 	TrustDomain string `hcl:"trust_domain"`
 
+	// TrustDomains is used instead of TrustDomain to publish the keys of the
+	// JWT bundles of more than one trust domain (e.g. federated trust
+	// domains), merged into a single key set.
+	TrustDomains []string `hcl:"trust_domains"`
+
 	// PollInterval controls how frequently the service polls the Workload
 	// API for the bundle containing the JWT public keys. This value is calculated
 	// by LoadConfig()/ParseConfig() from RawPollInterval.
 	PollInterval time.Duration `hcl:"-"`
 
+	// RawPollInterval holds the string version of the PollInterval. Consumers
+	// should use PollInterval instead.
+	RawPollInterval string `hcl:"poll_interval"`
+	// BackoffBase is the delay before polling again after a failure, which
+	// doubles with every consecutive failure up to BackoffMax. This value is
+	// calculated by LoadConfig()/ParseConfig() from RawBackoffBase. When
+	// unset, the source default is used.
+	BackoffBase time.Duration `hcl:"-"`
+
+	// RawBackoffBase holds the string version of the BackoffBase. Consumers
+	// should use BackoffBase instead.
+	RawBackoffBase string `hcl:"backoff_base"`
+
+	// BackoffMax caps the delay before polling again after consecutive
+	// failures. This value is calculated by LoadConfig()/ParseConfig() from
+	// RawBackoffMax. When unset, the poll interval is used.
+	BackoffMax time.Duration `hcl:"-"`
+
+	// RawBackoffMax holds the string version of the BackoffMax. Consumers
+	// should use BackoffMax instead.
+	RawBackoffMax string `hcl:"backoff_max"`
+
+	// FetchX509, if true, also fetches the X.509 bundles of the trust
+	// domains and serves their authorities on the /roots.pem endpoint.
+	FetchX509 bool `hcl:"fetch_x509"`
+
+	// MaxJWKSSize caps the size, in bytes, of the bundles fetched from the
+	// Workload API. When unset, the gRPC default (4MiB) is used.
+	MaxJWKSSize int `hcl:"max_jwks_size"`
+}
+
+type FileConfig struct {
+	// Path is the path to the JWKS file.
+	Path string `hcl:"path"`
+
+	// PollInterval controls how frequently the service re-reads the JWKS
+	// file for changes. This value is calculated by
+	// LoadConfig()/ParseConfig() from RawPollInterval.
+	PollInterval time.Duration `hcl:"-"`
+
 	// RawPollInterval holds the string version of the PollInterval. Consumers
 	// should use PollInterval instead.
 	RawPollInterval string `hcl:"poll_interval"`
@@ -122,12 +468,34 @@ func LoadConfig(path string) (*Config, error) {
 	if err != nil {
 		return nil, errs.New("unable to load configuration: %v", err)
 	}
-	return ParseConfig(string(hclBytes))
+	return ParseConfig(expandEnv(string(hclBytes)))
+}
+
+// expandEnv replaces ${VAR} and $VAR references in the configuration with the
+// value of the corresponding environment variable, or the empty string if it
+// is not set. A literal "$" is written as "$$".
+func expandEnv(s string) string {
+	return os.Expand(s, func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		return os.Getenv(name)
+	})
 }
 
 func ParseConfig(hclConfig string) (_ *Config, err error) {
+	file, err := hcl.Parse(hclConfig)
+	if err != nil {
+		return nil, errs.New("unable to decode configuration: %v", err)
+	}
+
 	c := new(Config)
-	if err := hcl.Decode(c, hclConfig); err != nil {
+	if err := hcl.DecodeObject(c, file); err != nil {
+		return nil, errs.New("unable to decode configuration: %v", err)
+	}
+
+	c.ACME, err = decodeACMEConfigs(file)
+	if err != nil {
 		return nil, errs.New("unable to decode configuration: %v", err)
 	}
 
@@ -140,29 +508,100 @@ func ParseConfig(hclConfig string) (_ *Config, err error) {
 	}
 	c.Domains = dedupeList(c.Domains)
 
-	if c.ACME != nil {
-		c.ACME.CacheDir = defaultCacheDir
-		if c.ACME.RawCacheDir != nil {
-			c.ACME.CacheDir = *c.ACME.RawCacheDir
+	for _, use := range c.PublishKeyUse {
+		if use != "sig" && use != "enc" {
+			return nil, errs.New("invalid key use %q in publish_key_use: expected \"sig\" or \"enc\"", use)
+		}
+	}
+
+	if err := validateDiscoveryDocument(c.DiscoveryDocument); err != nil {
+		return nil, errs.New("invalid discovery_document: %v", err)
+	}
+
+	for _, alg := range c.IDTokenSigningAlgs {
+		if !isSigningAlg(alg) {
+			return nil, errs.New("invalid signing algorithm %q in id_token_signing_alg_values", alg)
+		}
+	}
+
+	for _, origin := range c.AllowedOrigins {
+		if err := validateOrigin(origin); err != nil {
+			return nil, errs.New("invalid origin %q in allowed_origins: %v", origin, err)
+		}
+	}
+
+	if c.RawIssuer != "" {
+		c.Issuer, err = parseIssuer(c.RawIssuer, c.AllowInsecureScheme)
+		if err != nil {
+			return nil, errs.New("invalid issuer %q: %v", c.RawIssuer, err)
+		}
+	}
+
+	if c.PathPrefix != "" {
+		if !strings.HasPrefix(c.PathPrefix, "/") {
+			return nil, errs.New("invalid path_prefix %q: must begin with \"/\"", c.PathPrefix)
+		}
+		if strings.ContainsAny(c.PathPrefix, "?#") {
+			return nil, errs.New("invalid path_prefix %q: must not contain a query or fragment", c.PathPrefix)
 		}
+		c.PathPrefix = strings.TrimRight(c.PathPrefix, "/")
 	}
 
+	if c.ProtectedResource != nil {
+		if err := validateProtectedResourceConfig(c.ProtectedResource, c.AllowInsecureScheme); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.RawJWKSCacheMaxAge != "" {
+		c.JWKSCacheMaxAge, err = time.ParseDuration(c.RawJWKSCacheMaxAge)
+		if err != nil {
+			return nil, errs.New("invalid jwks_cache_max_age: %v", err)
+		}
+		if c.JWKSCacheMaxAge < time.Second {
+			return nil, errs.New("jwks_cache_max_age must be at least one second")
+		}
+	}
+
+	// The unix socket listener can run alongside the HTTPS listener served
+	// via ACME or a certificate file, but only one TCP listener is allowed.
 	switch {
-	case c.ACME == nil:
+	case c.ServingCertFile != nil:
+		if err := validateServingCertFileConfig(c); err != nil {
+			return nil, err
+		}
+	case len(c.ACME) == 0:
 		if c.InsecureAddr == "" && c.ListenSocketPath == "" {
-			return nil, errs.New("either acme or listen_socket_path must be configured")
+			return nil, errs.New("either acme, serving_cert_file, insecure_addr or listen_socket_path must be configured")
 		}
 		if c.InsecureAddr != "" && c.ListenSocketPath != "" {
 			return nil, errs.New("insecure_addr and listen_socket_path are mutually exclusive")
 		}
 	case c.InsecureAddr != "":
 		return nil, errs.New("insecure_addr and the acme section are mutually exclusive")
-	case c.ListenSocketPath != "":
-		return nil, errs.New("listen_socket_path and the acme section are mutually exclusive")
-	case !c.ACME.ToSAccepted:
-		return nil, errs.New("tos_accepted must be set to true in the acme configuration section")
-	case c.ACME.Email == "":
-		return nil, errs.New("email must be configured in the acme configuration section")
+	default:
+		if err := validateACMEConfigs(c.ACME, c.Domains); err != nil {
+			return nil, err
+		}
+	}
+
+	switch c.ListenNetwork {
+	case "", "tcp", "tcp4", "tcp6":
+	default:
+		return nil, errs.New("invalid listen_network %q: expected \"tcp\", \"tcp4\" or \"tcp6\"", c.ListenNetwork)
+	}
+
+	if c.UseProxyProtocol && c.InsecureAddr == "" && c.ServingCertFile == nil && len(c.ACME) == 0 {
+		return nil, errs.New("use_proxy_protocol requires insecure_addr, serving_cert_file or acme to be configured")
+	}
+
+	if c.ListenSocketPath != "" {
+		c.ListenSocketMode, err = parseListenSocketMode(c.RawListenSocketMode)
+		if err != nil {
+			return nil, errs.New("invalid listen_socket_mode: %v", err)
+		}
+	} else if c.RawListenSocketMode != "" || c.ListenSocketOwner != "" || c.ListenSocketGroup != "" {
+		return nil, errs.New("listen_socket_mode, listen_socket_owner and listen_socket_group require listen_socket_path")
 	}
 
 	var methodCount int
@@ -171,13 +610,50 @@ func ParseConfig(hclConfig string) (_ *Config, err error) {
 		if c.ServerAPI.Address == "" {
 			return nil, errs.New("address must be configured in the server_api configuration section")
 		}
-		if !strings.HasPrefix(c.ServerAPI.Address, "unix:") {
-			return nil, errs.New("address must use the unix name system in the server_api configuration section")
+		switch {
+		case strings.HasPrefix(c.ServerAPI.Address, "unix:"):
+			if c.ServerAPI.TLS != nil {
+				return nil, errs.New("tls cannot be configured with a unix address in the server_api configuration section")
+			}
+		case strings.HasPrefix(c.ServerAPI.Address, serverAPITCPScheme):
+			if _, _, err := net.SplitHostPort(strings.TrimPrefix(c.ServerAPI.Address, serverAPITCPScheme)); err != nil {
+				return nil, errs.New("invalid tcp address in the server_api configuration section: %v", err)
+			}
+			if c.ServerAPI.TLS == nil {
+				return nil, errs.New("tls must be configured with a tcp address in the server_api configuration section")
+			}
+			if err := parseServerAPITLSConfig(c.ServerAPI.TLS); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, errs.New("address must use the unix or tcp name system in the server_api configuration section")
 		}
 		c.ServerAPI.PollInterval, err = parsePollInterval(c.ServerAPI.RawPollInterval)
 		if err != nil {
 			return nil, errs.New("invalid poll_interval in the server_api configuration section: %v", err)
 		}
+		c.ServerAPI.BackoffBase, c.ServerAPI.BackoffMax, err = parseBackoff(c.ServerAPI.RawBackoffBase, c.ServerAPI.RawBackoffMax)
+		if err != nil {
+			return nil, errs.New("invalid backoff in the server_api configuration section: %v", err)
+		}
+		c.ServerAPI.DialTimeout, err = parseOptionalDuration(c.ServerAPI.RawDialTimeout)
+		if err != nil {
+			return nil, errs.New("invalid dial_timeout in the server_api configuration section: %v", err)
+		}
+		c.ServerAPI.KeepaliveTime, err = parseOptionalDuration(c.ServerAPI.RawKeepaliveTime)
+		if err != nil {
+			return nil, errs.New("invalid keepalive_time in the server_api configuration section: %v", err)
+		}
+		c.ServerAPI.KeepaliveTimeout, err = parseOptionalDuration(c.ServerAPI.RawKeepaliveTimeout)
+		if err != nil {
+			return nil, errs.New("invalid keepalive_timeout in the server_api configuration section: %v", err)
+		}
+		if c.ServerAPI.KeepaliveTimeout > 0 && c.ServerAPI.KeepaliveTime == 0 {
+			return nil, errs.New("keepalive_timeout requires keepalive_time in the server_api configuration section")
+		}
+		if c.ServerAPI.MaxJWKSSize < 0 {
+			return nil, errs.New("max_jwks_size must not be negative in the server_api configuration section")
+		}
 		methodCount++
 	}
 
@@ -185,27 +661,365 @@ func ParseConfig(hclConfig string) (_ *Config, err error) {
 		if c.WorkloadAPI.SocketPath == "" {
 			return nil, errs.New("socket_path must be configured in the workload_api configuration section")
 		}
-		if c.WorkloadAPI.TrustDomain == "" {
-			return nil, errs.New("trust_domain must be configured in the workload_api configuration section")
+		switch {
+		case c.WorkloadAPI.TrustDomain == "" && len(c.WorkloadAPI.TrustDomains) == 0:
+			return nil, errs.New("trust_domain or trust_domains must be configured in the workload_api configuration section")
+		case c.WorkloadAPI.TrustDomain != "" && len(c.WorkloadAPI.TrustDomains) > 0:
+			return nil, errs.New("trust_domain and trust_domains are mutually exclusive in the workload_api configuration section")
+		}
+		c.WorkloadAPI.TrustDomains = dedupeList(c.WorkloadAPI.TrustDomains)
+		for _, trustDomain := range c.WorkloadAPI.trustDomains() {
+			if _, err := spiffeid.TrustDomainFromString(trustDomain); err != nil {
+				return nil, errs.New("invalid trust domain %q in the workload_api configuration section: %v", trustDomain, err)
+			}
 		}
 		c.WorkloadAPI.PollInterval, err = parsePollInterval(c.WorkloadAPI.RawPollInterval)
 		if err != nil {
 			return nil, errs.New("invalid poll_interval in the workload_api configuration section: %v", err)
 		}
+		c.WorkloadAPI.BackoffBase, c.WorkloadAPI.BackoffMax, err = parseBackoff(c.WorkloadAPI.RawBackoffBase, c.WorkloadAPI.RawBackoffMax)
+		if err != nil {
+			return nil, errs.New("invalid backoff in the workload_api configuration section: %v", err)
+		}
+		if c.WorkloadAPI.MaxJWKSSize < 0 {
+			return nil, errs.New("max_jwks_size must not be negative in the workload_api configuration section")
+		}
+		methodCount++
+	}
+
+	if c.File != nil {
+		if c.File.Path == "" {
+			return nil, errs.New("path must be configured in the file configuration section")
+		}
+		c.File.PollInterval, err = parsePollInterval(c.File.RawPollInterval)
+		if err != nil {
+			return nil, errs.New("invalid poll_interval in the file configuration section: %v", err)
+		}
 		methodCount++
 	}
 
 	switch methodCount {
 	case 0:
-		return nil, errs.New("either the server_api or workload_api section must be configured")
+		return nil, errs.New("either the server_api, workload_api or file section must be configured")
 	case 1:
 	default:
-		return nil, errs.New("the server_api and workload_api sections are mutually exclusive")
+		return nil, errs.New("the server_api, workload_api and file sections are mutually exclusive")
+	}
+
+	if c.HealthChecks != nil {
+		if c.HealthChecks.BindAddress == "" {
+			c.HealthChecks.BindAddress = defaultHealthChecksBindAddress
+		}
+		if c.HealthChecks.BindPort == 0 {
+			c.HealthChecks.BindPort = defaultHealthChecksBindPort
+		}
+		if c.HealthChecks.RawReadyTimeout != "" {
+			c.HealthChecks.ReadyTimeout, err = time.ParseDuration(c.HealthChecks.RawReadyTimeout)
+			if err != nil {
+				return nil, errs.New("invalid ready_timeout in the health_checks configuration section: %v", err)
+			}
+		}
+		if c.HealthChecks.ReadyTimeout <= 0 {
+			c.HealthChecks.ReadyTimeout = defaultReadyTimeoutFactor * c.pollInterval()
+		}
 	}
 
 	return c, nil
 }
 
+func validateServingCertFileConfig(c *Config) (err error) {
+	switch {
+	case len(c.ACME) > 0:
+		return errs.New("the acme and serving_cert_file sections are mutually exclusive")
+	case c.InsecureAddr != "":
+		return errs.New("insecure_addr and the serving_cert_file section are mutually exclusive")
+	case c.ServingCertFile.CertFilePath == "":
+		return errs.New("cert_file_path must be configured in the serving_cert_file configuration section")
+	case c.ServingCertFile.KeyFilePath == "":
+		return errs.New("key_file_path must be configured in the serving_cert_file configuration section")
+	}
+
+	if c.ServingCertFile.Addr == "" {
+		c.ServingCertFile.Addr = defaultServingCertFileAddr
+	}
+
+	c.ServingCertFile.FileSyncInterval = defaultServingCertFileSyncInterval
+	if c.ServingCertFile.RawFileSyncInterval != "" {
+		c.ServingCertFile.FileSyncInterval, err = time.ParseDuration(c.ServingCertFile.RawFileSyncInterval)
+		if err != nil {
+			return errs.New("invalid file_sync_interval in the serving_cert_file configuration section: %v", err)
+		}
+		if c.ServingCertFile.FileSyncInterval <= 0 {
+			return errs.New("file_sync_interval must be positive in the serving_cert_file configuration section")
+		}
+	}
+	return nil
+}
+
+// decodeACMEConfigs decodes each acme section in the configuration. Sections
+// can be labeled with the domain they apply to, which the HCL decoder does not
+// support mixing with unlabeled sections, so the items are decoded one by one.
+func decodeACMEConfigs(file *ast.File) ([]*ACMEConfig, error) {
+	root, ok := file.Node.(*ast.ObjectList)
+	if !ok {
+		return nil, errs.New("malformed configuration")
+	}
+
+	var acmeConfigs []*ACMEConfig
+	for _, item := range root.Filter("acme").Items {
+		acmeConfig := new(ACMEConfig)
+		if err := hcl.DecodeObject(acmeConfig, item.Val); err != nil {
+			return nil, err
+		}
+		switch len(item.Keys) {
+		case 0:
+		case 1:
+			domain, ok := item.Keys[0].Token.Value().(string)
+			if !ok || domain == "" {
+				return nil, errs.New("acme section label must be a domain")
+			}
+			acmeConfig.Domain = domain
+		default:
+			return nil, errs.New("acme section only accepts a single domain label")
+		}
+		acmeConfigs = append(acmeConfigs, acmeConfig)
+	}
+	return acmeConfigs, nil
+}
+
+func validateACMEConfigs(acmeConfigs []*ACMEConfig, domains []string) error {
+	allowed := make(map[string]bool, len(domains))
+	for _, domain := range domains {
+		allowed[domain] = true
+	}
+
+	hasDefault := false
+	covered := make(map[string]bool)
+	for _, acmeConfig := range acmeConfigs {
+		switch {
+		case !acmeConfig.ToSAccepted:
+			return errs.New("tos_accepted must be set to true in the acme configuration section")
+		case acmeConfig.Email == "":
+			return errs.New("email must be configured in the acme configuration section")
+		}
+
+		if acmeConfig.RawRenewBefore != "" {
+			renewBefore, err := time.ParseDuration(acmeConfig.RawRenewBefore)
+			if err != nil {
+				return errs.New("invalid renew_before in the acme configuration section: %v", err)
+			}
+			// autocert falls back to its default for values within its
+			// renewal jitter, so reject them instead of silently ignoring
+			// them.
+			if renewBefore <= minACMERenewBefore {
+				return errs.New("renew_before must be greater than %s in the acme configuration section", minACMERenewBefore)
+			}
+			acmeConfig.RenewBefore = renewBefore
+		}
+
+		if err := validateACMEChallenge(acmeConfig); err != nil {
+			return err
+		}
+
+		acmeConfig.CacheDir = defaultCacheDir
+		if acmeConfig.Domain != "" {
+			// Keep the credentials for each domain apart so that sections
+			// using different ACME accounts do not clobber each other.
+			acmeConfig.CacheDir = filepath.Join(defaultCacheDir, acmeConfig.Domain)
+		}
+		if acmeConfig.RawCacheDir != nil {
+			acmeConfig.CacheDir = *acmeConfig.RawCacheDir
+		}
+
+		switch {
+		case acmeConfig.Domain == "":
+			if hasDefault {
+				return errs.New("only one acme configuration section can omit the domain")
+			}
+			hasDefault = true
+		case !allowed[acmeConfig.Domain]:
+			return errs.New("domain %q in the acme configuration section is not in the domains list", acmeConfig.Domain)
+		case covered[acmeConfig.Domain]:
+			return errs.New("more than one acme configuration section configured for domain %q", acmeConfig.Domain)
+		default:
+			covered[acmeConfig.Domain] = true
+		}
+	}
+
+	if !hasDefault {
+		for _, domain := range domains {
+			if !covered[domain] {
+				return errs.New("no acme configuration section configured for domain %q", domain)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateACMEChallenge(acmeConfig *ACMEConfig) error {
+	switch acmeConfig.ChallengeType {
+	case "", acmeChallengeTLSALPN01:
+		if acmeConfig.Route53 != nil || acmeConfig.CloudDNS != nil {
+			return errs.New("DNS providers can only be configured when challenge_type is %q in the acme configuration section", acmeChallengeDNS01)
+		}
+		return nil
+	case acmeChallengeDNS01:
+	default:
+		return errs.New("invalid challenge_type %q in the acme configuration section: must be %q or %q", acmeConfig.ChallengeType, acmeChallengeTLSALPN01, acmeChallengeDNS01)
+	}
+
+	switch {
+	case acmeConfig.Route53 != nil && acmeConfig.CloudDNS != nil:
+		return errs.New("only one of route53 or cloud_dns can be configured in the acme configuration section")
+	case acmeConfig.Route53 != nil:
+		r := acmeConfig.Route53
+		switch {
+		case r.HostedZoneID == "":
+			return errs.New("hosted_zone_id must be configured in the route53 section")
+		case (r.AccessKeyID == "") != (r.SecretAccessKey == ""):
+			return errs.New("access_key_id and secret_access_key must be configured together in the route53 section")
+		}
+	case acmeConfig.CloudDNS != nil:
+		c := acmeConfig.CloudDNS
+		switch {
+		case c.ProjectID == "":
+			return errs.New("project_id must be configured in the cloud_dns section")
+		case c.ManagedZone == "":
+			return errs.New("managed_zone must be configured in the cloud_dns section")
+		}
+	default:
+		return errs.New("a DNS provider (route53 or cloud_dns) must be configured in the acme configuration section when challenge_type is %q", acmeChallengeDNS01)
+	}
+	return nil
+}
+
+// sourceName returns the name of the configured source section.
+func (c *Config) sourceName() string {
+	switch {
+	case c.ServerAPI != nil:
+		return "server_api"
+	case c.WorkloadAPI != nil:
+		return "workload_api"
+	case c.File != nil:
+		return "file"
+	default:
+		return ""
+	}
+}
+
+// pollInterval returns the poll interval of the configured source.
+func (c *Config) pollInterval() time.Duration {
+	switch {
+	case c.ServerAPI != nil:
+		return c.ServerAPI.PollInterval
+	case c.WorkloadAPI != nil:
+		return c.WorkloadAPI.PollInterval
+	case c.File != nil:
+		return c.File.PollInterval
+	default:
+		return defaultPollInterval
+	}
+}
+
+// listenNetwork returns the network of the TCP listener.
+func (c *Config) listenNetwork() string {
+	if c.ListenNetwork == "" {
+		return "tcp"
+	}
+	return c.ListenNetwork
+}
+
+func validateDiscoveryDocument(claims map[string]interface{}) error {
+	// These claims are generated by the provider
+	for _, claim := range []string{
+		"issuer",
+		"jwks_uri",
+		"authorization_endpoint",
+		"response_types_supported",
+		"subject_types_supported",
+		"id_token_signing_alg_values_supported",
+	} {
+		if _, ok := claims[claim]; ok {
+			return errs.New("the %s claim cannot be overridden", claim)
+		}
+	}
+	if _, err := json.Marshal(claims); err != nil {
+		return err
+	}
+	return nil
+}
+
+func isSigningAlg(alg string) bool {
+	for _, signingAlg := range signingAlgOrder {
+		if alg == signingAlg {
+			return true
+		}
+	}
+	return false
+}
+
+func validateOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+
+	u, err := url.Parse(origin)
+	switch {
+	case err != nil:
+		return err
+	case u.Scheme != "http" && u.Scheme != "https":
+		return errs.New("scheme must be http or https")
+	case u.Host == "":
+		return errs.New("host is required")
+	case u.User != nil, u.Path != "", u.RawQuery != "", u.Fragment != "", u.Opaque != "":
+		return errs.New("must be of the form scheme://host[:port]")
+	}
+	return nil
+}
+
+func parseIssuer(rawIssuer string, allowInsecureScheme bool) (*url.URL, error) {
+	u, err := url.Parse(rawIssuer)
+	switch {
+	case err != nil:
+		return nil, err
+	case !u.IsAbs() || u.Host == "":
+		return nil, errs.New("must be an absolute URL")
+	case u.Scheme == "http" && !allowInsecureScheme:
+		return nil, errs.New("scheme must be https unless allow_insecure_scheme is set")
+	case u.Scheme != "https" && u.Scheme != "http":
+		return nil, errs.New("scheme must be https")
+	case u.User != nil, u.RawQuery != "", u.Fragment != "":
+		return nil, errs.New("must not contain user info, a query or a fragment")
+	}
+	return u, nil
+}
+
+// validateProtectedResourceConfig validates the resource and authorization
+// server identifiers, which are URLs with the same constraints as the issuer.
+func validateProtectedResourceConfig(c *ProtectedResourceConfig, allowInsecureScheme bool) error {
+	if c.Resource != "" {
+		if _, err := parseIssuer(c.Resource, allowInsecureScheme); err != nil {
+			return errs.New("invalid resource %q in the protected_resource configuration section: %v", c.Resource, err)
+		}
+	}
+	c.AuthorizationServers = dedupeList(c.AuthorizationServers)
+	for _, authorizationServer := range c.AuthorizationServers {
+		if _, err := parseIssuer(authorizationServer, allowInsecureScheme); err != nil {
+			return errs.New("invalid authorization server %q in the protected_resource configuration section: %v", authorizationServer, err)
+		}
+	}
+	return nil
+}
+
+// trustDomains returns the trust domains to publish the keys for.
+func (c *WorkloadAPIConfig) trustDomains() []string {
+	if c.TrustDomain != "" {
+		return []string{c.TrustDomain}
+	}
+	return c.TrustDomains
+}
+
 func dedupeList(items []string) []string {
 	keys := make(map[string]bool)
 	var list []string
@@ -220,6 +1034,21 @@ func dedupeList(items []string) []string {
 	return list
 }
 
+func parseServerAPITLSConfig(c *ServerAPITLSConfig) error {
+	if c.RawServerID == "" {
+		return errs.New("server_id must be configured in the server_api tls configuration section")
+	}
+	serverID, err := spiffeid.FromString(c.RawServerID)
+	if err != nil {
+		return errs.New("invalid server_id in the server_api tls configuration section: %v", err)
+	}
+	c.ServerID = serverID
+	if c.WorkloadAPISocketPath == "" {
+		return errs.New("workload_api_socket_path must be configured in the server_api tls configuration section")
+	}
+	return nil
+}
+
 func parsePollInterval(rawPollInterval string) (pollInterval time.Duration, err error) {
 	if rawPollInterval != "" {
 		pollInterval, err = time.ParseDuration(rawPollInterval)
@@ -232,3 +1061,58 @@ func parsePollInterval(rawPollInterval string) (pollInterval time.Duration, err
 	}
 	return pollInterval, nil
 }
+
+// parseOptionalDuration parses a duration that must be positive when set. A
+// zero duration is returned when it is not set.
+func parseOptionalDuration(raw string) (time.Duration, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, errs.New("must be positive")
+	}
+	return d, nil
+}
+
+func parseListenSocketMode(rawMode string) (os.FileMode, error) {
+	if rawMode == "" {
+		return defaultListenSocketMode, nil
+	}
+	mode, err := strconv.ParseUint(rawMode, 8, 32)
+	if err != nil {
+		return 0, errs.New("must be an octal number")
+	}
+	if mode&^uint64(os.ModePerm) != 0 {
+		return 0, errs.New("must only contain permission bits")
+	}
+	return os.FileMode(mode), nil
+}
+
+func parseBackoff(rawBase, rawMax string) (base, max time.Duration, err error) {
+	if rawBase != "" {
+		base, err = time.ParseDuration(rawBase)
+		if err != nil {
+			return 0, 0, errs.New("invalid backoff_base: %v", err)
+		}
+		if base <= 0 {
+			return 0, 0, errs.New("backoff_base must be positive")
+		}
+	}
+	if rawMax != "" {
+		max, err = time.ParseDuration(rawMax)
+		if err != nil {
+			return 0, 0, errs.New("invalid backoff_max: %v", err)
+		}
+		if max <= 0 {
+			return 0, 0, errs.New("backoff_max must be positive")
+		}
+	}
+	if base > 0 && max > 0 && base > max {
+		return 0, 0, errs.New("backoff_base must not be greater than backoff_max")
+	}
+	return base, max, nil
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"net/url"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/spire/pkg/common/telemetry"
 	"github.com/spiffe/spire/test/spiretest"
 	"github.com/stretchr/testify/require"
 )
@@ -43,11 +46,50 @@ func TestLoadConfig(t *testing.T) {
 	require.Equal(&Config{
 		LogLevel: defaultLogLevel,
 		Domains:  []string{"domain.test"},
-		ACME: &ACMEConfig{
+		ACME: []*ACMEConfig{{
 			CacheDir:    defaultCacheDir,
 			Email:       "admin@domain.test",
 			ToSAccepted: true,
+		}},
+		ServerAPI: &ServerAPIConfig{
+			Address:      "unix:///some/socket/path",
+			PollInterval: defaultPollInterval,
 		},
+	}, config)
+}
+
+func TestLoadConfigExpandsEnv(t *testing.T) {
+	require := require.New(t)
+
+	t.Setenv("OIDC_TEST_EMAIL", "admin@domain.test")
+	t.Setenv("OIDC_TEST_SOCKET_PATH", "/some/socket/path")
+
+	confPath := filepath.Join(spiretest.TempDir(t), "test.conf")
+	err := os.WriteFile(confPath, []byte(`
+		domains = ["domain.test"]
+		acme {
+			email = "${OIDC_TEST_EMAIL}"
+			directory_url = "https://directory.test/$$literal"
+			tos_accepted = true
+		}
+		server_api {
+			address = "unix://$OIDC_TEST_SOCKET_PATH"
+		}
+	`), 0600)
+	require.NoError(err)
+
+	config, err := LoadConfig(confPath)
+	require.NoError(err)
+
+	require.Equal(&Config{
+		LogLevel: defaultLogLevel,
+		Domains:  []string{"domain.test"},
+		ACME: []*ACMEConfig{{
+			CacheDir:     defaultCacheDir,
+			DirectoryURL: "https://directory.test/$literal",
+			Email:        "admin@domain.test",
+			ToSAccepted:  true,
+		}},
 		ServerAPI: &ServerAPIConfig{
 			Address:      "unix:///some/socket/path",
 			PollInterval: defaultPollInterval,
@@ -88,7 +130,7 @@ func TestParseConfig(t *testing.T) {
 					socket_path = "/other/socket/path"
 				}
 			`,
-			err: "either acme or listen_socket_path must be configured",
+			err: "either acme, serving_cert_file, insecure_addr or listen_socket_path must be configured",
 		},
 		{
 			name: "ACME ToS not accepted",
@@ -125,6 +167,7 @@ func TestParseConfig(t *testing.T) {
 					cache_dir = ""
 					directory_url = "https://directory.test"
 					email = "admin@domain.test"
+					renew_before = "720h"
 				}
 				server_api {
 					address = "unix:///some/socket/path"
@@ -133,13 +176,15 @@ func TestParseConfig(t *testing.T) {
 			out: &Config{
 				LogLevel: defaultLogLevel,
 				Domains:  []string{"domain.test"},
-				ACME: &ACMEConfig{
-					CacheDir:     "",
-					Email:        "admin@domain.test",
-					DirectoryURL: "https://directory.test",
-					RawCacheDir:  stringPtr(""),
-					ToSAccepted:  true,
-				},
+				ACME: []*ACMEConfig{{
+					CacheDir:       "",
+					Email:          "admin@domain.test",
+					DirectoryURL:   "https://directory.test",
+					RawCacheDir:    stringPtr(""),
+					RenewBefore:    720 * time.Hour,
+					RawRenewBefore: "720h",
+					ToSAccepted:    true,
+				}},
 				ServerAPI: &ServerAPIConfig{
 					Address:      "unix:///some/socket/path",
 					PollInterval: defaultPollInterval,
@@ -147,81 +192,99 @@ func TestParseConfig(t *testing.T) {
 			},
 		},
 		{
-			name: "both acme and insecure_addr configured",
+			name: "ACME invalid renew_before",
 			in: `
 				domains = ["domain.test"]
-				insecure_addr = ":8080"
 				acme {
 					email = "admin@domain.test"
 					tos_accepted = true
+					renew_before = "soon"
 				}
 				server_api {
-					socket_path = "/other/socket/path"
+					address = "unix:///some/socket/path"
 				}
 			`,
-			err: "insecure_addr and the acme section are mutually exclusive",
+			err: `invalid renew_before in the acme configuration section: time: invalid duration "soon"`,
 		},
 		{
-			name: "both acme and socket_listen_path configured",
+			name: "ACME renew_before too small",
 			in: `
 				domains = ["domain.test"]
-				listen_socket_path = "test"
 				acme {
 					email = "admin@domain.test"
 					tos_accepted = true
+					renew_before = "30m"
 				}
 				server_api {
-					socket_path = "/other/socket/path"
+					address = "unix:///some/socket/path"
 				}
 			`,
-			err: "listen_socket_path and the acme section are mutually exclusive",
+			err: "renew_before must be greater than 1h0m0s in the acme configuration section",
 		},
 		{
-			name: "both insecure_addr and socket_listen_path configured",
+			name: "ACME dns-01 with route53",
 			in: `
 				domains = ["domain.test"]
-				insecure_addr = ":8080"
-				listen_socket_path = "test"
-				server_api {
-					socket_path = "/other/socket/path"
+				acme {
+					email = "admin@domain.test"
+					tos_accepted = true
+					challenge_type = "dns-01"
+					route53 {
+						hosted_zone_id = "ZONEID"
+					}
 				}
-			`,
-			err: "insecure_addr and listen_socket_path are mutually exclusive",
-		},
-		{
-			name: "with insecure addr and key use",
-			in: `
-				domains = ["domain.test"]
-				insecure_addr = ":8080"
 				server_api {
 					address = "unix:///some/socket/path"
 				}
-				set_key_use = true
 			`,
 			out: &Config{
-				LogLevel:     defaultLogLevel,
-				Domains:      []string{"domain.test"},
-				InsecureAddr: ":8080",
+				LogLevel: defaultLogLevel,
+				Domains:  []string{"domain.test"},
+				ACME: []*ACMEConfig{{
+					CacheDir:      defaultCacheDir,
+					Email:         "admin@domain.test",
+					ToSAccepted:   true,
+					ChallengeType: "dns-01",
+					Route53:       &Route53Config{HostedZoneID: "ZONEID"},
+				}},
 				ServerAPI: &ServerAPIConfig{
 					Address:      "unix:///some/socket/path",
 					PollInterval: defaultPollInterval,
 				},
-				SetKeyUse: true,
 			},
 		},
 		{
-			name: "with listen_socket_path",
+			name: "ACME dns-01 with cloud_dns",
 			in: `
 				domains = ["domain.test"]
-				listen_socket_path = "/a/path/here"
+				acme {
+					email = "admin@domain.test"
+					tos_accepted = true
+					challenge_type = "dns-01"
+					cloud_dns {
+						project_id = "project"
+						managed_zone = "zone"
+						service_account_file = "/path/to/sa.json"
+					}
+				}
 				server_api {
 					address = "unix:///some/socket/path"
 				}
 			`,
 			out: &Config{
-				LogLevel:         defaultLogLevel,
-				Domains:          []string{"domain.test"},
-				ListenSocketPath: "/a/path/here",
+				LogLevel: defaultLogLevel,
+				Domains:  []string{"domain.test"},
+				ACME: []*ACMEConfig{{
+					CacheDir:      defaultCacheDir,
+					Email:         "admin@domain.test",
+					ToSAccepted:   true,
+					ChallengeType: "dns-01",
+					CloudDNS: &CloudDNSConfig{
+						ProjectID:          "project",
+						ManagedZone:        "zone",
+						ServiceAccountFile: "/path/to/sa.json",
+					},
+				}},
 				ServerAPI: &ServerAPIConfig{
 					Address:      "unix:///some/socket/path",
 					PollInterval: defaultPollInterval,
@@ -229,217 +292,1899 @@ func TestParseConfig(t *testing.T) {
 			},
 		},
 		{
-			name: "no source section configured",
+			name: "ACME invalid challenge_type",
 			in: `
 				domains = ["domain.test"]
 				acme {
 					email = "admin@domain.test"
 					tos_accepted = true
+					challenge_type = "http-01"
+				}
+				server_api {
+					address = "unix:///some/socket/path"
 				}
 			`,
-			err: "either the server_api or workload_api section must be configured",
+			err: `invalid challenge_type "http-01" in the acme configuration section: must be "tls-alpn-01" or "dns-01"`,
 		},
 		{
-			name: "more than one source section configured",
+			name: "ACME dns-01 without DNS provider",
 			in: `
 				domains = ["domain.test"]
 				acme {
 					email = "admin@domain.test"
 					tos_accepted = true
+					challenge_type = "dns-01"
+				}
+				server_api {
+					address = "unix:///some/socket/path"
 				}
-				server_api { address = "unix:///some/socket/path" }
-				workload_api { socket_path = "/some/socket/path" trust_domain="foo.test" }
 			`,
-			err: "the server_api and workload_api sections are mutually exclusive",
+			err: `a DNS provider (route53 or cloud_dns) must be configured in the acme configuration section when challenge_type is "dns-01"`,
 		},
 		{
-			name: "minimal server API config",
-			in:   minimalServerAPIConfig,
-			out: &Config{
-				LogLevel: defaultLogLevel,
-				Domains:  []string{"domain.test"},
-				ACME: &ACMEConfig{
-					CacheDir:    defaultCacheDir,
-					Email:       "admin@domain.test",
-					ToSAccepted: true,
-				},
-				ServerAPI: &ServerAPIConfig{
-					Address:      "unix:///some/socket/path",
-					PollInterval: defaultPollInterval,
-				},
-			},
+			name: "ACME DNS provider without dns-01",
+			in: `
+				domains = ["domain.test"]
+				acme {
+					email = "admin@domain.test"
+					tos_accepted = true
+					route53 {
+						hosted_zone_id = "ZONEID"
+					}
+				}
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: `DNS providers can only be configured when challenge_type is "dns-01" in the acme configuration section`,
 		},
 		{
-			name: "server API config overrides",
+			name: "ACME more than one DNS provider",
 			in: `
 				domains = ["domain.test"]
 				acme {
 					email = "admin@domain.test"
 					tos_accepted = true
+					challenge_type = "dns-01"
+					route53 {
+						hosted_zone_id = "ZONEID"
+					}
+					cloud_dns {
+						project_id = "project"
+						managed_zone = "zone"
+					}
 				}
 				server_api {
-					address = "unix:///other/socket/path"
-					poll_interval = "1h"
+					address = "unix:///some/socket/path"
 				}
 			`,
-			out: &Config{
-				LogLevel: defaultLogLevel,
-				Domains:  []string{"domain.test"},
-				ACME: &ACMEConfig{
-					CacheDir:    defaultCacheDir,
-					Email:       "admin@domain.test",
-					ToSAccepted: true,
-				},
-				ServerAPI: &ServerAPIConfig{
-					Address:         "unix:///other/socket/path",
-					PollInterval:    time.Hour,
-					RawPollInterval: "1h",
-				},
-			},
+			err: "only one of route53 or cloud_dns can be configured in the acme configuration section",
 		},
 		{
-			name: "server API config missing address",
+			name: "ACME route53 missing hosted_zone_id",
 			in: `
 				domains = ["domain.test"]
 				acme {
 					email = "admin@domain.test"
 					tos_accepted = true
+					challenge_type = "dns-01"
+					route53 {}
 				}
 				server_api {
+					address = "unix:///some/socket/path"
 				}
 			`,
-			err: "address must be configured in the server_api configuration section",
+			err: "hosted_zone_id must be configured in the route53 section",
 		},
 		{
-			name: "server API config invalid address",
+			name: "ACME route53 missing secret_access_key",
 			in: `
 				domains = ["domain.test"]
 				acme {
 					email = "admin@domain.test"
 					tos_accepted = true
+					challenge_type = "dns-01"
+					route53 {
+						hosted_zone_id = "ZONEID"
+						access_key_id = "ACCESSKEYID"
+					}
 				}
 				server_api {
-					address = "localhost:8199"
+					address = "unix:///some/socket/path"
 				}
 			`,
-			err: "address must use the unix name system in the server_api configuration section",
+			err: "access_key_id and secret_access_key must be configured together in the route53 section",
 		},
 		{
-			name: "server API config invalid poll interval",
+			name: "ACME cloud_dns missing project_id",
 			in: `
 				domains = ["domain.test"]
 				acme {
 					email = "admin@domain.test"
 					tos_accepted = true
+					challenge_type = "dns-01"
+					cloud_dns {
+						managed_zone = "zone"
+					}
 				}
 				server_api {
 					address = "unix:///some/socket/path"
-					poll_interval = "huh"
 				}
 			`,
-			err: "invalid poll_interval in the server_api configuration section: time: invalid duration \"huh\"",
+			err: "project_id must be configured in the cloud_dns section",
 		},
 		{
-			name: "minimal workload API config",
+			name: "ACME cloud_dns missing managed_zone",
 			in: `
 				domains = ["domain.test"]
 				acme {
 					email = "admin@domain.test"
 					tos_accepted = true
+					challenge_type = "dns-01"
+					cloud_dns {
+						project_id = "project"
+					}
 				}
-				workload_api {
-					socket_path = "/some/socket/path"
-					trust_domain = "domain.test"
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: "managed_zone must be configured in the cloud_dns section",
+		},
+		{
+			name: "ACME per domain",
+			in: `
+				domains = ["oidc.prod.test", "oidc.staging.test"]
+				acme "oidc.prod.test" {
+					email = "prod@domain.test"
+					tos_accepted = true
+				}
+				acme "oidc.staging.test" {
+					directory_url = "https://staging.directory.test"
+					email = "staging@domain.test"
+					tos_accepted = true
+				}
+				server_api {
+					address = "unix:///some/socket/path"
 				}
 			`,
 			out: &Config{
 				LogLevel: defaultLogLevel,
-				Domains:  []string{"domain.test"},
-				ACME: &ACMEConfig{
-					CacheDir:    defaultCacheDir,
-					Email:       "admin@domain.test",
-					ToSAccepted: true,
+				Domains:  []string{"oidc.prod.test", "oidc.staging.test"},
+				ACME: []*ACMEConfig{
+					{
+						Domain:      "oidc.prod.test",
+						CacheDir:    filepath.Join(defaultCacheDir, "oidc.prod.test"),
+						Email:       "prod@domain.test",
+						ToSAccepted: true,
+					},
+					{
+						Domain:       "oidc.staging.test",
+						CacheDir:     filepath.Join(defaultCacheDir, "oidc.staging.test"),
+						DirectoryURL: "https://staging.directory.test",
+						Email:        "staging@domain.test",
+						ToSAccepted:  true,
+					},
 				},
-				WorkloadAPI: &WorkloadAPIConfig{
-					SocketPath:   "/some/socket/path",
+				ServerAPI: &ServerAPIConfig{
+					Address:      "unix:///some/socket/path",
 					PollInterval: defaultPollInterval,
-					TrustDomain:  "domain.test",
 				},
 			},
 		},
 		{
-			name: "workload API config overrides",
+			name: "ACME per domain with default section",
 			in: `
-				domains = ["domain.test"]
+				domains = ["oidc.prod.test", "oidc.staging.test", "oidc.dev.test"]
 				acme {
 					email = "admin@domain.test"
 					tos_accepted = true
 				}
-				workload_api {
-					socket_path = "/other/socket/path"
-					poll_interval = "1h"
-					trust_domain = "foo.test"
+				acme "oidc.staging.test" {
+					email = "staging@domain.test"
+					tos_accepted = true
+				}
+				server_api {
+					address = "unix:///some/socket/path"
 				}
 			`,
 			out: &Config{
 				LogLevel: defaultLogLevel,
-				Domains:  []string{"domain.test"},
-				ACME: &ACMEConfig{
-					CacheDir:    defaultCacheDir,
-					Email:       "admin@domain.test",
-					ToSAccepted: true,
+				Domains:  []string{"oidc.prod.test", "oidc.staging.test", "oidc.dev.test"},
+				ACME: []*ACMEConfig{
+					{
+						CacheDir:    defaultCacheDir,
+						Email:       "admin@domain.test",
+						ToSAccepted: true,
+					},
+					{
+						Domain:      "oidc.staging.test",
+						CacheDir:    filepath.Join(defaultCacheDir, "oidc.staging.test"),
+						Email:       "staging@domain.test",
+						ToSAccepted: true,
+					},
 				},
-				WorkloadAPI: &WorkloadAPIConfig{
-					SocketPath:      "/other/socket/path",
-					PollInterval:    time.Hour,
-					RawPollInterval: "1h",
-					TrustDomain:     "foo.test",
+				ServerAPI: &ServerAPIConfig{
+					Address:      "unix:///some/socket/path",
+					PollInterval: defaultPollInterval,
 				},
 			},
 		},
 		{
-			name: "workload API config missing socket path",
+			name: "ACME per domain missing a domain",
+			in: `
+				domains = ["oidc.prod.test", "oidc.staging.test"]
+				acme "oidc.prod.test" {
+					email = "prod@domain.test"
+					tos_accepted = true
+				}
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: `no acme configuration section configured for domain "oidc.staging.test"`,
+		},
+		{
+			name: "ACME per domain with unknown domain",
+			in: `
+				domains = ["oidc.prod.test"]
+				acme "oidc.other.test" {
+					email = "prod@domain.test"
+					tos_accepted = true
+				}
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: `domain "oidc.other.test" in the acme configuration section is not in the domains list`,
+		},
+		{
+			name: "ACME per domain with duplicate domain",
+			in: `
+				domains = ["oidc.prod.test"]
+				acme "oidc.prod.test" {
+					email = "prod@domain.test"
+					tos_accepted = true
+				}
+				acme "oidc.prod.test" {
+					email = "other@domain.test"
+					tos_accepted = true
+				}
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: `more than one acme configuration section configured for domain "oidc.prod.test"`,
+		},
+		{
+			name: "more than one default ACME section",
 			in: `
 				domains = ["domain.test"]
 				acme {
 					email = "admin@domain.test"
 					tos_accepted = true
 				}
-				workload_api {
-					trust_domain = "domain.test"
+				acme {
+					email = "other@domain.test"
+					tos_accepted = true
+				}
+				server_api {
+					address = "unix:///some/socket/path"
 				}
 			`,
-			err: "socket_path must be configured in the workload_api configuration section",
+			err: "only one acme configuration section can omit the domain",
 		},
 		{
-			name: "workload API config invalid poll interval",
+			name: "both acme and insecure_addr configured",
 			in: `
 				domains = ["domain.test"]
+				insecure_addr = ":8080"
 				acme {
 					email = "admin@domain.test"
 					tos_accepted = true
 				}
-				workload_api {
-					socket_path = "/some/socket/path"
-					poll_interval = "huh"
-					trust_domain = "domain.test"
+				server_api {
+					socket_path = "/other/socket/path"
 				}
 			`,
-			err: "invalid poll_interval in the workload_api configuration section: time: invalid duration \"huh\"",
+			err: "insecure_addr and the acme section are mutually exclusive",
 		},
 		{
-			name: "workload API config missing trust domain",
+			name: "both acme and listen_socket_path configured",
 			in: `
 				domains = ["domain.test"]
+				listen_socket_path = "test"
+				acme {
+					email = "admin@domain.test"
+					tos_accepted = true
+				}
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			out: &Config{
+				LogLevel:         defaultLogLevel,
+				Domains:          []string{"domain.test"},
+				ListenSocketPath: "test",
+				ListenSocketMode: defaultListenSocketMode,
+				ACME: []*ACMEConfig{
+					{
+						CacheDir:    defaultCacheDir,
+						Email:       "admin@domain.test",
+						ToSAccepted: true,
+					},
+				},
+				ServerAPI: &ServerAPIConfig{
+					Address:      "unix:///some/socket/path",
+					PollInterval: defaultPollInterval,
+				},
+			},
+		},
+		{
+			name: "acme, listen_socket_path and insecure_addr configured",
+			in: `
+				domains = ["domain.test"]
+				listen_socket_path = "test"
+				insecure_addr = ":1234"
 				acme {
 					email = "admin@domain.test"
 					tos_accepted = true
 				}
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: "insecure_addr and the acme section are mutually exclusive",
+		},
+		{
+			name: "both insecure_addr and socket_listen_path configured",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				listen_socket_path = "test"
+				server_api {
+					socket_path = "/other/socket/path"
+				}
+			`,
+			err: "insecure_addr and listen_socket_path are mutually exclusive",
+		},
+		{
+			name: "with insecure addr and key use",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+				set_key_use = true
+			`,
+			out: &Config{
+				LogLevel:     defaultLogLevel,
+				Domains:      []string{"domain.test"},
+				InsecureAddr: ":8080",
+				ServerAPI: &ServerAPIConfig{
+					Address:      "unix:///some/socket/path",
+					PollInterval: defaultPollInterval,
+				},
+				SetKeyUse: true,
+			},
+		},
+		{
+			name: "with listen_network",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				listen_network = "tcp4"
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			out: &Config{
+				LogLevel:      defaultLogLevel,
+				Domains:       []string{"domain.test"},
+				InsecureAddr:  ":8080",
+				ListenNetwork: "tcp4",
+				ServerAPI: &ServerAPIConfig{
+					Address:      "unix:///some/socket/path",
+					PollInterval: defaultPollInterval,
+				},
+			},
+		},
+		{
+			name: "invalid listen_network",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				listen_network = "udp"
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: `invalid listen_network "udp": expected "tcp", "tcp4" or "tcp6"`,
+		},
+		{
+			name: "with use_proxy_protocol",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				use_proxy_protocol = true
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			out: &Config{
+				LogLevel:         defaultLogLevel,
+				Domains:          []string{"domain.test"},
+				InsecureAddr:     ":8080",
+				UseProxyProtocol: true,
+				ServerAPI: &ServerAPIConfig{
+					Address:      "unix:///some/socket/path",
+					PollInterval: defaultPollInterval,
+				},
+			},
+		},
+		{
+			name: "use_proxy_protocol without a TCP listener",
+			in: `
+				domains = ["domain.test"]
+				listen_socket_path = "test"
+				use_proxy_protocol = true
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: "use_proxy_protocol requires insecure_addr, serving_cert_file or acme to be configured",
+		},
+		{
+			name: "with listen_socket_path",
+			in: `
+				domains = ["domain.test"]
+				listen_socket_path = "/a/path/here"
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			out: &Config{
+				LogLevel:         defaultLogLevel,
+				Domains:          []string{"domain.test"},
+				ListenSocketPath: "/a/path/here",
+				ListenSocketMode: defaultListenSocketMode,
+				ServerAPI: &ServerAPIConfig{
+					Address:      "unix:///some/socket/path",
+					PollInterval: defaultPollInterval,
+				},
+			},
+		},
+		{
+			name: "with listen_socket_path permissions",
+			in: `
+				domains = ["domain.test"]
+				listen_socket_path = "/a/path/here"
+				listen_socket_mode = "0660"
+				listen_socket_owner = "oidc"
+				listen_socket_group = "1000"
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			out: &Config{
+				LogLevel:            defaultLogLevel,
+				Domains:             []string{"domain.test"},
+				ListenSocketPath:    "/a/path/here",
+				ListenSocketMode:    0660,
+				RawListenSocketMode: "0660",
+				ListenSocketOwner:   "oidc",
+				ListenSocketGroup:   "1000",
+				ServerAPI: &ServerAPIConfig{
+					Address:      "unix:///some/socket/path",
+					PollInterval: defaultPollInterval,
+				},
+			},
+		},
+		{
+			name: "listen_socket_mode is not octal",
+			in: `
+				domains = ["domain.test"]
+				listen_socket_path = "/a/path/here"
+				listen_socket_mode = "0698"
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: "invalid listen_socket_mode: must be an octal number",
+		},
+		{
+			name: "listen_socket_mode has more than permission bits",
+			in: `
+				domains = ["domain.test"]
+				listen_socket_path = "/a/path/here"
+				listen_socket_mode = "4755"
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: "invalid listen_socket_mode: must only contain permission bits",
+		},
+		{
+			name: "listen_socket_owner without listen_socket_path",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				listen_socket_owner = "oidc"
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: "listen_socket_mode, listen_socket_owner and listen_socket_group require listen_socket_path",
+		},
+		{
+			name: "both acme and serving_cert_file configured",
+			in: `
+				domains = ["domain.test"]
+				acme {
+					email = "admin@domain.test"
+					tos_accepted = true
+				}
+				serving_cert_file {
+					cert_file_path = "/some/cert/path"
+					key_file_path = "/some/key/path"
+				}
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: "the acme and serving_cert_file sections are mutually exclusive",
+		},
+		{
+			name: "both insecure_addr and serving_cert_file configured",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				serving_cert_file {
+					cert_file_path = "/some/cert/path"
+					key_file_path = "/some/key/path"
+				}
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: "insecure_addr and the serving_cert_file section are mutually exclusive",
+		},
+		{
+			name: "both listen_socket_path and serving_cert_file configured",
+			in: `
+				domains = ["domain.test"]
+				listen_socket_path = "test"
+				serving_cert_file {
+					cert_file_path = "/some/cert/path"
+					key_file_path = "/some/key/path"
+				}
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			out: &Config{
+				LogLevel:         defaultLogLevel,
+				Domains:          []string{"domain.test"},
+				ListenSocketPath: "test",
+				ListenSocketMode: defaultListenSocketMode,
+				ServingCertFile: &ServingCertFileConfig{
+					CertFilePath:     "/some/cert/path",
+					KeyFilePath:      "/some/key/path",
+					Addr:             defaultServingCertFileAddr,
+					FileSyncInterval: defaultServingCertFileSyncInterval,
+				},
+				ServerAPI: &ServerAPIConfig{
+					Address:      "unix:///some/socket/path",
+					PollInterval: defaultPollInterval,
+				},
+			},
+		},
+		{
+			name: "serving_cert_file, listen_socket_path and acme configured",
+			in: `
+				domains = ["domain.test"]
+				listen_socket_path = "test"
+				serving_cert_file {
+					cert_file_path = "/some/cert/path"
+					key_file_path = "/some/key/path"
+				}
+				acme {
+					email = "admin@domain.test"
+					tos_accepted = true
+				}
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: "the acme and serving_cert_file sections are mutually exclusive",
+		},
+		{
+			name: "serving_cert_file missing cert_file_path",
+			in: `
+				domains = ["domain.test"]
+				serving_cert_file {
+					key_file_path = "/some/key/path"
+				}
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: "cert_file_path must be configured in the serving_cert_file configuration section",
+		},
+		{
+			name: "serving_cert_file missing key_file_path",
+			in: `
+				domains = ["domain.test"]
+				serving_cert_file {
+					cert_file_path = "/some/cert/path"
+				}
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: "key_file_path must be configured in the serving_cert_file configuration section",
+		},
+		{
+			name: "serving_cert_file invalid file_sync_interval",
+			in: `
+				domains = ["domain.test"]
+				serving_cert_file {
+					cert_file_path = "/some/cert/path"
+					key_file_path = "/some/key/path"
+					file_sync_interval = "huh"
+				}
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: "invalid file_sync_interval in the serving_cert_file configuration section: time: invalid duration \"huh\"",
+		},
+		{
+			name: "minimal serving_cert_file config",
+			in: `
+				domains = ["domain.test"]
+				serving_cert_file {
+					cert_file_path = "/some/cert/path"
+					key_file_path = "/some/key/path"
+				}
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			out: &Config{
+				LogLevel: defaultLogLevel,
+				Domains:  []string{"domain.test"},
+				ServingCertFile: &ServingCertFileConfig{
+					CertFilePath:     "/some/cert/path",
+					KeyFilePath:      "/some/key/path",
+					Addr:             defaultServingCertFileAddr,
+					FileSyncInterval: defaultServingCertFileSyncInterval,
+				},
+				ServerAPI: &ServerAPIConfig{
+					Address:      "unix:///some/socket/path",
+					PollInterval: defaultPollInterval,
+				},
+			},
+		},
+		{
+			name: "serving_cert_file config overrides",
+			in: `
+				domains = ["domain.test"]
+				serving_cert_file {
+					cert_file_path = "/some/cert/path"
+					key_file_path = "/some/key/path"
+					addr = ":8443"
+					file_sync_interval = "5m"
+				}
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			out: &Config{
+				LogLevel: defaultLogLevel,
+				Domains:  []string{"domain.test"},
+				ServingCertFile: &ServingCertFileConfig{
+					CertFilePath:        "/some/cert/path",
+					KeyFilePath:         "/some/key/path",
+					Addr:                ":8443",
+					FileSyncInterval:    5 * time.Minute,
+					RawFileSyncInterval: "5m",
+				},
+				ServerAPI: &ServerAPIConfig{
+					Address:      "unix:///some/socket/path",
+					PollInterval: defaultPollInterval,
+				},
+			},
+		},
+		{
+			name: "minimal health checks config",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				health_checks {}
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			out: &Config{
+				LogLevel:     defaultLogLevel,
+				Domains:      []string{"domain.test"},
+				InsecureAddr: ":8080",
+				HealthChecks: &HealthChecksConfig{
+					BindAddress:  defaultHealthChecksBindAddress,
+					BindPort:     defaultHealthChecksBindPort,
+					ReadyTimeout: defaultReadyTimeoutFactor * defaultPollInterval,
+				},
+				ServerAPI: &ServerAPIConfig{
+					Address:      "unix:///some/socket/path",
+					PollInterval: defaultPollInterval,
+				},
+			},
+		},
+		{
+			name: "health checks config overrides",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				health_checks {
+					bind_address = "0.0.0.0"
+					bind_port = 8081
+					ready_timeout = "2m"
+				}
+				workload_api {
+					socket_path = "/some/socket/path"
+					trust_domain = "domain.test"
+				}
+			`,
+			out: &Config{
+				LogLevel:     defaultLogLevel,
+				Domains:      []string{"domain.test"},
+				InsecureAddr: ":8080",
+				HealthChecks: &HealthChecksConfig{
+					BindAddress:     "0.0.0.0",
+					BindPort:        8081,
+					ReadyTimeout:    2 * time.Minute,
+					RawReadyTimeout: "2m",
+				},
+				WorkloadAPI: &WorkloadAPIConfig{
+					SocketPath:   "/some/socket/path",
+					PollInterval: defaultPollInterval,
+					TrustDomain:  "domain.test",
+				},
+			},
+		},
+		{
+			name: "health checks config invalid ready timeout",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				health_checks {
+					ready_timeout = "huh"
+				}
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: "invalid ready_timeout in the health_checks configuration section: time: invalid duration \"huh\"",
+		},
+		{
+			name: "with jwks_cache_max_age",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				jwks_cache_max_age = "5m"
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			out: &Config{
+				LogLevel:           defaultLogLevel,
+				Domains:            []string{"domain.test"},
+				InsecureAddr:       ":8080",
+				JWKSCacheMaxAge:    5 * time.Minute,
+				RawJWKSCacheMaxAge: "5m",
+				ServerAPI: &ServerAPIConfig{
+					Address:      "unix:///some/socket/path",
+					PollInterval: defaultPollInterval,
+				},
+			},
+		},
+		{
+			name: "with telemetry",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				telemetry {
+					Prometheus {
+						port = 8088
+					}
+				}
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			out: &Config{
+				LogLevel:     defaultLogLevel,
+				Domains:      []string{"domain.test"},
+				InsecureAddr: ":8080",
+				Telemetry: telemetry.FileConfig{
+					Prometheus: &telemetry.PrometheusConfig{
+						Port: 8088,
+					},
+				},
+				ServerAPI: &ServerAPIConfig{
+					Address:      "unix:///some/socket/path",
+					PollInterval: defaultPollInterval,
+				},
+			},
+		},
+		{
+			name: "with issuer",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				issuer = "https://id.example.com/spire"
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			out: &Config{
+				LogLevel:     defaultLogLevel,
+				Domains:      []string{"domain.test"},
+				InsecureAddr: ":8080",
+				Issuer:       &url.URL{Scheme: "https", Host: "id.example.com", Path: "/spire"},
+				RawIssuer:    "https://id.example.com/spire",
+				ServerAPI: &ServerAPIConfig{
+					Address:      "unix:///some/socket/path",
+					PollInterval: defaultPollInterval,
+				},
+			},
+		},
+		{
+			name: "with insecure issuer and allow_insecure_scheme",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				allow_insecure_scheme = true
+				issuer = "http://id.example.com"
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			out: &Config{
+				LogLevel:            defaultLogLevel,
+				Domains:             []string{"domain.test"},
+				InsecureAddr:        ":8080",
+				AllowInsecureScheme: true,
+				Issuer:              &url.URL{Scheme: "http", Host: "id.example.com"},
+				RawIssuer:           "http://id.example.com",
+				ServerAPI: &ServerAPIConfig{
+					Address:      "unix:///some/socket/path",
+					PollInterval: defaultPollInterval,
+				},
+			},
+		},
+		{
+			name: "with path_prefix",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				path_prefix = "/spire/"
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			out: &Config{
+				LogLevel:     defaultLogLevel,
+				Domains:      []string{"domain.test"},
+				InsecureAddr: ":8080",
+				PathPrefix:   "/spire",
+				ServerAPI: &ServerAPIConfig{
+					Address:      "unix:///some/socket/path",
+					PollInterval: defaultPollInterval,
+				},
+			},
+		},
+		{
+			name: "path_prefix does not begin with a slash",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				path_prefix = "spire"
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: `invalid path_prefix "spire": must begin with "/"`,
+		},
+		{
+			name: "path_prefix with query",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				path_prefix = "/spire?x=y"
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: `invalid path_prefix "/spire?x=y": must not contain a query or fragment`,
+		},
+		{
+			name: "issuer is not absolute",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				issuer = "/spire"
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: `invalid issuer "/spire": must be an absolute URL`,
+		},
+		{
+			name: "insecure issuer without allow_insecure_scheme",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				issuer = "http://id.example.com"
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: `invalid issuer "http://id.example.com": scheme must be https unless allow_insecure_scheme is set`,
+		},
+		{
+			name: "issuer with query",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				issuer = "https://id.example.com?foo=bar"
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: `invalid issuer "https://id.example.com?foo=bar": must not contain user info, a query or a fragment`,
+		},
+		{
+			name: "invalid jwks_cache_max_age",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				jwks_cache_max_age = "huh"
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: "invalid jwks_cache_max_age: time: invalid duration \"huh\"",
+		},
+		{
+			name: "jwks_cache_max_age too small",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				jwks_cache_max_age = "500ms"
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: "jwks_cache_max_age must be at least one second",
+		},
+		{
+			name: "with discovery_document",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				discovery_document {
+					scopes_supported = ["openid"]
+					service_documentation = "https://docs.domain.test"
+				}
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			out: &Config{
+				LogLevel:     defaultLogLevel,
+				Domains:      []string{"domain.test"},
+				InsecureAddr: ":8080",
+				DiscoveryDocument: map[string]interface{}{
+					"scopes_supported":      []interface{}{"openid"},
+					"service_documentation": "https://docs.domain.test",
+				},
+				ServerAPI: &ServerAPIConfig{
+					Address:      "unix:///some/socket/path",
+					PollInterval: defaultPollInterval,
+				},
+			},
+		},
+		{
+			name: "discovery_document overriding issuer",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				discovery_document {
+					issuer = "https://other.test"
+				}
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: "invalid discovery_document: the issuer claim cannot be overridden",
+		},
+		{
+			name: "discovery_document overriding jwks_uri",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				discovery_document {
+					jwks_uri = "https://other.test/keys"
+				}
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: "invalid discovery_document: the jwks_uri claim cannot be overridden",
+		},
+		{
+			name: "with protected_resource",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				protected_resource {
+					resource = "https://api.domain.test"
+					authorization_servers = ["https://as.domain.test", "https://as.domain.test"]
+				}
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			out: &Config{
+				LogLevel:     defaultLogLevel,
+				Domains:      []string{"domain.test"},
+				InsecureAddr: ":8080",
+				ProtectedResource: &ProtectedResourceConfig{
+					Resource:             "https://api.domain.test",
+					AuthorizationServers: []string{"https://as.domain.test"},
+				},
+				ServerAPI: &ServerAPIConfig{
+					Address:      "unix:///some/socket/path",
+					PollInterval: defaultPollInterval,
+				},
+			},
+		},
+		{
+			name: "protected_resource with invalid resource",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				protected_resource {
+					resource = "https://api.domain.test#fragment"
+				}
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: `invalid resource "https://api.domain.test#fragment" in the protected_resource configuration section: must not contain user info, a query or a fragment`,
+		},
+		{
+			name: "protected_resource with insecure authorization server",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				protected_resource {
+					authorization_servers = ["http://as.domain.test"]
+				}
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: `invalid authorization server "http://as.domain.test" in the protected_resource configuration section: scheme must be https unless allow_insecure_scheme is set`,
+		},
+		{
+			name: "with allowed_origins",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				allowed_origins = ["https://app.domain.test", "http://localhost:3000", "*"]
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			out: &Config{
+				LogLevel:       defaultLogLevel,
+				Domains:        []string{"domain.test"},
+				InsecureAddr:   ":8080",
+				AllowedOrigins: []string{"https://app.domain.test", "http://localhost:3000", "*"},
+				ServerAPI: &ServerAPIConfig{
+					Address:      "unix:///some/socket/path",
+					PollInterval: defaultPollInterval,
+				},
+			},
+		},
+		{
+			name: "allowed_origins with unsupported scheme",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				allowed_origins = ["ftp://app.domain.test"]
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: `invalid origin "ftp://app.domain.test" in allowed_origins: scheme must be http or https`,
+		},
+		{
+			name: "allowed_origins without host",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				allowed_origins = ["app.domain.test"]
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: `invalid origin "app.domain.test" in allowed_origins: scheme must be http or https`,
+		},
+		{
+			name: "allowed_origins with path",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				allowed_origins = ["https://app.domain.test/"]
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: `invalid origin "https://app.domain.test/" in allowed_origins: must be of the form scheme://host[:port]`,
+		},
+		{
+			name: "with publish_key_use",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				set_key_use = true
+				publish_key_use = ["sig"]
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			out: &Config{
+				LogLevel:      defaultLogLevel,
+				Domains:       []string{"domain.test"},
+				InsecureAddr:  ":8080",
+				SetKeyUse:     true,
+				PublishKeyUse: []string{"sig"},
+				ServerAPI: &ServerAPIConfig{
+					Address:      "unix:///some/socket/path",
+					PollInterval: defaultPollInterval,
+				},
+			},
+		},
+		{
+			name: "with id_token_signing_alg_values",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				id_token_signing_alg_values = ["ES256", "RS256"]
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			out: &Config{
+				LogLevel:           defaultLogLevel,
+				Domains:            []string{"domain.test"},
+				InsecureAddr:       ":8080",
+				IDTokenSigningAlgs: []string{"ES256", "RS256"},
+				ServerAPI: &ServerAPIConfig{
+					Address:      "unix:///some/socket/path",
+					PollInterval: defaultPollInterval,
+				},
+			},
+		},
+		{
+			name: "invalid id_token_signing_alg_values",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				id_token_signing_alg_values = ["none"]
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: `invalid signing algorithm "none" in id_token_signing_alg_values`,
+		},
+		{
+			name: "invalid publish_key_use",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				publish_key_use = ["sign"]
+				server_api {
+					address = "unix:///some/socket/path"
+				}
+			`,
+			err: `invalid key use "sign" in publish_key_use: expected "sig" or "enc"`,
+		},
+		{
+			name: "no source section configured",
+			in: `
+				domains = ["domain.test"]
+				acme {
+					email = "admin@domain.test"
+					tos_accepted = true
+				}
+			`,
+			err: "either the server_api, workload_api or file section must be configured",
+		},
+		{
+			name: "more than one source section configured",
+			in: `
+				domains = ["domain.test"]
+				acme {
+					email = "admin@domain.test"
+					tos_accepted = true
+				}
+				server_api { address = "unix:///some/socket/path" }
+				workload_api { socket_path = "/some/socket/path" trust_domain="foo.test" }
+			`,
+			err: "the server_api, workload_api and file sections are mutually exclusive",
+		},
+		{
+			name: "file and another source section configured",
+			in: `
+				domains = ["domain.test"]
+				acme {
+					email = "admin@domain.test"
+					tos_accepted = true
+				}
+				server_api { address = "unix:///some/socket/path" }
+				file { path = "/some/jwks/path" }
+			`,
+			err: "the server_api, workload_api and file sections are mutually exclusive",
+		},
+		{
+			name: "minimal file config",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				file {
+					path = "/some/jwks/path"
+				}
+			`,
+			out: &Config{
+				LogLevel:     defaultLogLevel,
+				Domains:      []string{"domain.test"},
+				InsecureAddr: ":8080",
+				File: &FileConfig{
+					Path:         "/some/jwks/path",
+					PollInterval: defaultPollInterval,
+				},
+			},
+		},
+		{
+			name: "file config overrides",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				file {
+					path = "/some/jwks/path"
+					poll_interval = "1h"
+				}
+			`,
+			out: &Config{
+				LogLevel:     defaultLogLevel,
+				Domains:      []string{"domain.test"},
+				InsecureAddr: ":8080",
+				File: &FileConfig{
+					Path:            "/some/jwks/path",
+					PollInterval:    time.Hour,
+					RawPollInterval: "1h",
+				},
+			},
+		},
+		{
+			name: "file config missing path",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				file {}
+			`,
+			err: "path must be configured in the file configuration section",
+		},
+		{
+			name: "file config invalid poll interval",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				file {
+					path = "/some/jwks/path"
+					poll_interval = "huh"
+				}
+			`,
+			err: "invalid poll_interval in the file configuration section: time: invalid duration \"huh\"",
+		},
+		{
+			name: "minimal server API config",
+			in:   minimalServerAPIConfig,
+			out: &Config{
+				LogLevel: defaultLogLevel,
+				Domains:  []string{"domain.test"},
+				ACME: []*ACMEConfig{{
+					CacheDir:    defaultCacheDir,
+					Email:       "admin@domain.test",
+					ToSAccepted: true,
+				}},
+				ServerAPI: &ServerAPIConfig{
+					Address:      "unix:///some/socket/path",
+					PollInterval: defaultPollInterval,
+				},
+			},
+		},
+		{
+			name: "server API config overrides",
+			in: `
+				domains = ["domain.test"]
+				acme {
+					email = "admin@domain.test"
+					tos_accepted = true
+				}
+				server_api {
+					address = "unix:///other/socket/path"
+					poll_interval = "1h"
+				}
+			`,
+			out: &Config{
+				LogLevel: defaultLogLevel,
+				Domains:  []string{"domain.test"},
+				ACME: []*ACMEConfig{{
+					CacheDir:    defaultCacheDir,
+					Email:       "admin@domain.test",
+					ToSAccepted: true,
+				}},
+				ServerAPI: &ServerAPIConfig{
+					Address:         "unix:///other/socket/path",
+					PollInterval:    time.Hour,
+					RawPollInterval: "1h",
+				},
+			},
+		},
+		{
+			name: "server API config missing address",
+			in: `
+				domains = ["domain.test"]
+				acme {
+					email = "admin@domain.test"
+					tos_accepted = true
+				}
+				server_api {
+				}
+			`,
+			err: "address must be configured in the server_api configuration section",
+		},
+		{
+			name: "server API config invalid address",
+			in: `
+				domains = ["domain.test"]
+				acme {
+					email = "admin@domain.test"
+					tos_accepted = true
+				}
+				server_api {
+					address = "localhost:8199"
+				}
+			`,
+			err: "address must use the unix or tcp name system in the server_api configuration section",
+		},
+		{
+			name: "server API config with tcp address and tls",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				server_api {
+					address = "tcp://spire-server:8081"
+					tls {
+						server_id = "spiffe://domain.test/spire/server"
+						workload_api_socket_path = "/some/socket/path"
+						bundle_path = "/some/bundle.json"
+					}
+				}
+			`,
+			out: &Config{
+				LogLevel:     defaultLogLevel,
+				Domains:      []string{"domain.test"},
+				InsecureAddr: ":8080",
+				ServerAPI: &ServerAPIConfig{
+					Address:      "tcp://spire-server:8081",
+					PollInterval: defaultPollInterval,
+					TLS: &ServerAPITLSConfig{
+						ServerID:              spiffeid.RequireFromString("spiffe://domain.test/spire/server"),
+						RawServerID:           "spiffe://domain.test/spire/server",
+						WorkloadAPISocketPath: "/some/socket/path",
+						BundlePath:            "/some/bundle.json",
+					},
+				},
+			},
+		},
+		{
+			name: "server API config with tcp address requires tls",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				server_api {
+					address = "tcp://spire-server:8081"
+				}
+			`,
+			err: "tls must be configured with a tcp address in the server_api configuration section",
+		},
+		{
+			name: "server API config with tcp address without port",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				server_api {
+					address = "tcp://spire-server"
+					tls {
+						server_id = "spiffe://domain.test/spire/server"
+						workload_api_socket_path = "/some/socket/path"
+					}
+				}
+			`,
+			err: "invalid tcp address in the server_api configuration section: address spire-server: missing port in address",
+		},
+		{
+			name: "server API config with unix address and tls",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				server_api {
+					address = "unix:///some/socket/path"
+					tls {
+						server_id = "spiffe://domain.test/spire/server"
+						workload_api_socket_path = "/some/socket/path"
+					}
+				}
+			`,
+			err: "tls cannot be configured with a unix address in the server_api configuration section",
+		},
+		{
+			name: "server API config tls missing server_id",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				server_api {
+					address = "tcp://spire-server:8081"
+					tls {
+						workload_api_socket_path = "/some/socket/path"
+					}
+				}
+			`,
+			err: "server_id must be configured in the server_api tls configuration section",
+		},
+		{
+			name: "server API config tls invalid server_id",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				server_api {
+					address = "tcp://spire-server:8081"
+					tls {
+						server_id = "domain.test/spire/server"
+						workload_api_socket_path = "/some/socket/path"
+					}
+				}
+			`,
+			err: "invalid server_id in the server_api tls configuration section: scheme is missing or invalid",
+		},
+		{
+			name: "server API config tls missing workload_api_socket_path",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				server_api {
+					address = "tcp://spire-server:8081"
+					tls {
+						server_id = "spiffe://domain.test/spire/server"
+					}
+				}
+			`,
+			err: "workload_api_socket_path must be configured in the server_api tls configuration section",
+		},
+		{
+			name: "server API config invalid poll interval",
+			in: `
+				domains = ["domain.test"]
+				acme {
+					email = "admin@domain.test"
+					tos_accepted = true
+				}
+				server_api {
+					address = "unix:///some/socket/path"
+					poll_interval = "huh"
+				}
+			`,
+			err: "invalid poll_interval in the server_api configuration section: time: invalid duration \"huh\"",
+		},
+		{
+			name: "server API config with backoff",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				server_api {
+					address = "unix:///some/socket/path"
+					backoff_base = "500ms"
+					backoff_max = "5s"
+				}
+			`,
+			out: &Config{
+				LogLevel:     defaultLogLevel,
+				Domains:      []string{"domain.test"},
+				InsecureAddr: ":8080",
+				ServerAPI: &ServerAPIConfig{
+					Address:        "unix:///some/socket/path",
+					PollInterval:   defaultPollInterval,
+					BackoffBase:    500 * time.Millisecond,
+					RawBackoffBase: "500ms",
+					BackoffMax:     5 * time.Second,
+					RawBackoffMax:  "5s",
+				},
+			},
+		},
+		{
+			name: "server API config with connection tuning",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				server_api {
+					address = "unix:///some/socket/path"
+					dial_timeout = "5s"
+					keepalive_time = "30s"
+					keepalive_timeout = "10s"
+				}
+			`,
+			out: &Config{
+				LogLevel:     defaultLogLevel,
+				Domains:      []string{"domain.test"},
+				InsecureAddr: ":8080",
+				ServerAPI: &ServerAPIConfig{
+					Address:             "unix:///some/socket/path",
+					PollInterval:        defaultPollInterval,
+					DialTimeout:         5 * time.Second,
+					RawDialTimeout:      "5s",
+					KeepaliveTime:       30 * time.Second,
+					RawKeepaliveTime:    "30s",
+					KeepaliveTimeout:    10 * time.Second,
+					RawKeepaliveTimeout: "10s",
+				},
+			},
+		},
+		{
+			name: "server API config invalid dial timeout",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				server_api {
+					address = "unix:///some/socket/path"
+					dial_timeout = "huh"
+				}
+			`,
+			err: "invalid dial_timeout in the server_api configuration section: time: invalid duration \"huh\"",
+		},
+		{
+			name: "server API config invalid keepalive time",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				server_api {
+					address = "unix:///some/socket/path"
+					keepalive_time = "huh"
+				}
+			`,
+			err: "invalid keepalive_time in the server_api configuration section: time: invalid duration \"huh\"",
+		},
+		{
+			name: "server API config non-positive keepalive timeout",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				server_api {
+					address = "unix:///some/socket/path"
+					keepalive_time = "30s"
+					keepalive_timeout = "0s"
+				}
+			`,
+			err: "invalid keepalive_timeout in the server_api configuration section: must be positive",
+		},
+		{
+			name: "server API config keepalive timeout without keepalive time",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				server_api {
+					address = "unix:///some/socket/path"
+					keepalive_timeout = "10s"
+				}
+			`,
+			err: "keepalive_timeout requires keepalive_time in the server_api configuration section",
+		},
+		{
+			name: "server API config with max_jwks_size",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				server_api {
+					address = "unix:///some/socket/path"
+					max_jwks_size = 1048576
+				}
+			`,
+			out: &Config{
+				LogLevel:     defaultLogLevel,
+				Domains:      []string{"domain.test"},
+				InsecureAddr: ":8080",
+				ServerAPI: &ServerAPIConfig{
+					Address:      "unix:///some/socket/path",
+					PollInterval: defaultPollInterval,
+					MaxJWKSSize:  1048576,
+				},
+			},
+		},
+		{
+			name: "server API config negative max_jwks_size",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				server_api {
+					address = "unix:///some/socket/path"
+					max_jwks_size = -1
+				}
+			`,
+			err: "max_jwks_size must not be negative in the server_api configuration section",
+		},
+		{
+			name: "server API config invalid backoff base",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				server_api {
+					address = "unix:///some/socket/path"
+					backoff_base = "huh"
+				}
+			`,
+			err: "invalid backoff in the server_api configuration section: invalid backoff_base: time: invalid duration \"huh\"",
+		},
+		{
+			name: "workload API config backoff base greater than max",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				workload_api {
+					socket_path = "/other/socket/path"
+					trust_domain = "domain.test"
+					backoff_base = "10s"
+					backoff_max = "5s"
+				}
+			`,
+			err: "invalid backoff in the workload_api configuration section: backoff_base must not be greater than backoff_max",
+		},
+		{
+			name: "workload API config non-positive backoff max",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				workload_api {
+					socket_path = "/other/socket/path"
+					trust_domain = "domain.test"
+					backoff_max = "0s"
+				}
+			`,
+			err: "invalid backoff in the workload_api configuration section: backoff_max must be positive",
+		},
+		{
+			name: "minimal workload API config",
+			in: `
+				domains = ["domain.test"]
+				acme {
+					email = "admin@domain.test"
+					tos_accepted = true
+				}
+				workload_api {
+					socket_path = "/some/socket/path"
+					trust_domain = "domain.test"
+				}
+			`,
+			out: &Config{
+				LogLevel: defaultLogLevel,
+				Domains:  []string{"domain.test"},
+				ACME: []*ACMEConfig{{
+					CacheDir:    defaultCacheDir,
+					Email:       "admin@domain.test",
+					ToSAccepted: true,
+				}},
+				WorkloadAPI: &WorkloadAPIConfig{
+					SocketPath:   "/some/socket/path",
+					PollInterval: defaultPollInterval,
+					TrustDomain:  "domain.test",
+					FetchX509:    false,
+				},
+			},
+		},
+		{
+			name: "workload API config with fetch_x509",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				workload_api {
+					socket_path = "/some/socket/path"
+					trust_domain = "domain.test"
+					fetch_x509 = true
+				}
+			`,
+			out: &Config{
+				LogLevel:     defaultLogLevel,
+				Domains:      []string{"domain.test"},
+				InsecureAddr: ":8080",
+				WorkloadAPI: &WorkloadAPIConfig{
+					SocketPath:   "/some/socket/path",
+					PollInterval: defaultPollInterval,
+					TrustDomain:  "domain.test",
+					FetchX509:    true,
+				},
+			},
+		},
+		{
+			name: "workload API config with max_jwks_size",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				workload_api {
+					socket_path = "/some/socket/path"
+					trust_domain = "domain.test"
+					max_jwks_size = 1048576
+				}
+			`,
+			out: &Config{
+				LogLevel:     defaultLogLevel,
+				Domains:      []string{"domain.test"},
+				InsecureAddr: ":8080",
+				WorkloadAPI: &WorkloadAPIConfig{
+					SocketPath:   "/some/socket/path",
+					PollInterval: defaultPollInterval,
+					TrustDomain:  "domain.test",
+					MaxJWKSSize:  1048576,
+				},
+			},
+		},
+		{
+			name: "workload API config negative max_jwks_size",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				workload_api {
+					socket_path = "/some/socket/path"
+					trust_domain = "domain.test"
+					max_jwks_size = -1
+				}
+			`,
+			err: "max_jwks_size must not be negative in the workload_api configuration section",
+		},
+		{
+			name: "workload API config overrides",
+			in: `
+				domains = ["domain.test"]
+				acme {
+					email = "admin@domain.test"
+					tos_accepted = true
+				}
+				workload_api {
+					socket_path = "/other/socket/path"
+					poll_interval = "1h"
+					trust_domain = "foo.test"
+				}
+			`,
+			out: &Config{
+				LogLevel: defaultLogLevel,
+				Domains:  []string{"domain.test"},
+				ACME: []*ACMEConfig{{
+					CacheDir:    defaultCacheDir,
+					Email:       "admin@domain.test",
+					ToSAccepted: true,
+				}},
+				WorkloadAPI: &WorkloadAPIConfig{
+					SocketPath:      "/other/socket/path",
+					PollInterval:    time.Hour,
+					RawPollInterval: "1h",
+					TrustDomain:     "foo.test",
+				},
+			},
+		},
+		{
+			name: "workload API config missing socket path",
+			in: `
+				domains = ["domain.test"]
+				acme {
+					email = "admin@domain.test"
+					tos_accepted = true
+				}
+				workload_api {
+					trust_domain = "domain.test"
+				}
+			`,
+			err: "socket_path must be configured in the workload_api configuration section",
+		},
+		{
+			name: "workload API config invalid poll interval",
+			in: `
+				domains = ["domain.test"]
+				acme {
+					email = "admin@domain.test"
+					tos_accepted = true
+				}
+				workload_api {
+					socket_path = "/some/socket/path"
+					poll_interval = "huh"
+					trust_domain = "domain.test"
+				}
+			`,
+			err: "invalid poll_interval in the workload_api configuration section: time: invalid duration \"huh\"",
+		},
+		{
+			name: "workload API config missing trust domain",
+			in: `
+				domains = ["domain.test"]
+				acme {
+					email = "admin@domain.test"
+					tos_accepted = true
+				}
+				workload_api {
+					socket_path = "/some/socket/path"
+				}
+			`,
+			err: "trust_domain or trust_domains must be configured in the workload_api configuration section",
+		},
+		{
+			name: "workload API config with trust domains",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				workload_api {
+					socket_path = "/some/socket/path"
+					trust_domains = ["foo.test", "bar.test", "foo.test"]
+				}
+			`,
+			out: &Config{
+				LogLevel:     defaultLogLevel,
+				Domains:      []string{"domain.test"},
+				InsecureAddr: ":8080",
+				WorkloadAPI: &WorkloadAPIConfig{
+					SocketPath:   "/some/socket/path",
+					PollInterval: defaultPollInterval,
+					TrustDomains: []string{"foo.test", "bar.test"},
+				},
+			},
+		},
+		{
+			name: "workload API config with both trust domain and trust domains",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
+				workload_api {
+					socket_path = "/some/socket/path"
+					trust_domain = "foo.test"
+					trust_domains = ["bar.test"]
+				}
+			`,
+			err: "trust_domain and trust_domains are mutually exclusive in the workload_api configuration section",
+		},
+		{
+			name: "workload API config invalid trust domain",
+			in: `
+				domains = ["domain.test"]
+				insecure_addr = ":8080"
 				workload_api {
 					socket_path = "/some/socket/path"
+					trust_domains = ["foo.test", "Bar.test"]
 				}
 			`,
-			err: "trust_domain must be configured in the workload_api configuration section",
+			err: `invalid trust domain "Bar.test" in the workload_api configuration section`,
 		},
 	}
 
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/zeebo/errs"
+	"google.golang.org/api/dns/v1"
+	"google.golang.org/api/option"
+)
+
+const (
+	// dnsChallengeRecordTTL is the TTL of the challenge TXT records.
+	dnsChallengeRecordTTL = 60
+
+	// cloudDNSChangePollInterval is how often Cloud DNS is polled for the
+	// completion of a change.
+	cloudDNSChangePollInterval = 2 * time.Second
+)
+
+// dnsProvider publishes the TXT records used to answer dns-01 challenges.
+// Present returns once the record has been applied by the provider.
+type dnsProvider interface {
+	Present(ctx context.Context, fqdn, value string) error
+	CleanUp(ctx context.Context, fqdn, value string) error
+}
+
+func newDNSProvider(ctx context.Context, acmeConfig *ACMEConfig) (dnsProvider, error) {
+	switch {
+	case acmeConfig.Route53 != nil:
+		return newRoute53Provider(acmeConfig.Route53)
+	case acmeConfig.CloudDNS != nil:
+		return newCloudDNSProvider(ctx, acmeConfig.CloudDNS)
+	default:
+		// This is defensive; LoadConfig should prevent this from happening.
+		return nil, errs.New("no DNS provider has been configured")
+	}
+}
+
+// route53Client is the subset of the Route 53 API used by the provider.
+type route53Client interface {
+	ChangeResourceRecordSetsWithContext(ctx aws.Context, input *route53.ChangeResourceRecordSetsInput, opts ...request.Option) (*route53.ChangeResourceRecordSetsOutput, error)
+	WaitUntilResourceRecordSetsChangedWithContext(ctx aws.Context, input *route53.GetChangeInput, opts ...request.WaiterOption) error
+}
+
+type route53Provider struct {
+	client       route53Client
+	hostedZoneID string
+}
+
+func newRoute53Provider(config *Route53Config) (*route53Provider, error) {
+	// Route 53 is a global service, but the SDK requires a region
+	awsConfig := &aws.Config{Region: aws.String("us-east-1")}
+	if config.AccessKeyID != "" {
+		awsConfig.Credentials = credentials.NewStaticCredentials(config.AccessKeyID, config.SecretAccessKey, "")
+	}
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, errs.New("failed to create Route 53 session: %v", err)
+	}
+	return &route53Provider{
+		client:       route53.New(sess),
+		hostedZoneID: config.HostedZoneID,
+	}, nil
+}
+
+func (p *route53Provider) Present(ctx context.Context, fqdn, value string) error {
+	return p.change(ctx, route53.ChangeActionUpsert, fqdn, value)
+}
+
+func (p *route53Provider) CleanUp(ctx context.Context, fqdn, value string) error {
+	return p.change(ctx, route53.ChangeActionDelete, fqdn, value)
+}
+
+func (p *route53Provider) change(ctx context.Context, action, fqdn, value string) error {
+	resp, err := p.client.ChangeResourceRecordSetsWithContext(ctx, &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(p.hostedZoneID),
+		ChangeBatch: &route53.ChangeBatch{
+			Changes: []*route53.Change{
+				{
+					Action: aws.String(action),
+					ResourceRecordSet: &route53.ResourceRecordSet{
+						Name: aws.String(fqdn),
+						Type: aws.String(route53.RRTypeTxt),
+						TTL:  aws.Int64(dnsChallengeRecordTTL),
+						ResourceRecords: []*route53.ResourceRecord{
+							{Value: aws.String(strconv.Quote(value))},
+						},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return errs.New("failed to change Route 53 record %q: %v", fqdn, err)
+	}
+	if err := p.client.WaitUntilResourceRecordSetsChangedWithContext(ctx, &route53.GetChangeInput{Id: resp.ChangeInfo.Id}); err != nil {
+		return errs.New("failed to wait for Route 53 change of record %q: %v", fqdn, err)
+	}
+	return nil
+}
+
+type cloudDNSProvider struct {
+	changes     *dns.ChangesService
+	projectID   string
+	managedZone string
+}
+
+func newCloudDNSProvider(ctx context.Context, config *CloudDNSConfig) (*cloudDNSProvider, error) {
+	var opts []option.ClientOption
+	if config.ServiceAccountFile != "" {
+		opts = append(opts, option.WithCredentialsFile(config.ServiceAccountFile))
+	}
+	service, err := dns.NewService(ctx, opts...)
+	if err != nil {
+		return nil, errs.New("failed to create Cloud DNS client: %v", err)
+	}
+	return &cloudDNSProvider{
+		changes:     service.Changes,
+		projectID:   config.ProjectID,
+		managedZone: config.ManagedZone,
+	}, nil
+}
+
+func (p *cloudDNSProvider) Present(ctx context.Context, fqdn, value string) error {
+	return p.change(ctx, &dns.Change{Additions: []*dns.ResourceRecordSet{cloudDNSRecord(fqdn, value)}})
+}
+
+func (p *cloudDNSProvider) CleanUp(ctx context.Context, fqdn, value string) error {
+	return p.change(ctx, &dns.Change{Deletions: []*dns.ResourceRecordSet{cloudDNSRecord(fqdn, value)}})
+}
+
+func (p *cloudDNSProvider) change(ctx context.Context, change *dns.Change) error {
+	change, err := p.changes.Create(p.projectID, p.managedZone, change).Context(ctx).Do()
+	if err != nil {
+		return errs.New("failed to change Cloud DNS record: %v", err)
+	}
+	for change.Status != "done" {
+		select {
+		case <-time.After(cloudDNSChangePollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		change, err = p.changes.Get(p.projectID, p.managedZone, change.Id).Context(ctx).Do()
+		if err != nil {
+			return errs.New("failed to get Cloud DNS change: %v", err)
+		}
+	}
+	return nil
+}
+
+func cloudDNSRecord(fqdn, value string) *dns.ResourceRecordSet {
+	return &dns.ResourceRecordSet{
+		Name:    fqdn,
+		Type:    "TXT",
+		Ttl:     dnsChallengeRecordTTL,
+		Rrdatas: []string{strconv.Quote(value)},
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"sync"
 
 	"golang.org/x/net/idna"
 )
@@ -38,6 +39,33 @@ func AllowAnyDomain() DomainPolicy {
 	}
 }
 
+// DynamicDomainPolicy is a policy that delegates to a policy that can be
+// replaced at runtime, e.g. when the configuration is reloaded.
+type DynamicDomainPolicy struct {
+	mu     sync.RWMutex
+	policy DomainPolicy
+}
+
+func NewDynamicDomainPolicy(policy DomainPolicy) *DynamicDomainPolicy {
+	return &DynamicDomainPolicy{policy: policy}
+}
+
+// Set replaces the policy that is delegated to
+func (p *DynamicDomainPolicy) Set(policy DomainPolicy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.policy = policy
+}
+
+// Check checks the domain against the current policy. It satisfies the
+// DomainPolicy signature.
+func (p *DynamicDomainPolicy) Check(domain string) error {
+	p.mu.RLock()
+	policy := p.policy
+	p.mu.RUnlock()
+	return policy(domain)
+}
+
 func toDomainKey(domain string) (string, error) {
 	punycode, err := idna.Lookup.ToASCII(domain)
 	if err != nil {
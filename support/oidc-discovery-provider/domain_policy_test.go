@@ -47,3 +47,13 @@ func TestAllowAnyDomain(t *testing.T) {
 	assert.NoError(t, policy("baz"))
 	assert.EqualError(t, policy("invalid/domain.test"), `domain "invalid/domain.test" is not a valid domain name: idna: disallowed rune U+002F`)
 }
+
+func TestDynamicDomainPolicy(t *testing.T) {
+	policy := NewDynamicDomainPolicy(domainAllowlist(t, "foo"))
+	assert.NoError(t, policy.Check("foo"))
+	assert.EqualError(t, policy.Check("bar"), `domain "bar" is not allowed`)
+
+	policy.Set(domainAllowlist(t, "bar"))
+	assert.EqualError(t, policy.Check("foo"), `domain "foo" is not allowed`)
+	assert.NoError(t, policy.Check("bar"))
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/andres-erbsen/clock"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/spire/pkg/common/telemetry"
+	"gopkg.in/square/go-jose.v2"
+)
+
+const (
+	DefaultFilePollInterval = time.Second * 10
+)
+
+type FileSourceConfig struct {
+	Log          logrus.FieldLogger
+	Path         string
+	PollInterval time.Duration
+	Clock        clock.Clock
+	Metrics      telemetry.Metrics
+}
+
+// FileSource is a source that reads the JWKS from a file on disk. The file is
+// periodically re-read and the key set updated when the contents change.
+type FileSource struct {
+	log     logrus.FieldLogger
+	clock   clock.Clock
+	metrics sourceMetrics
+	path    string
+	cancel  context.CancelFunc
+
+	mu      sync.RWMutex
+	wg      sync.WaitGroup
+	rawJWKS []byte
+	jwks    *jose.JSONWebKeySet
+	modTime time.Time
+
+	lastSuccessfulPoll time.Time
+	pollInterval       time.Duration
+}
+
+func NewFileSource(config FileSourceConfig) *FileSource {
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultFilePollInterval
+	}
+	if config.Clock == nil {
+		config.Clock = clock.New()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &FileSource{
+		log:          config.Log,
+		clock:        config.Clock,
+		metrics:      newSourceMetrics(config.Metrics, "file"),
+		path:         config.Path,
+		cancel:       cancel,
+		pollInterval: config.PollInterval,
+	}
+
+	s.wg.Add(1)
+	go s.pollEvery(ctx)
+	return s
+}
+
+func (s *FileSource) Close() error {
+	s.cancel()
+	s.wg.Wait()
+	return nil
+}
+
+func (s *FileSource) FetchKeySet() (*jose.JSONWebKeySet, time.Time, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.jwks == nil {
+		return nil, time.Time{}, false
+	}
+	return s.jwks, s.modTime, true
+}
+
+// SetPollInterval changes the poll interval. It takes effect after the
+// next poll.
+func (s *FileSource) SetPollInterval(pollInterval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pollInterval = pollInterval
+}
+
+func (s *FileSource) getPollInterval() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pollInterval
+}
+
+func (s *FileSource) LastSuccessfulPoll() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastSuccessfulPoll
+}
+
+func (s *FileSource) pollEvery(ctx context.Context) {
+	defer s.wg.Done()
+
+	s.log.WithField("interval", s.getPollInterval()).Debug("Polling started")
+	for {
+		s.pollOnce()
+		select {
+		case <-ctx.Done():
+			s.log.WithError(ctx.Err()).Debug("Polling done")
+			return
+		case <-s.clock.After(s.getPollInterval()):
+		}
+	}
+}
+
+func (s *FileSource) pollOnce() {
+	start := time.Now()
+	rawJWKS, err := os.ReadFile(s.path)
+	s.metrics.MeasurePoll(start)
+	if err != nil {
+		s.metrics.IncrPollFailure()
+		s.log.WithError(err).Warn("Failed to read JWKS file")
+		return
+	}
+
+	if !s.setJWKS(rawJWKS) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastSuccessfulPoll = s.clock.Now()
+}
+
+func (s *FileSource) setJWKS(rawJWKS []byte) bool {
+	// If the file hasn't changed, don't bother continuing
+	s.mu.RLock()
+	unchanged := s.rawJWKS != nil && bytes.Equal(s.rawJWKS, rawJWKS)
+	s.mu.RUnlock()
+	if unchanged {
+		return true
+	}
+
+	jwks := new(jose.JSONWebKeySet)
+	if err := json.Unmarshal(rawJWKS, jwks); err != nil {
+		s.metrics.IncrPollFailure()
+		s.log.WithError(err).Warn("Failed to parse JWKS file")
+		return false
+	}
+
+	// The key set is published as is, so private keys must never make it
+	// into the served key set.
+	for _, key := range jwks.Keys {
+		if !key.IsPublic() {
+			s.metrics.IncrPollFailure()
+			s.log.WithField("kid", key.KeyID).Warn("JWKS file contains a private key; ignoring the file")
+			return false
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	logKeySetChanges(s.log, s.jwks, jwks)
+	s.rawJWKS = rawJWKS
+	s.jwks = jwks
+	s.modTime = s.clock.Now()
+	s.metrics.SetKeyCount(len(jwks.Keys))
+	return true
+}
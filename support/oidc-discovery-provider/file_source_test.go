@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiffe/spire/test/clock"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/spiffe/spire/test/testkey"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func TestFileSource(t *testing.T) {
+	const pollInterval = time.Second
+
+	path := filepath.Join(spiretest.TempDir(t), "jwks.json")
+
+	log, hook := test.NewNullLogger()
+	clock := clock.NewMock(t)
+
+	source := NewFileSource(FileSourceConfig{
+		Log:          log,
+		Path:         path,
+		PollInterval: pollInterval,
+		Clock:        clock,
+	})
+	defer source.Close()
+
+	// Wait for the poll to happen and assert there is no key set available
+	clock.WaitForAfter(time.Minute, "failed to wait for the poll timer")
+	_, _, ok := source.FetchKeySet()
+	require.False(t, ok, "No file was available but we have a keyset somehow")
+	require.True(t, source.LastSuccessfulPoll().IsZero(), "No file was available but the poll was successful somehow")
+
+	// Write a malformed file, step forward past the poll interval, wait for
+	// polling, and assert there is still no key set available.
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0600))
+	clock.Add(pollInterval)
+	clock.WaitForAfter(time.Minute, "failed to wait for the poll timer")
+	_, _, ok = source.FetchKeySet()
+	require.False(t, ok, "The file was malformed but we have a keyset somehow")
+
+	// Write the key set, step forward past the poll interval, wait for
+	// polling, and assert we have a keyset.
+	require.NoError(t, os.WriteFile(path, makeJWKS(t, &jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{
+			{
+				KeyID: "KID",
+				Key:   ec256Pubkey,
+			},
+		},
+	}), 0600))
+	clock.Add(pollInterval)
+	clock.WaitForAfter(time.Minute, "failed to wait for the poll timer")
+	keySet1, modTime1, ok := source.FetchKeySet()
+	require.True(t, ok)
+	require.Equal(t, clock.Now(), modTime1)
+	require.Equal(t, clock.Now(), source.LastSuccessfulPoll())
+	require.NotNil(t, keySet1)
+	require.Len(t, keySet1.Keys, 1)
+	require.Equal(t, "KID", keySet1.Keys[0].KeyID)
+	require.Equal(t, ec256Pubkey, keySet1.Keys[0].Key)
+
+	// Wait another poll interval, ensure the file was re-read and that the
+	// source reports no changes since nothing changed.
+	clock.Add(pollInterval)
+	clock.WaitForAfter(time.Minute, "failed to wait for the poll timer")
+	keySet2, modTime2, ok := source.FetchKeySet()
+	require.True(t, ok)
+	require.Equal(t, keySet1, keySet2)
+	require.Equal(t, modTime1, modTime2)
+	require.Equal(t, clock.Now(), source.LastSuccessfulPoll())
+
+	// Change the file, step forward past the poll interval, wait for polling,
+	// and assert that the changes have been picked up.
+	require.NoError(t, os.WriteFile(path, makeJWKS(t, &jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{
+			{
+				KeyID: "KID2",
+				Key:   ec256Pubkey,
+			},
+		},
+	}), 0600))
+	clock.Add(pollInterval)
+	clock.WaitForAfter(time.Minute, "failed to wait for the poll timer")
+	keySet3, modTime3, ok := source.FetchKeySet()
+	require.True(t, ok)
+	require.Equal(t, clock.Now(), modTime3)
+	require.NotNil(t, keySet3)
+	require.Len(t, keySet3.Keys, 1)
+	require.Equal(t, "KID2", keySet3.Keys[0].KeyID)
+	require.Equal(t, ec256Pubkey, keySet3.Keys[0].Key)
+
+	// Write key sets with private keys, step forward past the poll interval,
+	// wait for polling, and assert that the files were rejected and that no
+	// private key material is served.
+	for _, privateKey := range []interface{}{testkey.NewEC256(t), testkey.NewRSA2048(t)} {
+		hook.Reset()
+		require.NoError(t, os.WriteFile(path, makeJWKS(t, &jose.JSONWebKeySet{
+			Keys: []jose.JSONWebKey{
+				{
+					KeyID: "KID3",
+					Key:   ec256Pubkey,
+				},
+				{
+					KeyID: "PRIVATE",
+					Key:   privateKey,
+				},
+			},
+		}), 0600))
+		clock.Add(pollInterval)
+		clock.WaitForAfter(time.Minute, "failed to wait for the poll timer")
+		keySet4, modTime4, ok := source.FetchKeySet()
+		require.True(t, ok)
+		require.Equal(t, keySet3, keySet4)
+		require.Equal(t, modTime3, modTime4)
+		require.Equal(t, "JWKS file contains a private key; ignoring the file", hook.LastEntry().Message)
+
+		rawJWKS, err := json.Marshal(keySet4)
+		require.NoError(t, err)
+		var served struct {
+			Keys []map[string]interface{} `json:"keys"`
+		}
+		require.NoError(t, json.Unmarshal(rawJWKS, &served))
+		for _, key := range served.Keys {
+			for _, param := range []string{"d", "p", "q", "dp", "dq", "qi"} {
+				require.NotContains(t, key, param)
+			}
+		}
+	}
+}
@@ -2,43 +2,177 @@ package main
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"net"
 	"net/http"
 	"net/url"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/handlers"
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/telemetry"
+	"gopkg.in/square/go-jose.v2"
 )
 
 const (
 	keyUse = "sig"
+
+	corsAllowAnyOrigin = "*"
 )
 
+type HandlerConfig struct {
+	DomainPolicy        DomainPolicy
+	Source              JWKSSource
+	Issuer              *url.URL
+	AllowInsecureScheme bool
+	SetKeyUse           bool
+	PublishKeyUse       []string
+	JWKSCacheMaxAge     time.Duration
+	AllowedOrigins      []string
+	DiscoveryDocument   map[string]interface{}
+	ProtectedResource   *ProtectedResourceConfig
+	Metrics             telemetry.Metrics
+
+	// X509Source, if set, provides the X.509 authorities served on the
+	// /roots.pem endpoint. The endpoint is not served otherwise.
+	X509Source X509AuthoritiesSource
+
+	// IDTokenSigningAlgs, if set, overrides the signing algorithms advertised
+	// in the discovery document, which are otherwise derived from the keys
+	// in the JWKS.
+	IDTokenSigningAlgs []string
+
+	// PathPrefix, if set, prefixes the paths of all the served endpoints.
+	// It must begin with "/" and have no trailing slash.
+	PathPrefix string
+}
+
 type Handler struct {
 	source              JWKSSource
 	domainPolicy        DomainPolicy
+	issuer              *url.URL
 	allowInsecureScheme bool
 	setKeyUse           bool
+	publishKeyUse       map[string]bool
+	jwksCacheMaxAge     time.Duration
+	corsAllowAny        bool
+	corsAllowedOrigins  map[string]bool
+	extraClaims         map[string]interface{}
+	protectedResource   *ProtectedResourceConfig
+	x509Source          X509AuthoritiesSource
+	metrics             telemetry.Metrics
+	idTokenSigningAlgs  []string
+	pathPrefix          string
+
+	// jwks holds the *cachedJWKS for the key set last returned by the
+	// source. It is swapped atomically when the source reports a change so
+	// that requests can read it without locking.
+	jwks atomic.Value
 
 	http.Handler
 }
 
-func NewHandler(domainPolicy DomainPolicy, source JWKSSource, allowInsecureScheme bool, setKeyUse bool) *Handler {
+// cachedJWKS is the marshaled key set served on the /keys endpoint, along
+// with the source key set it was built from and the signing algorithms of
+// its keys.
+type cachedJWKS struct {
+	source      *jose.JSONWebKeySet
+	modTime     time.Time
+	body        []byte
+	etag        string
+	signingAlgs []string
+}
+
+func NewHandler(config HandlerConfig) *Handler {
+	if config.Metrics == nil {
+		config.Metrics = telemetry.Blackhole{}
+	}
+
 	h := &Handler{
-		domainPolicy:        domainPolicy,
-		source:              source,
-		allowInsecureScheme: allowInsecureScheme,
-		setKeyUse:           setKeyUse,
+		domainPolicy:        config.DomainPolicy,
+		source:              config.Source,
+		issuer:              config.Issuer,
+		allowInsecureScheme: config.AllowInsecureScheme,
+		setKeyUse:           config.SetKeyUse,
+		jwksCacheMaxAge:     config.JWKSCacheMaxAge,
+		corsAllowedOrigins:  make(map[string]bool, len(config.AllowedOrigins)),
+		extraClaims:         config.DiscoveryDocument,
+		protectedResource:   config.ProtectedResource,
+		x509Source:          config.X509Source,
+		metrics:             config.Metrics,
+		idTokenSigningAlgs:  config.IDTokenSigningAlgs,
+		pathPrefix:          config.PathPrefix,
+	}
+	if len(config.PublishKeyUse) > 0 {
+		h.publishKeyUse = make(map[string]bool, len(config.PublishKeyUse))
+		for _, use := range config.PublishKeyUse {
+			h.publishKeyUse[use] = true
+		}
+	}
+	for _, origin := range config.AllowedOrigins {
+		if origin == corsAllowAnyOrigin {
+			h.corsAllowAny = true
+		}
+		h.corsAllowedOrigins[origin] = true
 	}
 
 	mux := http.NewServeMux()
-	mux.Handle("/.well-known/openid-configuration", handlers.ProxyHeaders(http.HandlerFunc(h.serveWellKnown)))
-	mux.Handle("/keys", http.HandlerFunc(h.serveKeys))
+	mux.Handle(h.pathPrefix+"/.well-known/openid-configuration", handlers.ProxyHeaders(http.HandlerFunc(h.serveWellKnown)))
+	if h.protectedResource != nil {
+		mux.Handle(h.pathPrefix+"/.well-known/oauth-protected-resource", handlers.ProxyHeaders(http.HandlerFunc(h.serveProtectedResource)))
+	}
+	mux.Handle(h.pathPrefix+"/keys", countRequests(h.metrics, jwksRequestKey, http.HandlerFunc(h.serveKeys)))
+	if h.x509Source != nil {
+		mux.HandleFunc(h.pathPrefix+"/roots.pem", h.serveRoots)
+	}
 
 	h.Handler = mux
+	if len(config.AllowedOrigins) > 0 {
+		h.Handler = h.serveCORS(mux)
+	}
 	return h
 }
 
+// serveCORS adds the CORS headers to responses for requests coming from an
+// allowed origin, and answers preflight requests from those origins.
+func (h *Handler) serveCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.corsAllowAny {
+			// The response depends on the origin since it is echoed back
+			w.Header().Add("Vary", "Origin")
+		}
+
+		origin := r.Header.Get("Origin")
+		if origin == "" || !(h.corsAllowAny || h.corsAllowedOrigins[origin]) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowOrigin := origin
+		if h.corsAllowAny {
+			allowOrigin = corsAllowAnyOrigin
+		}
+		w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
+		w.Header().Set("Access-Control-Allow-Methods", "GET")
+
+		if r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (h *Handler) serveWellKnown(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -50,21 +184,8 @@ func (h *Handler) serveWellKnown(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	urlScheme := "https"
-	if h.allowInsecureScheme && r.TLS == nil && r.URL.Scheme != "https" {
-		urlScheme = "http"
-	}
-
-	issuerURL := url.URL{
-		Scheme: urlScheme,
-		Host:   r.Host,
-	}
-
-	jwksURI := url.URL{
-		Scheme: urlScheme,
-		Host:   r.Host,
-		Path:   "/keys",
-	}
+	issuerURL := h.issuerURL(r)
+	jwksURI := h.jwksURI(issuerURL)
 
 	doc := struct {
 		Issuer  string `json:"issuer"`
@@ -75,7 +196,7 @@ func (h *Handler) serveWellKnown(w http.ResponseWriter, r *http.Request) {
 		AuthorizationEndpoint            string   `json:"authorization_endpoint"`
 		ResponseTypesSupported           []string `json:"response_types_supported"`
 		SubjectTypesSupported            []string `json:"subject_types_supported"`
-		IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+		IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported,omitempty"`
 	}{
 		Issuer:  issuerURL.String(),
 		JWKSURI: jwksURI.String(),
@@ -83,7 +204,68 @@ func (h *Handler) serveWellKnown(w http.ResponseWriter, r *http.Request) {
 		AuthorizationEndpoint:            "",
 		ResponseTypesSupported:           []string{"id_token"},
 		SubjectTypesSupported:            []string{},
-		IDTokenSigningAlgValuesSupported: []string{"RS256", "ES256", "ES384"},
+		IDTokenSigningAlgValuesSupported: h.signingAlgs(),
+	}
+
+	docBytes, err := h.marshalDiscoveryDocument(doc)
+	if err != nil {
+		http.Error(w, "failed to marshal document", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(docBytes)
+}
+
+// signingAlgs returns the signing algorithms advertised in the discovery
+// document. Unless overridden, they are the algorithms of the signing keys
+// in the published key set, and nil while no key set is available.
+func (h *Handler) signingAlgs() []string {
+	if len(h.idTokenSigningAlgs) > 0 {
+		return h.idTokenSigningAlgs
+	}
+
+	jwks, modTime, ok := h.source.FetchKeySet()
+	if !ok {
+		return nil
+	}
+	cached, err := h.getCachedJWKS(jwks, modTime)
+	if err != nil {
+		return nil
+	}
+	return cached.signingAlgs
+}
+
+// serveProtectedResource serves the OAuth 2.0 Protected Resource Metadata
+// document (RFC 9728), advertising the same key set as the discovery
+// document. The additional discovery document claims are not merged in.
+func (h *Handler) serveProtectedResource(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := h.verifyHost(r.Host); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	issuerURL := h.issuerURL(r)
+	jwksURI := h.jwksURI(issuerURL)
+
+	resource := h.protectedResource.Resource
+	if resource == "" {
+		resource = issuerURL.String()
+	}
+
+	doc := struct {
+		Resource             string   `json:"resource"`
+		AuthorizationServers []string `json:"authorization_servers,omitempty"`
+		JWKSURI              string   `json:"jwks_uri"`
+	}{
+		Resource:             resource,
+		AuthorizationServers: h.protectedResource.AuthorizationServers,
+		JWKSURI:              jwksURI.String(),
 	}
 
 	docBytes, err := json.MarshalIndent(doc, "", "  ")
@@ -96,6 +278,74 @@ func (h *Handler) serveWellKnown(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write(docBytes)
 }
 
+// issuerURL returns the configured issuer, or the issuer derived from the
+// domain the request was received on and the path prefix.
+func (h *Handler) issuerURL(r *http.Request) url.URL {
+	if h.issuer != nil {
+		return *h.issuer
+	}
+
+	issuerURL := url.URL{
+		Scheme: "https",
+		Host:   r.Host,
+		Path:   h.pathPrefix,
+	}
+	if h.allowInsecureScheme && r.TLS == nil && r.URL.Scheme != "https" {
+		issuerURL.Scheme = "http"
+	}
+	return issuerURL
+}
+
+// jwksURI returns the URI of the JWKS. When a path prefix is set, the JWKS
+// is served under it on the issuer host. Otherwise, it is served relative to
+// the issuer.
+func (h *Handler) jwksURI(issuerURL url.URL) url.URL {
+	if h.pathPrefix == "" {
+		return jwksURIFromIssuer(issuerURL)
+	}
+	return url.URL{
+		Scheme: issuerURL.Scheme,
+		Host:   issuerURL.Host,
+		Path:   h.pathPrefix + "/keys",
+	}
+}
+
+// jwksURIFromIssuer returns the URI of the JWKS, which is served relative to
+// the issuer.
+func jwksURIFromIssuer(issuerURL url.URL) url.URL {
+	jwksURI := issuerURL
+	jwksURI.Path = strings.TrimSuffix(issuerURL.Path, "/") + "/keys"
+	jwksURI.RawPath = ""
+	return jwksURI
+}
+
+// marshalDiscoveryDocument marshals the discovery document, merging in the
+// configured extra claims. The claims of the generated document take
+// precedence.
+func (h *Handler) marshalDiscoveryDocument(doc interface{}) ([]byte, error) {
+	if len(h.extraClaims) == 0 {
+		return json.MarshalIndent(doc, "", "  ")
+	}
+
+	docBytes, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	claims := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(docBytes, &claims); err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string]interface{}, len(claims)+len(h.extraClaims))
+	for name, value := range h.extraClaims {
+		merged[name] = value
+	}
+	for name, value := range claims {
+		merged[name] = value
+	}
+	return json.MarshalIndent(merged, "", "  ")
+}
+
 func (h *Handler) serveKeys(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -108,25 +358,174 @@ func (h *Handler) serveKeys(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if h.setKeyUse {
-		for i := range jwks.Keys {
-			jwks.Keys[i].Use = keyUse
-		}
-	}
-
-	jwksBytes, err := json.MarshalIndent(jwks, "", "  ")
+	cached, err := h.getCachedJWKS(jwks, modTime)
 	if err != nil {
 		http.Error(w, "failed to marshal JWKS", http.StatusInternalServerError)
 		return
 	}
 
-	// Disable caching
-	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
-	w.Header().Set("Pragma", "no-cache")
-	w.Header().Set("Expires", "0")
+	if h.jwksCacheMaxAge > 0 {
+		// Let clients cache the key set and revalidate it using the ETag,
+		// which http.ServeContent takes into account for If-None-Match.
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int64(h.jwksCacheMaxAge/time.Second)))
+		w.Header().Set("ETag", cached.etag)
+	} else {
+		// Disable caching
+		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+		w.Header().Set("Pragma", "no-cache")
+		w.Header().Set("Expires", "0")
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	http.ServeContent(w, r, "keys", modTime, bytes.NewReader(jwksBytes))
+	http.ServeContent(w, r, "keys", modTime, bytes.NewReader(cached.body))
+}
+
+// serveRoots serves the X.509 authorities of the trust domains as
+// concatenated PEM blocks.
+func (h *Handler) serveRoots(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	authorities, modTime, ok := h.x509Source.FetchX509Authorities()
+	if !ok {
+		http.Error(w, "document not available", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-pem-file")
+	http.ServeContent(w, r, "roots.pem", modTime, bytes.NewReader(pemutil.EncodeCertificates(authorities)))
+}
+
+// getCachedJWKS returns the marshaled key set for the key set returned by the
+// source. The key set is only marshaled again when the source reports a
+// change. Concurrent requests racing to rebuild it do redundant work but
+// always serve a consistent key set.
+func (h *Handler) getCachedJWKS(jwks *jose.JSONWebKeySet, modTime time.Time) (*cachedJWKS, error) {
+	if cached, ok := h.jwks.Load().(*cachedJWKS); ok && cached.source == jwks && cached.modTime.Equal(modTime) {
+		return cached, nil
+	}
+
+	published := h.publishedKeySet(jwks)
+	body, err := json.MarshalIndent(published, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	cached := &cachedJWKS{
+		source:      jwks,
+		modTime:     modTime,
+		body:        body,
+		etag:        jwksETag(published),
+		signingAlgs: jwksSigningAlgs(published),
+	}
+	h.jwks.Store(cached)
+	return cached, nil
+}
+
+// publishedKeySet returns a copy of the key set as it is published, leaving
+// the key set owned by the source untouched.
+func (h *Handler) publishedKeySet(jwks *jose.JSONWebKeySet) *jose.JSONWebKeySet {
+	published := &jose.JSONWebKeySet{
+		Keys: append([]jose.JSONWebKey(nil), jwks.Keys...),
+	}
+
+	if h.setKeyUse {
+		for i := range published.Keys {
+			published.Keys[i].Use = keyUse
+		}
+	}
+
+	// Filtering happens after the use has been set, if configured to do so
+	if h.publishKeyUse != nil {
+		published = h.filterKeysByUse(published)
+	}
+	return published
+}
+
+// filterKeysByUse returns a copy of the key set only containing the keys
+// with a use that is published.
+func (h *Handler) filterKeysByUse(jwks *jose.JSONWebKeySet) *jose.JSONWebKeySet {
+	filtered := new(jose.JSONWebKeySet)
+	for _, key := range jwks.Keys {
+		if h.publishKeyUse[key.Use] {
+			filtered.Keys = append(filtered.Keys, key)
+		}
+	}
+	return filtered
+}
+
+// jwksETag computes an entity tag for the key set from its sorted key IDs.
+func jwksETag(jwks *jose.JSONWebKeySet) string {
+	keyIDs := make([]string, 0, len(jwks.Keys))
+	for _, key := range jwks.Keys {
+		keyIDs = append(keyIDs, key.KeyID)
+	}
+	sort.Strings(keyIDs)
+
+	sum := sha256.Sum256([]byte(strings.Join(keyIDs, "\n")))
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// signingAlgOrder is the order signing algorithms are advertised in.
+// Algorithms not listed are advertised after these, sorted by name.
+var signingAlgOrder = []string{
+	string(jose.RS256), string(jose.RS384), string(jose.RS512),
+	string(jose.PS256), string(jose.PS384), string(jose.PS512),
+	string(jose.ES256), string(jose.ES384), string(jose.ES512),
+	string(jose.EdDSA),
+}
+
+// jwksSigningAlgs returns the signing algorithms of the keys in the key set.
+// Keys that are only used for encryption are skipped. The algorithm of a key
+// is taken from its "alg" parameter or, when unset, inferred from its type.
+func jwksSigningAlgs(jwks *jose.JSONWebKeySet) []string {
+	algs := make(map[string]bool)
+	for _, key := range jwks.Keys {
+		if key.Use != "" && key.Use != keyUse {
+			continue
+		}
+		if alg := keySigningAlg(key); alg != "" {
+			algs[alg] = true
+		}
+	}
+
+	ordered := make([]string, 0, len(algs))
+	for _, alg := range signingAlgOrder {
+		if algs[alg] {
+			ordered = append(ordered, alg)
+			delete(algs, alg)
+		}
+	}
+	var others []string
+	for alg := range algs {
+		others = append(others, alg)
+	}
+	sort.Strings(others)
+	return append(ordered, others...)
+}
+
+func keySigningAlg(key jose.JSONWebKey) string {
+	if key.Algorithm != "" {
+		return key.Algorithm
+	}
+	switch publicKey := key.Key.(type) {
+	case *rsa.PublicKey:
+		return string(jose.RS256)
+	case *ecdsa.PublicKey:
+		switch publicKey.Curve {
+		case elliptic.P256():
+			return string(jose.ES256)
+		case elliptic.P384():
+			return string(jose.ES384)
+		case elliptic.P521():
+			return string(jose.ES512)
+		}
+	case ed25519.PublicKey:
+		return string(jose.EdDSA)
+	}
+	return ""
 }
 
 func (h *Handler) verifyHost(host string) error {
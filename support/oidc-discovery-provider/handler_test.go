@@ -1,12 +1,19 @@
 package main
 
 import (
+	"crypto/x509"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/test/testca"
+	"github.com/spiffe/spire/test/testkey"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/square/go-jose.v2"
@@ -14,14 +21,15 @@ import (
 
 func TestHandlerHTTPS(t *testing.T) {
 	testCases := []struct {
-		name      string
-		method    string
-		path      string
-		jwks      *jose.JSONWebKeySet
-		modTime   time.Time
-		code      int
-		body      string
-		setKeyUse bool
+		name          string
+		method        string
+		path          string
+		jwks          *jose.JSONWebKeySet
+		modTime       time.Time
+		code          int
+		body          string
+		setKeyUse     bool
+		publishKeyUse []string
 	}{
 		{
 			name:   "GET well-known",
@@ -35,12 +43,7 @@ func TestHandlerHTTPS(t *testing.T) {
   "response_types_supported": [
     "id_token"
   ],
-  "subject_types_supported": [],
-  "id_token_signing_alg_values_supported": [
-    "RS256",
-    "ES256",
-    "ES384"
-  ]
+  "subject_types_supported": []
 }`,
 		},
 		{
@@ -128,6 +131,91 @@ func TestHandlerHTTPS(t *testing.T) {
       "y": "Gb4gkQCeHj7HCbZzdctcAx9dxoDgC9sudsSG7ZLIWJs"
     }
   ]
+}`,
+		},
+		{
+			name:          "GET keys with publish key use filtering out keys without use",
+			method:        "GET",
+			path:          "/keys",
+			publishKeyUse: []string{"sig"},
+			jwks: &jose.JSONWebKeySet{
+				Keys: []jose.JSONWebKey{
+					{
+						Key:       ec256Pubkey,
+						KeyID:     "KEYID",
+						Algorithm: "ES256",
+					},
+				},
+			},
+			code: http.StatusOK,
+			body: `{
+  "keys": null
+}`,
+		},
+		{
+			name:          "GET keys with publish key use filtering by use",
+			method:        "GET",
+			path:          "/keys",
+			publishKeyUse: []string{"sig"},
+			jwks: &jose.JSONWebKeySet{
+				Keys: []jose.JSONWebKey{
+					{
+						Key:       ec256Pubkey,
+						KeyID:     "SIGKEYID",
+						Algorithm: "ES256",
+						Use:       "sig",
+					},
+					{
+						Key:       ec256Pubkey,
+						KeyID:     "ENCKEYID",
+						Algorithm: "ES256",
+						Use:       "enc",
+					},
+				},
+			},
+			code: http.StatusOK,
+			body: `{
+  "keys": [
+    {
+      "use": "sig",
+      "kty": "EC",
+      "kid": "SIGKEYID",
+      "crv": "P-256",
+      "alg": "ES256",
+      "x": "iSt7S4ih6QLodw9wf-zdPV8bmAlDJBCRRy24_UAZY70",
+      "y": "Gb4gkQCeHj7HCbZzdctcAx9dxoDgC9sudsSG7ZLIWJs"
+    }
+  ]
+}`,
+		},
+		{
+			name:          "GET keys with publish key use after key use is set",
+			method:        "GET",
+			path:          "/keys",
+			setKeyUse:     true,
+			publishKeyUse: []string{"sig"},
+			jwks: &jose.JSONWebKeySet{
+				Keys: []jose.JSONWebKey{
+					{
+						Key:       ec256Pubkey,
+						KeyID:     "KEYID",
+						Algorithm: "ES256",
+					},
+				},
+			},
+			code: http.StatusOK,
+			body: `{
+  "keys": [
+    {
+      "use": "sig",
+      "kty": "EC",
+      "kid": "KEYID",
+      "crv": "P-256",
+      "alg": "ES256",
+      "x": "iSt7S4ih6QLodw9wf-zdPV8bmAlDJBCRRy24_UAZY70",
+      "y": "Gb4gkQCeHj7HCbZzdctcAx9dxoDgC9sudsSG7ZLIWJs"
+    }
+  ]
 }`,
 		},
 	}
@@ -142,7 +230,12 @@ func TestHandlerHTTPS(t *testing.T) {
 			require.NoError(t, err)
 			w := httptest.NewRecorder()
 
-			h := NewHandler(domainAllowlist(t, "localhost", "domain.test"), source, false, testCase.setKeyUse)
+			h := NewHandler(HandlerConfig{
+				DomainPolicy:  domainAllowlist(t, "localhost", "domain.test"),
+				Source:        source,
+				SetKeyUse:     testCase.setKeyUse,
+				PublishKeyUse: testCase.publishKeyUse,
+			})
 			h.ServeHTTP(w, r)
 
 			t.Logf("HEADERS: %q", w.Header())
@@ -174,12 +267,7 @@ func TestHandlerHTTPInsecure(t *testing.T) {
   "response_types_supported": [
     "id_token"
   ],
-  "subject_types_supported": [],
-  "id_token_signing_alg_values_supported": [
-    "RS256",
-    "ES256",
-    "ES384"
-  ]
+  "subject_types_supported": []
 }`,
 		},
 		{
@@ -252,7 +340,11 @@ func TestHandlerHTTPInsecure(t *testing.T) {
 			require.NoError(t, err)
 			w := httptest.NewRecorder()
 
-			h := NewHandler(domainAllowlist(t, "localhost", "domain.test"), source, true, false)
+			h := NewHandler(HandlerConfig{
+				DomainPolicy:        domainAllowlist(t, "localhost", "domain.test"),
+				Source:              source,
+				AllowInsecureScheme: true,
+			})
 			h.ServeHTTP(w, r)
 
 			t.Logf("HEADERS: %q", w.Header())
@@ -285,12 +377,7 @@ func TestHandlerHTTP(t *testing.T) {
   "response_types_supported": [
     "id_token"
   ],
-  "subject_types_supported": [],
-  "id_token_signing_alg_values_supported": [
-    "RS256",
-    "ES256",
-    "ES384"
-  ]
+  "subject_types_supported": []
 }`,
 		},
 		{
@@ -306,12 +393,7 @@ func TestHandlerHTTP(t *testing.T) {
   "response_types_supported": [
     "id_token"
   ],
-  "subject_types_supported": [],
-  "id_token_signing_alg_values_supported": [
-    "RS256",
-    "ES256",
-    "ES384"
-  ]
+  "subject_types_supported": []
 }`,
 		},
 		{
@@ -327,12 +409,7 @@ func TestHandlerHTTP(t *testing.T) {
   "response_types_supported": [
     "id_token"
   ],
-  "subject_types_supported": [],
-  "id_token_signing_alg_values_supported": [
-    "RS256",
-    "ES256",
-    "ES384"
-  ]
+  "subject_types_supported": []
 }`,
 		},
 
@@ -419,7 +496,10 @@ func TestHandlerHTTP(t *testing.T) {
 			require.NoError(t, err)
 			w := httptest.NewRecorder()
 
-			h := NewHandler(domainAllowlist(t, "domain.test", "xn--n38h.test"), source, false, false)
+			h := NewHandler(HandlerConfig{
+				DomainPolicy: domainAllowlist(t, "domain.test", "xn--n38h.test"),
+				Source:       source,
+			})
 			h.ServeHTTP(w, r)
 
 			t.Logf("HEADERS: %q", w.Header())
@@ -451,12 +531,7 @@ func TestHandlerProxied(t *testing.T) {
   "response_types_supported": [
     "id_token"
   ],
-  "subject_types_supported": [],
-  "id_token_signing_alg_values_supported": [
-    "RS256",
-    "ES256",
-    "ES384"
-  ]
+  "subject_types_supported": []
 }`,
 		},
 		{
@@ -531,7 +606,10 @@ func TestHandlerProxied(t *testing.T) {
 			r.Header.Add("X-Forwarded-Host", "domain.test")
 			w := httptest.NewRecorder()
 
-			h := NewHandler(domainAllowlist(t, "domain.test"), source, false, false)
+			h := NewHandler(HandlerConfig{
+				DomainPolicy: domainAllowlist(t, "domain.test"),
+				Source:       source,
+			})
 			h.ServeHTTP(w, r)
 
 			t.Logf("HEADERS: %q", w.Header())
@@ -541,10 +619,779 @@ func TestHandlerProxied(t *testing.T) {
 	}
 }
 
+func TestHandlerIssuer(t *testing.T) {
+	testCases := []struct {
+		name          string
+		issuer        string
+		expectIssuer  string
+		expectJWKSURI string
+		allowInsecure bool
+	}{
+		{
+			name:          "issuer without path",
+			issuer:        "https://id.example.com",
+			expectIssuer:  "https://id.example.com",
+			expectJWKSURI: "https://id.example.com/keys",
+		},
+		{
+			name:          "issuer with path",
+			issuer:        "https://id.example.com/spire",
+			expectIssuer:  "https://id.example.com/spire",
+			expectJWKSURI: "https://id.example.com/spire/keys",
+		},
+		{
+			name:          "issuer with trailing slash",
+			issuer:        "https://id.example.com/spire/",
+			expectIssuer:  "https://id.example.com/spire/",
+			expectJWKSURI: "https://id.example.com/spire/keys",
+		},
+		{
+			name:          "insecure issuer",
+			issuer:        "http://id.example.com/spire",
+			allowInsecure: true,
+			expectIssuer:  "http://id.example.com/spire",
+			expectJWKSURI: "http://id.example.com/spire/keys",
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			issuer, err := url.Parse(testCase.issuer)
+			require.NoError(t, err)
+
+			r, err := http.NewRequest("GET", "http://domain.test/.well-known/openid-configuration", nil)
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+
+			h := NewHandler(HandlerConfig{
+				DomainPolicy:        domainAllowlist(t, "domain.test"),
+				Source:              new(FakeKeySetSource),
+				Issuer:              issuer,
+				AllowInsecureScheme: testCase.allowInsecure,
+			})
+			h.ServeHTTP(w, r)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var doc struct {
+				Issuer  string `json:"issuer"`
+				JWKSURI string `json:"jwks_uri"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+			assert.Equal(t, testCase.expectIssuer, doc.Issuer)
+			assert.Equal(t, testCase.expectJWKSURI, doc.JWKSURI)
+		})
+	}
+}
+
+func TestHandlerPathPrefix(t *testing.T) {
+	source := new(FakeKeySetSource)
+	source.SetKeySet(&jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{
+			{
+				Key:       ec256Pubkey,
+				KeyID:     "KEYID",
+				Algorithm: "ES256",
+			},
+		},
+	}, time.Now())
+
+	newHandler := func(issuer *url.URL) *Handler {
+		return NewHandler(HandlerConfig{
+			DomainPolicy: domainAllowlist(t, "domain.test"),
+			Source:       source,
+			Issuer:       issuer,
+			PathPrefix:   "/spire",
+		})
+	}
+
+	serve := func(h *Handler, path string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest("GET", "https://domain.test"+path, nil)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("routes are served under the prefix", func(t *testing.T) {
+		h := newHandler(nil)
+
+		w := serve(h, "/spire/.well-known/openid-configuration")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `{
+  "issuer": "https://domain.test/spire",
+  "jwks_uri": "https://domain.test/spire/keys",
+  "authorization_endpoint": "",
+  "response_types_supported": [
+    "id_token"
+  ],
+  "subject_types_supported": [],
+  "id_token_signing_alg_values_supported": [
+    "ES256"
+  ]
+}`, w.Body.String())
+
+		w = serve(h, "/spire/keys")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"kid": "KEYID"`)
+	})
+
+	t.Run("routes are not served without the prefix", func(t *testing.T) {
+		h := newHandler(nil)
+		assert.Equal(t, http.StatusNotFound, serve(h, "/.well-known/openid-configuration").Code)
+		assert.Equal(t, http.StatusNotFound, serve(h, "/keys").Code)
+	})
+
+	t.Run("JWKS URI is prefixed when the issuer is configured", func(t *testing.T) {
+		h := newHandler(&url.URL{Scheme: "https", Host: "id.example.com"})
+
+		w := serve(h, "/spire/.well-known/openid-configuration")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var doc struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+		assert.Equal(t, "https://id.example.com", doc.Issuer)
+		assert.Equal(t, "https://id.example.com/spire/keys", doc.JWKSURI)
+	})
+}
+
+func TestHandlerDiscoveryDocument(t *testing.T) {
+	r, err := http.NewRequest("GET", "https://domain.test/.well-known/openid-configuration", nil)
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+
+	h := NewHandler(HandlerConfig{
+		DomainPolicy: domainAllowlist(t, "domain.test"),
+		Source:       new(FakeKeySetSource),
+		DiscoveryDocument: map[string]interface{}{
+			"scopes_supported":      []interface{}{"openid"},
+			"service_documentation": "https://docs.domain.test",
+		},
+	})
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, `{
+  "authorization_endpoint": "",
+  "issuer": "https://domain.test",
+  "jwks_uri": "https://domain.test/keys",
+  "response_types_supported": [
+    "id_token"
+  ],
+  "scopes_supported": [
+    "openid"
+  ],
+  "service_documentation": "https://docs.domain.test",
+  "subject_types_supported": []
+}`, w.Body.String())
+}
+
+func TestHandlerSigningAlgs(t *testing.T) {
+	rsaKey := testkey.NewRSA2048(t).Public()
+	ec384Key := testkey.NewEC384(t).Public()
+
+	for _, tt := range []struct {
+		name               string
+		jwks               *jose.JSONWebKeySet
+		idTokenSigningAlgs []string
+		expectAlgs         []string
+	}{
+		{
+			name: "no key set",
+		},
+		{
+			name: "empty key set",
+			jwks: new(jose.JSONWebKeySet),
+		},
+		{
+			name: "EC only",
+			jwks: &jose.JSONWebKeySet{
+				Keys: []jose.JSONWebKey{
+					{Key: ec256Pubkey, KeyID: "EC256"},
+				},
+			},
+			expectAlgs: []string{"ES256"},
+		},
+		{
+			name: "mixed key types",
+			jwks: &jose.JSONWebKeySet{
+				Keys: []jose.JSONWebKey{
+					{Key: ec384Key, KeyID: "EC384"},
+					{Key: ec256Pubkey, KeyID: "EC256", Algorithm: "ES256"},
+					{Key: rsaKey, KeyID: "RSA"},
+					{Key: rsaKey, KeyID: "RSA-ENC", Use: "enc", Algorithm: "RSA-OAEP"},
+				},
+			},
+			expectAlgs: []string{"RS256", "ES256", "ES384"},
+		},
+		{
+			name: "overridden",
+			jwks: &jose.JSONWebKeySet{
+				Keys: []jose.JSONWebKey{
+					{Key: ec256Pubkey, KeyID: "EC256"},
+				},
+			},
+			idTokenSigningAlgs: []string{"ES256", "RS256"},
+			expectAlgs:         []string{"ES256", "RS256"},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			source := new(FakeKeySetSource)
+			source.SetKeySet(tt.jwks, time.Time{})
+
+			r, err := http.NewRequest("GET", "https://domain.test/.well-known/openid-configuration", nil)
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+
+			h := NewHandler(HandlerConfig{
+				DomainPolicy:       domainAllowlist(t, "domain.test"),
+				Source:             source,
+				IDTokenSigningAlgs: tt.idTokenSigningAlgs,
+			})
+			h.ServeHTTP(w, r)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			doc := make(map[string]interface{})
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+			if tt.expectAlgs == nil {
+				require.NotContains(t, doc, "id_token_signing_alg_values_supported")
+				return
+			}
+
+			var algs []string
+			for _, alg := range doc["id_token_signing_alg_values_supported"].([]interface{}) {
+				algs = append(algs, alg.(string))
+			}
+			require.Equal(t, tt.expectAlgs, algs)
+		})
+	}
+}
+
+func TestHandlerProtectedResource(t *testing.T) {
+	testCases := []struct {
+		name              string
+		method            string
+		issuer            string
+		protectedResource *ProtectedResourceConfig
+		code              int
+		body              string
+	}{
+		{
+			name:   "not configured",
+			method: "GET",
+			code:   http.StatusNotFound,
+			body:   "404 page not found\n",
+		},
+		{
+			name:              "resource derived from the request",
+			method:            "GET",
+			protectedResource: &ProtectedResourceConfig{},
+			code:              http.StatusOK,
+			body: `{
+  "resource": "https://domain.test",
+  "jwks_uri": "https://domain.test/keys"
+}`,
+		},
+		{
+			name:   "resource derived from the issuer",
+			method: "GET",
+			issuer: "https://id.example.com/spire",
+			protectedResource: &ProtectedResourceConfig{
+				AuthorizationServers: []string{"https://as.example.com"},
+			},
+			code: http.StatusOK,
+			body: `{
+  "resource": "https://id.example.com/spire",
+  "authorization_servers": [
+    "https://as.example.com"
+  ],
+  "jwks_uri": "https://id.example.com/spire/keys"
+}`,
+		},
+		{
+			name:   "configured resource",
+			method: "GET",
+			protectedResource: &ProtectedResourceConfig{
+				Resource:             "https://api.example.com",
+				AuthorizationServers: []string{"https://as1.example.com", "https://as2.example.com"},
+			},
+			code: http.StatusOK,
+			body: `{
+  "resource": "https://api.example.com",
+  "authorization_servers": [
+    "https://as1.example.com",
+    "https://as2.example.com"
+  ],
+  "jwks_uri": "https://domain.test/keys"
+}`,
+		},
+		{
+			name:              "PUT",
+			method:            "PUT",
+			protectedResource: &ProtectedResourceConfig{},
+			code:              http.StatusMethodNotAllowed,
+			body:              "method not allowed\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			var issuer *url.URL
+			if testCase.issuer != "" {
+				var err error
+				issuer, err = url.Parse(testCase.issuer)
+				require.NoError(t, err)
+			}
+
+			r, err := http.NewRequest(testCase.method, "https://domain.test/.well-known/oauth-protected-resource", nil)
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+
+			h := NewHandler(HandlerConfig{
+				DomainPolicy:      domainAllowlist(t, "domain.test"),
+				Source:            new(FakeKeySetSource),
+				Issuer:            issuer,
+				ProtectedResource: testCase.protectedResource,
+				DiscoveryDocument: map[string]interface{}{
+					"scopes_supported": []interface{}{"openid"},
+				},
+			})
+			h.ServeHTTP(w, r)
+
+			assert.Equal(t, testCase.code, w.Code)
+			assert.Equal(t, testCase.body, w.Body.String())
+			if testCase.code == http.StatusOK {
+				assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestHandlerRoots(t *testing.T) {
+	ca := testca.New(t, spiffeid.RequireTrustDomainFromString("domain.test"))
+	authorities := ca.X509Authorities()
+
+	testCases := []struct {
+		name        string
+		method      string
+		x509Source  X509AuthoritiesSource
+		code        int
+		contentType string
+		body        string
+	}{
+		{
+			name:   "not configured",
+			method: "GET",
+			code:   http.StatusNotFound,
+			body:   "404 page not found\n",
+		},
+		{
+			name:       "no authorities",
+			method:     "GET",
+			x509Source: new(fakeX509AuthoritiesSource),
+			code:       http.StatusInternalServerError,
+			body:       "document not available\n",
+		},
+		{
+			name:   "PUT",
+			method: "PUT",
+			x509Source: &fakeX509AuthoritiesSource{
+				authorities: authorities,
+			},
+			code: http.StatusMethodNotAllowed,
+			body: "method not allowed\n",
+		},
+		{
+			name:   "GET",
+			method: "GET",
+			x509Source: &fakeX509AuthoritiesSource{
+				authorities: authorities,
+			},
+			code:        http.StatusOK,
+			contentType: "application/x-pem-file",
+			body:        string(pemutil.EncodeCertificates(authorities)),
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			r, err := http.NewRequest(testCase.method, "https://domain.test/roots.pem", nil)
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+
+			h := NewHandler(HandlerConfig{
+				DomainPolicy: domainAllowlist(t, "domain.test"),
+				Source:       new(FakeKeySetSource),
+				X509Source:   testCase.x509Source,
+			})
+			h.ServeHTTP(w, r)
+
+			assert.Equal(t, testCase.code, w.Code)
+			assert.Equal(t, testCase.body, w.Body.String())
+			if testCase.contentType != "" {
+				assert.Equal(t, testCase.contentType, w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+type fakeX509AuthoritiesSource struct {
+	authorities []*x509.Certificate
+}
+
+func (s *fakeX509AuthoritiesSource) FetchX509Authorities() ([]*x509.Certificate, time.Time, bool) {
+	if s.authorities == nil {
+		return nil, time.Time{}, false
+	}
+	return s.authorities, time.Time{}, true
+}
+
+func TestHandlerJWKSCaching(t *testing.T) {
+	jwks := &jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{
+			{
+				Key:       ec256Pubkey,
+				KeyID:     "KEYID2",
+				Algorithm: "ES256",
+			},
+			{
+				Key:       ec256Pubkey,
+				KeyID:     "KEYID1",
+				Algorithm: "ES256",
+			},
+		},
+	}
+	// SHA-256 of the sorted key IDs, newline separated
+	const etag = `"5a61aab7d58b2b16eee9701c1b1304126dc37340e07af0a310626da78523851a"`
+
+	testCases := []struct {
+		name            string
+		jwksCacheMaxAge time.Duration
+		ifNoneMatch     string
+		code            int
+		headers         http.Header
+	}{
+		{
+			name: "caching disabled by default",
+			code: http.StatusOK,
+			headers: http.Header{
+				"Cache-Control": []string{"no-cache, no-store, must-revalidate"},
+				"Pragma":        []string{"no-cache"},
+				"Expires":       []string{"0"},
+			},
+		},
+		{
+			name:            "caching enabled",
+			jwksCacheMaxAge: 5 * time.Minute,
+			code:            http.StatusOK,
+			headers: http.Header{
+				"Cache-Control": []string{"public, max-age=300"},
+				"Etag":          []string{etag},
+			},
+		},
+		{
+			name:            "caching enabled with matching If-None-Match",
+			jwksCacheMaxAge: 5 * time.Minute,
+			ifNoneMatch:     etag,
+			code:            http.StatusNotModified,
+			headers: http.Header{
+				"Cache-Control": []string{"public, max-age=300"},
+				"Etag":          []string{etag},
+			},
+		},
+		{
+			name:            "caching enabled with stale If-None-Match",
+			jwksCacheMaxAge: 5 * time.Minute,
+			ifNoneMatch:     `"stale"`,
+			code:            http.StatusOK,
+			headers: http.Header{
+				"Cache-Control": []string{"public, max-age=300"},
+				"Etag":          []string{etag},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			source := new(FakeKeySetSource)
+			source.SetKeySet(jwks, time.Time{})
+
+			r, err := http.NewRequest("GET", "https://localhost/keys", nil)
+			require.NoError(t, err)
+			if testCase.ifNoneMatch != "" {
+				r.Header.Set("If-None-Match", testCase.ifNoneMatch)
+			}
+			w := httptest.NewRecorder()
+
+			h := NewHandler(HandlerConfig{
+				DomainPolicy:    domainAllowlist(t, "localhost"),
+				Source:          source,
+				JWKSCacheMaxAge: testCase.jwksCacheMaxAge,
+			})
+			h.ServeHTTP(w, r)
+
+			t.Logf("HEADERS: %q", w.Header())
+			assert.Equal(t, testCase.code, w.Code)
+			for key, value := range testCase.headers {
+				assert.Equal(t, value, w.Header()[key], "unexpected %s header", key)
+			}
+			if testCase.jwksCacheMaxAge > 0 {
+				assert.Empty(t, w.Header().Get("Pragma"))
+				assert.Empty(t, w.Header().Get("Expires"))
+			}
+		})
+	}
+}
+
+func TestHandlerJWKSUpdates(t *testing.T) {
+	source := new(FakeKeySetSource)
+	jwks1 := &jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{
+			{
+				Key:       ec256Pubkey,
+				KeyID:     "KEYID1",
+				Algorithm: "ES256",
+			},
+		},
+	}
+	source.SetKeySet(jwks1, time.Unix(10000, 0))
+
+	h := NewHandler(HandlerConfig{
+		DomainPolicy: domainAllowlist(t, "localhost"),
+		Source:       source,
+		SetKeyUse:    true,
+	})
+
+	serveKeys := func() *jose.JSONWebKeySet {
+		r, err := http.NewRequest("GET", "https://localhost/keys", nil)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		served := new(jose.JSONWebKeySet)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), served))
+		return served
+	}
+
+	served := serveKeys()
+	require.Len(t, served.Keys, 1)
+	assert.Equal(t, "KEYID1", served.Keys[0].KeyID)
+	assert.Equal(t, "sig", served.Keys[0].Use)
+
+	// The key set owned by the source is not modified
+	assert.Empty(t, jwks1.Keys[0].Use)
+
+	// Served bytes are updated once the source reports a change
+	jwks2 := &jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{
+			{
+				Key:       ec256Pubkey,
+				KeyID:     "KEYID2",
+				Algorithm: "ES256",
+			},
+		},
+	}
+	source.SetKeySet(jwks2, time.Unix(20000, 0))
+
+	served = serveKeys()
+	require.Len(t, served.Keys, 1)
+	assert.Equal(t, "KEYID2", served.Keys[0].KeyID)
+	assert.Equal(t, "sig", served.Keys[0].Use)
+}
+
+func BenchmarkHandlerServeKeys(b *testing.B) {
+	source := new(FakeKeySetSource)
+	source.SetKeySet(&jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{
+			{
+				Key:       ec256Pubkey,
+				KeyID:     "KEYID1",
+				Algorithm: "ES256",
+			},
+			{
+				Key:       ec256Pubkey,
+				KeyID:     "KEYID2",
+				Algorithm: "ES256",
+			},
+		},
+	}, time.Unix(10000, 0))
+
+	policy, err := DomainAllowlist("localhost")
+	require.NoError(b, err)
+	h := NewHandler(HandlerConfig{
+		DomainPolicy: policy,
+		Source:       source,
+	})
+
+	r, err := http.NewRequest("GET", "https://localhost/keys", nil)
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+}
+
+func TestHandlerCORS(t *testing.T) {
+	testCases := []struct {
+		name           string
+		allowedOrigins []string
+		method         string
+		path           string
+		headers        map[string]string
+		code           int
+		expectHeaders  http.Header
+	}{
+		{
+			name:   "CORS disabled",
+			method: "GET",
+			path:   "/keys",
+			headers: map[string]string{
+				"Origin": "https://app.domain.test",
+			},
+			code: http.StatusOK,
+			expectHeaders: http.Header{
+				"Access-Control-Allow-Origin":  nil,
+				"Access-Control-Allow-Methods": nil,
+				"Vary":                         nil,
+			},
+		},
+		{
+			name:           "GET keys from allowed origin",
+			allowedOrigins: []string{"https://app.domain.test"},
+			method:         "GET",
+			path:           "/keys",
+			headers: map[string]string{
+				"Origin": "https://app.domain.test",
+			},
+			code: http.StatusOK,
+			expectHeaders: http.Header{
+				"Access-Control-Allow-Origin":  []string{"https://app.domain.test"},
+				"Access-Control-Allow-Methods": []string{"GET"},
+				"Vary":                         []string{"Origin"},
+			},
+		},
+		{
+			name:           "GET well-known from allowed origin",
+			allowedOrigins: []string{"https://other.domain.test", "https://app.domain.test"},
+			method:         "GET",
+			path:           "/.well-known/openid-configuration",
+			headers: map[string]string{
+				"Origin": "https://app.domain.test",
+			},
+			code: http.StatusOK,
+			expectHeaders: http.Header{
+				"Access-Control-Allow-Origin":  []string{"https://app.domain.test"},
+				"Access-Control-Allow-Methods": []string{"GET"},
+				"Vary":                         []string{"Origin"},
+			},
+		},
+		{
+			name:           "GET keys from disallowed origin",
+			allowedOrigins: []string{"https://app.domain.test"},
+			method:         "GET",
+			path:           "/keys",
+			headers: map[string]string{
+				"Origin": "https://evil.domain.test",
+			},
+			code: http.StatusOK,
+			expectHeaders: http.Header{
+				"Access-Control-Allow-Origin":  nil,
+				"Access-Control-Allow-Methods": nil,
+				"Vary":                         []string{"Origin"},
+			},
+		},
+		{
+			name:           "GET keys with wildcard origin",
+			allowedOrigins: []string{"*"},
+			method:         "GET",
+			path:           "/keys",
+			headers: map[string]string{
+				"Origin": "https://app.domain.test",
+			},
+			code: http.StatusOK,
+			expectHeaders: http.Header{
+				"Access-Control-Allow-Origin":  []string{"*"},
+				"Access-Control-Allow-Methods": []string{"GET"},
+				"Vary":                         nil,
+			},
+		},
+		{
+			name:           "preflight from allowed origin",
+			allowedOrigins: []string{"https://app.domain.test"},
+			method:         "OPTIONS",
+			path:           "/keys",
+			headers: map[string]string{
+				"Origin":                        "https://app.domain.test",
+				"Access-Control-Request-Method": "GET",
+			},
+			code: http.StatusNoContent,
+			expectHeaders: http.Header{
+				"Access-Control-Allow-Origin":  []string{"https://app.domain.test"},
+				"Access-Control-Allow-Methods": []string{"GET"},
+			},
+		},
+		{
+			name:           "preflight from disallowed origin",
+			allowedOrigins: []string{"https://app.domain.test"},
+			method:         "OPTIONS",
+			path:           "/keys",
+			headers: map[string]string{
+				"Origin":                        "https://evil.domain.test",
+				"Access-Control-Request-Method": "GET",
+			},
+			code: http.StatusMethodNotAllowed,
+			expectHeaders: http.Header{
+				"Access-Control-Allow-Origin":  nil,
+				"Access-Control-Allow-Methods": nil,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			source := new(FakeKeySetSource)
+			source.SetKeySet(new(jose.JSONWebKeySet), time.Time{})
+
+			r, err := http.NewRequest(testCase.method, "https://localhost"+testCase.path, nil)
+			require.NoError(t, err)
+			for key, value := range testCase.headers {
+				r.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+
+			h := NewHandler(HandlerConfig{
+				DomainPolicy:   domainAllowlist(t, "localhost"),
+				Source:         source,
+				AllowedOrigins: testCase.allowedOrigins,
+			})
+			h.ServeHTTP(w, r)
+
+			t.Logf("HEADERS: %q", w.Header())
+			assert.Equal(t, testCase.code, w.Code)
+			for key, value := range testCase.expectHeaders {
+				assert.Equal(t, value, w.Header()[key], "unexpected %s header", key)
+			}
+		})
+	}
+}
+
 type FakeKeySetSource struct {
-	mu      sync.Mutex
-	jwks    *jose.JSONWebKeySet
-	modTime time.Time
+	mu                 sync.Mutex
+	jwks               *jose.JSONWebKeySet
+	modTime            time.Time
+	lastSuccessfulPoll time.Time
 }
 
 func (s *FakeKeySetSource) SetKeySet(jwks *jose.JSONWebKeySet, modTime time.Time) {
@@ -563,6 +1410,18 @@ func (s *FakeKeySetSource) FetchKeySet() (*jose.JSONWebKeySet, time.Time, bool)
 	return s.jwks, s.modTime, true
 }
 
+func (s *FakeKeySetSource) SetLastSuccessfulPoll(lastSuccessfulPoll time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastSuccessfulPoll = lastSuccessfulPoll
+}
+
+func (s *FakeKeySetSource) LastSuccessfulPoll() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastSuccessfulPoll
+}
+
 func (s *FakeKeySetSource) Close() error {
 	return nil
 }
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/andres-erbsen/clock"
+)
+
+const (
+	livePath  = "/live"
+	readyPath = "/ready"
+)
+
+// HealthChecksHandler serves the liveness and readiness probes. The provider
+// is live as soon as it is serving. It is ready once the source has
+// successfully fetched the key set and stays ready as long as the last
+// successful poll is not older than the ready timeout.
+type HealthChecksHandler struct {
+	source       JWKSSource
+	readyTimeout time.Duration
+	clock        clock.Clock
+
+	http.Handler
+}
+
+func NewHealthChecksHandler(source JWKSSource, readyTimeout time.Duration, clk clock.Clock) *HealthChecksHandler {
+	if clk == nil {
+		clk = clock.New()
+	}
+
+	h := &HealthChecksHandler{
+		source:       source,
+		readyTimeout: readyTimeout,
+		clock:        clk,
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(livePath, http.HandlerFunc(h.serveLive))
+	mux.Handle(readyPath, http.HandlerFunc(h.serveReady))
+
+	h.Handler = mux
+	return h
+}
+
+func (h *HealthChecksHandler) serveLive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *HealthChecksHandler) serveReady(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	lastSuccessfulPoll := h.source.LastSuccessfulPoll()
+	switch {
+	case lastSuccessfulPoll.IsZero():
+		http.Error(w, "key set not fetched yet", http.StatusServiceUnavailable)
+		return
+	case h.clock.Now().Sub(lastSuccessfulPoll) > h.readyTimeout:
+		http.Error(w, "key set not fetched recently", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/test/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthChecksHandler(t *testing.T) {
+	const readyTimeout = time.Minute
+
+	clock := clock.NewMock(t)
+
+	testCases := []struct {
+		name               string
+		method             string
+		path               string
+		lastSuccessfulPoll time.Time
+		code               int
+		body               string
+	}{
+		{
+			name:   "GET live",
+			method: "GET",
+			path:   "/live",
+			code:   http.StatusOK,
+		},
+		{
+			name:   "PUT live",
+			method: "PUT",
+			path:   "/live",
+			code:   http.StatusMethodNotAllowed,
+			body:   "method not allowed\n",
+		},
+		{
+			name:   "GET ready before first successful poll",
+			method: "GET",
+			path:   "/ready",
+			code:   http.StatusServiceUnavailable,
+			body:   "key set not fetched yet\n",
+		},
+		{
+			name:               "GET ready after successful poll",
+			method:             "GET",
+			path:               "/ready",
+			lastSuccessfulPoll: clock.Now().Add(-readyTimeout),
+			code:               http.StatusOK,
+		},
+		{
+			name:               "GET ready after polling failed for too long",
+			method:             "GET",
+			path:               "/ready",
+			lastSuccessfulPoll: clock.Now().Add(-readyTimeout - time.Second),
+			code:               http.StatusServiceUnavailable,
+			body:               "key set not fetched recently\n",
+		},
+		{
+			name:   "PUT ready",
+			method: "PUT",
+			path:   "/ready",
+			code:   http.StatusMethodNotAllowed,
+			body:   "method not allowed\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			source := new(FakeKeySetSource)
+			source.SetLastSuccessfulPoll(testCase.lastSuccessfulPoll)
+
+			r, err := http.NewRequest(testCase.method, "http://localhost"+testCase.path, nil)
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+
+			h := NewHealthChecksHandler(source, readyTimeout, clock)
+			h.ServeHTTP(w, r)
+
+			assert.Equal(t, testCase.code, w.Code)
+			assert.Equal(t, testCase.body, w.Body.String())
+		})
+	}
+}
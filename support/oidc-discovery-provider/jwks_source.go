@@ -1,6 +1,7 @@
 package main
 
 import (
+	"crypto/x509"
 	"time"
 
 	"gopkg.in/square/go-jose.v2"
@@ -10,6 +11,17 @@ type JWKSSource interface {
 	// FetchJWKS returns the key set and modified time.
 	FetchKeySet() (*jose.JSONWebKeySet, time.Time, bool)
 
+	// LastSuccessfulPoll returns the time the source last successfully
+	// fetched the key set, or the zero time if it has not done so yet.
+	LastSuccessfulPoll() time.Time
+
 	// Close closes the source.
 	Close() error
 }
+
+// X509AuthoritiesSource is implemented by sources able to provide the X.509
+// authorities of the trust domains.
+type X509AuthoritiesSource interface {
+	// FetchX509Authorities returns the X.509 authorities and modified time.
+	FetchX509Authorities() ([]*x509.Certificate, time.Time, bool)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/square/go-jose.v2"
+)
+
+// diffKeySets returns the sorted IDs of the keys that were added to and
+// removed from the previous key set, which may be nil.
+func diffKeySets(previous, current *jose.JSONWebKeySet) (added, removed []string) {
+	previousIDs := keyIDs(previous)
+	currentIDs := keyIDs(current)
+
+	for keyID := range currentIDs {
+		if !previousIDs[keyID] {
+			added = append(added, keyID)
+		}
+	}
+	for keyID := range previousIDs {
+		if !currentIDs[keyID] {
+			removed = append(removed, keyID)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// logKeySetChanges logs the keys added to and removed from the key set, if
+// any, to provide an audit trail of key rotations.
+func logKeySetChanges(log logrus.FieldLogger, previous, current *jose.JSONWebKeySet) {
+	added, removed := diffKeySets(previous, current)
+	if len(added) == 0 && len(removed) == 0 {
+		return
+	}
+	log.WithFields(logrus.Fields{
+		"added_kids":   added,
+		"removed_kids": removed,
+	}).Info("Key set changed")
+}
+
+func keyIDs(jwks *jose.JSONWebKeySet) map[string]bool {
+	ids := make(map[string]bool)
+	if jwks == nil {
+		return ids
+	}
+	for _, key := range jwks.Keys {
+		ids[key.KeyID] = true
+	}
+	return ids
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func TestDiffKeySets(t *testing.T) {
+	for _, tt := range []struct {
+		name          string
+		previous      *jose.JSONWebKeySet
+		current       *jose.JSONWebKeySet
+		expectAdded   []string
+		expectRemoved []string
+	}{
+		{
+			name:        "no previous key set",
+			current:     keySetWithIDs("B", "A"),
+			expectAdded: []string{"A", "B"},
+		},
+		{
+			name:     "unchanged",
+			previous: keySetWithIDs("A", "B"),
+			current:  keySetWithIDs("B", "A"),
+		},
+		{
+			name:          "rotated",
+			previous:      keySetWithIDs("A", "B"),
+			current:       keySetWithIDs("B", "D", "C"),
+			expectAdded:   []string{"C", "D"},
+			expectRemoved: []string{"A"},
+		},
+		{
+			name:          "all removed",
+			previous:      keySetWithIDs("A"),
+			current:       keySetWithIDs(),
+			expectRemoved: []string{"A"},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			added, removed := diffKeySets(tt.previous, tt.current)
+			assert.Equal(t, tt.expectAdded, added)
+			assert.Equal(t, tt.expectRemoved, removed)
+		})
+	}
+}
+
+func TestLogKeySetChanges(t *testing.T) {
+	log, hook := test.NewNullLogger()
+
+	logKeySetChanges(log, keySetWithIDs("A", "B"), keySetWithIDs("A", "B"))
+	spiretest.AssertLogs(t, hook.AllEntries(), nil)
+
+	logKeySetChanges(log, keySetWithIDs("A", "B"), keySetWithIDs("B", "C"))
+	spiretest.AssertLogs(t, hook.AllEntries(), []spiretest.LogEntry{
+		{
+			Level:   logrus.InfoLevel,
+			Message: "Key set changed",
+			Data: logrus.Fields{
+				"added_kids":   "[C]",
+				"removed_kids": "[A]",
+			},
+		},
+	})
+}
+
+func keySetWithIDs(keyIDs ...string) *jose.JSONWebKeySet {
+	jwks := new(jose.JSONWebKeySet)
+	for _, keyID := range keyIDs {
+		jwks.Keys = append(jwks.Keys, jose.JSONWebKey{KeyID: keyID, Key: ec256Pubkey})
+	}
+	return jwks
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+
+	"github.com/zeebo/errs"
+)
+
+// listenSocket listens for HTTP on a unix socket at the configured path and
+// applies the configured mode and ownership to it. The owner and group are
+// resolved before the socket is created so that a bad lookup fails startup
+// instead of leaving the socket with the default permissions.
+func listenSocket(config *Config) (net.Listener, error) {
+	uid, err := lookupUID(config.ListenSocketOwner)
+	if err != nil {
+		return nil, err
+	}
+	gid, err := lookupGID(config.ListenSocketGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = os.Remove(config.ListenSocketPath)
+
+	listener, err := net.Listen("unix", config.ListenSocketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(config.ListenSocketPath, config.ListenSocketMode); err != nil {
+		listener.Close()
+		return nil, errs.New("unable to change listen socket mode: %v", err)
+	}
+
+	if uid != -1 || gid != -1 {
+		if err := os.Chown(config.ListenSocketPath, uid, gid); err != nil {
+			listener.Close()
+			return nil, errs.New("unable to change listen socket ownership: %v", err)
+		}
+	}
+
+	return listener, nil
+}
+
+// lookupUID returns the user ID for the given user name or ID, or -1 if
+// none is given.
+func lookupUID(owner string) (int, error) {
+	if owner == "" {
+		return -1, nil
+	}
+	if uid, err := strconv.Atoi(owner); err == nil {
+		return uid, nil
+	}
+	u, err := user.Lookup(owner)
+	if err != nil {
+		return 0, errs.New("unable to look up listen_socket_owner %q: %v", owner, err)
+	}
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return 0, errs.New("unable to look up listen_socket_owner %q: unexpected user ID %q", owner, u.Uid)
+	}
+	return uid, nil
+}
+
+// lookupGID returns the group ID for the given group name or ID, or -1 if
+// none is given.
+func lookupGID(group string) (int, error) {
+	if group == "" {
+		return -1, nil
+	}
+	if gid, err := strconv.Atoi(group); err == nil {
+		return gid, nil
+	}
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return 0, errs.New("unable to look up listen_socket_group %q: %v", group, err)
+	}
+	gid, err := strconv.Atoi(g.Gid)
+	if err != nil {
+		return 0, errs.New("unable to look up listen_socket_group %q: unexpected group ID %q", group, g.Gid)
+	}
+	return gid, nil
+}
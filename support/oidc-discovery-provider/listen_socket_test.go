@@ -0,0 +1,66 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenSocket(t *testing.T) {
+	dir := spiretest.TempDir(t)
+
+	t.Run("mode and ownership applied", func(t *testing.T) {
+		config := &Config{
+			ListenSocketPath:  filepath.Join(dir, "ok.sock"),
+			ListenSocketMode:  0660,
+			ListenSocketOwner: strconv.Itoa(os.Getuid()),
+			ListenSocketGroup: strconv.Itoa(os.Getgid()),
+		}
+
+		listener, err := listenSocket(config)
+		require.NoError(t, err)
+		defer listener.Close()
+
+		info, err := os.Stat(config.ListenSocketPath)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0660), info.Mode().Perm())
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		require.True(t, ok)
+		require.Equal(t, uint32(os.Getuid()), stat.Uid)
+		require.Equal(t, uint32(os.Getgid()), stat.Gid)
+	})
+
+	t.Run("unknown owner", func(t *testing.T) {
+		config := &Config{
+			ListenSocketPath:  filepath.Join(dir, "owner.sock"),
+			ListenSocketMode:  0660,
+			ListenSocketOwner: "no-such-user-for-oidc-test",
+		}
+
+		_, err := listenSocket(config)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `unable to look up listen_socket_owner "no-such-user-for-oidc-test"`)
+		require.NoFileExists(t, config.ListenSocketPath)
+	})
+
+	t.Run("unknown group", func(t *testing.T) {
+		config := &Config{
+			ListenSocketPath:  filepath.Join(dir, "group.sock"),
+			ListenSocketMode:  0660,
+			ListenSocketGroup: "no-such-group-for-oidc-test",
+		}
+
+		_, err := listenSocket(config)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `unable to look up listen_socket_group "no-such-group-for-oidc-test"`)
+		require.NoFileExists(t, config.ListenSocketPath)
+	})
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/andres-erbsen/clock"
+	"github.com/sirupsen/logrus"
+)
+
+// logHandler emits an access log entry for every request handled by the
+// handler.
+func logHandler(log logrus.FieldLogger, clk clock.Clock, handler http.Handler) http.Handler {
+	if clk == nil {
+		clk = clock.New()
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := clk.Now()
+		sw := &statusResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(sw, r)
+		log.WithFields(logrus.Fields{
+			"method":      r.Method,
+			"path":        r.URL.Path,
+			"status":      sw.status,
+			"duration":    clk.Now().Sub(start),
+			"remote-addr": r.RemoteAddr,
+			"user-agent":  r.UserAgent(),
+		}).Info("Handled request")
+	})
+}
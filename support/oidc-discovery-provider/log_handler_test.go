@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiffe/spire/test/clock"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogHandler(t *testing.T) {
+	log, hook := test.NewNullLogger()
+	clock := clock.NewMock(t)
+
+	handler := logHandler(log, clock, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clock.Add(time.Second)
+		http.Error(w, "not found", http.StatusNotFound)
+	}))
+
+	r := httptest.NewRequest("GET", "http://localhost/keys?foo=bar", nil)
+	r.RemoteAddr = "1.2.3.4:5678"
+	r.Header.Set("User-Agent", "test-agent")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	spiretest.AssertLogs(t, hook.AllEntries(), []spiretest.LogEntry{
+		{
+			Level:   logrus.InfoLevel,
+			Message: "Handled request",
+			Data: logrus.Fields{
+				"method":      "GET",
+				"path":        "/keys",
+				"status":      "404",
+				"duration":    "1s",
+				"remote-addr": "1.2.3.4:5678",
+				"user-agent":  "test-agent",
+			},
+		},
+	})
+}
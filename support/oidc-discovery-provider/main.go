@@ -1,14 +1,21 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"flag"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spiffe/spire/pkg/common/log"
+	"github.com/spiffe/spire/pkg/common/telemetry"
 	"github.com/zeebo/errs"
 	"golang.org/x/crypto/acme"
 	"golang.org/x/crypto/acme/autocert"
@@ -38,109 +45,375 @@ func run(configPath string) error {
 	}
 	defer log.Close()
 
-	source, err := newSource(log, config)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	metrics, err := telemetry.NewMetrics(&telemetry.MetricsConfig{
+		FileConfig:  config.Telemetry,
+		Logger:      log,
+		ServiceName: metricsServiceName,
+	})
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := metrics.ListenAndServe(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			log.WithError(err).Error("Metrics server failed")
+		}
+	}()
+	labeledMetrics := issuerMetrics(metrics, config)
+
+	source, err := newSource(log, config, labeledMetrics)
 	if err != nil {
 		return err
 	}
 	defer source.Close()
 
-	domainPolicy, err := DomainAllowlist(config.Domains...)
+	if config.HealthChecks != nil {
+		healthChecksServer, err := startHealthChecksServer(log, config.HealthChecks, source)
+		if err != nil {
+			return err
+		}
+		defer healthChecksServer.Close()
+	}
+
+	allowlist, err := DomainAllowlist(config.Domains...)
 	if err != nil {
 		return err
 	}
+	domainPolicy := NewDynamicDomainPolicy(allowlist)
+
+	reloader := NewReloader(log, configPath, config, domainPolicy, source)
+	go reloader.Run(ctx)
 
-	var handler http.Handler = NewHandler(domainPolicy, source, config.AllowInsecureScheme, config.SetKeyUse)
+	var x509Source X509AuthoritiesSource
+	if config.WorkloadAPI != nil && config.WorkloadAPI.FetchX509 {
+		x509Source, _ = source.(X509AuthoritiesSource)
+	}
+
+	var handler http.Handler = NewHandler(HandlerConfig{
+		DomainPolicy:        domainPolicy.Check,
+		Source:              source,
+		Issuer:              config.Issuer,
+		AllowInsecureScheme: config.AllowInsecureScheme,
+		SetKeyUse:           config.SetKeyUse,
+		PublishKeyUse:       config.PublishKeyUse,
+		JWKSCacheMaxAge:     config.JWKSCacheMaxAge,
+		AllowedOrigins:      config.AllowedOrigins,
+		DiscoveryDocument:   config.DiscoveryDocument,
+		ProtectedResource:   config.ProtectedResource,
+		Metrics:             labeledMetrics,
+		X509Source:          x509Source,
+		IDTokenSigningAlgs:  config.IDTokenSigningAlgs,
+		PathPrefix:          config.PathPrefix,
+	})
 	if config.LogRequests {
 		log.Info("Logging all requests")
-		handler = logHandler(log, handler)
+		handler = logHandler(log, nil, handler)
 	}
 
-	var listener net.Listener
+	// The unix socket listener can be served alongside one of the TCP
+	// listeners, so collect them all and serve the handler on each.
+	var listeners []net.Listener
+	defer func() {
+		for _, listener := range listeners {
+			if err := listener.Close(); err != nil {
+				log.Error(err)
+			}
+		}
+	}()
+
+	if config.ListenSocketPath != "" {
+		listener, err := listenSocket(config)
+		if err != nil {
+			return err
+		}
+		listeners = append(listeners, listener)
+		log.WithField("socket", config.ListenSocketPath).Info("Serving HTTP (unix)")
+	}
 
 	switch {
 	case config.InsecureAddr != "":
-		listener, err = net.Listen("tcp", config.InsecureAddr)
+		listener, err := listenTCP(config, config.InsecureAddr)
 		if err != nil {
 			return err
 		}
+		listeners = append(listeners, listener)
 		log.WithField("address", config.InsecureAddr).Warn("Serving HTTP (insecure)")
-	case config.ListenSocketPath != "":
-		_ = os.Remove(config.ListenSocketPath)
-
-		listener, err = net.Listen("unix", config.ListenSocketPath)
+	case config.ServingCertFile != nil:
+		certManager, err := NewCertManager(CertManagerConfig{
+			Log:              log,
+			CertFilePath:     config.ServingCertFile.CertFilePath,
+			KeyFilePath:      config.ServingCertFile.KeyFilePath,
+			FileSyncInterval: config.ServingCertFile.FileSyncInterval,
+		})
 		if err != nil {
 			return err
 		}
+		defer certManager.Close()
 
-		if err := os.Chmod(config.ListenSocketPath, os.ModePerm); err != nil {
+		tcpListener, err := listenTCP(config, config.ServingCertFile.Addr)
+		if err != nil {
 			return err
 		}
-
-		log.WithField("socket", config.ListenSocketPath).Info("Serving HTTP (unix)")
-	default:
-		listener = acmeListener(log, config)
+		listeners = append(listeners, tls.NewListener(tcpListener, &tls.Config{
+			GetCertificate: certManager.GetCertificate,
+			NextProtos:     []string{"h2", "http/1.1"},
+		}))
+		log.WithField("address", config.ServingCertFile.Addr).Info("Serving HTTPS via certificate file")
+	case len(config.ACME) > 0:
+		listener, err := acmeListener(ctx, log, config, domainPolicy.Check, reloader.Domains)
+		if err != nil {
+			return err
+		}
+		listeners = append(listeners, listener)
 		log.Info("Serving HTTPS via ACME")
 	}
 
-	defer func() {
-		err := listener.Close()
-		log.Error(err)
+	return serveListeners(listeners, handler)
+}
+
+// serveListeners serves the handler on all of the listeners until serving on
+// any of them fails.
+func serveListeners(listeners []net.Listener, handler http.Handler) error {
+	errCh := make(chan error, len(listeners))
+	for _, listener := range listeners {
+		go func(listener net.Listener) {
+			errCh <- http.Serve(listener, handler)
+		}(listener)
+	}
+	return <-errCh
+}
+
+func startHealthChecksServer(log logrus.FieldLogger, config *HealthChecksConfig, source JWKSSource) (*http.Server, error) {
+	addr := net.JoinHostPort(config.BindAddress, strconv.Itoa(config.BindPort))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &http.Server{
+		Handler: NewHealthChecksHandler(source, config.ReadyTimeout, nil),
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.WithError(err).Error("Health checks server failed")
+		}
 	}()
 
-	return http.Serve(listener, handler)
+	log.WithField("address", addr).Info("Serving health checks")
+	return server, nil
 }
 
-func newSource(log logrus.FieldLogger, config *Config) (JWKSSource, error) {
+func newSource(log logrus.FieldLogger, config *Config, metrics telemetry.Metrics) (JWKSSource, error) {
 	switch {
 	case config.ServerAPI != nil:
 		return NewServerAPISource(ServerAPISourceConfig{
 			Log:          log,
 			Address:      config.ServerAPI.Address,
 			PollInterval: config.ServerAPI.PollInterval,
+			Metrics:      metrics,
+			BackoffBase:  config.ServerAPI.BackoffBase,
+			BackoffMax:   config.ServerAPI.BackoffMax,
+
+			DialTimeout:      config.ServerAPI.DialTimeout,
+			KeepaliveTime:    config.ServerAPI.KeepaliveTime,
+			KeepaliveTimeout: config.ServerAPI.KeepaliveTimeout,
+			MaxJWKSSize:      config.ServerAPI.MaxJWKSSize,
+
+			TLS: config.ServerAPI.TLS,
 		})
 	case config.WorkloadAPI != nil:
 		return NewWorkloadAPISource(WorkloadAPISourceConfig{
 			Log:          log,
 			SocketPath:   config.WorkloadAPI.SocketPath,
 			PollInterval: config.WorkloadAPI.PollInterval,
-			TrustDomain:  config.WorkloadAPI.TrustDomain,
+			TrustDomains: config.WorkloadAPI.trustDomains(),
+			Metrics:      metrics,
+			BackoffBase:  config.WorkloadAPI.BackoffBase,
+			BackoffMax:   config.WorkloadAPI.BackoffMax,
+			FetchX509:    config.WorkloadAPI.FetchX509,
+			MaxJWKSSize:  config.WorkloadAPI.MaxJWKSSize,
 		})
+	case config.File != nil:
+		return NewFileSource(FileSourceConfig{
+			Log:          log,
+			Path:         config.File.Path,
+			PollInterval: config.File.PollInterval,
+			Metrics:      metrics,
+		}), nil
 	default:
 		// This is defensive; LoadConfig should prevent this from happening.
 		return nil, errs.New("no source has been configured")
 	}
 }
 
-func acmeListener(log logrus.FieldLogger, config *Config) net.Listener {
+// listenTCP listens on the TCP address, parsing the PROXY protocol header
+// of the accepted connections if configured.
+func listenTCP(config *Config, addr string) (net.Listener, error) {
+	listener, err := net.Listen(config.listenNetwork(), addr)
+	if err != nil {
+		return nil, err
+	}
+	if config.UseProxyProtocol {
+		listener = newProxyProtocolListener(listener)
+	}
+	return listener, nil
+}
+
+func acmeListener(ctx context.Context, log logrus.FieldLogger, config *Config, domainPolicy DomainPolicy, domains func() []string) (net.Listener, error) {
+	selector, err := newACMECertSelector(ctx, log, config, domainPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := listenTCP(config, ":443")
+	if err != nil {
+		return nil, err
+	}
+
+	go newACMERenewalMonitor(log, nil, selector, domains).Run(ctx)
+
+	return tls.NewListener(listener, &tls.Config{
+		GetCertificate: selector.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1", acme.ALPNProto},
+	}), nil
+}
+
+// acmeCertSelector picks the ACME manager responsible for the domain
+// requested via SNI. Domains without a dedicated manager are served by the
+// default manager, if any.
+type acmeCertSelector struct {
+	managers       map[string]acmeManager
+	defaultManager acmeManager
+}
+
+// acmeManager obtains certificates from an ACME CA for the domains allowed by
+// its host policy.
+type acmeManager interface {
+	GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error)
+	renewBefore() time.Duration
+}
+
+func newACMECertSelector(ctx context.Context, log logrus.FieldLogger, config *Config, domainPolicy DomainPolicy) (*acmeCertSelector, error) {
+	s := &acmeCertSelector{
+		managers: make(map[string]acmeManager),
+	}
+
+	dedicated := make(map[string]bool)
+	for _, acmeConfig := range config.ACME {
+		if acmeConfig.Domain != "" {
+			dedicated[acmeConfig.Domain] = true
+		}
+	}
+
+	// The default manager covers the domains without a dedicated manager.
+	// The policy is consulted on every request so that changes to the
+	// domains are honored when the configuration is reloaded.
+	defaultHostPolicy := func(_ context.Context, host string) error {
+		if dedicated[host] {
+			return errs.New("domain %q is served by a dedicated ACME configuration", host)
+		}
+		return domainPolicy(host)
+	}
+
+	for _, acmeConfig := range config.ACME {
+		if acmeConfig.Domain == "" {
+			m, err := newACMEManager(ctx, log, acmeConfig, defaultHostPolicy)
+			if err != nil {
+				return nil, err
+			}
+			s.defaultManager = m
+			continue
+		}
+		m, err := newACMEManager(ctx, log, acmeConfig, autocert.HostWhitelist(acmeConfig.Domain))
+		if err != nil {
+			return nil, err
+		}
+		s.managers[acmeConfig.Domain] = m
+	}
+	return s, nil
+}
+
+func (s *acmeCertSelector) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m := s.managerFor(hello.ServerName)
+	if m == nil {
+		return nil, errs.New("no certificate configured for domain %q", hello.ServerName)
+	}
+	return m.GetCertificate(hello)
+}
+
+// RenewBefore returns how long before expiration the certificate for the
+// domain is renewed.
+func (s *acmeCertSelector) RenewBefore(domain string) time.Duration {
+	if m := s.managerFor(domain); m != nil && m.renewBefore() > minACMERenewBefore {
+		return m.renewBefore()
+	}
+	return defaultACMERenewBefore
+}
+
+func (s *acmeCertSelector) managerFor(serverName string) acmeManager {
+	domain := strings.ToLower(strings.TrimSuffix(serverName, "."))
+	if m, ok := s.managers[domain]; ok {
+		return m
+	}
+	return s.defaultManager
+}
+
+func newACMEManager(ctx context.Context, log logrus.FieldLogger, acmeConfig *ACMEConfig, hostPolicy autocert.HostPolicy) (acmeManager, error) {
 	var cache autocert.Cache
-	if config.ACME.CacheDir != "" {
-		cache = autocert.DirCache(config.ACME.CacheDir)
-	}
-
-	m := autocert.Manager{
-		Cache: cache,
-		Client: &acme.Client{
-			UserAgent:    "SPIRE OIDC Discovery Provider",
-			DirectoryURL: config.ACME.DirectoryURL,
-		},
-		Email:      config.ACME.Email,
-		HostPolicy: autocert.HostWhitelist(config.Domains...),
-		Prompt: func(tosURL string) bool {
-			log.WithField("url", tosURL).Info("ACME Terms Of Service accepted")
-			return config.ACME.ToSAccepted
-		},
-	}
-	return m.Listener()
-}
-
-func logHandler(log logrus.FieldLogger, handler http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.WithFields(logrus.Fields{
-			"remote-addr": r.RemoteAddr,
-			"method":      r.Method,
-			"url":         r.URL,
-			"user-agent":  r.UserAgent,
-		}).Debug("Incoming request")
-		handler.ServeHTTP(w, r)
-	})
+	if acmeConfig.CacheDir != "" {
+		cache = autocert.DirCache(acmeConfig.CacheDir)
+	}
+
+	client := &acme.Client{
+		UserAgent:    "SPIRE OIDC Discovery Provider",
+		DirectoryURL: acmeConfig.DirectoryURL,
+	}
+	prompt := func(tosURL string) bool {
+		log.WithField("url", tosURL).Info("ACME Terms Of Service accepted")
+		return acmeConfig.ToSAccepted
+	}
+
+	if acmeConfig.ChallengeType != acmeChallengeDNS01 {
+		return autocertManager{&autocert.Manager{
+			Cache:       cache,
+			Client:      client,
+			Email:       acmeConfig.Email,
+			HostPolicy:  hostPolicy,
+			RenewBefore: acmeConfig.RenewBefore,
+			Prompt:      prompt,
+		}}, nil
+	}
+
+	provider, err := newDNSProvider(ctx, acmeConfig)
+	if err != nil {
+		return nil, err
+	}
+	client.Key, err = acmeAccountKey(ctx, cache)
+	if err != nil {
+		return nil, err
+	}
+	if client.DirectoryURL == "" {
+		client.DirectoryURL = autocert.DefaultACMEDirectory
+	}
+	return newDNS01Manager(dns01ManagerConfig{
+		Log:         log,
+		Client:      client,
+		Cache:       cache,
+		Email:       acmeConfig.Email,
+		Prompt:      prompt,
+		HostPolicy:  hostPolicy,
+		RenewBefore: acmeConfig.RenewBefore,
+		Provider:    provider,
+	}), nil
+}
+
+// autocertManager obtains certificates answering tls-alpn-01 challenges.
+type autocertManager struct {
+	*autocert.Manager
+}
+
+func (m autocertManager) renewBefore() time.Duration {
+	return m.RenewBefore
 }
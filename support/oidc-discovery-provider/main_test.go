@@ -0,0 +1,48 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeListeners(t *testing.T) {
+	listener1, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener1.Close()
+	listener2, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener2.Close()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	})
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- serveListeners([]net.Listener{listener1, listener2}, handler)
+	}()
+
+	// The handler is served on both listeners at the same time
+	for _, listener := range []net.Listener{listener1, listener2} {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+		require.Equal(t, "ok", string(body))
+	}
+
+	// Serving stops as soon as any of the listeners fails
+	require.NoError(t, listener1.Close())
+	select {
+	case err := <-errCh:
+		require.Error(t, err)
+	case <-time.After(time.Minute):
+		require.Fail(t, "timed out waiting for serving to stop")
+	}
+}
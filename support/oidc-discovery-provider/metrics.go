@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/telemetry"
+)
+
+const (
+	metricsServiceName = "spire_oidc_discovery_provider"
+)
+
+var (
+	// jwksRequestKey counts the requests for the JWKS, labeled by status code
+	jwksRequestKey = []string{"jwks", "request"}
+
+	// jwksKeysKey is a gauge with the number of keys in the key set
+	jwksKeysKey = []string{"jwks", "keys"}
+
+	// sourcePollKey measures the latency of polling the source
+	sourcePollKey = []string{"source", "poll"}
+
+	// sourcePollFailureKey counts the failures polling the source
+	sourcePollFailureKey = []string{"source", "poll", "failure"}
+)
+
+// issuerMetrics labels every metric with the issuer the provider is
+// configured for, so that the metrics of providers for different trust
+// domains scraped together can be told apart. The issuer is the configured
+// issuer URL or, when it is not set, the configured domains, since the issuer
+// is otherwise derived from the domain of each request.
+func issuerMetrics(metrics telemetry.Metrics, config *Config) telemetry.Metrics {
+	issuer := strings.Join(config.Domains, ",")
+	if config.Issuer != nil {
+		issuer = config.Issuer.String()
+	}
+	return telemetry.WithLabels(metrics, []telemetry.Label{{Name: "issuer", Value: issuer}})
+}
+
+// sourceMetrics emits the metrics for a key set source
+type sourceMetrics struct {
+	metrics telemetry.Metrics
+	labels  []telemetry.Label
+}
+
+func newSourceMetrics(metrics telemetry.Metrics, source string) sourceMetrics {
+	if metrics == nil {
+		metrics = telemetry.Blackhole{}
+	}
+	return sourceMetrics{
+		metrics: metrics,
+		labels:  []telemetry.Label{{Name: "source", Value: source}},
+	}
+}
+
+func (m sourceMetrics) MeasurePoll(start time.Time) {
+	m.metrics.MeasureSinceWithLabels(sourcePollKey, start, m.labels)
+}
+
+func (m sourceMetrics) IncrPollFailure() {
+	m.metrics.IncrCounterWithLabels(sourcePollFailureKey, 1, m.labels)
+}
+
+func (m sourceMetrics) SetKeyCount(count int) {
+	m.metrics.SetGaugeWithLabels(jwksKeysKey, float32(count), m.labels)
+}
+
+// countRequests counts the requests handled by the handler, labeled by the
+// response status code.
+func countRequests(metrics telemetry.Metrics, key []string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(sw, r)
+		metrics.IncrCounterWithLabels(key, 1, []telemetry.Label{
+			{Name: "status_code", Value: strconv.Itoa(sw.status)},
+		})
+	})
+}
+
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiffe/spire/pkg/common/telemetry"
+	"github.com/spiffe/spire/test/clock"
+	"github.com/spiffe/spire/test/fakes/fakemetrics"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func TestHandlerMetrics(t *testing.T) {
+	source := new(FakeKeySetSource)
+	metrics := fakemetrics.New()
+
+	h := NewHandler(HandlerConfig{
+		DomainPolicy: domainAllowlist(t, "localhost"),
+		Source:       source,
+		Metrics:      metrics,
+	})
+
+	serveKeys := func(method string) int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, "http://localhost/keys", nil))
+		return w.Code
+	}
+
+	// The key set is not available yet
+	require.Equal(t, http.StatusInternalServerError, serveKeys("GET"))
+
+	source.SetKeySet(&jose.JSONWebKeySet{}, time.Now())
+	require.Equal(t, http.StatusOK, serveKeys("GET"))
+	require.Equal(t, http.StatusMethodNotAllowed, serveKeys("POST"))
+
+	// The discovery document is not counted
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "http://localhost/.well-known/openid-configuration", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	assert.Equal(t, []fakemetrics.MetricItem{
+		requestMetric(http.StatusInternalServerError),
+		requestMetric(http.StatusOK),
+		requestMetric(http.StatusMethodNotAllowed),
+	}, metrics.AllMetrics())
+}
+
+func TestFileSourceMetrics(t *testing.T) {
+	const pollInterval = time.Second
+
+	path := filepath.Join(spiretest.TempDir(t), "jwks.json")
+
+	log, _ := test.NewNullLogger()
+	clock := clock.NewMock(t)
+	metrics := fakemetrics.New()
+
+	source := NewFileSource(FileSourceConfig{
+		Log:          log,
+		Path:         path,
+		PollInterval: pollInterval,
+		Clock:        clock,
+		Metrics:      metrics,
+	})
+	defer source.Close()
+
+	// The file does not exist yet, so the poll fails
+	clock.WaitForAfter(time.Minute, "failed to wait for the poll timer")
+	assert.Equal(t, []fakemetrics.MetricItem{
+		pollMetric(),
+		pollFailureMetric(),
+	}, metrics.AllMetrics())
+
+	// Write the key set and assert the key count is reported
+	metrics.Reset()
+	require.NoError(t, os.WriteFile(path, makeJWKS(t, &jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{
+			{
+				KeyID: "KID1",
+				Key:   ec256Pubkey,
+			},
+			{
+				KeyID: "KID2",
+				Key:   ec256Pubkey,
+			},
+		},
+	}), 0600))
+	clock.Add(pollInterval)
+	clock.WaitForAfter(time.Minute, "failed to wait for the poll timer")
+	assert.Equal(t, []fakemetrics.MetricItem{
+		pollMetric(),
+		keyCountMetric(2),
+	}, metrics.AllMetrics())
+}
+
+func TestPrometheusMetricsEndpoint(t *testing.T) {
+	log, _ := test.NewNullLogger()
+	port := freePort(t)
+
+	// The Prometheus sink registers with the default registry, so use a
+	// fresh one to allow the test to run more than once.
+	registry := prometheus.NewRegistry()
+	defaultRegisterer, defaultGatherer := prometheus.DefaultRegisterer, prometheus.DefaultGatherer
+	prometheus.DefaultRegisterer, prometheus.DefaultGatherer = registry, registry
+	defer func() {
+		prometheus.DefaultRegisterer, prometheus.DefaultGatherer = defaultRegisterer, defaultGatherer
+	}()
+
+	metrics, err := telemetry.NewMetrics(&telemetry.MetricsConfig{
+		FileConfig: telemetry.FileConfig{
+			Prometheus: &telemetry.PrometheusConfig{
+				Host: "localhost",
+				Port: port,
+			},
+		},
+		Logger:      log,
+		ServiceName: metricsServiceName,
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- metrics.ListenAndServe(ctx)
+	}()
+	defer func() {
+		cancel()
+		err := <-errCh
+		if err != nil && !errors.Is(err, context.Canceled) {
+			t.Errorf("unexpected error serving metrics: %v", err)
+		}
+	}()
+
+	sourceMetrics := newSourceMetrics(metrics, "file")
+	sourceMetrics.MeasurePoll(time.Now())
+	sourceMetrics.IncrPollFailure()
+	sourceMetrics.SetKeyCount(3)
+
+	source := new(FakeKeySetSource)
+	source.SetKeySet(&jose.JSONWebKeySet{}, time.Now())
+	h := NewHandler(HandlerConfig{
+		DomainPolicy: domainAllowlist(t, "localhost"),
+		Source:       source,
+		Metrics:      metrics,
+	})
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "http://localhost/keys", nil))
+
+	var body string
+	require.Eventually(t, func() bool {
+		resp, err := http.Get("http://" + net.JoinHostPort("localhost", strconv.Itoa(port)) + "/metrics")
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil || resp.StatusCode != http.StatusOK {
+			return false
+		}
+		body = string(b)
+		return true
+	}, time.Minute, 50*time.Millisecond, "failed to scrape the metrics endpoint")
+
+	assert.Contains(t, body, `spire_oidc_discovery_provider_jwks_request{`)
+	assert.Contains(t, body, `status_code="200"`)
+	assert.Contains(t, body, `spire_oidc_discovery_provider_jwks_keys{`)
+	assert.Contains(t, body, `spire_oidc_discovery_provider_source_poll{`)
+	assert.Contains(t, body, `spire_oidc_discovery_provider_source_poll_failure{`)
+	assert.Contains(t, body, `source="file"`)
+}
+
+func TestIssuerMetrics(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		config       *Config
+		expectIssuer string
+	}{
+		{
+			name:         "configured issuer",
+			config:       &Config{Domains: []string{"localhost"}, Issuer: &url.URL{Scheme: "https", Host: "issuer.example.org"}},
+			expectIssuer: "https_issuer_example_org",
+		},
+		{
+			name:         "configured domains",
+			config:       &Config{Domains: []string{"localhost", "domain.test"}},
+			expectIssuer: "localhost_domain_test",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := fakemetrics.New()
+			issuerLabel := telemetry.Label{Name: "issuer", Value: tt.expectIssuer}
+
+			source := new(FakeKeySetSource)
+			source.SetKeySet(&jose.JSONWebKeySet{}, time.Now())
+			h := NewHandler(HandlerConfig{
+				DomainPolicy: domainAllowlist(t, "localhost"),
+				Source:       source,
+				Metrics:      issuerMetrics(metrics, tt.config),
+			})
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "http://localhost/keys", nil))
+
+			newSourceMetrics(issuerMetrics(metrics, tt.config), "file").SetKeyCount(1)
+
+			assert.Equal(t, []fakemetrics.MetricItem{
+				{
+					Type:   fakemetrics.IncrCounterWithLabelsType,
+					Key:    jwksRequestKey,
+					Val:    1,
+					Labels: []telemetry.Label{issuerLabel, {Name: "status_code", Value: "200"}},
+				},
+				{
+					Type:   fakemetrics.SetGaugeWithLabelsType,
+					Key:    jwksKeysKey,
+					Val:    1,
+					Labels: []telemetry.Label{issuerLabel, {Name: "source", Value: "file"}},
+				},
+			}, metrics.AllMetrics())
+		})
+	}
+}
+
+func requestMetric(statusCode int) fakemetrics.MetricItem {
+	return fakemetrics.MetricItem{
+		Type:   fakemetrics.IncrCounterWithLabelsType,
+		Key:    jwksRequestKey,
+		Val:    1,
+		Labels: []telemetry.Label{{Name: "status_code", Value: strconv.Itoa(statusCode)}},
+	}
+}
+
+func pollMetric() fakemetrics.MetricItem {
+	return fakemetrics.MetricItem{
+		Type:   fakemetrics.MeasureSinceWithLabelsType,
+		Key:    sourcePollKey,
+		Labels: []telemetry.Label{{Name: "source", Value: "file"}},
+	}
+}
+
+func pollFailureMetric() fakemetrics.MetricItem {
+	return fakemetrics.MetricItem{
+		Type:   fakemetrics.IncrCounterWithLabelsType,
+		Key:    sourcePollFailureKey,
+		Val:    1,
+		Labels: []telemetry.Label{{Name: "source", Value: "file"}},
+	}
+}
+
+func keyCountMetric(count int) fakemetrics.MetricItem {
+	return fakemetrics.MetricItem{
+		Type:   fakemetrics.SetGaugeWithLabelsType,
+		Key:    jwksKeysKey,
+		Val:    float32(count),
+		Labels: []telemetry.Label{{Name: "source", Value: "file"}},
+	}
+}
+
+func freePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zeebo/errs"
+)
+
+const (
+	// proxyProtocolHeaderTimeout bounds how long a connection may take to
+	// send its PROXY protocol header.
+	proxyProtocolHeaderTimeout = 10 * time.Second
+
+	// proxyProtocolV1MaxLength is the maximum length of a v1 header,
+	// including the trailing CRLF.
+	proxyProtocolV1MaxLength = 107
+)
+
+var proxyProtocolV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// proxyProtocolListener wraps a TCP listener whose connections are prefixed
+// with a PROXY protocol (v1 or v2) header by a load balancer. The client
+// address carried by the header is returned as the remote address of the
+// connection, so it is seen by the handlers and in the access logs.
+// Connections without a valid header are rejected.
+type proxyProtocolListener struct {
+	net.Listener
+}
+
+func newProxyProtocolListener(listener net.Listener) net.Listener {
+	return &proxyProtocolListener{Listener: listener}
+}
+
+func (l *proxyProtocolListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &proxyProtocolConn{
+		Conn:   conn,
+		reader: bufio.NewReader(conn),
+	}, nil
+}
+
+// proxyProtocolConn reads the PROXY protocol header on the first call to
+// Read or RemoteAddr instead of in Accept, so that a slow client does not
+// hold up accepting other connections. The connection is closed if the
+// header is missing or invalid, so that nothing is sent to the peer.
+type proxyProtocolConn struct {
+	net.Conn
+	reader *bufio.Reader
+
+	once       sync.Once
+	remoteAddr net.Addr
+	err        error
+}
+
+func (c *proxyProtocolConn) Read(b []byte) (int, error) {
+	if err := c.readHeader(); err != nil {
+		return 0, err
+	}
+	return c.reader.Read(b)
+}
+
+// RemoteAddr returns the client address from the PROXY protocol header.
+// The address of the peer is returned when the header does not carry one
+// (e.g. health checks sent by the load balancer itself) or is invalid.
+func (c *proxyProtocolConn) RemoteAddr() net.Addr {
+	if err := c.readHeader(); err == nil && c.remoteAddr != nil {
+		return c.remoteAddr
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *proxyProtocolConn) readHeader() error {
+	c.once.Do(func() {
+		if err := c.Conn.SetReadDeadline(time.Now().Add(proxyProtocolHeaderTimeout)); err != nil {
+			c.err = err
+			return
+		}
+		c.remoteAddr, c.err = readProxyProtocolHeader(c.reader)
+		if err := c.Conn.SetReadDeadline(time.Time{}); err != nil && c.err == nil {
+			c.err = err
+		}
+		if c.err != nil {
+			c.Conn.Close()
+		}
+	})
+	return c.err
+}
+
+// readProxyProtocolHeader reads a v1 or v2 PROXY protocol header. The
+// returned address is nil if the header does not carry a client address.
+func readProxyProtocolHeader(r *bufio.Reader) (net.Addr, error) {
+	signature, err := r.Peek(len(proxyProtocolV2Signature))
+	if err != nil {
+		return nil, errs.New("unable to read PROXY protocol header: %v", err)
+	}
+	switch {
+	case bytes.Equal(signature, proxyProtocolV2Signature):
+		return readProxyProtocolV2Header(r)
+	case bytes.HasPrefix(signature, []byte("PROXY ")):
+		return readProxyProtocolV1Header(r)
+	default:
+		return nil, errs.New("missing PROXY protocol header")
+	}
+}
+
+// readProxyProtocolV1Header reads a human-readable header, e.g.
+// "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n".
+func readProxyProtocolV1Header(r *bufio.Reader) (net.Addr, error) {
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		return nil, errs.New("unable to read PROXY protocol v1 header: %v", err)
+	}
+	if len(line) > proxyProtocolV1MaxLength || !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, errs.New("invalid PROXY protocol v1 header")
+	}
+
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, errs.New("invalid PROXY protocol v1 header")
+	}
+	ip := net.ParseIP(fields[2])
+	if ip == nil {
+		return nil, errs.New("invalid PROXY protocol v1 source address %q", fields[2])
+	}
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, errs.New("invalid PROXY protocol v1 source port %q", fields[4])
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// readProxyProtocolV2Header reads a binary header: the signature, the
+// version and command, the address family and protocol, the length of the
+// addresses, and the addresses themselves.
+func readProxyProtocolV2Header(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, len(proxyProtocolV2Signature)+4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, errs.New("unable to read PROXY protocol v2 header: %v", err)
+	}
+	versionCommand := header[12]
+	family := header[13]
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, errs.New("unable to read PROXY protocol v2 header: %v", err)
+	}
+
+	if version := versionCommand >> 4; version != 2 {
+		return nil, errs.New("unsupported PROXY protocol version %d", version)
+	}
+	switch versionCommand & 0xF {
+	case 0x0:
+		// LOCAL connections are established by the proxy itself (e.g. for
+		// health checks) and carry no client address.
+		return nil, nil
+	case 0x1:
+	default:
+		return nil, errs.New("unsupported PROXY protocol v2 command %d", versionCommand&0xF)
+	}
+
+	switch family {
+	case 0x11: // TCP over IPv4
+		if len(payload) < 12 {
+			return nil, errs.New("invalid PROXY protocol v2 header: addresses too short")
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:4]), Port: int(binary.BigEndian.Uint16(payload[8:10]))}, nil
+	case 0x21: // TCP over IPv6
+		if len(payload) < 36 {
+			return nil, errs.New("invalid PROXY protocol v2 header: addresses too short")
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:16]), Port: int(binary.BigEndian.Uint16(payload[32:34]))}, nil
+	default:
+		// Other families (e.g. UDP or unix sockets) carry no TCP client
+		// address.
+		return nil, nil
+	}
+}
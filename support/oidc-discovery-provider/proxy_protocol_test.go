@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyProtocolListener(t *testing.T) {
+	addr := serveProxyProtocol(t)
+
+	t.Run("v2 TCP over IPv4", func(t *testing.T) {
+		header := proxyProtocolV2Header(0x1, 0x11, tcp4Addresses(net.ParseIP("192.0.2.1"), net.ParseIP("198.51.100.1"), 56324, 443))
+		remoteAddr, err := proxyProtocolRoundTrip(addr, header)
+		require.NoError(t, err)
+		require.Equal(t, "192.0.2.1:56324", remoteAddr)
+	})
+
+	t.Run("v2 TCP over IPv6", func(t *testing.T) {
+		addresses := make([]byte, 36)
+		copy(addresses[0:16], net.ParseIP("2001:db8::1"))
+		copy(addresses[16:32], net.ParseIP("2001:db8::2"))
+		binary.BigEndian.PutUint16(addresses[32:34], 56324)
+		binary.BigEndian.PutUint16(addresses[34:36], 443)
+
+		remoteAddr, err := proxyProtocolRoundTrip(addr, proxyProtocolV2Header(0x1, 0x21, addresses))
+		require.NoError(t, err)
+		require.Equal(t, "[2001:db8::1]:56324", remoteAddr)
+	})
+
+	t.Run("v2 LOCAL", func(t *testing.T) {
+		remoteAddr, err := proxyProtocolRoundTrip(addr, proxyProtocolV2Header(0x0, 0x00, nil))
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(remoteAddr, "127.0.0.1:"), "expected the peer address, got %q", remoteAddr)
+	})
+
+	t.Run("v1 TCP over IPv4", func(t *testing.T) {
+		remoteAddr, err := proxyProtocolRoundTrip(addr, []byte("PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n"))
+		require.NoError(t, err)
+		require.Equal(t, "192.0.2.1:56324", remoteAddr)
+	})
+
+	t.Run("v1 UNKNOWN", func(t *testing.T) {
+		remoteAddr, err := proxyProtocolRoundTrip(addr, []byte("PROXY UNKNOWN\r\n"))
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(remoteAddr, "127.0.0.1:"), "expected the peer address, got %q", remoteAddr)
+	})
+
+	t.Run("missing header", func(t *testing.T) {
+		_, err := proxyProtocolRoundTrip(addr, nil)
+		require.Error(t, err)
+	})
+}
+
+func TestReadProxyProtocolHeader(t *testing.T) {
+	tcp4 := tcp4Addresses(net.ParseIP("192.0.2.1"), net.ParseIP("198.51.100.1"), 56324, 443)
+
+	for _, tt := range []struct {
+		name      string
+		header    []byte
+		expectErr string
+	}{
+		{
+			name:      "not a PROXY protocol header",
+			header:    []byte("GET / HTTP/1.1\r\n"),
+			expectErr: "missing PROXY protocol header",
+		},
+		{
+			name:      "v1 without CRLF",
+			header:    []byte("PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\n"),
+			expectErr: "invalid PROXY protocol v1 header",
+		},
+		{
+			name:      "v1 too long",
+			header:    []byte("PROXY TCP4 " + strings.Repeat("1", proxyProtocolV1MaxLength) + "\r\n"),
+			expectErr: "invalid PROXY protocol v1 header",
+		},
+		{
+			name:      "v1 missing fields",
+			header:    []byte("PROXY TCP4 192.0.2.1 198.51.100.1 56324\r\n"),
+			expectErr: "invalid PROXY protocol v1 header",
+		},
+		{
+			name:      "v1 invalid address",
+			header:    []byte("PROXY TCP4 192.0.2 198.51.100.1 56324 443\r\n"),
+			expectErr: `invalid PROXY protocol v1 source address "192.0.2"`,
+		},
+		{
+			name:      "v1 invalid port",
+			header:    []byte("PROXY TCP4 192.0.2.1 198.51.100.1 65536 443\r\n"),
+			expectErr: `invalid PROXY protocol v1 source port "65536"`,
+		},
+		{
+			name:      "v2 unsupported command",
+			header:    proxyProtocolV2Header(0x2, 0x11, tcp4),
+			expectErr: "unsupported PROXY protocol v2 command 2",
+		},
+		{
+			name:      "v2 truncated addresses",
+			header:    proxyProtocolV2Header(0x1, 0x11, tcp4[:8]),
+			expectErr: "invalid PROXY protocol v2 header: addresses too short",
+		},
+		{
+			name:      "v2 truncated header",
+			header:    proxyProtocolV2Header(0x1, 0x11, tcp4)[:20],
+			expectErr: "unable to read PROXY protocol v2 header: unexpected EOF",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readProxyProtocolHeader(bufio.NewReader(strings.NewReader(string(tt.header))))
+			require.EqualError(t, err, tt.expectErr)
+		})
+	}
+}
+
+// serveProxyProtocol serves a handler responding with the remote address of
+// the request on a listener expecting the PROXY protocol.
+func serveProxyProtocol(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	listener = newProxyProtocolListener(listener)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		_ = http.Serve(listener, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, r.RemoteAddr)
+		}))
+	}()
+	return listener.Addr().String()
+}
+
+// proxyProtocolRoundTrip sends a request prefixed with the given header and
+// returns the remote address seen by the handler.
+func proxyProtocolRoundTrip(addr string, header []byte) (string, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	request := "GET / HTTP/1.1\r\nHost: domain.test\r\nConnection: close\r\n\r\n"
+	if _, err := conn.Write(append(header, request...)); err != nil {
+		return "", err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+func proxyProtocolV2Header(command, family byte, addresses []byte) []byte {
+	header := append([]byte(nil), proxyProtocolV2Signature...)
+	header = append(header, 0x20|command, family, 0, 0)
+	binary.BigEndian.PutUint16(header[14:], uint16(len(addresses)))
+	return append(header, addresses...)
+}
+
+func tcp4Addresses(src, dst net.IP, srcPort, dstPort uint16) []byte {
+	addresses := make([]byte, 12)
+	copy(addresses[0:4], src.To4())
+	copy(addresses[4:8], dst.To4())
+	binary.BigEndian.PutUint16(addresses[8:10], srcPort)
+	binary.BigEndian.PutUint16(addresses[10:12], dstPort)
+	return addresses
+}
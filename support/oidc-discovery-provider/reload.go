@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/log"
+)
+
+// pollIntervalSetter is implemented by sources whose poll interval can be
+// changed at runtime.
+type pollIntervalSetter interface {
+	SetPollInterval(time.Duration)
+}
+
+// Reloader applies the reloadable subset of the configuration, i.e. the log
+// level, the domains and the source poll interval, without tearing down the
+// listener. Changes to any other configurable require a restart.
+type Reloader struct {
+	log          *log.Logger
+	configPath   string
+	domainPolicy *DynamicDomainPolicy
+	source       JWKSSource
+
+	// mu guards config, which is only replaced by Reload but is read
+	// concurrently through Domains.
+	mu     sync.RWMutex
+	config *Config
+}
+
+func NewReloader(log *log.Logger, configPath string, config *Config, domainPolicy *DynamicDomainPolicy, source JWKSSource) *Reloader {
+	return &Reloader{
+		log:          log,
+		configPath:   configPath,
+		config:       config,
+		domainPolicy: domainPolicy,
+		source:       source,
+	}
+}
+
+// Run reloads the configuration every time the process receives SIGHUP,
+// until the context is canceled.
+func (r *Reloader) Run(ctx context.Context) {
+	signalCh := make(chan os.Signal, 1)
+	signal.Notify(signalCh, syscall.SIGHUP)
+	defer signal.Stop(signalCh)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signalCh:
+			if err := r.Reload(); err != nil {
+				r.log.WithError(err).Error("Failed to reload configuration; continuing with the previous configuration")
+				continue
+			}
+			r.log.Info("Configuration reloaded")
+		}
+	}
+}
+
+// Reload loads the configuration from disk and applies it. If the
+// configuration fails to load or validate, nothing is applied.
+func (r *Reloader) Reload() error {
+	config, err := LoadConfig(r.configPath)
+	if err != nil {
+		return err
+	}
+
+	domainPolicy, err := DomainAllowlist(config.Domains...)
+	if err != nil {
+		return err
+	}
+
+	if config.sourceName() != r.config.sourceName() {
+		r.log.Warn("Changing the source requires a restart; the source poll interval will not be reloaded")
+	} else if setter, ok := r.source.(pollIntervalSetter); ok {
+		setter.SetPollInterval(config.pollInterval())
+	}
+
+	if err := log.WithLevel(config.LogLevel)(r.log); err != nil {
+		return err
+	}
+	r.domainPolicy.Set(domainPolicy)
+
+	r.mu.Lock()
+	r.config = config
+	r.mu.Unlock()
+	return nil
+}
+
+// Domains returns the domains of the configuration currently applied.
+func (r *Reloader) Domains() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.config.Domains
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/spire/pkg/common/log"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloader(t *testing.T) {
+	dir := spiretest.TempDir(t)
+	configPath := filepath.Join(dir, "test.conf")
+
+	writeConfig := func(config string) {
+		require.NoError(t, os.WriteFile(configPath, []byte(config), 0600))
+	}
+
+	writeConfig(`
+		log_level = "info"
+		domains = ["domain.test"]
+		insecure_addr = ":8080"
+		server_api {
+			address = "unix:///some/socket/path"
+		}
+	`)
+	config, err := LoadConfig(configPath)
+	require.NoError(t, err)
+
+	logger, err := log.NewLogger(log.WithLevel(config.LogLevel))
+	require.NoError(t, err)
+
+	domainPolicy := NewDynamicDomainPolicy(domainAllowlist(t, config.Domains...))
+	source := new(fakePollIntervalSource)
+	reloader := NewReloader(logger, configPath, config, domainPolicy, source)
+
+	// Reload a configuration that changes all of the reloadable fields
+	writeConfig(`
+		log_level = "debug"
+		domains = ["other.domain.test"]
+		insecure_addr = ":8080"
+		server_api {
+			address = "unix:///some/socket/path"
+			poll_interval = "1m"
+		}
+	`)
+	require.NoError(t, reloader.Reload())
+	assert.Equal(t, logrus.DebugLevel, logger.GetLevel())
+	assert.EqualError(t, domainPolicy.Check("domain.test"), `domain "domain.test" is not allowed`)
+	assert.NoError(t, domainPolicy.Check("other.domain.test"))
+	assert.Equal(t, time.Minute, source.getPollInterval())
+
+	// Reload an invalid configuration and assert nothing changed
+	writeConfig(`
+		log_level = "warn"
+		domains = ["domain.test"]
+		server_api {
+			address = "unix:///some/socket/path"
+			poll_interval = "1h"
+		}
+	`)
+	require.EqualError(t, reloader.Reload(), "either acme, serving_cert_file, insecure_addr or listen_socket_path must be configured")
+	assert.Equal(t, logrus.DebugLevel, logger.GetLevel())
+	assert.NoError(t, domainPolicy.Check("other.domain.test"))
+	assert.Equal(t, time.Minute, source.getPollInterval())
+
+	// Reload a configuration that changes the source and assert the poll
+	// interval is not applied.
+	writeConfig(`
+		log_level = "debug"
+		domains = ["other.domain.test"]
+		insecure_addr = ":8080"
+		workload_api {
+			socket_path = "/some/socket/path"
+			trust_domain = "domain.test"
+			poll_interval = "1h"
+		}
+	`)
+	require.NoError(t, reloader.Reload())
+	assert.Equal(t, time.Minute, source.getPollInterval())
+}
+
+type fakePollIntervalSource struct {
+	FakeKeySetSource
+
+	mu           sync.Mutex
+	pollInterval time.Duration
+}
+
+func (s *fakePollIntervalSource) SetPollInterval(pollInterval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pollInterval = pollInterval
+}
+
+func (s *fakePollIntervalSource) getPollInterval() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pollInterval
+}
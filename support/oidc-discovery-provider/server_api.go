@@ -3,22 +3,38 @@ package main
 import (
 	"context"
 	"crypto/x509"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/andres-erbsen/clock"
 	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	"github.com/spiffe/go-spiffe/v2/spiffetls/tlsconfig"
+	"github.com/spiffe/go-spiffe/v2/workloadapi"
 	bundlev1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/bundle/v1"
 	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
+	"github.com/spiffe/spire/pkg/common/telemetry"
 	"github.com/zeebo/errs"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/protobuf/proto"
 	"gopkg.in/square/go-jose.v2"
 )
 
 const (
 	DefaultServerAPIPollInterval = time.Second * 10
+
+	// DefaultServerAPIDialTimeout is the default time a poll may take,
+	// including establishing the connection. It matches the time gRPC waits
+	// for a connection attempt by default.
+	DefaultServerAPIDialTimeout = time.Second * 20
+
+	serverAPITCPScheme = "tcp://"
 )
 
 type ServerAPISourceConfig struct {
@@ -26,47 +42,156 @@ type ServerAPISourceConfig struct {
 	Address      string
 	PollInterval time.Duration
 	Clock        clock.Clock
+	Metrics      telemetry.Metrics
+
+	// BackoffBase and BackoffMax control the delay before polling again
+	// after consecutive failures. BackoffMax defaults to the poll interval.
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+
+	// DialTimeout bounds how long each poll may take, including
+	// establishing the connection to the Server API, so that an unresponsive
+	// Server API fails the poll instead of stalling polling. Defaults to
+	// DefaultServerAPIDialTimeout when zero.
+	DialTimeout time.Duration
+
+	// KeepaliveTime and KeepaliveTimeout control the keepalive pings used
+	// to detect broken connections while a request is outstanding. Pings are
+	// not sent when KeepaliveTime is zero. The gRPC default timeout is used
+	// when KeepaliveTimeout is zero.
+	KeepaliveTime    time.Duration
+	KeepaliveTimeout time.Duration
+
+	// MaxJWKSSize caps the size, in bytes, of the bundle received from the
+	// Server API. Larger responses are rejected before being buffered. The
+	// gRPC default is used when zero.
+	MaxJWKSSize int
+
+	// TLS, when set, authenticates the connection to the Server API with
+	// mTLS. Connections are not secured otherwise, which is only suitable
+	// for unix addresses.
+	TLS *ServerAPITLSConfig
 }
 
 type ServerAPISource struct {
-	log    logrus.FieldLogger
-	clock  clock.Clock
-	cancel context.CancelFunc
+	log        logrus.FieldLogger
+	clock      clock.Clock
+	metrics    sourceMetrics
+	cancel     context.CancelFunc
+	x509Source *workloadapi.X509Source
 
 	mu      sync.RWMutex
 	wg      sync.WaitGroup
 	bundle  *types.Bundle
 	jwks    *jose.JSONWebKeySet
 	modTime time.Time
+
+	lastSuccessfulPoll time.Time
+	pollInterval       time.Duration
+	dialTimeout        time.Duration
+	backoff            *pollBackoff
 }
 
 func NewServerAPISource(config ServerAPISourceConfig) (*ServerAPISource, error) {
 	if config.PollInterval <= 0 {
 		config.PollInterval = DefaultServerAPIPollInterval
 	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = DefaultServerAPIDialTimeout
+	}
 	if config.Clock == nil {
 		config.Clock = clock.New()
 	}
 
-	conn, err := grpc.Dial(config.Address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	creds := insecure.NewCredentials()
+	var x509Source *workloadapi.X509Source
+	if config.TLS != nil {
+		var err error
+		creds, x509Source, err = serverAPITLSCredentials(config.TLS)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	conn, err := grpc.Dial(strings.TrimPrefix(config.Address, serverAPITCPScheme), serverAPIDialOptions(config, creds)...)
 	if err != nil {
+		if x509Source != nil {
+			x509Source.Close()
+		}
 		return nil, errs.Wrap(err)
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	s := &ServerAPISource{
-		log:    config.Log,
-		clock:  config.Clock,
-		cancel: cancel,
+		log:          config.Log,
+		clock:        config.Clock,
+		metrics:      newSourceMetrics(config.Metrics, "server_api"),
+		cancel:       cancel,
+		x509Source:   x509Source,
+		pollInterval: config.PollInterval,
+		dialTimeout:  config.DialTimeout,
+		backoff:      newPollBackoff(config.BackoffBase, config.BackoffMax),
 	}
 
-	go s.pollEvery(ctx, conn, config.PollInterval)
+	s.wg.Add(1)
+	go s.pollEvery(ctx, conn)
 	return s, nil
 }
 
+// serverAPITLSCredentials returns mTLS credentials that present the X509-SVID
+// obtained from the Workload API and authenticate the SPIRE Server by its
+// SPIFFE ID. It blocks until the Workload API provides an X509-SVID.
+func serverAPITLSCredentials(config *ServerAPITLSConfig) (credentials.TransportCredentials, *workloadapi.X509Source, error) {
+	x509Source, err := workloadapi.NewX509Source(context.Background(),
+		workloadapi.WithClientOptions(workloadapi.WithAddr("unix://"+config.WorkloadAPISocketPath)))
+	if err != nil {
+		return nil, nil, errs.New("unable to obtain X509-SVID from the Workload API: %v", err)
+	}
+
+	var bundleSource x509bundle.Source = x509Source
+	if config.BundlePath != "" {
+		bundle, err := spiffebundle.Load(config.ServerID.TrustDomain(), config.BundlePath)
+		if err != nil {
+			x509Source.Close()
+			return nil, nil, errs.New("unable to load server bundle: %v", err)
+		}
+		bundleSource = bundle
+	}
+
+	tlsConfig := tlsconfig.MTLSClientConfig(x509Source, bundleSource, tlsconfig.AuthorizeID(config.ServerID))
+	return credentials.NewTLS(tlsConfig), x509Source, nil
+}
+
+func serverAPIDialOptions(config ServerAPISourceConfig, creds credentials.TransportCredentials) []grpc.DialOption {
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+	}
+	if config.DialTimeout > 0 {
+		// Polls are bounded by the dial timeout, so let connection attempts
+		// last as long as a poll instead of the gRPC default.
+		dialOpts = append(dialOpts, grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           backoff.DefaultConfig,
+			MinConnectTimeout: config.DialTimeout,
+		}))
+	}
+	if config.KeepaliveTime > 0 {
+		dialOpts = append(dialOpts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    config.KeepaliveTime,
+			Timeout: config.KeepaliveTimeout,
+		}))
+	}
+	if config.MaxJWKSSize > 0 {
+		dialOpts = append(dialOpts, grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(config.MaxJWKSSize)))
+	}
+	return dialOpts
+}
+
 func (s *ServerAPISource) Close() error {
 	s.cancel()
 	s.wg.Wait()
+	if s.x509Source != nil {
+		return s.x509Source.Close()
+	}
 	return nil
 }
 
@@ -79,41 +204,81 @@ func (s *ServerAPISource) FetchKeySet() (*jose.JSONWebKeySet, time.Time, bool) {
 	return s.jwks, s.modTime, true
 }
 
-func (s *ServerAPISource) pollEvery(ctx context.Context, conn *grpc.ClientConn, interval time.Duration) {
-	s.wg.Add(1)
+// SetPollInterval changes the poll interval. It takes effect after the
+// next poll.
+func (s *ServerAPISource) SetPollInterval(pollInterval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pollInterval = pollInterval
+}
+
+func (s *ServerAPISource) getPollInterval() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pollInterval
+}
+
+func (s *ServerAPISource) LastSuccessfulPoll() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastSuccessfulPoll
+}
+
+// pollEvery polls the Server API until the context is canceled. All polls
+// share the same connection, which gRPC reconnects as needed, and which is
+// closed once polling is done.
+func (s *ServerAPISource) pollEvery(ctx context.Context, conn *grpc.ClientConn) {
 	defer s.wg.Done()
 
 	defer conn.Close()
 	client := bundlev1.NewBundleClient(conn)
 
-	s.log.WithField("interval", interval).Debug("Polling started")
+	s.log.WithField("interval", s.getPollInterval()).Debug("Polling started")
 	for {
-		s.pollOnce(ctx, client)
+		next := s.getPollInterval()
+		if s.pollOnce(ctx, client) {
+			s.backoff.Reset()
+		} else {
+			next = s.backoff.Next(next)
+		}
 		select {
 		case <-ctx.Done():
 			s.log.WithError(ctx.Err()).Debug("Polling done")
 			return
-		case <-s.clock.After(interval):
+		case <-s.clock.After(next):
 		}
 	}
 }
 
-func (s *ServerAPISource) pollOnce(ctx context.Context, client bundlev1.BundleClient) {
-	// Ensure the stream gets cleaned up
-	ctx, cancel := context.WithCancel(ctx)
+func (s *ServerAPISource) pollOnce(ctx context.Context, client bundlev1.BundleClient) bool {
+	// Bound the poll, including waiting for the connection, so that an
+	// unresponsive Server API does not stall polling. This also ensures the
+	// stream gets cleaned up.
+	ctx, cancel := context.WithTimeout(ctx, s.dialTimeout)
 	defer cancel()
 
+	start := time.Now()
 	bundle, err := client.GetBundle(ctx, &bundlev1.GetBundleRequest{
 		OutputMask: &types.BundleMask{
 			JwtAuthorities: true,
 		},
 	})
+	s.metrics.MeasurePoll(start)
 	if err != nil {
+		s.metrics.IncrPollFailure()
 		s.log.WithError(err).Warn("Failed to fetch bundle")
-		return
+		return false
 	}
 
 	s.parseBundle(bundle)
+	s.setLastSuccessfulPoll()
+	return true
+}
+
+func (s *ServerAPISource) setLastSuccessfulPoll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastSuccessfulPoll = s.clock.Now()
 }
 
 func (s *ServerAPISource) parseBundle(bundle *types.Bundle) {
@@ -141,7 +306,9 @@ func (s *ServerAPISource) parseBundle(bundle *types.Bundle) {
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	logKeySetChanges(s.log, s.jwks, jwks)
 	s.bundle = bundle
 	s.jwks = jwks
 	s.modTime = s.clock.Now()
+	s.metrics.SetKeyCount(len(jwks.Keys))
 }
@@ -2,11 +2,14 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"net"
 	"runtime"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/sirupsen/logrus"
 	"github.com/sirupsen/logrus/hooks/test"
 	bundlev1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/bundle/v1"
 	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
@@ -15,6 +18,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/stats"
 	"google.golang.org/grpc/status"
 )
 
@@ -45,13 +49,14 @@ func TestServerAPISource(t *testing.T) {
 	defer source.Close()
 
 	// Wait for the poll to happen and assert there is no key set available
-	clock.WaitForAfter(time.Minute, "failed to wait for the poll timer")
+	retryAfter := waitForBackoff(t, clock, pollInterval)
 	_, _, ok := source.FetchKeySet()
 	require.False(t, ok, "No bundle was available but we have a keyset somehow")
 	require.Equal(t, 1, api.GetBundleCount())
+	require.True(t, source.LastSuccessfulPoll().IsZero(), "No bundle was available but the poll was successful somehow")
 
-	// Add a bundle, step forward past the poll interval, wait for polling,
-	// and assert we have a keyset.
+	// Add a bundle, step forward past the backoff, wait for polling, and
+	// assert we have a keyset.
 	api.SetBundle(&types.Bundle{
 		JwtAuthorities: []*types.JWTKey{
 			{
@@ -60,12 +65,13 @@ func TestServerAPISource(t *testing.T) {
 			},
 		},
 	})
-	clock.Add(pollInterval)
+	clock.Add(retryAfter)
 	clock.WaitForAfter(time.Minute, "failed to wait for the poll timer")
 	require.Equal(t, 2, api.GetBundleCount())
 	keySet1, modTime1, ok := source.FetchKeySet()
 	require.True(t, ok)
 	require.Equal(t, clock.Now(), modTime1)
+	require.Equal(t, clock.Now(), source.LastSuccessfulPoll())
 	require.NotNil(t, keySet1)
 	require.Len(t, keySet1.Keys, 1)
 	require.Equal(t, "KID", keySet1.Keys[0].KeyID)
@@ -103,6 +109,200 @@ func TestServerAPISource(t *testing.T) {
 	require.Equal(t, ec256Pubkey, keySet3.Keys[0].Key)
 }
 
+func TestServerAPISourceMaxJWKSSize(t *testing.T) {
+	// TODO: workload source is not supported on windows until we solve workload API
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+
+	const pollInterval = time.Second
+
+	// Publish a bundle with many keys, well over the configured cap
+	var jwtAuthorities []*types.JWTKey
+	for i := 0; i < 100; i++ {
+		jwtAuthorities = append(jwtAuthorities, &types.JWTKey{
+			KeyId:     fmt.Sprintf("KID%d", i),
+			PublicKey: ec256PubkeyPKIX,
+		})
+	}
+	api := &fakeServerAPIServer{}
+	api.SetBundle(&types.Bundle{JwtAuthorities: jwtAuthorities})
+
+	socketPath := spiretest.StartGRPCSocketServerOnTempSocket(t, func(s *grpc.Server) {
+		bundlev1.RegisterBundleServer(s, api)
+	})
+
+	log, hook := test.NewNullLogger()
+	clock := clock.NewMock(t)
+
+	source, err := NewServerAPISource(ServerAPISourceConfig{
+		Log:          log,
+		Address:      "unix://" + socketPath,
+		PollInterval: pollInterval,
+		Clock:        clock,
+		MaxJWKSSize:  4096,
+	})
+	require.NoError(t, err)
+	defer source.Close()
+
+	// The poll fails since the bundle exceeds the cap
+	waitForBackoff(t, clock, pollInterval)
+	_, _, ok := source.FetchKeySet()
+	require.False(t, ok, "The bundle exceeds the cap but we have a keyset somehow")
+	require.Equal(t, 1, api.GetBundleCount())
+	require.True(t, source.LastSuccessfulPoll().IsZero(), "The bundle exceeds the cap but the poll was successful somehow")
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	require.Equal(t, "Failed to fetch bundle", entry.Message)
+	err, _ = entry.Data[logrus.ErrorKey].(error)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestServerAPISourceDialTimeout(t *testing.T) {
+	const pollInterval = time.Second
+
+	// Accept connections but never complete the HTTP/2 handshake, like a
+	// Server API that stopped responding.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+
+	log, hook := test.NewNullLogger()
+	clock := clock.NewMock(t)
+
+	start := time.Now()
+	source, err := NewServerAPISource(ServerAPISourceConfig{
+		Log:          log,
+		Address:      "tcp://" + listener.Addr().String(),
+		PollInterval: pollInterval,
+		Clock:        clock,
+		DialTimeout:  100 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer source.Close()
+
+	// The poll fails once the dial timeout elapses
+	waitForBackoff(t, clock, pollInterval)
+	require.Less(t, time.Since(start), 10*time.Second)
+	_, _, ok := source.FetchKeySet()
+	require.False(t, ok, "The Server API never responded but we have a keyset somehow")
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	require.Equal(t, "Failed to fetch bundle", entry.Message)
+	err, _ = entry.Data[logrus.ErrorKey].(error)
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
+
+func TestServerAPISourceReusesConnection(t *testing.T) {
+	// TODO: workload source is not supported on windows until we solve workload API
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+
+	const pollInterval = time.Second
+
+	api := &fakeServerAPIServer{}
+	api.SetBundle(&types.Bundle{
+		JwtAuthorities: []*types.JWTKey{
+			{
+				KeyId:     "KID",
+				PublicKey: ec256PubkeyPKIX,
+			},
+		},
+	})
+
+	conns := &connCounter{}
+	server := grpc.NewServer(grpc.StatsHandler(conns))
+	bundlev1.RegisterBundleServer(server, api)
+	socketPath := spiretest.ServeGRPCServerOnTempSocket(t, server)
+
+	log, _ := test.NewNullLogger()
+	clock := clock.NewMock(t)
+
+	source, err := NewServerAPISource(ServerAPISourceConfig{
+		Log:          log,
+		Address:      "unix://" + socketPath,
+		PollInterval: pollInterval,
+		Clock:        clock,
+	})
+	require.NoError(t, err)
+
+	// Poll several times. All of the polls go over the same connection.
+	clock.WaitForAfter(time.Minute, "failed to wait for the poll timer")
+	for i := 0; i < 4; i++ {
+		clock.Add(pollInterval)
+		clock.WaitForAfter(time.Minute, "failed to wait for the poll timer")
+	}
+	require.Equal(t, 5, api.GetBundleCount())
+	require.Equal(t, 1, conns.Total())
+
+	// The connection is closed when the source is closed.
+	require.NoError(t, source.Close())
+	require.Eventually(t, func() bool {
+		return conns.Open() == 0
+	}, time.Minute, 10*time.Millisecond, "connection was not closed")
+}
+
+// connCounter is a gRPC stats handler that counts the server connections.
+type connCounter struct {
+	stats.Handler
+
+	mu    sync.Mutex
+	total int
+	open  int
+}
+
+func (c *connCounter) TagConn(ctx context.Context, info *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (c *connCounter) HandleConn(ctx context.Context, s stats.ConnStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch s.(type) {
+	case *stats.ConnBegin:
+		c.total++
+		c.open++
+	case *stats.ConnEnd:
+		c.open--
+	}
+}
+
+func (c *connCounter) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (c *connCounter) HandleRPC(ctx context.Context, s stats.RPCStats) {}
+
+func (c *connCounter) Total() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.total
+}
+
+func (c *connCounter) Open() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.open
+}
+
 type fakeServerAPIServer struct {
 	bundlev1.BundleServer
 
@@ -3,7 +3,10 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto"
+	"crypto/x509"
 	"encoding/json"
+	"sort"
 	"sync"
 	"time"
 
@@ -14,6 +17,7 @@ import (
 	"github.com/spiffe/go-spiffe/v2/workloadapi"
 	"github.com/spiffe/spire/pkg/common/telemetry"
 	"github.com/zeebo/errs"
+	"google.golang.org/grpc"
 	"gopkg.in/square/go-jose.v2"
 )
 
@@ -24,22 +28,46 @@ const (
 type WorkloadAPISourceConfig struct {
 	Log          logrus.FieldLogger
 	SocketPath   string
-	TrustDomain  string
+	TrustDomains []string
 	PollInterval time.Duration
 	Clock        clock.Clock
+	Metrics      telemetry.Metrics
+
+	// BackoffBase and BackoffMax control the delay before polling again
+	// after consecutive failures. BackoffMax defaults to the poll interval.
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+
+	// FetchX509, if true, also fetches the X.509 bundles of the trust
+	// domains, whose authorities are returned by FetchX509Authorities.
+	FetchX509 bool
+
+	// MaxJWKSSize caps the size, in bytes, of the bundles received from the
+	// Workload API. Larger responses are rejected before being buffered. The
+	// gRPC default is used when zero.
+	MaxJWKSSize int
 }
 
 type WorkloadAPISource struct {
-	log         logrus.FieldLogger
-	clock       clock.Clock
-	trustDomain spiffeid.TrustDomain
-	cancel      context.CancelFunc
+	log          logrus.FieldLogger
+	clock        clock.Clock
+	metrics      sourceMetrics
+	trustDomains []spiffeid.TrustDomain
+	fetchX509    bool
+	cancel       context.CancelFunc
+
+	mu         sync.RWMutex
+	wg         sync.WaitGroup
+	rawBundles [][]byte
+	jwks       *jose.JSONWebKeySet
+	modTime    time.Time
+
+	x509Authorities []*x509.Certificate
+	x509ModTime     time.Time
 
-	mu        sync.RWMutex
-	wg        sync.WaitGroup
-	rawBundle []byte
-	jwks      *jose.JSONWebKeySet
-	modTime   time.Time
+	lastSuccessfulPoll time.Time
+	pollInterval       time.Duration
+	backoff            *pollBackoff
 }
 
 func NewWorkloadAPISource(config WorkloadAPISourceConfig) (*WorkloadAPISource, error) {
@@ -53,10 +81,20 @@ func NewWorkloadAPISource(config WorkloadAPISourceConfig) (*WorkloadAPISource, e
 	if config.SocketPath != "" {
 		opts = append(opts, workloadapi.WithAddr("unix://"+config.SocketPath))
 	}
+	if config.MaxJWKSSize > 0 {
+		opts = append(opts, workloadapi.WithDialOptions(grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(config.MaxJWKSSize))))
+	}
 
-	trustDomain, err := spiffeid.TrustDomainFromString(config.TrustDomain)
-	if err != nil {
-		return nil, errs.Wrap(err)
+	if len(config.TrustDomains) == 0 {
+		return nil, errs.New("at least one trust domain must be configured")
+	}
+	var trustDomains []spiffeid.TrustDomain
+	for _, td := range config.TrustDomains {
+		trustDomain, err := spiffeid.TrustDomainFromString(td)
+		if err != nil {
+			return nil, errs.Wrap(err)
+		}
+		trustDomains = append(trustDomains, trustDomain)
 	}
 
 	client, err := workloadapi.New(context.Background(), opts...)
@@ -66,13 +104,18 @@ func NewWorkloadAPISource(config WorkloadAPISourceConfig) (*WorkloadAPISource, e
 
 	ctx, cancel := context.WithCancel(context.Background())
 	s := &WorkloadAPISource{
-		log:         config.Log,
-		clock:       config.Clock,
-		cancel:      cancel,
-		trustDomain: trustDomain,
+		log:          config.Log,
+		clock:        config.Clock,
+		metrics:      newSourceMetrics(config.Metrics, "workload_api"),
+		cancel:       cancel,
+		trustDomains: trustDomains,
+		fetchX509:    config.FetchX509,
+		pollInterval: config.PollInterval,
+		backoff:      newPollBackoff(config.BackoffBase, config.BackoffMax),
 	}
 
-	go s.pollEvery(ctx, client, config.PollInterval)
+	s.wg.Add(1)
+	go s.pollEvery(ctx, client)
 	return s, nil
 }
 
@@ -91,69 +134,224 @@ func (s *WorkloadAPISource) FetchKeySet() (*jose.JSONWebKeySet, time.Time, bool)
 	return s.jwks, s.modTime, true
 }
 
-func (s *WorkloadAPISource) pollEvery(ctx context.Context, client *workloadapi.Client, interval time.Duration) {
-	s.wg.Add(1)
+// FetchX509Authorities returns the X.509 authorities of the trust domains
+// and their modified time. They are only available when the source is
+// configured to fetch the X.509 bundles.
+func (s *WorkloadAPISource) FetchX509Authorities() ([]*x509.Certificate, time.Time, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.x509Authorities == nil {
+		return nil, time.Time{}, false
+	}
+	return s.x509Authorities, s.x509ModTime, true
+}
+
+// SetPollInterval changes the poll interval. It takes effect after the
+// next poll.
+func (s *WorkloadAPISource) SetPollInterval(pollInterval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pollInterval = pollInterval
+}
+
+func (s *WorkloadAPISource) getPollInterval() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pollInterval
+}
+
+func (s *WorkloadAPISource) LastSuccessfulPoll() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastSuccessfulPoll
+}
+
+func (s *WorkloadAPISource) pollEvery(ctx context.Context, client *workloadapi.Client) {
 	defer s.wg.Done()
 
 	defer client.Close()
 
-	s.log.WithField("interval", interval).Debug("Polling started")
+	s.log.WithField("interval", s.getPollInterval()).Debug("Polling started")
 	for {
-		s.pollOnce(ctx, client)
+		next := s.getPollInterval()
+		if s.pollOnce(ctx, client) {
+			s.backoff.Reset()
+		} else {
+			next = s.backoff.Next(next)
+		}
 		select {
 		case <-ctx.Done():
 			s.log.WithError(ctx.Err()).Debug("Polling done")
 			return
-		case <-s.clock.After(interval):
+		case <-s.clock.After(next):
 		}
 	}
 }
 
-func (s *WorkloadAPISource) pollOnce(ctx context.Context, client *workloadapi.Client) {
+func (s *WorkloadAPISource) pollOnce(ctx context.Context, client *workloadapi.Client) bool {
+	start := time.Now()
 	jwtBundles, err := client.FetchJWTBundles(ctx)
+	s.metrics.MeasurePoll(start)
 	if err != nil {
+		s.metrics.IncrPollFailure()
 		s.log.WithError(err).Warn("Failed to fetch JWKS from the Workload API")
-		return
+		return false
 	}
 
-	jwtBundle, ok := jwtBundles.Get(s.trustDomain)
-	if !ok {
-		s.log.WithField(telemetry.TrustDomainID, s.trustDomain.IDString()).Error("No bundle for trust domain in Workload API response")
-		return
+	var bundles []*jwtbundle.Bundle
+	for _, trustDomain := range s.trustDomains {
+		jwtBundle, ok := jwtBundles.Get(trustDomain)
+		if !ok {
+			s.metrics.IncrPollFailure()
+			s.log.WithField(telemetry.TrustDomainID, trustDomain.IDString()).Error("No bundle for trust domain in Workload API response")
+			return false
+		}
+		bundles = append(bundles, jwtBundle)
 	}
 
-	s.setJWKS(jwtBundle)
+	s.setJWKS(bundles)
+
+	if s.fetchX509 && !s.pollX509Once(ctx, client) {
+		return false
+	}
+
+	s.setLastSuccessfulPoll()
+	return true
 }
 
-func (s *WorkloadAPISource) setJWKS(bundle *jwtbundle.Bundle) {
-	rawBundle, err := bundle.Marshal()
+func (s *WorkloadAPISource) pollX509Once(ctx context.Context, client *workloadapi.Client) bool {
+	x509Bundles, err := client.FetchX509Bundles(ctx)
 	if err != nil {
-		s.log.WithError(err).Error("Failed to marshal JWKS bundle received from the Workload API")
+		s.metrics.IncrPollFailure()
+		s.log.WithError(err).Warn("Failed to fetch X.509 bundles from the Workload API")
+		return false
+	}
+
+	authorities := []*x509.Certificate{}
+	for _, trustDomain := range s.trustDomains {
+		x509Bundle, ok := x509Bundles.Get(trustDomain)
+		if !ok {
+			s.metrics.IncrPollFailure()
+			s.log.WithField(telemetry.TrustDomainID, trustDomain.IDString()).Error("No X.509 bundle for trust domain in Workload API response")
+			return false
+		}
+		authorities = append(authorities, x509Bundle.X509Authorities()...)
+	}
+
+	s.setX509Authorities(authorities)
+	return true
+}
+
+func (s *WorkloadAPISource) setX509Authorities(authorities []*x509.Certificate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.x509Authorities != nil && certificatesEqual(s.x509Authorities, authorities) {
 		return
 	}
+	s.x509Authorities = authorities
+	s.x509ModTime = s.clock.Now()
+}
 
-	// If the bundle hasn't changed, don't bother continuing
+func (s *WorkloadAPISource) setLastSuccessfulPoll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastSuccessfulPoll = s.clock.Now()
+}
+
+func (s *WorkloadAPISource) setJWKS(bundles []*jwtbundle.Bundle) {
+	var rawBundles [][]byte
+	for _, bundle := range bundles {
+		rawBundle, err := bundle.Marshal()
+		if err != nil {
+			s.log.WithError(err).WithField(telemetry.TrustDomainID, bundle.TrustDomain().IDString()).Error("Failed to marshal JWKS bundle received from the Workload API")
+			return
+		}
+		rawBundles = append(rawBundles, rawBundle)
+	}
+
+	// If the bundles haven't changed, don't bother continuing
 	s.mu.RLock()
-	unchanged := s.rawBundle != nil && bytes.Equal(s.rawBundle, rawBundle)
+	unchanged := s.rawBundles != nil && rawBundlesEqual(s.rawBundles, rawBundles)
 	s.mu.RUnlock()
 	if unchanged {
 		return
 	}
 
-	// Clean the JWKS
+	// Merge the keys of all of the bundles into a single JWKS, keeping the
+	// first key when more than one trust domain publishes the same key ID.
 	jwks := new(jose.JSONWebKeySet)
-	if err := json.Unmarshal(rawBundle, jwks); err != nil {
-		s.log.WithError(err).Error("Failed to parse trust domain bundle received from the Workload API")
-		return
-	}
-	for i, key := range jwks.Keys {
-		key.Use = ""
-		jwks.Keys[i] = key
+	seen := make(map[string]jose.JSONWebKey)
+	for i, rawBundle := range rawBundles {
+		trustDomain := bundles[i].TrustDomain()
+
+		// Clean the JWKS
+		bundleJWKS := new(jose.JSONWebKeySet)
+		if err := json.Unmarshal(rawBundle, bundleJWKS); err != nil {
+			s.log.WithError(err).WithField(telemetry.TrustDomainID, trustDomain.IDString()).Error("Failed to parse trust domain bundle received from the Workload API")
+			return
+		}
+		// Keep the order stable since the bundle keys are not ordered
+		sort.Slice(bundleJWKS.Keys, func(i, j int) bool {
+			return bundleJWKS.Keys[i].KeyID < bundleJWKS.Keys[j].KeyID
+		})
+		for _, key := range bundleJWKS.Keys {
+			key.Use = ""
+			if existing, ok := seen[key.KeyID]; ok {
+				if !sameKeyMaterial(existing, key) {
+					s.log.WithFields(logrus.Fields{
+						telemetry.TrustDomainID: trustDomain.IDString(),
+						"kid":                   key.KeyID,
+					}).Warn("Key ID published by more than one trust domain with different key material; keeping the first")
+				}
+				continue
+			}
+			seen[key.KeyID] = key
+			jwks.Keys = append(jwks.Keys, key)
+		}
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.rawBundle = rawBundle
+	logKeySetChanges(s.log, s.jwks, jwks)
+	s.rawBundles = rawBundles
 	s.jwks = jwks
 	s.modTime = s.clock.Now()
+	s.metrics.SetKeyCount(len(jwks.Keys))
+}
+
+func rawBundlesEqual(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func certificatesEqual(a, b []*x509.Certificate) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameKeyMaterial returns whether the two keys hold the same public key.
+func sameKeyMaterial(a, b jose.JSONWebKey) bool {
+	aThumbprint, err := a.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return false
+	}
+	bThumbprint, err := b.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(aThumbprint, bThumbprint)
 }
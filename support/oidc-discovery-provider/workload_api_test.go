@@ -7,10 +7,16 @@ import (
 	"testing"
 	"time"
 
+	"github.com/sirupsen/logrus"
 	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/spiffe/go-spiffe/v2/proto/spiffe/workload"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/spire/pkg/common/telemetry"
+	"github.com/spiffe/spire/pkg/common/x509util"
 	"github.com/spiffe/spire/test/clock"
 	"github.com/spiffe/spire/test/spiretest"
+	"github.com/spiffe/spire/test/testca"
+	"github.com/spiffe/spire/test/testkey"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -34,7 +40,7 @@ func TestWorkloadAPISource(t *testing.T) {
 	source, err := NewWorkloadAPISource(WorkloadAPISourceConfig{
 		Log:          log,
 		SocketPath:   socketPath,
-		TrustDomain:  "domain.test",
+		TrustDomains: []string{"domain.test"},
 		PollInterval: pollInterval,
 		Clock:        clock,
 	})
@@ -42,23 +48,24 @@ func TestWorkloadAPISource(t *testing.T) {
 	defer source.Close()
 
 	// Wait for the poll to happen and assert there is no key set available
-	clock.WaitForAfter(time.Minute, "failed to wait for the poll timer")
+	retryAfter := waitForBackoff(t, clock, pollInterval)
 	_, _, ok := source.FetchKeySet()
 	require.False(t, ok, "No bundle was available but we have a keyset somehow")
 	require.Equal(t, 1, api.GetFetchJWTBundlesCount())
 
-	// Set a bundle without an entry for the trust domain, advance to the next
-	// period, wait for the poll to happen and assert there is no key set
+	// Set a bundle without an entry for the trust domain, advance past the
+	// backoff, wait for the poll to happen and assert there is no key set
 	// available
 	api.SetJWTBundles(map[string][]byte{})
-	clock.Add(pollInterval)
-	clock.WaitForAfter(time.Minute, "failed to wait for the poll timer")
+	clock.Add(retryAfter)
+	retryAfter = waitForBackoff(t, clock, pollInterval)
 	_, _, ok = source.FetchKeySet()
 	require.False(t, ok, "No bundle was available but we have a keyset somehow")
 	require.Equal(t, 2, api.GetFetchJWTBundlesCount())
+	require.True(t, source.LastSuccessfulPoll().IsZero(), "No bundle was available but the poll was successful somehow")
 
-	// Add a bundle, step forward past the poll interval, wait for polling,
-	// and assert we have a keyset.
+	// Add a bundle, step forward past the backoff, wait for polling, and
+	// assert we have a keyset.
 	api.SetJWTBundles(map[string][]byte{
 		"spiffe://domain.test": makeJWKS(t, &jose.JSONWebKeySet{
 			Keys: []jose.JSONWebKey{
@@ -69,12 +76,13 @@ func TestWorkloadAPISource(t *testing.T) {
 			},
 		}),
 	})
-	clock.Add(pollInterval)
+	clock.Add(retryAfter)
 	clock.WaitForAfter(time.Minute, "failed to wait for the poll timer")
 	require.Equal(t, 3, api.GetFetchJWTBundlesCount())
 	keySet1, modTime1, ok := source.FetchKeySet()
 	require.True(t, ok)
 	require.Equal(t, clock.Now(), modTime1)
+	require.Equal(t, clock.Now(), source.LastSuccessfulPoll())
 	require.NotNil(t, keySet1)
 	require.Len(t, keySet1.Keys, 1)
 	require.Equal(t, "KID", keySet1.Keys[0].KeyID)
@@ -114,12 +122,240 @@ func TestWorkloadAPISource(t *testing.T) {
 	require.Equal(t, ec256Pubkey, keySet3.Keys[0].Key)
 }
 
+func TestWorkloadAPISourceMultipleTrustDomains(t *testing.T) {
+	// TODO: workload source is not supported on windows until we solve workload API
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+	const pollInterval = time.Second
+
+	otherPubkey := testkey.NewEC256(t).Public()
+
+	api := &fakeWorkloadAPIServer{}
+	api.SetJWTBundles(map[string][]byte{
+		"spiffe://foo.test": makeJWKS(t, &jose.JSONWebKeySet{
+			Keys: []jose.JSONWebKey{
+				{
+					KeyID: "FOO",
+					Key:   ec256Pubkey,
+				},
+				{
+					KeyID: "SHARED",
+					Key:   ec256Pubkey,
+				},
+				{
+					KeyID: "COLLIDING",
+					Key:   ec256Pubkey,
+				},
+			},
+		}),
+		"spiffe://bar.test": makeJWKS(t, &jose.JSONWebKeySet{
+			Keys: []jose.JSONWebKey{
+				{
+					KeyID: "BAR",
+					Key:   otherPubkey,
+				},
+				{
+					KeyID: "SHARED",
+					Key:   ec256Pubkey,
+				},
+				{
+					KeyID: "COLLIDING",
+					Key:   otherPubkey,
+				},
+			},
+		}),
+	})
+
+	socketPath := spiretest.StartWorkloadAPIOnTempSocket(t, api)
+
+	log, hook := test.NewNullLogger()
+	clock := clock.NewMock(t)
+
+	source, err := NewWorkloadAPISource(WorkloadAPISourceConfig{
+		Log:          log,
+		SocketPath:   socketPath,
+		TrustDomains: []string{"foo.test", "bar.test"},
+		PollInterval: pollInterval,
+		Clock:        clock,
+	})
+	require.NoError(t, err)
+	defer source.Close()
+
+	// Wait for the poll to happen and assert the keys of both trust domains
+	// are merged, ordered by key ID within each trust domain and deduplicated
+	// by key ID, keeping the first one.
+	clock.WaitForAfter(time.Minute, "failed to wait for the poll timer")
+	keySet, _, ok := source.FetchKeySet()
+	require.True(t, ok)
+	require.Equal(t, &jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{
+			{KeyID: "COLLIDING", Key: ec256Pubkey},
+			{KeyID: "FOO", Key: ec256Pubkey},
+			{KeyID: "SHARED", Key: ec256Pubkey},
+			{KeyID: "BAR", Key: otherPubkey},
+		},
+	}, stripKeyMetadata(keySet))
+
+	// Only the key ID published with different key material is reported
+	spiretest.AssertLogs(t, hook.AllEntries(), []spiretest.LogEntry{
+		{
+			Level:   logrus.WarnLevel,
+			Message: "Key ID published by more than one trust domain with different key material; keeping the first",
+			Data: logrus.Fields{
+				telemetry.TrustDomainID: "spiffe://bar.test",
+				"kid":                   "COLLIDING",
+			},
+		},
+		{
+			Level:   logrus.InfoLevel,
+			Message: "Key set changed",
+			Data: logrus.Fields{
+				"added_kids":   "[BAR COLLIDING FOO SHARED]",
+				"removed_kids": "[]",
+			},
+		},
+	})
+
+	// Remove the bundle for one of the trust domains, step forward past the
+	// poll interval, wait for polling, and assert the poll failed and the
+	// previous key set is still served.
+	api.SetJWTBundles(map[string][]byte{
+		"spiffe://foo.test": makeJWKS(t, &jose.JSONWebKeySet{}),
+	})
+	clock.Add(pollInterval)
+	waitForBackoff(t, clock, pollInterval)
+	keySet2, _, ok := source.FetchKeySet()
+	require.True(t, ok)
+	require.Equal(t, keySet, keySet2)
+}
+
+func TestWorkloadAPISourceFetchX509(t *testing.T) {
+	// TODO: workload source is not supported on windows until we solve workload API
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+	const pollInterval = time.Second
+
+	ca := testca.New(t, spiffeid.RequireTrustDomainFromString("domain.test"))
+
+	api := &fakeWorkloadAPIServer{}
+	api.SetJWTBundles(map[string][]byte{
+		"spiffe://domain.test": makeJWKS(t, &jose.JSONWebKeySet{}),
+	})
+
+	socketPath := spiretest.StartWorkloadAPIOnTempSocket(t, api)
+
+	log, _ := test.NewNullLogger()
+	clock := clock.NewMock(t)
+
+	source, err := NewWorkloadAPISource(WorkloadAPISourceConfig{
+		Log:          log,
+		SocketPath:   socketPath,
+		TrustDomains: []string{"domain.test"},
+		PollInterval: pollInterval,
+		Clock:        clock,
+		FetchX509:    true,
+	})
+	require.NoError(t, err)
+	defer source.Close()
+
+	// Wait for the poll to happen and assert the poll failed since there is
+	// no X.509 bundle, although the key set is available.
+	retryAfter := waitForBackoff(t, clock, pollInterval)
+	_, _, ok := source.FetchX509Authorities()
+	require.False(t, ok)
+	_, _, ok = source.FetchKeySet()
+	require.True(t, ok)
+	require.True(t, source.LastSuccessfulPoll().IsZero())
+
+	// Add the X.509 bundle, step forward past the backoff, wait for polling,
+	// and assert the authorities are available.
+	api.SetX509Bundles(map[string][]byte{
+		"spiffe://domain.test": x509util.DERFromCertificates(ca.X509Authorities()),
+	})
+	clock.Add(retryAfter)
+	clock.WaitForAfter(time.Minute, "failed to wait for the poll timer")
+	authorities, modTime, ok := source.FetchX509Authorities()
+	require.True(t, ok)
+	require.Equal(t, ca.X509Authorities(), authorities)
+	require.Equal(t, clock.Now(), modTime)
+	require.Equal(t, clock.Now(), source.LastSuccessfulPoll())
+
+	// Wait another poll interval and assert the modified time is unchanged
+	// since the authorities did not change.
+	clock.Add(pollInterval)
+	clock.WaitForAfter(time.Minute, "failed to wait for the poll timer")
+	_, modTime2, ok := source.FetchX509Authorities()
+	require.True(t, ok)
+	require.Equal(t, modTime, modTime2)
+}
+
+func TestWorkloadAPISourceDoesNotFetchX509ByDefault(t *testing.T) {
+	// TODO: workload source is not supported on windows until we solve workload API
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+	const pollInterval = time.Second
+
+	api := &fakeWorkloadAPIServer{}
+	api.SetJWTBundles(map[string][]byte{
+		"spiffe://domain.test": makeJWKS(t, &jose.JSONWebKeySet{}),
+	})
+
+	socketPath := spiretest.StartWorkloadAPIOnTempSocket(t, api)
+
+	log, _ := test.NewNullLogger()
+	clock := clock.NewMock(t)
+
+	source, err := NewWorkloadAPISource(WorkloadAPISourceConfig{
+		Log:          log,
+		SocketPath:   socketPath,
+		TrustDomains: []string{"domain.test"},
+		PollInterval: pollInterval,
+		Clock:        clock,
+	})
+	require.NoError(t, err)
+	defer source.Close()
+
+	clock.WaitForAfter(time.Minute, "failed to wait for the poll timer")
+	require.Equal(t, clock.Now(), source.LastSuccessfulPoll())
+	_, _, ok := source.FetchX509Authorities()
+	require.False(t, ok)
+	require.Equal(t, 0, api.GetFetchX509SVIDCount())
+}
+
+// stripKeyMetadata returns the key set with only the key IDs and keys, for
+// comparison purposes.
+func stripKeyMetadata(jwks *jose.JSONWebKeySet) *jose.JSONWebKeySet {
+	out := new(jose.JSONWebKeySet)
+	for _, key := range jwks.Keys {
+		out.Keys = append(out.Keys, jose.JSONWebKey{KeyID: key.KeyID, Key: key.Key})
+	}
+	return out
+}
+
 type fakeWorkloadAPIServer struct {
 	workload.SpiffeWorkloadAPIServer
 
 	mu                   sync.Mutex
 	bundles              map[string][]byte
+	x509Bundles          map[string][]byte
 	fetchJWTBundlesCount int
+	fetchX509SVIDCount   int
+}
+
+func (s *fakeWorkloadAPIServer) SetX509Bundles(bundles map[string][]byte) {
+	s.mu.Lock()
+	s.x509Bundles = bundles
+	s.mu.Unlock()
+}
+
+func (s *fakeWorkloadAPIServer) GetFetchX509SVIDCount() int {
+	s.mu.Lock()
+	count := s.fetchX509SVIDCount
+	s.mu.Unlock()
+	return count
 }
 
 func (s *fakeWorkloadAPIServer) SetJWTBundles(bundles map[string][]byte) {
@@ -156,6 +392,29 @@ func (s *fakeWorkloadAPIServer) FetchJWTBundles(_ *workload.JWTBundlesRequest, s
 	return nil
 }
 
+// FetchX509SVID only returns the X.509 bundles, as federated bundles, since
+// the Workload API client fetches the X.509 bundles through this RPC.
+func (s *fakeWorkloadAPIServer) FetchX509SVID(_ *workload.X509SVIDRequest, stream workload.SpiffeWorkloadAPI_FetchX509SVIDServer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fetchX509SVIDCount++
+
+	if s.x509Bundles == nil {
+		return status.Error(codes.NotFound, "no bundle")
+	}
+
+	// Send the X.509 bundles right away
+	if err := stream.Send(&workload.X509SVIDResponse{
+		FederatedBundles: s.x509Bundles,
+	}); err != nil {
+		return err
+	}
+
+	// Wait for the stream to close down
+	<-stream.Context().Done()
+	return nil
+}
+
 func makeJWKS(t *testing.T, jwks *jose.JSONWebKeySet) []byte {
 	out, err := json.Marshal(jwks)
 	require.NoError(t, err)
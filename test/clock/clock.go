@@ -48,6 +48,11 @@ func (m *Mock) TimerCh() <-chan time.Duration {
 	return m.timerC
 }
 
+// AfterCh returns a channel that receives the duration passed to After.
+func (m *Mock) AfterCh() <-chan time.Duration {
+	return m.afterC
+}
+
 // WaitForTimer waits up to the specified timeout for Timer to be called on the clock.
 func (m *Mock) WaitForTimer(timeout time.Duration, format string, args ...interface{}) {
 	select {
@@ -89,11 +89,11 @@ func (s *DataStore) DeleteBundle(ctx context.Context, trustDomain string, mode d
 	return s.ds.DeleteBundle(ctx, trustDomain, mode)
 }
 
-func (s *DataStore) FetchBundle(ctx context.Context, trustDomain string) (*common.Bundle, error) {
+func (s *DataStore) FetchBundle(ctx context.Context, trustDomain string, dataConsistency datastore.DataConsistency) (*common.Bundle, error) {
 	if err := s.getNextError(); err != nil {
 		return nil, err
 	}
-	return s.ds.FetchBundle(ctx, trustDomain)
+	return s.ds.FetchBundle(ctx, trustDomain, dataConsistency)
 }
 
 func (s *DataStore) ListBundles(ctx context.Context, req *datastore.ListBundlesRequest) (*datastore.ListBundlesResponse, error) {